@@ -0,0 +1,335 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// Package asyncqueue emulates the asynchronous invocation path of OpenFaaS,
+// where the gateway publishes requests to NATS and the queue-worker invokes
+// the function, then posts the result to any X-Callback-Url. The queue is
+// held in memory, so it is lost when the process exits.
+//
+// A request with an X-Deliver-At time is held until then, for queues which
+// support deferred messages, and the time is sent back to confirm it.
+package asyncqueue
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDepth is how many requests can be queued before new ones are rejected
+const DefaultDepth = 100
+
+// DeliverAtHeader is the RFC3339 time at which a request is to be invoked
+const DeliverAtHeader = "X-Deliver-At"
+
+// Queue accepts requests on /async-function/NAME and invokes the function
+// with a pool of workers. Requests to /function/NAME are proxied directly.
+type Queue struct {
+	mu        sync.RWMutex
+	functions map[string]*url.URL
+
+	jobs   chan job
+	closed bool
+	// scheduled holds the requests with a delivery time, the number of
+	// them is limited by the depth of the queue
+	scheduled map[*scheduledJob]struct{}
+	wg        sync.WaitGroup
+	client    *http.Client
+
+	// Logger records each invocation, log.Default() is used when nil
+	Logger *log.Logger
+	// Now is used for call IDs and durations and can be overridden by tests
+	Now func() time.Time
+}
+
+type job struct {
+	callID   string
+	name     string
+	method   string
+	path     string
+	rawQuery string
+	header   http.Header
+	body     []byte
+	queued   time.Time
+	// deliverAt is zero unless the request is deferred
+	deliverAt time.Time
+}
+
+// scheduledJob is a request waiting for its delivery time, the timer is
+// only set and read while the queue's lock is held
+type scheduledJob struct {
+	job   job
+	timer *time.Timer
+}
+
+// New starts a queue with the given number of workers, invoking functions
+// with a timeout per request
+func New(workers, depth int, timeout time.Duration) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	if depth < 1 {
+		depth = DefaultDepth
+	}
+
+	q := &Queue{
+		functions: map[string]*url.URL{},
+		jobs:      make(chan job, depth),
+		scheduled: map[*scheduledJob]struct{}{},
+		client:    &http.Client{Timeout: timeout},
+		Now:       time.Now,
+	}
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	return q
+}
+
+// AddFunction routes invocations of name to the given base URL
+func (q *Queue) AddFunction(name string, target *url.URL) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.functions[name] = target
+}
+
+// Close stops accepting requests and waits for queued ones to finish, those
+// which are scheduled for later are dropped
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	if len(q.scheduled) > 0 {
+		for scheduled := range q.scheduled {
+			scheduled.timer.Stop()
+		}
+		q.logf("[async] dropped %d scheduled call(s), which had not reached their delivery time", len(q.scheduled))
+		q.scheduled = map[*scheduledJob]struct{}{}
+	}
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+// ServeHTTP implements http.Handler
+func (q *Queue) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var async bool
+	var rest string
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/async-function/"):
+		async, rest = true, strings.TrimPrefix(r.URL.Path, "/async-function/")
+	case strings.HasPrefix(r.URL.Path, "/function/"):
+		rest = strings.TrimPrefix(r.URL.Path, "/function/")
+	case r.URL.Path == "/healthz":
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	name, path := rest, "/"
+	if i := strings.Index(rest, "/"); i > -1 {
+		name, path = rest[:i], rest[i:]
+	}
+
+	q.mu.RLock()
+	target, ok := q.functions[name]
+	q.mu.RUnlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("error finding function %s: not found", name), http.StatusNotFound)
+		return
+	}
+
+	if !async {
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		r.URL.Path = path
+		proxy.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	queued := q.Now()
+	j := job{
+		callID:   r.Header.Get("X-Call-Id"),
+		name:     name,
+		method:   r.Method,
+		path:     path,
+		rawQuery: r.URL.RawQuery,
+		header:   r.Header.Clone(),
+		body:     body,
+		queued:   queued,
+	}
+	if len(j.callID) == 0 {
+		j.callID = fmt.Sprintf("%d", queued.UnixNano())
+	}
+	if value := r.Header.Get(DeliverAtHeader); len(value) > 0 {
+		if j.deliverAt, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s, want an RFC3339 time: %s", DeliverAtHeader, value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if status, err := q.enqueue(j); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("X-Call-Id", j.callID)
+	if !j.deliverAt.IsZero() {
+		w.Header().Set(DeliverAtHeader, j.deliverAt.UTC().Format(time.RFC3339))
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (q *Queue) enqueue(j job) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return http.StatusServiceUnavailable, fmt.Errorf("the queue is shutting down")
+	}
+
+	if delay := j.deliverAt.Sub(q.Now()); !j.deliverAt.IsZero() && delay > 0 {
+		if len(q.scheduled) >= cap(q.jobs) {
+			return http.StatusTooManyRequests, fmt.Errorf("too many calls are scheduled")
+		}
+
+		// The job is its own key, as the timer can fire before AfterFunc
+		// returns, and release waits for the lock held here
+		scheduled := &scheduledJob{job: j}
+		q.scheduled[scheduled] = struct{}{}
+		scheduled.timer = time.AfterFunc(delay, func() { q.release(scheduled) })
+		return http.StatusAccepted, nil
+	}
+
+	select {
+	case q.jobs <- j:
+		return http.StatusAccepted, nil
+	default:
+		return http.StatusTooManyRequests, fmt.Errorf("the queue is full")
+	}
+}
+
+// release queues a scheduled request at its delivery time
+func (q *Queue) release(scheduled *scheduledJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Close has dropped it
+	if _, ok := q.scheduled[scheduled]; !ok {
+		return
+	}
+	delete(q.scheduled, scheduled)
+	j := scheduled.job
+
+	select {
+	case q.jobs <- j:
+	default:
+		q.logf("[async] %s call %s was dropped at its delivery time, as the queue is full", j.name, j.callID)
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for j := range q.jobs {
+		q.invoke(j)
+	}
+}
+
+func (q *Queue) logf(format string, v ...interface{}) {
+	if q.Logger != nil {
+		q.Logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// invoke calls the function, then posts its response to any callback URL
+// with the same headers as the queue-worker
+func (q *Queue) invoke(j job) {
+	q.mu.RLock()
+	target := q.functions[j.name]
+	q.mu.RUnlock()
+
+	u := *target
+	u.Path = strings.TrimRight(u.Path, "/") + j.path
+	u.RawQuery = j.rawQuery
+
+	start := q.Now()
+	status, header, body, err := q.do(j.method, u.String(), j.header, j.body)
+	duration := q.Now().Sub(start)
+
+	if err != nil {
+		q.logf("[async] %s call %s failed after %1.3fs: %s", j.name, j.callID, duration.Seconds(), err)
+		status = http.StatusBadGateway
+		body = []byte(err.Error())
+	} else {
+		q.logf("[async] %s call %s returned %d in %1.3fs, queued for %1.3fs", j.name, j.callID, status, duration.Seconds(), start.Sub(j.queued).Seconds())
+	}
+
+	callbackURL := j.header.Get("X-Callback-Url")
+	if len(callbackURL) == 0 {
+		return
+	}
+
+	callbackHeader := http.Header{}
+	if contentType := header.Get("Content-Type"); len(contentType) > 0 {
+		callbackHeader.Set("Content-Type", contentType)
+	}
+	callbackHeader.Set("X-Call-Id", j.callID)
+	callbackHeader.Set("X-Function-Name", j.name)
+	callbackHeader.Set("X-Function-Status", fmt.Sprintf("%d", status))
+	callbackHeader.Set("X-Duration-Seconds", fmt.Sprintf("%f", duration.Seconds()))
+
+	callbackStatus, _, _, err := q.do(http.MethodPost, callbackURL, callbackHeader, body)
+	if err != nil {
+		q.logf("[async] %s call %s callback to %s failed: %s", j.name, j.callID, callbackURL, err)
+		return
+	}
+	q.logf("[async] %s call %s callback to %s returned %d", j.name, j.callID, callbackURL, callbackStatus)
+}
+
+func (q *Queue) do(method, u string, header http.Header, body []byte) (int, http.Header, []byte, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	for k, values := range header {
+		if strings.EqualFold(k, "X-Callback-Url") {
+			continue
+		}
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+
+	res, err := q.client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer res.Body.Close()
+
+	out, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return res.StatusCode, res.Header, out, nil
+}
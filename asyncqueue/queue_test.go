@@ -0,0 +1,204 @@
+package asyncqueue
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestQueue(t *testing.T, function http.Handler) (*Queue, *httptest.Server) {
+	t.Helper()
+
+	fn := httptest.NewServer(function)
+	t.Cleanup(fn.Close)
+
+	target, _ := url.Parse(fn.URL)
+	q := New(1, 10, 5*time.Second)
+	q.Logger = log.New(ioutil.Discard, "", 0)
+	q.AddFunction("echo", target)
+
+	s := httptest.NewServer(q)
+	t.Cleanup(s.Close)
+	return q, s
+}
+
+func Test_Queue_AsyncWithCallback(t *testing.T) {
+	_, s := newTestQueue(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.URL.Path + ":" + string(body)))
+	}))
+
+	callbacks := make(chan *http.Request, 1)
+	bodies := make(chan string, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies <- string(body)
+		callbacks <- r
+	}))
+	defer callback.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/async-function/echo/sub", strings.NewReader("hi"))
+	req.Header.Set("X-Callback-Url", callback.URL)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("want 202, got %d", res.StatusCode)
+	}
+	callID := res.Header.Get("X-Call-Id")
+	if len(callID) == 0 {
+		t.Fatalf("want an X-Call-Id")
+	}
+
+	select {
+	case r := <-callbacks:
+		if got := <-bodies; got != "/sub:hi" {
+			t.Errorf("want the function's response in the callback, got: %q", got)
+		}
+		if r.Header.Get("X-Call-Id") != callID || r.Header.Get("X-Function-Status") != "200" {
+			t.Errorf("unexpected callback headers: %v", r.Header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the callback")
+	}
+}
+
+func Test_Queue_SyncIsProxied(t *testing.T) {
+	_, s := newTestQueue(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("sync " + r.URL.Path))
+	}))
+
+	res, err := http.Get(s.URL + "/function/echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+	if string(body) != "sync /" {
+		t.Fatalf("want the function's response, got: %q", string(body))
+	}
+}
+
+func Test_Queue_UnknownFunction(t *testing.T) {
+	_, s := newTestQueue(t, http.NotFoundHandler())
+
+	res, err := http.Post(s.URL+"/async-function/missing", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("want 404, got %d", res.StatusCode)
+	}
+}
+
+func Test_Queue_RejectsAfterClose(t *testing.T) {
+	q, s := newTestQueue(t, http.NotFoundHandler())
+	q.Close()
+
+	res, err := http.Post(s.URL+"/async-function/echo", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("want 503, got %d", res.StatusCode)
+	}
+}
+
+func Test_Queue_DeliverAt(t *testing.T) {
+	invoked := make(chan time.Time, 1)
+	q, s := newTestQueue(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invoked <- time.Now()
+	}))
+
+	deliverAt := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/async-function/echo", nil)
+	req.Header.Set(DeliverAtHeader, deliverAt.Format(time.RFC3339))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusAccepted || res.Header.Get(DeliverAtHeader) != deliverAt.UTC().Format(time.RFC3339) {
+		t.Fatalf("want 202 with the delivery time confirmed, got %d %v", res.StatusCode, res.Header)
+	}
+
+	select {
+	case at := <-invoked:
+		if at.Before(deliverAt) {
+			t.Errorf("want the function invoked from %s, got: %s", deliverAt, at)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the scheduled call")
+	}
+
+	// A scheduled call which has not been delivered is dropped by Close
+	req, _ = http.NewRequest(http.MethodPost, s.URL+"/async-function/echo", nil)
+	req.Header.Set(DeliverAtHeader, time.Now().Add(time.Hour).Format(time.RFC3339))
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	q.Close()
+
+	select {
+	case <-invoked:
+		t.Errorf("want the call dropped")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func Test_Queue_DeliverAt_NearZeroDelay(t *testing.T) {
+	invoked := make(chan struct{}, 5)
+	q, _ := newTestQueue(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invoked <- struct{}{}
+	}))
+
+	// The timers fire as soon as they are started, before enqueue returns
+	now := time.Now()
+	q.Now = func() time.Time { return now }
+	for i := 0; i < cap(invoked); i++ {
+		j := job{callID: "call", name: "echo", method: http.MethodPost, path: "/", header: http.Header{}, deliverAt: now.Add(time.Nanosecond)}
+		if status, err := q.enqueue(j); err != nil {
+			t.Fatalf("want the call scheduled, got %d: %v", status, err)
+		}
+	}
+
+	for i := 0; i < cap(invoked); i++ {
+		select {
+		case <-invoked:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for scheduled call %d, it was lost", i+1)
+		}
+	}
+}
+
+func Test_Queue_InvalidDeliverAt(t *testing.T) {
+	_, s := newTestQueue(t, http.NotFoundHandler())
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/async-function/echo", nil)
+	req.Header.Set(DeliverAtHeader, "tomorrow")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("want 400, got %d", res.StatusCode)
+	}
+}
@@ -4,9 +4,12 @@
 package builder
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
@@ -24,6 +27,23 @@ const AdditionalPackageBuildArg = "ADDITIONAL_PACKAGE"
 // BuildImage construct Docker image from function parameters
 // TODO: refactor signature to a struct to simplify the length of the method header
 func BuildImage(image string, handler string, functionName string, language string, nocache bool, squash bool, shrinkwrap bool, buildArgMap map[string]string, buildOptions []string, tagMode schema.BuildFormat, buildLabelMap map[string]string, quietBuild bool, copyExtraPaths []string) error {
+	return BuildImageWithOutput(nil, image, handler, functionName, language, nocache, squash, shrinkwrap, buildArgMap, buildOptions, tagMode, buildLabelMap, quietBuild, copyExtraPaths, "", false)
+}
+
+// BuildImageWithOutput is BuildImage, but writes its messages and the Docker
+// output to out instead of stdout, so that concurrent builds can be told apart.
+// When out is nil, output goes to stdout and stderr.
+//
+// When remoteContext is set, the build context is uploaded there and Docker is
+// given its URL instead of streaming the context from the local folder.
+//
+// When hermetic is set, the build's steps run without a network, and a build
+// which fails after a step tried to reach one returns a *NetworkAccessError.
+func BuildImageWithOutput(out io.Writer, image string, handler string, functionName string, language string, nocache bool, squash bool, shrinkwrap bool, buildArgMap map[string]string, buildOptions []string, tagMode schema.BuildFormat, buildLabelMap map[string]string, quietBuild bool, copyExtraPaths []string, remoteContext string, hermetic bool) error {
+	stdout := out
+	if stdout == nil {
+		stdout = os.Stdout
+	}
 
 	if stack.IsValidTemplate(language) {
 		pathToTemplateYAML := fmt.Sprintf("./template/%s/template.yml", language)
@@ -52,14 +72,14 @@ func BuildImage(image string, handler string, functionName string, language stri
 			return fmt.Errorf("building %s, %s is an invalid path", imageName, handler)
 		}
 
-		tempPath, err := createBuildContext(functionName, handler, language, isLanguageTemplate(language), langTemplate.HandlerFolder, copyExtraPaths)
-		fmt.Printf("Building: %s with %s template. Please wait..\n", imageName, language)
+		tempPath, err := createBuildContext(stdout, functionName, handler, language, isLanguageTemplate(language), langTemplate.HandlerFolder, copyExtraPaths)
+		fmt.Fprintf(stdout, "Building: %s with %s template. Please wait..\n", imageName, language)
 		if err != nil {
 			return err
 		}
 
 		if shrinkwrap {
-			fmt.Printf("%s shrink-wrapped to %s\n", functionName, tempPath)
+			fmt.Fprintf(stdout, "%s shrink-wrapped to %s\n", functionName, tempPath)
 			return nil
 		}
 
@@ -78,34 +98,75 @@ func BuildImage(image string, handler string, functionName string, language stri
 			BuildArgMap:      buildArgMap,
 			BuildOptPackages: buildOptPackages,
 			BuildLabelMap:    buildLabelMap,
+			Hermetic:         hermetic,
+		}
+
+		// A proxy is of no use to a build without a network
+		if hermetic {
+			dockerBuildVal.HTTPProxy, dockerBuildVal.HTTPSProxy = "", ""
+		}
+
+		if len(remoteContext) > 0 {
+			contextURL, err := uploadBuildContext(stdout, remoteContext, functionName, tempPath)
+			if err != nil {
+				return err
+			}
+			dockerBuildVal.Context = contextURL
 		}
 
 		command, args := getDockerBuildCommand(dockerBuildVal)
 
 		envs := os.Environ()
-		if mountSSH {
+		if mountSSH || hermetic {
 			envs = append(envs, "DOCKER_BUILDKIT=1")
 		}
 
-		task := v1execute.ExecTask{
-			Cwd:         tempPath,
-			Command:     command,
-			Args:        args,
-			StreamStdio: !quietBuild,
-			Env:         envs,
-		}
+		if out == nil {
+			task := v1execute.ExecTask{
+				Cwd:         tempPath,
+				Command:     command,
+				Args:        args,
+				StreamStdio: !quietBuild,
+				Env:         envs,
+			}
 
-		res, err := task.Execute()
+			res, err := task.Execute()
 
-		if err != nil {
-			return err
-		}
+			if err != nil {
+				return err
+			}
+
+			if res.ExitCode != 0 {
+				err := fmt.Errorf("[%s] received non-zero exit code from build, error: %s", functionName, res.Stderr)
+				if hermetic {
+					return networkAccessError(functionName, res.Stdout+res.Stderr, err)
+				}
+				return err
+			}
+		} else {
+			stderr := &bytes.Buffer{}
+			cmd := exec.Command(command, args...)
+			cmd.Dir = tempPath
+			cmd.Env = envs
+			cmd.Stderr = stderr
+			if !quietBuild {
+				cmd.Stdout = out
+				cmd.Stderr = io.MultiWriter(out, stderr)
+			}
 
-		if res.ExitCode != 0 {
-			return fmt.Errorf("[%s] received non-zero exit code from build, error: %s", functionName, res.Stderr)
+			if err := cmd.Run(); err != nil {
+				if _, ok := err.(*exec.ExitError); !ok {
+					return err
+				}
+				err = fmt.Errorf("[%s] received non-zero exit code from build, error: %s", functionName, stderr.String())
+				if hermetic {
+					return networkAccessError(functionName, stderr.String(), err)
+				}
+				return err
+			}
 		}
 
-		fmt.Printf("Image: %s built.\n", imageName)
+		fmt.Fprintf(stdout, "Image: %s built.\n", imageName)
 
 	} else {
 		return fmt.Errorf("language template: %s not supported, build a custom Dockerfile", language)
@@ -153,7 +214,16 @@ func getDockerBuildCommand(build dockerBuild) (string, []string) {
 	args := []string{"build"}
 	args = append(args, flagSlice...)
 
-	args = append(args, "--tag", build.Image, ".")
+	if build.Hermetic {
+		args = append(args, "--network=none")
+	}
+
+	buildContext := "."
+	if len(build.Context) > 0 {
+		buildContext = build.Context
+	}
+
+	args = append(args, "--tag", build.Image, buildContext)
 
 	command := "docker"
 
@@ -176,14 +246,23 @@ type dockerBuild struct {
 
 	// ExtraTags for published images like :latest
 	ExtraTags []string
+
+	// Registries the image is also pushed to, such as a mirror for disaster recovery
+	Registries []string
+
+	// Context is a URL for a remote build context, the current folder is used when empty
+	Context string
+
+	// Hermetic runs the steps of the build without a network
+	Hermetic bool
 }
 
 var defaultDirPermissions os.FileMode = 0700
 
 const defaultHandlerFolder string = "function"
 
-// isRunningInCI checks the ENV var CI and returns true if it's set to true or 1
-func isRunningInCI() bool {
+// IsRunningInCI checks the ENV var CI and returns true if it's set to true or 1
+func IsRunningInCI() bool {
 	if env, ok := os.LookupEnv("CI"); ok {
 		if env == "true" || env == "1" {
 			return true
@@ -193,12 +272,12 @@ func isRunningInCI() bool {
 }
 
 // createBuildContext creates temporary build folder to perform a Docker build with language template
-func createBuildContext(functionName string, handler string, language string, useFunction bool, handlerFolder string, copyExtraPaths []string) (string, error) {
+func createBuildContext(out io.Writer, functionName string, handler string, language string, useFunction bool, handlerFolder string, copyExtraPaths []string) (string, error) {
 	tempPath := fmt.Sprintf("./build/%s/", functionName)
-	fmt.Printf("Clearing temporary build folder: %s\n", tempPath)
+	fmt.Fprintf(out, "Clearing temporary build folder: %s\n", tempPath)
 
 	if err := os.RemoveAll(tempPath); err != nil {
-		fmt.Printf("Error clearing temporary build folder: %s\n", tempPath)
+		fmt.Fprintf(out, "Error clearing temporary build folder: %s\n", tempPath)
 		return tempPath, err
 	}
 
@@ -212,21 +291,21 @@ func createBuildContext(functionName string, handler string, language string, us
 		}
 	}
 
-	fmt.Printf("Preparing: %s %s\n", handler+"/", functionPath)
+	fmt.Fprintf(out, "Preparing: %s %s\n", handler+"/", functionPath)
 
-	if isRunningInCI() {
+	if IsRunningInCI() {
 		defaultDirPermissions = 0777
 	}
 
 	mkdirErr := os.MkdirAll(functionPath, defaultDirPermissions)
 	if mkdirErr != nil {
-		fmt.Printf("Error creating path: %s - %s.\n", functionPath, mkdirErr.Error())
+		fmt.Fprintf(out, "Error creating path: %s - %s.\n", functionPath, mkdirErr.Error())
 		return tempPath, mkdirErr
 	}
 
 	if useFunction {
 		if err := CopyFiles(path.Join("./template/", language), tempPath); err != nil {
-			fmt.Printf("Error copying template directory: %s.\n", err.Error())
+			fmt.Fprintf(out, "Error copying template directory: %s.\n", err.Error())
 			return tempPath, err
 		}
 	}
@@ -235,24 +314,39 @@ func createBuildContext(functionName string, handler string, language string, us
 	// CopyFiles(handler, functionPath)
 	infos, err := ioutil.ReadDir(handler)
 	if err != nil {
-		fmt.Printf("Error reading the handler: %s - %s.\n", handler, err.Error())
+		fmt.Fprintf(out, "Error reading the handler: %s - %s.\n", handler, err.Error())
+		return tempPath, err
+	}
+
+	ignore, err := readFaasIgnore(handler)
+	if err != nil {
 		return tempPath, err
 	}
 
+	skipped := 0
 	for _, info := range infos {
 		switch info.Name() {
 		case "build", "template":
-			fmt.Printf("Skipping \"%s\" folder\n", info.Name())
+			fmt.Fprintf(out, "Skipping \"%s\" folder\n", info.Name())
+			continue
+		case FaasIgnoreFile:
 			continue
 		default:
-			if err := CopyFiles(
+			n, err := copyIgnoring(
 				filepath.Clean(path.Join(handler, info.Name())),
 				filepath.Clean(path.Join(functionPath, info.Name())),
-			); err != nil {
+				info.Name(),
+				ignore,
+			)
+			if err != nil {
 				return tempPath, err
 			}
+			skipped += n
 		}
 	}
+	if skipped > 0 {
+		fmt.Fprintf(out, "Skipping %d path(s) listed in %s\n", skipped, path.Join(handler, FaasIgnoreFile))
+	}
 
 	for _, extraPath := range copyExtraPaths {
 		extraPathAbs, err := pathInScope(extraPath, ".")
@@ -536,3 +536,37 @@ func Test_pathInScope(t *testing.T) {
 		})
 	}
 }
+
+func Test_getDockerBuildCommand_RemoteContext(t *testing.T) {
+	dockerBuildVal := dockerBuild{
+		Image:   "imagename:latest",
+		Context: "https://bucket.s3.amazonaws.com/fn.tar.gz?X-Amz-Signature=abc",
+	}
+
+	_, values := getDockerBuildCommand(dockerBuildVal)
+
+	if got := values[len(values)-1]; got != dockerBuildVal.Context {
+		t.Errorf("want the remote context as the last argument, got: %s", got)
+	}
+}
+
+func Test_getDockerBuildxCommand_Registries(t *testing.T) {
+	_, args := getDockerBuildxCommand(dockerBuild{
+		Image:      "ghcr.io/acme/fn:0.1",
+		Platforms:  "linux/amd64",
+		ExtraTags:  []string{"latest"},
+		Registries: []string{"dr.example.com/acme/"},
+	})
+
+	var tags []string
+	for i, arg := range args {
+		if arg == "--tag" {
+			tags = append(tags, args[i+1])
+		}
+	}
+
+	want := []string{"ghcr.io/acme/fn:0.1", "ghcr.io/acme/fn:latest", "dr.example.com/acme/fn:0.1", "dr.example.com/acme/fn:latest"}
+	if strings.Join(tags, " ") != strings.Join(want, " ") {
+		t.Fatalf("want tags %v, got %v", want, tags)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FaasIgnoreFile lists the paths of a handler folder, in the syntax of a
+// .gitignore, which are left out of the function's build context
+const FaasIgnoreFile = ".faasignore"
+
+// ignoreRule is one pattern of an ignore file
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules are the patterns of an ignore file, the last which matches a
+// path decides whether it is ignored
+type ignoreRules []ignoreRule
+
+// readFaasIgnore reads the .faasignore of a handler folder, there are no
+// rules when it has none
+func readFaasIgnore(handler string) (ignoreRules, error) {
+	f, err := os.Open(filepath.Join(handler, FaasIgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules ignoreRules
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		rule, ok, err := parseIgnoreLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filepath.Join(handler, FaasIgnoreFile), line, err)
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// parseIgnoreLine reads a line of an ignore file, ok is false for a blank
+// line or a comment
+func parseIgnoreLine(line string) (ignoreRule, bool, error) {
+	line = strings.TrimRight(line, " \t\r")
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false, nil
+	}
+
+	rule := ignoreRule{}
+	if strings.HasPrefix(line, "!") {
+		rule.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	if len(line) == 0 {
+		return ignoreRule{}, false, nil
+	}
+
+	// A pattern with a slash before its end is relative to the handler
+	// folder, otherwise it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := ignorePatternExpr(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+
+	pattern, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignoreRule{}, false, fmt.Errorf("invalid pattern %q: %w", line, err)
+	}
+	rule.pattern = pattern
+	return rule, true, nil
+}
+
+// ignorePatternExpr translates the wildcards of a pattern into a regular
+// expression, where ** matches any number of folders
+func ignorePatternExpr(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**") && i+3 == len(pattern):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteString(regexp.QuoteMeta(pattern[i+1 : i+2]))
+			i++
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// ignored reports whether the path, relative to the handler folder and with
+// forward slashes, is ignored
+func (r ignoreRules) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// copyIgnoring copies src to dest as CopyFiles does, leaving out the paths
+// which rules ignore, where rel is the path of src within the handler folder.
+// As with git, a path within an ignored folder can't be included again. The
+// number of paths left out is returned.
+func copyIgnoring(src, dest, rel string, rules ignoreRules) (int, error) {
+	if len(rules) == 0 {
+		return 0, CopyFiles(src, dest)
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if rules.ignored(rel, info.IsDir()) {
+		return 1, nil
+	}
+	if !info.IsDir() {
+		return 0, copyFile(src, dest)
+	}
+
+	if err := os.MkdirAll(dest, info.Mode()); err != nil {
+		return 0, fmt.Errorf("error creating path: %s - %s", dest, err.Error())
+	}
+
+	infos, err := ioutil.ReadDir(src)
+	if err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	for _, info := range infos {
+		n, err := copyIgnoring(filepath.Join(src, info.Name()), filepath.Join(dest, info.Name()), path.Join(rel, info.Name()), rules)
+		if err != nil {
+			return skipped, err
+		}
+		skipped += n
+	}
+	return skipped, nil
+}
@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_ignoreRules(t *testing.T) {
+	var rules ignoreRules
+	for _, line := range []string{"# comment", "", "testdata/", "*.log", "!keep.log", "/build.sh", "docs/**/*.png", `\#notes`} {
+		rule, ok, err := parseIgnoreLine(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			rules = append(rules, rule)
+		}
+	}
+
+	cases := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{"testdata", true, true},
+		{"pkg/testdata", true, true},
+		{"testdata", false, false},
+		{"debug.log", false, true},
+		{"logs/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build.sh", false, true},
+		{"scripts/build.sh", false, false},
+		{"docs/a/b/diagram.png", false, true},
+		{"docs/diagram.png", false, true},
+		{"diagram.png", false, false},
+		{"#notes", false, true},
+		{"handler.js", false, false},
+	}
+	for _, c := range cases {
+		if got := rules.ignored(c.rel, c.isDir); got != c.ignored {
+			t.Errorf("%s (dir: %v): want ignored: %v, got: %v", c.rel, c.isDir, c.ignored, got)
+		}
+	}
+}
+
+func Test_copyIgnoring(t *testing.T) {
+	handler := t.TempDir()
+	for _, name := range []string{"handler.js", "package.json", "testdata/big.bin", "lib/util.js", "lib/util.test.js", "lib/testdata/fixture.json"} {
+		path := filepath.Join(handler, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(name), 0644)
+	}
+	os.WriteFile(filepath.Join(handler, FaasIgnoreFile), []byte("testdata/\n*.test.js\n!testdata/keep.json\n"), 0644)
+
+	rules, err := readFaasIgnore(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := t.TempDir()
+	skipped := 0
+	for _, name := range []string{"handler.js", "package.json", "testdata", "lib"} {
+		n, err := copyIgnoring(filepath.Join(handler, name), filepath.Join(dest, name), name, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		skipped += n
+	}
+
+	var copied []string
+	filepath.Walk(dest, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dest, path)
+			copied = append(copied, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(copied)
+
+	if got := strings.Join(copied, ","); got != "handler.js,lib/util.js,package.json" {
+		t.Errorf("want the ignored paths left out, got: %s", got)
+	}
+	if skipped != 3 {
+		t.Errorf("want 3 paths skipped, got: %d", skipped)
+	}
+}
+
+func Test_readFaasIgnore_Missing(t *testing.T) {
+	rules, err := readFaasIgnore(t.TempDir())
+	if err != nil || rules != nil {
+		t.Errorf("want no rules without a %s, got: %v, %v", FaasIgnoreFile, rules, err)
+	}
+}
+
+func Test_readFaasIgnore_InvalidPattern(t *testing.T) {
+	handler := t.TempDir()
+	os.WriteFile(filepath.Join(handler, FaasIgnoreFile), []byte("ok\n[]\n"), 0644)
+
+	if _, err := readFaasIgnore(handler); err == nil || !strings.Contains(err.Error(), FaasIgnoreFile+":2:") {
+		t.Errorf("want an error with the line of the pattern, got: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// networkFailures are written by package managers and other tools when a step
+// of a build can't reach the network, in lower case
+var networkFailures = []string{
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"name or service not known",
+	"network is unreachable",
+	"getaddrinfo eai_again",
+	"getaddrinfo enotfound",
+	"dial tcp: lookup",
+	"no such host",
+	"failed to establish a new connection",
+	"temporary error (try again later)",
+	"unable to resolve host",
+	"could not connect to",
+}
+
+// NetworkAccessError is returned by a hermetic build which failed after one
+// of its steps tried to reach the network
+type NetworkAccessError struct {
+	Function string
+	// Evidence is the line of the build's output which shows the attempt
+	Evidence string
+	Err      error
+}
+
+func (e *NetworkAccessError) Error() string {
+	return fmt.Sprintf("[%s] tried to reach the network during a hermetic build: %s", e.Function, e.Evidence)
+}
+
+func (e *NetworkAccessError) Unwrap() error {
+	return e.Err
+}
+
+// networkAccessError returns a *NetworkAccessError when the output of a failed
+// build shows that it tried to reach the network, or else err
+func networkAccessError(functionName, output string, err error) error {
+	if evidence := networkAccessAttempt(output); len(evidence) > 0 {
+		return &NetworkAccessError{Function: functionName, Evidence: evidence, Err: err}
+	}
+	return err
+}
+
+// networkAccessAttempt gives the first line of output which shows a tool
+// failing to reach the network, or "" when there is none
+func networkAccessAttempt(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		lower := strings.ToLower(line)
+		for _, failure := range networkFailures {
+			if strings.Contains(lower, failure) {
+				return strings.TrimSpace(line)
+			}
+		}
+	}
+	return ""
+}
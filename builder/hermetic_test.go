@@ -0,0 +1,47 @@
+package builder
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_getDockerBuildCommand_Hermetic(t *testing.T) {
+	_, args := getDockerBuildCommand(dockerBuild{Image: "imagename:latest", Hermetic: true})
+
+	want := "build --network=none --tag imagename:latest ."
+	if joined := strings.Join(args, " "); joined != want {
+		t.Errorf("want: %q, got: %q", want, joined)
+	}
+}
+
+func Test_networkAccessAttempt(t *testing.T) {
+	cases := map[string]string{
+		"#6 0.412 fetch https://dl-cdn.alpinelinux.org/alpine/v3.18/main/x86_64/APKINDEX.tar.gz\n#6 0.413 WARNING: fetching https://dl-cdn.alpinelinux.org/alpine/v3.18/main: temporary error (try again later)": "#6 0.413 WARNING: fetching https://dl-cdn.alpinelinux.org/alpine/v3.18/main: temporary error (try again later)",
+		"#8 1.20 npm ERR! request to https://registry.npmjs.org/express failed, reason: getaddrinfo EAI_AGAIN registry.npmjs.org":                                                                                "#8 1.20 npm ERR! request to https://registry.npmjs.org/express failed, reason: getaddrinfo EAI_AGAIN registry.npmjs.org",
+		"#9 2.01 go: github.com/pkg/errors@v0.9.1: Get \"https://proxy.golang.org/\": dial tcp: lookup proxy.golang.org on 127.0.0.11:53":                                                                        "#9 2.01 go: github.com/pkg/errors@v0.9.1: Get \"https://proxy.golang.org/\": dial tcp: lookup proxy.golang.org on 127.0.0.11:53",
+		"#7 0.91 ./handler.go:3:2: undefined: foo": "",
+	}
+	for output, want := range cases {
+		if got := networkAccessAttempt(output); got != want {
+			t.Errorf("want: %q, got: %q", want, got)
+		}
+	}
+}
+
+func Test_networkAccessError(t *testing.T) {
+	buildErr := errors.New("received non-zero exit code from build")
+
+	err := networkAccessError("figlet", "#6 pip: Failed to establish a new connection: [Errno -3]", buildErr)
+	var networkErr *NetworkAccessError
+	if !errors.As(err, &networkErr) || networkErr.Function != "figlet" || !errors.Is(err, buildErr) {
+		t.Fatalf("want a NetworkAccessError which wraps the build's error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "Failed to establish a new connection") {
+		t.Errorf("want the evidence in the error, got: %s", err)
+	}
+
+	if err := networkAccessError("figlet", "#7 compile error", buildErr); err != buildErr {
+		t.Errorf("want the build's error when there is no network access, got: %v", err)
+	}
+}
@@ -16,7 +16,7 @@ import (
 // PublishImage will publish images as multi-arch
 // TODO: refactor signature to a struct to simplify the length of the method header
 func PublishImage(image string, handler string, functionName string, language string, nocache bool, squash bool, shrinkwrap bool, buildArgMap map[string]string,
-	buildOptions []string, tagMode schema.BuildFormat, buildLabelMap map[string]string, quietBuild bool, copyExtraPaths []string, platforms string, extraTags []string) error {
+	buildOptions []string, tagMode schema.BuildFormat, buildLabelMap map[string]string, quietBuild bool, copyExtraPaths []string, platforms string, extraTags []string, registries []string) error {
 
 	if stack.IsValidTemplate(language) {
 		pathToTemplateYAML := fmt.Sprintf("./template/%s/template.yml", language)
@@ -40,7 +40,7 @@ func PublishImage(image string, handler string, functionName string, language st
 			return fmt.Errorf("building %s, %s is an invalid path", imageName, handler)
 		}
 
-		tempPath, buildErr := createBuildContext(functionName, handler, language, isLanguageTemplate(language), langTemplate.HandlerFolder, copyExtraPaths)
+		tempPath, buildErr := createBuildContext(os.Stdout, functionName, handler, language, isLanguageTemplate(language), langTemplate.HandlerFolder, copyExtraPaths)
 		fmt.Printf("Building: %s with %s template. Please wait..\n", imageName, language)
 		if buildErr != nil {
 			return buildErr
@@ -69,6 +69,7 @@ func PublishImage(image string, handler string, functionName string, language st
 			BuildLabelMap:    buildLabelMap,
 			Platforms:        platforms,
 			ExtraTags:        extraTags,
+			Registries:       registries,
 		}
 
 		command, args := getDockerBuildxCommand(dockerBuildVal)
@@ -113,15 +114,26 @@ func getDockerBuildxCommand(build dockerBuild) (string, []string) {
 
 	args = append(args, "--tag", build.Image, ".")
 
-	for _, t := range build.ExtraTags {
+	images := []string{build.Image}
+	for _, registry := range build.Registries {
+		images = append(images, MirrorImage(build.Image, registry))
+	}
+
+	for i, image := range images {
+		if i > 0 {
+			args = append(args, "--tag", image)
+		}
+
+		for _, t := range build.ExtraTags {
 
-		var tag string
-		if i := strings.LastIndex(build.Image, ":"); i > -1 {
-			tag = applyTag(i, build.Image, t)
-		} else {
-			tag = applyTag(len(build.Image)-1, build.Image, t)
+			var tag string
+			if i := strings.LastIndex(image, ":"); i > -1 {
+				tag = applyTag(i, image, t)
+			} else {
+				tag = applyTag(len(image)-1, image, t)
+			}
+			args = append(args, "--tag", tag)
 		}
-		args = append(args, "--tag", tag)
 	}
 
 	command := "docker"
@@ -132,3 +144,14 @@ func getDockerBuildxCommand(build dockerBuild) (string, []string) {
 func applyTag(index int, baseImage, tag string) string {
 	return fmt.Sprintf("%s:%s", baseImage[:index], tag)
 }
+
+// MirrorImage replaces the registry and any owner of image with registry,
+// keeping its repository name and tag, so that "ghcr.io/acme/fn:0.1" mirrored
+// to "dr.example.com/acme" gives "dr.example.com/acme/fn:0.1"
+func MirrorImage(image, registry string) string {
+	name := image
+	if i := strings.LastIndex(image, "/"); i > -1 {
+		name = image[i+1:]
+	}
+	return strings.TrimRight(registry, "/") + "/" + name
+}
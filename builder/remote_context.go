@@ -0,0 +1,398 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// remoteContextExpiry is how long a pre-signed URL for a build context is valid for
+const remoteContextExpiry = time.Hour
+
+// RemoteContext is an object store location for build contexts, such as
+// s3://bucket/prefix, gs://bucket/prefix or an http(s):// URL accepting PUT.
+//
+// S3 and GCS are accessed with HMAC credentials from AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY, for GCS these are the interoperability keys of a
+// service account. AWS_REGION and AWS_ENDPOINT_URL are honoured for S3
+// compatible stores such as MinIO.
+type RemoteContext struct {
+	Scheme string
+	Bucket string
+	Prefix string
+
+	// URL is used as-is for http and https locations
+	URL *url.URL
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Endpoint     string
+
+	// Now is used for signing and can be overridden by tests
+	Now func() time.Time
+	// Client is used for uploads, http.DefaultClient when nil
+	Client *http.Client
+}
+
+// ParseRemoteContext parses a location and reads any credentials it needs
+// from the environment.
+func ParseRemoteContext(location string) (*RemoteContext, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote context %q: %w", location, err)
+	}
+
+	r := &RemoteContext{Scheme: u.Scheme, Now: time.Now}
+
+	switch u.Scheme {
+	case "http", "https":
+		r.URL = u
+		return r, nil
+	case "s3", "gs":
+	default:
+		return nil, fmt.Errorf("remote context %q must start with s3://, gs://, http:// or https://", location)
+	}
+
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("remote context %q must give a bucket", location)
+	}
+
+	r.Bucket = u.Host
+	r.Prefix = strings.Trim(u.Path, "/")
+	r.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	r.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	r.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+
+	if len(r.AccessKey) == 0 || len(r.SecretKey) == 0 {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to use %s", location)
+	}
+
+	if u.Scheme == "gs" {
+		r.Region = "auto"
+		r.Endpoint = "https://storage.googleapis.com"
+		return r, nil
+	}
+
+	r.Region = os.Getenv("AWS_REGION")
+	if len(r.Region) == 0 {
+		r.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if len(r.Region) == 0 {
+		r.Region = "us-east-1"
+	}
+	r.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+
+	return r, nil
+}
+
+// objectURL returns the URL of key within the store, S3 uses virtual-hosted
+// buckets unless a custom endpoint is given
+func (r *RemoteContext) objectURL(key string) *url.URL {
+	if r.URL != nil {
+		u := *r.URL
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + key
+		return &u
+	}
+
+	objectPath := "/" + path.Join(r.Prefix, key)
+	if len(r.Endpoint) > 0 {
+		u, _ := url.Parse(strings.TrimRight(r.Endpoint, "/"))
+		u.Path = "/" + r.Bucket + objectPath
+		return u
+	}
+
+	host := r.Bucket + ".s3.amazonaws.com"
+	if r.Region != "us-east-1" {
+		host = fmt.Sprintf("%s.s3.%s.amazonaws.com", r.Bucket, r.Region)
+	}
+	return &url.URL{Scheme: "https", Host: host, Path: objectPath}
+}
+
+// Upload stores data under key, and returns a URL which a builder can use to
+// fetch it. For S3 and GCS the URL is pre-signed and expires.
+func (r *RemoteContext) Upload(key string, data []byte) (string, error) {
+	target := r.objectURL(key)
+
+	req, err := http.NewRequest(http.MethodPut, target.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+
+	if r.URL == nil {
+		r.signRequest(req, data)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to upload build context to %s: %w", target.Redacted(), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("unable to upload build context to %s, status: %d, %s", target.Redacted(), res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if r.URL != nil {
+		return target.String(), nil
+	}
+
+	return r.presignGet(target, remoteContextExpiry), nil
+}
+
+// signRequest adds an AWS Signature Version 4 Authorization header
+func (r *RemoteContext) signRequest(req *http.Request, payload []byte) {
+	now := r.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if len(r.SessionToken) > 0 {
+		req.Header.Set("x-amz-security-token", r.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		lower := strings.ToLower(k)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, k := range names {
+		canonicalHeaders += k + ":" + headers[k] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncodePath(req.URL.Path),
+		"",
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := r.scope(now)
+	signature := r.signature(now, amzDate, scope, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		r.AccessKey, scope, signedHeaders, signature))
+}
+
+// presignGet returns a query-string signed URL for a GET of target
+func (r *RemoteContext) presignGet(target *url.URL, expiry time.Duration) string {
+	now := r.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := r.scope(now)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", r.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if len(r.SessionToken) > 0 {
+		query.Set("X-Amz-Security-Token", r.SessionToken)
+	}
+
+	canonicalQuery := canonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		uriEncodePath(target.Path),
+		canonicalQuery,
+		"host:" + target.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	signature := r.signature(now, amzDate, scope, canonicalRequest)
+
+	u := *target
+	u.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return u.String()
+}
+
+func (r *RemoteContext) scope(now time.Time) string {
+	return fmt.Sprintf("%s/%s/s3/aws4_request", now.Format("20060102"), r.Region)
+}
+
+func (r *RemoteContext) signature(now time.Time, amzDate, scope, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+r.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, r.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// uriEncodePath encodes each segment of a path as required by SigV4
+func uriEncodePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func uriEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, uriEncode(k)+"="+uriEncode(query.Get(k)))
+	}
+	return strings.Join(parts, "&")
+}
+
+// TarGzDirectory archives the contents of dir with paths relative to it, so that
+// the result can be used as a Docker build context.
+func TarGzDirectory(dir string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// uploadBuildContext archives the build context in tempPath and uploads it,
+// named after the function and the digest of the archive so that unchanged
+// contexts are not duplicated.
+func uploadBuildContext(out io.Writer, location, functionName, tempPath string) (string, error) {
+	remote, err := ParseRemoteContext(location)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := TarGzDirectory(tempPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to archive build context %s: %w", tempPath, err)
+	}
+
+	key := fmt.Sprintf("%s-%s.tar.gz", functionName, sha256Hex(data)[:12])
+	fmt.Fprintf(out, "Uploading build context for %s to %s (%d bytes)\n", functionName, location, len(data))
+
+	return remote.Upload(key, data)
+}
@@ -0,0 +1,176 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// TemplateStage is a single FROM stage of a template's Dockerfile
+type TemplateStage struct {
+	// Name given with "AS name", if any
+	Name string
+	// Image the stage is based upon, with ARG defaults substituted
+	Image string
+	// WorkDir is the last WORKDIR set in the stage
+	WorkDir string
+	// HandlerPath is the absolute path that the handler folder is copied to
+	// within this stage, or empty when the stage doesn't copy the handler.
+	HandlerPath string
+}
+
+// TemplateDockerfile describes the parts of a language template's Dockerfile
+// that are useful when running a function's code outside of "faas-cli build".
+type TemplateDockerfile struct {
+	Stages []TemplateStage
+}
+
+// HandlerStage returns the stage into which the function's handler is copied.
+// This is where the language's toolchain is available. When no stage copies
+// the handler, the last stage is returned.
+func (t TemplateDockerfile) HandlerStage() (TemplateStage, error) {
+	if len(t.Stages) == 0 {
+		return TemplateStage{}, fmt.Errorf("no FROM instruction found in Dockerfile")
+	}
+
+	for _, stage := range t.Stages {
+		if len(stage.HandlerPath) > 0 {
+			return stage, nil
+		}
+	}
+
+	return t.Stages[len(t.Stages)-1], nil
+}
+
+// LoadTemplateDockerfile parses ./template/LANG/Dockerfile for the given
+// language, resolving the handler folder from its template.yml.
+func LoadTemplateDockerfile(language, handlerFolder string) (*TemplateDockerfile, error) {
+	dockerfile := filepath.Join("./template", language, "Dockerfile")
+	data, err := ioutil.ReadFile(dockerfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("template %s has no Dockerfile, run \"faas-cli template pull\"", language)
+		}
+		return nil, err
+	}
+
+	if len(handlerFolder) == 0 {
+		handlerFolder = defaultHandlerFolder
+	}
+
+	return ParseTemplateDockerfile(data, handlerFolder)
+}
+
+var (
+	argPattern        = regexp.MustCompile(`^(?i)ARG\s+([A-Za-z0-9_]+)(=(\S*))?`)
+	fromPattern       = regexp.MustCompile(`^(?i)FROM\s+(--platform=\S+\s+)?(\S+)(\s+AS\s+(\S+))?`)
+	workdirPattern    = regexp.MustCompile(`^(?i)WORKDIR\s+(\S+)`)
+	copyPattern       = regexp.MustCompile(`^(?i)(COPY|ADD)\s+(.*)$`)
+	variablePattern   = regexp.MustCompile(`\$\{?([A-Za-z0-9_]+)(:-([^}]*))?\}?`)
+	copyOptionPattern = regexp.MustCompile(`^--\S+`)
+)
+
+// ParseTemplateDockerfile reads the stages of a Dockerfile, and for each one
+// records its base image, working directory and where handlerFolder is copied.
+// Only ARG values declared before the first FROM are substituted into images.
+func ParseTemplateDockerfile(data []byte, handlerFolder string) (*TemplateDockerfile, error) {
+	result := &TemplateDockerfile{}
+	globalArgs := map[string]string{}
+
+	handlerFolder = strings.Trim(path.Clean(handlerFolder), "/")
+
+	scanner := bufio.NewScanner(bytes.NewReader(joinContinuations(data)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if match := argPattern.FindStringSubmatch(line); match != nil {
+			if len(result.Stages) == 0 {
+				globalArgs[match[1]] = match[3]
+			}
+			continue
+		}
+
+		if match := fromPattern.FindStringSubmatch(line); match != nil {
+			result.Stages = append(result.Stages, TemplateStage{
+				Image:   expandArgs(match[2], globalArgs),
+				Name:    match[4],
+				WorkDir: "/",
+			})
+			continue
+		}
+
+		if len(result.Stages) == 0 {
+			continue
+		}
+		stage := &result.Stages[len(result.Stages)-1]
+
+		if match := workdirPattern.FindStringSubmatch(line); match != nil {
+			stage.WorkDir = resolveContainerPath(stage.WorkDir, match[1])
+			continue
+		}
+
+		if match := copyPattern.FindStringSubmatch(line); match != nil {
+			fields := strings.Fields(match[2])
+			var paths []string
+			fromStage := false
+			for _, field := range fields {
+				if copyOptionPattern.MatchString(field) {
+					if strings.HasPrefix(field, "--from") {
+						fromStage = true
+					}
+					continue
+				}
+				paths = append(paths, field)
+			}
+
+			if fromStage || len(paths) < 2 {
+				continue
+			}
+
+			dest := paths[len(paths)-1]
+			for _, src := range paths[:len(paths)-1] {
+				// The contents of a folder are copied into dest, so both
+				// "COPY function ." and "COPY function function" resolve to dest.
+				if strings.Trim(path.Clean(src), "/") == handlerFolder {
+					stage.HandlerPath = resolveContainerPath(stage.WorkDir, dest)
+				}
+			}
+		}
+	}
+
+	return result, scanner.Err()
+}
+
+// joinContinuations merges lines ending with a backslash
+func joinContinuations(data []byte) []byte {
+	return regexp.MustCompile(`\\[ \t]*\r?\n`).ReplaceAll(data, []byte(" "))
+}
+
+func expandArgs(value string, args map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(value, func(ref string) string {
+		match := variablePattern.FindStringSubmatch(ref)
+		if v, ok := args[match[1]]; ok && len(v) > 0 {
+			return v
+		}
+		return match[3]
+	})
+}
+
+func resolveContainerPath(workdir, target string) string {
+	if path.IsAbs(target) {
+		return path.Clean(target)
+	}
+	return path.Join(workdir, target)
+}
@@ -0,0 +1,98 @@
+package builder
+
+import "testing"
+
+func Test_ParseTemplateDockerfile_MultiStage(t *testing.T) {
+	dockerfile := []byte(`ARG PYTHON_VERSION=3.10
+FROM --platform=${TARGETPLATFORM:-linux/amd64} ghcr.io/openfaas/of-watchdog:0.9.10 as watchdog
+FROM --platform=${TARGETPLATFORM:-linux/amd64} python:${PYTHON_VERSION}-alpine AS build
+
+COPY --from=watchdog /fwatchdog /usr/bin/fwatchdog
+WORKDIR /home/app/
+
+COPY --chown=app:app index.py           .
+RUN mkdir -p function && \
+    touch ./function/__init__.py
+COPY --chown=app:app function/   ./function/
+
+FROM build as ship
+WORKDIR /home/app/
+`)
+
+	parsed, err := ParseTemplateDockerfile(dockerfile, "function")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(parsed.Stages) != 3 {
+		t.Fatalf("want 3 stages, got %d", len(parsed.Stages))
+	}
+
+	stage, err := parsed.HandlerStage()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if stage.Name != "build" {
+		t.Errorf("want build stage, got %q", stage.Name)
+	}
+	if stage.Image != "python:3.10-alpine" {
+		t.Errorf("want image python:3.10-alpine, got %s", stage.Image)
+	}
+	if stage.HandlerPath != "/home/app/function" {
+		t.Errorf("want handler path /home/app/function, got %s", stage.HandlerPath)
+	}
+}
+
+func Test_ParseTemplateDockerfile_SingleStage(t *testing.T) {
+	dockerfile := []byte(`FROM ruby:2.4-alpine3.6
+WORKDIR /root/
+COPY Gemfile		.
+COPY function           function
+WORKDIR /root/function/
+`)
+
+	parsed, err := ParseTemplateDockerfile(dockerfile, "function")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stage, _ := parsed.HandlerStage()
+	if stage.Image != "ruby:2.4-alpine3.6" {
+		t.Errorf("want image ruby:2.4-alpine3.6, got %s", stage.Image)
+	}
+	if stage.HandlerPath != "/root/function" {
+		t.Errorf("want handler path /root/function, got %s", stage.HandlerPath)
+	}
+	if stage.WorkDir != "/root/function" {
+		t.Errorf("want workdir /root/function, got %s", stage.WorkDir)
+	}
+}
+
+func Test_ParseTemplateDockerfile_CustomHandlerFolder(t *testing.T) {
+	dockerfile := []byte(`FROM golang:1.19 as build
+WORKDIR /go/src/handler
+COPY src/ .
+`)
+
+	parsed, err := ParseTemplateDockerfile(dockerfile, "src")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	stage, _ := parsed.HandlerStage()
+	if stage.HandlerPath != "/go/src/handler" {
+		t.Errorf("want handler path /go/src/handler, got %s", stage.HandlerPath)
+	}
+}
+
+func Test_ParseTemplateDockerfile_NoFrom(t *testing.T) {
+	parsed, err := ParseTemplateDockerfile([]byte("# empty\n"), "function")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := parsed.HandlerStage(); err == nil {
+		t.Fatalf("want error when there are no stages")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	applyChangesetCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://, defaults to the gateway recorded in the changeset")
+	applyChangesetCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function, defaults to the namespace recorded in the changeset")
+	applyChangesetCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	applyChangesetCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
+
+	markMutating(applyChangesetCmd)
+	faasCmd.AddCommand(applyChangesetCmd)
+}
+
+var applyChangesetCmd = &cobra.Command{
+	Use:   `apply-changeset FILE|ID [--gateway GATEWAY_URL] [--namespace NAMESPACE]`,
+	Short: "Deploy a function exactly as recorded in a changeset",
+	Long: `Deploys the function described by a changeset written by "faas-cli deploy
+--history", performing a rolling update. The argument can be a path to a changeset file,
+or the ID of a changeset in ` + historyDir + `.
+
+Applying a changeset records a new changeset which refers to the one applied.`,
+	Example: `  faas-cli apply-changeset 20230504T101500.000000000Z-figlet
+  faas-cli apply-changeset ./prod/changesets/figlet.json --gateway https://gw.example.com`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("give the path or ID of a single changeset")
+		}
+		return nil
+	},
+	RunE: runApplyChangeset,
+}
+
+func runApplyChangeset(cmd *cobra.Command, args []string) error {
+	file := args[0]
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		file = filepath.Join(historyDir, args[0]+".json")
+	}
+
+	c, err := readChangeset(file)
+	if err != nil {
+		return err
+	}
+
+	if len(functionNamespace) > 0 {
+		c.Namespace = functionNamespace
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, c.Gateway, os.Getenv(openFaaSURLEnvironment))
+
+	cliAuth, err := proxy.NewCLIAuth(token, gatewayAddress)
+	if err != nil {
+		return err
+	}
+
+	transport := GetDefaultCLITransport(tlsInsecure, &commandTimeout)
+	client, err := proxy.NewClient(cliAuth, gatewayAddress, transport, &commandTimeout)
+	if err != nil {
+		return err
+	}
+
+	spec := c.deploySpec()
+	spec.TLSInsecure = tlsInsecure
+	spec.Token = token
+
+	fmt.Printf("Applying changeset %s to %s.%s\n", c.ID, c.Function, c.Namespace)
+
+	statusCode := client.DeployFunction(context.Background(), spec)
+	if badStatusCode(statusCode) {
+		return deployFailed(map[string]int{c.Function: statusCode})
+	}
+
+	recorded, err := recordChangeset(historyDir, gatewayAddress, spec, "apply-changeset "+c.ID)
+	if err != nil {
+		return fmt.Errorf("deployed, but unable to record changeset: %w", err)
+	}
+
+	fmt.Printf("Recorded changeset: %s\n", recorded.ID)
+	return nil
+}
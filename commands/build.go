@@ -11,8 +11,8 @@ import (
 	"sync"
 	"time"
 
-	"github.com/morikuni/aec"
 	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/output"
 	"github.com/openfaas/faas-cli/schema"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas-cli/util"
@@ -37,6 +37,8 @@ var (
 	envsubst         bool
 	quietBuild       bool
 	disableStackPull bool
+	progressMode     string
+	remoteContext    string
 )
 
 func init() {
@@ -59,6 +61,10 @@ func init() {
 	buildCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
 	buildCmd.Flags().BoolVar(&quietBuild, "quiet", false, "Perform a quiet build, without showing output from Docker")
 	buildCmd.Flags().BoolVar(&disableStackPull, "disable-stack-pull", false, "Disables the template configuration in the stack.yml")
+	buildCmd.Flags().StringVar(&remoteContext, "remote-context", "", "Upload build contexts to an object store such as s3://bucket/prefix and build from its URL")
+	buildCmd.Flags().BoolVar(&buildReport, "report", true, "Print the size, layers and estimated cold start of each image after a stack build, compared with the previous build")
+	buildCmd.Flags().BoolVar(&hermeticBuild, "hermetic", false, "Run the steps of each build without a network, and fail with a report of the functions which tried to reach it")
+	buildCmd.Flags().StringVar(&progressMode, "progress", progressAuto, "Set the type of progress output for stack builds: auto, plain or tty")
 
 	// Set bash-completion.
 	_ = buildCmd.Flags().SetAnnotation("handler", cobra.BashCompSubdirsInDir, []string{})
@@ -77,14 +83,54 @@ var buildCmd = &cobra.Command{
                  [--regex "REGEX"]
                  [--filter "WILDCARD"]
                  [--parallel PARALLEL_DEPTH]
+                 [--progress <auto|plain|tty>]
                  [--build-arg KEY=VALUE]
                  [--build-option VALUE]
                  [--copy-extra PATH]
+                 [--hermetic]
                  [--tag <sha|branch|describe>]`,
 	Short: "Builds OpenFaaS function containers",
 	Long: `Builds OpenFaaS function containers either via the supplied YAML config using
 the "--yaml" flag (which may contain multiple function definitions), or directly
-via flags.`,
+via flags.
+
+When building a stack file, --progress tty shows a live status board with one
+line per function, and only prints the output of builds which fail. The plain
+mode prefixes each line of output with the function's name, and is used by
+default in CI or when stdout is not a terminal.
+
+With --remote-context, or remote_context in the stack file, each build context
+is uploaded as a tarball to S3, GCS or an HTTP endpoint, and Docker is given its
+URL. Credentials for S3 and GCS (HMAC keys) are read from AWS_ACCESS_KEY_ID and
+AWS_SECRET_ACCESS_KEY.
+
+Paths listed in a .faasignore file in a function's handler folder, in the
+syntax of a .gitignore, are left out of its build context, as well as those of
+any .dockerignore. Use it for fixtures, tests and other large folders which the
+image doesn't need, so that they don't slow down each build and push:
+
+  # functions/render/.faasignore
+  testdata/
+  *.test.js
+  !keep.test.js
+
+With --hermetic, the steps of each build run without a network, as with
+docker build --network=none under BuildKit, for builds which must only use the
+dependencies vendored into the handler folder. The base images are still
+pulled, so pin them by digest. A build which fails after a step tried to reach
+the network, such as to install a package, is reported with the line of its
+output which shows the attempt.
+
+After a stack build, the size and layers of each image are printed with the
+change since the previous build, which is recorded in ` + buildReportFile + `,
+and an estimate of the time a cold start takes to pull it. A warning is printed
+for each image over the image_budget in the stack file's configuration:
+
+  configuration:
+    image_budget:
+      max_size: 200MB
+      max_growth: 10%
+      max_layers: 20`,
 	Example: `  faas-cli build -f https://domain/path/myfunctions.yml
   faas-cli build -f ./stack.yml --no-cache --build-arg NPM_VERSION=0.2.2
   faas-cli build -f ./stack.yml --build-option dev
@@ -93,6 +139,9 @@ via flags.`,
   faas-cli build -f ./stack.yml --tag describe
   faas-cli build -f ./stack.yml --filter "*gif*"
   faas-cli build -f ./stack.yml --regex "fn[0-9]_.*"
+  faas-cli build -f ./stack.yml --parallel 4 --progress tty
+  faas-cli build -f ./stack.yml --remote-context s3://my-bucket/contexts
+  faas-cli build -f ./stack.yml --hermetic
   faas-cli build --image=my_image --lang=python --handler=/path/to/fn/
                  --name=my_fn --squash
   faas-cli build -f ./stack.yml --build-label org.label-schema.label-name="value"`,
@@ -112,6 +161,10 @@ func preRunBuild(cmd *cobra.Command, args []string) error {
 
 	buildLabelMap, err = util.ParseMap(buildLabels, "build-label")
 
+	if progressMode != progressAuto && progressMode != progressPlain && progressMode != progressTTY {
+		return fmt.Errorf("the --progress flag must be one of: %s, %s, %s", progressAuto, progressPlain, progressTTY)
+	}
+
 	if parallel < 1 {
 		return fmt.Errorf("the --parallel flag must be great than 0")
 	}
@@ -164,20 +217,8 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if len(services.StackConfiguration.TemplateConfigs) > 0 && !disableStackPull {
-		newTemplateInfos, err := filterExistingTemplates(services.StackConfiguration.TemplateConfigs, "./template")
-		if err != nil {
-			return fmt.Errorf("already pulled templates directory has issue: %s", err.Error())
-		}
-
-		if err = pullStackTemplates(newTemplateInfos, cmd); err != nil {
-			return fmt.Errorf("could not pull templates from function yaml file: %s", err.Error())
-		}
-	} else {
-		templateAddress := getTemplateURL("", os.Getenv(templateURLEnvironment), DefaultTemplateRepository)
-		if pullErr := pullTemplates(templateAddress); pullErr != nil {
-			return fmt.Errorf("could not pull templates for OpenFaaS: %v", pullErr)
-		}
+	if err := pullBuildTemplates(&services, cmd); err != nil {
+		return err
 	}
 	if len(services.Functions) == 0 {
 		if len(image) == 0 {
@@ -189,7 +230,8 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		if len(functionName) == 0 {
 			return fmt.Errorf("please provide the deployed --name of your function")
 		}
-		err := builder.BuildImage(image,
+		err := builder.BuildImageWithOutput(nil,
+			image,
 			handler,
 			functionName,
 			language,
@@ -202,6 +244,8 @@ func runBuild(cmd *cobra.Command, args []string) error {
 			buildLabelMap,
 			quietBuild,
 			copyExtra,
+			remoteContext,
+			hermeticBuild,
 		)
 		if err != nil {
 			return err
@@ -209,13 +253,46 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	limits, err := parseImageBudget(services.StackConfiguration.ImageBudget)
+	if err != nil {
+		return err
+	}
+
 	errors := build(&services, parallel, shrinkwrap, quietBuild)
 	if len(errors) > 0 {
 		errorSummary := "Errors received during build:\n"
 		for _, err := range errors {
 			errorSummary = errorSummary + "- " + err.Error() + "\n"
 		}
-		return fmt.Errorf("%s", aec.Apply(errorSummary, aec.RedF))
+		if hermeticBuild {
+			errorSummary += hermeticReport(errors)
+		}
+		return fmt.Errorf("%s", output.Red.Apply(errorSummary))
+	}
+
+	if buildReport && !shrinkwrap {
+		return reportImages(cmd.OutOrStdout(), &services, limits, buildReportFile)
+	}
+	return nil
+}
+
+// pullBuildTemplates pulls the templates given in the stack file which are
+// missing, or the default templates when there are none
+func pullBuildTemplates(services *stack.Services, cmd *cobra.Command) error {
+	if len(services.StackConfiguration.TemplateConfigs) > 0 && !disableStackPull {
+		newTemplateInfos, err := filterExistingTemplates(services.StackConfiguration.TemplateConfigs, "./template")
+		if err != nil {
+			return fmt.Errorf("already pulled templates directory has issue: %s", err.Error())
+		}
+
+		if err = pullStackTemplates(newTemplateInfos, cmd); err != nil {
+			return fmt.Errorf("could not pull templates from function yaml file: %s", err.Error())
+		}
+	} else {
+		templateAddress := getTemplateURL("", os.Getenv(templateURLEnvironment), DefaultTemplateRepository)
+		if pullErr := pullTemplates(templateAddress); pullErr != nil {
+			return fmt.Errorf("could not pull templates for OpenFaaS: %v", pullErr)
+		}
 	}
 	return nil
 }
@@ -224,6 +301,9 @@ func build(services *stack.Services, queueDepth int, shrinkwrap, quietBuild bool
 	startOuter := time.Now()
 
 	errors := []error{}
+	errorsMu := sync.Mutex{}
+
+	progress := newBuildProgress(resolveProgressMode(progressMode, os.Stdout), os.Stdout)
 
 	wg := sync.WaitGroup{}
 
@@ -233,16 +313,18 @@ func build(services *stack.Services, queueDepth int, shrinkwrap, quietBuild bool
 	for i := 0; i < queueDepth; i++ {
 		go func(index int) {
 			for function := range workChannel {
-				start := time.Now()
+				out := progress.Start(function.Name)
 
-				fmt.Printf(aec.YellowF.Apply("[%d] > Building %s.\n"), index, function.Name)
+				var err error
 				if len(function.Language) == 0 {
-					fmt.Println("Please provide a valid language for your function.")
+					err = fmt.Errorf("please provide a valid language for your function: %s", function.Name)
+					fmt.Fprintln(out, err.Error())
 				} else {
 					combinedBuildOptions := combineBuildOpts(function.BuildOptions, buildOptions)
 					combinedBuildArgMap := util.MergeMap(function.BuildArgs, buildArgMap)
 					combinedExtraPaths := util.MergeSlice(services.StackConfiguration.CopyExtraPaths, copyExtra)
-					err := builder.BuildImage(function.Image,
+					err = builder.BuildImageWithOutput(out,
+						function.Image,
 						function.Handler,
 						function.Name,
 						function.Language,
@@ -255,38 +337,47 @@ func build(services *stack.Services, queueDepth int, shrinkwrap, quietBuild bool
 						buildLabelMap,
 						quietBuild,
 						combinedExtraPaths,
+						getRemoteContext(remoteContext, function.RemoteContext),
+						hermeticBuild,
 					)
 
 					if err != nil {
+						errorsMu.Lock()
 						errors = append(errors, err)
+						errorsMu.Unlock()
 					}
 				}
 
-				duration := time.Since(start)
-				fmt.Printf(aec.YellowF.Apply("[%d] < Building %s done in %1.2fs.\n"), index, function.Name, duration.Seconds())
+				progress.Done(function.Name, err)
 			}
 
-			fmt.Printf(aec.YellowF.Apply("[%d] Worker done.\n"), index)
 			wg.Done()
 		}(i)
 
 	}
 
+	functions := []stack.Function{}
 	for k, function := range services.Functions {
+		function.Name = k
 		if function.SkipBuild {
-			fmt.Printf("Skipping build of: %s.\n", function.Name)
+			progress.Skip(function.Name)
 		} else {
-			function.Name = k
-			workChannel <- function
+			progress.Queue(function.Name)
+			functions = append(functions, function)
 		}
 	}
 
+	for _, function := range functions {
+		workChannel <- function
+	}
+
 	close(workChannel)
 
 	wg.Wait()
+	progress.Close()
 
 	duration := time.Since(startOuter)
-	fmt.Printf("\n%s\n", aec.Apply(fmt.Sprintf("Total build time: %1.2fs", duration.Seconds()), aec.YellowF))
+	fmt.Printf("\n%s\n", output.Yellow.Apply(fmt.Sprintf("Total build time: %1.2fs", duration.Seconds())))
 	return errors
 }
 
@@ -307,6 +398,14 @@ func pullTemplates(templateURL string) error {
 	return err
 }
 
+// getRemoteContext gives the --remote-context flag priority over the stack file
+func getRemoteContext(flagValue, functionValue string) string {
+	if len(flagValue) > 0 {
+		return flagValue
+	}
+	return functionValue
+}
+
 func combineBuildOpts(YAMLBuildOpts []string, buildFlagBuildOpts []string) []string {
 	return util.MergeSlice(YAMLBuildOpts, buildFlagBuildOpts)
 }
@@ -0,0 +1,35 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/builder"
+)
+
+// hermeticBuild is set by build --hermetic
+var hermeticBuild bool
+
+// hermeticReport names the functions whose hermetic builds failed as they
+// tried to reach the network, or is "" when none did
+func hermeticReport(errs []error) string {
+	var names []string
+	for _, err := range errs {
+		var networkErr *builder.NetworkAccessError
+		if errors.As(err, &networkErr) {
+			names = append(names, networkErr.Function)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+
+	sort.Strings(names)
+	return fmt.Sprintf("Functions which tried to reach the network during the hermetic build: %s\nVendor their dependencies into the handler folder, or build them without --hermetic.\n",
+		strings.Join(names, ", "))
+}
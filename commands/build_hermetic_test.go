@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+)
+
+func Test_hermeticReport(t *testing.T) {
+	errs := []error{
+		&builder.NetworkAccessError{Function: "nodeinfo", Evidence: "getaddrinfo EAI_AGAIN registry.npmjs.org"},
+		errors.New("[figlet] received non-zero exit code from build"),
+		fmt.Errorf("wrapped: %w", &builder.NetworkAccessError{Function: "env", Evidence: "temporary error (try again later)"}),
+	}
+
+	report := hermeticReport(errs)
+	if !strings.Contains(report, "hermetic build: env, nodeinfo\n") {
+		t.Errorf("want the functions which tried to reach the network, got: %q", report)
+	}
+	if strings.Contains(report, "figlet") {
+		t.Errorf("want only the functions which tried to reach the network, got: %q", report)
+	}
+
+	if report := hermeticReport(errs[1:2]); report != "" {
+		t.Errorf("want no report without network access, got: %q", report)
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/term"
+	"github.com/morikuni/aec"
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/output"
+)
+
+const (
+	progressAuto  = "auto"
+	progressPlain = "plain"
+	progressTTY   = "tty"
+
+	// progressFailureLines is how much of a failed build's output is shown by the tty board
+	progressFailureLines = 20
+)
+
+const (
+	buildStatusQueued   = "queued"
+	buildStatusBuilding = "building"
+	buildStatusDone     = "done"
+	buildStatusFailed   = "failed"
+	buildStatusSkipped  = "skipped"
+)
+
+// buildProgress reports the status of builds which may be running concurrently
+type buildProgress interface {
+	// Queue registers a function before any build starts
+	Queue(name string)
+	// Skip marks a function as not being built
+	Skip(name string)
+	// Start marks the beginning of a build and returns where its output should go
+	Start(name string) io.Writer
+	// Done marks the end of a build, err is nil when it succeeded
+	Done(name string, err error)
+	// Close waits for any rendering to finish
+	Close()
+}
+
+// resolveProgressMode picks tty or plain for auto, tty is only used for an
+// interactive terminal outside of CI
+func resolveProgressMode(mode string, out *os.File) string {
+	if mode != progressAuto {
+		return mode
+	}
+
+	if output.Plain() {
+		return progressPlain
+	}
+
+	if !builder.IsRunningInCI() && term.IsTerminal(out.Fd()) {
+		return progressTTY
+	}
+	return progressPlain
+}
+
+func newBuildProgress(mode string, out io.Writer) buildProgress {
+	if mode == progressTTY {
+		return newTTYProgress(out, 250*time.Millisecond)
+	}
+	return newPlainProgress(out)
+}
+
+// plainProgress prefixes every line of a build's output with the function's
+// name, so that concurrent builds are readable in CI logs
+type plainProgress struct {
+	mu      sync.Mutex
+	out     io.Writer
+	starts  map[string]time.Time
+	writers map[string]*prefixWriter
+}
+
+func newPlainProgress(out io.Writer) *plainProgress {
+	return &plainProgress{out: out, starts: map[string]time.Time{}, writers: map[string]*prefixWriter{}}
+}
+
+func (p *plainProgress) Queue(name string) {}
+
+func (p *plainProgress) Skip(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, "Skipping build of: %s.\n", name)
+}
+
+func (p *plainProgress) Start(name string) io.Writer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.starts[name] = time.Now()
+	fmt.Fprintf(p.out, "[%s] > Building %s.\n", name, name)
+
+	w := &prefixWriter{mu: &p.mu, out: p.out, prefix: "[" + name + "] "}
+	p.writers[name] = w
+	return w
+}
+
+func (p *plainProgress) Done(name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if w, ok := p.writers[name]; ok {
+		w.flush()
+	}
+
+	duration := time.Since(p.starts[name]).Seconds()
+	if err != nil {
+		fmt.Fprintf(p.out, "[%s] < Building %s failed in %1.2fs.\n", name, name, duration)
+		return
+	}
+	fmt.Fprintf(p.out, "[%s] < Building %s done in %1.2fs.\n", name, name, duration)
+}
+
+func (p *plainProgress) Close() {}
+
+// prefixWriter writes whole lines to out with a prefix, holding back any
+// partial line until it is completed. When format is set, it writes each
+// line instead of the prefix.
+type prefixWriter struct {
+	mu      *sync.Mutex
+	out     io.Writer
+	prefix  string
+	format  func(line string) string
+	pending []byte
+}
+
+func (w *prefixWriter) line(text string) string {
+	if w.format != nil {
+		return w.format(text)
+	}
+	return w.prefix + text
+}
+
+func (w *prefixWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, data...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+
+		if _, err := fmt.Fprintln(w.out, w.line(strings.TrimRight(string(w.pending[:i]), "\r"))); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[i+1:]
+	}
+
+	return len(data), nil
+}
+
+// flush writes any partial line, it must be called with the lock held
+func (w *prefixWriter) flush() {
+	if len(w.pending) > 0 {
+		fmt.Fprintln(w.out, w.line(string(w.pending)))
+		w.pending = nil
+	}
+}
+
+// ttyBuild is one row of the tty board
+type ttyBuild struct {
+	name     string
+	status   string
+	start    time.Time
+	duration time.Duration
+	output   *bytes.Buffer
+}
+
+// ttyProgress redraws a board with one line per function in place, and keeps
+// each build's output to show if it fails
+type ttyProgress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	builds   map[string]*ttyBuild
+	drawn    int
+	stop     chan struct{}
+	finished chan struct{}
+}
+
+func newTTYProgress(out io.Writer, interval time.Duration) *ttyProgress {
+	p := &ttyProgress{
+		out:      out,
+		builds:   map[string]*ttyBuild{},
+		stop:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.finished)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.mu.Lock()
+				p.render()
+				p.mu.Unlock()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *ttyProgress) Queue(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.builds[name] = &ttyBuild{name: name, status: buildStatusQueued, output: &bytes.Buffer{}}
+}
+
+func (p *ttyProgress) Skip(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.builds[name] = &ttyBuild{name: name, status: buildStatusSkipped, output: &bytes.Buffer{}}
+}
+
+func (p *ttyProgress) Start(name string) io.Writer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b, ok := p.builds[name]
+	if !ok {
+		b = &ttyBuild{name: name, output: &bytes.Buffer{}}
+		p.builds[name] = b
+	}
+	b.status = buildStatusBuilding
+	b.start = time.Now()
+
+	return &lockedWriter{mu: &p.mu, out: b.output}
+}
+
+func (p *ttyProgress) Done(name string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b := p.builds[name]
+	b.duration = time.Since(b.start)
+	b.status = buildStatusDone
+	if err != nil {
+		b.status = buildStatusFailed
+	}
+}
+
+// Close draws the final board, followed by the tail of the output of any
+// failed builds
+func (p *ttyProgress) Close() {
+	close(p.stop)
+	<-p.finished
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.render()
+
+	for _, b := range p.sortedBuilds() {
+		if b.status != buildStatusFailed {
+			continue
+		}
+
+		fmt.Fprintf(p.out, "\n%s\n", output.Red.Apply(fmt.Sprintf("Output from %s:", b.name)))
+		for _, line := range lastLines(b.output.String(), progressFailureLines) {
+			fmt.Fprintf(p.out, "  %s\n", line)
+		}
+	}
+}
+
+// render must be called with the lock held
+func (p *ttyProgress) render() {
+	if p.drawn > 0 {
+		fmt.Fprint(p.out, aec.Up(uint(p.drawn)))
+	}
+
+	builds := p.sortedBuilds()
+	for _, b := range builds {
+		fmt.Fprint(p.out, aec.EraseLine(aec.EraseModes.All), "\r")
+		fmt.Fprintln(p.out, formatBuildRow(b, time.Now()))
+	}
+	p.drawn = len(builds)
+}
+
+func (p *ttyProgress) sortedBuilds() []*ttyBuild {
+	builds := make([]*ttyBuild, 0, len(p.builds))
+	for _, b := range p.builds {
+		builds = append(builds, b)
+	}
+	sort.Slice(builds, func(i, j int) bool {
+		return builds[i].name < builds[j].name
+	})
+	return builds
+}
+
+func formatBuildRow(b *ttyBuild, now time.Time) string {
+	var duration string
+	switch b.status {
+	case buildStatusBuilding:
+		duration = fmt.Sprintf("%1.1fs", now.Sub(b.start).Seconds())
+	case buildStatusDone, buildStatusFailed:
+		duration = fmt.Sprintf("%1.1fs", b.duration.Seconds())
+	}
+
+	// Pad before colouring, as the escape codes would count towards the width
+	status := fmt.Sprintf("%-8s", b.status)
+	switch b.status {
+	case buildStatusDone:
+		status = output.Green.Apply(status)
+	case buildStatusFailed:
+		status = output.Red.Apply(status)
+	case buildStatusBuilding:
+		status = output.Yellow.Apply(status)
+	}
+
+	return strings.TrimRight(fmt.Sprintf("%-30s %s %s", b.name, status, duration), " ")
+}
+
+func lastLines(text string, n int) []string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// lockedWriter serialises writes with the board's renderer
+type lockedWriter struct {
+	mu  *sync.Mutex
+	out io.Writer
+}
+
+func (w *lockedWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.out.Write(data)
+}
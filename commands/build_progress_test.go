@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_plainProgress_PrefixesLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := newPlainProgress(out)
+
+	a := p.Start("fn-a")
+	b := p.Start("fn-b")
+
+	fmt.Fprint(a, "Step 1/2\nStep ")
+	fmt.Fprint(b, "Step 1/3\n")
+	fmt.Fprint(a, "2/2\n")
+	fmt.Fprint(b, "no newline")
+
+	p.Done("fn-a", nil)
+	p.Done("fn-b", fmt.Errorf("exit 1"))
+
+	for _, want := range []string{
+		"[fn-a] > Building fn-a.\n",
+		"[fn-a] Step 1/2\n",
+		"[fn-b] Step 1/3\n",
+		"[fn-a] Step 2/2\n",
+		"[fn-b] no newline\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in output:\n%s", want, out.String())
+		}
+	}
+
+	if !strings.Contains(out.String(), "[fn-b] < Building fn-b failed in") {
+		t.Errorf("want fn-b to be reported as failed:\n%s", out.String())
+	}
+}
+
+func Test_ttyProgress_ShowsFailedOutput(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := newTTYProgress(out, time.Hour)
+
+	p.Queue("fn-a")
+	p.Queue("fn-b")
+	p.Skip("fn-c")
+
+	fmt.Fprintln(p.Start("fn-a"), "built fine")
+	p.Done("fn-a", nil)
+
+	fmt.Fprintln(p.Start("fn-b"), "compile error")
+	p.Done("fn-b", fmt.Errorf("exit 1"))
+
+	p.Close()
+
+	got := out.String()
+	if strings.Contains(got, "built fine") {
+		t.Errorf("want output of successful builds to be hidden:\n%s", got)
+	}
+
+	if !strings.Contains(got, "Output from fn-b:") || !strings.Contains(got, "  compile error") {
+		t.Errorf("want the output of fn-b to be shown:\n%s", got)
+	}
+
+	for _, want := range []string{"fn-a", "fn-c", buildStatusSkipped, buildStatusFailed} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q on the board:\n%s", want, got)
+		}
+	}
+}
+
+func Test_resolveProgressMode_Explicit(t *testing.T) {
+	for _, mode := range []string{progressPlain, progressTTY} {
+		if got := resolveProgressMode(mode, nil); got != mode {
+			t.Errorf("want %s, got %s", mode, got)
+		}
+	}
+}
+
+func Test_lastLines(t *testing.T) {
+	got := lastLines("a\nb\nc\n", 2)
+	if strings.Join(got, ",") != "b,c" {
+		t.Errorf("want b,c, got %v", got)
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/schema"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// buildReportFile records the images of the previous build, to compare with
+const buildReportFile = ".openfaas/build-report.json"
+
+// coldStartPullRate is the bytes per second assumed to pull and extract an
+// image onto a node which does not have it, which dominates a cold start
+const coldStartPullRate = 50e6
+
+// buildReport is set by build --report
+var buildReport bool
+
+// imageReport is the size of a function's image after a build
+type imageReport struct {
+	Image  string    `json:"image"`
+	Size   int64     `json:"size"`
+	Layers int       `json:"layers"`
+	Built  time.Time `json:"built"`
+}
+
+// imageLimits are the values of the stack file's image_budget
+type imageLimits struct {
+	maxSize   float64
+	maxGrowth float64
+	maxLayers int
+}
+
+// inspectImage reads the size and number of layers of a local image, and is
+// replaced by tests
+var inspectImage = func(image string) (int64, int, error) {
+	out, err := exec.Command("docker", "image", "inspect", "--format", "{{.Size}} {{len .RootFS.Layers}}", image).CombinedOutput()
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to inspect %s: %s", image, strings.TrimSpace(string(out)))
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected output from docker image inspect: %q", string(out))
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected size from docker image inspect: %q", fields[0])
+	}
+	layers, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected layers from docker image inspect: %q", fields[1])
+	}
+	return size, layers, nil
+}
+
+func parseImageBudget(budget stack.ImageBudget) (imageLimits, error) {
+	limits := imageLimits{maxLayers: budget.MaxLayers}
+
+	if len(budget.MaxSize) > 0 {
+		size, err := parseDockerSize(strings.TrimSpace(budget.MaxSize))
+		if err != nil {
+			return limits, fmt.Errorf("image_budget.max_size must be a size such as 200MB, got: %q", budget.MaxSize)
+		}
+		limits.maxSize = size
+	}
+
+	if len(budget.MaxGrowth) > 0 {
+		growth, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(budget.MaxGrowth), "%"), 64)
+		if err != nil || growth < 0 {
+			return limits, fmt.Errorf("image_budget.max_growth must be a percentage such as 10%%, got: %q", budget.MaxGrowth)
+		}
+		limits.maxGrowth = growth
+	}
+
+	if budget.MaxLayers < 0 {
+		return limits, fmt.Errorf("image_budget.max_layers must not be negative, got: %d", budget.MaxLayers)
+	}
+	return limits, nil
+}
+
+// reportImages prints the size of each function's image compared with the
+// previous build, and warns about those over the stack file's image_budget.
+// The sizes are then saved for the next build to compare with.
+func reportImages(w io.Writer, services *stack.Services, limits imageLimits, path string) error {
+	branch, version, err := builder.GetImageTagValues(tagFormat)
+	if err != nil {
+		return err
+	}
+
+	previous, err := readBuildReport(path)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(services.Functions))
+	for name, function := range services.Functions {
+		if !function.SkipBuild {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	current := map[string]imageReport{}
+	for name, report := range previous {
+		current[name] = report
+	}
+
+	table := output.NewTable("FUNCTION", "SIZE", "CHANGE", "LAYERS", "COLD START")
+	var warnings []string
+	for _, name := range names {
+		image := schema.BuildImageName(tagFormat, services.Functions[name].Image, version, branch)
+		size, layers, err := inspectImage(image)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+
+		report := imageReport{Image: image, Size: size, Layers: layers, Built: time.Now().UTC()}
+		last, found := previous[name]
+
+		change := "new"
+		coldStart := output.Duration(pullEstimate(size))
+		if found {
+			change = sizeChange(last.Size, size)
+			coldStart = fmt.Sprintf("%s (%s)", coldStart, durationChange(pullEstimate(size)-pullEstimate(last.Size)))
+		}
+		table.Row(name, output.Size(float64(size)), change, strconv.Itoa(layers), coldStart)
+
+		warnings = append(warnings, budgetWarnings(name, report, last, found, limits)...)
+		current[name] = report
+	}
+
+	fmt.Fprintln(w)
+	table.Write(w)
+	fmt.Fprintf(w, "\nCold starts are estimated from the time to pull each image at %s/s\n", output.Size(coldStartPullRate))
+	for _, warning := range warnings {
+		fmt.Fprintln(w, output.Yellow.Apply("Warning: "+warning))
+	}
+
+	return writeBuildReport(path, current)
+}
+
+// budgetWarnings describes how the image is over the limits
+func budgetWarnings(name string, report, last imageReport, found bool, limits imageLimits) []string {
+	var warnings []string
+	if limits.maxSize > 0 && float64(report.Size) > limits.maxSize {
+		warnings = append(warnings, fmt.Sprintf("%s is %s, over the image_budget.max_size of %s",
+			name, output.Size(float64(report.Size)), output.Size(limits.maxSize)))
+	}
+
+	if limits.maxGrowth > 0 && found && last.Size > 0 {
+		growth := float64(report.Size-last.Size) / float64(last.Size) * 100
+		if growth > limits.maxGrowth {
+			warnings = append(warnings, fmt.Sprintf("%s grew by %.1f%% since the previous build, over the image_budget.max_growth of %g%%",
+				name, growth, limits.maxGrowth))
+		}
+	}
+
+	if limits.maxLayers > 0 && report.Layers > limits.maxLayers {
+		warnings = append(warnings, fmt.Sprintf("%s has %d layers, over the image_budget.max_layers of %d",
+			name, report.Layers, limits.maxLayers))
+	}
+	return warnings
+}
+
+func pullEstimate(size int64) time.Duration {
+	return time.Duration(float64(size) / coldStartPullRate * float64(time.Second))
+}
+
+func sizeChange(before, after int64) string {
+	if before == after {
+		return "0"
+	}
+
+	sign := "+"
+	diff := after - before
+	if diff < 0 {
+		sign, diff = "-", -diff
+	}
+	if before == 0 {
+		return sign + output.Size(float64(diff))
+	}
+	return fmt.Sprintf("%s%s (%s%.1f%%)", sign, output.Size(float64(diff)), sign, float64(diff)/float64(before)*100)
+}
+
+func durationChange(d time.Duration) string {
+	if d < 0 {
+		return "-" + output.Duration(-d)
+	}
+	return "+" + output.Duration(d)
+}
+
+// readBuildReport reads the images of the previous build, a missing file is
+// not an error
+func readBuildReport(path string) (map[string]imageReport, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]imageReport{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	reports := map[string]imageReport{}
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", path, err)
+	}
+	return reports, nil
+}
+
+func writeBuildReport(path string, reports map[string]imageReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(out, '\n'), 0600)
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_parseImageBudget(t *testing.T) {
+	limits, err := parseImageBudget(stack.ImageBudget{MaxSize: "200MB", MaxGrowth: "10%", MaxLayers: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.maxSize != 200e6 || limits.maxGrowth != 10 || limits.maxLayers != 20 {
+		t.Fatalf("unexpected limits: %+v", limits)
+	}
+
+	for _, budget := range []stack.ImageBudget{{MaxSize: "big"}, {MaxGrowth: "ten"}, {MaxLayers: -1}} {
+		if _, err := parseImageBudget(budget); err == nil {
+			t.Errorf("want an error for %+v", budget)
+		}
+	}
+}
+
+func Test_reportImages_ComparesWithPreviousBuild(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	sizes := map[string]int64{
+		"acme/orders:latest":   100e6,
+		"acme/payments:latest": 50e6,
+	}
+	original := inspectImage
+	inspectImage = func(image string) (int64, int, error) {
+		size, ok := sizes[image]
+		if !ok {
+			return 0, 0, fmt.Errorf("unable to inspect %s: no such image", image)
+		}
+		return size, 12, nil
+	}
+	defer func() { inspectImage = original }()
+
+	services := &stack.Services{Functions: map[string]stack.Function{
+		"orders":   {Image: "acme/orders:latest"},
+		"payments": {Image: "acme/payments:latest"},
+		"legacy":   {Image: "acme/legacy:latest", SkipBuild: true},
+	}}
+	limits := imageLimits{maxSize: 110e6, maxGrowth: 10, maxLayers: 10}
+	path := filepath.Join(t.TempDir(), "build-report.json")
+
+	var first bytes.Buffer
+	if err := reportImages(&first, services, limits, path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(first.String(), "new") || strings.Contains(first.String(), "legacy") {
+		t.Fatalf("want new images without the skipped function, got:\n%s", first.String())
+	}
+
+	sizes["acme/orders:latest"] = 120e6
+
+	var second bytes.Buffer
+	if err := reportImages(&second, services, limits, path); err != nil {
+		t.Fatal(err)
+	}
+
+	out := second.String()
+	for _, want := range []string{
+		"(+20.0%)",
+		"(+400ms)",
+		"Warning: orders is 114.4 MiB, over the image_budget.max_size of 104.9 MiB",
+		"Warning: orders grew by 20.0% since the previous build, over the image_budget.max_growth of 10%",
+		"Warning: payments has 12 layers, over the image_budget.max_layers of 10",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "payments grew") {
+		t.Errorf("payments did not grow, got:\n%s", out)
+	}
+
+	reports, err := readBuildReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reports["orders"].Size != 120e6 || len(reports) != 2 {
+		t.Fatalf("want the latest sizes saved, got: %+v", reports)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	connectionProxy                string
+	connectionCABundle             string
+	connectionProtectedAnnotations []string
+	connectionProtectedLabels      []string
+)
+
+func init() {
+	connectionSetCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	connectionSetCmd.Flags().StringVar(&connectionProxy, "proxy", "", "URL of the HTTP(S) proxy for the gateway, or \""+config.DirectProxy+"\" to ignore HTTP_PROXY and HTTPS_PROXY")
+	connectionSetCmd.Flags().StringVar(&connectionCABundle, "ca-bundle", "", "PEM file of CA certificates to trust for the gateway, as well as the system's")
+	connectionSetCmd.Flags().StringArrayVar(&connectionProtectedAnnotations, "protect-annotation", []string{}, "Annotation which deploy must not change on the gateway's functions, a key ending in * matches its prefix (repeat for each)")
+	connectionSetCmd.Flags().StringArrayVar(&connectionProtectedLabels, "protect-label", []string{}, "Label which deploy must not change on the gateway's functions, a key ending in * matches its prefix (repeat for each)")
+
+	connectionRemoveCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+
+	connectionCmd.AddCommand(connectionSetCmd)
+	connectionCmd.AddCommand(connectionListCmd)
+	connectionCmd.AddCommand(connectionRemoveCmd)
+	faasCmd.AddCommand(connectionCmd)
+}
+
+var connectionCmd = &cobra.Command{
+	Use:   `connection [set|list|remove]`,
+	Short: "Manage the proxy, CA bundle and protected keys used for each gateway",
+	Long: `Saves the HTTP(S) proxy and CA bundle to use for a gateway in the CLI's config,
+so that they are used by every command which talks to it, without setting
+HTTP_PROXY, HTTPS_PROXY or NO_PROXY for each one.
+
+Gateways without saved settings use the proxy environment variables and the
+system's CAs, as before.
+
+The annotations and labels which are protected for a gateway, such as those
+managed by an operator, are not changed by "faas-cli deploy" on functions
+which are already deployed, whatever the stack file has.`,
+}
+
+var connectionSetCmd = &cobra.Command{
+	Use:   `set [--gateway GATEWAY_URL] [--proxy PROXY_URL] [--ca-bundle FILE] [--protect-annotation KEY] [--protect-label KEY]`,
+	Short: "Save the proxy, CA bundle and protected keys for a gateway",
+	Long: `Saves the proxy, CA bundle or protected keys for a gateway. A setting which is
+not given is kept from before, and the keys given for --protect-annotation or
+--protect-label replace those saved, use "faas-cli connection remove" to clear
+them.`,
+	Example: `  faas-cli connection set --gateway https://openfaas.example.com \
+    --proxy http://proxy.corp.example.com:3128
+  faas-cli connection set --gateway https://openfaas.internal \
+    --ca-bundle ./internal-ca.pem
+  faas-cli connection set --gateway http://127.0.0.1:8080 --proxy direct
+  faas-cli connection set --gateway https://openfaas.example.com \
+    --protect-annotation "com.openfaas.iam.*" --protect-label com.openfaas.scale.max`,
+	RunE: runConnectionSet,
+}
+
+var connectionListCmd = &cobra.Command{
+	Use:   `list`,
+	Short: "List the saved proxy, CA bundle and protected keys of each gateway",
+	RunE:  runConnectionList,
+}
+
+var connectionRemoveCmd = &cobra.Command{
+	Use:   `remove [--gateway GATEWAY_URL]`,
+	Short: "Remove the saved proxy, CA bundle and protected keys of a gateway",
+	RunE:  runConnectionRemove,
+}
+
+func runConnectionSet(cmd *cobra.Command, args []string) error {
+	protect := cmd.Flags().Changed("protect-annotation") || cmd.Flags().Changed("protect-label")
+	if len(connectionProxy) == 0 && len(connectionCABundle) == 0 && !protect {
+		return fmt.Errorf("give a --proxy, a --ca-bundle, a --protect-annotation or a --protect-label")
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "", os.Getenv(openFaaSURLEnvironment))
+
+	// Settings which are not given are kept from before
+	connection, _ := config.LookupConnectionConfig(gatewayAddress)
+	connection.Gateway = gatewayAddress
+
+	if len(connectionProxy) > 0 {
+		if err := validateConnectionProxy(connectionProxy); err != nil {
+			return err
+		}
+		connection.Proxy = connectionProxy
+	}
+
+	if len(connectionCABundle) > 0 {
+		path, err := validateCABundle(connectionCABundle)
+		if err != nil {
+			return err
+		}
+		connection.CABundle = path
+	}
+
+	if cmd.Flags().Changed("protect-annotation") {
+		connection.ProtectedAnnotations = connectionProtectedAnnotations
+	}
+	if cmd.Flags().Changed("protect-label") {
+		connection.ProtectedLabels = connectionProtectedLabels
+	}
+
+	if err := config.UpdateConnectionConfig(connection); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Saved the connection settings for %s\n", connection.Gateway)
+	return nil
+}
+
+func runConnectionList(cmd *cobra.Command, args []string) error {
+	connections, err := config.ListConnectionConfigs()
+	if err != nil {
+		return err
+	}
+
+	if len(connections) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No connection settings saved, the proxy environment variables and the system's CAs are used")
+		return nil
+	}
+
+	table := output.NewTable("GATEWAY", "PROXY", "CA BUNDLE", "PROTECTED")
+	for _, connection := range connections {
+		proxy := connection.Proxy
+		if len(proxy) == 0 {
+			proxy = "<environment>"
+		}
+		var protected []string
+		for _, key := range connection.ProtectedAnnotations {
+			protected = append(protected, "annotation "+key)
+		}
+		for _, key := range connection.ProtectedLabels {
+			protected = append(protected, "label "+key)
+		}
+		table.Row(connection.Gateway, proxy, valueOrDash(connection.CABundle), valueOrDash(strings.Join(protected, ", ")))
+	}
+	return table.Write(cmd.OutOrStdout())
+}
+
+func runConnectionRemove(cmd *cobra.Command, args []string) error {
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "", os.Getenv(openFaaSURLEnvironment))
+	if err := config.RemoveConnectionConfig(gatewayAddress); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Removed the connection settings for %s\n", gatewayAddress)
+	return nil
+}
+
+func validateConnectionProxy(proxy string) error {
+	if proxy == config.DirectProxy {
+		return nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || len(proxyURL.Host) == 0 {
+		return fmt.Errorf("--proxy must be a URL such as http://proxy:3128, or %s", config.DirectProxy)
+	}
+	switch strings.ToLower(proxyURL.Scheme) {
+	case "http", "https", "socks5":
+		return nil
+	}
+	return fmt.Errorf("--proxy must use http, https or socks5, but got: %s", proxyURL.Scheme)
+}
+
+// validateCABundle checks the bundle has a certificate, and returns its
+// absolute path, so that it is found from any working directory
+func validateCABundle(path string) (string, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(absolute)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the CA bundle: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return "", fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return absolute, nil
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+func runConnectionForTest(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	connectionProxy, connectionCABundle = "", ""
+	connectionProtectedAnnotations, connectionProtectedLabels = []string{}, []string{}
+	for _, name := range []string{"protect-annotation", "protect-label"} {
+		connectionSetCmd.Flags().Lookup(name).Changed = false
+	}
+
+	var out bytes.Buffer
+	faasCmd.SetOut(&out)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs(append([]string{"connection"}, args...))
+	err := faasCmd.Execute()
+	return out.String(), err
+}
+
+func Test_connectionSet_KeepsSettingsNotGiven(t *testing.T) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.Close()
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600)
+
+	gatewayURL := "https://openfaas.example.com"
+	if _, err := runConnectionForTest(t, "set", "--gateway", gatewayURL, "--ca-bundle", bundle); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runConnectionForTest(t, "set", "--gateway", gatewayURL, "--proxy", "http://proxy:3128"); err != nil {
+		t.Fatal(err)
+	}
+
+	connection, err := config.LookupConnectionConfig(gatewayURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if connection.Proxy != "http://proxy:3128" || connection.CABundle != bundle {
+		t.Errorf("want the proxy and the CA bundle, got: %v", connection)
+	}
+
+	out, err := runConnectionForTest(t, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, gatewayURL) || !strings.Contains(out, "http://proxy:3128") {
+		t.Errorf("want the connection listed, got: %s", out)
+	}
+
+	if _, err := runConnectionForTest(t, "remove", "--gateway", gatewayURL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.LookupConnectionConfig(gatewayURL); err == nil {
+		t.Errorf("want the connection to be removed")
+	}
+}
+
+func Test_validateConnectionProxy(t *testing.T) {
+	for _, proxy := range []string{"direct", "http://proxy:3128", "socks5://127.0.0.1:1080"} {
+		if err := validateConnectionProxy(proxy); err != nil {
+			t.Errorf("want %s to be valid, got: %s", proxy, err)
+		}
+	}
+	for _, proxy := range []string{"proxy:3128", "ftp://proxy:21", "none"} {
+		if err := validateConnectionProxy(proxy); err == nil {
+			t.Errorf("want an error for: %s", proxy)
+		}
+	}
+}
+
+func Test_validateCABundle_NoCertificates(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bundle, []byte("not a certificate"), 0600)
+
+	if _, err := validateCABundle(bundle); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Fatalf("want an error for a bundle without certificates, got: %v", err)
+	}
+}
+
+func Test_connectionSet_ProtectedKeys(t *testing.T) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+
+	gatewayURL := "https://openfaas.example.com"
+	if _, err := runConnectionForTest(t, "set", "--gateway", gatewayURL, "--proxy", "direct",
+		"--protect-annotation", "com.openfaas.iam.*", "--protect-annotation", "kubernetes.io/ingress.class"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runConnectionForTest(t, "set", "--gateway", gatewayURL, "--protect-label", "com.openfaas.scale.max"); err != nil {
+		t.Fatal(err)
+	}
+
+	connection, err := config.LookupConnectionConfig(gatewayURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(connection.ProtectedAnnotations, ",") != "com.openfaas.iam.*,kubernetes.io/ingress.class" ||
+		strings.Join(connection.ProtectedLabels, ",") != "com.openfaas.scale.max" || connection.Proxy != "direct" {
+		t.Errorf("want the protected keys saved with the proxy, got: %v", connection)
+	}
+
+	out, err := runConnectionForTest(t, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "annotation com.openfaas.iam.*, annotation kubernetes.io/ingress.class, label com.openfaas.scale.max") {
+		t.Errorf("want the protected keys listed, got: %s", out)
+	}
+}
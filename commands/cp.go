@@ -0,0 +1,165 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var cpFlags execTarget
+
+func init() {
+	addExecTargetFlags(cpCmd, &cpFlags)
+	cpCmd.Flags().BoolVar(&allowExec, "allow-exec", false, "Confirm that a command may be run inside a function's container")
+
+	// Copying from a function only reads the file
+	markMutatingWhen(cpCmd, func(cmd *cobra.Command, args []string) string {
+		if len(args) == 2 {
+			if _, ok := parseFunctionPath(args[1]); ok {
+				return cmd.CommandPath() + " SRC_PATH FUNCTION_NAME:DEST_PATH"
+			}
+		}
+		return ""
+	})
+	faasCmd.AddCommand(cpCmd)
+}
+
+var cpCmd = &cobra.Command{
+	Use:   `cp FUNCTION_NAME:SRC_PATH DEST_PATH | SRC_PATH FUNCTION_NAME:DEST_PATH --allow-exec`,
+	Short: "Copy a file to or from a running function replica",
+	Long: `Copies a single file between the local machine and a running replica of a
+function, using the same transport as "faas-cli exec". The container must have
+"cat", and "sh" to copy a file into it.
+
+Files written into a container are lost when it is restarted. With --read-only,
+a file can be copied from a function, but not into one.`,
+	Example: `  faas-cli cp env-dump:/tmp/heap.pprof ./heap.pprof --allow-exec
+  faas-cli cp ./config.json env-dump:/tmp/config.json --allow-exec
+  faas-cli cp env-dump:/tmp/dump.json . --allow-exec --provider faasd --ssh ubuntu@faasd`,
+	PreRunE: preRunCp,
+	RunE:    runCp,
+}
+
+// functionPathPattern matches FUNCTION_NAME:PATH, but not a Windows drive letter
+var functionPathPattern = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?):(.+)$`)
+
+// functionPath is a path within a function's container
+type functionPath struct {
+	function string
+	path     string
+}
+
+func parseFunctionPath(arg string) (*functionPath, bool) {
+	match := functionPathPattern.FindStringSubmatch(arg)
+	if match == nil || (len(match[1]) == 1 && strings.HasPrefix(match[3], `\`)) {
+		return nil, false
+	}
+	return &functionPath{function: match[1], path: match[3]}, true
+}
+
+func preRunCp(cmd *cobra.Command, args []string) error {
+	if !allowExec {
+		return fmt.Errorf("copying files to or from a function's container must be confirmed with --allow-exec")
+	}
+
+	if len(args) != 2 {
+		return fmt.Errorf("give a source and a destination, one of which must be FUNCTION_NAME:PATH")
+	}
+
+	_, srcRemote := parseFunctionPath(args[0])
+	_, destRemote := parseFunctionPath(args[1])
+	if srcRemote == destRemote {
+		return fmt.Errorf("exactly one of the source or destination must be FUNCTION_NAME:PATH")
+	}
+
+	return cpFlags.validate()
+}
+
+func runCp(cmd *cobra.Command, args []string) error {
+	if src, ok := parseFunctionPath(args[0]); ok {
+		return copyFromFunction(cpFlags, src, args[1])
+	}
+
+	dest, _ := parseFunctionPath(args[1])
+	return copyToFunction(cpFlags, args[0], dest)
+}
+
+func copyFromFunction(target execTarget, src *functionPath, dest string) error {
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		dest = strings.TrimRight(dest, `/\`) + string(os.PathSeparator) + lastPathElement(src.path)
+	}
+
+	// The file is written next to dest and renamed over it once it has all
+	// been copied, so that a failed copy leaves an existing file as it was
+	f, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if info, err := os.Stat(dest); err == nil {
+		if err := f.Chmod(info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	argv := target.command(src.function, []string{"cat", src.path}, false, false)
+	if err := runExecTransport(argv, nil, f, os.Stderr); err != nil {
+		return fmt.Errorf("unable to copy %s from %s: %w", src.path, src.function, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), dest); err != nil {
+		return err
+	}
+
+	fmt.Printf("Copied %s:%s to %s\n", src.function, src.path, dest)
+	return nil
+}
+
+func copyToFunction(target execTarget, src string, dest *functionPath) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a folder, only single files can be copied", src)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	destPath := dest.path
+	if strings.HasSuffix(destPath, "/") {
+		destPath += lastPathElement(src)
+	}
+
+	// The path is passed as a positional parameter so that it is never parsed by sh
+	argv := target.command(dest.function, []string{"sh", "-c", `cat > "$1"`, "sh", destPath}, true, false)
+	if err := runExecTransport(argv, f, os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("unable to copy %s to %s: %w", src, dest.function, err)
+	}
+
+	fmt.Printf("Copied %s to %s:%s\n", src, dest.function, destPath)
+	return nil
+}
+
+func lastPathElement(p string) string {
+	p = strings.TrimRight(p, `/\`)
+	if i := strings.LastIndexAny(p, `/\`); i > -1 {
+		return p[i+1:]
+	}
+	return p
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_parseFunctionPath(t *testing.T) {
+	cases := []struct {
+		arg      string
+		remote   bool
+		function string
+		path     string
+	}{
+		{"env-dump:/tmp/heap.pprof", true, "env-dump", "/tmp/heap.pprof"},
+		{"./heap.pprof", false, "", ""},
+		{"/tmp/a:b", false, "", ""},
+		{`c:\temp\heap.pprof`, false, "", ""},
+		{"fn:relative/file", true, "fn", "relative/file"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.arg, func(t *testing.T) {
+			got, ok := parseFunctionPath(tc.arg)
+			if ok != tc.remote {
+				t.Fatalf("want remote: %v, got: %v", tc.remote, ok)
+			}
+			if ok && (got.function != tc.function || got.path != tc.path) {
+				t.Errorf("want %s and %s, got %s and %s", tc.function, tc.path, got.function, got.path)
+			}
+		})
+	}
+}
+
+// fakeKubectl puts a kubectl on the PATH which runs the command after "--"
+// locally, in place of a function's container
+func fakeKubectl(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\nwhile [ \"$1\" != \"--\" ]; do shift; done\nshift\nexec \"$@\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func Test_copyFunctionFiles_RoundTrip(t *testing.T) {
+	fakeKubectl(t)
+
+	dir := t.TempDir()
+	target := execTarget{provider: execProviderKubernetes, namespace: execDefaultNamespace}
+
+	src := filepath.Join(dir, "dump.json")
+	ioutil.WriteFile(src, []byte(`{"heap": 1}`), 0600)
+
+	remoteDir := filepath.Join(dir, "container") + "/"
+	os.Mkdir(remoteDir, 0700)
+
+	if err := copyToFunction(target, src, &functionPath{function: "env-dump", path: remoteDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	localDir := filepath.Join(dir, "local")
+	os.Mkdir(localDir, 0700)
+
+	if err := copyFromFunction(target, &functionPath{function: "env-dump", path: remoteDir + "dump.json"}, localDir); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(filepath.Join(localDir, "dump.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != `{"heap": 1}` {
+		t.Errorf("want the file to be copied unchanged, got %q", string(got))
+	}
+}
+
+func Test_copyFromFunction_RemovesPartialFile(t *testing.T) {
+	fakeKubectl(t)
+
+	dest := filepath.Join(t.TempDir(), "missing.json")
+	target := execTarget{provider: execProviderKubernetes, namespace: execDefaultNamespace}
+
+	if err := copyFromFunction(target, &functionPath{function: "env-dump", path: "/does/not/exist"}, dest); err == nil {
+		t.Fatalf("want an error for a missing file")
+	}
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("want %s to be removed after a failed copy", dest)
+	}
+}
+
+func Test_copyFromFunction_KeepsExistingFileOnFailure(t *testing.T) {
+	fakeKubectl(t)
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "heap.pprof")
+	if err := ioutil.WriteFile(dest, []byte("the last profile"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := execTarget{provider: execProviderKubernetes, namespace: execDefaultNamespace}
+
+	if err := copyFromFunction(target, &functionPath{function: "env-dump", path: "/does/not/exist"}, dest); err == nil {
+		t.Fatalf("want an error for a missing file")
+	}
+
+	got, err := ioutil.ReadFile(dest)
+	if err != nil || string(got) != "the last profile" {
+		t.Errorf("want %s left as it was after a failed copy, got %q: %v", dest, got, err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("want no temporary file left, got %d files", len(files))
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// dashboardAudience is requested when no --dashboard URL is given
+	dashboardAudience = "openfaas-dashboard"
+
+	dashboardReadScope  = "dashboard:read"
+	dashboardWriteScope = "dashboard:write"
+)
+
+var (
+	dashboardURL   string
+	dashboardWrite bool
+	dashboardQuiet bool
+)
+
+func init() {
+	dashboardTokenCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	dashboardTokenCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	dashboardTokenCmd.Flags().StringVarP(&token, "token", "k", "", "Token to exchange, instead of the one saved by faas-cli login")
+	dashboardTokenCmd.Flags().StringVar(&dashboardURL, "dashboard", "", "URL of the dashboard, to print a link which signs in with the token")
+	dashboardTokenCmd.Flags().BoolVar(&dashboardWrite, "write", false, "Request a token which can also change functions, tokens are read-only by default")
+	dashboardTokenCmd.Flags().BoolVarP(&dashboardQuiet, "quiet", "q", false, "Only print the token, or the link when --dashboard is given")
+
+	faasCmd.AddCommand(dashboardTokenCmd)
+}
+
+var dashboardTokenCmd = &cobra.Command{
+	Use:   `dashboard-token [--gateway GATEWAY_URL] [--dashboard DASHBOARD_URL]`,
+	Short: "Generate a short-lived token for the OpenFaaS Pro dashboard",
+	Long: `Exchanges your token for a short-lived one for the OpenFaaS Pro dashboard, so
+that someone can be given temporary access, without sharing your own login.
+
+The token is read-only unless --write is given, and lasts for as long as the
+gateway's IAM allows. OpenFaaS IAM and a login with a token from your identity
+provider are required, a basic auth login can not be exchanged.
+
+With --dashboard, a link is printed which signs in to the dashboard with the
+token. The token is in the fragment of the link, so it is not sent to, or
+logged by, any server on the way.`,
+	Example: `  faas-cli dashboard-token
+  faas-cli dashboard-token --dashboard https://dashboard.example.com
+  faas-cli dashboard-token --write --quiet`,
+	RunE: runDashboardToken,
+}
+
+func runDashboardToken(cmd *cobra.Command, args []string) error {
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "", os.Getenv(openFaaSURLEnvironment))
+
+	subjectToken, err := dashboardSubjectToken(gatewayAddress, token)
+	if err != nil {
+		return err
+	}
+
+	audience := dashboardAudience
+	if len(dashboardURL) > 0 {
+		if _, err := url.ParseRequestURI(dashboardURL); err != nil {
+			return fmt.Errorf("invalid --dashboard URL: %s", dashboardURL)
+		}
+		audience = strings.TrimRight(dashboardURL, "/")
+	}
+
+	scope := []string{dashboardReadScope}
+	if dashboardWrite {
+		scope = append(scope, dashboardWriteScope)
+	}
+
+	cliAuth, err := proxy.NewCLIAuth(subjectToken, gatewayAddress)
+	if err != nil {
+		return err
+	}
+	transport := GetDefaultCLITransport(tlsInsecure, &commandTimeout)
+	client, err := proxy.NewClient(cliAuth, gatewayAddress, transport, &commandTimeout)
+	if err != nil {
+		return err
+	}
+
+	exchanged, err := client.ExchangeToken(context.Background(), proxy.TokenExchange{
+		SubjectToken: subjectToken,
+		Audience:     audience,
+		Scope:        scope,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if len(dashboardURL) > 0 {
+		link = dashboardLink(dashboardURL, exchanged.AccessToken)
+	}
+
+	w := cmd.OutOrStdout()
+	if dashboardQuiet {
+		if len(link) > 0 {
+			fmt.Fprintln(w, link)
+		} else {
+			fmt.Fprintln(w, exchanged.AccessToken)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(w, "Token: %s\n", exchanged.AccessToken)
+	if len(exchanged.Scope) > 0 {
+		fmt.Fprintf(w, "Scope: %s\n", exchanged.Scope)
+	}
+	now := time.Now()
+	if expiry := exchanged.Expiry(now); !expiry.IsZero() {
+		fmt.Fprintf(w, "Expires: %s (in %s)\n", expiry.Format(time.RFC3339), output.Duration(expiry.Sub(now)))
+	}
+	if len(link) > 0 {
+		fmt.Fprintf(w, "URL: %s\n", link)
+	}
+	return nil
+}
+
+// dashboardSubjectToken is the token given with --token, or saved by login
+func dashboardSubjectToken(gatewayAddress, flagToken string) (string, error) {
+	if len(flagToken) > 0 {
+		return flagToken, nil
+	}
+
+	authConfig, err := config.LookupAuthConfig(gatewayAddress)
+	if err == nil && authConfig.Auth == config.BasicAuthType {
+		return "", fmt.Errorf("the login for %s uses basic auth, which can not be exchanged for a dashboard token, OpenFaaS IAM is required", gatewayAddress)
+	}
+	if err != nil || len(authConfig.Token) == 0 {
+		return "", fmt.Errorf("no token found for %s, give one with --token or save one with \"faas-cli login\"", gatewayAddress)
+	}
+	return authConfig.Token, nil
+}
+
+func dashboardLink(dashboard, accessToken string) string {
+	return strings.TrimRight(dashboard, "/") + "/#token=" + url.QueryEscape(accessToken)
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+func Test_dashboardSubjectToken(t *testing.T) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+
+	gatewayURL := "http://127.0.0.1:8080"
+	if _, err := dashboardSubjectToken(gatewayURL, ""); err == nil || !strings.Contains(err.Error(), "no token found") {
+		t.Fatalf("want an error without a login, got: %v", err)
+	}
+
+	if got, _ := dashboardSubjectToken(gatewayURL, "flag-token"); got != "flag-token" {
+		t.Errorf("want the --token, got: %s", got)
+	}
+
+	config.UpdateAuthConfig(gatewayURL, config.EncodeAuth("admin", "secret"), config.BasicAuthType)
+	if _, err := dashboardSubjectToken(gatewayURL, ""); err == nil || !strings.Contains(err.Error(), "basic auth") {
+		t.Fatalf("want an error for a basic auth login, got: %v", err)
+	}
+
+	config.UpdateAuthConfig(gatewayURL, "saved-token", config.Oauth2AuthType)
+	if got, err := dashboardSubjectToken(gatewayURL, ""); err != nil || got != "saved-token" {
+		t.Errorf("want the saved token, got: %q, %v", got, err)
+	}
+}
+
+func Test_dashboardToken_PrintsLink(t *testing.T) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+
+	var scope string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		scope = r.Form.Get("scope")
+		w.Write([]byte(`{"access_token":"a.b+c","token_type":"Bearer","expires_in":900}`))
+	}))
+	defer s.Close()
+
+	resetForTest()
+	dashboardURL, dashboardWrite, dashboardQuiet = "", false, false
+	defer func() { token = "" }()
+
+	var out bytes.Buffer
+	faasCmd.SetOut(&out)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs([]string{"dashboard-token", "--gateway", s.URL, "--token", "id-token",
+		"--dashboard", "https://dashboard.example.com/", "--quiet"})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "https://dashboard.example.com/#token=a.b%2Bc\n"; out.String() != want {
+		t.Errorf("want: %q, got: %q", want, out.String())
+	}
+	if scope != dashboardReadScope {
+		t.Errorf("want a read-only token by default, got scope: %q", scope)
+	}
+}
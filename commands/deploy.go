@@ -6,6 +6,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/output"
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/schema"
 	"github.com/openfaas/faas-cli/stack"
@@ -26,6 +28,8 @@ var (
 	// readTemplate controls whether we should read the function's template when deploying.
 	readTemplate    bool
 	timeoutOverride time.Duration
+	// deployVerifyKey is a public key which must have signed the stack file
+	deployVerifyKey string
 )
 
 // DeployFlags holds flags that are to be added to commands.
@@ -38,6 +42,8 @@ type DeployFlags struct {
 	secrets                []string
 	labelOpts              []string
 	annotationOpts         []string
+	// routes are paths routed to the function, as well as its router's
+	routes []string
 }
 
 var deployFlags DeployFlags
@@ -59,6 +65,7 @@ func init() {
 	deployCmd.Flags().StringArrayVarP(&deployFlags.labelOpts, "label", "l", []string{}, "Set one or more label (LABEL=VALUE)")
 
 	deployCmd.Flags().StringArrayVarP(&deployFlags.annotationOpts, "annotation", "", []string{}, "Set one or more annotation (ANNOTATION=VALUE)")
+	deployCmd.Flags().StringArrayVar(&deployFlags.routes, "route", []string{}, "Route a path to the function, such as /api/orders or /api/orders/*, as well as the paths of its router in the stack file")
 
 	deployCmd.Flags().BoolVar(&deployFlags.replace, "replace", false, "Remove and re-create existing function(s)")
 	deployCmd.Flags().BoolVar(&deployFlags.update, "update", true, "Perform rolling update on existing function(s)")
@@ -77,7 +84,18 @@ func init() {
 	deployCmd.Flags().BoolVar(&readTemplate, "read-template", true, "Read the function's template")
 
 	deployCmd.Flags().DurationVar(&timeoutOverride, "timeout", commandTimeout, "Timeout for any HTTP calls made to the OpenFaaS API.")
-
+	deployCmd.Flags().StringVar(&deployVerifyKey, "verify-key", "", "Refuse to deploy unless the stack file's signature was made by this public key")
+	deployCmd.Flags().BoolVar(&registryFailover, "registry-failover", false, "Deploy from a mirror in configuration.registries of the stack file when the image's own registry doesn't have it")
+	deployCmd.Flags().StringVar(&checkArch, "check-arch", "", "Check each image has a variant for the cluster's architecture, read from the \"gateway\" or the nodes listed by \"kubectl\"")
+	deployCmd.Flags().BoolVar(&explainEnv, "explain-env", false, "Print each environment variable of a function from a stack file, with the source which set it")
+	deployCmd.Flags().BoolVar(&allowReservedEnv, "allow-reserved-env", false, "Allow functions to override environment variables reserved by their template")
+	deployCmd.Flags().BoolVar(&gatewayPrecheck, "precheck", true, "Check the gateway is healthy, the credentials are valid, and that secrets and annotations are accepted before deploying from a stack file")
+	deployCmd.Flags().BoolVar(&allowProtectedChanges, "allow-protected-changes", false, "Allow changes to the protected annotations and labels of the CLI's config and the stack file")
+	deployCmd.Flags().BoolVar(&createNamespace, "create-namespace", false, "Create the namespace of each function through the gateway when it doesn't exist")
+	deployCmd.Flags().StringArrayVar(&namespaceLabels, "namespace-label", []string{}, "Set a label on the namespaces created by --create-namespace (LABEL=VALUE)")
+	deployCmd.Flags().IntVar(&maxGatewayErrors, "max-gateway-errors", 3, "Stop deploying from a stack file after this many consecutive gateway errors, 0 to never stop")
+
+	markMutating(deployCmd)
 	faasCmd.AddCommand(deployCmd)
 }
 
@@ -107,7 +125,34 @@ var deployCmd = &cobra.Command{
 	Short: "Deploy OpenFaaS functions",
 	Long: `Deploys OpenFaaS function containers either via the supplied YAML config using
 the "--yaml" flag (which may contain multiple function definitions), or directly
-via flags. Note: --replace and --update are mutually exclusive.`,
+via flags. Note: --replace and --update are mutually exclusive.
+
+Each function is deployed to the namespace in its "namespace" field, or in the
+provider's "namespace" field when it has none, unless --namespace is given.
+The functions of each namespace are deployed at the same time as those of the
+other namespaces. With --create-namespace, each namespace which doesn't exist
+is created first, through the gateway's namespaces API, with a label for each
+--namespace-label, such as the tenant it belongs to.
+
+Annotations and labels protected for the gateway in the CLI's config, such as
+those managed by an operator, are not changed or removed on functions which
+are already deployed, unless --allow-protected-changes is given. They are set
+with "faas-cli connection set --protect-annotation", and the stack file's
+configuration.protected can add to them, but not remove them.
+
+With --urls-out, the URL and async URL of each function which was deployed are
+written to a file, as JSON, KEY=VALUE pairs or a markdown table, by whether it
+ends in .json, .env or .md. The URLs of a function deployed to a namespace end
+in .NAMESPACE, e.g. /function/figlet.staging.
+
+Paths are routed to a function by the router field of the stack file, or by
+--route for a single function, and deployed as annotations for routers which
+read them, see: faas-cli routes. The paths must start with /, can only end in
+a /* wildcard, and can't be routed to two functions of one namespace.
+
+When the gateway rejects a function, such as for a label value or a quantity of
+memory which the provider can't accept, the field its message is about is shown
+with its line in the stack file, e.g. stack.yml:12: functions.figlet.labels.tier.`,
 	Example: `  faas-cli deploy -f https://domain/path/myfunctions.yml
   faas-cli deploy -f ./stack.yml
   faas-cli deploy -f ./stack.yml --label canary=true
@@ -119,6 +164,11 @@ via flags. Note: --replace and --update are mutually exclusive.`,
   faas-cli deploy -f ./stack.yml --tag sha
   faas-cli deploy -f ./stack.yml --tag branch
   faas-cli deploy -f ./stack.yml --tag describe
+  faas-cli deploy -f ./stack.yml --max-gateway-errors 5
+  faas-cli deploy -f ./stack.yml --allow-protected-changes
+  faas-cli deploy -f ./stack.yml --urls-out urls.json
+  faas-cli deploy -f ./stack.yml --filter orders --route /api/orders --route "/api/orders/*"
+  faas-cli deploy -f ./stack.yml --namespace team-a --create-namespace --namespace-label tenant=team-a
   faas-cli deploy --image=alexellis/faas-url-ping --name=url-ping
   faas-cli deploy --image=my_image --name=my_fn --handler=/path/to/fn/
                   --gateway=http://remote-site.com:8080 --lang=python
@@ -146,8 +196,24 @@ func runDeployCommand(args []string, image string, fprocess string, functionName
 		return fmt.Errorf("cannot specify --update and --replace at the same time")
 	}
 
+	if len(namespaceLabels) > 0 && !createNamespace {
+		return fmt.Errorf("--namespace-label labels the namespaces created by --create-namespace, so must be used with it")
+	}
+
+	if len(deployURLsOut) > 0 {
+		if _, err := urlsFormat(deployURLsOut); err != nil {
+			return err
+		}
+	}
+
 	var services stack.Services
 	if len(yamlFile) > 0 {
+		if len(deployVerifyKey) > 0 {
+			if _, err := verifyStackSignature(yamlFile, stackSignaturePath(yamlFile, ""), deployVerifyKey); err != nil {
+				return fmt.Errorf("stack file signature: %w", err)
+			}
+		}
+
 		parsedServices, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst)
 		if err != nil {
 			return err
@@ -163,6 +229,11 @@ func runDeployCommand(args []string, image string, fprocess string, functionName
 	ctx := context.Background()
 
 	var failedStatusCodes = make(map[string]int)
+	// rejected is the message the gateway gave for each function it rejected
+	rejected := map[string]string{}
+	// deployed is the namespace of each function which was deployed
+	deployed := map[string]string{}
+	deployedGateway := services.Provider.GatewayURL
 	if len(services.Functions) > 0 {
 
 		cliAuth, err := proxy.NewCLIAuth(token, services.Provider.GatewayURL)
@@ -175,12 +246,52 @@ func runDeployCommand(args []string, image string, fprocess string, functionName
 			return err
 		}
 
-		for k, function := range services.Functions {
+		if !allowReservedEnv {
+			if err := checkReservedEnvironment(services.Functions); err != nil {
+				return err
+			}
+		}
+
+		if len(deployFlags.routes) > 0 && len(services.Functions) > 1 {
+			return fmt.Errorf("--route routes paths to one function, give --filter or use the router field of the stack file")
+		}
+		if err := checkRouteConflicts(services.Functions, functionNamespace); err != nil {
+			return err
+		}
+
+		if createNamespace {
+			var namespaces []string
+			for _, function := range services.Functions {
+				namespaces = append(namespaces, getNamespace(functionNamespace, function.Namespace))
+			}
+			if err := ensureNamespaces(ctx, proxyClient, namespaces, namespaceLabels, os.Stdout); err != nil {
+				return err
+			}
+		}
 
+		if gatewayPrecheck {
+			if err := checkGatewayReady(ctx, proxyClient, functionNamespace); err != nil {
+				return err
+			}
+
+			if err := preflightDeploy(ctx, proxyClient, services.Functions, functionNamespace, deployFlags.secrets); err != nil {
+				return err
+			}
+		}
+
+		var clusterArchs []string
+		if len(checkArch) > 0 {
+			if clusterArchs, err = clusterArchitectures(ctx, proxyClient, checkArch); err != nil {
+				return err
+			}
+		}
+
+		specs := map[string]*proxy.DeployFunctionSpec{}
+		for k, function := range services.Functions {
 			functionSecrets := deployFlags.secrets
 
 			function.Name = k
-			fmt.Printf("Deploying: %s.\n", function.Name)
+			fmt.Printf("Deploying: %s.\n", output.Bold.Apply(function.Name))
 			var functionConstraints []string
 			if function.Constraints != nil {
 				functionConstraints = *function.Constraints
@@ -218,6 +329,12 @@ func runDeployCommand(args []string, image string, fprocess string, functionName
 				return envErr
 			}
 
+			if explainEnv {
+				if err := explainFunctionEnvironment(os.Stdout, function, deployFlags.envvarOpts); err != nil {
+					return err
+				}
+			}
+
 			if readTemplate {
 				// Get FProcess to use from the ./template/template.yml, if a template is being used
 				if languageExistsNotDockerfile(function.Language) {
@@ -241,6 +358,25 @@ Error: %s`, fprocessErr.Error())
 				annotations = *function.Annotations
 			}
 
+			checkAnnotations, checkWarnings, err := healthAnnotations(function)
+			if err != nil {
+				return err
+			}
+			for _, warning := range checkWarnings {
+				fmt.Println(output.Warning("Warning: " + warning))
+			}
+			if len(checkAnnotations) > 0 {
+				annotations = util.MergeMap(annotations, checkAnnotations)
+			}
+
+			routeAnnotations, err := routerAnnotations(function, deployFlags.routes)
+			if err != nil {
+				return err
+			}
+			if len(routeAnnotations) > 0 {
+				annotations = util.MergeMap(annotations, routeAnnotations)
+			}
+
 			annotationArgs, annotationErr := util.ParseMap(deployFlags.annotationOpts, "annotation")
 			if annotationErr != nil {
 				return fmt.Errorf("error parsing annotations: %v", annotationErr)
@@ -255,6 +391,16 @@ Error: %s`, fprocessErr.Error())
 
 			function.Image = schema.BuildImageName(tagMode, function.Image, sha, branch)
 
+			if registryFailover && len(services.StackConfiguration.Registries) > 0 {
+				if function.Image, err = failoverImage(function.Image, services.StackConfiguration.Registries); err != nil {
+					return err
+				}
+			}
+
+			if err := checkImageArch(function.Image, clusterArchs); err != nil {
+				return err
+			}
+
 			if deployFlags.readOnlyRootFilesystem {
 				function.ReadOnlyRootFilesystem = deployFlags.readOnlyRootFilesystem
 			}
@@ -292,12 +438,51 @@ Error: %s`, fprocessErr.Error())
 			if msg := checkTLSInsecure(services.Provider.GatewayURL, deploySpec.TLSInsecure); len(msg) > 0 {
 				fmt.Println(msg)
 			}
-			statusCode := proxyClient.DeployFunction(ctx, deploySpec)
-			if badStatusCode(statusCode) {
-				failedStatusCodes[k] = statusCode
+			specs[k] = deploySpec
+		}
+
+		if !allowProtectedChanges {
+			protected := protectedKeys(services.Provider.GatewayURL, services.StackConfiguration.Protected)
+			if err := checkProtectedChanges(ctx, proxyClient, specs, protected); err != nil {
+				return err
+			}
+		}
+
+		// Each namespace is deployed to concurrently, so that a stack for many
+		// tenants doesn't take as long as deploying each function in turn
+		breaker := newGatewayBreaker(maxGatewayErrors)
+		attempted := deployByNamespace(ctx, proxyClient, specs, breaker, failedStatusCodes, rejected, services.Provider.GatewayURL)
+		printRejections(os.Stdout, yamlFile, specs, rejected)
+		for name := range attempted {
+			if _, failed := failedStatusCodes[name]; !failed {
+				deployed[name] = specs[name].Namespace
+			}
+		}
+
+		if breaker.tripped() {
+			var skipped []string
+			for k := range services.Functions {
+				if !attempted[k] {
+					skipped = append(skipped, k)
+				}
+			}
+
+			if len(skipped) > 0 {
+				if err := writeURLsOut(deployedGateway, deployed); err != nil {
+					return err
+				}
+
+				tripErr := breakerTripped(maxGatewayErrors, skipped)
+				if err := deployFailed(failedStatusCodes); err != nil {
+					return fmt.Errorf("%s\n%s", err, tripErr)
+				}
+				return tripErr
 			}
 		}
 	} else {
+		if len(deployVerifyKey) > 0 {
+			return fmt.Errorf("--verify-key can only be used with a stack file")
+		}
 		if len(image) == 0 || len(functionName) == 0 {
 			return fmt.Errorf("to deploy a function give --yaml/-f or a --image and --name flag")
 		}
@@ -311,6 +496,22 @@ Error: %s`, fprocessErr.Error())
 			return err
 		}
 
+		if createNamespace {
+			if err := ensureNamespaces(ctx, proxyClient, []string{functionNamespace}, namespaceLabels, os.Stdout); err != nil {
+				return err
+			}
+		}
+
+		routeAnnotations, err := routerAnnotations(stack.Function{Name: functionName}, deployFlags.routes)
+		if err != nil {
+			return err
+		}
+		annotationOpts := append([]string{}, deployFlags.annotationOpts...)
+		for _, key := range sortedKeys(routeAnnotations) {
+			annotationOpts = append(annotationOpts, key+"="+routeAnnotations[key])
+		}
+		deployFlags.annotationOpts = annotationOpts
+
 		// default to a readable filesystem until we get more input about the expected behavior
 		// and if we want to add another flag for this case
 		defaultReadOnlyRFS := false
@@ -322,7 +523,14 @@ Error: %s`, fprocessErr.Error())
 
 		if badStatusCode(statusCode) {
 			failedStatusCodes[functionName] = statusCode
+		} else {
+			deployed[functionName] = functionNamespace
 		}
+		deployedGateway = gateway
+	}
+
+	if err := writeURLsOut(deployedGateway, deployed); err != nil {
+		return err
 	}
 
 	if err := deployFailed(failedStatusCodes); err != nil {
@@ -391,11 +599,30 @@ func deployImage(
 		fmt.Println(msg)
 	}
 
+	if revisionLimit > 0 {
+		var deployed map[string]string
+		if fn, err := client.GetFunctionInfo(ctx, functionName, namespace); err == nil && fn.Annotations != nil {
+			deployed = *fn.Annotations
+		}
+		addRevision(deploySpec, deployed, revisionLimit)
+	}
+
 	statusCode = client.DeployFunction(ctx, deploySpec)
+	if recordHistory && !badStatusCode(statusCode) {
+		recordDeployment(os.Stdout, client.GatewayURL.String(), deploySpec, "deploy --image "+image)
+	}
 
 	return statusCode, nil
 }
 
+// recordDeployment writes a changeset for a deployment, a failure to do so is
+// reported but does not fail the deployment which has already taken place.
+func recordDeployment(out io.Writer, gateway string, spec *proxy.DeployFunctionSpec, source string) {
+	if _, err := recordChangeset(historyDir, gateway, spec, source); err != nil {
+		fmt.Fprintf(out, "Unable to record changeset for %s: %s\n", spec.FunctionName, err)
+	}
+}
+
 func readFiles(files []string) (map[string]string, error) {
 	envs := make(map[string]string)
 
@@ -0,0 +1,155 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+const (
+	archCheckGateway = "gateway"
+	archCheckKubectl = "kubectl"
+)
+
+// checkArch is where the architectures of the cluster are read from, either
+// the gateway's system information or the nodes listed by kubectl, an empty
+// value skips the check
+var checkArch string
+
+// imagePlatforms and kubectlNodeArchitectures are replaced by tests
+var imagePlatforms = func(image string) ([]string, error) {
+	manifest, err := crane.Manifest(image, registryAuth())
+	if err != nil {
+		return nil, err
+	}
+
+	var index struct {
+		Manifests []struct {
+			Platform *struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}
+	if err := json.Unmarshal(manifest, &index); err != nil {
+		return nil, err
+	}
+
+	var archs []string
+	for _, m := range index.Manifests {
+		// Attestations are listed with an "unknown" platform by buildx
+		if m.Platform != nil && m.Platform.Architecture != "unknown" {
+			archs = append(archs, m.Platform.Architecture)
+		}
+	}
+	if len(index.Manifests) > 0 {
+		return archs, nil
+	}
+
+	// A single image records its platform in the config
+	data, err := crane.Config(image, registryAuth())
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Architecture string `json:"architecture"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return []string{config.Architecture}, nil
+}
+
+var kubectlNodeArchitectures = func() ([]string, error) {
+	out, err := exec.Command("kubectl", "get", "nodes", "-o", "jsonpath={.items[*].status.nodeInfo.architecture}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes with kubectl: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// clusterArchitectures returns the distinct architectures of the cluster, with
+// names normalised to those used in image manifests
+func clusterArchitectures(ctx context.Context, client *proxy.Client, source string) ([]string, error) {
+	var archs []string
+
+	switch source {
+	case archCheckGateway:
+		info, err := client.GetSystemInfo(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(info.Arch) > 0 {
+			archs = []string{info.Arch}
+		}
+	case archCheckKubectl:
+		var err error
+		if archs, err = kubectlNodeArchitectures(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("--check-arch must be one of: %s, %s", archCheckGateway, archCheckKubectl)
+	}
+
+	seen := map[string]bool{}
+	var normalised []string
+	for _, arch := range archs {
+		arch = normaliseArch(arch)
+		if !seen[arch] {
+			seen[arch] = true
+			normalised = append(normalised, arch)
+		}
+	}
+	sort.Strings(normalised)
+	return normalised, nil
+}
+
+// normaliseArch maps the output of uname -m, as reported by the gateway, to
+// the architectures used in image manifests
+func normaliseArch(arch string) string {
+	switch arch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64", "arm64v8":
+		return "arm64"
+	case "armv7l", "armv6l", "armhf":
+		return "arm"
+	}
+	return arch
+}
+
+// checkImageArch errors when image has no variant for any of the cluster's
+// architectures. When the image's manifest can't be read, a warning is printed
+// and the deployment goes ahead.
+func checkImageArch(image string, clusterArchs []string) error {
+	if len(clusterArchs) == 0 {
+		return nil
+	}
+
+	archs, err := imagePlatforms(image)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to check the architecture of %s: %s\n", image, err)
+		return nil
+	}
+
+	for _, arch := range archs {
+		for _, clusterArch := range clusterArchs {
+			if arch == clusterArch {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("image %s is built for %s, but the cluster runs %s, so it would fail to start, rebuild it with: faas-cli publish --platforms linux/%s",
+		image, strings.Join(archs, ", "), strings.Join(clusterArchs, ", "), clusterArchs[0])
+}
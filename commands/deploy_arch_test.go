@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_checkImageArch(t *testing.T) {
+	platforms := imagePlatforms
+	defer func() { imagePlatforms = platforms }()
+
+	imagePlatforms = func(image string) ([]string, error) {
+		if strings.Contains(image, "multi") {
+			return []string{"amd64", "arm64"}, nil
+		}
+		return []string{"amd64"}, nil
+	}
+
+	if err := checkImageArch("ghcr.io/acme/multi:0.1", []string{"arm64"}); err != nil {
+		t.Fatalf("want a multi-arch image to pass, got: %s", err)
+	}
+
+	err := checkImageArch("ghcr.io/acme/fn:0.1", []string{"arm64"})
+	if err == nil || !strings.Contains(err.Error(), "built for amd64, but the cluster runs arm64") {
+		t.Fatalf("want an architecture mismatch, got: %v", err)
+	}
+
+	if err := checkImageArch("ghcr.io/acme/fn:0.1", nil); err != nil {
+		t.Fatalf("want no check without cluster architectures, got: %s", err)
+	}
+}
+
+func Test_clusterArchitectures(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"arch": "aarch64"})
+	}))
+	defer s.Close()
+
+	got, err := clusterArchitectures(context.Background(), newPrecheckClient(t, s.URL), archCheckGateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"arm64"}) {
+		t.Fatalf("want arm64 from the gateway, got: %v", got)
+	}
+
+	nodes := kubectlNodeArchitectures
+	defer func() { kubectlNodeArchitectures = nodes }()
+	kubectlNodeArchitectures = func() ([]string, error) {
+		return []string{"arm64", "amd64", "arm64"}, nil
+	}
+
+	got, err = clusterArchitectures(context.Background(), nil, archCheckKubectl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"amd64", "arm64"}) {
+		t.Fatalf("want each node architecture once, got: %v", got)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/util"
+)
+
+var (
+	// createNamespace creates the functions' namespaces when they don't exist
+	createNamespace bool
+	// namespaceLabels are given to the namespaces created by
+	// --create-namespace, such as the tenant they belong to
+	namespaceLabels []string
+)
+
+// ensureNamespaces creates each of the namespaces which the gateway doesn't
+// list, with the given labels. The provider's default namespace, given as "",
+// always exists.
+func ensureNamespaces(ctx context.Context, client *proxy.Client, namespaces []string, labelOpts []string, w io.Writer) error {
+	labels, err := util.ParseMap(labelOpts, "namespace-label")
+	if err != nil {
+		return fmt.Errorf("error parsing namespace labels: %v", err)
+	}
+
+	existing, err := client.ListNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, namespace := range existing {
+		known[namespace] = true
+	}
+
+	for _, namespace := range missingNamespaces(namespaces, known) {
+		if err := client.CreateNamespace(ctx, proxy.NamespaceRequest{Name: namespace, Labels: labels}); err != nil {
+			return fmt.Errorf("unable to create namespace %q: %w", namespace, err)
+		}
+		fmt.Fprintf(w, "Created namespace: %s\n", namespace)
+	}
+	return nil
+}
+
+// missingNamespaces are those which aren't known, once each and in order
+func missingNamespaces(namespaces []string, known map[string]bool) []string {
+	var missing []string
+	for _, namespace := range namespaces {
+		if len(namespace) == 0 || known[namespace] {
+			continue
+		}
+		known[namespace] = true
+		missing = append(missing, namespace)
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+func Test_ensureNamespaces(t *testing.T) {
+	var created []proxy.NamespaceRequest
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/system/namespaces":
+			w.Write([]byte(`["openfaas-fn","staging"]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/system/namespace":
+			var req proxy.NamespaceRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			created = append(created, req)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	var out bytes.Buffer
+	namespaces := []string{"", "staging", "team-b", "team-a", "team-b"}
+	if err := ensureNamespaces(context.Background(), newPrecheckClient(t, s.URL), namespaces, []string{"tenant=acme"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(created) != 2 || created[0].Name != "team-a" || created[1].Name != "team-b" {
+		t.Fatalf("want team-a and team-b created once each, got: %v", created)
+	}
+	if created[0].Labels["tenant"] != "acme" {
+		t.Errorf("want the namespace labelled, got: %v", created[0].Labels)
+	}
+	if got := out.String(); got != "Created namespace: team-a\nCreated namespace: team-b\n" {
+		t.Errorf("want each namespace printed, got: %q", got)
+	}
+}
+
+func Test_ensureNamespaces_Unsupported(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`["openfaas-fn"]`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer s.Close()
+
+	var out bytes.Buffer
+	err := ensureNamespaces(context.Background(), newPrecheckClient(t, s.URL), []string{"team-a"}, nil, &out)
+	if err == nil || !strings.Contains(err.Error(), "can't create namespaces") {
+		t.Errorf("want an error for a gateway without the namespaces API, got: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+var (
+	// quotedValue is a value quoted within a provider's message, such as
+	// Invalid value: "bad value!"
+	quotedValue = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"|'([^']*)'`)
+
+	// escapedValue is a value quoted within a message which is itself quoted,
+	// such as within JSON
+	escapedValue = regexp.MustCompile(`\\"((?:[^"\\]|\\[^"])*)\\"`)
+
+	// quantityFormat is the format Kubernetes accepts for a quantity of
+	// memory or CPU
+	quantityFormat = regexp.MustCompile(`^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$`)
+
+	// labelValueFormat is the format Kubernetes accepts for the value of a
+	// label, which must also be 63 characters or less
+	labelValueFormat = regexp.MustCompile(`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`)
+)
+
+// deployField is a field of a function's deployment and its value, the path
+// is relative to the function, such as labels then the name of a label, or
+// secrets then the name of a secret
+type deployField struct {
+	path  []string
+	value string
+}
+
+func (f deployField) String() string {
+	return strings.Join(f.path, ".")
+}
+
+// printRejections shows the field of the stack file, and its line where it can
+// be found, which the gateway's message names for each function it rejected
+func printRejections(w io.Writer, stackFile string, specs map[string]*proxy.DeployFunctionSpec, rejected map[string]string) {
+	if len(rejected) == 0 {
+		return
+	}
+
+	var lines []string
+	if data, err := os.ReadFile(stackFile); err == nil {
+		lines = strings.Split(string(data), "\n")
+	}
+
+	names := make([]string, 0, len(rejected))
+	for name := range rejected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		spec, ok := specs[name]
+		if !ok {
+			continue
+		}
+
+		message := strings.SplitN(rejected[name], "\n", 2)[0]
+		for _, field := range rejectedFields(rejected[name], spec) {
+			location := "functions." + name + "." + field.String()
+			if line := stackFieldLine(lines, name, field.path); line > 0 {
+				location = fmt.Sprintf("%s:%d: %s", stackFile, line, location)
+			}
+			fmt.Fprintln(w, output.Failure(fmt.Sprintf("%s: rejected by the gateway: %s", location, message)))
+		}
+	}
+}
+
+// rejectedFields gives the fields of spec which a provider's message is
+// about. A field matches when its value, or the name of a label, annotation
+// or environment variable, is quoted in the message. Otherwise, when the
+// message names the kind of field, the fields of that kind with a value that
+// Kubernetes would not accept are given.
+func rejectedFields(message string, spec *proxy.DeployFunctionSpec) []deployField {
+	fields := deployFields(spec)
+
+	quoted := map[string]bool{}
+	for _, pattern := range []*regexp.Regexp{quotedValue, escapedValue} {
+		for _, match := range pattern.FindAllStringSubmatch(message, -1) {
+			if value := strings.Join(match[1:], ""); len(value) > 0 && value != spec.FunctionName {
+				quoted[value] = true
+			}
+		}
+	}
+
+	var found []deployField
+	for _, field := range fields {
+		named := len(field.path) == 2 && quoted[field.path[1]] &&
+			(field.path[0] == "labels" || field.path[0] == "annotations" || field.path[0] == "environment")
+		if quoted[field.value] || named {
+			found = append(found, field)
+		}
+	}
+	if len(found) > 0 {
+		return found
+	}
+
+	// A quantity which can't be parsed is often reported without its value
+	lower := strings.ToLower(message)
+	for _, field := range fields {
+		switch field.path[0] {
+		case "limits", "requests":
+			if (strings.Contains(lower, "quantit") || strings.Contains(lower, field.path[1])) && !quantityFormat.MatchString(field.value) {
+				found = append(found, field)
+			}
+		case "labels":
+			if strings.Contains(lower, "label") && (len(field.value) > 63 || !labelValueFormat.MatchString(field.value)) {
+				found = append(found, field)
+			}
+		}
+	}
+	return found
+}
+
+// deployFields lists the fields of spec which a provider validates, in the
+// order they are usually written in a stack file
+func deployFields(spec *proxy.DeployFunctionSpec) []deployField {
+	fields := []deployField{{path: []string{"image"}, value: spec.Image}}
+
+	for _, section := range []struct {
+		name   string
+		values map[string]string
+	}{
+		{"labels", spec.Labels},
+		{"annotations", spec.Annotations},
+		{"environment", spec.EnvVars},
+	} {
+		keys := make([]string, 0, len(section.values))
+		for key := range section.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fields = append(fields, deployField{path: []string{section.name, key}, value: section.values[key]})
+		}
+	}
+
+	resources := spec.FunctionResourceRequest
+	if resources.Limits != nil {
+		fields = appendResourceFields(fields, "limits", resources.Limits.Memory, resources.Limits.CPU)
+	}
+	if resources.Requests != nil {
+		fields = appendResourceFields(fields, "requests", resources.Requests.Memory, resources.Requests.CPU)
+	}
+
+	for _, secret := range spec.Secrets {
+		fields = append(fields, deployField{path: []string{"secrets", secret}, value: secret})
+	}
+	for _, constraint := range spec.Constraints {
+		fields = append(fields, deployField{path: []string{"constraints", constraint}, value: constraint})
+	}
+	return fields
+}
+
+func appendResourceFields(fields []deployField, section, memory, cpu string) []deployField {
+	if len(memory) > 0 {
+		fields = append(fields, deployField{path: []string{section, "memory"}, value: memory})
+	}
+	if len(cpu) > 0 {
+		fields = append(fields, deployField{path: []string{section, "cpu"}, value: cpu})
+	}
+	return fields
+}
+
+// stackFieldLine gives the line number of a function's field in the lines of
+// a stack file, or 0 when it isn't in the file, such as for a label given with
+// --label. Each part of path is the key of a mapping, or a list item's value.
+func stackFieldLine(lines []string, function string, path []string) int {
+	owners := stackLineFunctions(lines)
+
+	at := -1
+	for i := range lines {
+		if owners[i] == function {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return 0
+	}
+
+	start := at
+	indent := lineIndent(lines[at])
+	for _, key := range path {
+		found := false
+		child := -1
+		for i := at + 1; i < len(lines) && owners[i] == function; i++ {
+			trimmed := strings.TrimSpace(lines[i])
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+
+			// The items of a list may be written at the same indent as its key
+			depth := lineIndent(lines[i])
+			item := strings.HasPrefix(trimmed, "- ")
+			if depth < indent || (depth == indent && (at == start || !item)) {
+				break
+			}
+			if child < 0 {
+				child = depth
+			}
+			if depth != child {
+				continue
+			}
+
+			if stackKey(trimmed) == key {
+				at, indent, found = i, depth, true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+	}
+	return at + 1
+}
+
+// stackKey is the key of a line of a mapping, or the value of a list item
+func stackKey(trimmed string) string {
+	if strings.HasPrefix(trimmed, "- ") {
+		return strings.Trim(strings.TrimSpace(trimmed[2:]), `"'`)
+	}
+	return strings.Trim(strings.SplitN(trimmed, ":", 2)[0], `"'`)
+}
+
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+const rejectedStack = `version: 1.0
+provider:
+  name: openfaas
+functions:
+  env:
+    image: ghcr.io/openfaas/alpine:latest
+  figlet:
+    image: ghcr.io/openfaas/figlet:latest
+    labels:
+      tier: "bad value!"
+      com.openfaas.scale.min: "1"
+    limits:
+      memory: 128Mi
+    requests:
+      memory: lots
+    secrets:
+    - api-key
+`
+
+func Test_rejectedFields(t *testing.T) {
+	spec := &proxy.DeployFunctionSpec{
+		FunctionName: "figlet",
+		Image:        "ghcr.io/openfaas/figlet:latest",
+		Labels:       map[string]string{"tier": "bad value!", "com.openfaas.scale.min": "1"},
+		EnvVars:      map[string]string{"write_debug": "true"},
+		Secrets:      []string{"api-key"},
+		FunctionResourceRequest: proxy.FunctionResourceRequest{
+			Limits:   &stack.FunctionResources{Memory: "128Mi"},
+			Requests: &stack.FunctionResources{Memory: "lots"},
+		},
+	}
+
+	cases := []struct {
+		message string
+		want    []string
+	}{
+		{`Deployment.apps "figlet" is invalid: spec.template.labels: Invalid value: "bad value!": a valid label must be an empty string or consist of alphanumeric characters`, []string{"labels.tier"}},
+		{`{"message":"secret \"api-key\" not found"}`, []string{"secrets.api-key"}},
+		{`unable to parse requests: quantities must match the regular expression`, []string{"requests.memory"}},
+		{`metadata.labels: invalid label`, []string{"labels.tier"}},
+		{`Deployment.apps "figlet" already exists`, nil},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, field := range rejectedFields(c.message, spec) {
+			got = append(got, field.String())
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: want: %v, got: %v", c.message, c.want, got)
+		}
+	}
+}
+
+func Test_stackFieldLine(t *testing.T) {
+	lines := strings.Split(rejectedStack, "\n")
+
+	cases := []struct {
+		function string
+		path     []string
+		want     int
+	}{
+		{"figlet", []string{"image"}, 8},
+		{"figlet", []string{"labels", "tier"}, 10},
+		{"figlet", []string{"labels", "com.openfaas.scale.min"}, 11},
+		{"figlet", []string{"requests", "memory"}, 15},
+		{"figlet", []string{"secrets", "api-key"}, 17},
+		{"env", []string{"image"}, 6},
+		{"env", []string{"labels", "tier"}, 0},
+		{"figlet", []string{"annotations", "topic"}, 0},
+		{"missing", []string{"image"}, 0},
+	}
+	for _, c := range cases {
+		if got := stackFieldLine(lines, c.function, c.path); got != c.want {
+			t.Errorf("%s %v: want line %d, got: %d", c.function, c.path, c.want, got)
+		}
+	}
+}
+
+func Test_printRejections(t *testing.T) {
+	stackFile := filepath.Join(t.TempDir(), "stack.yml")
+	if err := os.WriteFile(stackFile, []byte(rejectedStack), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	specs := map[string]*proxy.DeployFunctionSpec{
+		"figlet": {FunctionName: "figlet", Labels: map[string]string{"tier": "bad value!", "team": "a b"}},
+	}
+	rejected := map[string]string{
+		"figlet": "labels: Invalid value: \"bad value!\"\nsecond line",
+	}
+
+	var buf bytes.Buffer
+	printRejections(&buf, stackFile, specs, rejected)
+
+	got := buf.String()
+	want := stackFile + `:10: functions.figlet.labels.tier: rejected by the gateway: labels: Invalid value: "bad value!"`
+	if !strings.Contains(got, want) {
+		t.Fatalf("want: %q, got: %q", want, got)
+	}
+	if strings.Contains(got, "second line") || strings.Contains(got, "team") {
+		t.Errorf("want only the field named by the first line of the message, got: %q", got)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+// namespaceGroup is the functions of a stack which are in one namespace
+type namespaceGroup struct {
+	namespace string
+	names     []string
+}
+
+// groupByNamespace groups function names by their namespace, sorted so that
+// the functions of each namespace are deployed or removed in the same order
+// on each run
+func groupByNamespace(namespaces map[string]string) []namespaceGroup {
+	byNamespace := map[string][]string{}
+	for name, namespace := range namespaces {
+		byNamespace[namespace] = append(byNamespace[namespace], name)
+	}
+
+	groups := make([]namespaceGroup, 0, len(byNamespace))
+	for namespace, names := range byNamespace {
+		sort.Strings(names)
+		groups = append(groups, namespaceGroup{namespace: namespace, names: names})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].namespace < groups[j].namespace
+	})
+	return groups
+}
+
+// eachNamespace calls fn for each group at the same time, and waits for them
+// all to return. The functions within a group are left to fn to handle in
+// turn, so that no more than one request is made to a namespace at a time.
+// With more than one group, what fn writes is kept until they have all
+// returned, then written to out a group at a time, in the order of groups.
+func eachNamespace(groups []namespaceGroup, out io.Writer, fn func(group namespaceGroup, out io.Writer)) {
+	if len(groups) == 1 {
+		fn(groups[0], out)
+		return
+	}
+
+	buffers := make([]bytes.Buffer, len(groups))
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		wg.Add(1)
+		go func(group namespaceGroup, buf *bytes.Buffer) {
+			defer wg.Done()
+			fn(group, buf)
+		}(group, &buffers[i])
+	}
+	wg.Wait()
+
+	for i := range buffers {
+		buffers[i].WriteTo(out)
+	}
+}
+
+// deployByNamespace deploys specs with a goroutine for each namespace, and
+// returns the functions which were attempted before the breaker tripped. The
+// status code of each failed deployment is added to failed, and the message
+// the gateway rejected it with to rejected.
+func deployByNamespace(ctx context.Context, client *proxy.Client, specs map[string]*proxy.DeployFunctionSpec, breaker *gatewayBreaker, failed map[string]int, rejected map[string]string, gatewayURL string) map[string]bool {
+	namespaces := map[string]string{}
+	for name, spec := range specs {
+		namespaces[name] = spec.Namespace
+	}
+
+	groups := groupByNamespace(namespaces)
+
+	// The annotations are listed before fanning out, as listing functions sets
+	// the CheckRedirect of the shared client
+	deployed := map[string]map[string]map[string]string{}
+	if revisionLimit > 0 {
+		for _, group := range groups {
+			deployed[group.namespace] = deployedAnnotations(ctx, client, group.namespace)
+		}
+	}
+
+	var mu sync.Mutex
+	attempted := map[string]bool{}
+
+	eachNamespace(groups, os.Stdout, func(group namespaceGroup, out io.Writer) {
+		groupClient := client.WithOutput(out)
+		for _, name := range group.names {
+			mu.Lock()
+			if breaker.tripped() {
+				mu.Unlock()
+				return
+			}
+			attempted[name] = true
+			mu.Unlock()
+
+			spec := specs[name]
+			addRevision(spec, deployed[group.namespace][name], revisionLimit)
+			statusCode, message := groupClient.DeployFunctionResult(ctx, spec)
+
+			mu.Lock()
+			if badStatusCode(statusCode) {
+				failed[name] = statusCode
+				if len(message) > 0 {
+					rejected[name] = message
+				}
+			} else if recordHistory {
+				recordDeployment(out, gatewayURL, spec, "deploy -f "+yamlFile)
+			}
+			breaker.record(statusCode)
+			mu.Unlock()
+		}
+	})
+
+	return attempted
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/openfaas/faas-cli/mockgateway"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_groupByNamespace(t *testing.T) {
+	groups := groupByNamespace(map[string]string{
+		"invoices": "tenant-b",
+		"charge":   "tenant-a",
+		"refund":   "tenant-a",
+		"nodeinfo": "",
+	})
+
+	want := []namespaceGroup{
+		{namespace: "", names: []string{"nodeinfo"}},
+		{namespace: "tenant-a", names: []string{"charge", "refund"}},
+		{namespace: "tenant-b", names: []string{"invoices"}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Fatalf("want: %v, got: %v", want, groups)
+	}
+}
+
+func Test_eachNamespace_WritesEachGroupInTurn(t *testing.T) {
+	groups := []namespaceGroup{
+		{namespace: "tenant-a", names: []string{"charge", "refund"}},
+		{namespace: "tenant-b", names: []string{"invoices"}},
+	}
+
+	// tenant-a only writes its second line once tenant-b has written
+	written := make(chan struct{})
+	var out bytes.Buffer
+	eachNamespace(groups, &out, func(group namespaceGroup, w io.Writer) {
+		for i, name := range group.names {
+			if group.namespace == "tenant-a" && i == 1 {
+				<-written
+			}
+			fmt.Fprintf(w, "Deploying: %s.%s\n", name, group.namespace)
+		}
+		if group.namespace == "tenant-b" {
+			close(written)
+		}
+	})
+
+	want := "Deploying: charge.tenant-a\nDeploying: refund.tenant-a\nDeploying: invoices.tenant-b\n"
+	if out.String() != want {
+		t.Fatalf("want the output of each namespace in turn:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func Test_deployByNamespace(t *testing.T) {
+	savedHistory := recordHistory
+	recordHistory = false
+	defer func() { recordHistory = savedHistory }()
+
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+	client := newPrecheckClient(t, s.URL)
+
+	specs := map[string]*proxy.DeployFunctionSpec{
+		"charge":   {FunctionName: "charge", Image: "acme/charge:0.1.0", Namespace: "tenant-a"},
+		"refund":   {FunctionName: "refund", Image: "acme/refund:0.1.0", Namespace: "tenant-a"},
+		"invoices": {FunctionName: "invoices", Image: "acme/invoices:0.1.0", Namespace: "tenant-b"},
+	}
+
+	failed := map[string]int{}
+	var attempted map[string]bool
+	test.CaptureStdout(func() {
+		attempted = deployByNamespace(context.Background(), client, specs, newGatewayBreaker(3), failed, map[string]string{}, s.URL)
+	})
+
+	if len(attempted) != 3 || len(failed) != 0 {
+		t.Fatalf("want all functions to be deployed, attempted: %v, failed: %v", attempted, failed)
+	}
+
+	for name, spec := range specs {
+		if exists, _ := functionExists(context.Background(), client, name, spec.Namespace); !exists {
+			t.Errorf("want %s in namespace %s", name, spec.Namespace)
+		}
+	}
+}
+
+func Test_deployByNamespace_BreakerStopsEachNamespace(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.URL.Path)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer s.Close()
+	client := newPrecheckClient(t, s.URL)
+
+	specs := map[string]*proxy.DeployFunctionSpec{}
+	for _, name := range []string{"a1", "a2", "a3", "b1", "b2", "b3"} {
+		specs[name] = &proxy.DeployFunctionSpec{FunctionName: name, Image: "acme/" + name, Namespace: "tenant-" + name[:1]}
+	}
+
+	failed := map[string]int{}
+	var attempted map[string]bool
+	test.CaptureStdout(func() {
+		attempted = deployByNamespace(context.Background(), client, specs, newGatewayBreaker(2), failed, map[string]string{}, s.URL)
+	})
+
+	// Both namespaces may make their first attempt before the breaker trips,
+	// but no more than one each after that
+	if len(attempted) < 2 || len(attempted) > 3 {
+		var names []string
+		for name := range attempted {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		t.Fatalf("want the breaker to stop the deployments, attempted: %v", names)
+	}
+	if len(failed) != len(attempted) {
+		t.Fatalf("want each attempt to be recorded as failed, got: %v", failed)
+	}
+}
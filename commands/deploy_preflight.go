@@ -0,0 +1,167 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/util"
+)
+
+// profileAnnotation selects OpenFaaS Pro profiles, which only the Kubernetes
+// provider implements
+const profileAnnotation = "com.openfaas.profile"
+
+var qualifiedNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+var dnsSubdomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// preflightDeploy checks every function in a stack file before any are
+// deployed, so that all missing secrets and invalid annotations are reported
+// together, rather than one at a time as the rollout reaches them. The system
+// information and the secrets of each namespace are fetched in parallel.
+func preflightDeploy(ctx context.Context, client *proxy.Client, functions map[string]stack.Function, namespaceFlag string, secretFlags []string) error {
+	namespaces := map[string]bool{}
+	for _, function := range functions {
+		if len(function.Secrets) > 0 || len(secretFlags) > 0 {
+			namespaces[getNamespace(namespaceFlag, function.Namespace)] = true
+		}
+	}
+
+	var (
+		wg            sync.WaitGroup
+		mu            sync.Mutex
+		orchestration string
+		infoErr       error
+		secrets       = map[string]map[string]bool{}
+		problems      []string
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		info, err := client.GetSystemInfo(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		infoErr = err
+		if info.Provider != nil {
+			orchestration = info.Provider.Orchestration
+		}
+	}()
+
+	for namespace := range namespaces {
+		wg.Add(1)
+		go func(namespace string) {
+			defer wg.Done()
+			list, err := client.GetSecretList(ctx, namespace)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("unable to list secrets in namespace '%s': %s", namespaceLabel(namespace), err))
+				return
+			}
+
+			names := map[string]bool{}
+			for _, secret := range list {
+				names[secret.Name] = true
+			}
+			secrets[namespace] = names
+		}(namespace)
+	}
+
+	wg.Wait()
+
+	if infoErr != nil {
+		return fmt.Errorf("unable to get the provider's capabilities: %w", infoErr)
+	}
+
+	for name, function := range functions {
+		namespace := getNamespace(namespaceFlag, function.Namespace)
+
+		if names, ok := secrets[namespace]; ok {
+			for _, secret := range util.MergeSlice(function.Secrets, secretFlags) {
+				if !names[secret] {
+					problems = append(problems, fmt.Sprintf("function '%s' needs secret '%s', which does not exist in namespace '%s'", name, secret, namespaceLabel(namespace)))
+				}
+			}
+		}
+
+		problems = append(problems, annotationProblems(name, function, orchestration)...)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	return fmt.Errorf("%d problem(s) found before deploying, no functions were deployed:\n%s",
+		len(problems), strings.Join(problems, "\n"))
+}
+
+// annotationProblems checks the function's annotations against what the
+// provider accepts. Kubernetes validates annotation keys, so a bad key would
+// fail the deployment part way through a stack.
+func annotationProblems(name string, function stack.Function, orchestration string) []string {
+	if function.Annotations == nil {
+		return nil
+	}
+
+	kubernetes := orchestration == "kubernetes"
+
+	var problems []string
+	for key := range *function.Annotations {
+		if key == profileAnnotation && !kubernetes {
+			problems = append(problems, fmt.Sprintf("function '%s' sets the %s annotation, but profiles are not supported by the %s provider", name, profileAnnotation, orchestrationLabel(orchestration)))
+		}
+
+		if kubernetes {
+			if err := validAnnotationKey(key); err != nil {
+				problems = append(problems, fmt.Sprintf("function '%s' has an invalid annotation '%s': %s", name, key, err))
+			}
+		}
+	}
+
+	return problems
+}
+
+// validAnnotationKey follows the rules for Kubernetes qualified names: an
+// optional DNS subdomain prefix and a slash, then a name of up to 63 characters
+func validAnnotationKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i > -1 {
+		prefix := key[:i]
+		name = key[i+1:]
+
+		if len(prefix) == 0 || len(prefix) > 253 || !dnsSubdomainPattern.MatchString(prefix) {
+			return fmt.Errorf("the prefix must be a lowercase DNS subdomain of up to 253 characters")
+		}
+	}
+
+	if len(name) == 0 || len(name) > 63 || !qualifiedNamePattern.MatchString(name) {
+		return fmt.Errorf("the name must be up to 63 alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character")
+	}
+
+	return nil
+}
+
+func namespaceLabel(namespace string) string {
+	if len(namespace) == 0 {
+		return "default"
+	}
+	return namespace
+}
+
+func orchestrationLabel(orchestration string) string {
+	if len(orchestration) == 0 {
+		return "current"
+	}
+	return orchestration
+}
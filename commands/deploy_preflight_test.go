@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/mockgateway"
+	"github.com/openfaas/faas-cli/stack"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+func Test_preflightDeploy_ReportsAllMissingSecrets(t *testing.T) {
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+
+	client := newPrecheckClient(t, s.URL)
+	if status, _ := client.CreateSecret(context.Background(), types.Secret{Name: "db-password", Value: "x"}); status != http.StatusCreated && status != http.StatusAccepted && status != http.StatusOK {
+		t.Fatalf("unable to create secret, status: %d", status)
+	}
+
+	functions := map[string]stack.Function{
+		"fn1": {Secrets: []string{"db-password", "api-key"}},
+		"fn2": {Secrets: []string{"s3-key"}},
+		"fn3": {},
+	}
+
+	err := preflightDeploy(context.Background(), client, functions, "", nil)
+	if err == nil {
+		t.Fatalf("want an error")
+	}
+
+	for _, want := range []string{"2 problem(s)", "'fn1' needs secret 'api-key'", "'fn2' needs secret 's3-key'"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in error, got: %s", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "db-password") {
+		t.Errorf("want db-password to be found, got: %s", err)
+	}
+}
+
+func Test_preflightDeploy_NoProblems(t *testing.T) {
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+
+	functions := map[string]stack.Function{"fn1": {}}
+
+	if err := preflightDeploy(context.Background(), newPrecheckClient(t, s.URL), functions, "", nil); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+}
+
+func Test_annotationProblems(t *testing.T) {
+	annotations := map[string]string{
+		profileAnnotation:     "gpu",
+		"example.com/valid":   "1",
+		"Bad_Prefix/name":     "1",
+		"-starts-with-a-dash": "1",
+	}
+	function := stack.Function{Annotations: &annotations}
+
+	kubernetes := annotationProblems("fn1", function, "kubernetes")
+	if len(kubernetes) != 2 {
+		t.Errorf("want 2 invalid keys on kubernetes, got: %v", kubernetes)
+	}
+
+	faasd := annotationProblems("fn1", function, "containerd")
+	if len(faasd) != 1 || !strings.Contains(faasd[0], "profiles are not supported by the containerd provider") {
+		t.Errorf("want only the profile to be reported for containerd, got: %v", faasd)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+// allowProtectedChanges is set by deploy --allow-protected-changes
+var allowProtectedChanges bool
+
+// checkProtectedChanges compares the annotations and labels of each function
+// which is already deployed with those it is about to be deployed with. A
+// protected key which would be added, changed, or removed is reported, and
+// none of the functions are deployed, so that a stack file does not clobber
+// settings which are managed elsewhere.
+func checkProtectedChanges(ctx context.Context, client *proxy.Client, specs map[string]*proxy.DeployFunctionSpec, protected stack.ProtectedKeys) error {
+	if len(protected.Annotations) == 0 && len(protected.Labels) == 0 {
+		return nil
+	}
+
+	deployed := map[string]map[string]types.FunctionStatus{}
+	var problems []string
+	for name, spec := range specs {
+		functions, ok := deployed[spec.Namespace]
+		if !ok {
+			list, err := client.ListFunctions(ctx, spec.Namespace)
+			if err != nil {
+				return fmt.Errorf("unable to check the protected annotations and labels in namespace '%s': %w", namespaceLabel(spec.Namespace), err)
+			}
+
+			functions = map[string]types.FunctionStatus{}
+			for _, function := range list {
+				functions[function.Name] = function
+			}
+			deployed[spec.Namespace] = functions
+		}
+
+		function, ok := functions[spec.FunctionName]
+		if !ok {
+			continue
+		}
+
+		var annotations, labels map[string]string
+		if function.Annotations != nil {
+			annotations = *function.Annotations
+		}
+		if function.Labels != nil {
+			labels = *function.Labels
+		}
+
+		problems = append(problems, protectedProblems(name, "annotation", protected.Annotations, annotations, spec.Annotations)...)
+		problems = append(problems, protectedProblems(name, "label", protected.Labels, labels, spec.Labels)...)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	return fmt.Errorf("%d protected change(s) found, no functions were deployed, give --allow-protected-changes to make them:\n%s",
+		len(problems), strings.Join(problems, "\n"))
+}
+
+// protectedKeys are the keys protected for the gateway in the CLI's config,
+// with those of the stack file, which can add to them but not remove them
+func protectedKeys(gatewayAddress string, stackKeys stack.ProtectedKeys) stack.ProtectedKeys {
+	connection, _ := config.LookupConnectionConfig(gatewayAddress)
+	return stack.ProtectedKeys{
+		Annotations: append(append([]string{}, connection.ProtectedAnnotations...), stackKeys.Annotations...),
+		Labels:      append(append([]string{}, connection.ProtectedLabels...), stackKeys.Labels...),
+	}
+}
+
+// protectedProblems describes each protected key with a different value in
+// next than in deployed
+func protectedProblems(name, kind string, protected []string, deployed, next map[string]string) []string {
+	keys := map[string]bool{}
+	for key := range deployed {
+		keys[key] = true
+	}
+	for key := range next {
+		keys[key] = true
+	}
+
+	var problems []string
+	for key := range keys {
+		if !protectedKey(protected, key) {
+			continue
+		}
+
+		current, isDeployed := deployed[key]
+		value, isNext := next[key]
+		switch {
+		case isDeployed && !isNext:
+			problems = append(problems, fmt.Sprintf("function '%s' would remove the protected %s '%s', which is '%s'", name, kind, key, current))
+		case !isDeployed && isNext:
+			problems = append(problems, fmt.Sprintf("function '%s' would set the protected %s '%s' to '%s'", name, kind, key, value))
+		case current != value:
+			problems = append(problems, fmt.Sprintf("function '%s' would change the protected %s '%s' from '%s' to '%s'", name, kind, key, current, value))
+		}
+	}
+	return problems
+}
+
+// protectedKey matches the key by name, or by the prefix of an entry ending in *
+func protectedKey(protected []string, key string) bool {
+	for _, entry := range protected {
+		if strings.HasSuffix(entry, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(entry, "*")) {
+				return true
+			}
+		} else if entry == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/mockgateway"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_checkProtectedChanges(t *testing.T) {
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+
+	client := newPrecheckClient(t, s.URL)
+	status := client.DeployFunction(context.Background(), &proxy.DeployFunctionSpec{
+		FunctionName: "api",
+		Image:        "acme/api:0.1.0",
+		Annotations: map[string]string{
+			"kubernetes.io/ingress.class": "nginx",
+			"com.openfaas.iam.role":       "reader",
+			"team":                        "payments",
+		},
+		Labels: map[string]string{"com.openfaas.scale.max": "10"},
+	})
+	if badStatusCode(status) {
+		t.Fatalf("unable to deploy, status: %d", status)
+	}
+
+	protected := stack.ProtectedKeys{
+		Annotations: []string{"kubernetes.io/ingress.class", "com.openfaas.iam.*"},
+		Labels:      []string{"com.openfaas.scale.max"},
+	}
+
+	specs := map[string]*proxy.DeployFunctionSpec{
+		"api": {
+			FunctionName: "api",
+			Annotations:  map[string]string{"com.openfaas.iam.role": "writer", "team": "billing"},
+			Labels:       map[string]string{"com.openfaas.scale.max": "10"},
+		},
+		// Not deployed yet, so there is nothing to protect
+		"new": {
+			FunctionName: "new",
+			Annotations:  map[string]string{"kubernetes.io/ingress.class": "traefik"},
+		},
+	}
+
+	err := checkProtectedChanges(context.Background(), client, specs, protected)
+	if err == nil {
+		t.Fatal("want an error for the protected changes")
+	}
+
+	for _, want := range []string{
+		"2 protected change(s) found",
+		"--allow-protected-changes",
+		"function 'api' would remove the protected annotation 'kubernetes.io/ingress.class', which is 'nginx'",
+		"function 'api' would change the protected annotation 'com.openfaas.iam.role' from 'reader' to 'writer'",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in the error, got: %s", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "team") || strings.Contains(err.Error(), "'new'") {
+		t.Errorf("want only protected keys of deployed functions, got: %s", err)
+	}
+
+	specs["api"].Annotations["kubernetes.io/ingress.class"] = "nginx"
+	specs["api"].Annotations["com.openfaas.iam.role"] = "reader"
+	if err := checkProtectedChanges(context.Background(), client, specs, protected); err != nil {
+		t.Errorf("want no error when the protected keys are unchanged, got: %s", err)
+	}
+}
+
+func Test_protectedProblems_Added(t *testing.T) {
+	problems := protectedProblems("api", "label", []string{"com.example/*"}, nil, map[string]string{"com.example/owner": "ops"})
+	if len(problems) != 1 || problems[0] != "function 'api' would set the protected label 'com.example/owner' to 'ops'" {
+		t.Errorf("want the added label reported, got: %v", problems)
+	}
+}
+
+func Test_protectedKeys_FromConfig(t *testing.T) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+
+	gatewayURL := "https://openfaas.example.com"
+	if err := config.UpdateConnectionConfig(config.ConnectionConfig{
+		Gateway:              gatewayURL,
+		ProtectedAnnotations: []string{"com.openfaas.iam.*"},
+		ProtectedLabels:      []string{"com.openfaas.scale.max"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stack file can add keys, but not drop those of the config
+	got := protectedKeys(gatewayURL, stack.ProtectedKeys{Annotations: []string{"team"}})
+	if strings.Join(got.Annotations, ",") != "com.openfaas.iam.*,team" || strings.Join(got.Labels, ",") != "com.openfaas.scale.max" {
+		t.Errorf("want the keys of the config and the stack file, got: %v", got)
+	}
+
+	if got := protectedKeys("http://127.0.0.1:8080", stack.ProtectedKeys{}); len(got.Annotations) != 0 || len(got.Labels) != 0 {
+		t.Errorf("want no keys for a gateway without settings, got: %v", got)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+// revisionsAnnotation holds the recent versions of a function, newest first,
+// so that they can be read back from any gateway, not only the machine which
+// deployed them
+const revisionsAnnotation = "com.openfaas.cli.revisions"
+
+var (
+	revisionLimit  int
+	resolveDigests bool
+)
+
+// resolveImageDigest and revisionNow are replaced by tests
+var (
+	resolveImageDigest = func(image string) (string, error) { return crane.Digest(image, registryAuth()) }
+	revisionNow        = time.Now
+)
+
+func init() {
+	deployCmd.Flags().IntVar(&revisionLimit, "revisions", 10, "Number of versions of each function to keep in its "+revisionsAnnotation+" annotation, 0 to not record them")
+	deployCmd.Flags().BoolVar(&resolveDigests, "resolve-digests", false, "Look up the digest of each image in its registry to record it with the version, images given by digest are always recorded")
+}
+
+// functionRevision is one version of a function, as recorded when it was deployed
+type functionRevision struct {
+	Image    string    `json:"image"`
+	Digest   string    `json:"digest,omitempty"`
+	Config   string    `json:"config"`
+	Deployed time.Time `json:"deployed"`
+	User     string    `json:"user,omitempty"`
+}
+
+// parseRevisions reads the versions recorded in a function's annotations,
+// newest first. A missing or unreadable annotation has no versions.
+func parseRevisions(annotations map[string]string) []functionRevision {
+	value, ok := annotations[revisionsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	var revisions []functionRevision
+	if err := json.Unmarshal([]byte(value), &revisions); err != nil {
+		return nil
+	}
+	return revisions
+}
+
+// addRevision records the spec as the newest version, after those which were
+// in the deployed function's annotations, and keeps no more than limit.
+func addRevision(spec *proxy.DeployFunctionSpec, deployed map[string]string, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	revision := functionRevision{
+		Image:    spec.Image,
+		Digest:   imageDigest(spec.Image),
+		Config:   configDigest(spec),
+		Deployed: revisionNow().UTC(),
+		User:     currentUsername(),
+	}
+
+	revisions := append([]functionRevision{revision}, parseRevisions(deployed)...)
+	if len(revisions) > limit {
+		revisions = revisions[:limit]
+	}
+
+	value, err := json.Marshal(revisions)
+	if err != nil {
+		return
+	}
+	if spec.Annotations == nil {
+		spec.Annotations = map[string]string{}
+	}
+	spec.Annotations[revisionsAnnotation] = string(value)
+}
+
+// imageDigest returns the digest of an image given by digest, or otherwise
+// the one in its registry when --resolve-digests is set. An image which can't
+// be resolved is recorded by its tag alone.
+func imageDigest(image string) string {
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[i+1:]
+	}
+	if !resolveDigests {
+		return ""
+	}
+	digest, err := resolveImageDigest(image)
+	if err != nil {
+		return ""
+	}
+	return digest
+}
+
+// configDigest is a short hash of the settings sent to the gateway, so that
+// versions which differ by more than their image can be told apart.
+func configDigest(spec *proxy.DeployFunctionSpec) string {
+	data, _ := json.Marshal(newChangesetSpec(spec))
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// withoutRevisions drops the recorded versions from a function's annotations,
+// so that they are not part of its config, and are not deployed again from a
+// changeset.
+func withoutRevisions(annotations map[string]string) map[string]string {
+	if _, ok := annotations[revisionsAnnotation]; !ok {
+		return annotations
+	}
+	kept := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		if key != revisionsAnnotation {
+			kept[key] = value
+		}
+	}
+	return kept
+}
+
+// deployedAnnotations returns the annotations of each function in namespace,
+// by name. Functions which cannot be listed just start a new set of versions.
+func deployedAnnotations(ctx context.Context, client *proxy.Client, namespace string) map[string]map[string]string {
+	annotations := map[string]map[string]string{}
+	functions, err := client.ListFunctions(ctx, namespace)
+	if err != nil {
+		return annotations
+	}
+	for _, fn := range functions {
+		if fn.Annotations != nil {
+			annotations[fn.Name] = *fn.Annotations
+		}
+	}
+	return annotations
+}
+
+// printRevisions writes the versions as a table, numbered from 0 for the one
+// which is deployed
+func printRevisions(w io.Writer, revisions []functionRevision) error {
+	if len(revisions) == 0 {
+		_, err := fmt.Fprintln(w, "History:\t <none>")
+		return err
+	}
+
+	fmt.Fprintln(w, "History:")
+	table := output.NewTable("REVISION", "DEPLOYED", "IMAGE", "DIGEST", "CONFIG", "USER")
+	for i, revision := range revisions {
+		table.Row(strconv.Itoa(i),
+			revision.Deployed.Format(time.RFC3339),
+			revision.Image,
+			shortDigest(revision.Digest),
+			revision.Config,
+			valueOrDash(revision.User))
+	}
+	return table.Write(w)
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/mockgateway"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_addRevision_NewestFirstAndLimited(t *testing.T) {
+	spec := &proxy.DeployFunctionSpec{FunctionName: "charge", Image: "acme/charge:0.1.0"}
+
+	var deployed map[string]string
+	for _, image := range []string{"acme/charge:0.1.0", "acme/charge:0.2.0", "acme/charge@sha256:0123456789abcdef"} {
+		spec.Image = image
+		addRevision(spec, deployed, 2)
+		deployed = spec.Annotations
+	}
+
+	revisions := parseRevisions(deployed)
+	if len(revisions) != 2 {
+		t.Fatalf("want 2 revisions, got: %d", len(revisions))
+	}
+	if revisions[0].Image != "acme/charge@sha256:0123456789abcdef" || revisions[1].Image != "acme/charge:0.2.0" {
+		t.Errorf("want the newest revision first, got: %v", revisions)
+	}
+	if revisions[0].Digest != "sha256:0123456789abcdef" {
+		t.Errorf("want the digest of the image reference, got: %q", revisions[0].Digest)
+	}
+	if len(revisions[1].Digest) > 0 {
+		t.Errorf("want no digest for a tag without --resolve-digests, got: %q", revisions[1].Digest)
+	}
+}
+
+func Test_addRevision_Disabled(t *testing.T) {
+	spec := &proxy.DeployFunctionSpec{FunctionName: "charge", Image: "acme/charge:0.1.0"}
+	addRevision(spec, nil, 0)
+
+	if _, ok := spec.Annotations[revisionsAnnotation]; ok {
+		t.Fatalf("want no revisions with a limit of 0")
+	}
+}
+
+func Test_imageDigest_Resolves(t *testing.T) {
+	savedResolve, savedResolveDigests := resolveImageDigest, resolveDigests
+	defer func() { resolveImageDigest, resolveDigests = savedResolve, savedResolveDigests }()
+
+	resolveDigests = true
+	resolveImageDigest = func(image string) (string, error) {
+		return "sha256:feedface", nil
+	}
+
+	if got := imageDigest("acme/charge:0.1.0"); got != "sha256:feedface" {
+		t.Fatalf("want the resolved digest, got: %q", got)
+	}
+}
+
+func Test_configDigest_IgnoresRevisions(t *testing.T) {
+	spec := &proxy.DeployFunctionSpec{
+		FunctionName: "charge",
+		Image:        "acme/charge:0.1.0",
+		Annotations:  map[string]string{"topic": "payments"},
+	}
+	before := configDigest(spec)
+
+	addRevision(spec, nil, 10)
+	if after := configDigest(spec); after != before {
+		t.Errorf("want the same config digest, got: %s and %s", before, after)
+	}
+
+	spec.EnvVars = map[string]string{"mode": "live"}
+	if changed := configDigest(spec); changed == before {
+		t.Errorf("want a new config digest when the environment changes")
+	}
+
+	if _, ok := newChangesetSpec(spec).Annotations[revisionsAnnotation]; ok {
+		t.Errorf("want the revisions left out of the changeset")
+	}
+}
+
+func Test_deployByNamespace_RecordsRevisions(t *testing.T) {
+	savedHistory, savedNow := recordHistory, revisionNow
+	recordHistory = false
+	defer func() { recordHistory, revisionNow = savedHistory, savedNow }()
+
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+	client := newPrecheckClient(t, s.URL)
+
+	deployed := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, image := range []string{"acme/charge:0.1.0", "acme/charge:0.2.0"} {
+		revisionNow = func() time.Time { return deployed }
+		specs := map[string]*proxy.DeployFunctionSpec{
+			"charge": {FunctionName: "charge", Image: image, Namespace: "tenant-a", Update: true},
+		}
+		test.CaptureStdout(func() {
+			deployByNamespace(context.Background(), client, specs, newGatewayBreaker(3), map[string]int{}, map[string]string{}, s.URL)
+		})
+		deployed = deployed.Add(time.Hour)
+	}
+
+	function, err := client.GetFunctionInfo(context.Background(), "charge", "tenant-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revisions := parseRevisions(*function.Annotations)
+	if len(revisions) != 2 {
+		t.Fatalf("want 2 revisions, got: %v", revisions)
+	}
+	if revisions[0].Image != "acme/charge:0.2.0" || !revisions[0].Deployed.Equal(time.Date(2023, 6, 1, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("want the second deployment first, got: %v", revisions[0])
+	}
+}
+
+func Test_printRevisions(t *testing.T) {
+	var out bytes.Buffer
+	err := printRevisions(&out, []functionRevision{
+		{Image: "acme/charge:0.2.0", Digest: "sha256:0123456789abcdef0123", Config: "a1b2c3d4e5f6", Deployed: time.Date(2023, 6, 1, 13, 0, 0, 0, time.UTC)},
+		{Image: "acme/charge:0.1.0", Config: "f6e5d4c3b2a1", Deployed: time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"REVISION", "2023-06-01T13:00:00Z", "acme/charge:0.2.0", "sha256:0123456789ab", "f6e5d4c3b2a1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	printRevisions(&out, nil)
+	if !strings.Contains(out.String(), "<none>") {
+		t.Errorf("want <none> without revisions, got: %s", out.String())
+	}
+}
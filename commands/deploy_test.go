@@ -15,6 +15,11 @@ import (
 
 func Test_deploy(t *testing.T) {
 	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/test-function?usage=1",
+			ResponseStatusCode: http.StatusNotFound,
+		},
 		{
 			Method:             http.MethodPut,
 			Uri:                "/system/functions",
@@ -0,0 +1,139 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// deployURLsOut is a file the URLs of the deployed functions are written to
+var deployURLsOut string
+
+func init() {
+	deployCmd.Flags().StringVar(&deployURLsOut, "urls-out", "", "Write the URLs of the deployed functions to a file, as JSON, env or markdown by its extension: .json, .env or .md")
+}
+
+// functionURL is written by deploy --urls-out for each function deployed
+type functionURL struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	URL       string `json:"url"`
+	AsyncURL  string `json:"async_url"`
+}
+
+// urlsFormat is the format of --urls-out, from the file's extension
+func urlsFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return "json", nil
+	case ".env":
+		return "env", nil
+	case ".md", ".markdown":
+		return "markdown", nil
+	default:
+		return "", fmt.Errorf("--urls-out must end in .json, .env or .md, got: %q", path)
+	}
+}
+
+// functionURLs builds the URLs of each function by its name, from the
+// namespace it was deployed to, which scopes the URL when it was given
+func functionURLs(gateway string, namespaces map[string]string) []functionURL {
+	names := make([]string, 0, len(namespaces))
+	for name := range namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	urls := make([]functionURL, 0, len(names))
+	for _, name := range names {
+		url, asyncURL := getFunctionURLs(gateway, name, namespaces[name])
+		urls = append(urls, functionURL{Name: name, Namespace: namespaces[name], URL: url, AsyncURL: asyncURL})
+	}
+	return urls
+}
+
+// writeURLsOut writes the URLs for --urls-out, of the functions which were
+// deployed, so those which failed are left out
+func writeURLsOut(gateway string, deployed map[string]string) error {
+	if len(deployURLsOut) == 0 {
+		return nil
+	}
+
+	if err := writeDeployURLs(deployURLsOut, functionURLs(gateway, deployed)); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the URLs of %d function(s) to: %s\n", len(deployed), deployURLsOut)
+	return nil
+}
+
+// writeDeployURLs writes the URLs to path in the format of its extension
+func writeDeployURLs(path string, urls []functionURL) error {
+	format, err := urlsFormat(path)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(urls); err != nil {
+			return err
+		}
+	case "env":
+		writeURLsEnv(&buf, urls)
+	case "markdown":
+		writeURLsMarkdown(&buf, urls)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("unable to write --urls-out: %w", err)
+	}
+	return nil
+}
+
+// writeURLsEnv writes NAME_URL and NAME_ASYNC_URL for each function, with
+// its name upper-cased and any character which can't be in a variable's name
+// replaced by an underscore
+func writeURLsEnv(w io.Writer, urls []functionURL) {
+	for _, u := range urls {
+		key := urlsEnvKey(u.Name)
+		fmt.Fprintf(w, "%s_URL=%s\n", key, u.URL)
+		fmt.Fprintf(w, "%s_ASYNC_URL=%s\n", key, u.AsyncURL)
+	}
+}
+
+func urlsEnvKey(name string) string {
+	key := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+
+	if len(key) > 0 && key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
+}
+
+func writeURLsMarkdown(w io.Writer, urls []functionURL) {
+	fmt.Fprintln(w, "| Function | Namespace | URL | Async URL |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- |")
+	for _, u := range urls {
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n", u.Name, valueOrDash(u.Namespace), u.URL, u.AsyncURL)
+	}
+}
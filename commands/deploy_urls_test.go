@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_urlsFormat(t *testing.T) {
+	cases := map[string]string{
+		"urls.json":          "json",
+		"out/.env":           "env",
+		"URLS.MD":            "markdown",
+		"docs/urls.markdown": "markdown",
+	}
+	for path, want := range cases {
+		got, err := urlsFormat(path)
+		if err != nil || got != want {
+			t.Errorf("%s: want %s, got: %s, %v", path, want, got, err)
+		}
+	}
+
+	if _, err := urlsFormat("urls.txt"); err == nil || !strings.Contains(err.Error(), ".json, .env or .md") {
+		t.Errorf("want an error for an unknown extension, got: %v", err)
+	}
+}
+
+func Test_functionURLs(t *testing.T) {
+	urls := functionURLs("http://127.0.0.1:8080/", map[string]string{"figlet": "staging", "env": ""})
+
+	want := []functionURL{
+		{Name: "env", URL: "http://127.0.0.1:8080/function/env", AsyncURL: "http://127.0.0.1:8080/async-function/env"},
+		{Name: "figlet", Namespace: "staging", URL: "http://127.0.0.1:8080/function/figlet.staging", AsyncURL: "http://127.0.0.1:8080/async-function/figlet.staging"},
+	}
+	if !reflect.DeepEqual(urls, want) {
+		t.Fatalf("want: %v, got: %v", want, urls)
+	}
+}
+
+func Test_writeDeployURLs(t *testing.T) {
+	dir := t.TempDir()
+	urls := functionURLs("http://gw:8080", map[string]string{"nodeinfo-2": "dev"})
+
+	jsonPath := filepath.Join(dir, "urls.json")
+	if err := writeDeployURLs(jsonPath, urls); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(jsonPath)
+	var got []functionURL
+	if err := json.Unmarshal(data, &got); err != nil || !reflect.DeepEqual(got, urls) {
+		t.Errorf("want the URLs as JSON, got: %s, %v", data, err)
+	}
+
+	envPath := filepath.Join(dir, "urls.env")
+	if err := writeDeployURLs(envPath, urls); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(envPath)
+	wantEnv := "NODEINFO_2_URL=http://gw:8080/function/nodeinfo-2.dev\nNODEINFO_2_ASYNC_URL=http://gw:8080/async-function/nodeinfo-2.dev\n"
+	if string(data) != wantEnv {
+		t.Errorf("want: %q, got: %q", wantEnv, data)
+	}
+
+	mdPath := filepath.Join(dir, "urls.md")
+	if err := writeDeployURLs(mdPath, urls); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(mdPath)
+	if !strings.Contains(string(data), "| nodeinfo-2 | dev | http://gw:8080/function/nodeinfo-2.dev | http://gw:8080/async-function/nodeinfo-2.dev |") {
+		t.Errorf("want a markdown row for the function, got: %s", data)
+	}
+}
+
+func Test_urlsEnvKey(t *testing.T) {
+	cases := map[string]string{
+		"figlet":     "FIGLET",
+		"my.fn-name": "MY_FN_NAME",
+		"2fa":        "_2FA",
+	}
+	for name, want := range cases {
+		if got := urlsEnvKey(name); got != want {
+			t.Errorf("%s: want %s, got: %s", name, want, got)
+		}
+	}
+}
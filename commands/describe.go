@@ -14,6 +14,7 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"github.com/openfaas/faas-cli/output"
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/schema"
 	"github.com/openfaas/faas-cli/stack"
@@ -22,6 +23,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var describeHistory bool
+
 func init() {
 	describeCmd.Flags().StringVar(&functionName, "name", "", "Name of the function")
 	describeCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
@@ -30,6 +33,7 @@ func init() {
 	describeCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
 	describeCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
 	describeCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	describeCmd.Flags().BoolVar(&describeHistory, "history", false, "Show the versions of the function recorded by deploy")
 
 	faasCmd.AddCommand(describeCmd)
 }
@@ -37,8 +41,13 @@ func init() {
 var describeCmd = &cobra.Command{
 	Use:   "describe FUNCTION_NAME [--gateway GATEWAY_URL]",
 	Short: "Describe an OpenFaaS function",
-	Long:  `Display details of an OpenFaaS function`,
+	Long: `Display details of an OpenFaaS function
+
+With --history, the versions of the function which deploy recorded in its
+` + revisionsAnnotation + ` annotation are listed too, newest first. Revision 0
+is the one which is deployed.`,
 	Example: `faas-cli describe figlet
+faas-cli describe figlet --history
 faas-cli describe env --gateway http://127.0.0.1:8080
 faas-cli describe echo -g http://127.0.0.1.8080`,
 	PreRunE: preRunDescribe,
@@ -117,6 +126,15 @@ func runDescribe(cmd *cobra.Command, args []string) error {
 
 	printFunctionDescription(cmd.OutOrStdout(), funcDesc, verbose)
 
+	if describeHistory {
+		var annotations map[string]string
+		if function.Annotations != nil {
+			annotations = *function.Annotations
+		}
+		fmt.Fprintln(cmd.OutOrStdout())
+		return printRevisions(cmd.OutOrStdout(), parseRevisions(annotations))
+	}
+
 	return nil
 }
 
@@ -149,10 +167,10 @@ func printFunctionDescription(dst io.Writer, funcDesc schema.FunctionDescription
 	}
 
 	out.Printf("Name:\t%s\n", funcDesc.Name)
-	out.Printf("Status:\t%s\n", funcDesc.Status)
+	out.Printf("Status:\t%s\n", colorFunctionStatus(funcDesc.Status))
 	out.Printf("Replicas:\t%s\n", strconv.Itoa(int(funcDesc.Replicas)))
 	out.Printf("Available Replicas:\t%s\n", strconv.Itoa(int(funcDesc.AvailableReplicas)))
-	out.Printf("Invocations:\t%s\n", strconv.Itoa(int(funcDesc.InvocationCount)))
+	out.Printf("Invocations:\t%s\n", output.Count(int64(funcDesc.InvocationCount)))
 	out.Printf("Image:\t%s\n", funcDesc.Image)
 	out.Printf("Function Process:\t%s\n", process)
 	out.Printf("URL:\t%s\n", funcDesc.URL)
@@ -163,7 +181,7 @@ func printFunctionDescription(dst io.Writer, funcDesc schema.FunctionDescription
 		out.Printf("Labels", map[string]string{})
 	}
 	if funcDesc.Annotations != nil {
-		out.Printf("Annotations", *funcDesc.Annotations)
+		out.Printf("Annotations", withoutRevisions(*funcDesc.Annotations))
 	} else {
 		out.Printf("Annotations", map[string]string{})
 	}
@@ -175,6 +193,13 @@ func printFunctionDescription(dst io.Writer, funcDesc schema.FunctionDescription
 	out.Printf("", funcDesc.Usage)
 }
 
+func colorFunctionStatus(status string) string {
+	if status == "Ready" {
+		return output.Success(status)
+	}
+	return output.Warning(status)
+}
+
 type printer struct {
 	verbose bool
 	w       io.Writer
@@ -215,7 +240,11 @@ func printUsage(w io.Writer, usage *types.FunctionUsage, verbose bool) {
 	}
 
 	fmt.Fprintln(w, "Usage:")
-	fmt.Fprintf(w, "  RAM:\t %.2f MB\n", (usage.TotalMemoryBytes / 1024 / 1024))
+	if output.Humanized() {
+		fmt.Fprintf(w, "  RAM:\t %s\n", output.Size(usage.TotalMemoryBytes))
+	} else {
+		fmt.Fprintf(w, "  RAM:\t %.2f MB\n", (usage.TotalMemoryBytes / 1024 / 1024))
+	}
 	cpu := usage.CPU
 	if cpu < 0 {
 		cpu = 1
@@ -0,0 +1,249 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/util"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	devFormatDevcontainer = "devcontainer"
+	devFormatCompose      = "compose"
+
+	containerSecretsPath = "/var/openfaas/secrets"
+)
+
+var (
+	devFormat string
+	devOutput string
+)
+
+func init() {
+	devInitCmd.Flags().StringVar(&devFormat, "format", devFormatDevcontainer, "Format of the generated config: devcontainer or compose")
+	devInitCmd.Flags().StringVarP(&devOutput, "output", "o", "", "File to write, defaults to .devcontainer/NAME/devcontainer.json or docker-compose.NAME.dev.yml")
+	devInitCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+
+	devCmd.AddCommand(devInitCmd)
+	faasCmd.AddCommand(devCmd)
+}
+
+var devCmd = &cobra.Command{
+	Use:   `dev`,
+	Short: "Local development environment helpers",
+	Long:  "Generate configuration for editing functions against their template's runtime",
+}
+
+var devInitCmd = &cobra.Command{
+	Use:   `init FUNCTION_NAME [-f YAML_FILE] [--format devcontainer|compose] [--output FILE]`,
+	Short: "Generate a dev container for a function",
+	Long: `Generates a devcontainer.json or a docker compose override for a function.
+
+The image is the stage of the template's Dockerfile into which the handler is
+copied, so the toolchain matches the one used by "faas-cli build". The handler
+folder is mounted at the same path that the template copies it to, along with
+the function's environment and the local .secrets folder.`,
+	Example: `  faas-cli dev init stronghash
+  faas-cli dev init stronghash --format compose
+  faas-cli dev init stronghash -f ./stronghash.yml -o .devcontainer.json`,
+	PreRunE: preRunDevInit,
+	RunE:    runDevInit,
+}
+
+// devEnvironment is the runtime that a function's handler is developed against
+type devEnvironment struct {
+	Name        string
+	Image       string
+	HandlerDir  string
+	HandlerPath string
+	Environment map[string]string
+	Secrets     bool
+}
+
+func preRunDevInit(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the name of a single function")
+	}
+
+	if devFormat != devFormatDevcontainer && devFormat != devFormatCompose {
+		return fmt.Errorf("--format must be one of: %s, %s", devFormatDevcontainer, devFormatCompose)
+	}
+
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("give a stack file with --yaml/-f")
+	}
+
+	return nil
+}
+
+func runDevInit(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	services, err := stack.ParseYAMLFile(yamlFile, "", "", envsubst)
+	if err != nil {
+		return err
+	}
+
+	function, ok := services.Functions[name]
+	if !ok {
+		return fmt.Errorf("function %s was not found in %s", name, yamlFile)
+	}
+	function.Name = name
+
+	env, err := newDevEnvironment(function)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	file := devOutput
+	switch devFormat {
+	case devFormatCompose:
+		out, err = env.compose()
+		if len(file) == 0 {
+			file = fmt.Sprintf("docker-compose.%s.dev.yml", name)
+		}
+	default:
+		out, err = env.devcontainer()
+		if len(file) == 0 {
+			file = filepath.Join(".devcontainer", name, "devcontainer.json")
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(file); err == nil {
+		return fmt.Errorf("file: %s already exists", file)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(file, out, 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote: %s (image: %s, handler: %s)\n", file, env.Image, env.HandlerPath)
+	return nil
+}
+
+func newDevEnvironment(function stack.Function) (*devEnvironment, error) {
+	if !languageExistsNotDockerfile(function.Language) {
+		return nil, fmt.Errorf("function %s does not use a language template", function.Name)
+	}
+
+	langTemplate, err := stack.LoadLanguageTemplate(function.Language)
+	if err != nil {
+		return nil, fmt.Errorf(`template directory may be missing or invalid, please run "faas-cli template pull"
+Error: %s`, err.Error())
+	}
+
+	dockerfile, err := builder.LoadTemplateDockerfile(function.Language, langTemplate.HandlerFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	stage, err := dockerfile.HandlerStage()
+	if err != nil {
+		return nil, err
+	}
+
+	handlerPath := stage.HandlerPath
+	if len(handlerPath) == 0 {
+		handlerPath = path.Join(stage.WorkDir, "function")
+	}
+
+	fileEnvironment, err := readFiles(function.EnvironmentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &devEnvironment{
+		Name:        function.Name,
+		Image:       stage.Image,
+		HandlerDir:  filepath.ToSlash(filepath.Clean(function.Handler)),
+		HandlerPath: handlerPath,
+		Environment: util.MergeMap(function.Environment, fileEnvironment),
+		Secrets:     len(function.Secrets) > 0,
+	}, nil
+}
+
+// devcontainer renders the environment as a VS Code / devcontainers spec file,
+// which is expected to be opened from the folder holding the stack file.
+func (d devEnvironment) devcontainer() ([]byte, error) {
+	config := map[string]interface{}{
+		"name":            d.Name,
+		"image":           d.Image,
+		"workspaceMount":  fmt.Sprintf("source=${localWorkspaceFolder}/%s,target=%s,type=bind", d.HandlerDir, d.HandlerPath),
+		"workspaceFolder": d.HandlerPath,
+		"forwardPorts":    []int{8080},
+	}
+
+	if len(d.Environment) > 0 {
+		config["containerEnv"] = d.Environment
+	}
+
+	if d.Secrets {
+		config["mounts"] = []string{
+			fmt.Sprintf("source=${localWorkspaceFolder}/%s,target=%s,type=bind,readonly", localSecretsDir, containerSecretsPath),
+		}
+	}
+
+	out, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
+
+type devComposeFile struct {
+	Services map[string]devComposeService `yaml:"services"`
+}
+
+type devComposeService struct {
+	Image       string            `yaml:"image"`
+	WorkingDir  string            `yaml:"working_dir"`
+	Command     []string          `yaml:"command"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Volumes     []string          `yaml:"volumes"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+}
+
+// compose renders the environment as a docker compose file, the container is kept
+// running so that an editor or shell can be attached to it.
+func (d devEnvironment) compose() ([]byte, error) {
+	volumes := []string{fmt.Sprintf("./%s:%s", strings.TrimPrefix(d.HandlerDir, "./"), d.HandlerPath)}
+	if d.Secrets {
+		volumes = append(volumes, fmt.Sprintf("./%s:%s:ro", localSecretsDir, containerSecretsPath))
+	}
+
+	file := devComposeFile{
+		Services: map[string]devComposeService{
+			d.Name: {
+				Image:       d.Image,
+				WorkingDir:  d.HandlerPath,
+				Command:     []string{"sleep", "infinity"},
+				Ports:       []string{"8080:8080"},
+				Volumes:     volumes,
+				Environment: d.Environment,
+			},
+		},
+	}
+
+	return yaml.Marshal(file)
+}
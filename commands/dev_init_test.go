@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_devEnvironment_devcontainer(t *testing.T) {
+	env := devEnvironment{
+		Name:        "stronghash",
+		Image:       "python:3.10-alpine",
+		HandlerDir:  "stronghash",
+		HandlerPath: "/home/app/function",
+		Environment: map[string]string{"write_debug": "true"},
+		Secrets:     true,
+	}
+
+	out, err := env.devcontainer()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		t.Fatalf("invalid JSON: %s", err)
+	}
+
+	if parsed["image"] != "python:3.10-alpine" {
+		t.Errorf("unexpected image: %v", parsed["image"])
+	}
+	if parsed["workspaceFolder"] != "/home/app/function" {
+		t.Errorf("unexpected workspaceFolder: %v", parsed["workspaceFolder"])
+	}
+
+	want := "source=${localWorkspaceFolder}/stronghash,target=/home/app/function,type=bind"
+	if parsed["workspaceMount"] != want {
+		t.Errorf("want workspaceMount %s, got %v", want, parsed["workspaceMount"])
+	}
+
+	mounts, _ := parsed["mounts"].([]interface{})
+	if len(mounts) != 1 || !strings.Contains(mounts[0].(string), "target=/var/openfaas/secrets") {
+		t.Errorf("want secrets mount, got %v", parsed["mounts"])
+	}
+}
+
+func Test_devEnvironment_compose(t *testing.T) {
+	env := devEnvironment{
+		Name:        "stronghash",
+		Image:       "python:3.10-alpine",
+		HandlerDir:  "./functions/stronghash",
+		HandlerPath: "/home/app/function",
+	}
+
+	out, err := env.compose()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got := string(out)
+	for _, want := range []string{
+		"stronghash:",
+		"image: python:3.10-alpine",
+		"working_dir: /home/app/function",
+		"- ./functions/stronghash:/home/app/function",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in output:\n%s", want, got)
+		}
+	}
+
+	if strings.Contains(got, "secrets") {
+		t.Errorf("want no secrets mount:\n%s", got)
+	}
+}
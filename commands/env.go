@@ -0,0 +1,18 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	faasCmd.AddCommand(envCmd)
+}
+
+var envCmd = &cobra.Command{
+	Use:   `env`,
+	Short: "OpenFaaS environment variable commands",
+	Long:  "Inspect and rename the environment variables of functions in a stack file",
+}
@@ -0,0 +1,306 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+const (
+	auditIssueConflict = "conflict"
+	auditIssueReserved = "reserved"
+	auditIssueSecret   = "secret"
+	auditIssueTypo     = "typo"
+)
+
+// watchdogEnvironment lists the environment variables read by the classic
+// watchdog and of-watchdog, rather than by the function's own code.
+var watchdogEnvironment = map[string]bool{
+	"fprocess":             true,
+	"function_process":     true,
+	"mode":                 true,
+	"upstream_url":         true,
+	"port":                 true,
+	"read_timeout":         true,
+	"write_timeout":        true,
+	"exec_timeout":         true,
+	"healthcheck_interval": true,
+	"max_inflight":         true,
+	"prefix_logs":          true,
+	"log_buffer_size":      true,
+	"suppress_lock":        true,
+	"content_type":         true,
+	"write_debug":          true,
+	"combine_output":       true,
+	"buffer_http":          true,
+	"static_path":          true,
+	"ready_path":           true,
+	"jwt_auth":             true,
+	"jwt_auth_debug":       true,
+	"jwt_auth_local":       true,
+	"cgi_headers":          true,
+	"marshal_request":      true,
+	"RAW_BODY":             true,
+}
+
+var (
+	secretNamePattern  = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api[_-]?key|private[_-]?key|access[_-]?key|credential)`)
+	secretValuePattern = regexp.MustCompile(`^(AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{20,}|sk_(live|test)_[A-Za-z0-9]{10,}|xox[baprs]-[A-Za-z0-9-]{10,}|eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+|-----BEGIN [A-Z ]*PRIVATE KEY-----)`)
+)
+
+var envAuditOutput string
+
+func init() {
+	envAuditCmd.Flags().StringVarP(&envAuditOutput, "output", "o", "table", "Output format: table or json")
+	envAuditCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+
+	envCmd.AddCommand(envAuditCmd)
+}
+
+var envAuditCmd = &cobra.Command{
+	Use:   `audit [-f YAML_FILE] [--output table|json]`,
+	Short: "Audit environment variables across a stack",
+	Long: `Lists every environment variable set for the functions in a stack file,
+including those read from environment_file entries, and flags:
+
+- conflict  the same variable is given different values by different functions
+- reserved  the variable is set by the function's template, and deploy will refuse it
+- secret    the name or value looks like a credential, use a secret instead
+- typo      the name is close to, but not the same as, a watchdog setting
+
+Values flagged as secrets are masked in the output.`,
+	Example: `  faas-cli env audit
+  faas-cli env audit -f ./stack.yml --output json
+  faas-cli env audit --filter "api-*"`,
+	PreRunE: preRunEnvAudit,
+	RunE:    runEnvAudit,
+}
+
+// envAuditEntry is a single environment variable as seen by a single function
+type envAuditEntry struct {
+	Function string   `json:"function"`
+	Name     string   `json:"name"`
+	Value    string   `json:"value"`
+	Source   string   `json:"source"`
+	Watchdog bool     `json:"watchdog"`
+	Issues   []string `json:"issues,omitempty"`
+	Hint     string   `json:"hint,omitempty"`
+}
+
+func preRunEnvAudit(cmd *cobra.Command, args []string) error {
+	if envAuditOutput != "table" && envAuditOutput != "json" {
+		return fmt.Errorf("--output must be one of: table, json")
+	}
+
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("give a stack file with --yaml/-f")
+	}
+
+	return nil
+}
+
+func runEnvAudit(cmd *cobra.Command, args []string) error {
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst)
+	if err != nil {
+		return err
+	}
+
+	entries, err := auditEnvironment(services.Functions)
+	if err != nil {
+		return err
+	}
+
+	if envAuditOutput == "json" {
+		out, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(out))
+		return nil
+	}
+
+	printEnvAudit(cmd.OutOrStdout(), entries)
+	return nil
+}
+
+// auditEnvironment collects the environment of each function, in the same order of
+// precedence as deploy, and annotates each variable with any issues found.
+func auditEnvironment(functions map[string]stack.Function) ([]envAuditEntry, error) {
+	entries := []envAuditEntry{}
+	values := map[string]map[string]bool{}
+
+	for name, function := range functions {
+		sources := map[string]string{}
+		merged := map[string]string{}
+
+		for k, v := range function.Environment {
+			merged[k] = v
+			sources[k] = "environment"
+		}
+
+		for _, file := range function.EnvironmentFile {
+			fileEnvironment, err := readFiles([]string{file})
+			if err != nil {
+				return nil, err
+			}
+
+			for k, v := range fileEnvironment {
+				merged[k] = v
+				sources[k] = file
+			}
+		}
+
+		reserved := map[string]bool{}
+		if languageExistsNotDockerfile(function.Language) {
+			var err error
+			if reserved, err = templateReservedEnvironment(function.Language); err != nil {
+				return nil, err
+			}
+		}
+
+		for k, v := range merged {
+			entry := envAuditEntry{
+				Function: name,
+				Name:     k,
+				Value:    v,
+				Source:   sources[k],
+				Watchdog: watchdogEnvironment[k],
+			}
+			if reserved[k] {
+				entry.Issues = append(entry.Issues, auditIssueReserved)
+			}
+			entries = append(entries, entry)
+
+			if _, ok := values[k]; !ok {
+				values[k] = map[string]bool{}
+			}
+			values[k][v] = true
+		}
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+
+		if len(values[entry.Name]) > 1 {
+			entry.Issues = append(entry.Issues, auditIssueConflict)
+		}
+
+		if looksLikeSecret(entry.Name, entry.Value) {
+			entry.Issues = append(entry.Issues, auditIssueSecret)
+			entry.Value = maskValue(entry.Value)
+		}
+
+		if !entry.Watchdog {
+			if suggestion := closestWatchdogSetting(entry.Name); len(suggestion) > 0 {
+				entry.Issues = append(entry.Issues, auditIssueTypo)
+				entry.Hint = fmt.Sprintf("did you mean %s?", suggestion)
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name == entries[j].Name {
+			return entries[i].Function < entries[j].Function
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+func printEnvAudit(w io.Writer, entries []envAuditEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tFUNCTION\tVALUE\tSOURCE\tISSUES")
+	for _, entry := range entries {
+		issues := strings.Join(entry.Issues, ",")
+		if len(entry.Hint) > 0 {
+			issues += " (" + entry.Hint + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", entry.Name, entry.Function, entry.Value, entry.Source, issues)
+	}
+}
+
+func looksLikeSecret(name, value string) bool {
+	if len(value) == 0 {
+		return false
+	}
+	return secretNamePattern.MatchString(name) || secretValuePattern.MatchString(value)
+}
+
+func maskValue(value string) string {
+	if len(value) <= 4 {
+		return "****"
+	}
+	return value[:2] + strings.Repeat("*", 6) + value[len(value)-2:]
+}
+
+// closestWatchdogSetting returns a watchdog setting within a small edit distance
+// of name, ignoring case, or an empty string if there is none.
+func closestWatchdogSetting(name string) string {
+	lower := strings.ToLower(name)
+	best, bestDistance := "", 3
+
+	for setting := range watchdogEnvironment {
+		if strings.ToLower(setting) == lower {
+			return setting
+		}
+
+		// Short names such as "port" and "mode" produce too many false positives
+		if len(setting) < 6 {
+			continue
+		}
+
+		if d := levenshtein(lower, strings.ToLower(setting)); d < bestDistance || (d == bestDistance && setting < best) {
+			best, bestDistance = setting, d
+		}
+	}
+
+	if bestDistance > 2 {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,259 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// envNamePattern matches the names which can be given to environment variables
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var envRenameDryRun bool
+
+func init() {
+	envRenameCmd.Flags().BoolVar(&envRenameDryRun, "dry-run", false, "Print what would be renamed without changing any files")
+
+	envCmd.AddCommand(envRenameCmd)
+}
+
+var envRenameCmd = &cobra.Command{
+	Use:   `rename OLD NEW [-f YAML_FILE] [--dry-run]`,
+	Short: "Rename an environment variable across a stack",
+	Long: `Renames the environment variable OLD to NEW in the environment of each
+function in the stack file, and in every environment_file which the functions
+read, so that they stay consistent. Only the keys are changed, the values,
+comments and layout of the files are kept.
+
+The rename is refused when NEW is already set alongside OLD. The function's
+code is not changed, find where it reads the variable with:
+faas-cli grep OLD`,
+	Example: `  faas-cli env rename REDIS_HOST CACHE_HOST
+  faas-cli env rename db_url DATABASE_URL -f ./stack.yml --dry-run`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("give the OLD and NEW names of the environment variable")
+		}
+		for _, name := range args {
+			if !envNamePattern.MatchString(name) {
+				return fmt.Errorf("%q is not a valid name for an environment variable", name)
+			}
+		}
+		if args[0] == args[1] {
+			return fmt.Errorf("the OLD and NEW names are the same")
+		}
+		if len(yamlFile) == 0 {
+			return fmt.Errorf("give a stack file with --yaml/-f")
+		}
+		return nil
+	},
+	RunE: runEnvRename,
+}
+
+// envRenameEdit is a file with the variable renamed
+type envRenameEdit struct {
+	path    string
+	mode    os.FileMode
+	content string
+	renamed int
+}
+
+func runEnvRename(cmd *cobra.Command, args []string) error {
+	oldName, newName := args[0], args[1]
+
+	// Every function is renamed, as the stack file is edited as a whole
+	services, err := stack.ParseYAMLFile(yamlFile, "", "", false)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(services.Functions))
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := []string{yamlFile}
+	seen := map[string]bool{yamlFile: true}
+	for _, name := range names {
+		for _, file := range services.Functions[name].EnvironmentFile {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	var edits []envRenameEdit
+	for _, file := range files {
+		edit, err := renameEnvInFile(file, oldName, newName, file == yamlFile)
+		if err != nil {
+			return err
+		}
+		if edit.renamed > 0 {
+			edits = append(edits, edit)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if len(edits) == 0 {
+		return fmt.Errorf("%s is not set in %s, or its environment files", oldName, yamlFile)
+	}
+
+	verb := "Renamed"
+	if envRenameDryRun {
+		verb = "Would rename"
+	}
+	for _, edit := range edits {
+		if !envRenameDryRun {
+			if err := os.WriteFile(edit.path, []byte(edit.content), edit.mode); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(out, "%s %s to %s in: %s (%d)\n", verb, oldName, newName, edit.path, edit.renamed)
+	}
+
+	fmt.Fprintf(out, "\nFind where the functions read it with: faas-cli grep %s\n", oldName)
+	return nil
+}
+
+// renameEnvInFile renames the key in the file, then parses the result to
+// check that no environment still sets the old name, which is the case for
+// the flow style of YAML, e.g. environment: {OLD: value}
+func renameEnvInFile(path, oldName, newName string, stackFile bool) (envRenameEdit, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return envRenameEdit{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return envRenameEdit{}, err
+	}
+
+	lines, renamed, err := renameEnvKey(strings.Split(string(data), "\n"), oldName, newName)
+	if err != nil {
+		return envRenameEdit{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	content := strings.Join(lines, "\n")
+	remaining, err := envKeyUsers([]byte(content), oldName, stackFile)
+	if err != nil {
+		return envRenameEdit{}, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	if len(remaining) > 0 {
+		return envRenameEdit{}, fmt.Errorf("%s: %s is set where it can't be renamed automatically, for: %s", path, oldName, strings.Join(remaining, ", "))
+	}
+
+	return envRenameEdit{path: path, mode: info.Mode().Perm(), content: content, renamed: renamed}, nil
+}
+
+// renameEnvKey renames the key within each block style environment: mapping
+func renameEnvKey(lines []string, oldName, newName string) ([]string, int, error) {
+	environment := regexp.MustCompile(`^(\s*)environment:\s*(#.*)?$`)
+	keyPattern := regexp.MustCompile(`^(\s*)(["']?)([^"':\s]+)(["']?)(\s*:.*)$`)
+
+	out := make([]string, len(lines))
+	copy(out, lines)
+
+	renamed := 0
+	blockIndent, keyIndent := -1, -1
+	oldLine, newLine := -1, -1
+
+	closeBlock := func() error {
+		if oldLine > -1 && newLine > -1 {
+			return fmt.Errorf("can't rename %s on line %d, as %s is already set on line %d", oldName, oldLine+1, newName, newLine+1)
+		}
+		if oldLine > -1 {
+			match := keyPattern.FindStringSubmatch(out[oldLine])
+			out[oldLine] = match[1] + match[2] + newName + match[4] + match[5]
+			renamed++
+		}
+		blockIndent, keyIndent, oldLine, newLine = -1, -1, -1, -1
+		return nil
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		if blockIndent > -1 && indent <= blockIndent {
+			if err := closeBlock(); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		if match := environment.FindStringSubmatch(line); match != nil {
+			blockIndent = len(match[1])
+			continue
+		}
+		if blockIndent < 0 {
+			continue
+		}
+
+		if keyIndent < 0 {
+			keyIndent = indent
+		}
+		if indent != keyIndent {
+			continue
+		}
+
+		if match := keyPattern.FindStringSubmatch(line); match != nil {
+			switch match[3] {
+			case oldName:
+				oldLine = i
+			case newName:
+				newLine = i
+			}
+		}
+	}
+
+	if err := closeBlock(); err != nil {
+		return nil, 0, err
+	}
+	return out, renamed, nil
+}
+
+// envKeyUsers lists the functions of a stack file, or the environment file,
+// which still set the key
+func envKeyUsers(data []byte, key string, stackFile bool) ([]string, error) {
+	if !stackFile {
+		envFile := stack.EnvironmentFile{}
+		if err := yaml.Unmarshal(data, &envFile); err != nil {
+			return nil, err
+		}
+		if _, ok := envFile.Environment[key]; ok {
+			return []string{"environment"}, nil
+		}
+		return nil, nil
+	}
+
+	var parsed struct {
+		Functions map[string]struct {
+			Environment map[string]string `yaml:"environment"`
+		} `yaml:"functions"`
+	}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	var users []string
+	for name, function := range parsed.Functions {
+		if _, ok := function.Environment[key]; ok {
+			users = append(users, name)
+		}
+	}
+	sort.Strings(users)
+	return users, nil
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_renameEnvKey(t *testing.T) {
+	lines := strings.Split(`functions:
+  orders:
+    environment:
+      # the cache
+      redis_host: redis # local
+      redis_port: 6379
+    labels:
+      redis_host: keep
+  payments:
+    environment:
+      "redis_host": redis-replica`, "\n")
+
+	out, renamed, err := renameEnvKey(lines, "redis_host", "CACHE_HOST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed != 2 {
+		t.Fatalf("want 2 renames, got: %d", renamed)
+	}
+
+	got := strings.Join(out, "\n")
+	for _, want := range []string{"      CACHE_HOST: redis # local", "      redis_host: keep", `      "CACHE_HOST": redis-replica`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+}
+
+func Test_renameEnvKey_RefusesExistingName(t *testing.T) {
+	lines := strings.Split(`environment:
+  redis_host: redis
+  CACHE_HOST: cache`, "\n")
+
+	_, _, err := renameEnvKey(lines, "redis_host", "CACHE_HOST")
+	if err == nil || !strings.Contains(err.Error(), "CACHE_HOST is already set on line 3") {
+		t.Fatalf("want an error as the new name is set, got: %v", err)
+	}
+}
+
+func Test_runEnvRename_StackAndEnvironmentFiles(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "common.yml")
+	if err := os.WriteFile(envFile, []byte("environment:\n  redis_host: redis\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	stackYAML := `version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    image: acme/orders:latest
+    environment:
+      redis_host: redis
+    environment_file:
+      - ` + envFile + `
+  payments:
+    image: acme/payments:latest
+    environment: {redis_host: redis}
+`
+	if err := os.WriteFile(stackFile, []byte(stackYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	faasCmd.SetOut(&buf)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs([]string{"env", "rename", "redis_host", "CACHE_HOST", "-f", stackFile})
+	err := faasCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "can't be renamed automatically, for: payments") {
+		t.Fatalf("want an error for the flow style environment, got: %v", err)
+	}
+
+	stackYAML = strings.Replace(stackYAML, "environment: {redis_host: redis}", "environment:\n      redis_host: redis", 1)
+	if err := os.WriteFile(stackFile, []byte(stackYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	faasCmd.SetArgs([]string{"env", "rename", "redis_host", "CACHE_HOST", "-f", stackFile})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "Renamed redis_host to CACHE_HOST in: "+stackFile+" (2)") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	for _, file := range []string{stackFile, envFile} {
+		data, _ := os.ReadFile(file)
+		if strings.Contains(string(data), "redis_host") || !strings.Contains(string(data), "CACHE_HOST: redis") {
+			t.Errorf("want %s renamed, got:\n%s", file, string(data))
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+const (
+	execProviderKubernetes = "kubernetes"
+	execProviderFaasd      = "faasd"
+
+	// execDefaultNamespace is where both providers run functions by default
+	execDefaultNamespace = "openfaas-fn"
+)
+
+// execTarget describes how to reach the replicas of a function, either with
+// kubectl and the current kubeconfig, or with SSH to a faasd host
+type execTarget struct {
+	provider    string
+	namespace   string
+	kubeContext string
+	sshHost     string
+}
+
+var (
+	execFlags execTarget
+	allowExec bool
+	execTTY   bool
+)
+
+func init() {
+	addExecTargetFlags(execCmd, &execFlags)
+	execCmd.Flags().BoolVar(&allowExec, "allow-exec", false, "Confirm that a command may be run inside a function's container")
+	execCmd.Flags().BoolVarP(&execTTY, "tty", "t", true, "Allocate a TTY when stdin is a terminal")
+
+	markMutating(execCmd)
+	faasCmd.AddCommand(execCmd)
+}
+
+func addExecTargetFlags(cmd *cobra.Command, target *execTarget) {
+	cmd.Flags().StringVar(&target.provider, "provider", execProviderKubernetes, "How to reach the function: kubernetes (kubectl) or faasd (ssh)")
+	cmd.Flags().StringVarP(&target.namespace, "namespace", "n", execDefaultNamespace, "Namespace of the function")
+	cmd.Flags().StringVar(&target.kubeContext, "context", "", "kubeconfig context to use with kubectl")
+	cmd.Flags().StringVar(&target.sshHost, "ssh", "", "SSH destination of the faasd host, e.g. ubuntu@faasd.example.com")
+}
+
+var execCmd = &cobra.Command{
+	Use:   `exec FUNCTION_NAME --allow-exec [--provider kubernetes|faasd] -- COMMAND [ARGS...]`,
+	Short: "Run a command in a running function replica",
+	Long: `Runs a command, or an interactive shell, inside a running replica of a function.
+
+This bypasses the gateway and requires direct access to the cluster, through
+kubectl and a kubeconfig for Kubernetes, or SSH for faasd where "ctr" is run
+with sudo. Anything done to the container is lost when it is restarted, and
+changes to a production function can be risky, so --allow-exec must be given.`,
+	Example: `  faas-cli exec env-dump --allow-exec -- sh
+  faas-cli exec env-dump --allow-exec -n staging -- cat /proc/1/status
+  faas-cli exec env-dump --allow-exec --provider faasd --ssh ubuntu@faasd -- sh`,
+	PreRunE: preRunExec,
+	RunE:    runExec,
+}
+
+func preRunExec(cmd *cobra.Command, args []string) error {
+	if !allowExec {
+		return fmt.Errorf("running commands in a function's container must be confirmed with --allow-exec")
+	}
+
+	if len(args) < 2 || cmd.ArgsLenAtDash() != 1 {
+		return fmt.Errorf("give the name of a function, then a command after --")
+	}
+
+	return execFlags.validate()
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	interactive := term.IsTerminal(os.Stdin.Fd())
+	argv := execFlags.command(args[0], args[1:], true, execTTY && interactive)
+
+	return runExecTransport(argv, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (t execTarget) validate() error {
+	switch t.provider {
+	case execProviderKubernetes:
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("kubectl must be installed to use --provider %s", execProviderKubernetes)
+		}
+	case execProviderFaasd:
+		if len(t.sshHost) == 0 {
+			return fmt.Errorf("give the faasd host with --ssh")
+		}
+	default:
+		return fmt.Errorf("--provider must be one of: %s, %s", execProviderKubernetes, execProviderFaasd)
+	}
+	return nil
+}
+
+// command returns the command line which runs command within a replica of the
+// function. For Kubernetes, kubectl picks a pod of the function's deployment.
+func (t execTarget) command(name string, command []string, stdin, tty bool) []string {
+	if t.provider == execProviderFaasd {
+		ctr := []string{"sudo", "ctr", "--namespace", t.namespace, "task", "exec",
+			"--exec-id", fmt.Sprintf("faas-cli-%d", time.Now().UnixNano())}
+		if tty {
+			ctr = append(ctr, "--tty")
+		}
+		ctr = append(ctr, name)
+		ctr = append(ctr, command...)
+
+		argv := []string{"ssh"}
+		if tty {
+			argv = append(argv, "-t")
+		}
+		return append(argv, t.sshHost, shellQuoteArgs(ctr))
+	}
+
+	argv := []string{"kubectl"}
+	if len(t.kubeContext) > 0 {
+		argv = append(argv, "--context", t.kubeContext)
+	}
+	argv = append(argv, "exec", "--namespace", t.namespace)
+	if stdin {
+		argv = append(argv, "-i")
+	}
+	if tty {
+		argv = append(argv, "-t")
+	}
+	argv = append(argv, "deploy/"+name, "--")
+	return append(argv, command...)
+}
+
+// shellQuoteArgs joins args into a single string for a remote shell
+func shellQuoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if len(arg) > 0 && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:") == "" {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+func runExecTransport(argv []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := exec.Command(argv[0], argv[1:]...)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	if err := c.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("command exited with code: %d", exitErr.ExitCode())
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_execTarget_command_Kubernetes(t *testing.T) {
+	target := execTarget{provider: execProviderKubernetes, namespace: "openfaas-fn", kubeContext: "prod"}
+
+	got := target.command("env-dump", []string{"cat", "/etc/hostname"}, true, false)
+	want := []string{"kubectl", "--context", "prod", "exec", "--namespace", "openfaas-fn", "-i", "deploy/env-dump", "--", "cat", "/etc/hostname"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func Test_execTarget_command_Faasd(t *testing.T) {
+	target := execTarget{provider: execProviderFaasd, namespace: "openfaas-fn", sshHost: "ubuntu@faasd"}
+
+	got := target.command("env-dump", []string{"sh", "-c", "echo 'hi'"}, true, true)
+
+	if got[0] != "ssh" || got[1] != "-t" || got[2] != "ubuntu@faasd" {
+		t.Fatalf("want ssh -t ubuntu@faasd, got %v", got)
+	}
+
+	remote := got[3]
+	for _, want := range []string{"sudo ctr --namespace openfaas-fn task exec --exec-id faas-cli-", "--tty env-dump sh -c 'echo '\"'\"'hi'\"'\"''"} {
+		if !strings.Contains(remote, want) {
+			t.Errorf("want %q in remote command: %s", want, remote)
+		}
+	}
+}
+
+func Test_execTarget_validate(t *testing.T) {
+	if err := (execTarget{provider: execProviderFaasd}).validate(); err == nil {
+		t.Errorf("want an error for faasd without --ssh")
+	}
+
+	if err := (execTarget{provider: "swarm"}).validate(); err == nil {
+		t.Errorf("want an error for an unknown provider")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/util"
+)
+
+// explainEnv prints where each environment variable of a function came from
+var explainEnv bool
+
+// envLayer is one source of environment variables, later layers take precedence
+type envLayer struct {
+	source string
+	values map[string]string
+}
+
+// explainedVariable is the final value of a variable, and the sources it overrode
+type explainedVariable struct {
+	Name       string
+	Value      string
+	Source     string
+	Overridden []string
+}
+
+// functionEnvLayers returns the layers of a function's stack file entry, in
+// the order of precedence used by deploy and local-run: environment, then each
+// environment_file in turn.
+func functionEnvLayers(function stack.Function) ([]envLayer, error) {
+	layers := []envLayer{{source: "environment", values: function.Environment}}
+
+	for _, file := range function.EnvironmentFile {
+		fileEnvironment, err := readFiles([]string{file})
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, envLayer{source: file, values: fileEnvironment})
+	}
+
+	return layers, nil
+}
+
+// explainFunctionEnvironment prints the environment deploy will give a
+// function, with variables from --env taking precedence over the stack file
+func explainFunctionEnvironment(w io.Writer, function stack.Function, envvarOpts []string) error {
+	layers, err := functionEnvLayers(function)
+	if err != nil {
+		return err
+	}
+
+	envvarArguments, err := util.ParseMap(envvarOpts, "env")
+	if err != nil {
+		return fmt.Errorf("error parsing envvars: %v", err)
+	}
+	layers = append(layers, envLayer{source: "--env", values: envvarArguments})
+
+	printExplainedEnvironment(w, function.Name, explainEnvironment(layers))
+	return nil
+}
+
+// explainEnvironment resolves each variable to the value from the last layer
+// which sets it
+func explainEnvironment(layers []envLayer) []explainedVariable {
+	byName := map[string]*explainedVariable{}
+
+	for _, layer := range layers {
+		for name, value := range layer.values {
+			v, ok := byName[name]
+			if !ok {
+				byName[name] = &explainedVariable{Name: name, Value: value, Source: layer.source}
+				continue
+			}
+
+			v.Overridden = append(v.Overridden, v.Source)
+			v.Value, v.Source = value, layer.source
+		}
+	}
+
+	variables := make([]explainedVariable, 0, len(byName))
+	for _, v := range byName {
+		variables = append(variables, *v)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+
+	return variables
+}
+
+// printExplainedEnvironment masks values which look like credentials
+func printExplainedEnvironment(w io.Writer, function string, variables []explainedVariable) {
+	fmt.Fprintf(w, "Environment for: %s\n", function)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tSOURCE\tOVERRIDES")
+	for _, v := range variables {
+		value := v.Value
+		if looksLikeSecret(v.Name, value) {
+			value = maskValue(value)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", v.Name, value, v.Source, strings.Join(v.Overridden, ", "))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+}
+
+// explainLocalRunEnvironment follows the order of the arguments given to
+// docker run, where --env-file overrides the stack file, -e flags override
+// both and the debugger, and fprocess is last
+func explainLocalRunEnvironment(w io.Writer, function stack.Function, opts runOptions, fprocess string, debug *stack.DebugConvention) error {
+	layers, err := functionEnvLayers(function)
+	if err != nil {
+		return err
+	}
+
+	envFiles, err := readEnvFiles(opts.envFiles)
+	if err != nil {
+		return err
+	}
+	layers = append(layers, envFiles...)
+
+	fprocessSource := "template"
+	if debug != nil && len(debug.FProcess) > 0 {
+		fprocessSource = "--debug-port"
+	} else if len(opts.fprocess) > 0 {
+		fprocessSource = "--fprocess"
+	} else if len(function.FProcess) > 0 {
+		fprocessSource = "fprocess"
+	}
+
+	if debug != nil {
+		layers = append(layers, envLayer{source: "--debug-port", values: debug.Environment})
+	}
+	layers = append(layers, envLayer{source: "--env", values: opts.extraEnv})
+	if len(fprocess) > 0 {
+		layers = append(layers, envLayer{source: fprocessSource, values: map[string]string{"fprocess": fprocess}})
+	}
+
+	printExplainedEnvironment(w, function.Name, explainEnvironment(layers))
+	return nil
+}
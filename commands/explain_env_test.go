@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_explainEnvironment_LaterLayersWin(t *testing.T) {
+	layers := []envLayer{
+		{source: "environment", values: map[string]string{"a": "1", "b": "1"}},
+		{source: "env.yml", values: map[string]string{"b": "2", "c": "2"}},
+		{source: "--env", values: map[string]string{"c": "3"}},
+	}
+
+	got := explainEnvironment(layers)
+	want := []explainedVariable{
+		{Name: "a", Value: "1", Source: "environment"},
+		{Name: "b", Value: "2", Source: "env.yml", Overridden: []string{"environment"}},
+		{Name: "c", Value: "3", Source: "--env", Overridden: []string{"env.yml"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %#v, got %#v", want, got)
+	}
+}
+
+func Test_explainFunctionEnvironment(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env.yml")
+	if err := os.WriteFile(envFile, []byte("environment:\n  debug: \"false\"\n  api_token: abcdef123456\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	function := stack.Function{
+		Name:            "fn1",
+		Environment:     map[string]string{"debug": "true", "region": "eu"},
+		EnvironmentFile: []string{envFile},
+	}
+
+	var out bytes.Buffer
+	if err := explainFunctionEnvironment(&out, function, []string{"region=us"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"Environment for: fn1", envFile, "--env", "environment"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in output:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "abcdef123456") {
+		t.Errorf("want api_token to be masked, got:\n%s", out.String())
+	}
+}
+
+func Test_explainLocalRunEnvironment_FprocessFlag(t *testing.T) {
+	var out bytes.Buffer
+	opts := runOptions{fprocess: "python3 index.py", extraEnv: map[string]string{"fprocess": "cat"}}
+
+	if err := explainLocalRunEnvironment(&out, stack.Function{Name: "fn1"}, opts, opts.fprocess, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "python3 index.py --fprocess --env") {
+		t.Errorf("want fprocess from --fprocess, overriding --env, got:\n%s", out.String())
+	}
+}
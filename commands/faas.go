@@ -10,8 +10,11 @@ import (
 	"path"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/pkg/term"
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas-cli/version"
 	"github.com/spf13/cobra"
 )
@@ -21,13 +24,20 @@ const (
 	defaultNetwork       = ""
 	defaultYAML          = "stack.yml"
 	defaultSchemaVersion = "1.0"
+
+	// profileEnvironment is the default for --profile
+	profileEnvironment = "OPENFAAS_PROFILE"
 )
 
 // Flags that are to be added to all commands.
 var (
-	yamlFile string
-	regex    string
-	filter   string
+	yamlFile     string
+	regex        string
+	filter       string
+	noColor      bool
+	plainOutput  bool
+	stackProfile string
+	stackValues  []string
 )
 
 // Flags that are to be added to subset of commands.
@@ -56,6 +66,7 @@ func resetForTest() {
 	version.Version = ""
 	shortVersion = false
 	appendFile = ""
+	readOnly = false
 }
 
 func init() {
@@ -65,6 +76,15 @@ func init() {
 	faasCmd.PersistentFlags().StringVarP(&yamlFile, "yaml", "f", "", "Path to YAML file describing function(s)")
 	faasCmd.PersistentFlags().StringVarP(&regex, "regex", "", "", "Regex to match with function names in YAML file")
 	faasCmd.PersistentFlags().StringVarP(&filter, "filter", "", "", "Wildcard to match with function names in YAML file")
+	faasCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colours in output, also set by the NO_COLOR environment variable")
+	faasCmd.PersistentFlags().BoolVar(&plainOutput, "plain", false, "Print output for scripts: no colours, tab-separated tables and values which are not humanized")
+	faasCmd.PersistentFlags().StringVar(&stackProfile, "profile", os.Getenv(profileEnvironment), "Profile to read as \"profile\" in the enabled expressions of functions in the YAML file, also set by "+profileEnvironment)
+	faasCmd.PersistentFlags().StringArrayVar(&stackValues, "set", []string{}, "Set a value (KEY=VALUE) to read in the enabled expressions of functions in the YAML file")
+
+	cobra.OnInitialize(func() {
+		output.Configure(noColor, plainOutput)
+		stack.SetConditions(stackProfile, stackValues)
+	})
 
 	// Set Bash completion options
 	validYAMLFilenames := []string{"yaml", "yml"}
@@ -105,7 +125,11 @@ func Execute(customArgs []string) {
 		}
 	}
 
-	if err := faasCmd.Execute(); err != nil {
+	started := time.Now()
+	executed, err := faasCmd.ExecuteC()
+	recordUsage(executed, started, err)
+
+	if err != nil {
 		e := err.Error()
 		fmt.Println(strings.ToUpper(e[:1]) + e[1:])
 		os.Exit(1)
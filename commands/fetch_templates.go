@@ -19,33 +19,19 @@ const DefaultTemplateRepository = "https://github.com/openfaas/templates.git"
 
 const templateDirectory = "./template/"
 
-// fetchTemplates fetch code templates using git clone.
+// fetchTemplates fetch code templates using a tarball, a git clone or the template cache.
 func fetchTemplates(templateURL string, refName string, overwrite bool) error {
 	if len(templateURL) == 0 {
 		return fmt.Errorf("pass valid templateURL")
 	}
 
-	dir, err := ioutil.TempDir("", "openFaasTemplates")
-	if err != nil {
-		log.Fatal(err)
-	}
-	if !pullDebug {
-		defer os.RemoveAll(dir) // clean up
-	}
-
 	log.Printf("Attempting to expand templates from %s\n", templateURL)
-	pullDebugPrint(fmt.Sprintf("Temp files in %s", dir))
-	args := map[string]string{"dir": dir, "repo": templateURL}
-	cmd := versioncontrol.GitCloneDefault
 
-	if refName != "" {
-		args["refname"] = refName
-		cmd = versioncontrol.GitClone
-	}
-
-	if err := cmd.Invoke(".", args); err != nil {
+	dir, cleanup, err := fetchTemplateSource(templateURL, refName)
+	if err != nil {
 		return err
 	}
+	defer cleanup()
 
 	preExistingLanguages, fetchedLanguages, err := moveTemplates(dir, overwrite)
 	if err != nil {
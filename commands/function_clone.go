@@ -0,0 +1,301 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloneHandlerDir string
+	cloneImage      string
+	cloneRewrite    bool
+)
+
+func init() {
+	functionCloneCmd.Flags().StringVar(&cloneHandlerDir, "handler", "", "Directory the cloned handler will be written to, defaults to a sibling of the source handler")
+	functionCloneCmd.Flags().StringVar(&cloneImage, "image", "", "Image name for the cloned function, defaults to the source image renamed")
+	functionCloneCmd.Flags().BoolVar(&cloneRewrite, "rewrite", true, "Rewrite module and package names in the handler for known languages")
+
+	functionCmd.AddCommand(functionCloneCmd)
+	faasCmd.AddCommand(functionCmd)
+}
+
+var functionCmd = &cobra.Command{
+	Use:   `function`,
+	Short: "Manage function definitions in a stack file",
+	Long:  "Manage function definitions and their handlers in a stack file",
+}
+
+var functionCloneCmd = &cobra.Command{
+	Use:   `clone SOURCE NEW_NAME [-f YAML_FILE] [--handler DIR] [--image IMAGE]`,
+	Short: "Clone an existing function definition",
+	Long: `Copies the handler folder of an existing function, and adds a new entry
+to the stack file with the same configuration under a new name and image.
+
+For Go and Node templates, the module or package name in the handler is
+rewritten to match the new function's name unless --rewrite=false is given.
+`,
+	Example: `  faas-cli function clone resize-img resize-thumbnail
+  faas-cli function clone resize-img resize-thumbnail -f ./images.yml
+  faas-cli function clone resize-img resize-thumbnail --image ghcr.io/org/thumbnail:0.1.0`,
+	PreRunE: preRunFunctionClone,
+	RunE:    runFunctionClone,
+}
+
+func preRunFunctionClone(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("give the name of the function to clone and a name for the new function")
+	}
+
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("give a stack file with --yaml/-f")
+	}
+
+	return validateFunctionName(args[1])
+}
+
+func runFunctionClone(cmd *cobra.Command, args []string) error {
+	sourceName, newName := args[0], args[1]
+
+	services, err := stack.ParseYAMLFile(yamlFile, "", "", false)
+	if err != nil {
+		return err
+	}
+
+	source, ok := services.Functions[sourceName]
+	if !ok {
+		return fmt.Errorf("function %s was not found in %s", sourceName, yamlFile)
+	}
+
+	if _, exists := services.Functions[newName]; exists {
+		return fmt.Errorf("function %s already exists in %s", newName, yamlFile)
+	}
+
+	handler := cloneHandlerDir
+	if len(handler) == 0 && len(source.Handler) > 0 {
+		handler = "./" + filepath.Join(filepath.Dir(source.Handler), newName)
+	}
+
+	image := cloneImage
+	if len(image) == 0 {
+		image = cloneImageName(source.Image, sourceName, newName)
+	}
+
+	if len(source.Handler) > 0 {
+		if _, err := os.Stat(handler); err == nil {
+			return fmt.Errorf("folder: %s already exists", handler)
+		}
+
+		if err := builder.CopyFiles(source.Handler, handler); err != nil {
+			return fmt.Errorf("unable to copy handler from %s to %s: %w", source.Handler, handler, err)
+		}
+		fmt.Printf("Folder: %s created.\n", handler)
+
+		if cloneRewrite {
+			rewritten, err := rewriteHandlerNames(handler, source.Language, sourceName, newName)
+			if err != nil {
+				return err
+			}
+			for _, file := range rewritten {
+				fmt.Printf("Rewrote: %s\n", file)
+			}
+		}
+	}
+
+	data, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return err
+	}
+
+	out, err := cloneStackEntry(data, sourceName, newName, handler, image)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(yamlFile, out, 0600); err != nil {
+		return fmt.Errorf("error writing stack file %s", err)
+	}
+
+	fmt.Printf("Stack file updated: %s\n", yamlFile)
+	return nil
+}
+
+// cloneStackEntry duplicates the function SOURCE within the stack file data, and
+// gives the copy a new name, handler and image. The source entry is copied line
+// by line so that formatting, quoting and comments are kept intact. The copy is
+// written directly after the source.
+func cloneStackEntry(data []byte, sourceName, newName, handler, image string) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+
+	functionsStart := -1
+	for i, line := range lines {
+		if strings.TrimRight(line, " \t") == "functions:" {
+			functionsStart = i
+			break
+		}
+	}
+	if functionsStart == -1 {
+		return nil, fmt.Errorf("unable to find functions in the stack file")
+	}
+
+	entryKey := regexp.MustCompile(`^(\s+)` + regexp.QuoteMeta(sourceName) + `:\s*(#.*)?$`)
+
+	entryStart, entryIndent := -1, ""
+	for i := functionsStart + 1; i < len(lines); i++ {
+		if isTopLevelYAMLLine(lines[i]) {
+			break
+		}
+		if match := entryKey.FindStringSubmatch(lines[i]); match != nil {
+			entryStart, entryIndent = i, match[1]
+			break
+		}
+	}
+	if entryStart == -1 {
+		return nil, fmt.Errorf("function %s was not found in the stack file", sourceName)
+	}
+
+	entryEnd := entryStart + 1
+	for ; entryEnd < len(lines); entryEnd++ {
+		line := lines[entryEnd]
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if len(line)-len(strings.TrimLeft(line, " \t")) <= len(entryIndent) {
+			break
+		}
+	}
+
+	// Don't carry trailing blank lines or comments into the copy
+	for entryEnd > entryStart+1 {
+		trimmed := strings.TrimSpace(lines[entryEnd-1])
+		if len(trimmed) > 0 && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		entryEnd--
+	}
+
+	field := regexp.MustCompile(`^(\s+)(handler|image):(\s*)\S.*$`)
+	cloned := []string{entryIndent + newName + ":"}
+	for _, line := range lines[entryStart+1 : entryEnd] {
+		if match := field.FindStringSubmatch(line); match != nil && len(match[1]) > len(entryIndent) {
+			switch match[2] {
+			case "handler":
+				if len(handler) > 0 {
+					line = match[1] + "handler:" + match[3] + handler
+				}
+			case "image":
+				line = match[1] + "image:" + match[3] + image
+			}
+		}
+		cloned = append(cloned, line)
+	}
+
+	out := make([]string, 0, len(lines)+len(cloned))
+	out = append(out, lines[:entryEnd]...)
+	out = append(out, cloned...)
+	out = append(out, lines[entryEnd:]...)
+
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// isTopLevelYAMLLine returns true for a key which is not indented
+func isTopLevelYAMLLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+		return false
+	}
+	return !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t")
+}
+
+// cloneImageName renames the repository of an image, keeping its registry,
+// organisation and tag. When the repository is not exactly the source
+// function's name, any occurrence of the source name within it is replaced.
+func cloneImageName(image, sourceName, newName string) string {
+	if len(image) == 0 {
+		return newName + ":latest"
+	}
+
+	prefix := ""
+	repo := image
+	if i := strings.LastIndex(image, "/"); i > -1 {
+		prefix, repo = image[:i+1], image[i+1:]
+	}
+
+	tag := ""
+	if i := strings.Index(repo, "@"); i > -1 {
+		repo, tag = repo[:i], ":latest"
+	} else if i := strings.LastIndex(repo, ":"); i > -1 {
+		repo, tag = repo[:i], repo[i:]
+	}
+
+	if repo == sourceName {
+		return prefix + newName + tag
+	}
+
+	return prefix + strings.ReplaceAll(repo, sourceName, newName) + tag
+}
+
+// handlerRewrite replaces a name within a single file of a handler
+type handlerRewrite struct {
+	file    string
+	pattern *regexp.Regexp
+	replace string
+}
+
+// rewriteHandlerNames updates module and package names which normally match
+// the function's name, so that builds of the clone don't collide with the source.
+func rewriteHandlerNames(handler, language, sourceName, newName string) ([]string, error) {
+	var rewrites []handlerRewrite
+
+	language = strings.ToLower(language)
+	switch {
+	case strings.HasPrefix(language, "go"):
+		rewrites = append(rewrites, handlerRewrite{
+			file:    "go.mod",
+			pattern: regexp.MustCompile(`(?m)^module\s+(\S*/)?` + regexp.QuoteMeta(sourceName) + `[ \t]*$`),
+			replace: "module ${1}" + newName,
+		})
+	case strings.HasPrefix(language, "node"):
+		rewrites = append(rewrites, handlerRewrite{
+			file:    "package.json",
+			pattern: regexp.MustCompile(`"name"(\s*):(\s*)"` + regexp.QuoteMeta(sourceName) + `"`),
+			replace: `"name"${1}:${2}"` + newName + `"`,
+		})
+	}
+
+	var changed []string
+	for _, rewrite := range rewrites {
+		path := filepath.Join(handler, rewrite.file)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return changed, err
+		}
+
+		if !rewrite.pattern.Match(data) {
+			continue
+		}
+
+		out := rewrite.pattern.ReplaceAll(data, []byte(rewrite.replace))
+		if err := ioutil.WriteFile(path, out, 0600); err != nil {
+			return changed, err
+		}
+		changed = append(changed, path)
+	}
+
+	return changed, nil
+}
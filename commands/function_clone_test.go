@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_cloneImageName(t *testing.T) {
+	cases := []struct {
+		image string
+		want  string
+	}{
+		{"resize-img:latest", "thumbnail:latest"},
+		{"ghcr.io/openfaas/resize-img:0.1.0", "ghcr.io/openfaas/thumbnail:0.1.0"},
+		{"localhost:5000/resize-img", "localhost:5000/thumbnail"},
+		{"ghcr.io/openfaas/resize-img-fn:latest", "ghcr.io/openfaas/thumbnail-fn:latest"},
+		{"ghcr.io/openfaas/resize-img@sha256:abcdef", "ghcr.io/openfaas/thumbnail:latest"},
+		{"", "thumbnail:latest"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.image, func(t *testing.T) {
+			if got := cloneImageName(c.image, "resize-img", "thumbnail"); got != c.want {
+				t.Errorf("want %s, got %s", c.want, got)
+			}
+		})
+	}
+}
+
+func Test_cloneStackEntry(t *testing.T) {
+	data := []byte(`version: 1.0
+provider:
+  name: openfaas
+  gateway: http://127.0.0.1:8080
+functions:
+  resize-img:
+    lang: golang-middleware
+    handler: ./resize-img
+    image: ghcr.io/openfaas/resize-img:latest
+    environment:
+      max_size: "1024"
+    secrets:
+      - s3-key
+configuration:
+  templates:
+    - name: golang-middleware
+`)
+
+	out, err := cloneStackEntry(data, "resize-img", "thumbnail", "./thumbnail", "ghcr.io/openfaas/thumbnail:latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	services, err := stack.ParseYAMLData(out, "", "", false)
+	if err != nil {
+		t.Fatalf("cloned stack is invalid: %s\n%s", err, string(out))
+	}
+
+	if len(services.Functions) != 2 {
+		t.Fatalf("want 2 functions, got %d", len(services.Functions))
+	}
+
+	cloned := services.Functions["thumbnail"]
+	if cloned.Handler != "./thumbnail" || cloned.Image != "ghcr.io/openfaas/thumbnail:latest" {
+		t.Errorf("unexpected handler or image: %s %s", cloned.Handler, cloned.Image)
+	}
+	if cloned.Environment["max_size"] != "1024" || len(cloned.Secrets) != 1 {
+		t.Errorf("want environment and secrets to be copied, got: %v %v", cloned.Environment, cloned.Secrets)
+	}
+
+	source := services.Functions["resize-img"]
+	if source.Image != "ghcr.io/openfaas/resize-img:latest" {
+		t.Errorf("source function should not be modified, got image: %s", source.Image)
+	}
+
+	if len(services.StackConfiguration.TemplateConfigs) != 1 {
+		t.Errorf("want stack configuration to be kept")
+	}
+}
+
+func Test_cloneStackEntry_MissingSource(t *testing.T) {
+	data := []byte(`provider:
+  name: openfaas
+functions:
+  figlet:
+    image: figlet
+`)
+
+	if _, err := cloneStackEntry(data, "resize-img", "thumbnail", "", "thumbnail"); err == nil {
+		t.Fatalf("want error for missing source function")
+	}
+}
+
+func Test_rewriteHandlerNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	goMod := filepath.Join(dir, "go.mod")
+	ioutil.WriteFile(goMod, []byte("module github.com/org/resize-img\n\ngo 1.18\n"), 0600)
+
+	changed, err := rewriteHandlerNames(dir, "golang-middleware", "resize-img", "thumbnail")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(changed) != 1 {
+		t.Fatalf("want 1 file to be rewritten, got %d", len(changed))
+	}
+
+	data, _ := ioutil.ReadFile(goMod)
+	if !strings.HasPrefix(string(data), "module github.com/org/thumbnail\n\ngo 1.18") {
+		t.Errorf("unexpected go.mod: %q", string(data))
+	}
+
+	packageJSON := filepath.Join(dir, "package.json")
+	ioutil.WriteFile(packageJSON, []byte(`{"name": "resize-img", "version": "1.0.0"}`), 0600)
+
+	if _, err := rewriteHandlerNames(dir, "node18", "resize-img", "thumbnail"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, _ = ioutil.ReadFile(packageJSON)
+	if string(data) != `{"name": "thumbnail", "version": "1.0.0"}` {
+		t.Errorf("unexpected package.json: %s", string(data))
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+var (
+	// gatewayPrecheck checks the gateway can be reached, and that the
+	// credentials are accepted, before deploying from a stack file
+	gatewayPrecheck bool
+	// maxGatewayErrors is how many consecutive gateway errors stop a
+	// batch deploy, zero means never stop
+	maxGatewayErrors int
+)
+
+// checkGatewayReady stops a batch operation early, rather than letting every
+// function fail in turn when the gateway is down or the login has expired
+func checkGatewayReady(ctx context.Context, client *proxy.Client, namespace string) error {
+	gatewayAddress := client.GatewayURL.String()
+
+	if _, err := client.GetSystemInfo(ctx); err != nil {
+		return fmt.Errorf("gateway %s failed its pre-check, no functions were deployed: %w", gatewayAddress, err)
+	}
+
+	if _, err := client.ListFunctions(ctx, namespace); err != nil {
+		return fmt.Errorf("gateway %s failed its pre-check, no functions were deployed: %w", gatewayAddress, err)
+	}
+
+	return nil
+}
+
+// precheckStackGateway runs checkGatewayReady against the gateway of the
+// stack file, for commands such as up which do other work before deploying
+func precheckStackGateway() error {
+	if len(yamlFile) == 0 {
+		return nil
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst)
+	if err != nil {
+		return err
+	}
+	if services == nil || len(services.Functions) == 0 {
+		return nil
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, services.Provider.GatewayURL, os.Getenv(openFaaSURLEnvironment))
+
+	cliAuth, err := proxy.NewCLIAuth(token, gatewayAddress)
+	if err != nil {
+		return err
+	}
+
+	transport := GetDefaultCLITransport(tlsInsecure, &timeoutOverride)
+	client, err := proxy.NewClient(cliAuth, gatewayAddress, transport, &timeoutOverride)
+	if err != nil {
+		return err
+	}
+
+	return checkGatewayReady(context.Background(), client, functionNamespace)
+}
+
+// gatewayBreaker trips after a number of consecutive deployments fail because
+// of the gateway rather than the function
+type gatewayBreaker struct {
+	limit       int
+	consecutive int
+}
+
+func newGatewayBreaker(limit int) *gatewayBreaker {
+	return &gatewayBreaker{limit: limit}
+}
+
+// record counts the result of one deployment and reports whether the breaker
+// has tripped
+func (b *gatewayBreaker) record(statusCode int) bool {
+	if isGatewayError(statusCode) {
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+	return b.tripped()
+}
+
+func (b *gatewayBreaker) tripped() bool {
+	return b.limit > 0 && b.consecutive >= b.limit
+}
+
+// isGatewayError is true for a status code which says nothing about the
+// function itself. When the gateway cannot be reached, the proxy reports a 500.
+func isGatewayError(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError ||
+		statusCode == http.StatusUnauthorized ||
+		statusCode == http.StatusForbidden
+}
+
+// breakerTripped describes the functions which were not attempted
+func breakerTripped(limit int, skipped []string) error {
+	sort.Strings(skipped)
+	return fmt.Errorf("stopped after %d consecutive gateway errors, %d function(s) were not deployed: %s",
+		limit, len(skipped), strings.Join(skipped, ", "))
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/mockgateway"
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+func newPrecheckClient(t *testing.T, gatewayURL string) *proxy.Client {
+	t.Helper()
+
+	timeout := 5 * time.Second
+	client, err := proxy.NewClient(&proxy.BasicAuth{}, gatewayURL, http.DefaultTransport, &timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func Test_checkGatewayReady(t *testing.T) {
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+
+	if err := checkGatewayReady(context.Background(), newPrecheckClient(t, s.URL), ""); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+}
+
+func Test_checkGatewayReady_Unauthorized(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer s.Close()
+
+	err := checkGatewayReady(context.Background(), newPrecheckClient(t, s.URL), "")
+	if err == nil {
+		t.Fatalf("want an error")
+	}
+	if !strings.Contains(err.Error(), "faas-cli login") {
+		t.Fatalf("want the error to suggest a login, got: %s", err)
+	}
+}
+
+func Test_checkGatewayReady_Unreachable(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+
+	if err := checkGatewayReady(context.Background(), newPrecheckClient(t, s.URL), ""); err == nil {
+		t.Fatalf("want an error")
+	}
+}
+
+func Test_gatewayBreaker(t *testing.T) {
+	cases := []struct {
+		name     string
+		limit    int
+		statuses []int
+		want     bool
+	}{
+		{name: "trips after consecutive errors", limit: 2, statuses: []int{500, 502}, want: true},
+		{name: "a success resets the count", limit: 2, statuses: []int{500, 200, 502}, want: false},
+		{name: "function errors are not counted", limit: 2, statuses: []int{400, 404, 409}, want: false},
+		{name: "unauthorized is counted", limit: 2, statuses: []int{401, 401}, want: true},
+		{name: "zero never trips", limit: 0, statuses: []int{500, 500, 500}, want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newGatewayBreaker(tc.limit)
+			for _, status := range tc.statuses {
+				b.record(status)
+			}
+			if got := b.tripped(); got != tc.want {
+				t.Fatalf("want tripped: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_breakerTripped(t *testing.T) {
+	err := breakerTripped(3, []string{"fn-b", "fn-a"})
+
+	want := "stopped after 3 consecutive gateway errors, 2 function(s) were not deployed: fn-a, fn-b"
+	if err.Error() != want {
+		t.Fatalf("want: %q, got: %q", want, err.Error())
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// The secret and keys which the OpenFaaS chart creates for basic auth
+	basicAuthSecret      = "basic-auth"
+	basicAuthUserKey     = "basic-auth-user"
+	basicAuthPasswordKey = "basic-auth-password"
+
+	rotatePasswordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// rotateOptions are the flags of "gateway rotate-password"
+type rotateOptions struct {
+	namespace    string
+	secret       string
+	deployments  []string
+	length       int
+	timeout      time.Duration
+	kubeContext  string
+	kubeconfig   string
+	showPassword bool
+}
+
+var rotateFlags rotateOptions
+
+// runKubectl and rotatePollInterval are replaced by tests
+var (
+	runKubectl         = runExecTransport
+	rotatePollInterval = 2 * time.Second
+)
+
+func init() {
+	gatewayRotateCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	gatewayRotateCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	gatewayRotateCmd.Flags().StringVarP(&rotateFlags.namespace, "namespace", "n", ingressDefaultNamespace, "Namespace of the gateway")
+	gatewayRotateCmd.Flags().StringVar(&rotateFlags.secret, "secret", basicAuthSecret, "Secret which holds the gateway's basic auth credentials")
+	gatewayRotateCmd.Flags().StringSliceVar(&rotateFlags.deployments, "restart", []string{"gateway"}, "Deployments to restart to load the new password, e.g. gateway,queue-worker")
+	gatewayRotateCmd.Flags().IntVar(&rotateFlags.length, "length", 25, "Length of the generated password")
+	gatewayRotateCmd.Flags().DurationVar(&rotateFlags.timeout, "timeout", 2*time.Minute, "Time to wait for the restarted gateway to accept the new password")
+	gatewayRotateCmd.Flags().StringVar(&rotateFlags.kubeContext, "context", "", "kubeconfig context to use with kubectl")
+	gatewayRotateCmd.Flags().StringVar(&rotateFlags.kubeconfig, "kubeconfig", "", "Path to a kubeconfig file, instead of the default of kubectl")
+	gatewayRotateCmd.Flags().BoolVar(&rotateFlags.showPassword, "show-password", false, "Print the new password, it is only saved to the CLI's config by default")
+
+	markMutating(gatewayRotateCmd)
+	gatewayCmd.AddCommand(gatewayRotateCmd)
+	faasCmd.AddCommand(gatewayCmd)
+}
+
+var gatewayCmd = &cobra.Command{
+	Use:   `gateway [rotate-password]`,
+	Short: "Manage the OpenFaaS gateway",
+}
+
+var gatewayRotateCmd = &cobra.Command{
+	Use:   `rotate-password [--gateway GATEWAY_URL] [--namespace NAMESPACE]`,
+	Short: "Generate a new basic auth password for the gateway",
+	Long: `Generates a new password for the gateway's basic auth, and then:
+
+  1. Updates the basic-auth secret in the gateway's namespace
+  2. Restarts the gateway, and waits for its rollout
+  3. Waits for the gateway to accept the new password
+  4. Saves the new password to the CLI's login for the gateway
+
+The username in the secret is kept. Components which also read the secret,
+such as the queue-worker, must be restarted too, give them with --restart.
+
+kubectl and a kubeconfig with access to the gateway's namespace are required.`,
+	Example: `  faas-cli gateway rotate-password
+  faas-cli gateway rotate-password --gateway https://openfaas.example.com \
+    --context production
+  faas-cli gateway rotate-password --restart gateway,queue-worker
+  faas-cli gateway rotate-password --show-password`,
+	PreRunE: preRunGatewayRotate,
+	RunE:    runGatewayRotate,
+}
+
+func preRunGatewayRotate(cmd *cobra.Command, args []string) error {
+	if rotateFlags.length < 16 {
+		return fmt.Errorf("--length must be at least 16")
+	}
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		return fmt.Errorf("kubectl must be installed to rotate the password")
+	}
+	return nil
+}
+
+func runGatewayRotate(cmd *cobra.Command, args []string) error {
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "", os.Getenv(openFaaSURLEnvironment))
+
+	newPassword, err := generatePassword(rotateFlags.length)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updating secret: %s/%s\n", rotateFlags.namespace, rotateFlags.secret)
+	user, err := rotateFlags.updateSecret(newPassword)
+	if err != nil {
+		return err
+	}
+
+	// From here on the secret holds the new password, so each error says how
+	// to recover it
+	recovery := fmt.Sprintf("the new password is in the secret, get it with: %s",
+		rotateFlags.recoveryCommand())
+
+	for _, deployment := range rotateFlags.deployments {
+		fmt.Printf("Restarting: %s\n", deployment)
+		if err := runKubectl(rotateFlags.restartCommand(deployment), nil, os.Stdout, os.Stderr); err != nil {
+			return fmt.Errorf("unable to restart %s: %w, %s", deployment, err, recovery)
+		}
+	}
+	for _, deployment := range rotateFlags.deployments {
+		if err := runKubectl(rotateFlags.rolloutStatusCommand(deployment), nil, os.Stdout, os.Stderr); err != nil {
+			return fmt.Errorf("%s did not roll out: %w, %s", deployment, err, recovery)
+		}
+	}
+
+	fmt.Printf("Waiting for %s to accept the new password\n", gatewayAddress)
+	if err := waitForLogin(gatewayAddress, user, newPassword, rotateFlags.timeout); err != nil {
+		return fmt.Errorf("%w, %s", err, recovery)
+	}
+
+	if err := config.UpdateAuthConfig(gatewayAddress, config.EncodeAuth(user, newPassword), config.BasicAuthType); err != nil {
+		return fmt.Errorf("unable to save the new login: %w, %s", err, recovery)
+	}
+
+	fmt.Printf("Rotated the password for %s, and saved it for %s\n", user, gatewayAddress)
+	if rotateFlags.showPassword {
+		fmt.Printf("Password: %s\n", newPassword)
+	}
+	return nil
+}
+
+// generatePassword returns a random alphanumeric password, which is safe to
+// paste into any shell or config file
+func generatePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(rotatePasswordAlphabet)))
+	password := make([]byte, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = rotatePasswordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// updateSecret replaces the password in the secret and returns its username.
+// The secret is read and replaced whole, rather than applied, so that the
+// password is not written to its annotations, and is never in the arguments
+// of kubectl. Its resourceVersion fails the replace if the secret changed
+// in between.
+func (o rotateOptions) updateSecret(password string) (string, error) {
+	var current bytes.Buffer
+	if err := runKubectl(o.getSecretCommand(), nil, &current, os.Stderr); err != nil {
+		return "", fmt.Errorf("unable to get the secret %s: %w", o.secret, err)
+	}
+
+	secret, user, err := setSecretPassword(current.Bytes(), password)
+	if err != nil {
+		return "", fmt.Errorf("unable to update the secret %s: %w", o.secret, err)
+	}
+
+	if err := runKubectl(o.replaceSecretCommand(), bytes.NewReader(secret), os.Stdout, os.Stderr); err != nil {
+		return "", fmt.Errorf("unable to update the secret %s: %w", o.secret, err)
+	}
+	return user, nil
+}
+
+func setSecretPassword(secretJSON []byte, password string) ([]byte, string, error) {
+	var secret map[string]interface{}
+	if err := json.Unmarshal(secretJSON, &secret); err != nil {
+		return nil, "", err
+	}
+
+	data, _ := secret["data"].(map[string]interface{})
+	encodedUser, _ := data[basicAuthUserKey].(string)
+	user, err := base64.StdEncoding.DecodeString(encodedUser)
+	if err != nil || len(user) == 0 {
+		return nil, "", fmt.Errorf("it has no %s", basicAuthUserKey)
+	}
+
+	data[basicAuthPasswordKey] = base64.StdEncoding.EncodeToString([]byte(password))
+
+	out, err := json.Marshal(secret)
+	return out, string(user), err
+}
+
+// waitForLogin retries the new credentials, as the gateway may still be
+// starting, or the old replicas draining, just after the rollout
+func waitForLogin(gatewayAddress, user, password string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := validateLogin(gatewayAddress, user, password, 5*time.Second, tlsInsecure)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the gateway did not accept the new password after %s: %w", timeout.Round(time.Second), err)
+		}
+		time.Sleep(rotatePollInterval)
+	}
+}
+
+func (o rotateOptions) kubectl() []string {
+	argv := []string{"kubectl"}
+	if len(o.kubeconfig) > 0 {
+		argv = append(argv, "--kubeconfig", o.kubeconfig)
+	}
+	if len(o.kubeContext) > 0 {
+		argv = append(argv, "--context", o.kubeContext)
+	}
+	return append(argv, "--namespace", o.namespace)
+}
+
+func (o rotateOptions) getSecretCommand() []string {
+	return append(o.kubectl(), "get", "secret", o.secret, "-o", "json")
+}
+
+func (o rotateOptions) replaceSecretCommand() []string {
+	return append(o.kubectl(), "replace", "-f", "-")
+}
+
+func (o rotateOptions) restartCommand(deployment string) []string {
+	return append(o.kubectl(), "rollout", "restart", "deployment/"+deployment)
+}
+
+func (o rotateOptions) rolloutStatusCommand(deployment string) []string {
+	return append(o.kubectl(), "rollout", "status", "deployment/"+deployment, "--timeout", o.timeout.String())
+}
+
+func (o rotateOptions) recoveryCommand() string {
+	argv := append(o.kubectl(), "get", "secret", o.secret, "-o", "jsonpath='{.data."+basicAuthPasswordKey+"}'")
+	return strings.Join(argv, " ") + " | base64 --decode"
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/test"
+)
+
+const testBasicAuthSecret = `{
+  "apiVersion": "v1",
+  "kind": "Secret",
+  "metadata": {"name": "basic-auth", "namespace": "openfaas", "resourceVersion": "1234"},
+  "data": {"basic-auth-user": "YWRtaW4=", "basic-auth-password": "b2xk"}
+}`
+
+func Test_runGatewayRotate(t *testing.T) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+
+	var (
+		commands    []string
+		newPassword string
+	)
+
+	savedKubectl, savedInterval := runKubectl, rotatePollInterval
+	defer func() { runKubectl, rotatePollInterval = savedKubectl, savedInterval }()
+	rotatePollInterval = time.Millisecond
+
+	runKubectl = func(argv []string, stdin io.Reader, stdout, stderr io.Writer) error {
+		commands = append(commands, strings.Join(argv, " "))
+		switch {
+		case strings.Contains(strings.Join(argv, " "), " get secret "):
+			io.WriteString(stdout, testBasicAuthSecret)
+		case strings.Contains(strings.Join(argv, " "), " replace "):
+			var secret struct {
+				Metadata map[string]interface{} `json:"metadata"`
+				Data     map[string]string      `json:"data"`
+			}
+			body, _ := io.ReadAll(stdin)
+			if err := json.Unmarshal(body, &secret); err != nil {
+				t.Fatal(err)
+			}
+			if secret.Metadata["resourceVersion"] != "1234" {
+				t.Errorf("want the resourceVersion to be kept, got: %v", secret.Metadata["resourceVersion"])
+			}
+			password, _ := base64.StdEncoding.DecodeString(secret.Data[basicAuthPasswordKey])
+			newPassword = string(password)
+		}
+		return nil
+	}
+
+	attempts := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		user, pass, _ := r.BasicAuth()
+		// The first attempt reaches a replica which has the old password
+		if attempts == 1 || user != "admin" || pass != newPassword {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+	defer s.Close()
+
+	resetForTest()
+	gateway = s.URL
+	rotateFlags = rotateOptions{
+		namespace:   "openfaas",
+		secret:      basicAuthSecret,
+		deployments: []string{"gateway", "queue-worker"},
+		length:      25,
+		timeout:     time.Second,
+	}
+
+	out := test.CaptureStdout(func() {
+		if err := runGatewayRotate(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if len(newPassword) != 25 || newPassword == "old" {
+		t.Fatalf("want a new password of 25 characters, got: %q", newPassword)
+	}
+	if strings.Contains(out, newPassword) {
+		t.Errorf("want the password to be hidden without --show-password, got:\n%s", out)
+	}
+
+	want := []string{
+		"kubectl --namespace openfaas get secret basic-auth -o json",
+		"kubectl --namespace openfaas replace -f -",
+		"kubectl --namespace openfaas rollout restart deployment/gateway",
+		"kubectl --namespace openfaas rollout restart deployment/queue-worker",
+		"kubectl --namespace openfaas rollout status deployment/gateway --timeout 1s",
+		"kubectl --namespace openfaas rollout status deployment/queue-worker --timeout 1s",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("want commands:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(commands, "\n"))
+	}
+
+	authConfig, err := config.LookupAuthConfig(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, pass, _ := config.DecodeAuth(authConfig.Token)
+	if user != "admin" || pass != newPassword {
+		t.Fatalf("want the new login to be saved, got: %s:%s", user, pass)
+	}
+}
+
+func Test_setSecretPassword_NoUser(t *testing.T) {
+	_, _, err := setSecretPassword([]byte(`{"data":{}}`), "new")
+	if err == nil {
+		t.Fatal("want an error for a secret without a username")
+	}
+}
+
+func Test_generatePassword(t *testing.T) {
+	a, _ := generatePassword(25)
+	b, _ := generatePassword(25)
+
+	if len(a) != 25 || a == b {
+		t.Fatalf("want two different passwords of 25 characters, got: %q and %q", a, b)
+	}
+	if strings.Trim(a, rotatePasswordAlphabet) != "" || bytes.ContainsAny([]byte(a), "'\"$ ") {
+		t.Fatalf("want an alphanumeric password, got: %q", a)
+	}
+}
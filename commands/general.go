@@ -1,38 +1,25 @@
 package commands
 
 import (
-	"crypto/tls"
-	"net"
 	"net/http"
 	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
 )
 
 var (
 	commandTimeout = 60 * time.Second
 )
 
+// GetDefaultCLITransport returns the transport shared by the clients of a
+// command, so that they reuse connections to the gateway
 func GetDefaultCLITransport(tlsInsecure bool, timeout *time.Duration) *http.Transport {
 	if timeout != nil || tlsInsecure {
-		tr := &http.Transport{
-			Proxy:             http.ProxyFromEnvironment,
-			DisableKeepAlives: false,
-		}
-
+		var dialTimeout time.Duration
 		if timeout != nil {
-			tr.DialContext = (&net.Dialer{
-				Timeout: *timeout,
-			}).DialContext
-
-			tr.IdleConnTimeout = 120 * time.Millisecond
-			tr.ExpectContinueTimeout = 1500 * time.Millisecond
+			dialTimeout = *timeout
 		}
-
-		if tlsInsecure {
-			tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: tlsInsecure}
-		}
-		tr.DisableKeepAlives = false
-
-		return tr
+		return proxy.SharedTransport(dialTimeout, tlsInsecure, false)
 	}
 	return nil
 }
@@ -209,7 +209,7 @@ func generateCRDYAML(services stack.Services, format schema.BuildFormat, apiVers
 				Environment:            allEnvironment,
 				Labels:                 function.Labels,
 				Annotations:            function.Annotations,
-				Limits:                 function.Limits,
+				Limits:                 withoutGPU(function.Limits),
 				Requests:               function.Requests,
 				Constraints:            function.Constraints,
 				Secrets:                function.Secrets,
@@ -324,6 +324,18 @@ func generateknativev1ServingServiceCRDYAML(services stack.Services, format sche
 	return objectsString, nil
 }
 
+// withoutGPU leaves out limits.gpu of the stack file, which only local-run
+// reads, as the Function resource has no such field
+func withoutGPU(limits *stack.FunctionResources) *stack.FunctionResources {
+	if limits == nil || limits.GPU == "" {
+		return limits
+	}
+	if limits.Memory == "" && limits.CPU == "" {
+		return nil
+	}
+	return &stack.FunctionResources{Memory: limits.Memory, CPU: limits.CPU}
+}
+
 func generateFunctionOrder(functions map[string]stack.Function) []string {
 
 	var functionNames []string
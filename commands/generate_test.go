@@ -523,3 +523,14 @@ func Test_generateFunctionOrder(t *testing.T) {
 
 	}
 }
+
+func Test_withoutGPU(t *testing.T) {
+	if got := withoutGPU(&stack.FunctionResources{GPU: "1"}); got != nil {
+		t.Errorf("want no limits when only a GPU was given, got: %v", got)
+	}
+
+	got := withoutGPU(&stack.FunctionResources{Memory: "1Gi", GPU: "all"})
+	if got == nil || got.Memory != "1Gi" || got.GPU != "" {
+		t.Errorf("want the memory limit kept without the GPU, got: %v", got)
+	}
+}
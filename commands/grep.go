@@ -0,0 +1,247 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// grepSkipDirs are folders of dependencies, which are not the function's code
+var grepSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+}
+
+var grepIgnoreCase bool
+
+func init() {
+	grepCmd.Flags().BoolVarP(&grepIgnoreCase, "ignore-case", "i", false, "Match the pattern without regard to case")
+	grepCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+
+	faasCmd.AddCommand(grepCmd)
+}
+
+var grepCmd = &cobra.Command{
+	Use:   `grep PATTERN [-f YAML_FILE] [--ignore-case]`,
+	Short: "Search the handlers and stack file of functions",
+	Long: `Searches the handler folder of each function in the stack file, and the stack
+file itself, for lines which match PATTERN, a regular expression. Each match is
+printed with the function it belongs to, so that a setting or call can be found
+across a stack. A line of the stack file belongs to the function it is defined
+under, and a handler folder shared by more than one function lists each of them.
+
+Hidden folders and those of dependencies, such as node_modules and vendor, are
+not searched, nor are binary files. --filter and --regex limit the functions
+searched.`,
+	Example: `  faas-cli grep TODO
+  faas-cli grep -i "redis_(host|port)"
+  faas-cli grep os.Getenv --filter "api-*"`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("give the pattern to search for, e.g. faas-cli grep TODO")
+		}
+		if len(yamlFile) == 0 {
+			return fmt.Errorf("give a stack file with --yaml/-f")
+		}
+		return nil
+	},
+	RunE: runGrep,
+}
+
+// grepMatch is a matching line and the functions it belongs to
+type grepMatch struct {
+	functions []string
+	path      string
+	line      int
+	text      string
+}
+
+func runGrep(cmd *cobra.Command, args []string) error {
+	pattern := args[0]
+	if grepIgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", args[0], err)
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst)
+	if err != nil {
+		return err
+	}
+
+	matches, err := grepStack(re, yamlFile, services.Functions)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no matches found for: %s", args[0])
+	}
+
+	writeGrepMatches(cmd.OutOrStdout(), re, matches)
+	return nil
+}
+
+// grepStack searches the stack file, then each handler folder in turn
+func grepStack(re *regexp.Regexp, stackFile string, functions map[string]stack.Function) ([]grepMatch, error) {
+	data, err := os.ReadFile(stackFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []grepMatch
+	lines := strings.Split(string(data), "\n")
+	owners := stackLineFunctions(lines)
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		// Lines of functions which were filtered out are not shown
+		if owners[i] != "" {
+			if _, ok := functions[owners[i]]; !ok {
+				continue
+			}
+		}
+		matches = append(matches, grepMatch{functions: []string{valueOrDash(owners[i])}, path: stackFile, line: i + 1, text: line})
+	}
+
+	handlers := map[string][]string{}
+	for name, function := range functions {
+		if len(function.Handler) == 0 {
+			continue
+		}
+		handler := filepath.Clean(function.Handler)
+		handlers[handler] = append(handlers[handler], name)
+	}
+
+	dirs := make([]string, 0, len(handlers))
+	for handler := range handlers {
+		dirs = append(dirs, handler)
+	}
+	sort.Strings(dirs)
+
+	for _, handler := range dirs {
+		names := handlers[handler]
+		sort.Strings(names)
+
+		found, err := grepDir(re, handler, names)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, found...)
+	}
+	return matches, nil
+}
+
+func grepDir(re *regexp.Regexp, dir string, functions []string) ([]grepMatch, error) {
+	var matches []grepMatch
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return nil
+			}
+			return err
+		}
+
+		if info.IsDir() {
+			if path != dir && (strings.HasPrefix(info.Name(), ".") || grepSkipDirs[info.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		found, err := grepFile(re, path, functions)
+		if err != nil {
+			return err
+		}
+		matches = append(matches, found...)
+		return nil
+	})
+	return matches, err
+}
+
+// grepFile searches a file line by line, a file with a NUL byte in its first
+// block is taken to be binary and skipped
+func grepFile(re *regexp.Regexp, path string, functions []string) ([]grepMatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	if head, _ := reader.Peek(8000); bytes.IndexByte(head, 0) > -1 {
+		return nil, nil
+	}
+
+	var matches []grepMatch
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if text := scanner.Text(); re.MatchString(text) {
+			matches = append(matches, grepMatch{functions: functions, path: path, line: line, text: text})
+		}
+	}
+	return matches, scanner.Err()
+}
+
+// stackLineFunctions gives the name of the function which each line of a
+// stack file is under, or "" for lines outside of the functions
+func stackLineFunctions(lines []string) []string {
+	owners := make([]string, len(lines))
+	inFunctions := false
+	functionIndent := -1
+	current := ""
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			owners[i] = current
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == 0 {
+			inFunctions = strings.HasPrefix(trimmed, "functions:")
+			functionIndent = -1
+			current = ""
+			continue
+		}
+
+		if inFunctions {
+			if functionIndent < 0 {
+				functionIndent = indent
+			}
+			if indent == functionIndent {
+				current = strings.Trim(strings.SplitN(trimmed, ":", 2)[0], `"'`)
+			}
+		}
+		owners[i] = current
+	}
+	return owners
+}
+
+func writeGrepMatches(w io.Writer, re *regexp.Regexp, matches []grepMatch) {
+	for _, match := range matches {
+		text := re.ReplaceAllStringFunc(match.text, func(s string) string { return output.Bold.Apply(s) })
+		fmt.Fprintf(w, "%s\t%s:%d: %s\n", output.Blue.Apply(strings.Join(match.functions, ",")), match.path, match.line, text)
+	}
+}
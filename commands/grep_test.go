@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_stackLineFunctions(t *testing.T) {
+	lines := strings.Split(`version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    lang: go
+    environment:
+      redis_host: redis
+
+  payments:
+    lang: python3
+configuration:
+  copy:
+    - ./common`, "\n")
+
+	owners := stackLineFunctions(lines)
+	want := []string{"", "", "", "", "orders", "orders", "orders", "orders", "orders", "payments", "payments", "", "", ""}
+	if strings.Join(owners, ",") != strings.Join(want, ",") {
+		t.Fatalf("want: %v, got: %v", want, owners)
+	}
+}
+
+func Test_grepStack(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "shared")
+	for path, content := range map[string]string{
+		filepath.Join(shared, "handler.go"):                    "package function\n\nvar host = os.Getenv(\"redis_host\")\n",
+		filepath.Join(shared, "node_modules", "redis", "x.js"): "redis_host\n",
+		filepath.Join(shared, "binary"):                        "redis_host\x00",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	stackYAML := `functions:
+  orders:
+    handler: ` + shared + `
+    environment:
+      redis_host: redis
+  payments:
+    handler: ` + shared + `
+`
+	if err := os.WriteFile(stackFile, []byte(stackYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	functions := map[string]stack.Function{
+		"orders":   {Handler: shared},
+		"payments": {Handler: shared},
+	}
+	matches, err := grepStack(regexp.MustCompile("redis_host"), stackFile, functions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 2 {
+		t.Fatalf("want 2 matches, got: %+v", matches)
+	}
+	if matches[0].path != stackFile || matches[0].line != 5 || matches[0].functions[0] != "orders" {
+		t.Errorf("want the stack file's match to belong to orders, got: %+v", matches[0])
+	}
+	if matches[1].line != 3 || strings.Join(matches[1].functions, ",") != "orders,payments" {
+		t.Errorf("want the shared handler's match to belong to both functions, got: %+v", matches[1])
+	}
+}
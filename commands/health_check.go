@@ -0,0 +1,125 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+const (
+	healthAnnotationPrefix = "com.openfaas.health.http."
+	readyAnnotationPrefix  = "com.openfaas.ready.http."
+
+	// watchdogHealthPath is served by both watchdogs without calling the function
+	watchdogHealthPath = "/_/health"
+)
+
+// healthAnnotations translates the health and readiness checks of a function
+// in the stack file to the annotations read by the provider. Warnings are
+// returned when the template's watchdog would not serve a custom path itself.
+func healthAnnotations(function stack.Function) (map[string]string, []string, error) {
+	annotations := map[string]string{}
+	var warnings []string
+
+	checks := []struct {
+		name   string
+		prefix string
+		check  *stack.HealthCheck
+	}{
+		{"health", healthAnnotationPrefix, function.Health},
+		{"readiness", readyAnnotationPrefix, function.Readiness},
+	}
+
+	for _, c := range checks {
+		if c.check == nil {
+			continue
+		}
+
+		values, err := healthCheckAnnotations(c.prefix, *c.check)
+		if err != nil {
+			return nil, nil, fmt.Errorf("function '%s' has an invalid %s check: %w", function.Name, c.name, err)
+		}
+
+		for key, value := range values {
+			if function.Annotations != nil {
+				if existing, ok := (*function.Annotations)[key]; ok && existing != value {
+					return nil, nil, fmt.Errorf("function '%s' sets %s to %q in annotations, and %q in its %s check, remove one of them",
+						function.Name, key, existing, value, c.name)
+				}
+			}
+			annotations[key] = value
+		}
+
+		if len(c.check.Path) > 0 && c.check.Path != watchdogHealthPath {
+			if warning := watchdogPathWarning(function.Language, c.check.Path); len(warning) > 0 {
+				warnings = append(warnings, fmt.Sprintf("function '%s' %s check: %s", function.Name, c.name, warning))
+			}
+		}
+	}
+
+	sort.Strings(warnings)
+	return annotations, warnings, nil
+}
+
+func healthCheckAnnotations(prefix string, check stack.HealthCheck) (map[string]string, error) {
+	annotations := map[string]string{}
+
+	if len(check.Path) > 0 {
+		if !strings.HasPrefix(check.Path, "/") {
+			return nil, fmt.Errorf("path %q must start with /", check.Path)
+		}
+		annotations[prefix+"path"] = check.Path
+	}
+
+	if len(check.InitialDelay) > 0 {
+		delay, err := time.ParseDuration(check.InitialDelay)
+		if err != nil || delay < 0 {
+			return nil, fmt.Errorf("initial_delay %q must be a duration such as 2s", check.InitialDelay)
+		}
+		annotations[prefix+"initialDelay"] = delay.String()
+	}
+
+	if len(check.Period) > 0 {
+		period, err := time.ParseDuration(check.Period)
+		if err != nil || period < time.Second || period%time.Second != 0 {
+			return nil, fmt.Errorf("period %q must be a duration of whole seconds such as 5s", check.Period)
+		}
+		annotations[prefix+"periodSeconds"] = fmt.Sprintf("%d", int(period.Seconds()))
+	}
+
+	return annotations, nil
+}
+
+// watchdogPathWarning explains what happens to a custom check path, based on
+// the mode which the template's Dockerfile sets for the watchdog
+func watchdogPathWarning(language, path string) string {
+	if !languageExistsNotDockerfile(language) {
+		return ""
+	}
+
+	env, err := dockerfileEnv(filepath.Join(templateDirectory, language, "Dockerfile"))
+	if err != nil {
+		return ""
+	}
+
+	switch mode := env["mode"]; mode {
+	case "", "serializing", "streaming":
+		watchdog := "the classic watchdog"
+		if len(mode) > 0 {
+			watchdog = fmt.Sprintf("the watchdog's %s mode", mode)
+		}
+		return fmt.Sprintf("%s starts a process for every request, so each check of %s runs the function, use %s unless that is intended",
+			watchdog, path, watchdogHealthPath)
+	case "static":
+		return fmt.Sprintf("the watchdog's static mode serves files, so %s is only healthy when it exists in the function's public folder", path)
+	}
+
+	return ""
+}
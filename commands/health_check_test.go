@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_healthAnnotations(t *testing.T) {
+	function := stack.Function{
+		Name:      "fn1",
+		Language:  "health-test-missing-lang",
+		Health:    &stack.HealthCheck{Path: "/healthz", InitialDelay: "2s", Period: "5s"},
+		Readiness: &stack.HealthCheck{Path: "/ready"},
+	}
+
+	got, warnings, err := healthAnnotations(function)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"com.openfaas.health.http.path":          "/healthz",
+		"com.openfaas.health.http.initialDelay":  "2s",
+		"com.openfaas.health.http.periodSeconds": "5",
+		"com.openfaas.ready.http.path":           "/ready",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("want no warnings without a template, got: %v", warnings)
+	}
+}
+
+func Test_healthAnnotations_Invalid(t *testing.T) {
+	cases := []stack.HealthCheck{
+		{Path: "healthz"},
+		{InitialDelay: "soon"},
+		{Period: "500ms"},
+		{Period: "1.5s"},
+	}
+
+	for _, check := range cases {
+		check := check
+		if _, _, err := healthAnnotations(stack.Function{Name: "fn1", Health: &check}); err == nil {
+			t.Errorf("want an error for %+v", check)
+		}
+	}
+}
+
+func Test_healthAnnotations_ConflictsWithAnnotation(t *testing.T) {
+	annotations := map[string]string{"com.openfaas.health.http.path": "/other"}
+	function := stack.Function{Name: "fn1", Annotations: &annotations, Health: &stack.HealthCheck{Path: "/healthz"}}
+
+	_, _, err := healthAnnotations(function)
+	if err == nil || !strings.Contains(err.Error(), "remove one of them") {
+		t.Fatalf("want a conflict, got: %v", err)
+	}
+}
+
+func Test_watchdogPathWarning_ClassicWatchdog(t *testing.T) {
+	lang := "health-check-test"
+	dir := filepath.Join(templateDirectory, lang)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM ghcr.io/openfaas/classic-watchdog:0.2.1\nENV fprocess=\"node index.js\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "template.yml"), []byte("language: "+lang+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if warning := watchdogPathWarning(lang, "/healthz"); !strings.Contains(warning, "classic watchdog") {
+		t.Fatalf("want a warning for the classic watchdog, got: %q", warning)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM ghcr.io/openfaas/of-watchdog:0.9.10\nENV mode=\"http\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if warning := watchdogPathWarning(lang, "/healthz"); len(warning) > 0 {
+		t.Fatalf("want no warning in http mode, got: %q", warning)
+	}
+}
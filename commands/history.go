@@ -0,0 +1,373 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// historyDir is where a changeset is written for each deployment
+const historyDir = ".openfaas/history"
+
+// recordHistory controls whether deploy writes a changeset
+var recordHistory bool
+
+var historyNamespace string
+
+func init() {
+	deployCmd.Flags().BoolVar(&recordHistory, "history", false, "Record each deployment as a changeset in "+historyDir+" of the current directory")
+
+	historyCmd.Flags().StringVarP(&historyNamespace, "namespace", "n", "", "Only show changesets for the given namespace")
+
+	faasCmd.AddCommand(historyCmd)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   `history [FUNCTION_NAME] [--namespace NAMESPACE]`,
+	Short: "List the changesets recorded by deploy",
+	Long: `Lists the changesets written to ` + historyDir + ` by "faas-cli deploy --history",
+oldest first. Each changeset records who deployed which function, when, to which
+gateway, and what changed since the previous changeset for that function.
+
+A changeset can be deployed again with "faas-cli apply-changeset".`,
+	Example: `  faas-cli history
+  faas-cli history figlet
+  faas-cli history figlet --namespace staging`,
+	RunE: runHistory,
+}
+
+// changeset is a single recorded deployment
+type changeset struct {
+	ID        string        `json:"id"`
+	Function  string        `json:"function"`
+	Namespace string        `json:"namespace"`
+	Gateway   string        `json:"gateway"`
+	User      string        `json:"user"`
+	Timestamp time.Time     `json:"timestamp"`
+	Source    string        `json:"source,omitempty"`
+	Changes   []string      `json:"changes"`
+	Spec      changesetSpec `json:"spec"`
+}
+
+// changesetSpec holds the parts of a deployment which are sent to the gateway,
+// the token and other client-side settings are never written to disk.
+type changesetSpec struct {
+	Image                  string                   `json:"image"`
+	FProcess               string                   `json:"fprocess,omitempty"`
+	Language               string                   `json:"language,omitempty"`
+	Network                string                   `json:"network,omitempty"`
+	EnvVars                map[string]string        `json:"environment,omitempty"`
+	Constraints            []string                 `json:"constraints,omitempty"`
+	Secrets                []string                 `json:"secrets,omitempty"`
+	Labels                 map[string]string        `json:"labels,omitempty"`
+	Annotations            map[string]string        `json:"annotations,omitempty"`
+	Limits                 *stack.FunctionResources `json:"limits,omitempty"`
+	Requests               *stack.FunctionResources `json:"requests,omitempty"`
+	ReadOnlyRootFilesystem bool                     `json:"readOnlyRootFilesystem,omitempty"`
+	Shms                   []string                 `json:"shms,omitempty"`
+	Privileged             bool                     `json:"privileged,omitempty"`
+	RunAsUser              string                   `json:"runAsUser,omitempty"`
+}
+
+func newChangesetSpec(spec *proxy.DeployFunctionSpec) changesetSpec {
+	return changesetSpec{
+		Image:                  spec.Image,
+		FProcess:               spec.FProcess,
+		Language:               spec.Language,
+		Network:                spec.Network,
+		EnvVars:                spec.EnvVars,
+		Constraints:            spec.Constraints,
+		Secrets:                spec.Secrets,
+		Labels:                 spec.Labels,
+		Annotations:            withoutRevisions(spec.Annotations),
+		Limits:                 spec.FunctionResourceRequest.Limits,
+		Requests:               spec.FunctionResourceRequest.Requests,
+		ReadOnlyRootFilesystem: spec.ReadOnlyRootFilesystem,
+		Shms:                   spec.Shms,
+		Privileged:             spec.Privileged,
+		RunAsUser:              spec.RunAsUser,
+	}
+}
+
+// deploySpec converts the changeset back into a spec which performs a rolling update
+func (c changeset) deploySpec() *proxy.DeployFunctionSpec {
+	s := c.Spec
+	return &proxy.DeployFunctionSpec{
+		FProcess:     s.FProcess,
+		FunctionName: c.Function,
+		Image:        s.Image,
+		Language:     s.Language,
+		Network:      s.Network,
+		EnvVars:      s.EnvVars,
+		Constraints:  s.Constraints,
+		Secrets:      s.Secrets,
+		Labels:       s.Labels,
+		Annotations:  s.Annotations,
+		FunctionResourceRequest: proxy.FunctionResourceRequest{
+			Limits:   s.Limits,
+			Requests: s.Requests,
+		},
+		ReadOnlyRootFilesystem: s.ReadOnlyRootFilesystem,
+		Update:                 true,
+		Namespace:              c.Namespace,
+		Shms:                   s.Shms,
+		Privileged:             s.Privileged,
+		RunAsUser:              s.RunAsUser,
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("give at most one function name")
+	}
+
+	changesets, err := loadChangesets(historyDir)
+	if err != nil {
+		return err
+	}
+
+	filtered := []changeset{}
+	for _, c := range changesets {
+		if len(args) == 1 && c.Function != args[0] {
+			continue
+		}
+		if len(historyNamespace) > 0 && c.Namespace != historyNamespace {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+
+	if len(filtered) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No changesets found in %s\n", historyDir)
+		return nil
+	}
+
+	printHistory(cmd.OutOrStdout(), filtered)
+	return nil
+}
+
+func printHistory(w io.Writer, changesets []changeset) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "ID\tFUNCTION\tNAMESPACE\tUSER\tDEPLOYED\tCHANGES")
+	for _, c := range changesets {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.ID, c.Function, c.Namespace, c.User,
+			c.Timestamp.Local().Format(time.RFC3339),
+			strings.Join(c.Changes, ", "))
+	}
+}
+
+// recordChangeset writes a changeset for a successful deployment to dir,
+// describing what changed since the last changeset for the same function.
+func recordChangeset(dir, gateway string, spec *proxy.DeployFunctionSpec, source string) (*changeset, error) {
+	namespace := spec.Namespace
+	if len(namespace) == 0 {
+		namespace = defaultFunctionNamespace
+	}
+
+	existing, err := loadChangesets(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous *changeset
+	for i := range existing {
+		if existing[i].Function == spec.FunctionName && existing[i].Namespace == namespace {
+			previous = &existing[i]
+		}
+	}
+
+	now := time.Now().UTC()
+	c := changeset{
+		ID:        fmt.Sprintf("%s-%s", now.Format("20060102T150405.000000000Z"), spec.FunctionName),
+		Function:  spec.FunctionName,
+		Namespace: namespace,
+		Gateway:   gateway,
+		User:      currentUsername(),
+		Timestamp: now,
+		Source:    source,
+		Spec:      newChangesetSpec(spec),
+	}
+
+	if previous == nil {
+		c.Changes = []string{"created"}
+	} else {
+		c.Changes = diffChangesetSpecs(previous.Spec, c.Spec)
+		if len(c.Changes) == 0 {
+			c.Changes = []string{"no changes"}
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, c.ID+".json"), append(out, '\n'), 0600); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// loadChangesets reads every changeset within dir, oldest first. A missing
+// directory is not an error.
+func loadChangesets(dir string) ([]changeset, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	changesets := []changeset{}
+	for _, file := range files {
+		c, err := readChangeset(file)
+		if err != nil {
+			return nil, err
+		}
+		changesets = append(changesets, *c)
+	}
+
+	sort.SliceStable(changesets, func(i, j int) bool {
+		return changesets[i].Timestamp.Before(changesets[j].Timestamp)
+	})
+
+	return changesets, nil
+}
+
+func readChangeset(file string) (*changeset, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var c changeset
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("unable to parse changeset %s: %w", file, err)
+	}
+
+	if len(c.Function) == 0 || len(c.Spec.Image) == 0 {
+		return nil, fmt.Errorf("changeset %s must give a function and an image", file)
+	}
+
+	return &c, nil
+}
+
+// diffChangesetSpecs lists the fields which differ between two specs, for
+// maps the individual keys are named, but values are not to avoid leaking
+// anything sensitive into the summary.
+func diffChangesetSpecs(before, after changesetSpec) []string {
+	var changes []string
+
+	if before.Image != after.Image {
+		changes = append(changes, fmt.Sprintf("image %s -> %s", before.Image, after.Image))
+	}
+
+	changes = append(changes, diffStringMaps("env", before.EnvVars, after.EnvVars)...)
+	changes = append(changes, diffStringMaps("label", before.Labels, after.Labels)...)
+	changes = append(changes, diffStringMaps("annotation", before.Annotations, after.Annotations)...)
+	changes = append(changes, diffStringSlices("secret", before.Secrets, after.Secrets)...)
+	changes = append(changes, diffStringSlices("constraint", before.Constraints, after.Constraints)...)
+
+	scalar := []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"fprocess", before.FProcess, after.FProcess},
+		{"network", before.Network, after.Network},
+		{"limits", before.Limits, after.Limits},
+		{"requests", before.Requests, after.Requests},
+		{"readOnlyRootFilesystem", before.ReadOnlyRootFilesystem, after.ReadOnlyRootFilesystem},
+		{"shms", before.Shms, after.Shms},
+		{"privileged", before.Privileged, after.Privileged},
+		{"runAsUser", before.RunAsUser, after.RunAsUser},
+	}
+
+	for _, field := range scalar {
+		if !reflect.DeepEqual(field.before, field.after) {
+			changes = append(changes, field.name+" changed")
+		}
+	}
+
+	return changes
+}
+
+func diffStringMaps(kind string, before, after map[string]string) []string {
+	var changes []string
+	for _, k := range sortedKeys(after) {
+		if v, ok := before[k]; !ok {
+			changes = append(changes, fmt.Sprintf("%s %s added", kind, k))
+		} else if v != after[k] {
+			changes = append(changes, fmt.Sprintf("%s %s changed", kind, k))
+		}
+	}
+	for _, k := range sortedKeys(before) {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, fmt.Sprintf("%s %s removed", kind, k))
+		}
+	}
+	return changes
+}
+
+func diffStringSlices(kind string, before, after []string) []string {
+	inBefore, inAfter := map[string]bool{}, map[string]bool{}
+	for _, v := range before {
+		inBefore[v] = true
+	}
+	for _, v := range after {
+		inAfter[v] = true
+	}
+
+	var changes []string
+	for _, v := range after {
+		if !inBefore[v] {
+			changes = append(changes, fmt.Sprintf("%s %s added", kind, v))
+		}
+	}
+	for _, v := range before {
+		if !inAfter[v] {
+			changes = append(changes, fmt.Sprintf("%s %s removed", kind, v))
+		}
+	}
+	return changes
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && len(u.Username) > 0 {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); len(name) > 0 {
+		return name
+	}
+	return "unknown"
+}
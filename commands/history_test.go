@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_recordChangeset_DescribesChanges(t *testing.T) {
+	dir := t.TempDir()
+
+	spec := &proxy.DeployFunctionSpec{
+		FunctionName: "figlet",
+		Image:        "ghcr.io/openfaas/figlet:0.1.0",
+		EnvVars:      map[string]string{"write_debug": "true"},
+		Secrets:      []string{"api-key"},
+		Token:        "do-not-store",
+	}
+
+	first, err := recordChangeset(dir, "http://127.0.0.1:8080", spec, "deploy -f stack.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(first.Changes, []string{"created"}) {
+		t.Errorf("want the first changeset to be created, got %v", first.Changes)
+	}
+
+	if first.Namespace != defaultFunctionNamespace {
+		t.Errorf("want namespace %s, got %s", defaultFunctionNamespace, first.Namespace)
+	}
+
+	spec.Image = "ghcr.io/openfaas/figlet:0.2.0"
+	spec.EnvVars = map[string]string{"write_debug": "false", "exec_timeout": "10s"}
+	spec.Secrets = nil
+
+	second, err := recordChangeset(dir, "http://127.0.0.1:8080", spec, "deploy -f stack.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"image ghcr.io/openfaas/figlet:0.1.0 -> ghcr.io/openfaas/figlet:0.2.0",
+		"env exec_timeout added",
+		"env write_debug changed",
+		"secret api-key removed",
+	}
+	if !reflect.DeepEqual(second.Changes, want) {
+		t.Errorf("want changes:\n%v\ngot:\n%v", want, second.Changes)
+	}
+
+	changesets, err := loadChangesets(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changesets) != 2 || changesets[0].ID != first.ID || changesets[1].ID != second.ID {
+		t.Fatalf("want both changesets oldest first, got %v", changesets)
+	}
+}
+
+func Test_changeset_deploySpecRoundTrip(t *testing.T) {
+	spec := &proxy.DeployFunctionSpec{
+		FunctionName: "figlet",
+		Image:        "ghcr.io/openfaas/figlet:0.1.0",
+		EnvVars:      map[string]string{"write_debug": "true"},
+		Labels:       map[string]string{"team": "a"},
+		FunctionResourceRequest: proxy.FunctionResourceRequest{
+			Limits: &stack.FunctionResources{Memory: "128Mi"},
+		},
+		Namespace: "staging",
+	}
+
+	c := changeset{Function: spec.FunctionName, Namespace: spec.Namespace, Spec: newChangesetSpec(spec)}
+	got := c.deploySpec()
+
+	if !got.Update {
+		t.Errorf("want a changeset to be applied as an update")
+	}
+
+	got.Update = false
+	if !reflect.DeepEqual(got, spec) {
+		t.Errorf("want:\n%+v\ngot:\n%+v", spec, got)
+	}
+}
+
+func Test_loadChangesets_MissingDirectory(t *testing.T) {
+	changesets, err := loadChangesets(t.TempDir() + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changesets) != 0 {
+		t.Errorf("want no changesets, got %d", len(changesets))
+	}
+}
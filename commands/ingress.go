@@ -0,0 +1,214 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	functionIngressAPIVersion = "openfaas.com/v1"
+	functionIngressKind       = "FunctionIngress"
+
+	// ingressDefaultNamespace is where the ingress-operator watches for
+	// FunctionIngress resources, alongside the gateway
+	ingressDefaultNamespace = "openfaas"
+)
+
+// ingressOptions are the flags of "ingress create" and "ingress list"
+type ingressOptions struct {
+	domain      string
+	tlsIssuer   string
+	issuerKind  string
+	ingressType string
+	path        string
+	namespace   string
+	kubeContext string
+	print       bool
+}
+
+var ingressFlags ingressOptions
+
+var ingressDomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+$`)
+
+func init() {
+	for _, cmd := range []*cobra.Command{ingressCreateCmd, ingressListCmd} {
+		cmd.Flags().StringVarP(&ingressFlags.namespace, "namespace", "n", ingressDefaultNamespace, "Namespace of the gateway, where the ingress-operator watches for FunctionIngresses")
+		cmd.Flags().StringVar(&ingressFlags.kubeContext, "context", "", "kubeconfig context to use with kubectl")
+	}
+
+	ingressCreateCmd.Flags().StringVar(&ingressFlags.domain, "domain", "", "Domain to route to the function, e.g. fn.example.com")
+	ingressCreateCmd.Flags().StringVar(&ingressFlags.tlsIssuer, "tls", "", "Name of a cert-manager issuer, to enable TLS for the domain")
+	ingressCreateCmd.Flags().StringVar(&ingressFlags.issuerKind, "issuer-kind", "Issuer", "Kind of the cert-manager issuer: Issuer or ClusterIssuer")
+	ingressCreateCmd.Flags().StringVar(&ingressFlags.ingressType, "ingress-type", "nginx", "Ingress controller which serves the domain, e.g. nginx or traefik")
+	ingressCreateCmd.Flags().StringVar(&ingressFlags.path, "path", "", "Path on the domain to route to the function, all paths by default")
+	ingressCreateCmd.Flags().BoolVar(&ingressFlags.print, "print", false, "Print the FunctionIngress instead of applying it")
+
+	markMutating(ingressCreateCmd)
+	ingressCmd.AddCommand(ingressCreateCmd, ingressListCmd)
+	faasCmd.AddCommand(ingressCmd)
+}
+
+var ingressCmd = &cobra.Command{
+	Use:   `ingress [create|list]`,
+	Short: "Manage custom domains for functions on Kubernetes",
+	Long: `Manages FunctionIngress resources, which the OpenFaaS ingress-operator turns
+into an Ingress for a custom domain, and a certificate when TLS is enabled.
+
+kubectl and a kubeconfig with access to the gateway's namespace are required.`,
+}
+
+var ingressCreateCmd = &cobra.Command{
+	Use:   `create FUNCTION_NAME --domain DOMAIN [--tls ISSUER]`,
+	Short: "Route a custom domain to a function",
+	Example: `  faas-cli ingress create nodeinfo --domain nodeinfo.example.com
+  faas-cli ingress create nodeinfo --domain nodeinfo.example.com --tls letsencrypt-prod
+  faas-cli ingress create nodeinfo --domain api.example.com --path "/nodeinfo/(.*)" \
+    --tls letsencrypt-prod --issuer-kind ClusterIssuer
+  faas-cli ingress create nodeinfo --domain nodeinfo.example.com --print`,
+	PreRunE: preRunIngressCreate,
+	RunE:    runIngressCreate,
+}
+
+var ingressListCmd = &cobra.Command{
+	Use:     `list [--namespace NAMESPACE]`,
+	Aliases: []string{"ls"},
+	Short:   "List the custom domains of functions",
+	Example: `  faas-cli ingress list
+  faas-cli ingress list --context production`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("kubectl must be installed to list ingresses")
+		}
+		return runExecTransport(ingressFlags.listCommand(), nil, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	},
+}
+
+func preRunIngressCreate(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the name of the function")
+	}
+
+	if !ingressDomainPattern.MatchString(ingressFlags.domain) {
+		return fmt.Errorf("give a lowercase domain with --domain, e.g. fn.example.com")
+	}
+
+	if ingressFlags.issuerKind != "Issuer" && ingressFlags.issuerKind != "ClusterIssuer" {
+		return fmt.Errorf("--issuer-kind must be Issuer or ClusterIssuer")
+	}
+
+	if !ingressFlags.print {
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("kubectl must be installed to create an ingress, or use --print")
+		}
+	}
+	return nil
+}
+
+func runIngressCreate(cmd *cobra.Command, args []string) error {
+	manifest, err := yaml.Marshal(ingressFlags.functionIngress(args[0]))
+	if err != nil {
+		return err
+	}
+
+	if ingressFlags.print {
+		fmt.Fprint(cmd.OutOrStdout(), string(manifest))
+		return nil
+	}
+
+	if err := runExecTransport(ingressFlags.applyCommand(), bytes.NewReader(manifest), cmd.OutOrStdout(), cmd.ErrOrStderr()); err != nil {
+		return err
+	}
+
+	scheme := "http"
+	if len(ingressFlags.tlsIssuer) > 0 {
+		scheme = "https"
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s will be served at: %s://%s%s once the ingress-operator has created the Ingress\n",
+		args[0], scheme, ingressFlags.domain, ingressFlags.path)
+	return nil
+}
+
+// functionIngress follows the FunctionIngress CRD of the ingress-operator
+type functionIngress struct {
+	APIVersion string                  `yaml:"apiVersion"`
+	Kind       string                  `yaml:"kind"`
+	Metadata   functionIngressMetadata `yaml:"metadata"`
+	Spec       functionIngressSpec     `yaml:"spec"`
+}
+
+type functionIngressMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type functionIngressSpec struct {
+	Domain      string              `yaml:"domain"`
+	Function    string              `yaml:"function"`
+	IngressType string              `yaml:"ingressType,omitempty"`
+	Path        string              `yaml:"path,omitempty"`
+	TLS         *functionIngressTLS `yaml:"tls,omitempty"`
+}
+
+type functionIngressTLS struct {
+	Enabled   bool                     `yaml:"enabled"`
+	IssuerRef functionIngressIssuerRef `yaml:"issuerRef"`
+}
+
+type functionIngressIssuerRef struct {
+	Name string `yaml:"name"`
+	Kind string `yaml:"kind"`
+}
+
+// functionIngress is named after the domain, so that a function can have
+// more than one, and creating it again for the same domain updates it
+func (o ingressOptions) functionIngress(function string) functionIngress {
+	fi := functionIngress{
+		APIVersion: functionIngressAPIVersion,
+		Kind:       functionIngressKind,
+		Metadata: functionIngressMetadata{
+			Name:      strings.ReplaceAll(o.domain, ".", "-"),
+			Namespace: o.namespace,
+		},
+		Spec: functionIngressSpec{
+			Domain:      o.domain,
+			Function:    function,
+			IngressType: o.ingressType,
+			Path:        o.path,
+		},
+	}
+
+	if len(o.tlsIssuer) > 0 {
+		fi.Spec.TLS = &functionIngressTLS{
+			Enabled:   true,
+			IssuerRef: functionIngressIssuerRef{Name: o.tlsIssuer, Kind: o.issuerKind},
+		}
+	}
+
+	return fi
+}
+
+func (o ingressOptions) kubectl() []string {
+	argv := []string{"kubectl"}
+	if len(o.kubeContext) > 0 {
+		argv = append(argv, "--context", o.kubeContext)
+	}
+	return append(argv, "--namespace", o.namespace)
+}
+
+func (o ingressOptions) applyCommand() []string {
+	return append(o.kubectl(), "apply", "-f", "-")
+}
+
+func (o ingressOptions) listCommand() []string {
+	return append(o.kubectl(), "get", "functioningresses",
+		"-o", "custom-columns=NAME:.metadata.name,FUNCTION:.spec.function,DOMAIN:.spec.domain,PATH:.spec.path,TLS:.spec.tls.enabled")
+}
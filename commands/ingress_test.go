@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func Test_functionIngress_WithTLS(t *testing.T) {
+	opts := ingressOptions{
+		domain:      "nodeinfo.example.com",
+		tlsIssuer:   "letsencrypt-prod",
+		issuerKind:  "ClusterIssuer",
+		ingressType: "nginx",
+		namespace:   "openfaas",
+	}
+
+	out, err := yaml.Marshal(opts.functionIngress("nodeinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `apiVersion: openfaas.com/v1
+kind: FunctionIngress
+metadata:
+  name: nodeinfo-example-com
+  namespace: openfaas
+spec:
+  domain: nodeinfo.example.com
+  function: nodeinfo
+  ingressType: nginx
+  tls:
+    enabled: true
+    issuerRef:
+      name: letsencrypt-prod
+      kind: ClusterIssuer
+`
+	if string(out) != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, string(out))
+	}
+}
+
+func Test_functionIngress_WithoutTLS(t *testing.T) {
+	opts := ingressOptions{domain: "fn.example.com", namespace: "openfaas", path: "/v1/(.*)"}
+
+	fi := opts.functionIngress("fn")
+	if fi.Spec.TLS != nil {
+		t.Fatalf("want no TLS without an issuer")
+	}
+	if fi.Spec.Path != "/v1/(.*)" {
+		t.Fatalf("want the path, got: %q", fi.Spec.Path)
+	}
+}
+
+func Test_ingressOptions_Commands(t *testing.T) {
+	opts := ingressOptions{namespace: "openfaas", kubeContext: "prod"}
+
+	if got := strings.Join(opts.applyCommand(), " "); got != "kubectl --context prod --namespace openfaas apply -f -" {
+		t.Fatalf("unexpected apply command: %s", got)
+	}
+	if got := strings.Join(opts.listCommand(), " "); !strings.HasPrefix(got, "kubectl --context prod --namespace openfaas get functioningresses") {
+		t.Fatalf("unexpected list command: %s", got)
+	}
+}
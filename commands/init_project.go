@@ -0,0 +1,452 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moby/term"
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/versioncontrol"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// initFunctionsDir holds the handler of each function in the project
+	initFunctionsDir = "functions"
+	// initProfilesDir holds an environment file for each profile
+	initProfilesDir = "profiles"
+
+	initDefaultFunction = "hello=python3-http"
+
+	initCIGitHub = "github"
+	initCIGitLab = "gitlab"
+	initCINone   = "none"
+)
+
+// initProfiles are written as environment files, and chosen by OPENFAAS_PROFILE
+var initProfiles = []string{"dev", "prod"}
+
+var (
+	initGateway      string
+	initPrefix       string
+	initFunctions    []string
+	initTemplateRepo string
+	initCI           string
+	initYes          bool
+)
+
+// initInteractive is true when the answers can be prompted for
+var initInteractive = func() bool {
+	return term.IsTerminal(os.Stdin.Fd()) && !builder.IsRunningInCI()
+}
+
+// pullInitTemplates pulls the templates of the project into ./template
+var pullInitTemplates = func(source string) error {
+	return pullTemplate(source)
+}
+
+// latestTemplateTag is the newest tag of a template repository, so that the
+// project builds with the same templates until it is changed on purpose
+var latestTemplateTag = func(repository string) (string, error) {
+	out, err := exec.Command("git", "ls-remote", "--tags", "--refs", "--sort=-v:refname", repository).Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to list the tags of %s: %w", repository, err)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.HasPrefix(fields[1], "refs/tags/") {
+			return strings.TrimPrefix(fields[1], "refs/tags/"), nil
+		}
+	}
+	return "", fmt.Errorf("no tags found for %s", repository)
+}
+
+func init() {
+	initProjectCmd.Flags().StringVarP(&initGateway, "gateway", "g", "", "Gateway URL for the stack file, defaults to "+defaultGateway)
+	initProjectCmd.Flags().StringVarP(&initPrefix, "prefix", "p", "", "Prefix for the images of the functions, such as a registry and account")
+	initProjectCmd.Flags().StringArrayVar(&initFunctions, "function", []string{}, "Function to create (NAME=LANG), may be given more than once")
+	initProjectCmd.Flags().StringVar(&initTemplateRepo, "template-repo", "", "Git repository of the templates, pinned with #REF, or to its latest tag when no ref is given")
+	initProjectCmd.Flags().StringVar(&initCI, "ci", "", "CI pipeline to write: github, gitlab or none, defaults to github")
+	initProjectCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "Use the flags and defaults without prompting")
+
+	faasCmd.AddCommand(initProjectCmd)
+}
+
+var initProjectCmd = &cobra.Command{
+	Use:   `init [DIRECTORY] [--function NAME=LANG ...] [--ci github|gitlab|none] [--yes]`,
+	Short: "Create a project for several functions",
+	Long: `Creates a project in the given directory, or the current one, with:
+
+- stack.yml with the provider, and each function's handler in functions/NAME
+- the templates of the functions, pulled and pinned to a tag in stack.yml
+- an environment file for the dev and prod profiles, in profiles/, which is
+  chosen by OPENFAAS_PROFILE, and defaults to dev
+- a .gitignore, and a CI pipeline which builds the functions
+
+Any value not given by a flag is asked for, unless --yes is given or the
+input is not a terminal, when the defaults are used.`,
+	Example: `  faas-cli init
+  faas-cli init shop --function orders=golang-middleware --function emails=node18
+  faas-cli init --prefix ghcr.io/acme --ci gitlab --yes
+  OPENFAAS_PROFILE=prod faas-cli up`,
+	PreRunE: preRunInitProject,
+	RunE:    runInitProject,
+}
+
+// initFunction is a function to create in the project
+type initFunction struct {
+	Name     string
+	Language string
+}
+
+func preRunInitProject(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("give one directory for the project")
+	}
+
+	if len(initCI) > 0 {
+		if err := validateInitCI(initCI); err != nil {
+			return err
+		}
+	}
+
+	if _, err := parseInitFunctions(initFunctions); err != nil {
+		return err
+	}
+	return nil
+}
+
+func runInitProject(cmd *cobra.Command, args []string) error {
+	if len(args) == 1 {
+		if err := os.MkdirAll(args[0], 0755); err != nil {
+			return fmt.Errorf("unable to create %s: %w", args[0], err)
+		}
+		// Templates are pulled into ./template, so the project must be the
+		// working directory
+		if err := os.Chdir(args[0]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(defaultYAML); err == nil {
+		return fmt.Errorf("%s already exists, use \"faas-cli new --append %s\" to add functions", defaultYAML, defaultYAML)
+	}
+
+	prompt := newInitPrompt(cmd.InOrStdin(), cmd.OutOrStdout(), !initYes && initInteractive())
+
+	gatewayURL := initGateway
+	if len(gatewayURL) == 0 {
+		gatewayURL = prompt.ask("Gateway URL", defaultGateway)
+	}
+	prefix := initPrefix
+	if len(prefix) == 0 {
+		prefix = prompt.ask("Image prefix, such as ghcr.io/ACCOUNT (optional)", getPrefixValue())
+	}
+
+	functions, _ := parseInitFunctions(initFunctions)
+	if len(functions) == 0 {
+		answer := prompt.ask("Functions, as NAME=LANG separated by commas", initDefaultFunction)
+		var err error
+		if functions, err = parseInitFunctions(strings.Split(answer, ",")); err != nil {
+			return err
+		}
+	}
+
+	if len(functions) == 0 {
+		return fmt.Errorf("give at least one function as NAME=LANG")
+	}
+
+	ci := initCI
+	if len(ci) == 0 {
+		ci = prompt.ask("CI pipeline: github, gitlab or none", initCIGitHub)
+		if err := validateInitCI(ci); err != nil {
+			return err
+		}
+	}
+
+	repository := initTemplateRepo
+	if len(repository) == 0 {
+		repository = getTemplateURL("", os.Getenv(templateURLEnvironment), DefaultTemplateRepository)
+	}
+	source := pinTemplateSource(repository)
+
+	if err := pullInitTemplates(source); err != nil {
+		return err
+	}
+
+	for _, fn := range functions {
+		if !stack.IsValidTemplate(fn.Language) {
+			return fmt.Errorf("template: %q was not found in %s", fn.Language, repository)
+		}
+	}
+
+	for _, fn := range functions {
+		if err := copyInitHandler(fn); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Function: %s created in %s\n", fn.Name, filepath.Join(initFunctionsDir, fn.Name))
+	}
+
+	files := map[string]string{
+		defaultYAML: initStackFile(gatewayURL, prefix, source, functions),
+	}
+	for _, profile := range initProfiles {
+		files[filepath.Join(initProfilesDir, profile+".yml")] = initProfileFile(profile)
+	}
+	if path, content := initPipeline(ci); len(path) > 0 {
+		files[path] = content
+	}
+
+	for _, path := range sortedKeys(files) {
+		if err := writeInitFile(path, files[path]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Written: %s\n", path)
+	}
+
+	if err := updateGitignore(); err != nil {
+		return fmt.Errorf("got unexpected error while updating .gitignore file: %s", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "\nBuild and run a function with: faas-cli local-run %s --build\n", functions[0].Name)
+	fmt.Fprintf(cmd.OutOrStdout(), "Deploy with the prod profile: OPENFAAS_PROFILE=prod faas-cli up\n")
+	return nil
+}
+
+func validateInitCI(ci string) error {
+	switch ci {
+	case initCIGitHub, initCIGitLab, initCINone:
+		return nil
+	}
+	return fmt.Errorf("--ci must be one of: %s, %s, %s", initCIGitHub, initCIGitLab, initCINone)
+}
+
+// parseInitFunctions reads NAME=LANG pairs, blank values are skipped
+func parseInitFunctions(values []string) ([]initFunction, error) {
+	functions := []initFunction{}
+	seen := map[string]bool{}
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) == 0 {
+			continue
+		}
+
+		name, lang, ok := strings.Cut(value, "=")
+		name, lang = strings.TrimSpace(name), strings.TrimSpace(lang)
+		if !ok || len(lang) == 0 {
+			return nil, fmt.Errorf("give each function as NAME=LANG, got: %s", value)
+		}
+		if err := validateFunctionName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("function %s was given more than once", name)
+		}
+		seen[name] = true
+
+		functions = append(functions, initFunction{Name: name, Language: lang})
+	}
+	return functions, nil
+}
+
+// pinTemplateSource pins the repository to its latest tag, unless it is
+// already pinned or is a local folder
+func pinTemplateSource(repository string) string {
+	if versioncontrol.IsPinnedGitRemote(repository) || !versioncontrol.IsGitRemote(repository) {
+		return repository
+	}
+
+	tag, err := latestTemplateTag(repository)
+	if err != nil {
+		fmt.Printf("Unable to pin the templates, the default branch will be used: %s\n", err)
+		return repository
+	}
+	return repository + "#" + tag
+}
+
+func copyInitHandler(fn initFunction) error {
+	handlerDir := filepath.Join(initFunctionsDir, fn.Name)
+	if _, err := os.Stat(handlerDir); err == nil {
+		return fmt.Errorf("folder: %s already exists", handlerDir)
+	}
+
+	langTemplate, err := stack.LoadLanguageTemplate(fn.Language)
+	if err != nil {
+		return fmt.Errorf("error reading language template: %s", err.Error())
+	}
+
+	templateHandlerFolder := "function"
+	if len(langTemplate.HandlerFolder) > 0 {
+		templateHandlerFolder = langTemplate.HandlerFolder
+	}
+
+	if err := os.MkdirAll(handlerDir, 0700); err != nil {
+		return fmt.Errorf("folder: could not create %s : %s", handlerDir, err)
+	}
+	return builder.CopyFiles(filepath.Join("template", fn.Language, templateHandlerFolder), handlerDir)
+}
+
+func initStackFile(gatewayURL, prefix, source string, functions []initFunction) string {
+	content := `version: ` + defaultSchemaVersion + `
+provider:
+  name: openfaas
+  gateway: ` + gatewayURL + `
+
+configuration:
+  templates:
+`
+	languages := map[string]bool{}
+	for _, fn := range functions {
+		languages[fn.Language] = true
+	}
+	names := make([]string, 0, len(languages))
+	for lang := range languages {
+		names = append(names, lang)
+	}
+	sort.Strings(names)
+
+	for _, lang := range names {
+		content += `    - name: ` + lang + `
+      source: ` + source + `
+`
+	}
+
+	content += `
+functions:
+`
+	for _, fn := range functions {
+		image := fn.Name + ":latest"
+		if prefix = strings.TrimSpace(prefix); len(prefix) > 0 {
+			image = strings.TrimRight(prefix, "/") + "/" + image
+		}
+
+		content += `  ` + fn.Name + `:
+    lang: ` + fn.Language + `
+    handler: ./` + initFunctionsDir + `/` + fn.Name + `
+    image: ` + image + `
+    environment_file:
+      - ./` + initProfilesDir + `/${OPENFAAS_PROFILE:-dev}.yml
+    # Only deploy a function with a given profile with:
+    # enabled: profile == "dev"
+
+`
+	}
+
+	return content
+}
+
+func initProfileFile(profile string) string {
+	writeDebug := "false"
+	if profile == "dev" {
+		writeDebug = "true"
+	}
+
+	return `# Environment for the ` + profile + ` profile, used when OPENFAAS_PROFILE=` + profile + `
+environment:
+  write_debug: "` + writeDebug + `"
+`
+}
+
+// initPipeline is the path and content of the CI pipeline, which checks the
+// stack file and builds the functions
+func initPipeline(ci string) (string, string) {
+	switch ci {
+	case initCIGitHub:
+		return filepath.Join(".github", "workflows", "openfaas.yml"), `name: openfaas
+
+on:
+  push:
+    branches: [ main ]
+  pull_request:
+
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - name: Get faas-cli
+        run: curl -sLSf https://cli.openfaas.com | sudo sh
+      - name: Pull the templates
+        run: faas-cli template pull stack
+      - name: Check the stack file
+        run: faas-cli stack render > /dev/null
+      - name: Build the functions
+        run: faas-cli build
+      # Log in to your registry and gateway, then publish and deploy with:
+      # - run: OPENFAAS_PROFILE=prod faas-cli up
+`
+	case initCIGitLab:
+		return ".gitlab-ci.yml", `stages:
+  - build
+
+build:
+  stage: build
+  image: docker:latest
+  services:
+    - docker:dind
+  before_script:
+    - apk add --no-cache curl git
+    - curl -sLSf https://cli.openfaas.com | sh
+    - faas-cli template pull stack
+  script:
+    - faas-cli stack render > /dev/null
+    - faas-cli build
+    # Log in to your registry and gateway, then publish and deploy with:
+    # - OPENFAAS_PROFILE=prod faas-cli up
+`
+	}
+	return "", ""
+}
+
+func writeInitFile(path, content string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("file: %s already exists", path)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// initPrompt asks for a value, or gives the default when not interactive
+type initPrompt struct {
+	in          *bufio.Reader
+	out         io.Writer
+	interactive bool
+}
+
+func newInitPrompt(in io.Reader, out io.Writer, interactive bool) *initPrompt {
+	return &initPrompt{in: bufio.NewReader(in), out: out, interactive: interactive}
+}
+
+func (p *initPrompt) ask(question, defaultValue string) string {
+	if !p.interactive {
+		return defaultValue
+	}
+
+	if len(defaultValue) > 0 {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	answer, _ := p.in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); len(answer) > 0 {
+		return answer
+	}
+	return defaultValue
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_initProject_Scaffold(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+
+	savedPull, savedTag := pullInitTemplates, latestTemplateTag
+	defer func() {
+		pullInitTemplates, latestTemplateTag = savedPull, savedTag
+		initFunctions, initPrefix, initGateway, initCI, initYes = []string{}, "", "", "", false
+	}()
+
+	pulled := ""
+	pullInitTemplates = func(source string) error {
+		pulled = source
+		os.MkdirAll(filepath.Join("template", "python3-http", "function"), 0700)
+		os.WriteFile(filepath.Join("template", "python3-http", "template.yml"), []byte("language: python3-http\nfprocess: python index.py\n"), 0600)
+		return os.WriteFile(filepath.Join("template", "python3-http", "function", "handler.py"), []byte("def handle(event, context):\n"), 0600)
+	}
+	latestTemplateTag = func(repository string) (string, error) {
+		return "1.2.3", nil
+	}
+
+	resetForTest()
+	var buf bytes.Buffer
+	faasCmd.SetOut(&buf)
+	defer faasCmd.SetOut(nil)
+
+	project := filepath.Join(dir, "shop")
+	faasCmd.SetArgs([]string{"init", project,
+		"--function", "orders=python3-http",
+		"--function", "emails=python3-http",
+		"--prefix", "ghcr.io/acme",
+		"--ci", "gitlab",
+		"--yes",
+	})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := DefaultTemplateRepository + "#1.2.3"; pulled != want {
+		t.Errorf("want the templates pulled from: %s, got: %s", want, pulled)
+	}
+
+	for _, path := range []string{
+		"functions/orders/handler.py",
+		"functions/emails/handler.py",
+		"profiles/dev.yml",
+		"profiles/prod.yml",
+		".gitlab-ci.yml",
+		".gitignore",
+	} {
+		if _, err := os.Stat(filepath.Join(project, path)); err != nil {
+			t.Errorf("want %s written, got: %s", path, err)
+		}
+	}
+
+	t.Setenv("OPENFAAS_PROFILE", "prod")
+	services, err := stack.ParseYAMLFile(filepath.Join(project, "stack.yml"), "", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orders := services.Functions["orders"]
+	if orders.Handler != "./functions/orders" || orders.Image != "ghcr.io/acme/orders:latest" {
+		t.Errorf("unexpected function: %+v", orders)
+	}
+	if len(orders.EnvironmentFile) != 1 || orders.EnvironmentFile[0] != "./profiles/prod.yml" {
+		t.Errorf("want the prod profile's environment file, got: %v", orders.EnvironmentFile)
+	}
+
+	templates := services.StackConfiguration.TemplateConfigs
+	if len(templates) != 1 || templates[0].Source != DefaultTemplateRepository+"#1.2.3" {
+		t.Errorf("want one pinned template, got: %v", templates)
+	}
+}
+
+func Test_parseInitFunctions(t *testing.T) {
+	functions, err := parseInitFunctions([]string{" api = node18", "", "worker=golang-middleware"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 2 || functions[0] != (initFunction{Name: "api", Language: "node18"}) {
+		t.Fatalf("unexpected functions: %v", functions)
+	}
+
+	for _, values := range [][]string{{"api"}, {"Api=node18"}, {"api=node18", "api=go"}} {
+		if _, err := parseInitFunctions(values); err == nil {
+			t.Errorf("want an error for: %v", values)
+		}
+	}
+}
+
+func Test_initPrompt_Defaults(t *testing.T) {
+	var out bytes.Buffer
+	prompt := newInitPrompt(strings.NewReader("\nghcr.io/acme\n"), &out, true)
+
+	if got := prompt.ask("Gateway URL", defaultGateway); got != defaultGateway {
+		t.Errorf("want the default for an empty answer, got: %s", got)
+	}
+	if got := prompt.ask("Image prefix", ""); got != "ghcr.io/acme" {
+		t.Errorf("want the answer, got: %s", got)
+	}
+	if !strings.Contains(out.String(), "Gateway URL ["+defaultGateway+"]: ") {
+		t.Errorf("want the default shown, got: %q", out.String())
+	}
+
+	if got := newInitPrompt(strings.NewReader("x\n"), &out, false).ask("CI", initCIGitHub); got != initCIGitHub {
+		t.Errorf("want the default when not interactive, got: %s", got)
+	}
+}
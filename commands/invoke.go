@@ -8,6 +8,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/alexellis/hmac"
 	"github.com/openfaas/faas-cli/proxy"
@@ -16,14 +20,21 @@ import (
 )
 
 var (
-	contentType             string
-	query                   []string
-	headers                 []string
-	invokeAsync             bool
-	httpMethod              string
-	sigHeader               string
-	key                     string
-	functionInvokeNamespace string
+	contentType              string
+	query                    []string
+	headers                  []string
+	invokeAsync              bool
+	httpMethod               string
+	sigHeader                string
+	key                      string
+	functionInvokeNamespace  string
+	invokeTimeout            time.Duration
+	invokeMaxRetries         int
+	invokeRetryOn            []int
+	invokeRetryNonIdempotent bool
+	invokeVerbose            bool
+	invokePayloadCmd         string
+	invokeOutput             string
 )
 
 func init() {
@@ -44,22 +55,86 @@ func init() {
 
 	invokeCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
 
+	invokeCmd.Flags().DurationVar(&invokeTimeout, "timeout", 0, "Timeout for each attempt, such as 30s, no timeout by default")
+	invokeCmd.Flags().IntVar(&invokeMaxRetries, "max-retries", 0, "Number of times to retry after a connection error, or a status given by --retry-on")
+	invokeCmd.Flags().IntSliceVar(&invokeRetryOn, "retry-on", []int{}, "HTTP status codes to retry, such as 502,503")
+	invokeCmd.Flags().BoolVar(&invokeRetryNonIdempotent, "retry-non-idempotent", false, "Retry POST and PATCH after the request was sent, although the function may then run more than once")
+	invokeCmd.Flags().BoolVarP(&invokeVerbose, "verbose", "v", false, "Print the timing of each attempt, and the call ID, duration and replica of the response to stderr")
+	invokeCmd.Flags().StringVar(&invokePayloadCmd, "payload-cmd", "", "Run a command with the shell and use its output as the request body, instead of STDIN")
+	invokeCmd.Flags().StringVarP(&invokeOutput, "output", "o", "", "Print json with the body and metadata of the response, instead of only the body")
+
+	// An invocation which is queued can't be taken back
+	markMutatingWhen(invokeCmd, func(cmd *cobra.Command, args []string) string {
+		if invokeAsync || invokeDefer > 0 || len(invokeAt) > 0 {
+			return cmd.CommandPath() + " --async"
+		}
+		return ""
+	})
 	faasCmd.AddCommand(invokeCmd)
 }
 
 var invokeCmd = &cobra.Command{
 	Use:   `invoke FUNCTION_NAME [--gateway GATEWAY_URL] [--content-type CONTENT_TYPE] [--query PARAM=VALUE] [--header PARAM=VALUE] [--method HTTP_METHOD]`,
 	Short: "Invoke an OpenFaaS function",
-	Long:  `Invokes an OpenFaaS function and reads from STDIN for the body of the request`,
+	Long: `Invokes an OpenFaaS function and reads from STDIN for the body of the request.
+
+With --max-retries, an attempt which could not connect to the gateway is made
+again. For GET, PUT and DELETE, so is one which returns a status code given by
+--retry-on, exceeds --timeout, or loses its connection. A POST or PATCH is only
+retried when the gateway could not be reached at all, since once the request
+is sent the function may have already run, unless --retry-non-idempotent is
+given. So --retry-on is refused for them without --retry-non-idempotent.
+
+With --payload-cmd, the command is run by the shell, and its output is used as
+the body instead of STDIN. The invocation is not made if the command fails.
+
+With --verbose or --output json, the X-Call-Id and X-Duration-Seconds of the
+response are printed, with the replica which served it and whether it was a
+cold start, when the provider sends them.
+
+With --async, --defer or --at ask the queue to invoke the function later, by
+sending the time in the X-Deliver-At header. A queue which supports deferred
+messages, such as that of local-run, sends the time back, otherwise a warning
+is printed as the function may be invoked straight away.
+
+With --read-only, an invocation is allowed, as the function is only run, but
+one which is queued with --async is refused.`,
 	Example: `  faas-cli invoke echo --gateway https://host:port
   faas-cli invoke echo --gateway https://host:port --content-type application/json
   faas-cli invoke env --query repo=faas-cli --query org=openfaas
   faas-cli invoke env --header X-Ping-Url=http://request.bin/etc
   faas-cli invoke resize-img --async -H "X-Callback-Url=http://gateway:8080/function/send2slack" < image.png
   faas-cli invoke env -H X-Ping-Url=http://request.bin/etc
+  faas-cli invoke send-reminder --async --defer 10m < reminder.json
+  faas-cli invoke new-year --async --at 2024-01-01T00:00Z
   faas-cli invoke flask --method GET --namespace dev
-  faas-cli invoke env --sign X-GitHub-Event --key yoursecret`,
-	RunE: runInvoke,
+  faas-cli invoke env --sign X-GitHub-Event --key yoursecret
+  faas-cli invoke env --method GET --timeout 10s --max-retries 3 --retry-on 502,503 -v
+  faas-cli invoke resize --max-retries 3 --retry-on 503 --retry-non-idempotent
+  faas-cli invoke ingest --payload-cmd 'jq -c ".sent = now" event.json'
+  echo -n "" | faas-cli invoke env -o json | jq .call_id`,
+	PreRunE: preRunInvoke,
+	RunE:    runInvoke,
+}
+
+func preRunInvoke(cmd *cobra.Command, args []string) error {
+	if invokeMaxRetries < 0 {
+		return fmt.Errorf("--max-retries must be zero or greater")
+	}
+
+	for _, code := range invokeRetryOn {
+		if code < 400 || code > 599 {
+			return fmt.Errorf("--retry-on only accepts 4xx and 5xx status codes, got: %d", code)
+		}
+	}
+
+	// A status code is only retried for a POST or PATCH when it is confirmed
+	method := strings.ToUpper(httpMethod)
+	if len(invokeRetryOn) > 0 && !invokeRetryNonIdempotent && !proxy.IsIdempotentMethod(method) {
+		return fmt.Errorf("--retry-on is never used for a %s, as the function may have already run, give --retry-non-idempotent to retry it anyway", method)
+	}
+
+	return nil
 }
 
 func runInvoke(cmd *cobra.Command, args []string) error {
@@ -73,6 +148,15 @@ func runInvoke(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("signing requires both --sign <header-value> and --key <key-value>")
 	}
 
+	if invokeOutput != "" && invokeOutput != "json" {
+		return fmt.Errorf("--output can only be json")
+	}
+
+	deliverAt, err := invokeDeliverAt(time.Now(), invokeDefer, invokeAt, invokeAsync)
+	if err != nil {
+		return err
+	}
+
 	var yamlGateway string
 	functionName = args[0]
 
@@ -90,14 +174,32 @@ func runInvoke(cmd *cobra.Command, args []string) error {
 
 	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway, os.Getenv(openFaaSURLEnvironment))
 
-	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
-		fmt.Fprintf(os.Stderr, "Reading from STDIN - hit (Control + D) to stop.\n")
+	// A function served by local-run --gateway is invoked there, unless a
+	// gateway was given
+	if !cmd.Flags().Changed("gateway") && len(os.Getenv(openFaaSURLEnvironment)) == 0 {
+		if localURL, ok := localRunGatewayFor(functionName); ok {
+			gatewayAddress = localURL
+			fmt.Fprintf(os.Stderr, "Invoking %s through the local-run gateway: %s\n", functionName, localURL)
+		}
 	}
 
-	functionInput, err := ioutil.ReadAll(os.Stdin)
-	if err != nil {
-		return fmt.Errorf("unable to read standard input: %s", err.Error())
+	var functionInput []byte
+	if len(invokePayloadCmd) > 0 {
+		var err error
+		if functionInput, err = payloadFromCommand(invokePayloadCmd); err != nil {
+			return err
+		}
+	} else {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeCharDevice) != 0 {
+			fmt.Fprintf(os.Stderr, "Reading from STDIN - hit (Control + D) to stop.\n")
+		}
+
+		var err error
+		functionInput, err = ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("unable to read standard input: %s", err.Error())
+		}
 	}
 
 	if len(sigHeader) > 0 {
@@ -108,7 +210,35 @@ func runInvoke(cmd *cobra.Command, args []string) error {
 		headers = append(headers, signedHeader)
 	}
 
-	response, err := proxy.InvokeFunction(gatewayAddress, functionName, &functionInput, contentType, query, headers, invokeAsync, httpMethod, tlsInsecure, functionInvokeNamespace)
+	if !deliverAt.IsZero() {
+		headers = append(headers, proxy.DeliverAtHeader+"="+deliverAt.UTC().Format(time.RFC3339))
+	}
+
+	retry := proxy.InvokeRetry{
+		Timeout:    invokeTimeout,
+		MaxRetries: invokeMaxRetries,
+		RetryOn:    invokeRetryOn,
+		Backoff:    500 * time.Millisecond,
+		Verbose:    invokeVerbose,
+
+		RetryNonIdempotent: invokeRetryNonIdempotent,
+	}
+
+	response, metadata, err := proxy.InvokeFunctionWithMetadata(gatewayAddress, functionName, &functionInput, contentType, query, headers, invokeAsync, httpMethod, tlsInsecure, functionInvokeNamespace, retry)
+	if invokeVerbose && metadata != nil {
+		printInvokeMetadata(os.Stderr, metadata)
+	}
+	if !deliverAt.IsZero() && err == nil {
+		if warning := deliverAtWarning(metadata); len(warning) > 0 {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	}
+	if invokeOutput == "json" {
+		if writeErr := writeInvokeJSON(os.Stdout, response, metadata, err); writeErr != nil {
+			return writeErr
+		}
+		return err
+	}
 	if err != nil {
 		return err
 	}
@@ -120,6 +250,25 @@ func runInvoke(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// payloadFromCommand runs command with the shell and returns its standard
+// output, standard error is passed through so failures can be seen
+func payloadFromCommand(command string) ([]byte, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	cmd := exec.Command(shell, flag, command)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to run --payload-cmd: %s", err.Error())
+	}
+	return out, nil
+}
+
 func generateSignedHeader(message []byte, key string, headerName string) (string, error) {
 
 	if len(headerName) == 0 {
@@ -0,0 +1,72 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+var (
+	invokeDefer time.Duration
+	invokeAt    string
+)
+
+// invokeAtLayouts are accepted by --at, a time without a zone is local
+var invokeAtLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+func init() {
+	invokeCmd.Flags().DurationVar(&invokeDefer, "defer", 0, "Ask the queue to invoke the function after a delay, such as 10m, requires --async")
+	invokeCmd.Flags().StringVar(&invokeAt, "at", "", "Ask the queue to invoke the function at a time, such as 2024-01-01T00:00Z, requires --async")
+}
+
+// invokeDeliverAt is when the function is to be invoked, from --defer or
+// --at, or zero when neither was given
+func invokeDeliverAt(now time.Time, deferFor time.Duration, at string, async bool) (time.Time, error) {
+	if deferFor == 0 && len(at) == 0 {
+		return time.Time{}, nil
+	}
+	if !async {
+		return time.Time{}, fmt.Errorf("--defer and --at schedule an asynchronous invocation, so need --async")
+	}
+	if deferFor != 0 && len(at) > 0 {
+		return time.Time{}, fmt.Errorf("give either --defer or --at, not both")
+	}
+
+	if deferFor != 0 {
+		if deferFor < 0 {
+			return time.Time{}, fmt.Errorf("--defer must be a positive duration, got: %s", deferFor)
+		}
+		return now.Add(deferFor), nil
+	}
+
+	for _, layout := range invokeAtLayouts {
+		deliverAt, err := time.ParseInLocation(layout, at, now.Location())
+		if err != nil {
+			continue
+		}
+		if !deliverAt.After(now) {
+			return time.Time{}, fmt.Errorf("--at must be in the future, got: %s", deliverAt.Format(time.RFC3339))
+		}
+		return deliverAt, nil
+	}
+	return time.Time{}, fmt.Errorf("--at must be a time such as 2024-01-01T00:00Z or %s, got: %s", time.RFC3339, at)
+}
+
+// deliverAtWarning is printed when the queue did not confirm the delivery
+// time, as a queue without deferred messages invokes the function straight away
+func deliverAtWarning(metadata *proxy.InvokeMetadata) string {
+	if metadata == nil || metadata.StatusCode != http.StatusAccepted || metadata.DeliverAt != nil {
+		return ""
+	}
+	return fmt.Sprintf("Warning: the gateway did not confirm the delivery time with %s, its queue may not support deferred messages, so the function may be invoked straight away", proxy.DeliverAtHeader)
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+func Test_invokeDeliverAt(t *testing.T) {
+	now := time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		deferFor time.Duration
+		at       string
+		async    bool
+		want     time.Time
+		err      string
+	}{
+		{name: "neither", async: true},
+		{name: "defer", deferFor: 10 * time.Minute, async: true, want: now.Add(10 * time.Minute)},
+		{name: "at without seconds", at: "2024-01-01T00:00Z", async: true, want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "at RFC3339", at: "2024-01-01T01:30:00+01:00", async: true, want: time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC)},
+		{name: "at local", at: "2024-01-01T00:00", async: true, want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "not async", deferFor: time.Minute, err: "need --async"},
+		{name: "both", deferFor: time.Minute, at: "2024-01-01T00:00Z", async: true, err: "not both"},
+		{name: "negative", deferFor: -time.Minute, async: true, err: "positive"},
+		{name: "past", at: "2023-01-01T00:00Z", async: true, err: "in the future"},
+		{name: "invalid", at: "tomorrow", async: true, err: "must be a time"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := invokeDeliverAt(now, tc.deferFor, tc.at, tc.async)
+			if len(tc.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("want an error with %q, got: %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("want: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_deliverAtWarning(t *testing.T) {
+	confirmed := time.Now()
+	if got := deliverAtWarning(&proxy.InvokeMetadata{StatusCode: http.StatusAccepted, DeliverAt: &confirmed}); got != "" {
+		t.Errorf("want no warning when the time is confirmed, got: %s", got)
+	}
+	if got := deliverAtWarning(&proxy.InvokeMetadata{StatusCode: http.StatusAccepted}); !strings.Contains(got, "may not support deferred messages") {
+		t.Errorf("want a warning, got: %q", got)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+// invokeJSON is written by invoke --output json
+type invokeJSON struct {
+	*proxy.InvokeMetadata
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	ElapsedSeconds  float64 `json:"elapsed_seconds"`
+
+	// Body is the response as text, or BodyBase64 when it is not UTF-8, such
+	// as an image
+	Body       string `json:"body,omitempty"`
+	BodyBase64 []byte `json:"body_base64,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func writeInvokeJSON(w io.Writer, response *[]byte, metadata *proxy.InvokeMetadata, invokeErr error) error {
+	if metadata == nil {
+		metadata = &proxy.InvokeMetadata{}
+	}
+
+	out := invokeJSON{
+		InvokeMetadata:  metadata,
+		DurationSeconds: metadata.Duration.Seconds(),
+		ElapsedSeconds:  metadata.Elapsed.Seconds(),
+	}
+	if response != nil {
+		if utf8.Valid(*response) {
+			out.Body = string(*response)
+		} else {
+			out.BodyBase64 = *response
+		}
+	}
+	if invokeErr != nil {
+		out.Error = invokeErr.Error()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// printInvokeMetadata writes what the gateway said about the invocation, with
+// a dash for each header which was not sent
+func printInvokeMetadata(w io.Writer, metadata *proxy.InvokeMetadata) {
+	if metadata.StatusCode == 0 {
+		return
+	}
+
+	duration := "-"
+	if metadata.Duration > 0 {
+		duration = output.Duration(metadata.Duration)
+	}
+	coldStart := "no"
+	if metadata.ColdStart {
+		coldStart = "yes"
+	}
+
+	fmt.Fprintf(w, "Status:      %d\n", metadata.StatusCode)
+	fmt.Fprintf(w, "Call ID:     %s\n", valueOrDash(metadata.CallID))
+	fmt.Fprintf(w, "Duration:    %s\n", duration)
+	fmt.Fprintf(w, "Served by:   %s\n", valueOrDash(metadata.ServedBy))
+	fmt.Fprintf(w, "Cold start:  %s\n", coldStart)
+	if metadata.DeliverAt != nil {
+		fmt.Fprintf(w, "Deliver at:  %s\n", metadata.DeliverAt.Format(time.RFC3339))
+	}
+}
+
+func valueOrDash(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return value
+}
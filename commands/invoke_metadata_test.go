@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+func Test_writeInvokeJSON(t *testing.T) {
+	metadata := &proxy.InvokeMetadata{
+		StatusCode: 200,
+		CallID:     "call-1",
+		Duration:   1500 * time.Millisecond,
+		Attempts:   1,
+		Elapsed:    2 * time.Second,
+	}
+	body := []byte("hello")
+
+	var b bytes.Buffer
+	if err := writeInvokeJSON(&b, &body, metadata, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"status_code":      float64(200),
+		"call_id":          "call-1",
+		"cold_start":       false,
+		"attempts":         float64(1),
+		"duration_seconds": 1.5,
+		"elapsed_seconds":  float64(2),
+		"body":             "hello",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("want: %v\ngot: %v", want, got)
+	}
+}
+
+func Test_writeInvokeJSON_BinaryBodyAndError(t *testing.T) {
+	body := []byte{0xff, 0xd8, 0xff}
+
+	var b bytes.Buffer
+	writeInvokeJSON(&b, &body, &proxy.InvokeMetadata{StatusCode: 502}, fmt.Errorf("server returned unexpected status code: 502"))
+
+	out := b.String()
+	if !strings.Contains(out, `"body_base64": "/9j/"`) {
+		t.Errorf("want a body which is not UTF-8 to be base64 encoded, got:\n%s", out)
+	}
+	if !strings.Contains(out, `"error": "server returned unexpected status code: 502"`) {
+		t.Errorf("want the error, got:\n%s", out)
+	}
+}
+
+func Test_printInvokeMetadata(t *testing.T) {
+	var b bytes.Buffer
+	printInvokeMetadata(&b, &proxy.InvokeMetadata{StatusCode: 200, CallID: "call-1", Duration: 250 * time.Millisecond, ColdStart: true})
+
+	want := `Status:      200
+Call ID:     call-1
+Duration:    250ms
+Served by:   -
+Cold start:  yes
+`
+	if b.String() != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, b.String())
+	}
+}
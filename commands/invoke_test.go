@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -199,3 +200,44 @@ func Test_missingSignFlag(t *testing.T) {
 		})
 	}
 }
+
+func Test_payloadFromCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	got, err := payloadFromCommand(`printf '{"n":%d}' 42`)
+	if err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	if string(got) != `{"n":42}` {
+		t.Fatalf("want the command's output, got: %q", string(got))
+	}
+
+	if _, err := payloadFromCommand("exit 3"); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Fatalf("want the exit status in the error, got: %v", err)
+	}
+}
+
+func Test_preRunInvoke_RetryOn(t *testing.T) {
+	savedMethod, savedRetryOn, savedNonIdempotent := httpMethod, invokeRetryOn, invokeRetryNonIdempotent
+	defer func() {
+		httpMethod, invokeRetryOn, invokeRetryNonIdempotent = savedMethod, savedRetryOn, savedNonIdempotent
+	}()
+	invokeRetryOn = []int{503}
+
+	httpMethod, invokeRetryNonIdempotent = "POST", false
+	if err := preRunInvoke(invokeCmd, []string{"resize"}); err == nil || !strings.Contains(err.Error(), "--retry-non-idempotent") {
+		t.Errorf("want --retry-on refused for a POST, got: %v", err)
+	}
+
+	httpMethod, invokeRetryNonIdempotent = "POST", true
+	if err := preRunInvoke(invokeCmd, []string{"resize"}); err != nil {
+		t.Errorf("want --retry-on allowed for a POST with --retry-non-idempotent, got: %s", err)
+	}
+
+	httpMethod, invokeRetryNonIdempotent = "get", false
+	if err := preRunInvoke(invokeCmd, []string{"env"}); err != nil {
+		t.Errorf("want --retry-on allowed for a GET, got: %s", err)
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
+	"time"
 
+	"github.com/openfaas/faas-cli/output"
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas-provider/types"
@@ -90,30 +93,29 @@ func runList(cmd *cobra.Command, args []string) error {
 		for _, function := range functions {
 			fmt.Printf("%s\n", function.Name)
 		}
-	} else if verboseList {
+		return nil
+	}
 
-		maxWidth := 40
+	now := time.Now()
+	var table *output.Table
+	if verboseList {
+		table = output.NewTable("Function", "Image", "Invocations", "Replicas", "CreatedAt")
 		for _, function := range functions {
-			if len(function.Image) > maxWidth {
-				maxWidth = len(function.Image)
+			createdAt := function.CreatedAt.String()
+			if output.Humanized() {
+				createdAt = output.Age(function.CreatedAt, now)
 			}
-		}
-
-		fmt.Printf("%-30s\t%-"+fmt.Sprintf("%d", maxWidth)+"s\t%-15s\t%-5s\t%-5s\n", "Function", "Image", "Invocations", "Replicas", "CreatedAt")
-		for _, function := range functions {
-			functionImage := function.Image
-			// if len(function.Image) > 40 {
-			// 	functionImage = functionImage[0:38] + ".."
-			// }
-			fmt.Printf("%-30s\t%-"+fmt.Sprintf("%d", maxWidth)+"s\t%-15d\t%-5d\t\t%-5s\n", function.Name, functionImage, int64(function.InvocationCount), function.Replicas, function.CreatedAt.String())
+			table.Row(function.Name, function.Image, output.Count(int64(function.InvocationCount)),
+				strconv.FormatUint(function.Replicas, 10), createdAt)
 		}
 	} else {
-		fmt.Printf("%-30s\t%-15s\t%-5s\n", "Function", "Invocations", "Replicas")
+		table = output.NewTable("Function", "Invocations", "Replicas")
 		for _, function := range functions {
-			fmt.Printf("%-30s\t%-15d\t%-5d\n", function.Name, int64(function.InvocationCount), function.Replicas)
+			table.Row(function.Name, output.Count(int64(function.InvocationCount)), strconv.FormatUint(function.Replicas, 10))
 		}
 	}
-	return nil
+
+	return table.Write(os.Stdout)
 }
 
 type byName []types.FunctionStatus
@@ -39,6 +39,8 @@ func Test_list(t *testing.T) {
 	defer s.Close()
 
 	resetForTest()
+	// Invocations are not grouped by thousands for a script
+	t.Setenv("LANG", "fr_FR.UTF-8")
 
 	stdOut := test.CaptureStdout(func() {
 		faasCmd.SetArgs([]string{
@@ -49,7 +51,7 @@ func Test_list(t *testing.T) {
 	})
 
 	matches := regexp.MustCompile(`(?m:function-test-[12])`).FindAllStringSubmatch(stdOut, 2)
-	if len(matches) != 2 {
+	if len(matches) != 2 || !regexp.MustCompile(`function-test-2\s+999999\s`).MatchString(stdOut) {
 		t.Fatalf("Output is not as expected:\n%s", stdOut)
 	}
 }
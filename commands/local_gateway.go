@@ -0,0 +1,226 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/openfaas/faas-cli/asyncqueue"
+	"github.com/spf13/cobra"
+)
+
+var (
+	localGatewayPort     int
+	localGatewayUpstream string
+)
+
+func init() {
+	localGatewayCmd.Flags().IntVarP(&localGatewayPort, "port", "p", 8080, "Port to listen on")
+	localGatewayCmd.Flags().StringVarP(&localGatewayUpstream, "gateway", "g", "", "Remote gateway for functions which are not running locally")
+
+	faasCmd.AddCommand(localGatewayCmd)
+}
+
+var localGatewayCmd = &cobra.Command{
+	Use:   `local-gateway [--port PORT] [--gateway REMOTE_GATEWAY_URL]`,
+	Short: "Route function invocations to functions started by local-run",
+	Long: `Starts a gateway which routes /function/NAME and /async-function/NAME to
+the functions started by "faas-cli local-run", so that a function which calls
+another through the gateway works locally as it does in the cluster.
+
+Requests for a function which isn't running locally, and for the /system API,
+are proxied to the remote gateway given by --gateway, or rejected without one.
+Functions started with local-run can reach this gateway from their container
+at http://host.docker.internal:PORT.`,
+	Example: `  faas-cli local-run orders --detach --port 8081
+  faas-cli local-run payments --detach --port 8082
+  faas-cli local-gateway --port 8080 --gateway https://gw.example.com
+  curl http://127.0.0.1:8080/function/orders`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkLocalRunExperimental(); err != nil {
+			return err
+		}
+
+		if err := validateContainerRuntime(); err != nil {
+			return err
+		}
+
+		if len(localGatewayUpstream) > 0 {
+			if u, err := url.Parse(localGatewayUpstream); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("--gateway must be a URL starting with http(s)://")
+			}
+		}
+		return nil
+	},
+	RunE: runLocalGateway,
+	// Hidden while local-run is experimental
+	Hidden: true,
+}
+
+func runLocalGateway(cmd *cobra.Command, args []string) error {
+	var upstream *url.URL
+	if len(localGatewayUpstream) > 0 {
+		upstream, _ = url.Parse(strings.TrimRight(localGatewayUpstream, "/"))
+	}
+
+	gw := newLocalGateway(localRunPorts, upstream, 2*time.Second)
+	defer gw.queue.Close()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", localGatewayPort))
+	if err != nil {
+		return fmt.Errorf("unable to listen on port %d: %w", localGatewayPort, err)
+	}
+
+	server := &http.Server{
+		Handler:           gw,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Local gateway listening on: http://127.0.0.1:%d\n", localGatewayPort)
+	if upstream != nil {
+		fmt.Printf("Proxying other functions to: %s\n", upstream)
+	}
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// localGateway routes invocations to local functions, found by discover,
+// and everything else to an optional upstream gateway
+type localGateway struct {
+	discover func() (map[string]int, error)
+	upstream *url.URL
+	interval time.Duration
+	queue    *asyncqueue.Queue
+	// host is where the functions' ports are published
+	host string
+
+	mu        sync.Mutex
+	routes    map[string]int
+	refreshed time.Time
+}
+
+func newLocalGateway(discover func() (map[string]int, error), upstream *url.URL, interval time.Duration) *localGateway {
+	return &localGateway{
+		discover: discover,
+		upstream: upstream,
+		interval: interval,
+		queue:    asyncqueue.New(1, asyncqueue.DefaultDepth, asyncQueueTimeout),
+		host:     "127.0.0.1",
+		routes:   map[string]int{},
+	}
+}
+
+func (g *localGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	for _, prefix := range []string{"/function/", "/async-function/"} {
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			continue
+		}
+
+		ref, remainder := strings.TrimPrefix(r.URL.Path, prefix), ""
+		if i := strings.Index(ref, "/"); i > -1 {
+			ref, remainder = ref[:i], ref[i:]
+		}
+
+		// Local functions have no namespace, so "NAME.NAMESPACE" is routed to NAME
+		name := ref
+		if i := strings.Index(name, "."); i > -1 {
+			name = name[:i]
+		}
+
+		if g.isLocal(name) {
+			r.URL.Path = prefix + name + remainder
+			g.queue.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	if g.upstream == nil {
+		http.Error(w, fmt.Sprintf("no local function or remote gateway for: %s", r.URL.Path), http.StatusNotFound)
+		return
+	}
+
+	httputil.NewSingleHostReverseProxy(g.upstream).ServeHTTP(w, r)
+}
+
+// isLocal refreshes the routes when they are older than the interval
+func (g *localGateway) isLocal(name string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if time.Since(g.refreshed) > g.interval {
+		routes, err := g.discover()
+		if err != nil {
+			log.Printf("Unable to find local functions: %s", err)
+		} else {
+			g.routes = routes
+			for fn, port := range routes {
+				target, _ := url.Parse(fmt.Sprintf("http://%s", net.JoinHostPort(g.host, strconv.Itoa(port))))
+				g.queue.AddFunction(fn, target)
+			}
+		}
+		g.refreshed = time.Now()
+	}
+
+	_, ok := g.routes[name]
+	return ok
+}
+
+// localRunPorts maps the functions started by local-run to their ports
+func localRunPorts() (map[string]int, error) {
+	out, err := exec.Command(containerRuntime(), "ps",
+		"--filter", fmt.Sprintf("label=%s=true", localRunLabel),
+		"--format", fmt.Sprintf(`{{.Label "%s"}} {{.Label "%s"}}`, localRunFunctionLabel, localRunPortLabel)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list local-run containers: %w", err)
+	}
+
+	return parseLocalRunPorts(string(out)), nil
+}
+
+func parseLocalRunPorts(out string) map[string]int {
+	routes := map[string]int{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if port, err := strconv.Atoi(fields[1]); err == nil {
+			routes[fields[0]] = port
+		}
+	}
+	return routes
+}
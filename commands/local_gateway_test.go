@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_localGateway_Routes(t *testing.T) {
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("local " + r.URL.Path))
+	}))
+	defer local.Close()
+
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("remote " + r.URL.Path))
+	}))
+	defer remote.Close()
+
+	localURL, _ := url.Parse(local.URL)
+	port, _ := strconv.Atoi(localURL.Port())
+	upstream, _ := url.Parse(remote.URL)
+
+	gw := newLocalGateway(func() (map[string]int, error) {
+		return map[string]int{"orders": port}, nil
+	}, upstream, time.Minute)
+	defer gw.queue.Close()
+
+	s := httptest.NewServer(gw)
+	defer s.Close()
+
+	cases := map[string]string{
+		"/function/orders":                  "local /",
+		"/function/orders.openfaas-fn/list": "local /list",
+		"/function/payments":                "remote /function/payments",
+		"/system/functions":                 "remote /system/functions",
+	}
+
+	for path, want := range cases {
+		res, err := http.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if string(body) != want {
+			t.Errorf("%s: want %q, got %q", path, want, string(body))
+		}
+	}
+}
+
+func Test_localGateway_NoUpstream(t *testing.T) {
+	gw := newLocalGateway(func() (map[string]int, error) {
+		return map[string]int{}, nil
+	}, nil, time.Minute)
+	defer gw.queue.Close()
+
+	s := httptest.NewServer(gw)
+	defer s.Close()
+
+	res, err := http.Get(s.URL + "/function/payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("want 404, got %d", res.StatusCode)
+	}
+}
+
+func Test_parseLocalRunPorts(t *testing.T) {
+	got := parseLocalRunPorts("orders 8081\npayments 8082\nbroken\nlegacy \n")
+	want := map[string]int{"orders": 8081, "payments": 8082}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
@@ -2,14 +2,20 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"os/exec"
 
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/flags"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/spf13/cobra"
 )
@@ -22,51 +28,520 @@ func init() {
 }
 
 type runOptions struct {
-	print    bool
-	port     int
-	network  string
-	extraEnv map[string]string
-	output   io.Writer
-	err      io.Writer
+	print       bool
+	printFormat string
+	// compose prints a docker-compose.yml rather than running the containers
+	compose bool
+	port    int
+	// portRange limits the ports picked when --port is in use, e.g. 8080-8090
+	portRange string
+	network   string
+	extraEnv  map[string]string
+	// envFiles are read in turn, after the stack file's environment
+	envFiles []string
+	// image runs a pre-built image in place of a function of the stack file
+	image string
+	// secrets are mounted from the secrets folder with --image
+	secrets []string
+	// gpus overrides the GPUs of the stack file's limits
+	gpus string
+	// debugPort publishes the port of the function's debugger
+	debugPort int
+	// debugConventions are those of the stack file's configuration.debug
+	debugConventions map[string]stack.DebugConvention
+	// volumes are extra host mounts given by --volume, as src:dst[:ro]
+	volumes  []string
+	fprocess string
+	workdir  string
+	// mountHandler bind-mounts the handler folder over its copy in the image
+	mountHandler bool
+	detach       bool
+	withAsync    bool
+	asyncPort    int
+	// gateway serves a local gateway in front of the functions on gatewayPort
+	gateway     bool
+	gatewayPort int
+	explainEnv  bool
+	stats       bool
+	// capture is the file which the requests to the function are saved to,
+	// as HAR or JSONL lines, by a proxy on capturePort
+	capture     string
+	capturePort int
+	// statsInterval is how often --stats prints the latest usage, 0 only
+	// prints it on exit
+	statsInterval time.Duration
+	// all starts every function in the stack file
+	all bool
+	// stackFile and session are recorded on the containers by their labels,
+	// see localRunStackLabel and localRunSessionLabel
+	stackFile string
+	session   string
+	// watch rebuilds and restarts the function when its handler changes
+	watch bool
+	// build builds the image before it is run
+	build bool
+	// runtime is the container runtime's binary, such as docker or podman
+	runtime string
+	// remoteHost is the host of the runtime's daemon when it is on another
+	// machine, which the functions' ports are published on
+	remoteHost string
+	// readyTimeout is how long to wait for the watchdog to respond
+	readyTimeout time.Duration
+	// stopGrace is how long the functions have to exit after Control+C
+	stopGrace time.Duration
+	// open opens the function's URL in a browser once it is ready
+	open bool
+	// invoke sends invokePayload to the function once it is ready, then
+	// stops it unless keep is set
+	invoke            bool
+	invokePayload     []byte
+	invokeContentType string
+	keep              bool
+	// logFormat and logTimeFormat format the lines written by the containers
+	logFormat     flags.LogFormat
+	logTimeFormat flags.TimeFormat
+	output        io.Writer
+	err           io.Writer
 }
 
 func newLocalRunCmd() *cobra.Command {
 	opts := runOptions{}
+	invokeData := ""
 
 	cmd := &cobra.Command{
-		Use:   `local-run NAME --port PORT -f YAML_FILE`,
+		Use:   `local-run [NAME | --all] --port PORT -f YAML_FILE`,
 		Short: "Start a function with docker for local testing (experimental feature)",
 		Long: `Providing faas-cli build has already been run, this command will use the
 docker command to start a container on your local machine using its image.
+With --build, the image is built first, as by faas-cli build.
+
+podman and nerdctl can be used instead of docker with --runtime, or by setting
+` + containerRuntimeEnv + `, which local-gateway also reads. Without either, the
+first of docker, podman and nerdctl which is installed is used. The image must
+be available to the runtime, so for podman and nerdctl it may have to be pushed
+or loaded after faas-cli build.
+
+When DOCKER_HOST or the active docker context, or CONTAINER_HOST for podman,
+is a daemon on another machine, the functions' URLs are printed with its host
+name rather than 0.0.0.0. The function's secrets are copied into its container
+with docker cp before it starts, as the daemon can't see this machine's files,
+and a warning is printed for each --volume or --mount-handler, which mount the
+folder of the daemon's machine.
 
 The function will be bound to the port specified by the --port flag, or 8080
-by default.
+by default. When the port is in use, the next free port is picked, or the
+first free port in --port-range. Its URL is printed once the watchdog responds
+on its health endpoint, and local-run fails if the container exits first, or
+is not ready within --ready-timeout. With --open, the URL is also opened in a browser.
+A container which exits first is reported with its exit code, its last lines
+of output and the likely cause, such as an fprocess which is not in the image,
+or an image built for another architecture.
+
+With --invoke, a request is sent to the function once it is ready, and the
+response is printed before the function is stopped, so that one command
+builds, runs and tests it in CI. The body is given as text, or read from a
+file with @payload.json, or from STDIN with @-, and is sent as JSON when it is
+valid JSON. local-run fails when the response has a status of 400 or above.
+With --keep, the function keeps running after the request, and with --detach
+it is left running in the background.
+
+Control+C stops the containers, giving each function --grace-period to finish
+its in-flight requests before it is killed, and press it again to remove them
+straight away. Any container which is left once local-run exits is removed.
 
 There is limited support for secrets, and the function cannot contact other
-services deployed within your OpenFaaS cluster.`,
+services deployed within your OpenFaaS cluster. Secrets are mounted from the
+` + localSecretsDir + ` folder, and can be copied into it from a cluster with
+"faas-cli local-run secrets pull".
+
+For interpreted languages such as Python and Node.js, --mount-handler mounts
+the function's handler folder over the copy in the image, so that changes take
+effect when the function is restarted, without a rebuild. Dependencies which
+the template installs into the handler folder at build time are hidden by the
+mount, so must also be installed locally.
+
+Other host folders or files, such as fixtures for tests or a folder to write
+artifacts to, are mounted with --volume SRC:DST, or SRC:DST:ro to mount them
+read-only. The source must exist, and a relative source is from the current
+directory.
+
+With --image, a pre-built image, such as one pulled from a registry, is run
+without reading the stack file, and is named after its repository unless a
+NAME is given. The image's own fprocess and environment are kept, and --env,
+--env-file, --port and --secret work as they do for a function of a stack file.
+
+GPUs are given to the container with --gpus, as for docker run --gpus, such
+as --gpus all or --gpus device=0, or from the gpu field of the function's
+limits in the stack file, so that an inference function can be tested with the
+GPUs it is scheduled with in the cluster:
+
+  functions:
+    classify:
+      limits:
+        gpu: 1
+
+The memory limit of the stack file is applied as a soft limit, so that a
+function is not killed while being debugged, and the memory request is used
+when there is no limit. The CPU limit is applied with --cpus, and the CPU
+request as a relative weight with --cpu-shares, as the kubelet does. A
+function's shm_size, ulimits and tmpfs are also passed to the container:
+
+  functions:
+    render:
+      requests:
+        cpu: 500m
+      shm_size: 256m
+      ulimits:
+        nofile: 1024:4096
+      tmpfs:
+        - /tmp:size=64m
+
+The container runs with the securityContext the function has in the cluster,
+so that permission errors show up before it is deployed: readonly_root_filesystem
+and privileged are applied, and runasuser becomes --user. Privilege escalation
+is disabled with no-new-privileges, and capabilities are dropped or added, as
+given by annotations:
+
+  functions:
+    resize:
+      runasuser: "12000"
+      annotations:
+        ` + securityDropCapabilitiesAnnotation + `: ALL
+        ` + securityAddCapabilitiesAnnotation + `: NET_BIND_SERVICE
+        ` + securityPrivilegeEscalation + `: "false"
+
+Environment variables are read from files of KEY=VALUE lines, such as a .env
+file, with --env-file, which can be given more than once. When a variable is
+set more than once, the later source wins, in this order: the stack file's
+environment, then its environment_file entries, then each --env-file in the
+order given, then the debugger's, then --env. Use --explain-env to see where
+each value came from.
+
+With --debug-port, a debugger is started in the container and its port is
+published on the given port: dlv for Go, --inspect for Node.js, debugpy for
+Python and JDWP for Java, which must be installed in the image. The convention
+for a language can be set or replaced in the stack file, where {fprocess} is
+the function's fprocess, and {args} is the fprocess without its first word:
+
+  configuration:
+    debug:
+      python3-flask:
+        port: 5678
+        fprocess: python3 -m debugpy --listen 0.0.0.0:5678 {args}
+
+The watchdog's exec_timeout still applies while paused at a breakpoint, so it
+may need to be raised with -e exec_timeout=10m.
+
+With --with-async, an in-memory queue is served on --async-port, which accepts
+requests on /async-function/NAME and invokes the function in the background,
+posting the result to any X-Callback-Url, like the gateway and queue-worker.
+
+With --gateway, a gateway is served on --gateway-port which routes
+/function/NAME to the function's container, and /async-function/NAME through
+an in-memory queue. The functions' ports then start after --gateway-port,
+unless --port is given. While it runs, "faas-cli invoke NAME" from the same
+folder is sent to this gateway, without a --gateway flag.
+
+Each function's container is named openfaas-local-NAME, so that only one copy
+runs at a time, and is labelled with the stack file's path. When faas-cli is
+killed before it can remove the containers, "faas-cli local-run prune" finds
+and removes them.
+
+The containers of the stack file's x-local-services, such as Redis or
+Postgres, are started before the functions, on a network shared with them, so
+that a function reaches each one by its name, e.g. redis:6379. local-run waits
+for each to be running, or healthy when its image has a health check, and
+removes them when it exits, or with "faas-cli local-run stop --all" after
+--detach:
+
+  x-local-services:
+    redis:
+      image: redis:7-alpine
+      ports:
+        - 6379:6379
+    postgres:
+      image: postgres:16
+      command: postgres -c log_statement=all
+      environment:
+        POSTGRES_PASSWORD: local
+      volumes:
+        - ./testdata/schema.sql:/docker-entrypoint-initdb.d/schema.sql:ro
+
+With --watch, the handler folder is checked for changes, then the image is
+rebuilt and the container restarted. A build which fails leaves the last
+container running. With --mount-handler as well, the container is restarted
+without a rebuild.
+
+The output of each container is written line by line, prefixed with the name of
+its function. With --log-format keyvalue or json, the lines are formatted as
+"faas-cli logs" formats those of a deployed function, for tools to parse, and
+--time-format adds the time each line was written.
+
+With --stats, the container's CPU and memory are sampled with docker stats,
+and when it exits, the peak and average usage are printed with suggested
+limits and requests for the stack file. While it runs, the latest usage is
+printed between its logs every --stats-interval, with the share of its
+limits.memory, which is highlighted from 90%.
+
+With --capture, a proxy in front of the function saves each request sent to
+it, and the response, with their headers, bodies and latency. A file ending in
+.har is written as an HTTP Archive when local-run exits, which the developer
+tools of a browser can import, any other file gets a JSON line per request as
+it completes. Send requests to the proxy's port, which is printed on start.
+
+Without a NAME, or with --all, every function in the stack file is started on
+a shared docker network, with free ports assigned in turn from --port in the
+order of their names. Each function can call the others at http://NAME:8080.`,
 		Example: `
   # Run a function locally
   faas-cli local-run stronghash
 
+  # Build the image, then run it
+  faas-cli local-run stronghash --build
+
+  # Run with podman rather than docker
+  faas-cli local-run stronghash --runtime podman
+
+  # Open the function in a browser once it is ready
+  faas-cli local-run stronghash --open
+
+  # Build and run the function, then test it with a request, as in CI
+  faas-cli local-run stronghash --build --invoke @payload.json
+
   # Run on a custom port
   faas-cli local-run stronghash --port 8081
 
+  # Pick the first free port from 9000 to 9010
+  faas-cli local-run stronghash --port-range 9000-9010
+
   # Use a custom YAML file other than stack.yml
   faas-cli local-run stronghash -f ./stronghash.yml
+
+  # Try a different entrypoint without rebuilding the image
+  faas-cli local-run stronghash --fprocess "python3 -m pdb index.py"
+
+  # Start the process from another directory in the container
+  faas-cli local-run stronghash --workdir /home/app/function
+
+  # Use the handler's source from the local folder, rather than the image
+  faas-cli local-run stronghash --mount-handler
+
+  # Mount test fixtures read-only, and a folder for the function's output
+  faas-cli local-run stronghash -v ./fixtures:/fixtures:ro -v ./out:/tmp/out
+
+  # Attach a debugger such as dlv, or Chrome DevTools for Node.js
+  faas-cli local-run stronghash --debug-port 2345
+
+  # Invoke the function asynchronously, with a callback
+  faas-cli local-run stronghash --with-async
+  curl -d "data" -H "X-Callback-Url: http://127.0.0.1:8888/" \
+    http://127.0.0.1:8081/async-function/stronghash
+
+  # Serve the stack behind a local gateway, then invoke a function through it
+  faas-cli local-run --all --gateway
+  faas-cli invoke stronghash <<< "data"
+
+  # Run a pre-built image from a registry, with one of the local secrets
+  faas-cli local-run --image ghcr.io/openfaas/figlet:latest --secret api-key
+
+  # Load local configuration, with a value overridden for this run
+  faas-cli local-run stronghash --env-file .env --env-file .env.local -e debug=true
+
+  # Rebuild and restart the function when its handler changes
+  faas-cli local-run stronghash --watch
+
+  # Measure CPU and memory while load testing, then stop with Control+C
+  faas-cli local-run stronghash --stats
+
+  # Save the requests sent through the proxy on port 8081, for a bug report
+  faas-cli local-run stronghash --capture session.har --capture-port 8081
+
+  # Print the usage every 2s, to see how close it comes to limits.memory
+  faas-cli local-run stronghash --stats --stats-interval 2s
+
+  # Write the stack's output as JSON lines, with the time of each
+  faas-cli local-run --all --log-format json --time-format RFC3339 | jq .text
+
+  # Describe the container as JSON, for other tools to read or modify
+  faas-cli local-run stronghash --print-format json
+
+  # Write the stack as a docker-compose.yml, to run it with compose
+  faas-cli local-run --all --compose > docker-compose.yml
+
+  # Start every function in the stack, on ports 8080, 8081 and so on
+  faas-cli local-run --all
+
+  # Start the whole stack in the background, with a gateway in front of it
+  faas-cli local-run --all --detach --port 8081
+  faas-cli local-gateway --port 8080
+
+  # Run functions in the background, then manage them
+  faas-cli local-run stronghash --detach --port 8081
+  faas-cli local-run ps
+  faas-cli local-run logs stronghash --follow
+  faas-cli local-run stop stronghash
+
+  # Remove the containers left by a faas-cli which was killed
+  faas-cli local-run prune
+
+  # Copy the function's secrets from the cluster, then run it
+  faas-cli local-run secrets pull stronghash
+  faas-cli local-run stronghash
 		`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 
-			if v, ok := os.LookupEnv("OPENFAAS_EXPERIMENTAL"); !ok || v == "0" {
-				return fmt.Errorf("this command is experimental, set OPENFAAS_EXPERIMENTAL=1 to use it")
+			if err := checkLocalRunExperimental(); err != nil {
+				return err
 			}
 
-			if len(args) < 1 {
-				return fmt.Errorf("expected the name of the function")
+			if err := validateContainerRuntime(); err != nil {
+				return err
 			}
+			opts.runtime = containerRuntime()
 
 			if len(args) > 1 {
 				return fmt.Errorf("only one function name is allowed")
 			}
+
+			if len(opts.image) > 0 {
+				if opts.all {
+					return fmt.Errorf("--image runs one image, so can't be used with --all")
+				}
+				if opts.build || opts.watch || opts.mountHandler {
+					return fmt.Errorf("--image runs a pre-built image without a stack file, so can't be used with --build, --watch or --mount-handler")
+				}
+			} else if len(opts.secrets) > 0 {
+				return fmt.Errorf("--secret is for --image, the secrets of a function are read from the stack file")
+			}
+
+			if len(args) == 0 && len(opts.image) == 0 {
+				opts.all = true
+			} else if opts.all {
+				return fmt.Errorf("give the name of a function or --all, not both")
+			}
+
+			if opts.watch && (opts.detach || opts.all || opts.stats) {
+				return fmt.Errorf("--watch restarts one function in the foreground, so can't be used with --detach, --all or --stats")
+			}
+
+			if opts.build && opts.print {
+				return fmt.Errorf("--print only prints the docker command, so can't be used with --build")
+			}
+
+			if opts.all && opts.open {
+				return fmt.Errorf("--open opens one function, so can't be used with --all")
+			}
+
+			if cmd.Flags().Changed("invoke") {
+				if opts.all || opts.print || opts.watch {
+					return fmt.Errorf("--invoke tests one function once it is ready, so can't be used with --all, --print or --watch")
+				}
+				var err error
+				if opts.invokePayload, opts.invokeContentType, err = readInvokePayload(invokeData); err != nil {
+					return err
+				}
+				opts.invoke = true
+			}
+
+			if opts.keep && (!opts.invoke || opts.detach) {
+				return fmt.Errorf("--keep keeps the function running after --invoke, so must be used with --invoke and without --detach")
+			}
+
+			if opts.all && opts.stats {
+				return fmt.Errorf("--stats samples one function, so can't be used with --all")
+			}
+
+			if opts.debugPort < 0 || opts.debugPort > 65535 {
+				return fmt.Errorf("--debug-port must be between 1 and 65535, got: %d", opts.debugPort)
+			}
+
+			if opts.all && opts.debugPort > 0 {
+				return fmt.Errorf("--debug-port debugs one function, so can't be used with --all")
+			}
+
+			if opts.all && opts.network == "host" {
+				return fmt.Errorf("--network host can't be used with --all, as every function would listen on the same port")
+			}
+
+			if opts.gateway {
+				if opts.detach {
+					return fmt.Errorf("--gateway runs the gateway within faas-cli, so can't be used with --detach, see: faas-cli local-gateway")
+				}
+				if opts.withAsync {
+					return fmt.Errorf("--gateway serves /async-function/NAME already, so --with-async isn't needed")
+				}
+				if opts.network == "host" {
+					return fmt.Errorf("--gateway can't be used with --network host, as the function's port is not known")
+				}
+				if opts.gatewayPort < 1 || opts.gatewayPort > 65535 {
+					return fmt.Errorf("--gateway-port must be between 1 and 65535, got: %d", opts.gatewayPort)
+				}
+				if !cmd.Flags().Changed("port") {
+					opts.port = opts.gatewayPort + 1
+				} else if opts.port == opts.gatewayPort {
+					return fmt.Errorf("--port and --gateway-port must be different, both are: %d", opts.port)
+				}
+			}
+
+			if opts.stopGrace < 0 {
+				return fmt.Errorf("--grace-period must not be negative, got: %s", opts.stopGrace)
+			}
+
+			if opts.withAsync && opts.detach {
+				return fmt.Errorf("--with-async runs the queue within faas-cli, so can't be used with --detach")
+			}
+
+			if opts.stats && opts.detach {
+				return fmt.Errorf("--stats prints the usage when the function exits, so can't be used with --detach")
+			}
+
+			if len(opts.capture) > 0 {
+				if opts.detach || opts.all {
+					return fmt.Errorf("--capture proxies one function from within faas-cli, so can't be used with --detach or --all")
+				}
+			} else if cmd.Flags().Changed("capture-port") {
+				return fmt.Errorf("--capture-port is the port of the --capture proxy, so can only be used with --capture")
+			}
+			if opts.capturePort < 0 || opts.capturePort > 65535 {
+				return fmt.Errorf("--capture-port must be between 0 and 65535, got: %d", opts.capturePort)
+			}
+
+			if cmd.Flags().Changed("stats-interval") && !opts.stats {
+				return fmt.Errorf("--stats-interval sets how often --stats prints the usage, so can only be used with --stats")
+			}
+			if opts.statsInterval < 0 {
+				return fmt.Errorf("--stats-interval must not be negative, got: %s", opts.statsInterval)
+			}
+
+			if opts.compose {
+				if cmd.Flags().Changed("print-format") {
+					return fmt.Errorf("--compose prints a docker-compose.yml, so can't be used with --print-format")
+				}
+				opts.printFormat = printFormatCompose
+			}
+
+			for _, volume := range opts.volumes {
+				if _, err := parseLocalRunVolume(volume); err != nil {
+					return err
+				}
+			}
+
+			if opts.portRange != "" {
+				if _, err := parsePortRange(opts.portRange); err != nil {
+					return err
+				}
+			}
+
+			if opts.printFormat != printFormatShell && opts.printFormat != printFormatJSON && opts.printFormat != printFormatCompose {
+				return fmt.Errorf("--print-format must be %s, %s or %s", printFormatShell, printFormatJSON, printFormatCompose)
+			}
+			if cmd.Flags().Changed("print-format") || opts.compose {
+				opts.print = true
+			}
+
+			if opts.printFormat != printFormatCompose {
+				opts.remoteHost = remoteDaemonHost(localRunDaemonEndpoint(opts.runtime))
+			}
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -75,76 +550,375 @@ services deployed within your OpenFaaS cluster.`,
 			opts.output = cmd.OutOrStdout()
 			opts.err = cmd.ErrOrStderr()
 
-			return runFunction(ctx, args[0], opts)
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			} else if len(opts.image) > 0 {
+				var err error
+				if name, err = imageFunctionName(opts.image); err != nil {
+					return err
+				}
+			}
+
+			if len(opts.image) == 0 {
+				opts.stackFile = localRunStackFile(yamlFile)
+			}
+			if !opts.print && !opts.detach {
+				opts.session = localRunSession()
+			}
+
+			if opts.build {
+				services, err := localRunServices(name)
+				if err != nil {
+					return err
+				}
+				if err := buildLocalRun(cmd, services); err != nil {
+					return err
+				}
+			}
+
+			if opts.all {
+				return runStack(ctx, opts)
+			}
+			return runFunction(ctx, name, opts)
 		},
 		// TODO: unhide once we are happy with the DX.
 		Hidden: true,
 	}
 
+	cmd.AddCommand(newLocalRunPsCmd(), newLocalRunStopCmd(), newLocalRunLogsCmd(), newLocalRunPruneCmd(), newLocalRunSecretsCmd())
+
+	cmd.PersistentFlags().StringVar(&localRunRuntime, "runtime", "", "container runtime to use: docker, podman or nerdctl, detected when not given")
+	cmd.Flags().BoolVar(&opts.all, "all", false, "start every function in the stack file, on a shared network with sequential ports, the default when no NAME is given")
+	cmd.Flags().BoolVarP(&opts.detach, "detach", "d", false, "run the function in the background, see \"local-run ps\"")
+	cmd.Flags().BoolVar(&opts.withAsync, "with-async", false, "serve /async-function/NAME from an in-memory queue, for testing asynchronous invocations")
+	cmd.Flags().IntVar(&opts.asyncPort, "async-port", 0, "port for the queue started by --with-async, defaults to the port after the last function's")
+	cmd.Flags().BoolVar(&opts.gateway, "gateway", false, "serve a gateway which routes /function/NAME and /async-function/NAME to the functions, for faas-cli invoke")
+	cmd.Flags().IntVar(&opts.gatewayPort, "gateway-port", 8080, "port for the gateway started by --gateway, the functions' ports follow it")
+	cmd.Flags().BoolVar(&opts.build, "build", false, "build the image with faas-cli build before running it")
+	cmd.Flags().BoolVar(&opts.watch, "watch", false, "rebuild the image and restart the container when the function's handler changes")
+	cmd.Flags().DurationVar(&opts.readyTimeout, "ready-timeout", 30*time.Second, "time to wait for the function's watchdog to respond before failing")
+	cmd.Flags().DurationVar(&opts.stopGrace, "grace-period", localRunStopGrace, "time for the function to exit after Control+C, before its container is killed and removed")
+	opts.logFormat = flags.PlainLogFormat
+	cmd.Flags().Var(&opts.logFormat, "log-format", "format the containers' output as plain lines prefixed by the function's name, keyvalue or json, as faas-cli logs does")
+	cmd.Flags().Var(&opts.logTimeFormat, "time-format", "prefix each line of the containers' output with the time, in a go time format or a name such as RFC3339")
+	cmd.Flags().BoolVar(&opts.open, "open", false, "open the function's URL in a browser once it is ready")
+	cmd.Flags().StringVar(&invokeData, "invoke", "", "send a request to the function once it is ready and print the response, then stop it, the body is given as text, or as @FILE or @- for STDIN")
+	cmd.Flags().BoolVar(&opts.keep, "keep", false, "keep the function running after the request sent by --invoke")
+	cmd.Flags().BoolVar(&opts.stats, "stats", false, "sample the function's CPU and memory with docker stats, and print the peak and average usage on exit")
+	cmd.Flags().DurationVar(&opts.statsInterval, "stats-interval", 10*time.Second, "how often --stats prints the latest usage, and its share of limits.memory, 0 only prints the usage on exit")
+	cmd.Flags().StringVar(&opts.capture, "capture", "", "save each request to the function and its response to a file, as a HAR for a .har file, otherwise as JSON lines, through a proxy on --capture-port")
+	cmd.Flags().IntVar(&opts.capturePort, "capture-port", 0, "port for the proxy of --capture, a free port by default")
 	cmd.Flags().BoolVar(&opts.print, "print", false, "Print the docker command instead of running it")
+	cmd.Flags().StringVar(&opts.printFormat, "print-format", printFormatShell, "format for --print: shell, json to describe the container's image, env, mounts, ports and limits, or compose, implies --print")
+	cmd.Flags().BoolVar(&opts.compose, "compose", false, "Print a docker-compose.yml for the function's container, or every function's with --all, instead of running them")
 	cmd.Flags().IntVarP(&opts.port, "port", "p", 8080, "port to bind the function to")
+	cmd.Flags().StringVar(&opts.portRange, "port-range", "", "ports to pick from when --port is in use, e.g. 8080-8090, by default up to 100 ports after --port are tried")
 	cmd.Flags().StringVar(&opts.network, "network", "", "connect function to an existing network, use 'host' to access other process already running on localhost. When using this, '--port' is ignored, if you have port collisions, you may change the port using '-e port=NEW_PORT'")
+	cmd.Flags().StringVar(&opts.gpus, "gpus", "", "GPUs to give the container, as for docker run --gpus, such as all, 1 or device=0, instead of limits.gpu from the stack file")
+	cmd.Flags().IntVar(&opts.debugPort, "debug-port", 0, "publish the port of a debugger started in the container, such as dlv for Go or --inspect for Node.js")
+	cmd.Flags().StringArrayVarP(&opts.volumes, "volume", "v", []string{}, "mount a host folder or file into the container (SRC:DST[:ro]), can be given more than once")
+	cmd.Flags().StringVar(&opts.fprocess, "fprocess", "", "override the fprocess of the function, instead of the value from the stack file or template")
+	cmd.Flags().StringVar(&opts.workdir, "workdir", "", "override the working directory of the function's container")
+	cmd.Flags().BoolVar(&opts.mountHandler, "mount-handler", false, "mount the function's handler folder into the container, read-only, instead of using the copy in the image")
+	cmd.Flags().BoolVar(&opts.explainEnv, "explain-env", false, "print each environment variable of the function, with the source which set it")
 	cmd.Flags().StringToStringVarP(&opts.extraEnv, "env", "e", map[string]string{}, "additional environment variables (ENVVAR=VALUE), use this to experiment with different values for your function")
+	cmd.Flags().StringVar(&opts.image, "image", "", "run a pre-built image, such as one from a registry, without reading the stack file")
+	cmd.Flags().StringArrayVar(&opts.secrets, "secret", []string{}, "mount a secret from the "+localSecretsDir+" folder into the container started by --image, can be given more than once")
+	cmd.Flags().StringArrayVar(&opts.envFiles, "env-file", []string{}, "read environment variables from a file of KEY=VALUE lines, such as .env, can be given more than once")
 
 	return cmd
 }
 
 func runFunction(ctx context.Context, name string, opts runOptions) error {
-	services, err := stack.ParseYAMLFile(yamlFile, "", name, true)
-	if err != nil {
-		return err
+	var services *stack.Services
+	if len(opts.image) > 0 {
+		services = imageServices(name, opts.image, opts.secrets)
+	} else {
+		var err error
+		if services, err = localRunServices(name); err != nil {
+			return err
+		}
+
+		if len(services.Functions) > 1 {
+			return fmt.Errorf("multiple functions matching %q in the stack file", name)
+		}
 	}
 
-	if len(services.Functions) > 1 {
-		return fmt.Errorf("multiple functions matching %q in the stack file", name)
+	if len(opts.image) == 0 || len(opts.secrets) > 0 {
+		if err := updateGitignore(); err != nil {
+			return err
+		}
 	}
 
-	err = updateGitignore()
+	fnc := services.Functions[name]
+	fnc.Name = name
+	opts.debugConventions = services.StackConfiguration.Debug
+
+	// The stack's x-local-services are reached by their names, so the function
+	// is put on a network with them, unless --network gives one
+	network := opts.network
+	if network == "" {
+		network = localRunStackNetwork
+	}
+	servicePlans, err := planLocalServices(services, network, opts)
 	if err != nil {
 		return err
 	}
+	ownNetwork := false
+	if len(servicePlans) > 0 {
+		if network == "host" {
+			return fmt.Errorf("%s are reached by name on a network, so can't be used with --network host", stack.LocalServicesExtension)
+		}
+		ownNetwork = opts.network == ""
+		opts.network = network
+	}
+
+	// With --network host the watchdog listens on its own port instead
+	if opts.network != "host" {
+		ports, err := selectPorts(1, opts)
+		if err != nil {
+			return err
+		}
+		opts.port = ports[0]
+	}
 
-	fnc := services.Functions[name]
 	// TODO: we should probably use a levelled logger here
 	// fmt.Fprintf(opts.output, "%#v\n\n", fnc)
 
-	cmd, err := buildDockerRun(ctx, fnc, opts)
+	plan, err := planDockerRun(fnc, opts)
 	if err != nil {
 		return err
 	}
+	warnRemoteMounts(append(servicePlans, plan), opts)
 
 	if opts.print {
-		fmt.Fprintf(opts.output, "%s\n", cmd.String())
+		switch opts.printFormat {
+		case printFormatJSON:
+			return plan.writeJSON(opts.output)
+		case printFormatCompose:
+			return writeCompose(opts.output, append(servicePlans, plan), !ownNetwork)
+		}
+		if ownNetwork {
+			fmt.Fprintf(opts.output, "%s\n", localRunNetworkCommand(plan.runtime(), network))
+		}
+		printLocalServices(ctx, opts.output, servicePlans)
+		fmt.Fprintf(opts.output, "%s\n", strings.Join(plan.shellCommands(ctx), "\n"))
 		return nil
 	}
 
+	if ownNetwork {
+		if err := ensureLocalRunNetwork(ctx, network); err != nil {
+			return err
+		}
+	}
+	if len(servicePlans) > 0 {
+		removeServices, err := startLocalServices(ctx, servicePlans, opts)
+		if err != nil {
+			return err
+		}
+		if !opts.detach {
+			defer removeServices()
+		}
+	}
+
+	cmd := plan.command(ctx)
 	cmd.Stdout = opts.output
 	cmd.Stderr = opts.err
 
-	fmt.Printf("Starting local-run for: %s on: http://0.0.0.0:%d\n\n", name, opts.port)
+	if opts.detach {
+		if err := stageLocalRun(ctx, plan, opts.err); err != nil {
+			return err
+		}
+		if err := cmd.Run(); err != nil {
+			return err
+		}
 
+		stopped := func() bool { return !localRunContainerRunning(name) }
+		if err := waitForWatchdog(ctx, localRunHealthURL(opts), opts.readyTimeout, stopped); err != nil {
+			if errors.Is(err, errExitedBeforeReady) {
+				return fmt.Errorf("%s %w, run it without --detach to see its output", name, err)
+			}
+			return fmt.Errorf("%s %w, see: faas-cli local-run logs %s", name, err, name)
+		}
+
+		printLocalRunReady(name, opts, true)
+		if opts.invoke {
+			return invokeLocalRun(ctx, name, opts)
+		}
+		return nil
+	}
+
+	if opts.withAsync {
+		stopQueue, err := startAsyncQueue(map[string]int{name: opts.port}, opts)
+		if err != nil {
+			return err
+		}
+		defer stopQueue()
+	}
+
+	if opts.gateway {
+		stopGateway, err := startLocalRunGateway(map[string]int{name: opts.port}, opts)
+		if err != nil {
+			return err
+		}
+		defer stopGateway()
+	}
+
+	if len(opts.capture) > 0 {
+		stopCapture, err := startCaptureProxy(name, opts)
+		if err != nil {
+			return err
+		}
+		defer stopCapture()
+	}
+
+	if opts.watch {
+		fmt.Fprintf(opts.output, "Starting local-run for: %s on: %s\n\n", name, localRunURL(opts, opts.port))
+		return watchFunction(fnc, services.StackConfiguration.CopyExtraPaths, opts)
+	}
+
+	logs := newLocalRunLogs(opts)
+	stdout, stderr := logs.streams(name)
+	defer logs.flush()
+
+	// The last lines are shown again when the container exits before it is
+	// ready, as they usually give the reason
+	tail := newTailWriter(localRunTailLines)
+	cmd.Stdout, cmd.Stderr = io.MultiWriter(stdout, tail), io.MultiWriter(stderr, tail)
+
+	defer teardownOnSignal([]string{name}, opts)()
+
+	if err := stageLocalRun(ctx, plan, opts.err); err != nil {
+		return err
+	}
 	if err = cmd.Start(); err != nil {
 		return err
 	}
 
-	return cmd.Wait()
+	exited := make(chan struct{})
+	readyErr := make(chan error, 1)
+	var invokeErr error
+	go func() {
+		stopped := func() bool {
+			select {
+			case <-exited:
+				return true
+			default:
+				return false
+			}
+		}
+
+		err := waitForWatchdog(ctx, localRunHealthURL(opts), opts.readyTimeout, stopped)
+		if err == nil {
+			printLocalRunReady(name, opts, false)
+			if opts.invoke {
+				invokeErr = invokeLocalRun(ctx, name, opts)
+				if invokeErr != nil && opts.keep {
+					fmt.Fprintln(opts.err, invokeErr)
+				}
+				if !opts.keep {
+					stopLocalRunContainer(name, opts.stopGrace)
+				}
+			}
+		} else if !errors.Is(err, errExitedBeforeReady) && !stopped() {
+			// The container is stopped, so that faas-cli exits with the reason
+			stopLocalRunContainer(name, opts.stopGrace)
+		}
+		readyErr <- err
+	}()
+
+	if opts.stats {
+		live := liveStats{interval: opts.statsInterval, print: logs.note}
+		if fnc.Limits != nil {
+			live.memoryLimit = fnc.Limits.Memory
+		}
+		err = runWithStats(ctx, cmd, name, opts.output, live)
+	} else {
+		err = cmd.Wait()
+	}
+	close(exited)
+
+	if notReady := <-readyErr; notReady != nil && !errors.Is(notReady, context.Canceled) {
+		if errors.Is(notReady, errExitedBeforeReady) {
+			return fmt.Errorf("%s %w%s", name, notReady, crashDiagnostics(plan, err, tail.Lines(), len(opts.remoteHost) > 0))
+		}
+		if err != nil {
+			return fmt.Errorf("%s %w: %s", name, notReady, err)
+		}
+		return fmt.Errorf("%s %w", name, notReady)
+	} else if notReady == nil && opts.invoke && !opts.keep {
+		// The container was stopped after the request, so its exit code is
+		// that of the stop rather than a failure
+		return invokeErr
+	}
+	return err
 }
 
 // buildDockerRun constructs a exec.Cmd from the given stack Function
 func buildDockerRun(ctx context.Context, fnc stack.Function, opts runOptions) (*exec.Cmd, error) {
-	args := []string{"run", "--rm", "-i", fmt.Sprintf("-p=%d:8080", opts.port)}
+	plan, err := planDockerRun(fnc, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	if opts.network != "" {
-		args = append(args, fmt.Sprintf("--network=%s", opts.network))
+	return plan.command(ctx), nil
+}
+
+// planDockerRun describes the container to start for the given stack Function
+func planDockerRun(fnc stack.Function, opts runOptions) (*localRunPlan, error) {
+	plan := &localRunPlan{
+		Runtime: opts.runtime,
+		Name:    localRunContainerName(fnc.Name),
+		Image:   fnc.Image,
+		Env:     map[string]string{},
+		Ports:   []localRunPort{{Host: opts.port, Container: 8080}},
+		// A known name and labels let "local-run ps|stop|logs|prune" find the container
+		Labels: map[string]string{
+			localRunLabel:         "true",
+			localRunFunctionLabel: fnc.Name,
+			localRunPortLabel:     strconv.Itoa(opts.port),
+		},
+		Network:  opts.network,
+		Workdir:  opts.workdir,
+		ReadOnly: fnc.ReadOnlyRootFilesystem,
+		Detach:   opts.detach,
 	}
+	addLocalRunLabels(plan.Labels, opts)
 
-	fprocess, err := deriveFprocess(fnc)
-	if err != nil {
-		return nil, err
+	if opts.mountHandler {
+		hostPath, containerPath, err := handlerMount(fnc)
+		if err != nil {
+			return nil, err
+		}
+		plan.Mounts = append(plan.Mounts, localRunMount{Source: hostPath, Target: containerPath, ReadOnly: true})
+	}
+
+	// With --image, the fprocess built into the image is used
+	fprocess := opts.fprocess
+	if fprocess == "" && len(opts.image) == 0 {
+		var err error
+		if fprocess, err = deriveFprocess(fnc); err != nil {
+			return nil, err
+		}
 	}
 
-	for name, value := range fnc.Environment {
-		args = append(args, fmt.Sprintf("-e=%s=%s", name, value))
+	var debug *stack.DebugConvention
+	var debugEnv map[string]string
+	if opts.debugPort > 0 {
+		convention, err := localRunDebugConvention(fnc.Language, opts.debugConventions)
+		if err != nil {
+			return nil, err
+		}
+		plan.Ports = append(plan.Ports, localRunPort{Host: opts.debugPort, Container: convention.Port})
+		fprocess = debugFprocess(convention, fprocess)
+		debug, debugEnv = &convention, convention.Environment
+	}
+
+	if opts.explainEnv {
+		if err := explainLocalRunEnvironment(opts.output, fnc, opts, fprocess, debug); err != nil {
+			return nil, err
+		}
 	}
 
 	moreEnv, err := readFiles(fnc.EnvironmentFile)
@@ -152,27 +926,42 @@ func buildDockerRun(ctx context.Context, fnc stack.Function, opts runOptions) (*
 		return nil, err
 	}
 
-	for name, value := range moreEnv {
-		args = append(args, fmt.Sprintf("-e=%s=%s", name, value))
+	envFiles, err := readEnvFiles(opts.envFiles)
+	if err != nil {
+		return nil, err
 	}
 
-	for name, value := range opts.extraEnv {
-		args = append(args, fmt.Sprintf("-e=%s=%s", name, value))
+	// Later sources override earlier ones, as they did when each was passed
+	// to docker with -e in turn
+	sources := []map[string]string{fnc.Environment, moreEnv}
+	for _, layer := range envFiles {
+		sources = append(sources, layer.values)
+	}
+	for _, env := range append(sources, debugEnv, opts.extraEnv) {
+		for name, value := range env {
+			plan.Env[name] = value
+		}
+	}
+	if len(fprocess) > 0 {
+		plan.Env["fprocess"] = fprocess
 	}
 
-	if fnc.ReadOnlyRootFilesystem {
-		args = append(args, "--read-only")
+	limits, err := localRunResources(fnc)
+	if err != nil {
+		return nil, err
 	}
+	plan.Limits = limits
 
-	if fnc.Limits != nil {
-		if fnc.Limits.Memory != "" {
-			// use a soft limit for debugging
-			args = append(args, fmt.Sprintf("--memory-reservation=%s", fnc.Limits.Memory))
-		}
+	if err := planContainerOptions(plan, fnc); err != nil {
+		return nil, err
+	}
+	if err := planSecurityContext(plan, fnc); err != nil {
+		return nil, err
+	}
 
-		if fnc.Limits.CPU != "" {
-			args = append(args, fmt.Sprintf("--cpus=%s", fnc.Limits.CPU))
-		}
+	plan.GPUs = opts.gpus
+	if plan.GPUs == "" && fnc.Limits != nil {
+		plan.GPUs = fnc.Limits.GPU
 	}
 
 	if len(fnc.Secrets) > 0 {
@@ -193,15 +982,98 @@ func buildDockerRun(ctx context.Context, fnc stack.Function, opts runOptions) (*
 			}
 		}
 
-		args = append(args, fmt.Sprintf("--volume=%s:/var/openfaas/secrets", secretsPath))
+		if len(opts.remoteHost) > 0 {
+			// Only the function's own secrets are copied to the other machine
+			for _, secret := range fnc.Secrets {
+				plan.Copies = append(plan.Copies, localRunMount{Source: filepath.Join(secretsPath, secret), Target: path.Join(containerSecretsPath, secret)})
+			}
+		} else {
+			plan.Mounts = append(plan.Mounts, localRunMount{Source: secretsPath, Target: containerSecretsPath})
+		}
+	}
+
+	for _, volume := range opts.volumes {
+		mount, err := parseLocalRunVolume(volume)
+		if err != nil {
+			return nil, err
+		}
+
+		if !opts.print {
+			if _, err := os.Stat(mount.Source); err != nil {
+				return nil, fmt.Errorf("can't mount --volume %s: %w", volume, err)
+			}
+		}
+		plan.Mounts = append(plan.Mounts, mount)
+	}
+
+	return plan, nil
+}
+
+// parseLocalRunVolume reads a --volume of SRC:DST[:ro|rw]. The source is made
+// absolute, as docker would otherwise treat a relative path as the name of a
+// volume.
+func parseLocalRunVolume(volume string) (localRunMount, error) {
+	parts := strings.Split(volume, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return localRunMount{}, fmt.Errorf("--volume must be SRC:DST or SRC:DST:ro, got: %q", volume)
+	}
+
+	if !strings.HasPrefix(parts[1], "/") {
+		return localRunMount{}, fmt.Errorf("--volume must mount to an absolute path in the container, got: %q", volume)
+	}
+
+	mount := localRunMount{Target: parts[1]}
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+			mount.ReadOnly = true
+		case "rw":
+		default:
+			return localRunMount{}, fmt.Errorf("--volume mode must be ro or rw, got: %q", volume)
+		}
+	}
+
+	source, err := filepath.Abs(parts[0])
+	if err != nil {
+		return localRunMount{}, fmt.Errorf("can't determine --volume source %q: %w", parts[0], err)
+	}
+	mount.Source = source
+	return mount, nil
+}
+
+// handlerMount returns the local handler folder, and where the final stage of
+// the template's Dockerfile copies it to
+func handlerMount(fnc stack.Function) (string, string, error) {
+	if !languageExistsNotDockerfile(fnc.Language) {
+		return "", "", fmt.Errorf("--mount-handler needs a function which uses a language template")
 	}
 
-	args = append(args, fmt.Sprintf("-e=fprocess=%s", fprocess))
-	args = append(args, fnc.Image)
+	langTemplate, err := stack.LoadLanguageTemplate(fnc.Language)
+	if err != nil {
+		return "", "", fmt.Errorf(`template directory may be missing or invalid, please run "faas-cli template pull"
+Error: %s`, err.Error())
+	}
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	dockerfile, err := builder.LoadTemplateDockerfile(fnc.Language, langTemplate.HandlerFolder)
+	if err != nil {
+		return "", "", err
+	}
+
+	// For compiled languages the handler is only copied into a build stage
+	if len(dockerfile.Stages) == 0 || len(dockerfile.Stages[len(dockerfile.Stages)-1].HandlerPath) == 0 {
+		return "", "", fmt.Errorf("the %s template does not copy the handler into its final image, --mount-handler only works with interpreted languages", fnc.Language)
+	}
+
+	hostPath, err := filepath.Abs(fnc.Handler)
+	if err != nil {
+		return "", "", fmt.Errorf("can't determine handler folder: %w", err)
+	}
+
+	if info, err := os.Stat(hostPath); err != nil || !info.IsDir() {
+		return "", "", fmt.Errorf("handler folder %q not found", fnc.Handler)
+	}
 
-	return cmd, nil
+	return hostPath, dockerfile.Stages[len(dockerfile.Stages)-1].HandlerPath, nil
 }
 
 func dirContainsFiles(dir string, names ...string) error {
@@ -231,7 +1103,7 @@ type missingFileError struct {
 }
 
 func (m missingFileError) Error() string {
-	return fmt.Sprintf("create the following secrets (%s) in: %q", strings.Join(m.missing, ", "), m.dir)
+	return fmt.Sprintf("create the following secrets (%s) in: %q, or copy them from a cluster with: faas-cli local-run secrets pull", strings.Join(m.missing, ", "), m.dir)
 }
 
 func (m *missingFileError) AddMissingSecret(p string) {
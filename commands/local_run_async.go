@@ -0,0 +1,72 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/openfaas/faas-cli/asyncqueue"
+)
+
+// asyncQueueTimeout is the longest the queue waits for a function to respond
+const asyncQueueTimeout = 5 * time.Minute
+
+// startAsyncQueue serves an in-memory queue for the functions, which are
+// mapped to their ports. The queue's port follows the highest of the
+// functions' ports unless --async-port is given, the returned func drains the
+// queue and stops the server
+func startAsyncQueue(functions map[string]int, opts runOptions) (func(), error) {
+	port := opts.asyncPort
+	if port == 0 {
+		for _, functionPort := range functions {
+			if functionPort >= port {
+				port = functionPort + 1
+			}
+		}
+	}
+
+	queue := asyncqueue.New(1, asyncqueue.DefaultDepth, asyncQueueTimeout)
+	queue.Logger = log.New(opts.err, "", log.LstdFlags)
+	for name, functionPort := range functions {
+		target, err := url.Parse(fmt.Sprintf("http://%s", net.JoinHostPort(localRunHost(opts), strconv.Itoa(functionPort))))
+		if err != nil {
+			queue.Close()
+			return nil, err
+		}
+		queue.AddFunction(name, target)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		queue.Close()
+		return nil, fmt.Errorf("unable to start the queue on port %d: %w", port, err)
+	}
+
+	server := &http.Server{Handler: queue, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(opts.err, "Queue stopped: %s\n", err)
+		}
+	}()
+
+	for _, name := range namesByPort(functions) {
+		fmt.Fprintf(opts.output, "Async invocations: http://127.0.0.1:%d/async-function/%s\n", port, name)
+	}
+	fmt.Fprintln(opts.output)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+		queue.Close()
+	}, nil
+}
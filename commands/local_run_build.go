@@ -0,0 +1,40 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// buildLocalRun builds the functions which local-run is about to start, with
+// the same pipeline as "faas-cli build", so that a stale or missing image is
+// never run
+var buildLocalRun = func(cmd *cobra.Command, services *stack.Services) error {
+	if err := pullBuildTemplates(services, cmd); err != nil {
+		return err
+	}
+
+	errors := build(services, 1, false, false)
+	if len(errors) > 0 {
+		errorSummary := "Errors received during build:\n"
+		for _, err := range errors {
+			errorSummary = errorSummary + "- " + err.Error() + "\n"
+		}
+		return fmt.Errorf("%s", output.Red.Apply(errorSummary))
+	}
+	return nil
+}
+
+// localRunServices reads the function given by name, or when name is empty,
+// every function matched by --regex and --filter
+func localRunServices(name string) (*stack.Services, error) {
+	if len(name) > 0 {
+		return stack.ParseYAMLFile(yamlFile, "", name, true)
+	}
+	return stack.ParseYAMLFile(yamlFile, regex, filter, true)
+}
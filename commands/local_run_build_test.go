@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+func Test_localRun_BuildsNamedFunction(t *testing.T) {
+	t.Setenv("OPENFAAS_EXPERIMENTAL", "1")
+
+	stackFile := filepath.Join(t.TempDir(), "stack.yml")
+	os.WriteFile(stackFile, []byte(`version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    lang: python3-http
+    handler: ./orders
+    image: acme/orders:0.1.0
+  payments:
+    lang: python3-http
+    handler: ./payments
+    image: acme/payments:0.1.0
+`), 0600)
+
+	resetForTest()
+	yamlFile = stackFile
+
+	savedBuild := buildLocalRun
+	defer func() { buildLocalRun = savedBuild }()
+
+	errBuilt := errors.New("built")
+	var built []string
+	buildLocalRun = func(cmd *cobra.Command, services *stack.Services) error {
+		for name := range services.Functions {
+			built = append(built, name)
+		}
+		return errBuilt
+	}
+
+	cmd := newLocalRunCmd()
+	if err := cmd.ParseFlags([]string{"--build"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.PreRunE(cmd, []string{"orders"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.RunE(cmd, []string{"orders"}); !errors.Is(err, errBuilt) {
+		t.Fatalf("want the build to run before the container, got: %v", err)
+	}
+	if len(built) != 1 || built[0] != "orders" {
+		t.Errorf("want only orders built, got: %v", built)
+	}
+}
+
+func Test_localRun_BuildWithPrint(t *testing.T) {
+	t.Setenv("OPENFAAS_EXPERIMENTAL", "1")
+
+	cmd := newLocalRunCmd()
+	if err := cmd.ParseFlags([]string{"--build", "--print"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cmd.PreRunE(cmd, []string{"orders"})
+	if err == nil || !strings.Contains(err.Error(), "--build") {
+		t.Fatalf("want --build rejected with --print, got: %v", err)
+	}
+}
@@ -0,0 +1,389 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/openfaas/faas-cli/version"
+)
+
+// captureBodyLimit is the most of each body which is saved, the rest is
+// still sent on to the function or client
+const captureBodyLimit = 1 << 20
+
+// captureKey holds the start and body of a request in its context, for when
+// its response is recorded
+type captureKey int
+
+const (
+	captureStartKey captureKey = iota
+	captureBodyKey
+)
+
+// captureExchange is a request to the function and its response, as written
+// on each line of a JSONL capture
+type captureExchange struct {
+	Started         time.Time   `json:"started"`
+	DurationMs      float64     `json:"duration_ms"`
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"request_headers"`
+	RequestBody     captureBody `json:"request_body"`
+	Status          int         `json:"status"`
+	ResponseHeaders http.Header `json:"response_headers"`
+	ResponseBody    captureBody `json:"response_body"`
+}
+
+// captureBody is saved as text, or as base64 when it isn't UTF-8
+type captureBody struct {
+	Size      int    `json:"size"`
+	Text      string `json:"text,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+func newCaptureBody(data []byte) captureBody {
+	body := captureBody{Size: len(data)}
+	if len(data) > captureBodyLimit {
+		data, body.Truncated = data[:captureBodyLimit], true
+	}
+	if utf8.Valid(data) {
+		body.Text = string(data)
+	} else {
+		body.Text, body.Encoding = base64.StdEncoding.EncodeToString(data), "base64"
+	}
+	return body
+}
+
+// captureRecorder saves the exchanges to a file. A JSONL file is written as
+// each exchange completes, while a HAR file is written once local-run exits,
+// as it is one document.
+type captureRecorder struct {
+	mu        sync.Mutex
+	path      string
+	har       bool
+	file      *os.File
+	exchanges []captureExchange
+	count     int
+}
+
+func newCaptureRecorder(path string) (*captureRecorder, error) {
+	r := &captureRecorder{path: path, har: strings.EqualFold(filepath.Ext(path), ".har")}
+	if r.har {
+		return r, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the file for --capture: %w", err)
+	}
+	r.file = file
+	return r, nil
+}
+
+func (r *captureRecorder) record(exchange captureExchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.count++
+	if r.har {
+		r.exchanges = append(r.exchanges, exchange)
+		return nil
+	}
+
+	line, err := json.Marshal(exchange)
+	if err != nil {
+		return err
+	}
+	_, err = r.file.Write(append(line, '\n'))
+	return err
+}
+
+// close writes the HAR file, or closes the JSONL file, and returns how many
+// exchanges were saved
+func (r *captureRecorder) close() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.har {
+		return r.count, r.file.Close()
+	}
+
+	data, err := json.MarshalIndent(newHAR(r.exchanges), "", "  ")
+	if err != nil {
+		return r.count, err
+	}
+	return r.count, os.WriteFile(r.path, data, 0644)
+}
+
+// newCaptureProxy forwards requests to target, and records each one with its
+// response once the response has been read
+func newCaptureProxy(target *url.URL, recorder *captureRecorder, errs io.Writer) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	save := func(req *http.Request, status int, header http.Header, body []byte) {
+		start, _ := req.Context().Value(captureStartKey).(time.Time)
+		requestBody, _ := req.Context().Value(captureBodyKey).([]byte)
+
+		exchange := captureExchange{
+			Started:         start,
+			DurationMs:      float64(time.Since(start).Microseconds()) / 1000,
+			Method:          req.Method,
+			URL:             req.URL.RequestURI(),
+			RequestHeaders:  req.Header,
+			RequestBody:     newCaptureBody(requestBody),
+			Status:          status,
+			ResponseHeaders: header,
+			ResponseBody:    newCaptureBody(body),
+		}
+		if err := recorder.record(exchange); err != nil {
+			fmt.Fprintf(errs, "Unable to save a request to %s: %s\n", recorder.path, err)
+		}
+	}
+
+	proxy.ModifyResponse = func(res *http.Response) error {
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		res.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		save(res.Request, res.StatusCode, res.Header, body)
+		return nil
+	}
+
+	// A request which the function didn't answer, such as while it restarts,
+	// is saved with the 502 sent back to the client
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		body := []byte(fmt.Sprintf("unable to reach the function: %s\n", err))
+		header := http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}}
+		save(req, http.StatusBadGateway, header, body)
+
+		w.Header().Set("Content-Type", header.Get("Content-Type"))
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write(body)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := context.WithValue(r.Context(), captureStartKey, time.Now())
+		ctx = context.WithValue(ctx, captureBodyKey, body)
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// startCaptureProxy serves the capture proxy in front of the function on
+// opts.capturePort, or a free port when it is 0. The returned func stops
+// it and saves the capture.
+func startCaptureProxy(name string, opts runOptions) (func(), error) {
+	target, err := url.Parse(strings.TrimSuffix(localRunInvokeURL(opts), "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	recorder, err := newCaptureRecorder(opts.capture)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.capturePort))
+	if err != nil {
+		recorder.close()
+		return nil, fmt.Errorf("unable to start the capture proxy on port %d: %w", opts.capturePort, err)
+	}
+
+	server := &http.Server{Handler: newCaptureProxy(target, recorder, opts.err), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(opts.err, "Capture proxy stopped: %s\n", err)
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	fmt.Fprintf(opts.output, "Capturing requests to %s on: http://127.0.0.1:%d, saved to: %s\n\n", name, port, opts.capture)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+
+		count, err := recorder.close()
+		if err != nil {
+			fmt.Fprintf(opts.err, "Unable to save the capture to %s: %s\n", opts.capture, err)
+			return
+		}
+		fmt.Fprintf(opts.output, "Captured %d request(s) to: %s\n", count, opts.capture)
+	}, nil
+}
+
+// harLog is the document of an HTTP Archive 1.2, with the fields the
+// browsers' developer tools need to import it
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// newHAR converts the exchanges to an HTTP Archive. The time is all given to
+// wait, as the proxy can't tell how long the function took to receive the
+// request.
+func newHAR(exchanges []captureExchange) harLog {
+	var har harLog
+	har.Log.Version = "1.2"
+	har.Log.Creator = harCreator{Name: "faas-cli", Version: version.BuildVersion()}
+	har.Log.Entries = []harEntry{}
+
+	for _, e := range exchanges {
+		requestURL, _ := url.Parse(e.URL)
+
+		entry := harEntry{
+			StartedDateTime: e.Started.Format(time.RFC3339Nano),
+			Time:            e.DurationMs,
+			Request: harRequest{
+				Method:      e.Method,
+				URL:         e.URL,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(e.RequestHeaders),
+				QueryString: []harNameValue{},
+				HeadersSize: -1,
+				BodySize:    e.RequestBody.Size,
+			},
+			Response: harResponse{
+				Status:      e.Status,
+				StatusText:  http.StatusText(e.Status),
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harNameValue{},
+				Headers:     harHeaders(e.ResponseHeaders),
+				Content: harContent{
+					Size:     e.ResponseBody.Size,
+					MimeType: e.ResponseHeaders.Get("Content-Type"),
+					Text:     e.ResponseBody.Text,
+					Encoding: e.ResponseBody.Encoding,
+				},
+				HeadersSize: -1,
+				BodySize:    e.ResponseBody.Size,
+			},
+			Timings: harTimings{Wait: e.DurationMs},
+		}
+
+		if requestURL != nil {
+			for key, values := range requestURL.Query() {
+				for _, value := range values {
+					entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: key, Value: value})
+				}
+			}
+			sort.Slice(entry.Request.QueryString, func(i, j int) bool {
+				return entry.Request.QueryString[i].Name < entry.Request.QueryString[j].Name
+			})
+		}
+		if e.RequestBody.Size > 0 {
+			entry.Request.PostData = &harPostData{MimeType: e.RequestHeaders.Get("Content-Type"), Text: e.RequestBody.Text}
+		}
+
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+	return har
+}
+
+// harHeaders lists headers in the order of their names, so that a capture
+// is the same each time it is written
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			headers = append(headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
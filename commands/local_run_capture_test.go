@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCaptureTestProxy(t *testing.T, path string) (*httptest.Server, *captureRecorder) {
+	function := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"echo":"` + string(body) + `"}`))
+	}))
+	t.Cleanup(function.Close)
+
+	target, _ := url.Parse(function.URL)
+	recorder, err := newCaptureRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httptest.NewServer(newCaptureProxy(target, recorder, io.Discard))
+	t.Cleanup(proxy.Close)
+	return proxy, recorder
+}
+
+func Test_captureProxy_JSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	proxy, recorder := newCaptureTestProxy(t, path)
+
+	res, err := http.Post(proxy.URL+"/orders?id=1", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated || string(body) != `{"echo":"hello"}` {
+		t.Fatalf("want the function's response passed through, got %d: %s", res.StatusCode, body)
+	}
+
+	if count, err := recorder.close(); err != nil || count != 1 {
+		t.Fatalf("want one request saved, got %d: %v", count, err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatalf("want a line for the request")
+	}
+	var exchange captureExchange
+	if err := json.Unmarshal(scanner.Bytes(), &exchange); err != nil {
+		t.Fatal(err)
+	}
+
+	if exchange.Method != http.MethodPost || exchange.URL != "/orders?id=1" || exchange.Status != http.StatusCreated {
+		t.Errorf("want the request and status, got: %+v", exchange)
+	}
+	if exchange.RequestBody.Text != "hello" || exchange.ResponseBody.Text != `{"echo":"hello"}` {
+		t.Errorf("want both bodies, got %q and %q", exchange.RequestBody.Text, exchange.ResponseBody.Text)
+	}
+	if exchange.RequestHeaders.Get("Content-Type") != "text/plain" || exchange.ResponseHeaders.Get("Content-Type") != "application/json" {
+		t.Errorf("want both headers, got %v and %v", exchange.RequestHeaders, exchange.ResponseHeaders)
+	}
+}
+
+func Test_captureProxy_HAR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.har")
+	proxy, recorder := newCaptureTestProxy(t, path)
+
+	res, err := http.Get(proxy.URL + "/?b=2&a=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if _, err := recorder.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har harLog
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatal(err)
+	}
+
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 1 {
+		t.Fatalf("want a HAR 1.2 with one entry, got: %s", data)
+	}
+	entry := har.Log.Entries[0]
+	if entry.Request.Method != http.MethodGet || entry.Response.Status != http.StatusCreated || entry.Response.Content.MimeType != "application/json" {
+		t.Errorf("want the request and response, got: %+v", entry)
+	}
+	if entry.Request.PostData != nil {
+		t.Errorf("want no postData for a request without a body")
+	}
+	if q := entry.Request.QueryString; len(q) != 2 || q[0].Name != "a" || q[1].Name != "b" {
+		t.Errorf("want the query string in order, got: %v", q)
+	}
+}
+
+func Test_captureProxy_FunctionDown(t *testing.T) {
+	recorder, err := newCaptureRecorder(filepath.Join(t.TempDir(), "session.har"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ := url.Parse("http://127.0.0.1:1")
+	proxy := httptest.NewServer(newCaptureProxy(target, recorder, io.Discard))
+	defer proxy.Close()
+
+	res, err := http.Get(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadGateway {
+		t.Errorf("want 502, got %d", res.StatusCode)
+	}
+	if len(recorder.exchanges) != 1 || recorder.exchanges[0].Status != http.StatusBadGateway {
+		t.Errorf("want the failed request saved, got: %+v", recorder.exchanges)
+	}
+}
+
+func Test_newCaptureBody(t *testing.T) {
+	if body := newCaptureBody([]byte{0xff, 0x00}); body.Encoding != "base64" || body.Text != "/wA=" || body.Size != 2 {
+		t.Errorf("want binary saved as base64, got: %+v", body)
+	}
+	if body := newCaptureBody(make([]byte, captureBodyLimit+1)); !body.Truncated || body.Size != captureBodyLimit+1 {
+		t.Errorf("want a large body truncated with its size, got size %d truncated %v", body.Size, body.Truncated)
+	}
+}
+
+func Test_localRun_CaptureWithDetach(t *testing.T) {
+	t.Setenv("OPENFAAS_EXPERIMENTAL", "1")
+
+	cmd := newLocalRunCmd()
+	if err := cmd.ParseFlags([]string{"--capture", "session.har", "--detach"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cmd.PreRunE(cmd, []string{"orders"})
+	if err == nil || !strings.Contains(err.Error(), "--capture") {
+		t.Fatalf("want --capture rejected with --detach, got: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// printFormatCompose prints a docker-compose.yml for the containers
+const printFormatCompose = "compose"
+
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+	Networks map[string]composeNetwork `yaml:"networks,omitempty"`
+}
+
+type composeService struct {
+	Image             string                           `yaml:"image"`
+	Command           []string                         `yaml:"command,omitempty"`
+	ContainerName     string                           `yaml:"container_name"`
+	StdinOpen         bool                             `yaml:"stdin_open"`
+	Ports             []string                         `yaml:"ports,omitempty"`
+	Environment       map[string]string                `yaml:"environment,omitempty"`
+	Labels            map[string]string                `yaml:"labels,omitempty"`
+	Volumes           []string                         `yaml:"volumes,omitempty"`
+	WorkingDir        string                           `yaml:"working_dir,omitempty"`
+	ReadOnly          bool                             `yaml:"read_only,omitempty"`
+	User              string                           `yaml:"user,omitempty"`
+	Privileged        bool                             `yaml:"privileged,omitempty"`
+	CapDrop           []string                         `yaml:"cap_drop,omitempty"`
+	CapAdd            []string                         `yaml:"cap_add,omitempty"`
+	SecurityOpt       []string                         `yaml:"security_opt,omitempty"`
+	MemoryReservation string                           `yaml:"mem_reservation,omitempty"`
+	CPUs              string                           `yaml:"cpus,omitempty"`
+	CPUShares         int                              `yaml:"cpu_shares,omitempty"`
+	ShmSize           string                           `yaml:"shm_size,omitempty"`
+	Ulimits           map[string]interface{}           `yaml:"ulimits,omitempty"`
+	Tmpfs             []string                         `yaml:"tmpfs,omitempty"`
+	NetworkMode       string                           `yaml:"network_mode,omitempty"`
+	Networks          map[string]composeServiceNetwork `yaml:"networks,omitempty"`
+	Deploy            *composeDeploy                   `yaml:"deploy,omitempty"`
+}
+
+// composeDeploy reserves the GPUs given by --gpus or limits.gpu
+type composeDeploy struct {
+	Resources struct {
+		Reservations struct {
+			Devices []composeDevice `yaml:"devices"`
+		} `yaml:"reservations"`
+	} `yaml:"resources"`
+}
+
+type composeDevice struct {
+	Driver       string      `yaml:"driver"`
+	Count        interface{} `yaml:"count,omitempty"`
+	DeviceIDs    []string    `yaml:"device_ids,omitempty"`
+	Capabilities []string    `yaml:"capabilities"`
+}
+
+type composeServiceNetwork struct {
+	Aliases []string `yaml:"aliases,omitempty"`
+}
+
+type composeNetwork struct {
+	Name     string            `yaml:"name,omitempty"`
+	External bool              `yaml:"external,omitempty"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+}
+
+// writeCompose prints the plans as a docker-compose.yml, with a service for
+// each function, and for each of the stack's x-local-services. A network given by --network is expected to exist already,
+// so it is marked as external. Mounts from within the working directory are
+// written relative to it, so that the file can be used from another checkout.
+func writeCompose(w io.Writer, plans []*localRunPlan, external bool) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	compose := composeFile{Services: map[string]composeService{}}
+	for _, plan := range plans {
+		service := composeService{
+			Image:         plan.Image,
+			Command:       plan.Command,
+			ContainerName: plan.Name,
+			StdinOpen:     true,
+			Environment:   plan.Env,
+			Labels:        plan.Labels,
+			WorkingDir:    plan.Workdir,
+			ReadOnly:      plan.ReadOnly,
+			User:          plan.User,
+			Privileged:    plan.Privileged,
+			CapDrop:       plan.CapDrop,
+			CapAdd:        plan.CapAdd,
+			SecurityOpt:   plan.SecurityOpts,
+		}
+
+		if plan.Network == "host" {
+			service.NetworkMode = "host"
+		} else {
+			for _, port := range plan.Ports {
+				service.Ports = append(service.Ports, fmt.Sprintf("%d:%d", port.Host, port.Container))
+			}
+		}
+
+		for _, mount := range plan.Mounts {
+			volume := fmt.Sprintf("%s:%s", composePath(wd, mount.Source), mount.Target)
+			if mount.ReadOnly {
+				volume += ":ro"
+			}
+			service.Volumes = append(service.Volumes, volume)
+		}
+
+		if plan.Limits != nil {
+			service.MemoryReservation = plan.Limits.MemoryReservation
+			service.CPUs = plan.Limits.CPUs
+			service.CPUShares = plan.Limits.CPUShares
+		}
+
+		service.ShmSize = plan.ShmSize
+		service.Tmpfs = plan.Tmpfs
+		for _, ulimit := range plan.Ulimits {
+			if service.Ulimits == nil {
+				service.Ulimits = map[string]interface{}{}
+			}
+			name, value := composeUlimit(ulimit)
+			service.Ulimits[name] = value
+		}
+
+		if plan.GPUs != "" {
+			device, err := composeGPUs(plan.GPUs)
+			if err != nil {
+				return err
+			}
+			service.Deploy = &composeDeploy{}
+			service.Deploy.Resources.Reservations.Devices = []composeDevice{device}
+		}
+
+		if plan.Network != "" && plan.Network != "host" {
+			service.Networks = map[string]composeServiceNetwork{plan.Network: {Aliases: plan.Aliases}}
+
+			if compose.Networks == nil {
+				compose.Networks = map[string]composeNetwork{}
+			}
+			network := composeNetwork{Name: plan.Network, External: external}
+			if !external {
+				network.Labels = map[string]string{localRunLabel: "true"}
+			}
+			compose.Networks[plan.Network] = network
+		}
+
+		name := plan.Labels[localRunFunctionLabel]
+		if localService, ok := plan.Labels[localRunServiceLabel]; ok {
+			name = localService
+		}
+		compose.Services[name] = service
+	}
+
+	out, err := yaml.Marshal(compose)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+func composePath(wd, path string) string {
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	if rel == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(rel)
+}
+
+// composeGPUs converts the value of docker run --gpus to a device reservation,
+// from all, a count, or device=ID[,ID]
+func composeGPUs(gpus string) (composeDevice, error) {
+	device := composeDevice{Driver: "nvidia", Capabilities: []string{"gpu"}}
+
+	value := strings.Trim(gpus, `"'`)
+	switch {
+	case value == "all":
+		device.Count = "all"
+	case strings.HasPrefix(value, "device="):
+		device.DeviceIDs = strings.Split(strings.TrimPrefix(value, "device="), ",")
+	default:
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 1 {
+			return device, fmt.Errorf("--compose can write GPUs of all, a count or device=ID, got: %q", gpus)
+		}
+		device.Count = count
+	}
+	return device, nil
+}
+
+// composeUlimit converts NAME=SOFT[:HARD] to a limit of compose, which is a
+// number, or the soft and hard limits
+func composeUlimit(ulimit string) (string, interface{}) {
+	name, value := ulimit, ""
+	if index := strings.Index(ulimit, "="); index > -1 {
+		name, value = ulimit[:index], ulimit[index+1:]
+	}
+
+	parts := strings.SplitN(value, ":", 2)
+	soft, _ := strconv.Atoi(parts[0])
+	if len(parts) == 1 {
+		return name, soft
+	}
+	hard, _ := strconv.Atoi(parts[1])
+	return name, map[string]int{"soft": soft, "hard": hard}
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func Test_runStack_Compose(t *testing.T) {
+	dir := t.TempDir()
+	stackFile := filepath.Join(dir, "stack.yml")
+	os.WriteFile(stackFile, []byte(`version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    lang: dockerfile
+    handler: ./orders
+    image: acme/orders:0.1.0
+    fprocess: ./handler
+    secrets:
+    - db-password
+    limits:
+      memory: 128Mi
+  payments:
+    lang: dockerfile
+    handler: ./payments
+    image: acme/payments:0.1.0
+    fprocess: ./handler
+`), 0600)
+
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	resetForTest()
+	yamlFile = stackFile
+	defer func() { yamlFile = "" }()
+
+	var buf bytes.Buffer
+	err := runStack(context.Background(), runOptions{port: 8080, print: true, printFormat: printFormatCompose, output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var compose composeFile
+	if err := yaml.Unmarshal(buf.Bytes(), &compose); err != nil {
+		t.Fatalf("want a valid compose file, got: %s\n%s", err, buf.String())
+	}
+
+	orders := compose.Services["orders"]
+	if orders.Image != "acme/orders:0.1.0" || orders.ContainerName != "openfaas-local-orders" {
+		t.Errorf("want the image and container name, got: %+v", orders)
+	}
+	if !reflect.DeepEqual(orders.Ports, []string{"8080:8080"}) || !reflect.DeepEqual(compose.Services["payments"].Ports, []string{"8081:8080"}) {
+		t.Errorf("want ports in turn, got: %v and %v", orders.Ports, compose.Services["payments"].Ports)
+	}
+	if !reflect.DeepEqual(orders.Volumes, []string{"./.secrets:/var/openfaas/secrets"}) {
+		t.Errorf("want the secrets relative to the stack, got: %v", orders.Volumes)
+	}
+	if orders.Environment["fprocess"] != "./handler" || orders.MemoryReservation != "128Mi" {
+		t.Errorf("want the environment and limits, got: %+v", orders)
+	}
+	if aliases := orders.Networks[localRunStackNetwork].Aliases; !reflect.DeepEqual(aliases, []string{"orders"}) {
+		t.Errorf("want the function reachable by name, got: %v", aliases)
+	}
+
+	network := compose.Networks[localRunStackNetwork]
+	if network.External || network.Name != localRunStackNetwork || network.Labels[localRunLabel] != "true" {
+		t.Errorf("want the network created by compose, got: %+v", network)
+	}
+}
+
+func Test_composeGPUs(t *testing.T) {
+	cases := map[string]composeDevice{
+		"all":          {Driver: "nvidia", Count: "all", Capabilities: []string{"gpu"}},
+		"2":            {Driver: "nvidia", Count: 2, Capabilities: []string{"gpu"}},
+		`"device=0,2"`: {Driver: "nvidia", DeviceIDs: []string{"0", "2"}, Capabilities: []string{"gpu"}},
+	}
+	for gpus, want := range cases {
+		got, err := composeGPUs(gpus)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: want %v, got: %v, %v", gpus, want, got, err)
+		}
+	}
+
+	if _, err := composeGPUs("count=2,capabilities=utility"); err == nil {
+		t.Error("want an error for a value compose can't express")
+	}
+}
+
+func Test_composeUlimit(t *testing.T) {
+	if name, value := composeUlimit("nproc=512"); name != "nproc" || value != 512 {
+		t.Errorf("want nproc: 512, got: %s: %v", name, value)
+	}
+
+	name, value := composeUlimit("nofile=1024:4096")
+	if want := map[string]int{"soft": 1024, "hard": 4096}; name != "nofile" || !reflect.DeepEqual(value, want) {
+		t.Errorf("want nofile with soft and hard limits, got: %s: %v", name, value)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// localRunTailLines is how many of the last lines of a container's output
+// are shown when it exits before it is ready
+const localRunTailLines = 10
+
+// localRunImageArch is the architecture of an image, such as arm64, or ""
+// when it can't be inspected. It is replaced by tests.
+var localRunImageArch = func(runtime, image string) string {
+	out, err := exec.Command(runtime, "image", "inspect", "--format", "{{.Architecture}}", image).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// tailWriter keeps the last lines written to it
+type tailWriter struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial string
+}
+
+func newTailWriter(max int) *tailWriter {
+	return &tailWriter{max: max}
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	text := w.partial + string(p)
+	lines := strings.Split(text, "\n")
+	w.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		w.lines = append(w.lines, strings.TrimRight(line, "\r"))
+	}
+	if len(w.lines) > w.max {
+		w.lines = w.lines[len(w.lines)-w.max:]
+	}
+	return len(p), nil
+}
+
+// Lines are the last lines written, including one without a newline
+func (w *tailWriter) Lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lines := append([]string{}, w.lines...)
+	if len(w.partial) > 0 {
+		lines = append(lines, w.partial)
+	}
+	if len(lines) > w.max {
+		lines = lines[len(lines)-w.max:]
+	}
+	return lines
+}
+
+// crashDiagnostics describes why a container exited before its watchdog was
+// ready, from the exit code of docker run, its last lines of output and the
+// image's architecture, with the fixes for the common causes. The image's
+// architecture is only compared with this machine's when the daemon is local.
+func crashDiagnostics(plan *localRunPlan, runErr error, lines []string, remote bool) string {
+	var b strings.Builder
+
+	code := -1
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		code = exitErr.ExitCode()
+		fmt.Fprintf(&b, ", with exit code %d", code)
+	}
+
+	if len(lines) > 0 {
+		fmt.Fprintf(&b, "\n\nThe last lines of its output were:\n")
+		for _, line := range lines {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+
+	var hints []string
+	output := strings.ToLower(strings.Join(lines, "\n"))
+	fprocess := plan.Env["fprocess"]
+
+	switch {
+	case strings.Contains(output, "exec format error"):
+		hints = append(hints, fmt.Sprintf("the image was built for another architecture, rebuild it for %s, such as with faas-cli publish --platforms linux/%s", runtime.GOARCH, runtime.GOARCH))
+	case strings.Contains(output, "provide a valid process") || (strings.Contains(output, "provide") && strings.Contains(output, "fprocess")):
+		hints = append(hints, "the watchdog has no fprocess, set fprocess in the stack file or give --fprocess")
+	case code == 126 || code == 127 || strings.Contains(output, "executable file not found") || strings.Contains(output, "no such file or directory"):
+		if len(fprocess) > 0 {
+			hints = append(hints, fmt.Sprintf("the fprocess %q may not be in the image, check the fprocess of the stack file or template, or give --fprocess", fprocess))
+		} else {
+			hints = append(hints, "the image's command may not be in the image, check its Dockerfile or give --fprocess")
+		}
+	case code == 125:
+		hints = append(hints, "the container could not be started, see the output of "+plan.runtime()+" above, such as for a port which is in use")
+	case code == 137:
+		hints = append(hints, "the container was killed, which may be for running out of memory")
+	}
+
+	// The daemon may run images of another architecture under emulation, so
+	// an image which doesn't match this machine is only a hint
+	if !remote && !strings.Contains(output, "exec format error") {
+		if arch := localRunImageArch(plan.runtime(), plan.Image); len(arch) > 0 && arch != runtime.GOARCH {
+			hints = append(hints, fmt.Sprintf("the image is for %s, but this machine is %s", arch, runtime.GOARCH))
+		}
+	}
+
+	if len(hints) > 0 {
+		if len(lines) == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("\nThis may be because:\n")
+		for _, hint := range hints {
+			fmt.Fprintf(&b, "- %s\n", hint)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
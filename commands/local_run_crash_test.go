@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_tailWriter(t *testing.T) {
+	w := newTailWriter(3)
+	fmt.Fprint(w, "one\ntwo\r\nthr")
+	fmt.Fprint(w, "ee\nfour\nfive")
+
+	if got := strings.Join(w.Lines(), "|"); got != "three|four|five" {
+		t.Errorf("want the last 3 lines, got: %q", got)
+	}
+}
+
+// exitError runs a shell which exits with code, for an *exec.ExitError
+func exitError(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	if err == nil {
+		t.Fatal("want an error from the shell")
+	}
+	return err
+}
+
+func Test_crashDiagnostics(t *testing.T) {
+	savedArch := localRunImageArch
+	defer func() { localRunImageArch = savedArch }()
+	localRunImageArch = func(string, string) string { return "" }
+
+	plan := &localRunPlan{Runtime: "docker", Image: "stronghash:latest", Env: map[string]string{"fprocess": "./handler"}}
+
+	got := crashDiagnostics(plan, exitError(t, 127), []string{"Forking - ./handler []", "exec: \"./handler\": stat ./handler: no such file or directory"}, false)
+	for _, want := range []string{", with exit code 127", "  Forking - ./handler []", `the fprocess "./handler" may not be in the image`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in: %s", want, got)
+		}
+	}
+
+	got = crashDiagnostics(plan, exitError(t, 1), []string{"exec /usr/bin/fwatchdog: exec format error"}, false)
+	if !strings.Contains(got, "built for another architecture, rebuild it for "+runtime.GOARCH) {
+		t.Errorf("want a hint for the architecture, got: %s", got)
+	}
+
+	got = crashDiagnostics(plan, exitError(t, 1), []string{"Provide a valid process via fprocess environmental variable."}, false)
+	if !strings.Contains(got, "the watchdog has no fprocess") {
+		t.Errorf("want a hint for a missing fprocess, got: %s", got)
+	}
+
+	localRunImageArch = func(string, string) string { return "other" }
+	got = crashDiagnostics(plan, exitError(t, 2), nil, false)
+	if !strings.Contains(got, ", with exit code 2\n\nThis may be because:\n- the image is for other, but this machine is "+runtime.GOARCH) {
+		t.Errorf("want the image's architecture compared, got: %q", got)
+	}
+	if got := crashDiagnostics(plan, exitError(t, 2), nil, true); got != ", with exit code 2" {
+		t.Errorf("want no comparison with a remote daemon, got: %q", got)
+	}
+}
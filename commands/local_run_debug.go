@@ -0,0 +1,74 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// debugConventions start a debugger for the languages of the official
+// templates, the language of a function is matched by its prefix, e.g.
+// golang-http, node20 or python3-http. Each debugger must be installed in
+// the function's image.
+var debugConventions = map[string]stack.DebugConvention{
+	"go": {
+		Port:     2345,
+		FProcess: "dlv exec --headless --listen=:2345 --api-version=2 --accept-multiclient --continue {fprocess}",
+	},
+	"node": {
+		Port:        9229,
+		Environment: map[string]string{"NODE_OPTIONS": "--inspect=0.0.0.0:9229"},
+	},
+	"python": {
+		Port:     5678,
+		FProcess: "python3 -m debugpy --listen 0.0.0.0:5678 {args}",
+	},
+	"java": {
+		Port:        5005,
+		Environment: map[string]string{"JAVA_TOOL_OPTIONS": "-agentlib:jdwp=transport=dt_socket,server=y,suspend=n,address=*:5005"},
+	},
+}
+
+// localRunDebugConvention finds how to debug the language, from the stack
+// file's configuration by its exact name, then the built-in conventions by
+// the longest matching prefix
+func localRunDebugConvention(language string, configured map[string]stack.DebugConvention) (stack.DebugConvention, error) {
+	if convention, ok := configured[language]; ok {
+		if convention.Port < 1 || convention.Port > 65535 {
+			return stack.DebugConvention{}, fmt.Errorf("configuration.debug.%s.port must be between 1 and 65535, got: %d", language, convention.Port)
+		}
+		return convention, nil
+	}
+
+	prefixes := make([]string, 0, len(debugConventions))
+	for prefix := range debugConventions {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(strings.ToLower(language), prefix) {
+			return debugConventions[prefix], nil
+		}
+	}
+
+	return stack.DebugConvention{}, fmt.Errorf("--debug-port has no convention for the %s language, add one to the stack file under configuration.debug.%s", valueOrDash(language), valueOrDash(language))
+}
+
+// debugFprocess wraps the function's fprocess with the convention's
+func debugFprocess(convention stack.DebugConvention, fprocess string) string {
+	if convention.FProcess == "" {
+		return fprocess
+	}
+
+	args := ""
+	if fields := strings.Fields(fprocess); len(fields) > 1 {
+		args = strings.Join(fields[1:], " ")
+	}
+	return strings.NewReplacer("{fprocess}", fprocess, "{args}", args).Replace(convention.FProcess)
+}
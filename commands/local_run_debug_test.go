@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_localRunDebugConvention(t *testing.T) {
+	convention, err := localRunDebugConvention("golang-http", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if convention.Port != 2345 || !strings.HasPrefix(convention.FProcess, "dlv exec") {
+		t.Fatalf("want dlv for golang-http, got: %+v", convention)
+	}
+
+	configured := map[string]stack.DebugConvention{"node20": {Port: 9230}}
+	if convention, err = localRunDebugConvention("node20", configured); err != nil || convention.Port != 9230 {
+		t.Fatalf("want the stack file's convention, got: %+v, %v", convention, err)
+	}
+
+	if _, err := localRunDebugConvention("dockerfile", nil); err == nil || !strings.Contains(err.Error(), "configuration.debug.dockerfile") {
+		t.Fatalf("want an error without a convention, got: %v", err)
+	}
+	if _, err := localRunDebugConvention("rust", map[string]stack.DebugConvention{"rust": {}}); err == nil {
+		t.Fatalf("want an error for a convention without a port")
+	}
+}
+
+func Test_debugFprocess(t *testing.T) {
+	python := debugConventions["python"]
+	if got := debugFprocess(python, "python index.py"); got != "python3 -m debugpy --listen 0.0.0.0:5678 index.py" {
+		t.Errorf("unexpected fprocess: %s", got)
+	}
+
+	golang := debugConventions["go"]
+	if got := debugFprocess(golang, "./handler"); !strings.HasSuffix(got, "--continue ./handler") {
+		t.Errorf("unexpected fprocess: %s", got)
+	}
+
+	if got := debugFprocess(debugConventions["node"], "node index.js"); got != "node index.js" {
+		t.Errorf("want the fprocess kept, got: %s", got)
+	}
+}
+
+func Test_planDockerRun_DebugPort(t *testing.T) {
+	fnc := stack.Function{Name: "stronghash", Image: "stronghash:latest", Language: "node20", FProcess: "node index.js"}
+
+	var out bytes.Buffer
+	plan, err := planDockerRun(fnc, runOptions{port: 8080, debugPort: 9229, explainEnv: true, output: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := strings.Join(plan.args(), " ")
+	for _, want := range []string{"-p=8080:8080", "-p=9229:9229", "-e=NODE_OPTIONS=--inspect=0.0.0.0:9229", "-e=fprocess=node index.js"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("want %q in: %s", want, args)
+		}
+	}
+	if !strings.Contains(out.String(), "--debug-port") {
+		t.Errorf("want the debugger's variables explained, got:\n%s", out.String())
+	}
+
+	plan, err = planDockerRun(fnc, runOptions{port: 8080, debugPort: 9229, extraEnv: map[string]string{"NODE_OPTIONS": "--inspect-brk=0.0.0.0:9229"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Env["NODE_OPTIONS"] != "--inspect-brk=0.0.0.0:9229" {
+		t.Errorf("want --env to override the debugger's variables, got: %s", plan.Env["NODE_OPTIONS"])
+	}
+}
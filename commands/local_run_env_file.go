@@ -0,0 +1,94 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readEnvFiles reads each file given to --env-file, as a layer which takes
+// precedence over the files before it
+func readEnvFiles(paths []string) ([]envLayer, error) {
+	layers := make([]envLayer, 0, len(paths))
+	for _, path := range paths {
+		values, err := parseEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, envLayer{source: "--env-file " + path, values: values})
+	}
+	return layers, nil
+}
+
+// parseEnvFile reads KEY=VALUE pairs, one per line, as written for docker
+// compose or dotenv. Blank lines and lines starting with # are skipped, a
+// leading "export " is allowed, and a value may be quoted, where a double
+// quoted value can contain escapes such as \n.
+func parseEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read --env-file: %w", err)
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		index := strings.Index(line, "=")
+		if index < 1 {
+			return nil, fmt.Errorf("%s:%d: want KEY=VALUE, got: %q", path, number, line)
+		}
+
+		key, value := strings.TrimSpace(line[:index]), strings.TrimSpace(line[index+1:])
+		if strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: the name %q can't contain spaces", path, number, key)
+		}
+
+		if value, err = unquoteEnvValue(value); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, number, err)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read --env-file: %w", err)
+	}
+
+	return values, nil
+}
+
+func unquoteEnvValue(value string) (string, error) {
+	if len(value) == 0 {
+		return value, nil
+	}
+
+	switch value[0] {
+	case '"':
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("unterminated or invalid double quoted value: %s", value)
+		}
+		return unquoted, nil
+	case '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return "", fmt.Errorf("unterminated single quoted value: %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	}
+
+	// An unquoted value may be followed by a comment
+	if index := strings.Index(value, " #"); index > -1 {
+		value = strings.TrimSpace(value[:index])
+	}
+	return value, nil
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func writeEnvFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_parseEnvFile(t *testing.T) {
+	path := writeEnvFile(t, ".env", `# local configuration
+
+export DB_HOST=localhost
+DB_PORT = 5432
+GREETING="hello\nworld"
+RAW='a "quoted" $value'
+MODE=dev # a comment
+EMPTY=
+`)
+
+	got, err := parseEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"DB_HOST":  "localhost",
+		"DB_PORT":  "5432",
+		"GREETING": "hello\nworld",
+		"RAW":      `a "quoted" $value`,
+		"MODE":     "dev",
+		"EMPTY":    "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func Test_parseEnvFile_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"no equals":     "DB_HOST\n",
+		"no name":       "=value\n",
+		"unterminated":  "GREETING=\"hello\n",
+		"space in name": "DB HOST=localhost\n",
+	}
+	for name, content := range cases {
+		path := writeEnvFile(t, ".env", content)
+		if _, err := parseEnvFile(path); err == nil || !strings.Contains(err.Error(), path+":1:") {
+			t.Errorf("%s: want an error with the line number, got: %v", name, err)
+		}
+	}
+
+	if _, err := parseEnvFile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("want an error for a missing file")
+	}
+}
+
+func Test_planDockerRun_EnvFilePrecedence(t *testing.T) {
+	first := writeEnvFile(t, ".env", "level=env-file\nfirst=1\nshared=.env\n")
+	second := writeEnvFile(t, ".env.local", "shared=.env.local\n")
+
+	fnc := stack.Function{
+		Name:        "stronghash",
+		Image:       "stronghash:latest",
+		FProcess:    "./handler",
+		Environment: map[string]string{"level": "environment", "stack": "yes"},
+	}
+
+	var out bytes.Buffer
+	plan, err := planDockerRun(fnc, runOptions{
+		port:       8080,
+		envFiles:   []string{first, second},
+		extraEnv:   map[string]string{"first": "--env"},
+		explainEnv: true,
+		output:     &out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"level": "env-file", "stack": "yes", "first": "--env", "shared": ".env.local"}
+	for name, value := range want {
+		if plan.Env[name] != value {
+			t.Errorf("want %s=%s, got: %q", name, value, plan.Env[name])
+		}
+	}
+	if !strings.Contains(out.String(), "--env-file "+second) {
+		t.Errorf("want the env file explained as a source, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localRunGatewayFile records the gateway started by local-run --gateway and
+// its functions, so that faas-cli invoke can find it from the same folder
+const localRunGatewayFile = ".openfaas/local-gateway.json"
+
+type localRunGatewayState struct {
+	URL       string   `json:"url"`
+	Functions []string `json:"functions"`
+}
+
+// startLocalRunGateway serves /function/NAME and /async-function/NAME for
+// the functions, which are mapped to their ports, the returned func drains
+// the queue, stops the server and removes localRunGatewayFile
+func startLocalRunGateway(functions map[string]int, opts runOptions) (func(), error) {
+	discover := func() (map[string]int, error) { return functions, nil }
+
+	// The functions don't change while local-run is running, so the routes
+	// are kept rather than refreshed
+	gw := newLocalGateway(discover, nil, time.Hour)
+	gw.host = localRunHost(opts)
+	gw.queue.Logger = log.New(opts.err, "", log.LstdFlags)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.gatewayPort))
+	if err != nil {
+		gw.queue.Close()
+		return nil, fmt.Errorf("unable to start the gateway on port %d: %w", opts.gatewayPort, err)
+	}
+
+	server := &http.Server{Handler: gw, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(opts.err, "Gateway stopped: %s\n", err)
+		}
+	}()
+
+	gatewayURL := fmt.Sprintf("http://127.0.0.1:%d", opts.gatewayPort)
+	names := namesByPort(functions)
+	if err := writeLocalRunGateway(localRunGatewayState{URL: gatewayURL, Functions: names}); err != nil {
+		fmt.Fprintf(opts.err, "Unable to write %s, faas-cli invoke needs --gateway %s: %s\n", localRunGatewayFile, gatewayURL, err)
+	}
+
+	fmt.Fprintf(opts.output, "Gateway: %s\n", gatewayURL)
+	for _, name := range names {
+		fmt.Fprintf(opts.output, "  %s/function/%s\n  %s/async-function/%s\n", gatewayURL, name, gatewayURL, name)
+	}
+	fmt.Fprintf(opts.output, "Invoke a function through it with: faas-cli invoke %s\n\n", names[0])
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+		gw.queue.Close()
+		os.Remove(localRunGatewayFile)
+	}, nil
+}
+
+func writeLocalRunGateway(state localRunGatewayState) error {
+	if err := os.MkdirAll(filepath.Dir(localRunGatewayFile), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(localRunGatewayFile, data, 0600)
+}
+
+// localRunGatewayHealthy is replaced by tests, a gateway which was not
+// stopped cleanly leaves localRunGatewayFile behind
+var localRunGatewayHealthy = func(gatewayURL string) bool {
+	client := http.Client{Timeout: 500 * time.Millisecond}
+	res, err := client.Get(gatewayURL + "/healthz")
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode == http.StatusOK
+}
+
+// localRunGatewayFor returns the URL of the gateway started by local-run
+// --gateway when it serves the function
+func localRunGatewayFor(functionName string) (string, bool) {
+	data, err := os.ReadFile(localRunGatewayFile)
+	if err != nil {
+		return "", false
+	}
+
+	state := localRunGatewayState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return "", false
+	}
+
+	// Local functions have no namespace, so "NAME.NAMESPACE" is served as NAME
+	name := strings.SplitN(functionName, ".", 2)[0]
+	for _, fn := range state.Functions {
+		if fn == name {
+			return state.URL, localRunGatewayHealthy(state.URL)
+		}
+	}
+	return "", false
+}
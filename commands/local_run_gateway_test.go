@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_startLocalRunGateway(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(wd)
+
+	function := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello from " + r.URL.Path))
+	}))
+	defer function.Close()
+	u, _ := url.Parse(function.URL)
+	functionPort, _ := strconv.Atoi(u.Port())
+
+	// Find a free port for the gateway
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gatewayPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	var out bytes.Buffer
+	opts := runOptions{gateway: true, gatewayPort: gatewayPort, output: &out, err: &out}
+	stop, err := startLocalRunGateway(map[string]int{"orders": functionPort}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gatewayURL := "http://127.0.0.1:" + strconv.Itoa(gatewayPort)
+	res, err := http.Get(gatewayURL + "/function/orders.openfaas-fn/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "hello from /items" {
+		t.Errorf("want the function's response, got: %d %q", res.StatusCode, body)
+	}
+
+	if res, _ := http.Get(gatewayURL + "/function/payments"); res == nil || res.StatusCode != http.StatusNotFound {
+		t.Errorf("want a 404 for a function which isn't running")
+	}
+
+	if got, ok := localRunGatewayFor("orders"); !ok || got != gatewayURL {
+		t.Errorf("want invoke to find the gateway, got: %q, %v", got, ok)
+	}
+	if _, ok := localRunGatewayFor("payments"); ok {
+		t.Errorf("want invoke to use its own gateway for other functions")
+	}
+	if !strings.Contains(out.String(), gatewayURL+"/async-function/orders") {
+		t.Errorf("want the async URL printed, got: %s", out.String())
+	}
+
+	stop()
+	if _, err := os.Stat(localRunGatewayFile); !os.IsNotExist(err) {
+		t.Errorf("want %s removed when the gateway stops", localRunGatewayFile)
+	}
+}
+
+func Test_localRunGatewayFor_Stale(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	defer os.Chdir(wd)
+
+	healthy := localRunGatewayHealthy
+	defer func() { localRunGatewayHealthy = healthy }()
+	localRunGatewayHealthy = func(string) bool { return false }
+
+	if err := writeLocalRunGateway(localRunGatewayState{URL: "http://127.0.0.1:8080", Functions: []string{"orders"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := localRunGatewayFor("orders"); ok {
+		t.Errorf("want a gateway which doesn't respond to be ignored")
+	}
+}
+
+func Test_selectPorts_SkipsGatewayPort(t *testing.T) {
+	available := portAvailable
+	defer func() { portAvailable = available }()
+	portAvailable = func(int) bool { return true }
+
+	ports, err := selectPorts(2, runOptions{port: 8080, gateway: true, gatewayPort: 8081, output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ports[0] != 8080 || ports[1] != 8082 {
+		t.Errorf("want 8080 and 8082, got: %v", ports)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// imageFunctionName names the function run by --image when no NAME is given,
+// from the last part of the image's repository, e.g. fn for ghcr.io/org/fn:tag
+func imageFunctionName(image string) (string, error) {
+	name := image
+	if index := strings.Index(name, "@"); index > -1 {
+		name = name[:index]
+	}
+	if index := strings.LastIndex(name, "/"); index > -1 {
+		name = name[index+1:]
+	}
+	if index := strings.Index(name, ":"); index > -1 {
+		name = name[:index]
+	}
+
+	if len(name) == 0 {
+		return "", fmt.Errorf("unable to name the function from the image %q, give a NAME", image)
+	}
+	return name, nil
+}
+
+// imageServices stands in for the stack file with --image, the image's
+// fprocess and environment are kept as they were built
+func imageServices(name, image string, secrets []string) *stack.Services {
+	return &stack.Services{
+		Functions: map[string]stack.Function{
+			name: {Name: name, Image: image, Secrets: secrets},
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_imageFunctionName(t *testing.T) {
+	cases := map[string]string{
+		"ghcr.io/org/fn:tag":                   "fn",
+		"figlet":                               "figlet",
+		"localhost:5000/team/stronghash:0.1.0": "stronghash",
+		"ghcr.io/org/fn@sha256:abcdef":         "fn",
+	}
+	for image, want := range cases {
+		got, err := imageFunctionName(image)
+		if err != nil || got != want {
+			t.Errorf("%s: want %s, got: %s, %v", image, want, got, err)
+		}
+	}
+
+	if _, err := imageFunctionName("ghcr.io/org/"); err == nil {
+		t.Error("want an error when the image has no name")
+	}
+}
+
+func Test_runFunction_Image(t *testing.T) {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var out bytes.Buffer
+	opts := runOptions{
+		image:    "ghcr.io/org/fn:tag",
+		secrets:  []string{"api-key"},
+		extraEnv: map[string]string{"debug": "true"},
+		port:     18080,
+		print:    true,
+		runtime:  "docker",
+		output:   &out,
+		err:      &out,
+	}
+	if err := runFunction(context.Background(), "fn", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	command := out.String()
+	for _, want := range []string{"--name=" + localRunContainerName("fn"), "-e=debug=true", containerSecretsPath, "ghcr.io/org/fn:tag"} {
+		if !strings.Contains(command, want) {
+			t.Errorf("want %q in: %s", want, command)
+		}
+	}
+	if strings.Contains(command, "fprocess") {
+		t.Errorf("want the image's own fprocess kept, got: %s", command)
+	}
+}
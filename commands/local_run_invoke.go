@@ -0,0 +1,74 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// readInvokePayload reads the body of local-run --invoke, which is read from a
+// file when it starts with @, or from STDIN for @-. The content type is JSON
+// when the body is valid JSON, and text otherwise.
+func readInvokePayload(value string) ([]byte, string, error) {
+	body := []byte(value)
+	if strings.HasPrefix(value, "@") {
+		var err error
+		if value == "@-" {
+			body, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			body, err = ioutil.ReadFile(value[1:])
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to read the payload for --invoke: %s", err.Error())
+		}
+	}
+
+	if len(bytes.TrimSpace(body)) > 0 && json.Valid(body) {
+		return body, "application/json", nil
+	}
+	return body, "text/plain", nil
+}
+
+// localRunInvokeURL is the function's root path on the host, next to its
+// health endpoint
+func localRunInvokeURL(opts runOptions) string {
+	return strings.TrimSuffix(localRunHealthURL(opts), watchdogHealthPath) + "/"
+}
+
+// invokeLocalRun sends the payload of --invoke to the function, writing the
+// status and time of the response to opts.err and its body to opts.output. A
+// response of 400 or above is an error, so that a test in CI fails with it.
+func invokeLocalRun(ctx context.Context, name string, opts runOptions) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, localRunInvokeURL(opts), bytes.NewReader(opts.invokePayload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", opts.invokeContentType)
+
+	start := time.Now()
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to invoke %s: %s", name, err.Error())
+	}
+	defer res.Body.Close()
+
+	fmt.Fprintf(opts.err, "Invoked %s: %s in %s\n", name, res.Status, time.Since(start).Round(time.Millisecond))
+	if _, err := io.Copy(opts.output, res.Body); err != nil {
+		return fmt.Errorf("unable to read the response of %s: %s", name, err.Error())
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("invoking %s returned: %s", name, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_readInvokePayload(t *testing.T) {
+	body, contentType, err := readInvokePayload("hello")
+	if err != nil || string(body) != "hello" || contentType != "text/plain" {
+		t.Errorf("want the text sent as text/plain, got: %q, %q, %v", body, contentType, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "payload.json")
+	os.WriteFile(path, []byte(`{"url": "https://example.com"}`), 0644)
+
+	body, contentType, err = readInvokePayload("@" + path)
+	if err != nil || string(body) != `{"url": "https://example.com"}` || contentType != "application/json" {
+		t.Errorf("want the file sent as application/json, got: %q, %q, %v", body, contentType, err)
+	}
+
+	if _, _, err := readInvokePayload("@" + filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "--invoke") {
+		t.Errorf("want an error for a missing file, got: %v", err)
+	}
+}
+
+func Test_invokeLocalRun(t *testing.T) {
+	status := http.StatusOK
+	var received, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received, contentType = string(data), r.Header.Get("Content-Type")
+		w.WriteHeader(status)
+		w.Write([]byte("hashed: " + received))
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	p, _ := strconv.Atoi(port)
+
+	var out, errOut bytes.Buffer
+	opts := runOptions{port: p, invokePayload: []byte("data"), invokeContentType: "text/plain", output: &out, err: &errOut}
+
+	if err := invokeLocalRun(context.Background(), "stronghash", opts); err != nil {
+		t.Fatal(err)
+	}
+	if received != "data" || contentType != "text/plain" {
+		t.Errorf("want the payload sent as text/plain, got: %q, %q", received, contentType)
+	}
+	if out.String() != "hashed: data" {
+		t.Errorf("want the response's body printed, got: %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "Invoked stronghash: 200 OK") {
+		t.Errorf("want the response's status printed, got: %q", errOut.String())
+	}
+
+	status = http.StatusInternalServerError
+	if err := invokeLocalRun(context.Background(), "stronghash", opts); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("want an error for a 500 response, got: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-cli/flags"
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-provider/logs"
+)
+
+// localRunLogColors are given to the functions in turn, so that the lines of
+// each can be told apart
+var localRunLogColors = []output.Color{output.Blue, output.Green, output.Yellow, output.Bold}
+
+// localRunLogNow is replaced by tests
+var localRunLogNow = time.Now
+
+// localRunLogs formats the output of the containers run in the foreground,
+// line by line, as faas-cli logs does for a deployed function
+type localRunLogs struct {
+	mu      sync.Mutex
+	opts    runOptions
+	names   []string
+	writers []*prefixWriter
+}
+
+func newLocalRunLogs(opts runOptions) *localRunLogs {
+	return &localRunLogs{opts: opts}
+}
+
+// streams returns the writers for the stdout and stderr of a function
+func (l *localRunLogs) streams(name string) (io.Writer, io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	color := localRunLogColors[len(l.names)%len(localRunLogColors)]
+	l.names = append(l.names, name)
+
+	format := localRunLogLine(name, color, l.opts.logFormat, l.opts.logTimeFormat.String())
+	stdout := &prefixWriter{mu: &l.mu, out: l.opts.output, format: format}
+	stderr := &prefixWriter{mu: &l.mu, out: l.opts.err, format: format}
+	l.writers = append(l.writers, stdout, stderr)
+	return stdout, stderr
+}
+
+// flush writes the last line of each function, when it had no newline
+func (l *localRunLogs) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, w := range l.writers {
+		w.flush()
+	}
+}
+
+// note writes a line of faas-cli's own to stderr, between the lines of the
+// functions rather than within one
+func (l *localRunLogs) note(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintln(l.opts.err, line)
+}
+
+// localRunLogLine formats a line with the formatters of faas-cli logs, the
+// plain format is prefixed with the function's name in its colour
+func localRunLogLine(name string, color output.Color, format flags.LogFormat, timeFormat string) func(string) string {
+	return func(text string) string {
+		msg := logs.Message{
+			Name:      name,
+			Instance:  localRunContainerName(name),
+			Timestamp: localRunLogNow(),
+			Text:      text,
+		}
+
+		switch format {
+		case flags.JSONLogFormat:
+			return JSONFormatMessage(msg, timeFormat, true, true)
+		case flags.KeyValueLogFormat:
+			return KeyValueFormatMessage(msg, timeFormat, true, false)
+		}
+
+		prefix := color.Apply("["+name+"]") + " "
+		if timeFormat != "" {
+			prefix = msg.Timestamp.Format(timeFormat) + " " + prefix
+		}
+		return prefix + text
+	}
+}
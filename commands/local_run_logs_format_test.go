@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/flags"
+	"github.com/openfaas/faas-provider/logs"
+)
+
+func Test_localRunLogs(t *testing.T) {
+	now := localRunLogNow
+	defer func() { localRunLogNow = now }()
+	at := time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC)
+	localRunLogNow = func() time.Time { return at }
+
+	cases := []struct {
+		name       string
+		format     flags.LogFormat
+		timeFormat string
+		want       string
+	}{
+		{name: "plain", format: flags.PlainLogFormat, want: "[orders] Forking fprocess.\n[orders] partial\n"},
+		{name: "plain with time", format: flags.PlainLogFormat, timeFormat: time.RFC3339, want: "2023-06-01T12:30:00Z [orders] Forking fprocess.\n2023-06-01T12:30:00Z [orders] partial\n"},
+		{name: "keyvalue", format: flags.KeyValueLogFormat, want: "name=\"orders\" text=\"Forking fprocess.\" \nname=\"orders\" text=\"partial\" \n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			l := newLocalRunLogs(runOptions{logFormat: tc.format, logTimeFormat: flags.TimeFormat(tc.timeFormat), output: &out, err: &out})
+			stdout, _ := l.streams("orders")
+
+			fmt.Fprint(stdout, "Forking fprocess.\r\npar")
+			fmt.Fprint(stdout, "tial")
+			l.flush()
+
+			if got := out.String(); got != tc.want {
+				t.Errorf("want:\n%q\ngot:\n%q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_localRunLogs_JSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	l := newLocalRunLogs(runOptions{logFormat: flags.JSONLogFormat, output: &stdout, err: &stderr})
+	_, ordersErr := l.streams("orders")
+	paymentsOut, _ := l.streams("payments")
+
+	fmt.Fprintln(ordersErr, "exit status 1")
+	fmt.Fprintln(paymentsOut, "listening")
+
+	msg := logs.Message{}
+	if err := json.Unmarshal(stderr.Bytes(), &msg); err != nil {
+		t.Fatalf("want a JSON line on stderr, got: %q, %s", stderr.String(), err)
+	}
+	if msg.Name != "orders" || msg.Instance != localRunContainerName("orders") || msg.Text != "exit status 1" || msg.Timestamp.IsZero() {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &msg); err != nil || msg.Name != "payments" {
+		t.Errorf("want the other function's line on stdout, got: %q", stdout.String())
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// localRunLabel marks containers started by local-run
+	localRunLabel = "com.openfaas.local-run"
+	// localRunFunctionLabel records the function's name on its container
+	localRunFunctionLabel = "com.openfaas.function"
+	// localRunPortLabel records the port the function is published on
+	localRunPortLabel = "com.openfaas.local-run.port"
+	// localRunStackLabel records the absolute path of the stack file
+	localRunStackLabel = "com.openfaas.local-run.stack"
+	// localRunSessionLabel records the host and PID of the faas-cli which runs
+	// the container in the foreground, so that prune can tell it has exited
+	localRunSessionLabel = "com.openfaas.local-run.session"
+)
+
+// localRunContainerName is the name of the container for a function, so that
+// only one copy of each function runs at a time, and a container left by a
+// session which crashed can be found by "local-run prune"
+func localRunContainerName(name string) string {
+	return "openfaas-local-" + name
+}
+
+func checkLocalRunExperimental() error {
+	if v, ok := os.LookupEnv("OPENFAAS_EXPERIMENTAL"); !ok || v == "0" {
+		return fmt.Errorf("this command is experimental, set OPENFAAS_EXPERIMENTAL=1 to use it")
+	}
+	return nil
+}
+
+func newLocalRunPsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     `ps`,
+		Aliases: []string{"list"},
+		Short:   "List functions started in the background by local-run",
+		Example: `  faas-cli local-run ps
+  faas-cli local-run list`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkLocalRunExperimental(); err != nil {
+				return err
+			}
+			return validateContainerRuntime()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDocker(cmd, localRunPsArgs())
+		},
+	}
+}
+
+func newLocalRunStopCmd() *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   `stop NAME [NAME...] | --all`,
+		Short: "Stop functions started in the background by local-run",
+		Long: `Stop functions started in the background by local-run. With --all, the
+containers of the stack's x-local-services are stopped too.`,
+		Example: `  faas-cli local-run stop stronghash
+  faas-cli local-run stop --all`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkLocalRunExperimental(); err != nil {
+				return err
+			}
+
+			if all == (len(args) > 0) {
+				return fmt.Errorf("give the names of the functions to stop, or --all")
+			}
+			return validateContainerRuntime()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names := args
+			var services []string
+			if all {
+				var err error
+				if names, err = localRunFunctions(); err != nil {
+					return err
+				}
+				if services, err = runningLocalServices(); err != nil {
+					return err
+				}
+
+				if len(names) == 0 && len(services) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "No functions are running")
+					return nil
+				}
+			}
+
+			return runDocker(cmd, append(localRunStopArgs(names), services...))
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "stop all functions started by local-run")
+
+	return cmd
+}
+
+func newLocalRunLogsCmd() *cobra.Command {
+	var (
+		follow bool
+		tail   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   `logs NAME`,
+		Short: "Show the logs of a function started in the background by local-run",
+		Example: `  faas-cli local-run logs stronghash
+  faas-cli local-run logs stronghash --follow --tail 20`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkLocalRunExperimental(); err != nil {
+				return err
+			}
+
+			if len(args) != 1 {
+				return fmt.Errorf("give the name of one function")
+			}
+			return validateContainerRuntime()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDocker(cmd, localRunLogsArgs(args[0], follow, tail))
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "follow the logs as they are written")
+	cmd.Flags().IntVar(&tail, "tail", -1, "number of lines to show from the end of the logs, -1 for all")
+
+	return cmd
+}
+
+func localRunPsArgs() []string {
+	return []string{"ps",
+		"--filter", fmt.Sprintf("label=%s=true", localRunLabel),
+		"--format", fmt.Sprintf(`table {{.Label "%s"}}\t{{.Status}}\t{{.Ports}}\t{{.Image}}`, localRunFunctionLabel),
+	}
+}
+
+func localRunStopArgs(names []string) []string {
+	args := []string{"stop"}
+	for _, name := range names {
+		args = append(args, localRunContainerName(name))
+	}
+	return args
+}
+
+func localRunLogsArgs(name string, follow bool, tail int) []string {
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	if tail >= 0 {
+		args = append(args, "--tail", strconv.Itoa(tail))
+	}
+	return append(args, localRunContainerName(name))
+}
+
+// localRunFunctions lists the names of the functions which are running
+func localRunFunctions() ([]string, error) {
+	out, err := exec.Command(containerRuntime(), "ps",
+		"--filter", fmt.Sprintf("label=%s=true", localRunLabel),
+		"--format", fmt.Sprintf(`{{.Label "%s"}}`, localRunFunctionLabel)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list local-run containers: %w", err)
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+func runDocker(cmd *cobra.Command, args []string) error {
+	docker := exec.CommandContext(cmd.Context(), containerRuntime(), args...)
+	docker.Stdout = cmd.OutOrStdout()
+	docker.Stderr = cmd.ErrOrStderr()
+	return docker.Run()
+}
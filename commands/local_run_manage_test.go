@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_buildDockerRun_NameAndLabels(t *testing.T) {
+	fnc := stack.Function{Name: "stronghash", Image: "stronghash:latest", Language: "dockerfile", FProcess: "./handler"}
+
+	cmd, err := buildDockerRun(context.Background(), fnc, runOptions{port: 8080, detach: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := strings.Join(cmd.Args, " ")
+	for _, want := range []string{
+		"--name=openfaas-local-stronghash",
+		"--label=com.openfaas.local-run=true",
+		"--label=com.openfaas.function=stronghash",
+		"--detach",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("want %q in: %s", want, args)
+		}
+	}
+}
+
+func Test_localRunStopArgs(t *testing.T) {
+	got := localRunStopArgs([]string{"a", "b"})
+	want := []string{"stop", "openfaas-local-a", "openfaas-local-b"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func Test_localRunLogsArgs(t *testing.T) {
+	cases := []struct {
+		follow bool
+		tail   int
+		want   []string
+	}{
+		{tail: -1, want: []string{"logs", "openfaas-local-fn"}},
+		{follow: true, tail: 20, want: []string{"logs", "--follow", "--tail", "20", "openfaas-local-fn"}},
+	}
+
+	for _, tc := range cases {
+		if got := localRunLogsArgs("fn", tc.follow, tc.tail); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("want: %v, got: %v", tc.want, got)
+		}
+	}
+}
+
+func Test_localRunPsCmd_ListAlias(t *testing.T) {
+	cmd := newLocalRunCmd()
+
+	found, _, err := cmd.Find([]string{"list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name() != "ps" {
+		t.Fatalf("want list to run ps, got: %s", found.Name())
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+const (
+	printFormatShell = "shell"
+	printFormatJSON  = "json"
+)
+
+// localRunPlan is the container which local-run starts, and is printed by
+// --print-format json for wrapper tools and editors to read or modify
+type localRunPlan struct {
+	Runtime string `json:"runtime"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	// Command replaces the image's command, for the stack's x-local-services
+	Command []string          `json:"command,omitempty"`
+	Env     map[string]string `json:"env"`
+	Mounts  []localRunMount   `json:"mounts,omitempty"`
+	// Copies are copied into the container before it starts, in place of
+	// mounts, as a remote daemon can't see the files of this machine
+	Copies   []localRunMount   `json:"copies,omitempty"`
+	Ports    []localRunPort    `json:"ports"`
+	Limits   *localRunLimits   `json:"limits,omitempty"`
+	GPUs     string            `json:"gpus,omitempty"`
+	ShmSize  string            `json:"shm_size,omitempty"`
+	Ulimits  []string          `json:"ulimits,omitempty"`
+	Tmpfs    []string          `json:"tmpfs,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Network  string            `json:"network,omitempty"`
+	Aliases  []string          `json:"aliases,omitempty"`
+	Workdir  string            `json:"workdir,omitempty"`
+	ReadOnly bool              `json:"read_only"`
+	// User, capabilities and security options mirror the securityContext
+	// which the function has in the cluster
+	User         string   `json:"user,omitempty"`
+	Privileged   bool     `json:"privileged,omitempty"`
+	CapDrop      []string `json:"cap_drop,omitempty"`
+	CapAdd       []string `json:"cap_add,omitempty"`
+	SecurityOpts []string `json:"security_opts,omitempty"`
+	Detach       bool     `json:"detach"`
+}
+
+type localRunMount struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+type localRunPort struct {
+	Host      int `json:"host"`
+	Container int `json:"container"`
+}
+
+// localRunLimits are applied as soft limits, so that a function which is
+// being debugged is not killed for exceeding its memory. The CPU request is
+// a relative weight, as Kubernetes sets it.
+type localRunLimits struct {
+	MemoryReservation string `json:"memory_reservation,omitempty"`
+	CPUs              string `json:"cpus,omitempty"`
+	CPUShares         int    `json:"cpu_shares,omitempty"`
+}
+
+func (p *localRunPlan) writeJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// command runs the container, or with Copies, starts the container which
+// stageLocalRun has created
+func (p *localRunPlan) command(ctx context.Context) *exec.Cmd {
+	if len(p.Copies) > 0 {
+		return exec.CommandContext(ctx, p.runtime(), p.startArgs()...)
+	}
+	return exec.CommandContext(ctx, p.runtime(), runtimeRunArgs(p.runtime(), p.args())...)
+}
+
+// shellCommands are the commands printed by --print, which create the
+// container and copy its Copies into it first, when it has any
+func (p *localRunPlan) shellCommands(ctx context.Context) []string {
+	var commands []string
+	if len(p.Copies) > 0 {
+		commands = append(commands, exec.CommandContext(ctx, p.runtime(), runtimeRunArgs(p.runtime(), p.createArgs())...).String())
+		for _, staged := range p.Copies {
+			commands = append(commands, exec.CommandContext(ctx, p.runtime(), "cp", staged.Source, p.Name+":"+staged.Target).String())
+		}
+	}
+	return append(commands, p.command(ctx).String())
+}
+
+func (p *localRunPlan) runtime() string {
+	if p.Runtime == "" {
+		return runtimeDocker
+	}
+	return p.Runtime
+}
+
+// args renders the plan as the arguments of docker run, the labels and
+// environment are sorted so that --print is the same on each run
+func (p *localRunPlan) args() []string {
+	args := []string{"run", "--rm", "-i"}
+	for _, port := range p.Ports {
+		args = append(args, fmt.Sprintf("-p=%d:%d", port.Host, port.Container))
+	}
+
+	args = append(args, fmt.Sprintf("--name=%s", p.Name))
+	for _, name := range sortedKeys(p.Labels) {
+		args = append(args, fmt.Sprintf("--label=%s=%s", name, p.Labels[name]))
+	}
+
+	if p.Detach {
+		args = append(args, "--detach")
+	}
+	if p.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", p.Network))
+	}
+	for _, alias := range p.Aliases {
+		args = append(args, fmt.Sprintf("--network-alias=%s", alias))
+	}
+	if p.Workdir != "" {
+		args = append(args, fmt.Sprintf("--workdir=%s", p.Workdir))
+	}
+
+	for _, mount := range p.Mounts {
+		volume := fmt.Sprintf("--volume=%s:%s", mount.Source, mount.Target)
+		if mount.ReadOnly {
+			volume += ":ro"
+		}
+		args = append(args, volume)
+	}
+
+	for _, name := range sortedKeys(p.Env) {
+		args = append(args, fmt.Sprintf("-e=%s=%s", name, p.Env[name]))
+	}
+
+	if p.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	if p.User != "" {
+		args = append(args, fmt.Sprintf("--user=%s", p.User))
+	}
+	if p.Privileged {
+		args = append(args, "--privileged")
+	}
+	for _, capability := range p.CapDrop {
+		args = append(args, fmt.Sprintf("--cap-drop=%s", capability))
+	}
+	for _, capability := range p.CapAdd {
+		args = append(args, fmt.Sprintf("--cap-add=%s", capability))
+	}
+	for _, option := range p.SecurityOpts {
+		args = append(args, fmt.Sprintf("--security-opt=%s", option))
+	}
+
+	if p.Limits != nil {
+		if p.Limits.MemoryReservation != "" {
+			args = append(args, fmt.Sprintf("--memory-reservation=%s", p.Limits.MemoryReservation))
+		}
+		if p.Limits.CPUs != "" {
+			args = append(args, fmt.Sprintf("--cpus=%s", p.Limits.CPUs))
+		}
+		if p.Limits.CPUShares > 0 {
+			args = append(args, fmt.Sprintf("--cpu-shares=%d", p.Limits.CPUShares))
+		}
+	}
+	if p.ShmSize != "" {
+		args = append(args, fmt.Sprintf("--shm-size=%s", p.ShmSize))
+	}
+	for _, ulimit := range p.Ulimits {
+		args = append(args, fmt.Sprintf("--ulimit=%s", ulimit))
+	}
+	for _, tmpfs := range p.Tmpfs {
+		args = append(args, fmt.Sprintf("--tmpfs=%s", tmpfs))
+	}
+	if p.GPUs != "" {
+		args = append(args, fmt.Sprintf("--gpus=%s", p.GPUs))
+	}
+
+	return append(append(args, p.Image), p.Command...)
+}
+
+// createArgs renders the plan as the arguments of docker create, for a
+// container which is started by startArgs once its Copies are copied in
+func (p *localRunPlan) createArgs() []string {
+	args := []string{"create"}
+	for _, arg := range p.args()[1:] {
+		if arg != "--detach" {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// startArgs start the created container, attached to it unless detached
+func (p *localRunPlan) startArgs() []string {
+	if p.Detach {
+		return []string{"start", p.Name}
+	}
+	return []string{"start", "--attach", "--interactive", p.Name}
+}
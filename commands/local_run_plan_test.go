@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_planDockerRun_JSON(t *testing.T) {
+	fnc := stack.Function{
+		Name:        "stronghash",
+		Image:       "stronghash:latest",
+		Language:    "dockerfile",
+		FProcess:    "./handler",
+		Environment: map[string]string{"mode": "debug", "write_debug": "false"},
+		Limits:      &stack.FunctionResources{Memory: "128Mi"},
+	}
+	opts := runOptions{
+		port:     8081,
+		extraEnv: map[string]string{"write_debug": "true"},
+	}
+
+	plan, err := planDockerRun(fnc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := plan.writeJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Image  string            `json:"image"`
+		Env    map[string]string `json:"env"`
+		Ports  []localRunPort    `json:"ports"`
+		Limits map[string]string `json:"limits"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Image != "stronghash:latest" {
+		t.Errorf("want the image, got: %q", got.Image)
+	}
+	if got.Env["write_debug"] != "true" || got.Env["mode"] != "debug" || got.Env["fprocess"] != "./handler" {
+		t.Errorf("want --env to override the stack file, and the fprocess, got: %v", got.Env)
+	}
+	if len(got.Ports) != 1 || got.Ports[0] != (localRunPort{Host: 8081, Container: 8080}) {
+		t.Errorf("want the port, got: %v", got.Ports)
+	}
+	if got.Limits["memory_reservation"] != "128Mi" {
+		t.Errorf("want the memory limit, got: %v", got.Limits)
+	}
+}
+
+func Test_localRunPlan_Args(t *testing.T) {
+	plan := localRunPlan{
+		Name:   "faas-cli-local-run-env",
+		Image:  "env:latest",
+		Env:    map[string]string{"b": "2", "a": "1"},
+		Ports:  []localRunPort{{Host: 8080, Container: 8080}},
+		Labels: map[string]string{"com.openfaas.local-run": "true"},
+		Mounts: []localRunMount{
+			{Source: "/src/env", Target: "/home/app/function", ReadOnly: true},
+			{Source: "/src/.secrets", Target: "/var/openfaas/secrets"},
+		},
+		Limits: &localRunLimits{CPUs: "0.5"},
+	}
+
+	got := strings.Join(plan.args(), " ")
+	want := "run --rm -i -p=8080:8080 --name=faas-cli-local-run-env --label=com.openfaas.local-run=true " +
+		"--volume=/src/env:/home/app/function:ro --volume=/src/.secrets:/var/openfaas/secrets " +
+		"-e=a=1 -e=b=2 --cpus=0.5 env:latest"
+	if got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/openfaas/faas-cli/output"
+)
+
+// localRunPortSearch is how many ports after --port are tried, when no
+// --port-range is given
+const localRunPortSearch = 100
+
+// portAvailable reports whether nothing is listening on the port, and is
+// replaced by tests
+var portAvailable = func(port int) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// portRange is an inclusive range of ports, such as 8080-8090
+type portRange struct {
+	first int
+	last  int
+}
+
+func parsePortRange(value string) (portRange, error) {
+	first, last, ok := strings.Cut(value, "-")
+	if !ok {
+		return portRange{}, fmt.Errorf("--port-range must be two ports such as 8080-8090, got: %q", value)
+	}
+
+	r := portRange{}
+	var err error
+	if r.first, err = strconv.Atoi(strings.TrimSpace(first)); err != nil {
+		return portRange{}, fmt.Errorf("--port-range must be two ports such as 8080-8090, got: %q", value)
+	}
+	if r.last, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+		return portRange{}, fmt.Errorf("--port-range must be two ports such as 8080-8090, got: %q", value)
+	}
+
+	if r.first < 1 || r.last > 65535 || r.first > r.last {
+		return portRange{}, fmt.Errorf("--port-range must be from a lower to a higher port between 1 and 65535, got: %q", value)
+	}
+	return r, nil
+}
+
+// selectPorts picks a free port for each of count functions, in order from
+// --port, skipping those which are in use. With --port-range, only ports in
+// the range are used, starting from --port when it is within the range. With
+// --print the ports are not checked, so that the same command is printed on
+// each run. Nor are they when the daemon is remote, as a port in use on this
+// machine may be free on the daemon's, and the other way round. The port of
+// --gateway is never picked.
+func selectPorts(count int, opts runOptions) ([]int, error) {
+	r := portRange{first: opts.port, last: opts.port + localRunPortSearch}
+	if r.last > 65535 {
+		r.last = 65535
+	}
+	if opts.portRange != "" {
+		var err error
+		if r, err = parsePortRange(opts.portRange); err != nil {
+			return nil, err
+		}
+		if opts.port >= r.first && opts.port <= r.last {
+			r.first = opts.port
+		}
+	}
+
+	check := !opts.print && len(opts.remoteHost) == 0
+
+	var ports []int
+	for port := r.first; port <= r.last && len(ports) < count; port++ {
+		if opts.gateway && port == opts.gatewayPort {
+			continue
+		}
+		if !check || portAvailable(port) {
+			ports = append(ports, port)
+			continue
+		}
+		fmt.Fprintf(opts.output, "Port %d is in use, so it was skipped\n", port)
+	}
+
+	if len(ports) < count {
+		return nil, fmt.Errorf("only %d of the %d ports needed are free from %d to %d, give another --port or --port-range", len(ports), count, r.first, r.last)
+	}
+
+	if !opts.print && len(opts.remoteHost) > 0 {
+		fmt.Fprintln(opts.err, output.Warning(fmt.Sprintf("Warning: the container runtime's daemon is on %s, so ports %s can't be checked, give another --port or --port-range if they are in use there",
+			opts.remoteHost, joinPorts(ports))))
+	}
+	return ports, nil
+}
+
+func joinPorts(ports []int) string {
+	values := make([]string, len(ports))
+	for i, port := range ports {
+		values[i] = strconv.Itoa(port)
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parsePortRange(t *testing.T) {
+	r, err := parsePortRange("9000-9010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.first != 9000 || r.last != 9010 {
+		t.Fatalf("want 9000-9010, got: %d-%d", r.first, r.last)
+	}
+
+	for _, value := range []string{"9000", "a-b", "9010-9000", "0-10", "65535-70000"} {
+		if _, err := parsePortRange(value); err == nil {
+			t.Errorf("want an error for %q", value)
+		}
+	}
+}
+
+func stubPortsInUse(t *testing.T, inUse ...int) {
+	busy := map[int]bool{}
+	for _, port := range inUse {
+		busy[port] = true
+	}
+
+	original := portAvailable
+	portAvailable = func(port int) bool { return !busy[port] }
+	t.Cleanup(func() { portAvailable = original })
+}
+
+func Test_selectPorts_SkipsPortsInUse(t *testing.T) {
+	stubPortsInUse(t, 8080, 8082)
+
+	var out bytes.Buffer
+	ports, err := selectPorts(3, runOptions{port: 8080, output: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{8081, 8083, 8084}; !reflect.DeepEqual(ports, want) {
+		t.Fatalf("want ports: %v, got: %v", want, ports)
+	}
+	if want := "Port 8080 is in use, so it was skipped\nPort 8082 is in use, so it was skipped\n"; out.String() != want {
+		t.Fatalf("want output:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func Test_selectPorts_PortRange(t *testing.T) {
+	stubPortsInUse(t, 9000)
+
+	ports, err := selectPorts(2, runOptions{port: 8080, portRange: "9000-9002", output: &bytes.Buffer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{9001, 9002}; !reflect.DeepEqual(ports, want) {
+		t.Fatalf("want ports: %v, got: %v", want, ports)
+	}
+
+	_, err = selectPorts(3, runOptions{port: 8080, portRange: "9000-9002", output: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "only 2 of the 3 ports needed are free from 9000 to 9002") {
+		t.Fatalf("want an error as the range is too small, got: %v", err)
+	}
+}
+
+func Test_selectPorts_PrintDoesNotCheck(t *testing.T) {
+	stubPortsInUse(t, 8080)
+
+	ports, err := selectPorts(2, runOptions{port: 8080, print: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{8080, 8081}; !reflect.DeepEqual(ports, want) {
+		t.Fatalf("want ports: %v, got: %v", want, ports)
+	}
+}
+
+func Test_selectPorts_RemoteDaemonDoesNotCheck(t *testing.T) {
+	stubPortsInUse(t, 8080)
+
+	var errOut bytes.Buffer
+	ports, err := selectPorts(2, runOptions{port: 8080, remoteHost: "build-box", output: &bytes.Buffer{}, err: &errOut})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{8080, 8081}; !reflect.DeepEqual(ports, want) {
+		t.Fatalf("want ports: %v, got: %v", want, ports)
+	}
+	if !strings.Contains(errOut.String(), "daemon is on build-box, so ports 8080, 8081 can't be checked") {
+		t.Fatalf("want a warning that the ports on the daemon's machine aren't checked, got: %q", errOut.String())
+	}
+}
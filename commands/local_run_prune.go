@@ -0,0 +1,235 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// localRunContainer is a container started by local-run, running or not
+type localRunContainer struct {
+	Name  string
+	State string
+	// Session is the host and PID of the faas-cli which runs it in the
+	// foreground, empty when it was started with --detach
+	Session string
+}
+
+// listLocalRunContainers lists the containers which have all of the labels,
+// given as KEY or KEY=VALUE, and is replaced by tests
+var listLocalRunContainers = func(labels []string) ([]localRunContainer, error) {
+	args := []string{"ps", "--all", "--format", fmt.Sprintf(`{{.Names}}\t{{.State}}\t{{.Label "%s"}}`, localRunSessionLabel)}
+	for _, label := range labels {
+		args = append(args, "--filter", "label="+label)
+	}
+
+	out, err := exec.Command(containerRuntime(), args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list local-run containers: %w", err)
+	}
+	return parseLocalRunContainers(string(out)), nil
+}
+
+// removeLocalRunContainers force-removes the containers, and is replaced by
+// tests
+var removeLocalRunContainers = func(names []string) error {
+	out, err := exec.Command(containerRuntime(), append([]string{"rm", "--force"}, names...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to remove the containers: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// pruneLocalRunNetworks removes the networks created by local-run which no
+// container uses, and is replaced by tests
+var pruneLocalRunNetworks = func() error {
+	out, err := exec.Command(containerRuntime(), "network", "prune", "--force",
+		"--filter", fmt.Sprintf("label=%s=true", localRunLabel)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to remove the local-run networks: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// localRunSessionAlive reports whether the faas-cli of a session is still
+// running, and is replaced by tests
+var localRunSessionAlive = func(session string) bool {
+	host, value, ok := strings.Cut(session, ":")
+	pid, err := strconv.Atoi(value)
+	if !ok || err != nil {
+		return true
+	}
+
+	// A session of another machine, when the runtime's daemon is shared,
+	// can't be checked so is left to --all
+	if hostname, _ := os.Hostname(); host != hostname {
+		return true
+	}
+	return processRunning(pid)
+}
+
+func newLocalRunPruneCmd() *cobra.Command {
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:   `prune [--all]`,
+		Short: "Remove the containers left by local-run sessions which have exited",
+		Long: `Remove the containers which local-run could not clean up, such as when
+faas-cli was killed, so that the functions can be started again under their
+names, openfaas-local-NAME. Containers which have stopped are removed, as are
+those still running for a faas-cli which has exited, along with any of the
+stack's x-local-services. The networks created by local-run which no container
+uses are removed too.
+
+The functions started with --detach, and by sessions which are still running,
+are kept unless --all is given. With --yaml, only the containers of that stack
+file are removed.`,
+		Example: `  faas-cli local-run prune
+  faas-cli local-run prune --all
+  faas-cli local-run prune -f stack.yml`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkLocalRunExperimental(); err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				return fmt.Errorf("prune takes no function names, use \"local-run stop\" to stop a function")
+			}
+			return validateContainerRuntime()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var stackLabel []string
+			if cmd.Flags().Changed("yaml") {
+				stackLabel = []string{localRunStackLabel + "=" + localRunStackFile(yamlFile)}
+			}
+			return runLocalRunPrune(cmd, stackLabel, all)
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "remove the containers which are running too, such as functions started with --detach")
+
+	return cmd
+}
+
+func runLocalRunPrune(cmd *cobra.Command, stackLabel []string, all bool) error {
+	var containers []localRunContainer
+	// The services are not labelled as functions, so are listed by their own
+	for _, label := range []string{localRunLabel + "=true", localRunServiceLabel} {
+		listed, err := listLocalRunContainers(append([]string{label}, stackLabel...))
+		if err != nil {
+			return err
+		}
+		containers = append(containers, listed...)
+	}
+
+	var names, removed []string
+	kept := 0
+	for _, container := range containers {
+		reason, ok := pruneReason(container, all)
+		if !ok {
+			kept++
+			continue
+		}
+		names = append(names, container.Name)
+		removed = append(removed, fmt.Sprintf("Removed %s, %s", container.Name, reason))
+	}
+
+	out := cmd.OutOrStdout()
+	if len(names) > 0 {
+		if err := removeLocalRunContainers(names); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, strings.Join(removed, "\n"))
+	} else {
+		fmt.Fprintln(out, "No containers are left by local-run")
+	}
+	if kept > 0 {
+		fmt.Fprintf(out, "Kept %d running, remove them with --all\n", kept)
+	}
+
+	return pruneLocalRunNetworks()
+}
+
+// pruneReason says why a container is removed, those which are running are
+// only removed when their session has exited, or with all
+func pruneReason(container localRunContainer, all bool) (string, bool) {
+	switch {
+	case container.State != "running" && container.State != "paused" && container.State != "restarting":
+		return "it is " + container.State, true
+	case len(container.Session) > 0 && !localRunSessionAlive(container.Session):
+		return "the faas-cli which ran it has exited", true
+	case all:
+		return "it was " + container.State, true
+	}
+	return "", false
+}
+
+func parseLocalRunContainers(out string) []localRunContainer {
+	var containers []localRunContainer
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "\t", 3)
+		if len(fields[0]) == 0 {
+			continue
+		}
+		for len(fields) < 3 {
+			fields = append(fields, "")
+		}
+		containers = append(containers, localRunContainer{Name: fields[0], State: strings.ToLower(fields[1]), Session: fields[2]})
+	}
+	return containers
+}
+
+// addLocalRunLabels records the stack file and session of opts on a
+// container, so that prune can find the containers which are left behind
+func addLocalRunLabels(labels map[string]string, opts runOptions) {
+	if len(opts.stackFile) > 0 {
+		labels[localRunStackLabel] = opts.stackFile
+	}
+	if len(opts.session) > 0 {
+		labels[localRunSessionLabel] = opts.session
+	}
+}
+
+// localRunStackFile is the absolute path of the stack file, so that the same
+// stack is matched from any folder, a URL is kept as it is
+func localRunStackFile(path string) string {
+	if len(path) == 0 || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// localRunSession is the host and PID of this faas-cli, such as laptop:4021
+func localRunSession() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer process.Release()
+
+	// FindProcess opens the process on Windows, so it fails once it exits
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+func Test_planDockerRun_StackAndSessionLabels(t *testing.T) {
+	fnc := stack.Function{Name: "orders", Image: "orders:latest", FProcess: "./handler"}
+
+	plan, err := planDockerRun(fnc, runOptions{port: 8080, stackFile: "/src/shop/stack.yml", session: "laptop:4021"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Name != "openfaas-local-orders" {
+		t.Errorf("want the name openfaas-local-orders, got: %s", plan.Name)
+	}
+	if plan.Labels[localRunStackLabel] != "/src/shop/stack.yml" || plan.Labels[localRunSessionLabel] != "laptop:4021" {
+		t.Errorf("want the stack file and session labelled, got: %v", plan.Labels)
+	}
+
+	// --print and --detach have no session, so the command is the same each time
+	plan, _ = planDockerRun(fnc, runOptions{port: 8080})
+	if _, ok := plan.Labels[localRunSessionLabel]; ok {
+		t.Errorf("want no session label, got: %v", plan.Labels)
+	}
+}
+
+func Test_parseLocalRunContainers(t *testing.T) {
+	out := "openfaas-local-orders\trunning\tlaptop:4021\nopenfaas-local-svc-redis\tExited\t\n\n"
+
+	want := []localRunContainer{
+		{Name: "openfaas-local-orders", State: "running", Session: "laptop:4021"},
+		{Name: "openfaas-local-svc-redis", State: "exited"},
+	}
+	if got := parseLocalRunContainers(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+}
+
+func Test_runLocalRunPrune(t *testing.T) {
+	list, alive, remove, prune := listLocalRunContainers, localRunSessionAlive, removeLocalRunContainers, pruneLocalRunNetworks
+	defer func() {
+		listLocalRunContainers, localRunSessionAlive = list, alive
+		removeLocalRunContainers, pruneLocalRunNetworks = remove, prune
+	}()
+
+	var listed [][]string
+	listLocalRunContainers = func(labels []string) ([]localRunContainer, error) {
+		listed = append(listed, labels)
+		if labels[0] == localRunServiceLabel {
+			return []localRunContainer{{Name: "openfaas-local-svc-redis", State: "running", Session: "laptop:1"}}, nil
+		}
+		return []localRunContainer{
+			{Name: "openfaas-local-orders", State: "running", Session: "laptop:1"},
+			{Name: "openfaas-local-figlet", State: "exited"},
+			{Name: "openfaas-local-stronghash", State: "running"},
+			{Name: "openfaas-local-resize", State: "running", Session: "laptop:2"},
+		}, nil
+	}
+	localRunSessionAlive = func(session string) bool { return session == "laptop:2" }
+	var removed []string
+	removeLocalRunContainers = func(names []string) error {
+		removed = names
+		return nil
+	}
+	networksPruned := false
+	pruneLocalRunNetworks = func() error {
+		networksPruned = true
+		return nil
+	}
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	if err := runLocalRunPrune(cmd, []string{localRunStackLabel + "=/src/shop/stack.yml"}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"openfaas-local-orders", "openfaas-local-figlet", "openfaas-local-svc-redis"}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("want removed: %v, got: %v", want, removed)
+	}
+	if !networksPruned {
+		t.Errorf("want the unused networks pruned")
+	}
+	if len(listed) != 2 || listed[0][1] != localRunStackLabel+"=/src/shop/stack.yml" {
+		t.Errorf("want the functions and services of the stack listed, got: %v", listed)
+	}
+	for _, line := range []string{
+		"Removed openfaas-local-orders, the faas-cli which ran it has exited",
+		"Removed openfaas-local-figlet, it is exited",
+		"Kept 2 running, remove them with --all",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("want %q in:\n%s", line, out.String())
+		}
+	}
+
+	if err := runLocalRunPrune(cmd, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 5 {
+		t.Errorf("want every container removed with --all, got: %v", removed)
+	}
+}
+
+func Test_localRunSession(t *testing.T) {
+	session := localRunSession()
+	if !strings.HasSuffix(session, ":"+strconv.Itoa(os.Getpid())) {
+		t.Errorf("want the PID in the session, got: %s", session)
+	}
+	if !processRunning(os.Getpid()) {
+		t.Errorf("want this process to be running")
+	}
+}
+
+func Test_localRunPruneCmd_NoNames(t *testing.T) {
+	t.Setenv("OPENFAAS_EXPERIMENTAL", "1")
+
+	cmd := newLocalRunPruneCmd()
+	if err := cmd.PreRunE(cmd, []string{"orders"}); err == nil || !strings.Contains(err.Error(), "local-run stop") {
+		t.Fatalf("want function names rejected, got: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localRunReadyInterval is how often the watchdog is checked, and is
+// shortened by tests
+var localRunReadyInterval = 250 * time.Millisecond
+
+// localRunContainerRunning reports whether a container started with
+// --detach is still running, and is replaced by tests
+var localRunContainerRunning = func(name string) bool {
+	out, err := exec.Command(containerRuntime(), "inspect", "--format", "{{.State.Running}}", localRunContainerName(name)).Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// openBrowser opens the URL with the desktop's default browser, and is
+// replaced by tests
+var openBrowser = func(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	}
+	return exec.Command("xdg-open", url).Start()
+}
+
+// errExitedBeforeReady is returned when the container stops before its
+// watchdog responds
+var errExitedBeforeReady = errors.New("exited before it was ready")
+
+// localRunHealthURL is the watchdog's health endpoint on the host. With
+// --network host the watchdog listens on its own port, which is 8080 unless
+// it was changed with -e port=.
+func localRunHealthURL(opts runOptions) string {
+	port := opts.port
+	if opts.network == "host" {
+		port = 8080
+		if value, ok := opts.extraEnv["port"]; ok {
+			if p, err := strconv.Atoi(value); err == nil {
+				port = p
+			}
+		}
+	}
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(localRunHost(opts), strconv.Itoa(port)), watchdogHealthPath)
+}
+
+// waitForWatchdog polls the watchdog's health endpoint until it responds,
+// the timeout passes, or exited reports that the container has stopped. Both
+// watchdogs return 503 until the function is ready. A response with any other
+// status means that the container is serving, as a function built from a
+// Dockerfile may not have the health endpoint.
+func waitForWatchdog(ctx context.Context, url string, timeout time.Duration, exited func() bool) error {
+	client := &http.Client{Timeout: time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if res, err := client.Do(req); err == nil {
+			res.Body.Close()
+			if res.StatusCode != http.StatusServiceUnavailable {
+				return nil
+			}
+		}
+
+		if exited() {
+			return errExitedBeforeReady
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("was not ready after %s", timeout.Round(time.Second))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(localRunReadyInterval):
+		}
+	}
+}
+
+// printLocalRunReady prints the function's URL, and opens it with --open
+func printLocalRunReady(name string, opts runOptions, detached bool) {
+	url := localRunURL(opts, opts.port)
+	if detached {
+		fmt.Fprintf(opts.output, "Started local-run for: %s on: %s in the background\n", name, url)
+	} else {
+		fmt.Fprintf(opts.output, "Ready: local-run for: %s on: %s\n", name, url)
+	}
+
+	if opts.debugPort > 0 {
+		fmt.Fprintf(opts.output, "Attach a debugger to: %s\n", net.JoinHostPort(localRunHost(opts), strconv.Itoa(opts.debugPort)))
+	}
+
+	if detached {
+		fmt.Fprintf(opts.output, "View its logs with: faas-cli local-run logs %s, and stop it with: faas-cli local-run stop %s\n", name, name)
+	} else {
+		fmt.Fprintln(opts.output)
+	}
+
+	if opts.open {
+		browse := fmt.Sprintf("http://%s", net.JoinHostPort(localRunHost(opts), strconv.Itoa(opts.port)))
+		if err := openBrowser(browse); err != nil {
+			fmt.Fprintf(opts.err, "Unable to open %s in a browser: %s\n", browse, err)
+		}
+	}
+}
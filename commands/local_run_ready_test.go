@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_waitForWatchdog(t *testing.T) {
+	defer func(previous time.Duration) { localRunReadyInterval = previous }(localRunReadyInterval)
+	localRunReadyInterval = time.Millisecond
+
+	var checks int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != watchdogHealthPath {
+			t.Errorf("want the health endpoint, got: %s", r.URL.Path)
+		}
+		if atomic.AddInt64(&checks, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	notExited := func() bool { return false }
+	if err := waitForWatchdog(context.Background(), s.URL+watchdogHealthPath, time.Second, notExited); err != nil {
+		t.Fatal(err)
+	}
+	if checks != 3 {
+		t.Errorf("want the endpoint checked until it was ready, got: %d checks", checks)
+	}
+
+	// Nothing listens on the closed server
+	s.Close()
+	err := waitForWatchdog(context.Background(), s.URL+watchdogHealthPath, time.Second, func() bool { return true })
+	if !errors.Is(err, errExitedBeforeReady) {
+		t.Errorf("want an error for the container exiting, got: %v", err)
+	}
+
+	err = waitForWatchdog(context.Background(), s.URL+watchdogHealthPath, 10*time.Millisecond, notExited)
+	if err == nil || !strings.Contains(err.Error(), "was not ready after") {
+		t.Errorf("want an error for the timeout, got: %v", err)
+	}
+}
+
+func Test_localRunHealthURL(t *testing.T) {
+	if got := localRunHealthURL(runOptions{port: 8081}); got != "http://127.0.0.1:8081/_/health" {
+		t.Errorf("want the published port, got: %s", got)
+	}
+
+	opts := runOptions{port: 8081, network: "host", extraEnv: map[string]string{"port": "9000"}}
+	if got := localRunHealthURL(opts); got != "http://127.0.0.1:9000/_/health" {
+		t.Errorf("want the watchdog's port on the host network, got: %s", got)
+	}
+}
+
+func Test_printLocalRunReady_Open(t *testing.T) {
+	defer func(previous func(string) error) { openBrowser = previous }(openBrowser)
+
+	var opened string
+	openBrowser = func(url string) error {
+		opened = url
+		return nil
+	}
+
+	var out bytes.Buffer
+	printLocalRunReady("stronghash", runOptions{port: 8081, open: true, output: &out, err: &out}, false)
+
+	if !strings.Contains(out.String(), "Ready: local-run for: stronghash on: http://0.0.0.0:8081") {
+		t.Errorf("want the URL printed, got: %q", out.String())
+	}
+	if opened != "http://127.0.0.1:8081" {
+		t.Errorf("want the function opened in a browser, got: %q", opened)
+	}
+}
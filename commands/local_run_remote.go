@@ -0,0 +1,175 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/openfaas/faas-cli/output"
+)
+
+// localRunDaemonEndpoint is the address of the daemon which the runtime
+// manages containers with, from DOCKER_HOST or the active docker context, or
+// CONTAINER_HOST for podman. It is replaced by tests.
+var localRunDaemonEndpoint = func(runtime string) string {
+	switch runtime {
+	case runtimeDocker:
+		if host := os.Getenv("DOCKER_HOST"); len(host) > 0 {
+			return host
+		}
+
+		// DOCKER_CONTEXT, when set, is the context which is inspected
+		out, err := exec.Command(runtime, "context", "inspect", "--format", "{{.Endpoints.docker.Host}}").Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	case runtimePodman:
+		return os.Getenv("CONTAINER_HOST")
+	}
+	return ""
+}
+
+// remoteDaemonHost is the host name of a daemon on another machine, or ""
+// when it is on this one, such as on a unix socket or the loopback address
+func remoteDaemonHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "tcp", "ssh", "http", "https":
+	default:
+		return ""
+	}
+
+	host := u.Hostname()
+	if len(host) == 0 || host == "localhost" {
+		return ""
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		return ""
+	}
+	return host
+}
+
+// localRunHost is the host which the functions' ports are published on
+func localRunHost(opts runOptions) string {
+	if len(opts.remoteHost) > 0 {
+		return opts.remoteHost
+	}
+	return "127.0.0.1"
+}
+
+// localRunURL is the URL printed for a function's port, which is published
+// on every interface of this machine, or of the remote daemon's
+func localRunURL(opts runOptions, port int) string {
+	host := "0.0.0.0"
+	if len(opts.remoteHost) > 0 {
+		host = opts.remoteHost
+	}
+	return fmt.Sprintf("http://%s", net.JoinHostPort(host, fmt.Sprint(port)))
+}
+
+// warnRemoteMounts warns that the folders mounted into the containers are
+// those of the remote daemon's machine, as it can't see the files of this one
+func warnRemoteMounts(plans []*localRunPlan, opts runOptions) {
+	if len(opts.remoteHost) == 0 {
+		return
+	}
+	for _, plan := range plans {
+		for _, mount := range plan.Mounts {
+			fmt.Fprintln(opts.err, output.Warning(fmt.Sprintf("Warning: the container runtime's daemon is on %s, so %s is mounted into %s from that machine, not this one",
+				opts.remoteHost, mount.Source, plan.Name)))
+		}
+	}
+}
+
+// stageLocalRun creates the container of a plan with Copies, and copies them
+// into it, so that the plan's command starts it. It is replaced by tests.
+var stageLocalRun = func(ctx context.Context, plan *localRunPlan, stderr io.Writer) error {
+	if len(plan.Copies) == 0 {
+		return nil
+	}
+
+	create := exec.CommandContext(ctx, plan.runtime(), runtimeRunArgs(plan.runtime(), plan.createArgs())...)
+	create.Stderr = stderr
+	if err := create.Run(); err != nil {
+		return fmt.Errorf("unable to create %s: %w", plan.Name, err)
+	}
+
+	for _, staged := range plan.Copies {
+		// A tar stream creates the target's parent folders, which docker cp
+		// of a path would not
+		var archive bytes.Buffer
+		if err := writeCopyArchive(&archive, staged); err != nil {
+			removeLocalRunContainer(plan.Name)
+			return fmt.Errorf("unable to copy %s into %s: %w", staged.Source, plan.Name, err)
+		}
+
+		cp := exec.CommandContext(ctx, plan.runtime(), "cp", "-", plan.Name+":/")
+		cp.Stdin = &archive
+		cp.Stderr = stderr
+		if err := cp.Run(); err != nil {
+			removeLocalRunContainer(plan.Name)
+			return fmt.Errorf("unable to copy %s into %s: %w", staged.Source, plan.Name, err)
+		}
+	}
+	return nil
+}
+
+// writeCopyArchive writes a tar of the file or folder at staged.Source, with
+// its paths under staged.Target. Files are readable by any user, as a secret
+// mounted by Kubernetes is, since the function may not run as root.
+func writeCopyArchive(w io.Writer, staged localRunMount) error {
+	tw := tar.NewWriter(w)
+	root := strings.TrimPrefix(path.Clean(staged.Target), "/")
+
+	err := filepath.Walk(staged.Source, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(staged.Source, file)
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{Name: path.Join(root, filepath.ToSlash(rel)), ModTime: info.ModTime()}
+		switch {
+		case info.IsDir():
+			header.Typeflag, header.Mode, header.Name = tar.TypeDir, 0755, header.Name+"/"
+			return tw.WriteHeader(header)
+		case info.Mode().IsRegular():
+			header.Typeflag, header.Mode, header.Size = tar.TypeReg, 0644, info.Size()
+		default:
+			return nil
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_remoteDaemonHost(t *testing.T) {
+	cases := map[string]string{
+		"ssh://dev@build-box":            "build-box",
+		"tcp://10.0.0.5:2376":            "10.0.0.5",
+		"tcp://127.0.0.1:2375":           "",
+		"tcp://localhost:2375":           "",
+		"tcp://0.0.0.0:2375":             "",
+		"unix:///var/run/docker.sock":    "",
+		"npipe:////./pipe/docker_engine": "",
+		"":                               "",
+	}
+	for endpoint, want := range cases {
+		if got := remoteDaemonHost(endpoint); got != want {
+			t.Errorf("%q: want %q, got: %q", endpoint, want, got)
+		}
+	}
+}
+
+func Test_localRunURL(t *testing.T) {
+	if got := localRunURL(runOptions{}, 8080); got != "http://0.0.0.0:8080" {
+		t.Errorf("want the port on every interface, got: %s", got)
+	}
+
+	opts := runOptions{remoteHost: "build-box", port: 8081}
+	if got := localRunURL(opts, 8081); got != "http://build-box:8081" {
+		t.Errorf("want the remote daemon's host, got: %s", got)
+	}
+	if got := localRunHealthURL(opts); got != "http://build-box:8081"+watchdogHealthPath {
+		t.Errorf("want the health check sent to the remote daemon's host, got: %s", got)
+	}
+}
+
+func Test_planDockerRun_RemoteSecrets(t *testing.T) {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	os.MkdirAll(localSecretsDir, 0700)
+	os.WriteFile(filepath.Join(localSecretsDir, "api-key"), []byte("s3cr3t"), 0600)
+	os.WriteFile(filepath.Join(localSecretsDir, "unused"), []byte("other"), 0600)
+
+	fnc := stack.Function{Name: "stronghash", Image: "stronghash:latest", Language: "dockerfile", FProcess: "./handler", Secrets: []string{"api-key"}}
+	plan, err := planDockerRun(fnc, runOptions{port: 8080, remoteHost: "build-box", detach: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Mounts) != 0 {
+		t.Errorf("want the secrets copied rather than mounted, got mounts: %v", plan.Mounts)
+	}
+	if len(plan.Copies) != 1 || plan.Copies[0].Target != containerSecretsPath+"/api-key" {
+		t.Fatalf("want only the function's secret copied, got: %v", plan.Copies)
+	}
+
+	commands := plan.shellCommands(context.Background())
+	if len(commands) != 3 {
+		t.Fatalf("want create, cp and start, got: %q", commands)
+	}
+	if !strings.Contains(commands[0], "docker create --rm") || strings.Contains(commands[0], "--detach") {
+		t.Errorf("want the container created without --detach, got: %s", commands[0])
+	}
+	if !strings.HasSuffix(commands[1], "cp "+plan.Copies[0].Source+" "+plan.Name+":"+containerSecretsPath+"/api-key") {
+		t.Errorf("want the secret copied, got: %s", commands[1])
+	}
+	if !strings.HasSuffix(commands[2], "docker start "+plan.Name) {
+		t.Errorf("want the detached container started, got: %s", commands[2])
+	}
+
+	plan.Detach = false
+	if got := strings.Join(plan.command(context.Background()).Args, " "); got != "docker start --attach --interactive "+plan.Name {
+		t.Errorf("want the container started attached, got: %s", got)
+	}
+}
+
+func Test_writeCopyArchive(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "api-key"), []byte("s3cr3t"), 0600)
+
+	var buf bytes.Buffer
+	if err := writeCopyArchive(&buf, localRunMount{Source: dir, Target: containerSecretsPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[header.Name] = string(data)
+		if header.Typeflag == tar.TypeReg && header.Mode != 0644 {
+			t.Errorf("want %s readable by the function's user, got mode: %o", header.Name, header.Mode)
+		}
+	}
+
+	root := strings.TrimPrefix(containerSecretsPath, "/")
+	if _, ok := files[root+"/"]; !ok || files[root+"/api-key"] != "s3cr3t" {
+		t.Errorf("want the folder and its file under %s, got: %v", root, files)
+	}
+}
+
+func Test_warnRemoteMounts(t *testing.T) {
+	plans := []*localRunPlan{{Name: "stronghash", Mounts: []localRunMount{{Source: "/home/dev/fixtures", Target: "/fixtures"}}}}
+
+	var buf bytes.Buffer
+	warnRemoteMounts(plans, runOptions{err: &buf})
+	if buf.Len() > 0 {
+		t.Errorf("want no warning for a local daemon, got: %s", buf.String())
+	}
+
+	warnRemoteMounts(plans, runOptions{err: &buf, remoteHost: "build-box"})
+	if got := buf.String(); !strings.Contains(got, "/home/dev/fixtures") || !strings.Contains(got, "build-box") {
+		t.Errorf("want a warning for the mount, got: %s", got)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+var (
+	ulimitNamePattern  = regexp.MustCompile(`^[a-z]+$`)
+	ulimitValuePattern = regexp.MustCompile(`^-?[0-9]+(:-?[0-9]+)?$`)
+)
+
+// localRunResources maps the limits and requests of the stack file to
+// docker run. The memory limit is a soft limit for debugging, and the memory
+// request is used for it when there is no limit. The CPU request becomes
+// --cpu-shares, of 1024 per CPU, as the kubelet sets cpu.shares.
+func localRunResources(fnc stack.Function) (*localRunLimits, error) {
+	limits := &localRunLimits{}
+	if fnc.Limits != nil {
+		limits.MemoryReservation = fnc.Limits.Memory
+		limits.CPUs = fnc.Limits.CPU
+	}
+
+	if fnc.Requests != nil {
+		if limits.MemoryReservation == "" {
+			limits.MemoryReservation = fnc.Requests.Memory
+		}
+		if fnc.Requests.CPU != "" {
+			cpus, err := parseCPUQuantity(fnc.Requests.CPU)
+			if err != nil {
+				return nil, fmt.Errorf("requests.cpu of %s: %w", fnc.Name, err)
+			}
+			limits.CPUShares = cpuShares(cpus)
+		}
+	}
+
+	if *limits == (localRunLimits{}) {
+		return nil, nil
+	}
+	return limits, nil
+}
+
+// parseCPUQuantity reads a number of CPUs as Kubernetes does, e.g. 0.5 or 500m
+func parseCPUQuantity(quantity string) (float64, error) {
+	value := strings.TrimSpace(quantity)
+	divisor := 1.0
+	if strings.HasSuffix(value, "m") {
+		value, divisor = strings.TrimSuffix(value, "m"), 1000
+	}
+
+	cpus, err := strconv.ParseFloat(value, 64)
+	if err != nil || cpus <= 0 {
+		return 0, fmt.Errorf("want a number of CPUs such as 0.5 or 500m, got: %q", quantity)
+	}
+	return cpus / divisor, nil
+}
+
+// parseMemoryQuantity reads a number of bytes as Kubernetes does, e.g. 128Mi,
+// 1G or 1048576
+func parseMemoryQuantity(quantity string) (float64, error) {
+	value := strings.TrimSpace(quantity)
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+		{"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+	}
+
+	multiplier := 1.0
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, multiplier = strings.TrimSuffix(value, unit.suffix), unit.multiplier
+			break
+		}
+	}
+
+	bytes, err := strconv.ParseFloat(value, 64)
+	if err != nil || bytes < 0 {
+		return 0, fmt.Errorf("want a size such as 128Mi or 1G, got: %q", quantity)
+	}
+	return bytes * multiplier, nil
+}
+
+// cpuShares is at least 2, the lowest weight the kernel accepts
+func cpuShares(cpus float64) int {
+	shares := int(cpus * 1024)
+	if shares < 2 {
+		return 2
+	}
+	return shares
+}
+
+// planContainerOptions adds the shared memory size, ulimits and tmpfs mounts
+// of the stack file, which are sorted so that --print is the same each time
+func planContainerOptions(plan *localRunPlan, fnc stack.Function) error {
+	plan.ShmSize = fnc.ShmSize
+
+	names := make([]string, 0, len(fnc.Ulimits))
+	for name := range fnc.Ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := strings.TrimSpace(fnc.Ulimits[name])
+		if !ulimitNamePattern.MatchString(name) || !ulimitValuePattern.MatchString(value) {
+			return fmt.Errorf("ulimits of %s must be NAME: SOFT or NAME: SOFT:HARD, such as nofile: 1024:4096, got: %s: %q", fnc.Name, name, value)
+		}
+		plan.Ulimits = append(plan.Ulimits, name+"="+value)
+	}
+
+	for _, tmpfs := range fnc.Tmpfs {
+		target := strings.SplitN(tmpfs, ":", 2)[0]
+		if !path.IsAbs(target) {
+			return fmt.Errorf("tmpfs of %s must be an absolute path, optionally followed by :OPTIONS, such as /tmp:size=64m, got: %q", fnc.Name, tmpfs)
+		}
+		plan.Tmpfs = append(plan.Tmpfs, tmpfs)
+	}
+	return nil
+}
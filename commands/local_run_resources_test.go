@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_planDockerRun_Resources(t *testing.T) {
+	fnc := stack.Function{
+		Name:     "render",
+		Image:    "render:latest",
+		Language: "dockerfile",
+		FProcess: "./handler",
+		Limits:   &stack.FunctionResources{CPU: "2"},
+		Requests: &stack.FunctionResources{Memory: "128Mi", CPU: "500m"},
+		ShmSize:  "256m",
+		Ulimits:  map[string]string{"nproc": "512", "nofile": "1024:4096"},
+		Tmpfs:    []string{"/tmp:size=64m", "/run"},
+	}
+
+	plan, err := planDockerRun(fnc, runOptions{port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := strings.Join(plan.args(), " ")
+	want := "--memory-reservation=128Mi --cpus=2 --cpu-shares=512 --shm-size=256m --ulimit=nofile=1024:4096 --ulimit=nproc=512 --tmpfs=/tmp:size=64m --tmpfs=/run"
+	if !strings.Contains(args, want) {
+		t.Errorf("want %q in: %s", want, args)
+	}
+}
+
+func Test_planDockerRun_LimitsTakePrecedence(t *testing.T) {
+	fnc := stack.Function{
+		Name:     "render",
+		Image:    "render:latest",
+		Language: "dockerfile",
+		FProcess: "./handler",
+		Limits:   &stack.FunctionResources{Memory: "256Mi"},
+		Requests: &stack.FunctionResources{Memory: "128Mi"},
+	}
+
+	plan, err := planDockerRun(fnc, runOptions{port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(plan.args(), " "); !strings.Contains(args, "--memory-reservation=256Mi") || strings.Contains(args, "--cpu-shares") {
+		t.Errorf("want the memory limit as the reservation, got: %s", args)
+	}
+}
+
+func Test_planDockerRun_InvalidOptions(t *testing.T) {
+	cases := map[string]stack.Function{
+		"requests.cpu": {Requests: &stack.FunctionResources{CPU: "half"}},
+		"ulimits":      {Ulimits: map[string]string{"nofile": "lots"}},
+		"tmpfs":        {Tmpfs: []string{"tmp:size=64m"}},
+	}
+	for want, fnc := range cases {
+		fnc.Name, fnc.Image, fnc.FProcess = "render", "render:latest", "./handler"
+		if _, err := planDockerRun(fnc, runOptions{port: 8080}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("want an error for %s, got: %v", want, err)
+		}
+	}
+}
+
+func Test_parseCPUQuantity(t *testing.T) {
+	cases := map[string]float64{"500m": 0.5, "1": 1, "0.25": 0.25, "1500m": 1.5}
+	for quantity, want := range cases {
+		if got, err := parseCPUQuantity(quantity); err != nil || got != want {
+			t.Errorf("%s: want %v, got: %v, %v", quantity, want, got, err)
+		}
+	}
+	if got := cpuShares(0.001); got != 2 {
+		t.Errorf("want at least 2 shares, got: %d", got)
+	}
+}
+
+func Test_parseMemoryQuantity(t *testing.T) {
+	cases := map[string]float64{"128Mi": 128 << 20, "1Gi": 1 << 30, "1G": 1e9, "500k": 5e5, "1048576": 1 << 20}
+	for quantity, want := range cases {
+		if got, err := parseMemoryQuantity(quantity); err != nil || got != want {
+			t.Errorf("%s: want %v, got: %v, %v", quantity, want, got, err)
+		}
+	}
+	if _, err := parseMemoryQuantity("lots"); err == nil {
+		t.Errorf("want an error for a size which isn't a number")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	runtimeDocker  = "docker"
+	runtimePodman  = "podman"
+	runtimeNerdctl = "nerdctl"
+
+	// containerRuntimeEnv picks the runtime for commands which have no
+	// --runtime flag, such as local-gateway
+	containerRuntimeEnv = "OPENFAAS_CONTAINER_RUNTIME"
+)
+
+// containerRuntimes are detected in this order, when none is given
+var containerRuntimes = []string{runtimeDocker, runtimePodman, runtimeNerdctl}
+
+// localRunRuntime is set by local-run's --runtime flag
+var localRunRuntime string
+
+// runtimeLookPath is replaced by tests
+var runtimeLookPath = exec.LookPath
+
+// validateContainerRuntime checks the runtime given by --runtime or the
+// environment, if any
+func validateContainerRuntime() error {
+	name, source := requestedContainerRuntime()
+	if name == "" {
+		return nil
+	}
+	for _, runtime := range containerRuntimes {
+		if name == runtime {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be one of: %s, got: %q", source, strings.Join(containerRuntimes, ", "), name)
+}
+
+func requestedContainerRuntime() (string, string) {
+	if localRunRuntime != "" {
+		return localRunRuntime, "--runtime"
+	}
+	return os.Getenv(containerRuntimeEnv), containerRuntimeEnv
+}
+
+// containerRuntime is the binary which local-run uses to manage containers,
+// from --runtime, the environment, or the first runtime which is installed.
+// docker is used when none is found, so that its error is the one reported.
+func containerRuntime() string {
+	if name, _ := requestedContainerRuntime(); name != "" {
+		return name
+	}
+	for _, runtime := range containerRuntimes {
+		if _, err := runtimeLookPath(runtime); err == nil {
+			return runtime
+		}
+	}
+	return runtimeDocker
+}
+
+// runtimeRunArgs translates the arguments of docker run for runtimes where
+// a flag differs. nerdctl has no network aliases, but adds the hostname of
+// each container to the hosts file of the others on its network.
+func runtimeRunArgs(runtime string, args []string) []string {
+	if runtime != runtimeNerdctl {
+		return args
+	}
+
+	translated := make([]string, 0, len(args))
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--network-alias=") {
+			arg = "--hostname=" + strings.TrimPrefix(arg, "--network-alias=")
+		}
+		translated = append(translated, arg)
+	}
+	return translated
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func Test_containerRuntime_Detected(t *testing.T) {
+	defer func() { runtimeLookPath = exec.LookPath; localRunRuntime = "" }()
+	t.Setenv(containerRuntimeEnv, "")
+
+	runtimeLookPath = func(file string) (string, error) {
+		if file == runtimePodman {
+			return "/usr/bin/podman", nil
+		}
+		return "", exec.ErrNotFound
+	}
+	if got := containerRuntime(); got != runtimePodman {
+		t.Errorf("want podman detected, got: %s", got)
+	}
+
+	runtimeLookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	if got := containerRuntime(); got != runtimeDocker {
+		t.Errorf("want docker when none is installed, got: %s", got)
+	}
+
+	t.Setenv(containerRuntimeEnv, runtimeNerdctl)
+	if got := containerRuntime(); got != runtimeNerdctl {
+		t.Errorf("want the runtime from %s, got: %s", containerRuntimeEnv, got)
+	}
+
+	localRunRuntime = runtimePodman
+	if got := containerRuntime(); got != runtimePodman {
+		t.Errorf("want --runtime to take precedence, got: %s", got)
+	}
+}
+
+func Test_validateContainerRuntime(t *testing.T) {
+	defer func() { localRunRuntime = "" }()
+	t.Setenv(containerRuntimeEnv, "")
+
+	localRunRuntime = "rkt"
+	err := validateContainerRuntime()
+	if err == nil || !strings.Contains(err.Error(), "--runtime must be one of: docker, podman, nerdctl") {
+		t.Fatalf("want an error for an unknown runtime, got: %v", err)
+	}
+
+	localRunRuntime = runtimeNerdctl
+	if err := validateContainerRuntime(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_localRunPlan_command_Runtime(t *testing.T) {
+	plan := &localRunPlan{
+		Runtime: runtimeNerdctl,
+		Name:    localRunContainerName("orders"),
+		Image:   "orders:latest",
+		Ports:   []localRunPort{{Host: 8080, Container: 8080}},
+		Network: localRunStackNetwork,
+		Aliases: []string{"orders"},
+	}
+
+	cmd := plan.command(context.Background())
+	if cmd.Args[0] != runtimeNerdctl {
+		t.Errorf("want nerdctl run, got: %v", cmd.Args)
+	}
+	args := strings.Join(cmd.Args[1:], " ")
+	if strings.Contains(args, "--network-alias") || !strings.Contains(args, "--hostname=orders") {
+		t.Errorf("want the alias given as the hostname for nerdctl, got: %s", args)
+	}
+
+	plan.Runtime = runtimePodman
+	if args := strings.Join(plan.command(context.Background()).Args, " "); !strings.HasPrefix(args, "podman run") || !strings.Contains(args, "--network-alias=orders") {
+		t.Errorf("want podman to take the docker flags, got: %s", args)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// pulledSecret is a secret which a function in the stack file uses, and the
+// namespace to read it from
+type pulledSecret struct {
+	name      string
+	namespace string
+}
+
+func newLocalRunSecretsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   `secrets`,
+		Short: "Manage the secrets in " + localSecretsDir + " which local-run mounts into functions",
+	}
+
+	cmd.AddCommand(newLocalRunSecretsPullCmd())
+	return cmd
+}
+
+func newLocalRunSecretsPullCmd() *cobra.Command {
+	var (
+		target    execTarget
+		overwrite bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   `pull [NAME] [--provider kubernetes|faasd] [--namespace NAMESPACE] [--overwrite]`,
+		Short: "Copy the secrets of functions from a cluster into " + localSecretsDir,
+		Long: `Reads the secrets which the functions in the stack file use from a cluster,
+as "faas-cli secret get" does, and writes each one into ` + localSecretsDir + `, where
+local-run mounts them from. Without a NAME, the secrets of every function are
+pulled.
+
+The gateway's API never returns the values of secrets, so kubectl is used with
+RBAC access to them for Kubernetes, or SSH and sudo for faasd. Each secret is
+read from the function's namespace in the stack file, unless --namespace is
+given. Secrets which are already in ` + localSecretsDir + ` are kept, unless
+--overwrite is given, and ` + localSecretsDir + ` is added to .gitignore.`,
+		Example: `  faas-cli local-run secrets pull
+  faas-cli local-run secrets pull stronghash --namespace staging
+  faas-cli local-run secrets pull --provider faasd --ssh ubuntu@faasd.example.com --overwrite`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkLocalRunExperimental(); err != nil {
+				return err
+			}
+
+			if len(args) > 1 {
+				return fmt.Errorf("only one function name is allowed")
+			}
+			return target.validate()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
+
+			secrets, err := localRunSecrets(name, target, cmd.Flags().Changed("namespace"))
+			if err != nil {
+				return err
+			}
+			if len(secrets) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No secrets are used by the functions")
+				return nil
+			}
+
+			if err := updateGitignore(); err != nil {
+				return err
+			}
+			return pullLocalSecrets(cmd, target, secrets, overwrite)
+		},
+	}
+
+	addExecTargetFlags(cmd, &target)
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "replace secrets which are already in "+localSecretsDir)
+
+	return cmd
+}
+
+// localRunSecrets lists the secrets used by the functions, with the namespace
+// of the function which uses each one, or the target's with --namespace
+func localRunSecrets(name string, target execTarget, namespaceFlag bool) ([]pulledSecret, error) {
+	services, err := localRunServices(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first function by name picks the namespace of a shared secret, as
+	// there is one file for each secret
+	names := make([]string, 0, len(services.Functions))
+	for fnName := range services.Functions {
+		names = append(names, fnName)
+	}
+	sort.Strings(names)
+
+	seen := map[string]bool{}
+	var secrets []pulledSecret
+	for _, fnName := range names {
+		fnc := services.Functions[fnName]
+		namespace := target.namespace
+		if !namespaceFlag && len(fnc.Namespace) > 0 {
+			namespace = fnc.Namespace
+		}
+
+		for _, secret := range fnc.Secrets {
+			if seen[secret] {
+				continue
+			}
+			if isValid, err := validateSecretName(secret); !isValid {
+				return nil, fmt.Errorf("function %s: %w", fnName, err)
+			}
+			seen[secret] = true
+			secrets = append(secrets, pulledSecret{name: secret, namespace: namespace})
+		}
+	}
+
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].name < secrets[j].name })
+	return secrets, nil
+}
+
+// pullLocalSecrets writes each secret into the secrets folder. Every secret
+// is tried, so that all which could not be read are reported together.
+func pullLocalSecrets(cmd *cobra.Command, target execTarget, secrets []pulledSecret, overwrite bool) error {
+	dir, err := filepath.Abs(localSecretsDir)
+	if err != nil {
+		return fmt.Errorf("can't determine secrets folder: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("can't create local secrets folder %q: %w", dir, err)
+	}
+
+	failed := 0
+	for _, secret := range secrets {
+		path := filepath.Join(dir, secret.name)
+		if _, err := os.Stat(path); err == nil && !overwrite {
+			fmt.Fprintf(cmd.OutOrStdout(), "Kept: %s, which is already in %s\n", secret.name, localSecretsDir)
+			continue
+		}
+
+		secretTarget := target
+		secretTarget.namespace = secret.namespace
+
+		value, err := readSecretValue(secretTarget, secret.name)
+		if err == nil {
+			err = os.WriteFile(path, value, 0600)
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Unable to pull %s: %s\n", secret.name, err)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Pulled: %s.%s\n", secret.name, secret.namespace)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("unable to pull %d of %d secrets", failed, len(secrets))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_localRunSecretsPull(t *testing.T) {
+	t.Setenv("OPENFAAS_EXPERIMENTAL", "1")
+
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	os.WriteFile("stack.yml", []byte(`version: 1.0
+provider:
+  name: openfaas
+functions:
+  api:
+    image: acme/api:latest
+    namespace: staging
+    secrets:
+    - db-password
+    - api-key
+  worker:
+    image: acme/worker:latest
+    secrets:
+    - api-key
+`), 0600)
+	os.MkdirAll(localSecretsDir, 0700)
+	os.WriteFile(filepath.Join(localSecretsDir, "api-key"), []byte("local"), 0600)
+
+	resetForTest()
+	yamlFile = "stack.yml"
+	defer func() { yamlFile = "" }()
+
+	savedRun := runSecretGet
+	defer func() { runSecretGet = savedRun }()
+
+	var pulled []string
+	runSecretGet = func(argv []string, stdin io.Reader, stdout, stderr io.Writer) error {
+		pulled = append(pulled, strings.Join(argv, " "))
+		_, err := io.WriteString(stdout, "s3cr3t")
+		return err
+	}
+
+	cmd := newLocalRunSecretsPullCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	if err := cmd.ParseFlags([]string{"--provider", "faasd", "--ssh", "faasd.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.PreRunE(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.RunE(cmd, nil); err != nil {
+		t.Fatalf("%s\n%s", err, out.String())
+	}
+
+	if len(pulled) != 1 || !strings.Contains(pulled[0], "/staging/db-password") {
+		t.Errorf("want only db-password read from the function's namespace, got: %v", pulled)
+	}
+	if value, _ := os.ReadFile(filepath.Join(localSecretsDir, "db-password")); string(value) != "s3cr3t" {
+		t.Errorf("want the secret written, got: %q", value)
+	}
+	if value, _ := os.ReadFile(filepath.Join(localSecretsDir, "api-key")); string(value) != "local" {
+		t.Errorf("want the existing secret kept, got: %q", value)
+	}
+	if !strings.Contains(out.String(), "Kept: api-key") || !strings.Contains(out.String(), "Pulled: db-password.staging") {
+		t.Errorf("want each secret reported, got:\n%s", out.String())
+	}
+
+	gitignore, _ := os.ReadFile(".gitignore")
+	if !strings.Contains(string(gitignore), localSecretsDir) {
+		t.Errorf("want %s ignored by git, got:\n%s", localSecretsDir, gitignore)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// The capabilities and privilege escalation of a function's securityContext
+// are given by these annotations, as the stack file has no fields for them
+const (
+	securityDropCapabilitiesAnnotation = "com.openfaas.security.capabilities.drop"
+	securityAddCapabilitiesAnnotation  = "com.openfaas.security.capabilities.add"
+	securityPrivilegeEscalation        = "com.openfaas.security.allow-privilege-escalation"
+)
+
+var (
+	runAsUserPattern  = regexp.MustCompile(`^[A-Za-z0-9_.-]+(:[A-Za-z0-9_.-]+)?$`)
+	capabilityPattern = regexp.MustCompile(`^[A-Z][A-Z_]*$`)
+)
+
+// planSecurityContext applies the securityContext which the function has in
+// the cluster to its container: the runasuser and privileged fields of the
+// stack file, and the capabilities annotations. Privilege escalation is
+// disabled with no-new-privileges unless the function is privileged, or its
+// annotation allows it.
+func planSecurityContext(plan *localRunPlan, fnc stack.Function) error {
+	if len(fnc.RunAsUser) > 0 {
+		if !runAsUserPattern.MatchString(fnc.RunAsUser) {
+			return fmt.Errorf("runasuser of %s must be a user or UID, with an optional :GROUP, got: %q", fnc.Name, fnc.RunAsUser)
+		}
+		plan.User = fnc.RunAsUser
+	}
+	plan.Privileged = fnc.Privileged
+
+	var annotations map[string]string
+	if fnc.Annotations != nil {
+		annotations = *fnc.Annotations
+	}
+
+	var err error
+	if plan.CapDrop, err = parseCapabilities(annotations[securityDropCapabilitiesAnnotation]); err != nil {
+		return fmt.Errorf("%s of %s: %w", securityDropCapabilitiesAnnotation, fnc.Name, err)
+	}
+	if plan.CapAdd, err = parseCapabilities(annotations[securityAddCapabilitiesAnnotation]); err != nil {
+		return fmt.Errorf("%s of %s: %w", securityAddCapabilitiesAnnotation, fnc.Name, err)
+	}
+
+	allowEscalation := false
+	if value, ok := annotations[securityPrivilegeEscalation]; ok {
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true":
+			allowEscalation = true
+		case "false":
+		default:
+			return fmt.Errorf("%s of %s must be true or false, got: %q", securityPrivilegeEscalation, fnc.Name, value)
+		}
+	}
+	if !allowEscalation && !plan.Privileged {
+		plan.SecurityOpts = append(plan.SecurityOpts, "no-new-privileges")
+	}
+	return nil
+}
+
+// parseCapabilities reads a comma-separated list of capabilities, such as
+// ALL or NET_BIND_SERVICE, with or without the CAP_ prefix of Linux
+func parseCapabilities(value string) ([]string, error) {
+	var capabilities []string
+	for _, field := range strings.Split(value, ",") {
+		capability := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(field)), "CAP_")
+		if len(capability) == 0 {
+			continue
+		}
+		if !capabilityPattern.MatchString(capability) {
+			return nil, fmt.Errorf("want capabilities such as ALL or NET_RAW, got: %q", field)
+		}
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	return capabilities, nil
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_planSecurityContext(t *testing.T) {
+	fnc := stack.Function{
+		Name:      "resize",
+		Image:     "resize:latest",
+		FProcess:  "./handler",
+		RunAsUser: "12000:12000",
+		Annotations: &map[string]string{
+			securityDropCapabilitiesAnnotation: "ALL",
+			securityAddCapabilitiesAnnotation:  "net_bind_service, CAP_CHOWN",
+		},
+	}
+
+	plan, err := planDockerRun(fnc, runOptions{port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(plan.args(), " ")
+	want := "--user=12000:12000 --cap-drop=ALL --cap-add=CHOWN --cap-add=NET_BIND_SERVICE --security-opt=no-new-privileges"
+	if !strings.Contains(got, want) {
+		t.Errorf("want %q in:\n%s", want, got)
+	}
+}
+
+func Test_planSecurityContext_Escalation(t *testing.T) {
+	cases := []struct {
+		name string
+		fnc  stack.Function
+		want []string
+	}{
+		{
+			name: "no-new-privileges by default",
+			fnc:  stack.Function{Name: "env"},
+			want: []string{"no-new-privileges"},
+		},
+		{
+			name: "allowed by the annotation",
+			fnc:  stack.Function{Name: "env", Annotations: &map[string]string{securityPrivilegeEscalation: "true"}},
+		},
+		{
+			name: "privileged",
+			fnc:  stack.Function{Name: "env", Privileged: true},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			plan := &localRunPlan{}
+			if err := planSecurityContext(plan, c.fnc); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(plan.SecurityOpts, ",") != strings.Join(c.want, ",") {
+				t.Errorf("want security options %v, got %v", c.want, plan.SecurityOpts)
+			}
+			if plan.Privileged != c.fnc.Privileged {
+				t.Errorf("want privileged %v, got %v", c.fnc.Privileged, plan.Privileged)
+			}
+		})
+	}
+}
+
+func Test_planSecurityContext_Invalid(t *testing.T) {
+	for _, fnc := range []stack.Function{
+		{Name: "env", RunAsUser: "12000 12000"},
+		{Name: "env", Annotations: &map[string]string{securityDropCapabilitiesAnnotation: "NET-RAW"}},
+		{Name: "env", Annotations: &map[string]string{securityPrivilegeEscalation: "sometimes"}},
+	} {
+		if err := planSecurityContext(&localRunPlan{}, fnc); err == nil || !strings.Contains(err.Error(), "of env") {
+			t.Errorf("want an error naming the function for %+v, got: %v", fnc, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// localRunServiceLabel marks the containers of the stack's x-local-services
+// with their name, they are not labelled as functions so that ps and
+// local-gateway leave them out
+const localRunServiceLabel = "com.openfaas.local-run.service"
+
+func localServiceContainerName(name string) string {
+	return "openfaas-local-svc-" + name
+}
+
+// runLocalService starts the detached container of a service, and is
+// replaced by tests
+var runLocalService = func(ctx context.Context, plan *localRunPlan) error {
+	cmd := plan.command(ctx)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// localServiceState is the health of a service's container when its image
+// has a health check, otherwise its state, such as running. It is replaced
+// by tests.
+var localServiceState = func(name string) (string, error) {
+	out, err := exec.Command(containerRuntime(), "inspect", "--format",
+		"{{if .State.Health}}{{.State.Health.Status}}{{else}}{{.State.Status}}{{end}}",
+		localServiceContainerName(name)).Output()
+	if err != nil {
+		return "", fmt.Errorf("it is not running")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// removeLocalService force-removes a service's container, and is replaced by
+// tests
+var removeLocalService = func(name string) {
+	exec.Command(containerRuntime(), "rm", "--force", localServiceContainerName(name)).Run()
+}
+
+// planLocalServices plans a detached container on network for each of the
+// stack's x-local-services, in the order of their names
+func planLocalServices(services *stack.Services, network string, opts runOptions) ([]*localRunPlan, error) {
+	localServices, err := services.LocalServices()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(localServices))
+	for name := range localServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	plans := make([]*localRunPlan, 0, len(names))
+	for _, name := range names {
+		service := localServices[name]
+
+		plan := &localRunPlan{
+			Runtime: opts.runtime,
+			Name:    localServiceContainerName(name),
+			Image:   service.Image,
+			Env:     service.Environment,
+			Command: service.Command,
+			Labels:  map[string]string{localRunServiceLabel: name},
+			Network: network,
+			Aliases: []string{name},
+			Detach:  true,
+		}
+		addLocalRunLabels(plan.Labels, opts)
+		if plan.Env == nil {
+			plan.Env = map[string]string{}
+		}
+
+		for _, port := range service.Ports {
+			host, container, _ := stack.ParseLocalServicePort(port)
+			plan.Ports = append(plan.Ports, localRunPort{Host: host, Container: container})
+		}
+		for _, volume := range service.Volumes {
+			mount, err := parseLocalRunVolume(volume)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", stack.LocalServicesExtension, name, err)
+			}
+			plan.Mounts = append(plan.Mounts, mount)
+		}
+
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// startLocalServices starts the services' containers, and waits for each to
+// be running, or healthy when its image has a health check. The returned
+// func removes them, which is left to "local-run stop --all" with --detach.
+func startLocalServices(ctx context.Context, plans []*localRunPlan, opts runOptions) (func(), error) {
+	var started []string
+	remove := func() {
+		for _, name := range started {
+			removeLocalService(name)
+		}
+	}
+
+	for _, plan := range plans {
+		name := plan.Labels[localRunServiceLabel]
+		if err := runLocalService(ctx, plan); err != nil {
+			remove()
+			return nil, fmt.Errorf("unable to start %s: %w", name, err)
+		}
+		started = append(started, name)
+
+		if err := waitForLocalService(ctx, name, opts.readyTimeout); err != nil {
+			remove()
+			return nil, fmt.Errorf("%s %w, see: %s logs %s", name, err, plan.runtime(), plan.Name)
+		}
+		fmt.Fprintf(opts.output, "Started service: %s (%s)\n", name, plan.Image)
+	}
+	return remove, nil
+}
+
+// waitForLocalService polls the state of a service's container until it is
+// ready, or the timeout passes
+func waitForLocalService(ctx context.Context, name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		state, err := localServiceState(name)
+		if err != nil {
+			return err
+		}
+
+		switch state {
+		case "healthy", "running":
+			return nil
+		case "starting", "created", "restarting":
+		default:
+			return fmt.Errorf("is %s", state)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("was not ready after %s", timeout.Round(time.Second))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(localRunReadyInterval):
+		}
+	}
+}
+
+// printLocalServices prints the commands which start the services, for --print
+func printLocalServices(ctx context.Context, w io.Writer, plans []*localRunPlan) {
+	for _, plan := range plans {
+		fmt.Fprintf(w, "%s\n", plan.command(ctx).String())
+	}
+}
+
+// runningLocalServices lists the container names of the services which are
+// running
+func runningLocalServices() ([]string, error) {
+	out, err := exec.Command(containerRuntime(), "ps",
+		"--filter", "label="+localRunServiceLabel,
+		"--format", fmt.Sprintf(`{{.Label "%s"}}`, localRunServiceLabel)).Output()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list local-run containers: %w", err)
+	}
+
+	var names []string
+	for _, name := range strings.Fields(string(out)) {
+		names = append(names, localServiceContainerName(name))
+	}
+	return names, nil
+}
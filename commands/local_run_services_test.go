@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+const localServicesStack = `version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    lang: dockerfile
+    handler: ./orders
+    image: acme/orders:0.1.0
+    fprocess: ./handler
+x-local-services:
+  redis:
+    image: redis:7-alpine
+    command: redis-server --appendonly yes
+    ports:
+      - 6379:6379
+  postgres:
+    image: postgres:16
+    environment:
+      POSTGRES_PASSWORD: local
+`
+
+func Test_planLocalServices(t *testing.T) {
+	services := &stack.Services{Extensions: stack.Extensions{stack.LocalServicesExtension: map[interface{}]interface{}{
+		"redis": map[interface{}]interface{}{
+			"image":   "redis:7-alpine",
+			"command": "redis-server --appendonly yes",
+			"ports":   []interface{}{"6379:6379"},
+		},
+	}}}
+
+	plans, err := planLocalServices(services, localRunStackNetwork, runOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("want one plan, got: %d", len(plans))
+	}
+
+	args := strings.Join(plans[0].args(), " ")
+	for _, want := range []string{
+		"-p=6379:6379",
+		"--name=openfaas-local-svc-redis",
+		"--label=" + localRunServiceLabel + "=redis",
+		"--detach",
+		"--network=openfaas-local-run",
+		"--network-alias=redis",
+		"redis:7-alpine redis-server --appendonly yes",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("want %q in: %s", want, args)
+		}
+	}
+	if strings.Contains(args, "--label="+localRunLabel+"=true") {
+		t.Errorf("want the service not labelled as a function, got: %s", args)
+	}
+}
+
+func Test_startLocalServices(t *testing.T) {
+	oldRun, oldState, oldRemove, oldInterval := runLocalService, localServiceState, removeLocalService, localRunReadyInterval
+	defer func() {
+		runLocalService, localServiceState, removeLocalService, localRunReadyInterval = oldRun, oldState, oldRemove, oldInterval
+	}()
+	localRunReadyInterval = time.Millisecond
+
+	var started, removed []string
+	runLocalService = func(ctx context.Context, plan *localRunPlan) error {
+		started = append(started, plan.Labels[localRunServiceLabel])
+		return nil
+	}
+	removeLocalService = func(name string) { removed = append(removed, name) }
+
+	checks := map[string]int{}
+	localServiceState = func(name string) (string, error) {
+		checks[name]++
+		if name == "postgres" && checks[name] < 3 {
+			return "starting", nil
+		}
+		if name == "broken" {
+			return "unhealthy", nil
+		}
+		return "running", nil
+	}
+
+	plans := []*localRunPlan{
+		{Name: "openfaas-local-svc-postgres", Labels: map[string]string{localRunServiceLabel: "postgres"}},
+		{Name: "openfaas-local-svc-redis", Labels: map[string]string{localRunServiceLabel: "redis"}},
+	}
+
+	var out bytes.Buffer
+	remove, err := startLocalServices(context.Background(), plans, runOptions{output: &out, readyTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(started, []string{"postgres", "redis"}) || checks["postgres"] != 3 {
+		t.Errorf("want each service started once the last is ready, got: %v, checks: %v", started, checks)
+	}
+	if !strings.Contains(out.String(), "Started service: redis") {
+		t.Errorf("want each service printed, got: %s", out.String())
+	}
+
+	remove()
+	if !reflect.DeepEqual(removed, []string{"postgres", "redis"}) {
+		t.Errorf("want the services removed, got: %v", removed)
+	}
+
+	removed = nil
+	plans = append(plans, &localRunPlan{Name: "openfaas-local-svc-broken", Labels: map[string]string{localRunServiceLabel: "broken"}})
+	if _, err := startLocalServices(context.Background(), plans, runOptions{output: &out, readyTimeout: time.Second}); err == nil || !strings.Contains(err.Error(), "broken is unhealthy") {
+		t.Errorf("want an error for an unhealthy service, got: %v", err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("want the started services removed after a failure, got: %v", removed)
+	}
+
+	runLocalService = func(ctx context.Context, plan *localRunPlan) error { return errors.New("pull access denied") }
+	if _, err := startLocalServices(context.Background(), plans[:1], runOptions{output: &out}); err == nil || !strings.Contains(err.Error(), "pull access denied") {
+		t.Errorf("want the runtime's error, got: %v", err)
+	}
+}
+
+func Test_runFunction_PrintLocalServices(t *testing.T) {
+	dir := t.TempDir()
+	stackFile := filepath.Join(dir, "stack.yml")
+	os.WriteFile(stackFile, []byte(localServicesStack), 0600)
+
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	resetForTest()
+	yamlFile = stackFile
+
+	var buf bytes.Buffer
+	err := runFunction(context.Background(), "orders", runOptions{port: 8080, print: true, printFormat: printFormatShell, output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("want the network, two services and the function, got:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[0], "network create") {
+		t.Errorf("want the network created first, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "openfaas-local-svc-postgres") || !strings.Contains(lines[2], "openfaas-local-svc-redis") {
+		t.Errorf("want the services started before the function, got:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[3], "--network=openfaas-local-run") {
+		t.Errorf("want the function on the services' network, got: %s", lines[3])
+	}
+
+	buf.Reset()
+	err = runFunction(context.Background(), "orders", runOptions{port: 8080, print: true, printFormat: printFormatCompose, output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"redis:", "postgres:", "orders:", "- redis-server"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("want %q in the compose file, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// localRunStackNetwork is created for local-run --all, unless --network is
+// given, so that the functions can reach each other by name
+const localRunStackNetwork = "openfaas-local-run"
+
+// ensureLocalRunNetwork creates the network when it does not exist
+var ensureLocalRunNetwork = func(ctx context.Context, name string) error {
+	runtime := containerRuntime()
+	if exec.CommandContext(ctx, runtime, "network", "inspect", name).Run() == nil {
+		return nil
+	}
+
+	out, err := exec.CommandContext(ctx, runtime, "network", "create",
+		fmt.Sprintf("--label=%s=true", localRunLabel), name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to create the network %s: %s", name, string(out))
+	}
+	return nil
+}
+
+// localRunNetworkCommand is printed by --print to create the network when it
+// does not exist
+func localRunNetworkCommand(runtime, network string) string {
+	return fmt.Sprintf("%s network inspect %s >/dev/null 2>&1 || %s network create --label=%s=true %s", runtime, network, runtime, localRunLabel, network)
+}
+
+// runStack starts every function in the stack file, each on the next free
+// port from opts.port
+func runStack(ctx context.Context, opts runOptions) error {
+	services, err := localRunServices("")
+	if err != nil {
+		return err
+	}
+
+	if len(services.Functions) == 0 {
+		return fmt.Errorf("no functions found in the stack file")
+	}
+
+	err = updateGitignore()
+	if err != nil {
+		return err
+	}
+
+	network := opts.network
+	if network == "" {
+		network = localRunStackNetwork
+	}
+
+	hostPorts, err := selectPorts(len(services.Functions), opts)
+	if err != nil {
+		return err
+	}
+
+	plans, ports, err := planStack(services.Functions, network, hostPorts, opts)
+	if err != nil {
+		return err
+	}
+	servicePlans, err := planLocalServices(services, network, opts)
+	if err != nil {
+		return err
+	}
+	warnRemoteMounts(append(servicePlans, plans...), opts)
+
+	if opts.print {
+		switch opts.printFormat {
+		case printFormatJSON:
+			encoder := json.NewEncoder(opts.output)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(append(servicePlans, plans...))
+		case printFormatCompose:
+			return writeCompose(opts.output, append(servicePlans, plans...), opts.network != "")
+		}
+		if opts.network == "" {
+			fmt.Fprintf(opts.output, "%s\n", localRunNetworkCommand(plans[0].runtime(), network))
+		}
+		printLocalServices(ctx, opts.output, servicePlans)
+		for _, plan := range plans {
+			fmt.Fprintf(opts.output, "%s\n", strings.Join(plan.shellCommands(ctx), "\n"))
+		}
+		return nil
+	}
+
+	// A network given by --network is expected to exist already
+	if opts.network == "" {
+		if err := ensureLocalRunNetwork(ctx, network); err != nil {
+			return err
+		}
+	}
+
+	if len(servicePlans) > 0 {
+		removeServices, err := startLocalServices(ctx, servicePlans, opts)
+		if err != nil {
+			return err
+		}
+		if !opts.detach {
+			defer removeServices()
+		}
+	}
+
+	if opts.detach {
+		for _, plan := range plans {
+			if err := stageLocalRun(ctx, plan, opts.err); err != nil {
+				return err
+			}
+			cmd := plan.command(ctx)
+			cmd.Stdout = opts.output
+			cmd.Stderr = opts.err
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("unable to start %s: %w", plan.Labels[localRunFunctionLabel], err)
+			}
+		}
+
+		printStackPorts(opts, ports)
+		fmt.Fprintln(opts.output, "\nView them with: faas-cli local-run ps, and stop them with: faas-cli local-run stop --all")
+		return nil
+	}
+
+	printStackPorts(opts, ports)
+	fmt.Fprintln(opts.output)
+
+	if opts.withAsync {
+		stopQueue, err := startAsyncQueue(ports, opts)
+		if err != nil {
+			return err
+		}
+		defer stopQueue()
+	}
+
+	if opts.gateway {
+		stopGateway, err := startLocalRunGateway(ports, opts)
+		if err != nil {
+			return err
+		}
+		defer stopGateway()
+	}
+
+	return runPlans(ctx, plans, opts)
+}
+
+// planStack plans a container for each function, the host ports are assigned
+// in the order of the functions' names so that they are the same on each run
+func planStack(functions map[string]stack.Function, network string, hostPorts []int, opts runOptions) ([]*localRunPlan, map[string]int, error) {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	plans := make([]*localRunPlan, 0, len(names))
+	ports := map[string]int{}
+	for i, name := range names {
+		fnc := functions[name]
+		fnc.Name = name
+
+		fnOpts := opts
+		fnOpts.port = hostPorts[i]
+		fnOpts.network = network
+
+		plan, err := planDockerRun(fnc, fnOpts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		plan.Aliases = []string{name}
+
+		plans = append(plans, plan)
+		ports[name] = fnOpts.port
+	}
+
+	return plans, ports, nil
+}
+
+// runPlans runs the containers in the foreground, with their output prefixed
+// by the function's name, until they have all exited
+func runPlans(ctx context.Context, plans []*localRunPlan, opts runOptions) error {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	names := make([]string, 0, len(plans))
+	for _, plan := range plans {
+		names = append(names, plan.Labels[localRunFunctionLabel])
+	}
+	defer teardownOnSignal(names, opts)()
+
+	logs := newLocalRunLogs(opts)
+	for _, plan := range plans {
+		name := plan.Labels[localRunFunctionLabel]
+
+		if err := stageLocalRun(ctx, plan, opts.err); err != nil {
+			return err
+		}
+		cmd := plan.command(ctx)
+		cmd.Stdout, cmd.Stderr = logs.streams(name)
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("unable to start %s: %w", name, err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := cmd.Wait()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", name, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	logs.flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return firstErr
+}
+
+func printStackPorts(opts runOptions, ports map[string]int) {
+	table := output.NewTable("FUNCTION", "URL", "NETWORK ALIAS")
+	for _, name := range namesByPort(ports) {
+		table.Row(name, localRunURL(opts, ports[name]), fmt.Sprintf("http://%s:8080", name))
+	}
+	table.Write(opts.output)
+}
+
+// namesByPort sorts the names of the functions by their ports
+func namesByPort(ports map[string]int) []string {
+	names := make([]string, 0, len(ports))
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if ports[names[i]] == ports[names[j]] {
+			return names[i] < names[j]
+		}
+		return ports[names[i]] < ports[names[j]]
+	})
+	return names
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_planStack_SequentialPorts(t *testing.T) {
+	functions := map[string]stack.Function{
+		"payments": {Image: "acme/payments:0.1.0", Language: "dockerfile", FProcess: "./handler"},
+		"orders":   {Image: "acme/orders:0.1.0", Language: "dockerfile", FProcess: "./handler"},
+		"emails":   {Image: "acme/emails:0.1.0", Language: "dockerfile", FProcess: "./handler"},
+	}
+
+	plans, ports, err := planStack(functions, localRunStackNetwork, []int{8081, 8082, 8083}, runOptions{port: 8081, detach: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"emails": 8081, "orders": 8082, "payments": 8083}
+	if !reflect.DeepEqual(ports, want) {
+		t.Fatalf("want ports: %v, got: %v", want, ports)
+	}
+
+	args := strings.Join(plans[1].args(), " ")
+	for _, want := range []string{
+		"-p=8082:8080",
+		"--name=openfaas-local-orders",
+		"--label=com.openfaas.local-run.port=8082",
+		"--network=openfaas-local-run",
+		"--network-alias=orders",
+		"--detach",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("want %q in: %s", want, args)
+		}
+	}
+}
+
+func Test_runStack_Print(t *testing.T) {
+	dir := t.TempDir()
+	stackFile := filepath.Join(dir, "stack.yml")
+	os.WriteFile(stackFile, []byte(`version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    lang: dockerfile
+    handler: ./orders
+    image: acme/orders:0.1.0
+    fprocess: ./handler
+  payments:
+    lang: dockerfile
+    handler: ./payments
+    image: acme/payments:0.1.0
+    fprocess: ./handler
+`), 0600)
+
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	resetForTest()
+	yamlFile = stackFile
+
+	var buf bytes.Buffer
+	err := runStack(context.Background(), runOptions{port: 8080, print: true, printFormat: printFormatShell, output: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want the network and two functions, got:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[0], "docker network create --label=com.openfaas.local-run=true openfaas-local-run") {
+		t.Errorf("want the network created first, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[2], "-p=8081:8080") || !strings.Contains(lines[2], "acme/payments:0.1.0") {
+		t.Errorf("want payments on the second port, got: %s", lines[2])
+	}
+}
+
+func Test_namesByPort(t *testing.T) {
+	got := namesByPort(map[string]int{"b": 8082, "a": 8083, "c": 8081})
+	want := []string{"c", "b", "a"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/openfaas/faas-cli/output"
+)
+
+const (
+	// statsMemoryHeadroom is added to the sampled memory for the suggestions
+	statsMemoryHeadroom = 1.25
+	statsMemoryStep     = 16 * 1024 * 1024
+	// statsMemoryWarning is the share of the memory limit above which the
+	// usage printed by --stats-interval is highlighted
+	statsMemoryWarning = 0.9
+)
+
+// dockerStatsCommand streams the usage of a container, and is replaced by tests
+var dockerStatsCommand = func(ctx context.Context, container string) *exec.Cmd {
+	return exec.CommandContext(ctx, containerRuntime(), "stats", "--format", "{{json .}}", container)
+}
+
+// terminalEscapes are written by docker stats to redraw its output
+var terminalEscapes = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// usageStats accumulates samples of a container's CPU and memory
+type usageStats struct {
+	mu       sync.Mutex
+	samples  int
+	cpuPeak  float64
+	cpuTotal float64
+	memPeak  float64
+	memTotal float64
+
+	// last is the latest sample, fresh until it is read by latest
+	lastCPU    float64
+	lastMemory float64
+	fresh      bool
+}
+
+func (s *usageStats) add(cpuPercent, memoryBytes float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples++
+	s.cpuTotal += cpuPercent
+	s.memTotal += memoryBytes
+	s.cpuPeak = math.Max(s.cpuPeak, cpuPercent)
+	s.memPeak = math.Max(s.memPeak, memoryBytes)
+	s.lastCPU, s.lastMemory, s.fresh = cpuPercent, memoryBytes, true
+}
+
+// latest returns the last sample, when it hasn't been returned before
+func (s *usageStats) latest() (float64, float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fresh := s.fresh
+	s.fresh = false
+	return s.lastCPU, s.lastMemory, fresh
+}
+
+// liveStats prints the latest usage every interval while the function runs
+type liveStats struct {
+	interval time.Duration
+	// memoryLimit is the limits.memory of the stack file, e.g. 128Mi, which
+	// the usage is shown as a share of when set
+	memoryLimit string
+	// print writes a line without interleaving it with the function's logs
+	print func(line string)
+}
+
+// printLiveStats prints the latest sample each interval, until ctx is
+// cancelled
+func printLiveStats(ctx context.Context, name string, stats *usageStats, live liveStats) {
+	ticker := time.NewTicker(live.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if cpu, memory, ok := stats.latest(); ok {
+				live.print(liveStatsLine(name, cpu, memory, live.memoryLimit))
+			}
+		}
+	}
+}
+
+// liveStatsLine describes a sample, and when the memory limit can be read,
+// the share of it which is used, highlighted from statsMemoryWarning
+func liveStatsLine(name string, cpuPercent, memoryBytes float64, memoryLimit string) string {
+	line := fmt.Sprintf("Usage of %s: CPU %.1f%%, memory %s", name, cpuPercent, output.Size(memoryBytes))
+
+	limit, err := parseMemoryQuantity(memoryLimit)
+	if err != nil || limit == 0 {
+		return line
+	}
+
+	share := memoryBytes / limit
+	line += fmt.Sprintf(", %.0f%% of its %s limit", share*100, memoryLimit)
+	if share >= statsMemoryWarning {
+		return output.Warning(line)
+	}
+	return line
+}
+
+// sampleContainerStats reads docker stats until ctx is cancelled. The
+// container may not have been created when sampling starts, so docker stats
+// is retried until it is.
+func sampleContainerStats(ctx context.Context, container string, stats *usageStats) {
+	for ctx.Err() == nil {
+		cmd := dockerStatsCommand(ctx, container)
+		stdout, err := cmd.StdoutPipe()
+		if err == nil && cmd.Start() == nil {
+			readDockerStats(stdout, stats)
+			cmd.Wait()
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// readDockerStats adds a sample for each line of JSON written by docker stats
+func readDockerStats(r io.Reader, stats *usageStats) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		cpu, memory, err := parseDockerStatsLine(scanner.Text())
+		if err != nil {
+			continue
+		}
+		stats.add(cpu, memory)
+	}
+}
+
+func parseDockerStatsLine(line string) (float64, float64, error) {
+	line = strings.TrimSpace(terminalEscapes.ReplaceAllString(line, ""))
+
+	var sample struct {
+		CPUPerc  string `json:"CPUPerc"`
+		MemUsage string `json:"MemUsage"`
+	}
+	if err := json.Unmarshal([]byte(line), &sample); err != nil {
+		return 0, 0, err
+	}
+
+	// Stats for a container which is starting or stopping are shown as --
+	cpu, err := strconv.ParseFloat(strings.TrimSuffix(sample.CPUPerc, "%"), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid CPU usage %q", sample.CPUPerc)
+	}
+
+	used, _, _ := strings.Cut(sample.MemUsage, "/")
+	memory, err := parseDockerSize(strings.TrimSpace(used))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return cpu, memory, nil
+}
+
+// parseDockerSize reads the sizes printed by docker, such as 12.5MiB or 3kB
+func parseDockerSize(size string) (float64, error) {
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"kB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"B", 1},
+	}
+
+	for _, unit := range units {
+		if strings.HasSuffix(size, unit.suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(size, unit.suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid size %q", size)
+			}
+			return value * unit.multiplier, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size %q", size)
+}
+
+// runWithStats waits for the container started by cmd while sampling its
+// usage, which is printed every live.interval and once it exits. An interrupt from the terminal also
+// reaches docker, so faas-cli keeps running to print the usage.
+func runWithStats(ctx context.Context, cmd *exec.Cmd, name string, w io.Writer, live liveStats) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGTERM {
+				cmd.Process.Signal(sig)
+			}
+		}
+	}()
+
+	stats := &usageStats{}
+	statsCtx, stopStats := context.WithCancel(ctx)
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		sampleContainerStats(statsCtx, localRunContainerName(name), stats)
+	}()
+
+	printed := make(chan struct{})
+	go func() {
+		defer close(printed)
+		if live.interval > 0 {
+			printLiveStats(statsCtx, name, stats, live)
+		}
+	}()
+
+	err := cmd.Wait()
+
+	stopStats()
+	<-sampled
+	<-printed
+	printUsageStats(w, name, stats)
+
+	return err
+}
+
+// printUsageStats prints the peak and average usage, with a memory limit for
+// the stack file which leaves some headroom above the peak, and requests from
+// the average usage
+func printUsageStats(w io.Writer, name string, stats *usageStats) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	if stats.samples == 0 {
+		fmt.Fprintf(w, "\nNo usage was sampled for %s, it may have exited before docker stats could read it\n", name)
+		return
+	}
+
+	samples := float64(stats.samples)
+	fmt.Fprintf(w, "\nUsage of %s from %d samples:\n", name, stats.samples)
+
+	table := output.NewTable("", "PEAK", "AVERAGE")
+	table.Row("CPU", fmt.Sprintf("%.1f%%", stats.cpuPeak), fmt.Sprintf("%.1f%%", stats.cpuTotal/samples))
+	table.Row("Memory", output.Size(stats.memPeak), output.Size(stats.memTotal/samples))
+	table.Write(w)
+
+	memoryLimit := suggestedMemory(stats.memPeak)
+	memoryRequest := suggestedMemory(stats.memTotal / samples)
+	fmt.Fprintf(w, `
+Suggested resources for %s in stack.yml, based on these samples:
+
+    limits:
+      memory: %s
+    requests:
+      memory: %s
+      cpu: %s
+`, name, memoryLimit, memoryRequest, suggestedCPU(stats.cpuTotal/samples))
+}
+
+// suggestedMemory adds headroom to a number of bytes, rounded up to 16Mi
+func suggestedMemory(bytes float64) string {
+	memory := math.Ceil(bytes*statsMemoryHeadroom/statsMemoryStep) * statsMemoryStep
+	if memory == 0 {
+		memory = statsMemoryStep
+	}
+	return fmt.Sprintf("%dMi", int64(memory)/(1024*1024))
+}
+
+// suggestedCPU converts a percentage of one core to millicores, rounded up
+// to 10m
+func suggestedCPU(percent float64) string {
+	// 100% is 1000m, so rounding the percentage up gives a multiple of 10m
+	millicores := math.Ceil(percent) * 10
+	if millicores < 10 {
+		millicores = 10
+	}
+	return fmt.Sprintf("%dm", int64(millicores))
+}
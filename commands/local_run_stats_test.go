@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/output"
+)
+
+func Test_parseDockerStatsLine(t *testing.T) {
+	cases := []struct {
+		name       string
+		line       string
+		wantCPU    float64
+		wantMemory float64
+		wantErr    bool
+	}{
+		{
+			name:       "binary units",
+			line:       `{"CPUPerc":"12.50%","MemUsage":"64MiB / 7.7GiB","Name":"openfaas-local-fn"}`,
+			wantCPU:    12.5,
+			wantMemory: 64 * 1024 * 1024,
+		},
+		{
+			name:       "decimal units after a redraw",
+			line:       "\x1b[2J\x1b[H" + `{"CPUPerc":"0.00%","MemUsage":"1.5kB / 1GB"}`,
+			wantCPU:    0,
+			wantMemory: 1500,
+		},
+		{
+			name:    "container which is starting",
+			line:    `{"CPUPerc":"--","MemUsage":"-- / --"}`,
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			line:    "Error response from daemon: No such container",
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cpu, memory, err := parseDockerStatsLine(c.line)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if cpu != c.wantCPU || memory != c.wantMemory {
+				t.Errorf("want cpu %v memory %v, got cpu %v memory %v", c.wantCPU, c.wantMemory, cpu, memory)
+			}
+		})
+	}
+}
+
+func Test_sampleContainerStats(t *testing.T) {
+	lines := `{"CPUPerc":"10.00%","MemUsage":"40MiB / 1GiB"}
+{"CPUPerc":"30.00%","MemUsage":"100MiB / 1GiB"}
+{"CPUPerc":"--","MemUsage":"-- / --"}
+`
+	defer func(previous func(context.Context, string) *exec.Cmd) { dockerStatsCommand = previous }(dockerStatsCommand)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dockerStatsCommand = func(_ context.Context, container string) *exec.Cmd {
+		if container != "openfaas-local-stronghash" {
+			t.Errorf("want stats for the local-run container, got %s", container)
+		}
+		// Stop after the first read, as docker stats would when the container exits
+		defer cancel()
+		cmd := exec.Command("cat")
+		cmd.Stdin = strings.NewReader(lines)
+		return cmd
+	}
+
+	stats := &usageStats{}
+	sampleContainerStats(ctx, localRunContainerName("stronghash"), stats)
+
+	var out bytes.Buffer
+	printUsageStats(&out, "stronghash", stats)
+
+	for _, want := range []string{
+		"from 2 samples",
+		"CPU     30.0%      20.0%",
+		"Memory  100.0 MiB  70.0 MiB",
+		"memory: 128Mi",
+		"memory: 96Mi",
+		"cpu: 200m",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func Test_printUsageStats_NoSamples(t *testing.T) {
+	var out bytes.Buffer
+	printUsageStats(&out, "stronghash", &usageStats{})
+
+	if !strings.Contains(out.String(), "No usage was sampled") {
+		t.Errorf("want a note that nothing was sampled, got: %s", out.String())
+	}
+}
+
+func Test_suggestedResources(t *testing.T) {
+	if got := suggestedMemory(0); got != "16Mi" {
+		t.Errorf("want at least 16Mi, got %s", got)
+	}
+	if got := suggestedMemory(200 * 1024 * 1024); got != "256Mi" {
+		t.Errorf("want 256Mi, got %s", got)
+	}
+	if got := suggestedCPU(0.2); got != "10m" {
+		t.Errorf("want at least 10m, got %s", got)
+	}
+	if got := suggestedCPU(150); got != "1500m" {
+		t.Errorf("want 1500m, got %s", got)
+	}
+}
+
+func Test_liveStatsLine(t *testing.T) {
+	mib := float64(1024 * 1024)
+	cases := []struct {
+		name        string
+		memory      float64
+		memoryLimit string
+		want        string
+	}{
+		{
+			name:   "without a limit",
+			memory: 64 * mib,
+			want:   "Usage of stronghash: CPU 12.5%, memory 64.0 MiB",
+		},
+		{
+			name:        "below the limit",
+			memory:      64 * mib,
+			memoryLimit: "128Mi",
+			want:        "Usage of stronghash: CPU 12.5%, memory 64.0 MiB, 50% of its 128Mi limit",
+		},
+		{
+			name:        "close to the limit",
+			memory:      120 * mib,
+			memoryLimit: "128Mi",
+			want:        output.Warning("Usage of stronghash: CPU 12.5%, memory 120.0 MiB, 94% of its 128Mi limit"),
+		},
+		{
+			name:        "limit which can't be read",
+			memory:      64 * mib,
+			memoryLimit: "lots",
+			want:        "Usage of stronghash: CPU 12.5%, memory 64.0 MiB",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := liveStatsLine("stronghash", 12.5, c.memory, c.memoryLimit); got != c.want {
+				t.Errorf("want %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func Test_usageStats_latest(t *testing.T) {
+	stats := &usageStats{}
+	if _, _, ok := stats.latest(); ok {
+		t.Fatalf("want no sample before one is added")
+	}
+
+	stats.add(10, 100)
+	stats.add(20, 200)
+	cpu, memory, ok := stats.latest()
+	if !ok || cpu != 20 || memory != 200 {
+		t.Errorf("want the last sample, got cpu %v memory %v ok %v", cpu, memory, ok)
+	}
+	if _, _, ok := stats.latest(); ok {
+		t.Errorf("want the sample to be returned once")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// localRunStopGrace is how long a function has to exit once it is stopped,
+// as the watchdog waits for in-flight requests to complete
+const localRunStopGrace = 10 * time.Second
+
+// stopLocalRunContainer stops the function's container, which is killed once
+// the grace period has passed, and is removed as it was started with --rm
+var stopLocalRunContainer = func(name string, grace time.Duration) error {
+	seconds := strconv.Itoa(int(math.Ceil(grace.Seconds())))
+	return exec.Command(containerRuntime(), "stop", "--time", seconds, localRunContainerName(name)).Run()
+}
+
+// removeLocalRunContainer force-removes the function's container, when docker
+// run exited before it could be removed. It is not an error when the
+// container is already gone, so the result is ignored.
+var removeLocalRunContainer = func(name string) {
+	exec.Command(containerRuntime(), "rm", "--force", localRunContainerName(name)).Run()
+}
+
+// notifyLocalRunSignals and stopLocalRunSignals are replaced by tests
+var (
+	notifyLocalRunSignals = func(c chan<- os.Signal) { signal.Notify(c, os.Interrupt, syscall.SIGTERM) }
+	stopLocalRunSignals   = func(c chan<- os.Signal) { signal.Stop(c) }
+)
+
+// teardownOnSignal traps Control+C and SIGTERM while the functions run in the
+// foreground. docker run can exit on an interrupt before the container has
+// been stopped, so each one is stopped with a grace period instead, and a
+// second signal removes them straight away. The returned func must be called
+// once they have exited, it force-removes any container which is left, after
+// waiting for the stop when docker run exited before it.
+func teardownOnSignal(names []string, opts runOptions) func() {
+	signals := make(chan os.Signal, 2)
+	notifyLocalRunSignals(signals)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+
+		fmt.Fprintf(opts.err, "\nStopping %s, waiting up to %s for it to exit, press Control+C again to remove it now\n",
+			strings.Join(names, ", "), opts.stopGrace)
+
+		stopped := make(chan struct{})
+		go func() {
+			var wg sync.WaitGroup
+			for _, name := range names {
+				wg.Add(1)
+				go func(name string) {
+					defer wg.Done()
+					stopLocalRunContainer(name, opts.stopGrace)
+				}(name)
+			}
+			wg.Wait()
+			close(stopped)
+		}()
+
+		// The wait goes on once docker run has exited, so that the function
+		// still has its grace period
+		select {
+		case <-stopped:
+		case <-signals:
+			for _, name := range names {
+				removeLocalRunContainer(name)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+		stopLocalRunSignals(signals)
+
+		for _, name := range names {
+			removeLocalRunContainer(name)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func stubLocalRunTeardown(t *testing.T, stop func(name string, grace time.Duration) error) (chan chan<- os.Signal, *[]string) {
+	t.Helper()
+
+	notify, release, stopContainer, remove := notifyLocalRunSignals, stopLocalRunSignals, stopLocalRunContainer, removeLocalRunContainer
+	t.Cleanup(func() {
+		notifyLocalRunSignals, stopLocalRunSignals, stopLocalRunContainer, removeLocalRunContainer = notify, release, stopContainer, remove
+	})
+
+	trapped := make(chan chan<- os.Signal, 1)
+	notifyLocalRunSignals = func(c chan<- os.Signal) { trapped <- c }
+	stopLocalRunSignals = func(c chan<- os.Signal) {}
+	stopLocalRunContainer = stop
+
+	var mu sync.Mutex
+	removed := []string{}
+	removeLocalRunContainer = func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		removed = append(removed, name)
+	}
+	return trapped, &removed
+}
+
+func Test_teardownOnSignal_StopsWithGracePeriod(t *testing.T) {
+	var mu sync.Mutex
+	stopped := map[string]time.Duration{}
+	trapped, removed := stubLocalRunTeardown(t, func(name string, grace time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped[name] = grace
+		return nil
+	})
+
+	var errOut bytes.Buffer
+	opts := runOptions{stopGrace: 3 * time.Second, err: &errOut}
+	cleanup := teardownOnSignal([]string{"fn1", "fn2"}, opts)
+
+	signals := <-trapped
+	signals <- os.Interrupt
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(stopped)
+		mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cleanup()
+
+	for _, name := range []string{"fn1", "fn2"} {
+		if grace, ok := stopped[name]; !ok || grace != 3*time.Second {
+			t.Errorf("want %s stopped with a grace period of 3s, got: %v", name, stopped)
+		}
+	}
+
+	sort.Strings(*removed)
+	if strings.Join(*removed, ",") != "fn1,fn2" {
+		t.Errorf("want both containers removed on exit, got: %v", *removed)
+	}
+	if !strings.Contains(errOut.String(), "Stopping fn1, fn2, waiting up to 3s") {
+		t.Errorf("want the grace period printed, got: %q", errOut.String())
+	}
+}
+
+func Test_teardownOnSignal_SecondSignalRemoves(t *testing.T) {
+	entered, unblock, returned := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	trapped, removed := stubLocalRunTeardown(t, func(name string, grace time.Duration) error {
+		close(entered)
+		<-unblock
+		close(returned)
+		return nil
+	})
+	defer func() {
+		close(unblock)
+		<-returned
+	}()
+
+	var errOut bytes.Buffer
+	cleanup := teardownOnSignal([]string{"fn1"}, runOptions{stopGrace: time.Minute, err: &errOut})
+
+	signals := <-trapped
+	signals <- os.Interrupt
+	<-entered
+	signals <- os.Interrupt
+
+	done := make(chan struct{})
+	go func() {
+		cleanup()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("want the second signal to end the wait for the grace period")
+	}
+
+	// Removed by the second signal, then again on exit
+	if len(*removed) != 2 {
+		t.Errorf("want fn1 removed twice, got: %v", *removed)
+	}
+}
+
+func Test_teardownOnSignal_RemovesWithoutSignal(t *testing.T) {
+	_, removed := stubLocalRunTeardown(t, func(name string, grace time.Duration) error {
+		t.Errorf("want no stop without a signal, got: %s", name)
+		return nil
+	})
+
+	teardownOnSignal([]string{"fn1"}, runOptions{stopGrace: time.Second, err: &bytes.Buffer{}})()
+
+	if len(*removed) != 1 || (*removed)[0] != "fn1" {
+		t.Errorf("want fn1 removed on exit, got: %v", *removed)
+	}
+}
+
+func Test_teardownOnSignal_CleanupWaitsForStop(t *testing.T) {
+	entered, unblock := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	stopReturned := false
+	trapped, removed := stubLocalRunTeardown(t, func(name string, grace time.Duration) error {
+		close(entered)
+		<-unblock
+		mu.Lock()
+		defer mu.Unlock()
+		stopReturned = true
+		return nil
+	})
+	removeLocalRunContainer = func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !stopReturned {
+			t.Errorf("want %s removed only once it has been stopped", name)
+		}
+		*removed = append(*removed, name)
+	}
+
+	cleanup := teardownOnSignal([]string{"fn1"}, runOptions{stopGrace: time.Minute, err: &bytes.Buffer{}})
+
+	signals := <-trapped
+	signals <- os.Interrupt
+	<-entered
+
+	// docker run has exited before the container was stopped
+	done := make(chan struct{})
+	go func() {
+		cleanup()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("want the cleanup to wait for the grace period")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(unblock)
+	<-done
+
+	if len(*removed) != 1 {
+		t.Errorf("want fn1 removed once on exit, got: %v", *removed)
+	}
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_buildDockerRun_Overrides(t *testing.T) {
+	fnc := stack.Function{
+		Name:     "stronghash",
+		Image:    "stronghash:latest",
+		Language: "dockerfile",
+		FProcess: "./handler",
+	}
+
+	opts := runOptions{
+		port:     8081,
+		fprocess: "dlv exec ./handler",
+		workdir:  "/home/app/function",
+	}
+
+	cmd, err := buildDockerRun(context.Background(), fnc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := strings.Join(cmd.Args, " ")
+	for _, want := range []string{"-p=8081:8080", "--workdir=/home/app/function", "-e=fprocess=dlv exec ./handler"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("want %q in: %s", want, args)
+		}
+	}
+	if strings.Contains(args, "fprocess=./handler") {
+		t.Errorf("--fprocess should take precedence over the stack file, got: %s", args)
+	}
+}
+
+func Test_buildDockerRun_DefaultFprocess(t *testing.T) {
+	fnc := stack.Function{
+		Name:     "stronghash",
+		Image:    "stronghash:latest",
+		Language: "dockerfile",
+		FProcess: "./handler",
+	}
+
+	cmd, err := buildDockerRun(context.Background(), fnc, runOptions{port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := strings.Join(cmd.Args, " ")
+	if !strings.Contains(args, "-e=fprocess=./handler") || strings.Contains(args, "--workdir") {
+		t.Errorf("unexpected command: %s", args)
+	}
+}
+
+func Test_handlerMount(t *testing.T) {
+	templatePath := filepath.Join("template", "mount-handler-test")
+	if err := os.MkdirAll(templatePath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.RemoveAll(templatePath)
+		os.Remove("template")
+	}()
+
+	dockerfiles := map[string]string{
+		"interpreted": `FROM python:3-alpine
+WORKDIR /home/app/
+COPY function function
+`,
+		"compiled": `FROM golang:1.20 as build
+WORKDIR /go/src/handler
+COPY function function
+RUN go build -o /handler .
+
+FROM alpine:3.18
+COPY --from=build /handler /usr/bin/handler
+`,
+	}
+
+	handler := t.TempDir()
+	fnc := stack.Function{Name: "stronghash", Language: "mount-handler-test", Handler: handler}
+
+	for name, dockerfile := range dockerfiles {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(templatePath, "template.yml"), []byte("language: python\nfprocess: python index.py\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(templatePath, "Dockerfile"), []byte(dockerfile), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			hostPath, containerPath, err := handlerMount(fnc)
+			if name == "compiled" {
+				if err == nil {
+					t.Fatalf("want an error for a compiled template")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hostPath != handler || containerPath != "/home/app/function" {
+				t.Fatalf("want %s:/home/app/function, got %s:%s", handler, hostPath, containerPath)
+			}
+		})
+	}
+}
+
+func Test_planDockerRun_Volumes(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := filepath.Join(dir, "fixtures")
+	if err := os.Mkdir(fixtures, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fnc := stack.Function{Name: "stronghash", Image: "stronghash:latest", Language: "dockerfile", FProcess: "./handler"}
+	opts := runOptions{port: 8080, volumes: []string{fixtures + ":/fixtures:ro", dir + ":/tmp/out"}}
+
+	plan, err := planDockerRun(fnc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := strings.Join(plan.args(), " ")
+	for _, want := range []string{"--volume=" + fixtures + ":/fixtures:ro", "--volume=" + dir + ":/tmp/out"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("want %q in: %s", want, args)
+		}
+	}
+
+	opts.volumes = []string{filepath.Join(dir, "missing") + ":/missing"}
+	if _, err := planDockerRun(fnc, opts); err == nil || !strings.Contains(err.Error(), "can't mount --volume") {
+		t.Fatalf("want an error for a missing source, got: %v", err)
+	}
+}
+
+func Test_planDockerRun_GPUs(t *testing.T) {
+	fnc := stack.Function{
+		Name:     "classify",
+		Image:    "classify:latest",
+		Language: "dockerfile",
+		FProcess: "./handler",
+		Limits:   &stack.FunctionResources{GPU: "1"},
+	}
+
+	plan, err := planDockerRun(fnc, runOptions{port: 8080})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(plan.args(), " "); !strings.Contains(args, "--gpus=1") || strings.Contains(args, "--memory-reservation") {
+		t.Errorf("want the GPU from the stack file's limits, got: %s", args)
+	}
+
+	plan, err = planDockerRun(fnc, runOptions{port: 8080, gpus: "all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.Join(plan.args(), " "); !strings.Contains(args, "--gpus=all") {
+		t.Errorf("want --gpus to take precedence, got: %s", args)
+	}
+}
+
+func Test_parseLocalRunVolume(t *testing.T) {
+	mount, err := parseLocalRunVolume("fixtures:/fixtures:ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	if mount.Source != filepath.Join(wd, "fixtures") || mount.Target != "/fixtures" || !mount.ReadOnly {
+		t.Fatalf("unexpected mount: %+v", mount)
+	}
+
+	for _, volume := range []string{"fixtures", ":/fixtures", "fixtures:relative", "fixtures:/fixtures:rx", "a:/b:ro:z"} {
+		if _, err := parseLocalRunVolume(volume); err == nil {
+			t.Errorf("want an error for %q", volume)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/schema"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// watchInterval is how often the handler folder is checked for changes, and
+// how long it must be unchanged before a rebuild, so that an editor saving
+// several files only causes one
+var watchInterval = 500 * time.Millisecond
+
+// rebuildFunction builds the function's image as "faas-cli build" would
+var rebuildFunction = func(out io.Writer, fnc stack.Function, copyExtraPaths []string) error {
+	return builder.BuildImageWithOutput(out,
+		fnc.Image,
+		fnc.Handler,
+		fnc.Name,
+		fnc.Language,
+		false,
+		false,
+		false,
+		fnc.BuildArgs,
+		fnc.BuildOptions,
+		schema.DefaultFormat,
+		map[string]string{},
+		true,
+		copyExtraPaths,
+		"",
+		false,
+	)
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// handlerSnapshot records the size and modification time of each file in
+// the handler folder, hidden folders such as .git are skipped
+func handlerSnapshot(dir string) (map[string]fileState, error) {
+	snapshot := map[string]fileState{}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		snapshot[rel] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+
+	return snapshot, err
+}
+
+// changedFiles lists the files which were added, removed or modified
+func changedFiles(before, after map[string]fileState) []string {
+	changed := []string{}
+	for path, state := range after {
+		if previous, ok := before[path]; !ok || previous != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// watchFunction runs the function, and when its handler changes, rebuilds
+// the image and restarts the container. With --mount-handler the handler is
+// already in the container, so it is only restarted. A build which fails
+// leaves the last container running, until the next change.
+func watchFunction(fnc stack.Function, copyExtraPaths []string, opts runOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer removeLocalRunContainer(fnc.Name)
+
+	snapshot, err := handlerSnapshot(fnc.Handler)
+	if err != nil {
+		return fmt.Errorf("unable to watch the handler folder %q: %w", fnc.Handler, err)
+	}
+
+	fmt.Fprintf(opts.output, "Watching %s for changes, press Control+C to stop\n\n", fnc.Handler)
+
+	for {
+		exited, err := startWatchedContainer(fnc, opts)
+		if err != nil {
+			return err
+		}
+
+		changed := []string{}
+		for len(changed) == 0 {
+			select {
+			case <-ctx.Done():
+				if exited != nil {
+					stopLocalRunContainer(fnc.Name, opts.stopGrace)
+					<-exited
+				}
+				return nil
+			case err := <-exited:
+				if err != nil {
+					fmt.Fprintf(opts.err, "%s exited: %s, waiting for a change to restart it\n", fnc.Name, err)
+				} else {
+					fmt.Fprintf(opts.err, "%s exited, waiting for a change to restart it\n", fnc.Name)
+				}
+				exited = nil
+			case <-time.After(watchInterval):
+			}
+
+			next, err := handlerSnapshot(fnc.Handler)
+			if err != nil {
+				return fmt.Errorf("unable to watch the handler folder %q: %w", fnc.Handler, err)
+			}
+			changed = changedFiles(snapshot, next)
+			snapshot = next
+
+			if len(changed) > 0 {
+				settled, err := waitForChangesToSettle(ctx, fnc.Handler, snapshot)
+				if err != nil {
+					return err
+				}
+				snapshot = settled
+
+				// Stop on the next loop, rather than rebuilding
+				if ctx.Err() != nil {
+					changed = nil
+					continue
+				}
+			}
+
+			if len(changed) > 0 && !opts.mountHandler {
+				fmt.Fprintf(opts.output, "\nChanged: %s, rebuilding %s\n", strings.Join(changed, ", "), fnc.Name)
+				start := time.Now()
+				if err := rebuildFunction(opts.output, fnc, copyExtraPaths); err != nil {
+					fmt.Fprintf(opts.err, "Build failed, the last image is still running: %s\n", err)
+					changed = nil
+					continue
+				}
+				fmt.Fprintf(opts.output, "Rebuilt %s in %1.2fs\n", fnc.Name, time.Since(start).Seconds())
+			} else if len(changed) > 0 {
+				fmt.Fprintf(opts.output, "\nChanged: %s\n", strings.Join(changed, ", "))
+			}
+		}
+
+		if exited != nil {
+			stopLocalRunContainer(fnc.Name, opts.stopGrace)
+			<-exited
+		}
+		fmt.Fprintf(opts.output, "Restarting %s on: %s\n\n", fnc.Name, localRunURL(opts, opts.port))
+	}
+}
+
+// waitForChangesToSettle waits until the handler is unchanged for an interval
+func waitForChangesToSettle(ctx context.Context, dir string, snapshot map[string]fileState) (map[string]fileState, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return snapshot, nil
+		case <-time.After(watchInterval):
+		}
+
+		next, err := handlerSnapshot(dir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to watch the handler folder %q: %w", dir, err)
+		}
+		if len(changedFiles(snapshot, next)) == 0 {
+			return next, nil
+		}
+		snapshot = next
+	}
+}
+
+// startWatchedContainer starts the container, the channel receives the
+// result of docker run when it exits
+func startWatchedContainer(fnc stack.Function, opts runOptions) (chan error, error) {
+	plan, err := planDockerRun(fnc, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The container is stopped by the watcher, so must outlive a Control+C
+	// which cancels the command's context
+	if err := stageLocalRun(context.Background(), plan, opts.err); err != nil {
+		return nil, err
+	}
+	cmd := plan.command(context.Background())
+	logs := newLocalRunLogs(opts)
+	cmd.Stdout, cmd.Stderr = logs.streams(fnc.Name)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		logs.flush()
+		exited <- err
+	}()
+	return exited, nil
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_changedFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "handler.py"), []byte("one"), 0600)
+	os.WriteFile(filepath.Join(dir, "requirements.txt"), []byte(""), 0600)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0700)
+	os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0600)
+
+	before, err := handlerSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := before[filepath.Join(".git", "HEAD")]; ok {
+		t.Errorf("want hidden folders skipped, got: %v", before)
+	}
+
+	os.WriteFile(filepath.Join(dir, "handler.py"), []byte("two!"), 0600)
+	os.Remove(filepath.Join(dir, "requirements.txt"))
+	os.WriteFile(filepath.Join(dir, "util.py"), []byte(""), 0600)
+	os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref2"), 0600)
+
+	after, err := handlerSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := changedFiles(before, after)
+	want := []string{"handler.py", "requirements.txt", "util.py"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	if got := changedFiles(after, after); len(got) > 0 {
+		t.Errorf("want no changes, got: %v", got)
+	}
+}
+
+func Test_changedFiles_ModTime(t *testing.T) {
+	before := map[string]fileState{"handler.go": {size: 10, modTime: time.Unix(100, 0)}}
+	after := map[string]fileState{"handler.go": {size: 10, modTime: time.Unix(200, 0)}}
+
+	if got := changedFiles(before, after); !reflect.DeepEqual(got, []string{"handler.go"}) {
+		t.Fatalf("want a file saved with the same size to be changed, got: %v", got)
+	}
+}
+
+func Test_localRun_WatchWithDetach(t *testing.T) {
+	t.Setenv("OPENFAAS_EXPERIMENTAL", "1")
+	cmd := newLocalRunCmd()
+	if err := cmd.ParseFlags([]string{"--watch", "--detach"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cmd.PreRunE(cmd, []string{"stronghash"})
+	if err == nil || !strings.Contains(err.Error(), "--watch restarts one function") {
+		t.Fatalf("want --watch rejected with --detach, got: %v", err)
+	}
+}
@@ -18,9 +18,10 @@ import (
 )
 
 var (
-	username      string
-	password      string
-	passwordStdin bool
+	username       string
+	password       string
+	passwordStdin  bool
+	execCredential string
 )
 
 func init() {
@@ -28,6 +29,7 @@ func init() {
 	loginCmd.Flags().StringVarP(&username, "username", "u", "admin", "Gateway username")
 	loginCmd.Flags().StringVarP(&password, "password", "p", "", "Gateway password")
 	loginCmd.Flags().BoolVarP(&passwordStdin, "password-stdin", "s", false, "Reads the gateway password from stdin")
+	loginCmd.Flags().StringVar(&execCredential, "exec-credential", "", "A command which prints the credentials as JSON each time they are needed, instead of saving them")
 	loginCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
 	loginCmd.Flags().Duration("timeout", time.Second*5, "Override the timeout for this API call")
 
@@ -37,10 +39,19 @@ func init() {
 var loginCmd = &cobra.Command{
 	Use:   `login [--username admin|USERNAME] [--password PASSWORD] [--gateway GATEWAY_URL] [--tls-no-verify]`,
 	Short: "Log in to OpenFaaS gateway",
-	Long:  "Log in to OpenFaaS gateway.\nIf no gateway is specified, the default value will be used.",
+	Long: `Log in to OpenFaaS gateway.
+If no gateway is specified, the default value will be used.
+
+With --exec-credential, no credentials are saved. The command is saved instead
+and run whenever the gateway is called, so that short-lived credentials can be
+fetched from a vault. It must print JSON to stdout with either a "token", or a
+"username" and "password", and optionally an "expiresAt" time in RFC3339 until
+which the CLI reuses them. FAAS_CLI_CREDENTIAL_KIND is set to "gateway" and
+FAAS_CLI_CREDENTIAL_SERVER to the gateway's URL for the command.`,
 	Example: `  cat ~/faas_pass.txt | faas-cli login -u user --password-stdin
   echo $PASSWORD | faas-cli login -s  --gateway https://openfaas.mydomain.com
-  faas-cli login -u user -p password`,
+  faas-cli login -u user -p password
+  faas-cli login --exec-credential "vault-openfaas-creds --role dev"`,
 	RunE: runLogin,
 }
 
@@ -51,6 +62,13 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if len(execCredential) > 0 {
+		if len(password) > 0 || passwordStdin {
+			return fmt.Errorf("--exec-credential can't be used with --password or --password-stdin")
+		}
+		return runExecCredentialLogin(timeout)
+	}
+
 	if len(username) == 0 {
 		return fmt.Errorf("must provide --username or -u")
 	}
@@ -111,7 +129,47 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runExecCredentialLogin checks what the command prints against the gateway,
+// then saves the command in place of the credentials
+func runExecCredentialLogin(timeout time.Duration) error {
+	fields := strings.Fields(execCredential)
+	if len(fields) == 0 {
+		return fmt.Errorf("give the command to run with --exec-credential")
+	}
+	execConfig := config.ExecConfig{Command: fields[0], Args: fields[1:]}
+
+	gateway = getGatewayURL(gateway, defaultGateway, "", os.Getenv(openFaaSURLEnvironment))
+
+	credential, err := proxy.RunExecCredential(execConfig, proxy.ExecCredentialGateway, gateway)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Calling the OpenFaaS server to validate the credentials...")
+
+	if err := validateLoginAuth(gateway, credential.ClientAuth().Set, timeout, tlsInsecure); err != nil {
+		return err
+	}
+
+	if err := config.UpdateExecAuthConfig(gateway, execConfig); err != nil {
+		return err
+	}
+
+	fmt.Println("credentials will be fetched for", gateway, "by:", execConfig.String())
+	return nil
+}
+
 func validateLogin(gatewayURL string, user string, pass string, timeout time.Duration, insecureTLS bool) error {
+	setAuth := func(req *http.Request) error {
+		req.SetBasicAuth(user, pass)
+		return nil
+	}
+	return validateLoginAuth(gatewayURL, setAuth, timeout, insecureTLS)
+}
+
+// validateLoginAuth calls the gateway with the authentication which setAuth
+// adds to the request
+func validateLoginAuth(gatewayURL string, setAuth func(*http.Request) error, timeout time.Duration, insecureTLS bool) error {
 
 	if len(checkTLSInsecure(gatewayURL, insecureTLS)) > 0 {
 		fmt.Println(NoTLSWarn)
@@ -123,7 +181,9 @@ func validateLogin(gatewayURL string, user string, pass string, timeout time.Dur
 		return fmt.Errorf("invalid URL: %s", gatewayURL)
 	}
 
-	req.SetBasicAuth(user, pass)
+	if err := setAuth(req); err != nil {
+		return err
+	}
 	res, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s. %v", gatewayURL, err)
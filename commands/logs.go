@@ -5,7 +5,6 @@ package commands
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
@@ -35,6 +34,7 @@ type logFlags struct {
 	includeName     bool
 	includeInstance bool
 	timeFormat      flags.TimeFormat
+	pageSize        int
 }
 
 func init() {
@@ -52,6 +52,7 @@ var functionLogsCmd = &cobra.Command{
   faas-cli logs FN --lines=5
   faas-cli logs FN --tail=false --since=10m
   faas-cli logs FN --tail=false --since=2010-01-01T00:00:00Z
+  faas-cli logs FN --tail=false --since=24h --lines=100000
 `,
 	Args:    cobra.MaximumNArgs(1),
 	RunE:    runLogs,
@@ -79,6 +80,7 @@ func initLogCmdFlags(cmd *cobra.Command) {
 	cmd.Flags().Var(&logFlagValues.sinceTime, "since-time", "include logs since the given timestamp (RFC3339)")
 	cmd.Flags().IntVar(&logFlagValues.lines, "lines", -1, "number of recent log lines file to display. Defaults to -1, unlimited if <=0")
 	cmd.Flags().BoolVarP(&logFlagValues.tail, "tail", "t", true, "tail logs and continue printing new logs until the end of the request, up to 30s")
+	cmd.Flags().IntVar(&logFlagValues.pageSize, "page-size", defaultLogPageSize, "maximum number of messages to fetch per request when reading logs since a time, 0 fetches them in one request")
 	cmd.Flags().StringVarP(&logFlagValues.token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
 
 	logFlagValues.timeFormat = flags.TimeFormat(time.RFC3339)
@@ -106,14 +108,32 @@ func runLogs(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	formatter := GetLogFormatter(string(logFlagValues.logFormat))
+	printMessage := func(logMsg logs.Message) {
+		fmt.Fprintln(os.Stdout, formatter(logMsg, logFlagValues.timeFormat.String(), logFlagValues.includeName, logFlagValues.includeInstance))
+	}
+
+	// A long backfill is read in pages, so that each request completes within
+	// the gateway's timeouts, rather than being cut off part way through
+	if logRequest.Since != nil && logFlagValues.pageSize > 0 {
+		pager := logPager{source: cliClient, pageSize: logFlagValues.pageSize, warnings: os.Stderr}
+		return pager.fetch(context.Background(), logRequest, printMessage)
+	}
+
 	logEvents, err := cliClient.GetLogs(context.Background(), logRequest)
 	if err != nil {
 		return err
 	}
 
-	formatter := GetLogFormatter(string(logFlagValues.logFormat))
+	received := 0
 	for logMsg := range logEvents {
-		fmt.Fprintln(os.Stdout, formatter(logMsg, logFlagValues.timeFormat.String(), logFlagValues.includeName, logFlagValues.includeInstance))
+		received++
+		printMessage(logMsg)
+	}
+
+	if !logRequest.Follow && logRequest.Tail > received && logFlagValues.pageSize > 0 && received >= logFlagValues.pageSize {
+		fmt.Fprintf(os.Stderr, "Received %d of %d lines, the provider may have limited the request, use --since to read the logs in pages\n",
+			received, logRequest.Tail)
 	}
 
 	return nil
@@ -147,14 +167,8 @@ func sinceValue(t time.Time, d time.Duration) *time.Time {
 	return nil
 }
 
+// getLogStreamingTransport has the proxy and CA bundle saved for the gateway,
+// and no timeout, so that following the logs is not cut off
 func getLogStreamingTransport(tlsInsecure bool) http.RoundTripper {
-	if tlsInsecure {
-		tr := &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-		}
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: tlsInsecure}
-
-		return tr
-	}
-	return nil
+	return proxy.StreamingTransport(tlsInsecure)
 }
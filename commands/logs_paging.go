@@ -0,0 +1,150 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/openfaas/faas-provider/logs"
+)
+
+// defaultLogPageSize keeps each request short enough to finish within the
+// gateway's timeouts, which otherwise cut off a long backfill without an error
+const defaultLogPageSize = 5000
+
+// logSource opens a stream of logs, it is satisfied by *proxy.Client
+type logSource interface {
+	GetLogs(ctx context.Context, params logs.Request) (<-chan logs.Message, error)
+}
+
+// logPager fetches the logs since a point in time as a series of requests,
+// each one starting from the timestamp of the last message received.
+//
+// Providers differ in which parameters they honour, so messages older than
+// the requested since are dropped, and when more than --lines are received,
+// only the most recent are kept, as if the provider had applied tail itself.
+type logPager struct {
+	source   logSource
+	pageSize int
+	warnings io.Writer
+}
+
+func (p logPager) fetch(ctx context.Context, req logs.Request, emit func(logs.Message)) error {
+	var recent []logs.Message
+	keep := func(msg logs.Message) {
+		if req.Tail <= 0 {
+			emit(msg)
+			return
+		}
+		recent = append(recent, msg)
+		if len(recent) > req.Tail {
+			recent = recent[1:]
+		}
+	}
+
+	c := logCursor{since: *req.Since, seen: map[string]bool{}}
+
+	for {
+		since := c.since
+		pageReq := req
+		pageReq.Tail = 0
+		pageReq.Follow = false
+		pageReq.Since = &since
+
+		received, added, err := p.read(ctx, pageReq, p.pageSize, &c, keep)
+		if err != nil {
+			return err
+		}
+
+		if received < p.pageSize {
+			break
+		}
+		if added == 0 {
+			fmt.Fprintf(p.warnings, "Unable to page past %s, more than %d messages share the same timestamp, try a larger --page-size\n",
+				c.since.Format(time.RFC3339Nano), p.pageSize)
+			break
+		}
+	}
+
+	for _, msg := range recent {
+		emit(msg)
+	}
+
+	if !req.Follow {
+		return nil
+	}
+
+	since := c.since
+	followReq := req
+	followReq.Tail = 0
+	followReq.Since = &since
+	_, _, err := p.read(ctx, followReq, 0, &c, emit)
+	return err
+}
+
+// logCursor is the timestamp to request the next page from, the messages
+// at that timestamp have been emitted already and are returned again
+type logCursor struct {
+	since        time.Time
+	seen         map[string]bool
+	ignoredSince bool
+}
+
+// read consumes up to limit messages of a request, or all of them when limit
+// is 0, and moves the cursor to the last message. It returns the number of
+// messages received, and how many of those were new.
+func (p logPager) read(ctx context.Context, req logs.Request, limit int, c *logCursor, emit func(logs.Message)) (int, int, error) {
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := p.source.GetLogs(readCtx, req)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	from := c.since
+	received, added := 0, 0
+	for msg := range stream {
+		received++
+
+		key := logMessageKey(msg)
+		switch {
+		case msg.Timestamp.Before(from):
+			if !c.ignoredSince {
+				fmt.Fprintf(p.warnings, "The provider returned logs from before %s, they are being filtered by faas-cli\n", from.Format(time.RFC3339))
+				c.ignoredSince = true
+			}
+		case msg.Timestamp.Equal(from) && c.seen[key]:
+		default:
+			emit(msg)
+			added++
+
+			if msg.Timestamp.After(c.since) {
+				c.since = msg.Timestamp
+				c.seen = map[string]bool{}
+			}
+			if msg.Timestamp.Equal(c.since) {
+				c.seen[key] = true
+			}
+		}
+
+		if limit > 0 && received >= limit {
+			break
+		}
+	}
+
+	// Stop the request, then drain the stream so that its reader can exit
+	cancel()
+	for range stream {
+	}
+
+	return received, added, nil
+}
+
+func logMessageKey(msg logs.Message) string {
+	return msg.Instance + "\x00" + msg.Timestamp.Format(time.RFC3339Nano) + "\x00" + msg.Text
+}
@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-provider/logs"
+)
+
+// fakeLogSource serves messages since the requested time, optionally ignoring
+// since, as some providers do
+type fakeLogSource struct {
+	messages    []logs.Message
+	ignoreSince bool
+	requests    []logs.Request
+}
+
+func (f *fakeLogSource) GetLogs(ctx context.Context, req logs.Request) (<-chan logs.Message, error) {
+	f.requests = append(f.requests, req)
+
+	stream := make(chan logs.Message)
+	go func() {
+		defer close(stream)
+		for _, msg := range f.messages {
+			if !f.ignoreSince && msg.Timestamp.Before(*req.Since) {
+				continue
+			}
+			select {
+			case stream <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return stream, nil
+}
+
+func fakeMessages(start time.Time, count int, perTimestamp int) []logs.Message {
+	var messages []logs.Message
+	for i := 0; i < count; i++ {
+		messages = append(messages, logs.Message{
+			Name:      "fn",
+			Instance:  "fn-1",
+			Timestamp: start.Add(time.Duration(i/perTimestamp) * time.Millisecond),
+			Text:      fmt.Sprintf("line %d", i),
+		})
+	}
+	return messages
+}
+
+func fetchTexts(t *testing.T, pager logPager, req logs.Request) []string {
+	t.Helper()
+
+	var texts []string
+	err := pager.fetch(context.Background(), req, func(msg logs.Message) {
+		texts = append(texts, msg.Text)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return texts
+}
+
+func Test_logPager_fetch(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		messages     []logs.Message
+		ignoreSince  bool
+		pageSize     int
+		tail         int
+		wantFirst    string
+		wantLast     string
+		wantCount    int
+		wantRequests int
+		wantWarning  string
+	}{
+		{
+			name:         "reads every message across pages",
+			messages:     fakeMessages(start, 25, 1),
+			pageSize:     10,
+			tail:         -1,
+			wantFirst:    "line 0",
+			wantLast:     "line 24",
+			wantCount:    25,
+			wantRequests: 3,
+		},
+		{
+			name:         "messages sharing a timestamp are not repeated",
+			messages:     fakeMessages(start, 25, 3),
+			pageSize:     10,
+			tail:         -1,
+			wantFirst:    "line 0",
+			wantLast:     "line 24",
+			wantCount:    25,
+			wantRequests: 3,
+		},
+		{
+			name:         "lines keeps the most recent messages",
+			messages:     fakeMessages(start, 25, 1),
+			pageSize:     10,
+			tail:         5,
+			wantFirst:    "line 20",
+			wantLast:     "line 24",
+			wantCount:    5,
+			wantRequests: 3,
+		},
+		{
+			name:         "messages before since are filtered when the provider ignores it",
+			messages:     fakeMessages(start.Add(-5*time.Millisecond), 10, 1),
+			ignoreSince:  true,
+			pageSize:     100,
+			tail:         -1,
+			wantFirst:    "line 5",
+			wantLast:     "line 9",
+			wantCount:    5,
+			wantRequests: 1,
+			wantWarning:  "The provider returned logs from before",
+		},
+		{
+			name:         "stops when a page makes no progress",
+			messages:     fakeMessages(start, 20, 20),
+			pageSize:     5,
+			tail:         -1,
+			wantFirst:    "line 0",
+			wantLast:     "line 4",
+			wantCount:    5,
+			wantRequests: 2,
+			wantWarning:  "try a larger --page-size",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			source := &fakeLogSource{messages: c.messages, ignoreSince: c.ignoreSince}
+			warnings := &bytes.Buffer{}
+			pager := logPager{source: source, pageSize: c.pageSize, warnings: warnings}
+
+			texts := fetchTexts(t, pager, logs.Request{Name: "fn", Tail: c.tail, Since: &start})
+
+			if len(texts) != c.wantCount {
+				t.Fatalf("want %d messages, got %d: %v", c.wantCount, len(texts), texts)
+			}
+			if texts[0] != c.wantFirst || texts[len(texts)-1] != c.wantLast {
+				t.Errorf("want messages from %q to %q, got %q to %q", c.wantFirst, c.wantLast, texts[0], texts[len(texts)-1])
+			}
+			if len(source.requests) != c.wantRequests {
+				t.Errorf("want %d requests, got %d", c.wantRequests, len(source.requests))
+			}
+			for _, req := range source.requests {
+				if req.Tail != 0 || req.Follow {
+					t.Errorf("pages should not set tail or follow, got %s", req)
+				}
+			}
+			if c.wantWarning == "" && warnings.Len() > 0 {
+				t.Errorf("want no warnings, got %q", warnings.String())
+			}
+			if !strings.Contains(warnings.String(), c.wantWarning) {
+				t.Errorf("want warning %q, got %q", c.wantWarning, warnings.String())
+			}
+		})
+	}
+}
+
+func Test_logPager_fetch_Follow(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &fakeLogSource{messages: fakeMessages(start, 12, 1)}
+	pager := logPager{source: source, pageSize: 5, warnings: &bytes.Buffer{}}
+
+	texts := fetchTexts(t, pager, logs.Request{Name: "fn", Tail: -1, Since: &start, Follow: true})
+
+	if len(texts) != 12 {
+		t.Fatalf("want 12 messages, got %d: %v", len(texts), texts)
+	}
+
+	last := source.requests[len(source.requests)-1]
+	if !last.Follow {
+		t.Errorf("want the final request to follow the logs")
+	}
+	if want := start.Add(11 * time.Millisecond); !last.Since.Equal(want) {
+		t.Errorf("want the final request to follow from %s, got %s", want, last.Since)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	types "github.com/openfaas/faas-provider/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// maintenanceImageAnnotation records the image that was deployed before
+	// maintenance mode was enabled, so that it can be restored afterwards.
+	maintenanceImageAnnotation = "com.openfaas.maintenance.image"
+	// maintenanceFprocessAnnotation records the original fprocess value.
+	maintenanceFprocessAnnotation = "com.openfaas.maintenance.fprocess"
+
+	maintenanceMessageEnv = "maintenance_message"
+
+	defaultMaintenanceMessage = "This function is undergoing maintenance, please try again later."
+	// defaultMaintenanceImage is built from contrib/maintenance, it answers
+	// every request with 503 and the message, as a watchdog can only fail an
+	// invocation with 500
+	defaultMaintenanceImage = "ghcr.io/openfaas/faas-cli-maintenance:latest"
+)
+
+var (
+	maintenanceMessage string
+	maintenanceImage   string
+)
+
+func init() {
+	maintenanceCmd.PersistentFlags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	maintenanceCmd.PersistentFlags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	maintenanceCmd.PersistentFlags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
+	maintenanceCmd.PersistentFlags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+	maintenanceCmd.PersistentFlags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+
+	maintenanceEnableCmd.Flags().StringVar(&maintenanceMessage, "message", defaultMaintenanceMessage, "Message returned to callers whilst the function is in maintenance")
+	maintenanceEnableCmd.Flags().StringVar(&maintenanceImage, "image", defaultMaintenanceImage, "Image which responds to requests with 503 and its maintenance_message environment variable whilst the function is in maintenance")
+
+	maintenanceCmd.AddCommand(maintenanceEnableCmd)
+	maintenanceCmd.AddCommand(maintenanceDisableCmd)
+	markMutating(maintenanceCmd)
+	faasCmd.AddCommand(maintenanceCmd)
+}
+
+var maintenanceCmd = &cobra.Command{
+	Use:   `maintenance`,
+	Short: "Manage planned downtime for functions",
+	Long: `Swap a function for a placeholder which responds with a maintenance message,
+then restore the original deployment once the work is complete.`,
+}
+
+var maintenanceEnableCmd = &cobra.Command{
+	Use:   `enable FUNCTION_NAME [--message MESSAGE] [--gateway GATEWAY_URL]`,
+	Short: "Put a function into maintenance mode",
+	Long: `Replaces the image of a deployed function with a static responder which
+answers every request with 503 Service Unavailable and the given message, and a
+Retry-After header. The responder is built from contrib/maintenance, another
+image given by --image must read the message from maintenance_message. The
+original image and fprocess are recorded as annotations on the function so that
+"maintenance disable" can restore them.`,
+	Example: `  faas-cli maintenance enable figlet
+  faas-cli maintenance enable figlet --message "Back at 14:00 UTC"
+  faas-cli maintenance enable figlet --namespace staging`,
+	PreRunE: preRunMaintenance,
+	RunE:    runMaintenanceEnable,
+}
+
+var maintenanceDisableCmd = &cobra.Command{
+	Use:   `disable FUNCTION_NAME [--gateway GATEWAY_URL]`,
+	Short: "Take a function out of maintenance mode",
+	Long:  `Restores the image and fprocess which were recorded by "maintenance enable".`,
+	Example: `  faas-cli maintenance disable figlet
+  faas-cli maintenance disable figlet --namespace staging`,
+	PreRunE: preRunMaintenance,
+	RunE:    runMaintenanceDisable,
+}
+
+func preRunMaintenance(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of a function")
+	}
+
+	if len(args) > 1 {
+		return fmt.Errorf("only one function name is allowed")
+	}
+
+	return nil
+}
+
+func runMaintenanceEnable(cmd *cobra.Command, args []string) error {
+	client, err := newMaintenanceClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	function, err := client.GetFunctionInfo(ctx, args[0], functionNamespace)
+	if err != nil {
+		return err
+	}
+
+	spec, err := maintenanceEnableSpec(function, maintenanceImage, maintenanceMessage)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Enabling maintenance mode for: %s\n", generateFunctionRef(spec.FunctionName, spec.Namespace))
+	if statusCode := client.DeployFunction(ctx, spec); badStatusCode(statusCode) {
+		return fmt.Errorf("unable to enable maintenance mode, status code: %d", statusCode)
+	}
+
+	return nil
+}
+
+func runMaintenanceDisable(cmd *cobra.Command, args []string) error {
+	client, err := newMaintenanceClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	function, err := client.GetFunctionInfo(ctx, args[0], functionNamespace)
+	if err != nil {
+		return err
+	}
+
+	spec, err := maintenanceDisableSpec(function)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Disabling maintenance mode for: %s\n", generateFunctionRef(spec.FunctionName, spec.Namespace))
+	if statusCode := client.DeployFunction(ctx, spec); badStatusCode(statusCode) {
+		return fmt.Errorf("unable to disable maintenance mode, status code: %d", statusCode)
+	}
+
+	return nil
+}
+
+func newMaintenanceClient() (*proxy.Client, error) {
+	var yamlGateway string
+	if len(yamlFile) > 0 {
+		services, err := stack.ParseYAMLFile(yamlFile, "", "", envsubst)
+		if err != nil {
+			return nil, err
+		}
+		yamlGateway = services.Provider.GatewayURL
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway, os.Getenv(openFaaSURLEnvironment))
+	if msg := checkTLSInsecure(gatewayAddress, tlsInsecure); len(msg) > 0 {
+		fmt.Println(msg)
+	}
+
+	cliAuth, err := proxy.NewCLIAuth(token, gatewayAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := GetDefaultCLITransport(tlsInsecure, &commandTimeout)
+	return proxy.NewClient(cliAuth, gatewayAddress, transport, &commandTimeout)
+}
+
+// maintenanceEnableSpec builds an update which swaps the function's image for the
+// maintenance responder, whilst keeping its secrets, constraints and metadata.
+func maintenanceEnableSpec(function types.FunctionStatus, image, message string) (*proxy.DeployFunctionSpec, error) {
+	spec := deploySpecFromStatus(function)
+
+	if _, ok := spec.Annotations[maintenanceImageAnnotation]; ok {
+		return nil, fmt.Errorf("function %s is already in maintenance mode", function.Name)
+	}
+
+	spec.Annotations[maintenanceImageAnnotation] = function.Image
+	if len(function.EnvProcess) > 0 {
+		spec.Annotations[maintenanceFprocessAnnotation] = function.EnvProcess
+	}
+
+	// The responder is its own server, so it has no fprocess
+	spec.Image = image
+	spec.FProcess = ""
+	spec.EnvVars[maintenanceMessageEnv] = message
+
+	return spec, nil
+}
+
+// maintenanceDisableSpec builds an update which restores the image and fprocess
+// recorded by maintenanceEnableSpec.
+func maintenanceDisableSpec(function types.FunctionStatus) (*proxy.DeployFunctionSpec, error) {
+	spec := deploySpecFromStatus(function)
+
+	image, ok := spec.Annotations[maintenanceImageAnnotation]
+	if !ok || len(image) == 0 {
+		return nil, fmt.Errorf("function %s is not in maintenance mode", function.Name)
+	}
+
+	spec.Image = image
+	spec.FProcess = spec.Annotations[maintenanceFprocessAnnotation]
+
+	delete(spec.Annotations, maintenanceImageAnnotation)
+	delete(spec.Annotations, maintenanceFprocessAnnotation)
+	delete(spec.EnvVars, maintenanceMessageEnv)
+
+	return spec, nil
+}
+
+// deploySpecFromStatus converts a deployed function back into a spec which can be
+// used to perform a rolling update of the function. Maps are always allocated so
+// that callers can modify them freely.
+func deploySpecFromStatus(function types.FunctionStatus) *proxy.DeployFunctionSpec {
+	spec := &proxy.DeployFunctionSpec{
+		FProcess:               function.EnvProcess,
+		FunctionName:           function.Name,
+		Image:                  function.Image,
+		Update:                 true,
+		EnvVars:                map[string]string{},
+		Constraints:            function.Constraints,
+		Secrets:                function.Secrets,
+		Labels:                 map[string]string{},
+		Annotations:            map[string]string{},
+		ReadOnlyRootFilesystem: function.ReadOnlyRootFilesystem,
+		TLSInsecure:            tlsInsecure,
+		Token:                  token,
+		Namespace:              function.Namespace,
+	}
+
+	for k, v := range function.EnvVars {
+		spec.EnvVars[k] = v
+	}
+
+	if function.Labels != nil {
+		for k, v := range *function.Labels {
+			spec.Labels[k] = v
+		}
+	}
+
+	if function.Annotations != nil {
+		for k, v := range *function.Annotations {
+			spec.Annotations[k] = v
+		}
+	}
+
+	if function.Limits != nil {
+		spec.FunctionResourceRequest.Limits = &stack.FunctionResources{
+			Memory: function.Limits.Memory,
+			CPU:    function.Limits.CPU,
+		}
+	}
+
+	if function.Requests != nil {
+		spec.FunctionResourceRequest.Requests = &stack.FunctionResources{
+			Memory: function.Requests.Memory,
+			CPU:    function.Requests.CPU,
+		}
+	}
+
+	return spec
+}
+
+// generateFunctionRef returns NAME or NAME.NAMESPACE for printing
+func generateFunctionRef(name, namespace string) string {
+	if len(namespace) > 0 {
+		return fmt.Sprintf("%s.%s", name, namespace)
+	}
+	return name
+}
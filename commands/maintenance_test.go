@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"testing"
+
+	types "github.com/openfaas/faas-provider/types"
+)
+
+func Test_maintenanceEnableSpec_RecordsOriginal(t *testing.T) {
+	function := types.FunctionStatus{
+		Name:        "figlet",
+		Image:       "ghcr.io/openfaas/figlet:latest",
+		EnvProcess:  "figlet",
+		EnvVars:     map[string]string{"write_debug": "true"},
+		Secrets:     []string{"api-key"},
+		Annotations: &map[string]string{"topic": "cron"},
+	}
+
+	spec, err := maintenanceEnableSpec(function, defaultMaintenanceImage, "back soon")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if spec.Image != defaultMaintenanceImage {
+		t.Errorf("want image %s, got %s", defaultMaintenanceImage, spec.Image)
+	}
+	if spec.FProcess != "" {
+		t.Errorf("want no fprocess for the responder, got %s", spec.FProcess)
+	}
+	if got := spec.Annotations[maintenanceImageAnnotation]; got != function.Image {
+		t.Errorf("want image annotation %s, got %s", function.Image, got)
+	}
+	if got := spec.Annotations[maintenanceFprocessAnnotation]; got != "figlet" {
+		t.Errorf("want fprocess annotation figlet, got %s", got)
+	}
+	if got := spec.EnvVars[maintenanceMessageEnv]; got != "back soon" {
+		t.Errorf("want message env back soon, got %s", got)
+	}
+	if got := spec.Annotations["topic"]; got != "cron" {
+		t.Errorf("want existing annotation to be kept, got %q", got)
+	}
+	if len(spec.Secrets) != 1 || spec.Secrets[0] != "api-key" {
+		t.Errorf("want secrets to be kept, got %v", spec.Secrets)
+	}
+	if !spec.Update {
+		t.Errorf("want a rolling update")
+	}
+	if _, ok := (*function.Annotations)[maintenanceImageAnnotation]; ok {
+		t.Errorf("source annotations should not be modified")
+	}
+}
+
+func Test_maintenanceEnableSpec_AlreadyEnabled(t *testing.T) {
+	function := types.FunctionStatus{
+		Name:        "figlet",
+		Image:       defaultMaintenanceImage,
+		Annotations: &map[string]string{maintenanceImageAnnotation: "ghcr.io/openfaas/figlet:latest"},
+	}
+
+	if _, err := maintenanceEnableSpec(function, defaultMaintenanceImage, "back soon"); err == nil {
+		t.Fatalf("want error when function is already in maintenance mode")
+	}
+}
+
+func Test_maintenanceDisableSpec_RestoresOriginal(t *testing.T) {
+	function := types.FunctionStatus{
+		Name:    "figlet",
+		Image:   defaultMaintenanceImage,
+		EnvVars: map[string]string{maintenanceMessageEnv: "back soon", "write_debug": "true"},
+		Annotations: &map[string]string{
+			maintenanceImageAnnotation:    "ghcr.io/openfaas/figlet:latest",
+			maintenanceFprocessAnnotation: "figlet",
+			"topic":                       "cron",
+		},
+	}
+
+	spec, err := maintenanceDisableSpec(function)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if spec.Image != "ghcr.io/openfaas/figlet:latest" {
+		t.Errorf("want original image, got %s", spec.Image)
+	}
+	if spec.FProcess != "figlet" {
+		t.Errorf("want original fprocess, got %s", spec.FProcess)
+	}
+	if _, ok := spec.EnvVars[maintenanceMessageEnv]; ok {
+		t.Errorf("want maintenance message env to be removed")
+	}
+	if _, ok := spec.Annotations[maintenanceImageAnnotation]; ok {
+		t.Errorf("want maintenance annotations to be removed")
+	}
+	if spec.EnvVars["write_debug"] != "true" || spec.Annotations["topic"] != "cron" {
+		t.Errorf("want other env vars and annotations to be kept")
+	}
+}
+
+func Test_maintenanceDisableSpec_NotEnabled(t *testing.T) {
+	function := types.FunctionStatus{
+		Name:  "figlet",
+		Image: "ghcr.io/openfaas/figlet:latest",
+	}
+
+	if _, err := maintenanceDisableSpec(function); err == nil {
+		t.Fatalf("want error when function is not in maintenance mode")
+	}
+}
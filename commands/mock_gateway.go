@@ -0,0 +1,73 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/openfaas/faas-cli/mockgateway"
+	"github.com/spf13/cobra"
+)
+
+var mockGatewayPort int
+
+func init() {
+	mockGatewayCmd.Flags().IntVarP(&mockGatewayPort, "port", "p", 8080, "Port to listen on")
+
+	faasCmd.AddCommand(mockGatewayCmd)
+}
+
+var mockGatewayCmd = &cobra.Command{
+	Use:   `mock-gateway [--port PORT]`,
+	Short: "Run an in-memory gateway for offline testing",
+	Long: `Starts a gateway which implements the core OpenFaaS API in memory, so that
+deploy, list, describe, scale, invoke, logs and secret commands can be tried
+without a cluster.
+
+Deployed functions are never started. Invoking one echoes the request body
+back, and each deployment and invocation is recorded as a log message.
+All state is lost when the command exits.`,
+	Example: `  faas-cli mock-gateway
+  faas-cli mock-gateway --port 8081 &
+  faas-cli deploy --gateway http://127.0.0.1:8081
+  echo hi | faas-cli invoke figlet --gateway http://127.0.0.1:8081`,
+	RunE: runMockGateway,
+}
+
+func runMockGateway(cmd *cobra.Command, args []string) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", mockGatewayPort))
+	if err != nil {
+		return fmt.Errorf("unable to listen on port %d: %w", mockGatewayPort, err)
+	}
+
+	server := &http.Server{
+		Handler:           mockgateway.NewServer(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Mock gateway listening on: http://127.0.0.1:%d\n", mockGatewayPort)
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
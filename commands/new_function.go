@@ -42,6 +42,8 @@ func init() {
 	newFunctionCmd.Flags().BoolVar(&list, "list", false, "List available languages")
 	newFunctionCmd.Flags().StringVarP(&appendFile, "append", "a", "", "Append to existing YAML file")
 	newFunctionCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Skip template notes")
+	newFunctionCmd.Flags().BoolVar(&newInitGit, "init-git", false, "Initialise a git repository in the current folder, if it is not already in one")
+	newFunctionCmd.Flags().BoolVar(&newPreCommitHook, "pre-commit-hook", false, "Install a git pre-commit hook which checks the stack file with \"faas-cli stack render\"")
 
 	faasCmd.AddCommand(newFunctionCmd)
 }
@@ -51,11 +53,17 @@ var newFunctionCmd = &cobra.Command{
 	Use:   "new FUNCTION_NAME --lang=FUNCTION_LANGUAGE [--gateway=http://host:port] | --list | --append=STACK_FILE)",
 	Short: "Create a new template in the current folder with the name given as name",
 	Long: `The new command creates a new function based upon hello-world in the given
-language or type in --list for a list of languages available.`,
+language or type in --list for a list of languages available.
+
+The .gitignore file is created or updated to ignore the build, template and
+.secrets folders. With --init-git, a git repository is initialised, and with
+--pre-commit-hook, a hook is installed which checks that the stack file, its
+environment files and enabled expressions can be read before each commit.`,
 	Example: `  faas-cli new chatbot --lang node
   faas-cli new chatbot --lang node --append stack.yml
   faas-cli new text-parser --lang python --quiet
   faas-cli new text-parser --lang python --gateway http://mydomain:8080
+  faas-cli new text-parser --lang python --init-git --pre-commit-hook
   faas-cli new --list`,
 	PreRunE: preRunNewFunction,
 	RunE:    runNewFunction,
@@ -247,6 +255,12 @@ Download templates:
 
 	fmt.Print(outputMsg)
 
+	if newInitGit || newPreCommitHook {
+		if err := setupNewFunctionGit(fileName, newInitGit, newPreCommitHook); err != nil {
+			return err
+		}
+	}
+
 	if !quiet {
 		languageTemplate, _ := stack.LoadLanguageTemplate(language)
 
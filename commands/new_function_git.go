@@ -0,0 +1,89 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// preCommitHookMarker identifies a hook written by faas-cli, so that it can
+// be updated, and any other hook is left alone
+const preCommitHookMarker = "# Installed by faas-cli new --pre-commit-hook"
+
+var (
+	newInitGit       bool
+	newPreCommitHook bool
+)
+
+// insideGitRepo is true when the working directory is in a git work tree
+var insideGitRepo = func() bool {
+	out, err := exec.Command("git", "rev-parse", "--is-inside-work-tree").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// setupNewFunctionGit initialises a git repository for the new project, and
+// installs a pre-commit hook which checks the stack file
+func setupNewFunctionGit(stackFile string, initGit, preCommitHook bool) error {
+	if initGit {
+		if insideGitRepo() {
+			fmt.Printf("Git repository: already initialised.\n")
+		} else {
+			if out, err := exec.Command("git", "init").CombinedOutput(); err != nil {
+				return fmt.Errorf("unable to initialise a git repository: %s", strings.TrimSpace(string(out)))
+			}
+			fmt.Printf("Git repository: initialised.\n")
+		}
+	}
+
+	if !preCommitHook {
+		return nil
+	}
+
+	if !insideGitRepo() {
+		return fmt.Errorf("--pre-commit-hook needs a git repository, add --init-git to create one")
+	}
+
+	out, err := exec.Command("git", "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return fmt.Errorf("unable to find the git hooks folder: %w", err)
+	}
+	hooksDir := strings.TrimSpace(string(out))
+	hookPath := filepath.Join(hooksDir, "pre-commit")
+
+	if existing, err := os.ReadFile(hookPath); err == nil && !strings.Contains(string(existing), preCommitHookMarker) {
+		fmt.Printf("Pre-commit hook: %s already exists, add: faas-cli stack render -f %s > /dev/null\n", hookPath, stackFile)
+		return nil
+	}
+
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(hookPath, []byte(preCommitHookScript(stackFile)), 0755); err != nil {
+		return fmt.Errorf("unable to write the pre-commit hook: %w", err)
+	}
+	fmt.Printf("Pre-commit hook: %s written.\n", hookPath)
+
+	return nil
+}
+
+// preCommitHookScript checks that the stack file parses, with its environment
+// files and enabled expressions, before each commit
+func preCommitHookScript(stackFile string) string {
+	return `#!/bin/sh
+` + preCommitHookMarker + `
+# Checks the stack file before each commit, skip with: git commit --no-verify
+set -e
+
+if ! command -v faas-cli >/dev/null 2>&1; then
+  echo "faas-cli not found, skipping the stack file checks" >&2
+  exit 0
+fi
+
+faas-cli stack render -f ` + shellQuote(stackFile) + ` > /dev/null
+`
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_setupNewFunctionGit_InitAndHook(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	if err := setupNewFunctionGit("text parser.yml", true, true); err != nil {
+		t.Fatal(err)
+	}
+
+	hookPath := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	info, err := os.Stat(hookPath)
+	if err != nil {
+		t.Fatalf("want a pre-commit hook, got: %s", err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("want the hook to be executable, got: %s", info.Mode())
+	}
+
+	hook, _ := os.ReadFile(hookPath)
+	if !strings.Contains(string(hook), "faas-cli stack render -f 'text parser.yml' > /dev/null") {
+		t.Errorf("want the stack file checked, got:\n%s", hook)
+	}
+
+	// A hook which faas-cli did not write is left alone
+	os.WriteFile(hookPath, []byte("#!/bin/sh\nmake test\n"), 0755)
+	if err := setupNewFunctionGit("other.yml", false, true); err != nil {
+		t.Fatal(err)
+	}
+	if hook, _ := os.ReadFile(hookPath); string(hook) != "#!/bin/sh\nmake test\n" {
+		t.Errorf("want the existing hook kept, got:\n%s", hook)
+	}
+}
+
+func Test_setupNewFunctionGit_HookNeedsRepo(t *testing.T) {
+	savedInsideGitRepo := insideGitRepo
+	insideGitRepo = func() bool { return false }
+	defer func() { insideGitRepo = savedInsideGitRepo }()
+
+	err := setupNewFunctionGit("stack.yml", false, true)
+	if err == nil || !strings.Contains(err.Error(), "--init-git") {
+		t.Fatalf("want an error suggesting --init-git, got: %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// gatewayHealthPath is served by the gateway without authentication
+const gatewayHealthPath = "/healthz"
+
+var pingCount int
+
+func init() {
+	pingCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	pingCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+	pingCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	pingCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+	pingCmd.Flags().IntVarP(&pingCount, "count", "c", 3, "number of requests to send to the gateway, and to the function")
+
+	faasCmd.AddCommand(pingCmd)
+}
+
+var pingCmd = &cobra.Command{
+	Use:   `ping [NAME] [--gateway GATEWAY_URL] [--count COUNT]`,
+	Short: "Measure the latency of the gateway, and of a function",
+	Long: `Measure the time taken by each part of a request to the gateway, so that a
+slow network can be told apart from a slow gateway or a function's cold start.
+
+The DNS lookup, TCP connection and TLS handshake are timed once, then the
+gateway's ` + gatewayHealthPath + ` endpoint is requested --count times over the same connection.
+With a NAME, the function's ` + watchdogHealthPath + ` endpoint is then requested through the
+gateway, which is served by the watchdog without calling the function's
+handler, and the gateway's own time is taken from the function's.
+
+The route of the requests is printed too: the addresses the gateway's name
+resolves to, the address connected to, any HTTP proxy, and the proxies which
+added themselves to the response's Via header.
+
+When the first request to the function is much slower than the rest, it was
+most likely scaled from zero, or its pod had just started.`,
+	Example: `  faas-cli ping
+  faas-cli ping figlet
+  faas-cli ping figlet --count 10 --gateway https://gateway.example.com
+  faas-cli ping figlet --namespace staging`,
+	RunE: runPing,
+}
+
+// pingResult is the time taken by each part of the requests sent by ping
+type pingResult struct {
+	Gateway string
+	// Addresses are those the gateway's name resolves to
+	Addresses []string
+	// Proxy is the HTTP proxy the requests are sent through, if any
+	Proxy string
+	// Connected is the address of the connection
+	Connected string
+	// Via lists the proxies named in the Via header of the responses
+	Via []string
+
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+
+	// GatewaySamples are the times from sending each request to the
+	// gateway's health endpoint, to the first byte of its response
+	GatewaySamples []time.Duration
+
+	Function        string
+	FunctionURL     string
+	FunctionStatus  int
+	FunctionSamples []time.Duration
+}
+
+func runPing(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("give at most one function name")
+	}
+	if pingCount < 1 {
+		return fmt.Errorf("--count must be at least 1, got: %d", pingCount)
+	}
+
+	var yamlGateway string
+	if len(yamlFile) > 0 {
+		if services, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst); err == nil && services != nil {
+			yamlGateway = services.Provider.GatewayURL
+		}
+	}
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway, os.Getenv(openFaaSURLEnvironment))
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	// A transport of its own means that the first request opens a new
+	// connection, so that it can be timed
+	transport := proxy.SharedTransport(commandTimeout, tlsInsecure, false).Clone()
+	client := &http.Client{Transport: transport, Timeout: commandTimeout}
+
+	result, err := pingGateway(cmd.Context(), client, gatewayAddress, name, functionNamespace, pingCount)
+	if err != nil {
+		return err
+	}
+
+	printPing(cmd.OutOrStdout(), result)
+	if result.FunctionStatus == http.StatusNotFound {
+		return fmt.Errorf("function %s was not found at: %s", name, result.FunctionURL)
+	}
+	return nil
+}
+
+// pingGateway times the DNS lookup of the gateway, then sends count requests
+// to its health endpoint, and count to the function's through the gateway
+func pingGateway(ctx context.Context, client *http.Client, gatewayAddress, name, namespace string, count int) (*pingResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	u, err := url.Parse(strings.TrimRight(gatewayAddress, "/"))
+	if err != nil || len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid gateway URL: %s", gatewayAddress)
+	}
+
+	result := &pingResult{Gateway: u.String()}
+
+	// The transport may cache the addresses of a host, so the lookup is timed
+	// on its own
+	start := time.Now()
+	addresses, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve the gateway's host %s: %s", u.Hostname(), err.Error())
+	}
+	result.DNS = time.Since(start)
+	result.Addresses = addresses
+
+	if tr, ok := client.Transport.(*http.Transport); ok && tr.Proxy != nil {
+		req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+		if proxyURL, err := tr.Proxy(req); err == nil && proxyURL != nil {
+			result.Proxy = proxyURL.Redacted()
+		}
+	}
+
+	for i := 0; i < count; i++ {
+		sample, status, err := pingRequest(ctx, client, result.Gateway+gatewayHealthPath, result)
+		if err != nil {
+			return nil, fmt.Errorf("unable to reach the gateway: %s", err.Error())
+		}
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("the gateway is not healthy, %s returned: %d", gatewayHealthPath, status)
+		}
+		result.GatewaySamples = append(result.GatewaySamples, sample)
+	}
+
+	if len(name) == 0 {
+		return result, nil
+	}
+
+	functionURL, _ := getFunctionURLs(result.Gateway, name, namespace)
+	result.Function = name
+	result.FunctionURL = functionURL + watchdogHealthPath
+
+	for i := 0; i < count; i++ {
+		sample, status, err := pingRequest(ctx, client, result.FunctionURL, result)
+		if err != nil {
+			return nil, fmt.Errorf("unable to reach function %s: %s", name, err.Error())
+		}
+		result.FunctionStatus = status
+		if status == http.StatusNotFound {
+			break
+		}
+		result.FunctionSamples = append(result.FunctionSamples, sample)
+	}
+
+	return result, nil
+}
+
+// pingRequest sends one GET request, and returns the time from writing it to
+// the first byte of the response. The connection's timings are recorded in
+// result when the request opens a new one.
+func pingRequest(ctx context.Context, client *http.Client, target string, result *pingResult) (time.Duration, int, error) {
+	var connectStart, tlsStart, wrote, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) { connectStart = time.Now() },
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil && !connectStart.IsZero() {
+				result.Connect = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err == nil && !tlsStart.IsZero() {
+				result.TLS = time.Since(tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				result.Connected = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	// The body is read so that the connection is reused
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	for _, via := range res.Header.Values("Via") {
+		for _, hop := range strings.Split(via, ",") {
+			if hop = strings.TrimSpace(hop); len(hop) > 0 && !containsString(result.Via, hop) {
+				result.Via = append(result.Via, hop)
+			}
+		}
+	}
+
+	return firstByte.Sub(wrote), res.StatusCode, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// medianDuration is the middle of the samples, or 0 when there are none
+func medianDuration(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func printPing(w io.Writer, result *pingResult) {
+	fmt.Fprintf(w, "Gateway: %s\n", result.Gateway)
+	fmt.Fprintf(w, "Resolves to: %s\n", strings.Join(result.Addresses, ", "))
+	if len(result.Proxy) > 0 {
+		fmt.Fprintf(w, "Through proxy: %s\n", result.Proxy)
+	}
+	if len(result.Connected) > 0 {
+		fmt.Fprintf(w, "Connected to: %s\n", result.Connected)
+	}
+	if len(result.Via) > 0 {
+		fmt.Fprintf(w, "Via: %s\n", strings.Join(result.Via, ", "))
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "DNS lookup\t%s\n", roundLatency(result.DNS))
+	fmt.Fprintf(tw, "TCP connect\t%s\n", roundLatency(result.Connect))
+	if strings.HasPrefix(result.Gateway, "https://") {
+		fmt.Fprintf(tw, "TLS handshake\t%s\n", roundLatency(result.TLS))
+	}
+	gateway := medianDuration(result.GatewaySamples)
+	fmt.Fprintf(tw, "Gateway\t%s\tmedian of %d requests to %s\n", roundLatency(gateway), len(result.GatewaySamples), gatewayHealthPath)
+
+	if len(result.FunctionSamples) > 0 {
+		function := medianDuration(result.FunctionSamples)
+		fmt.Fprintf(tw, "Function %s\t%s\tmedian of %d requests to %s, beyond the gateway's time\n",
+			result.Function, roundLatency(positiveDuration(function-gateway)), len(result.FunctionSamples), watchdogHealthPath)
+		if len(result.FunctionSamples) > 1 {
+			fmt.Fprintf(tw, "First request\t%s\n", roundLatency(result.FunctionSamples[0]))
+		}
+	}
+	tw.Flush()
+
+	if verdict := pingVerdict(result); len(verdict) > 0 {
+		fmt.Fprintf(w, "\n%s\n", verdict)
+	}
+}
+
+// pingVerdict explains where most of the time was spent, or is "" when
+// nothing stands out
+func pingVerdict(result *pingResult) string {
+	gateway := medianDuration(result.GatewaySamples)
+
+	if len(result.FunctionSamples) > 1 {
+		first, rest := result.FunctionSamples[0], medianDuration(result.FunctionSamples[1:])
+		if first > 2*rest && first-rest > 500*time.Millisecond {
+			return fmt.Sprintf("The first request to %s took %s longer than the rest, which suggests a cold start, such as scaling from zero.",
+				result.Function, roundLatency(first-rest))
+		}
+	}
+	if len(result.FunctionSamples) > 0 && result.FunctionStatus != http.StatusOK {
+		return fmt.Sprintf("The function's %s returned: %d, so it may not be ready.", watchdogHealthPath, result.FunctionStatus)
+	}
+
+	network := result.DNS + result.Connect + result.TLS
+	if network > gateway && network > 100*time.Millisecond {
+		return "Most of the time was spent on the network, before the request reached the gateway."
+	}
+	return ""
+}
+
+func positiveDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// roundLatency rounds a duration for printing, keeping a tenth of a
+// millisecond for fast local requests
+func roundLatency(d time.Duration) time.Duration {
+	if d < 10*time.Millisecond {
+		return d.Round(100 * time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
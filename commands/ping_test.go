@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_pingGateway(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case gatewayHealthPath, "/function/figlet.staging" + watchdogHealthPath:
+			w.Header().Set("Via", "1.1 ingress")
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	result, err := pingGateway(context.Background(), srv.Client(), srv.URL+"/", "figlet", "staging", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.GatewaySamples) != 3 || len(result.FunctionSamples) != 3 {
+		t.Errorf("want 3 samples of the gateway and function, got: %d and %d", len(result.GatewaySamples), len(result.FunctionSamples))
+	}
+	if result.FunctionURL != srv.URL+"/function/figlet.staging"+watchdogHealthPath {
+		t.Errorf("want the function's health endpoint in its namespace, got: %s", result.FunctionURL)
+	}
+	if result.Connected != strings.TrimPrefix(srv.URL, "http://") {
+		t.Errorf("want the connected address, got: %s", result.Connected)
+	}
+	if len(result.Via) != 1 || result.Via[0] != "1.1 ingress" {
+		t.Errorf("want the proxy from the Via header once, got: %v", result.Via)
+	}
+
+	var out bytes.Buffer
+	printPing(&out, result)
+	for _, want := range []string{"DNS lookup", "TCP connect", "Gateway", "Function figlet", "Via: 1.1 ingress"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in the output, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "TLS handshake") {
+		t.Errorf("want no TLS handshake for http, got:\n%s", out.String())
+	}
+
+	result, err = pingGateway(context.Background(), srv.Client(), srv.URL, "missing", "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FunctionStatus != http.StatusNotFound || len(result.FunctionSamples) != 0 {
+		t.Errorf("want a missing function to stop after the first request, got: %d, %v", result.FunctionStatus, result.FunctionSamples)
+	}
+}
+
+func Test_pingGateway_Unhealthy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	if _, err := pingGateway(context.Background(), srv.Client(), srv.URL, "", "", 1); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("want an error for an unhealthy gateway, got: %v", err)
+	}
+}
+
+func Test_pingVerdict(t *testing.T) {
+	cold := &pingResult{
+		GatewaySamples:  []time.Duration{5 * time.Millisecond},
+		Function:        "figlet",
+		FunctionStatus:  http.StatusOK,
+		FunctionSamples: []time.Duration{2 * time.Second, 10 * time.Millisecond, 12 * time.Millisecond},
+	}
+	if got := pingVerdict(cold); !strings.Contains(got, "cold start") {
+		t.Errorf("want a cold start, got: %q", got)
+	}
+
+	network := &pingResult{DNS: 200 * time.Millisecond, Connect: 50 * time.Millisecond, GatewaySamples: []time.Duration{5 * time.Millisecond}}
+	if got := pingVerdict(network); !strings.Contains(got, "network") {
+		t.Errorf("want the network blamed, got: %q", got)
+	}
+
+	fast := &pingResult{DNS: time.Millisecond, GatewaySamples: []time.Duration{5 * time.Millisecond}}
+	if got := pingVerdict(fast); got != "" {
+		t.Errorf("want no verdict, got: %q", got)
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"time"
 
 	v1execute "github.com/alexellis/go-execute/pkg/v1"
-	"github.com/morikuni/aec"
+	"github.com/openfaas/faas-cli/output"
 	"github.com/openfaas/faas-cli/util"
 
 	"github.com/openfaas/faas-cli/builder"
@@ -23,6 +23,8 @@ var (
 	extraTags []string
 	resetQemu bool
 	mountSSH  bool
+	// publishRegistries are mirrors to push to, as well as the image's own registry
+	publishRegistries []string
 )
 
 func init() {
@@ -47,11 +49,13 @@ func init() {
 	publishCmd.Flags().BoolVar(&disableStackPull, "disable-stack-pull", false, "Disables the template configuration in the stack.yml")
 	publishCmd.Flags().StringVar(&platforms, "platforms", "linux/amd64", "A set of platforms to publish")
 	publishCmd.Flags().StringArrayVar(&extraTags, "extra-tag", []string{}, "Additional extra image tag")
+	publishCmd.Flags().StringArrayVar(&publishRegistries, "registry", []string{}, "Also push each image to this registry and owner, e.g. dr.example.com/acme, adds to configuration.registries in the stack file")
 	publishCmd.Flags().BoolVar(&resetQemu, "reset-qemu", false, "Runs \"docker run multiarch/qemu-user-static --reset -p yes\" to enable multi-arch builds. Compatible with AMD64 machines only.")
 
 	// Set bash-completion.
 	_ = publishCmd.Flags().SetAnnotation("handler", cobra.BashCompSubdirsInDir, []string{})
 
+	markMutating(publishCmd)
 	faasCmd.AddCommand(publishCmd)
 }
 
@@ -83,6 +87,11 @@ available in the local Docker library. This is due to technical constraints in
 Docker and buildx. You must use a multi-arch template to use this command with 
 correctly configured TARGETPLATFORM and BUILDPLATFORM arguments.
 
+Each image can also be pushed to mirrors, given with --registry or in the
+configuration.registries list of the stack file, such as a registry for
+disaster recovery. "faas-cli deploy --registry-failover" deploys from the first
+mirror which has the image when the image's own registry doesn't.
+
 See also: faas-cli build`,
 	Example: `  faas-cli publish --platforms linux/amd64,linux/arm64,linux/arm/7
   faas-cli publish --platforms linux/arm/7 --filter webhook
@@ -90,6 +99,7 @@ See also: faas-cli build`,
   faas-cli publish --build-option dev
   faas-cli publish --tag sha
   faas-cli publish --reset-qemu
+  faas-cli publish --registry dr.example.com/acme
   `,
 	PreRunE: preRunPublish,
 	RunE:    runPublish,
@@ -195,7 +205,7 @@ func runPublish(cmd *cobra.Command, args []string) error {
 		for _, err := range errors {
 			errorSummary = errorSummary + "- " + err.Error() + "\n"
 		}
-		return fmt.Errorf("%s", aec.Apply(errorSummary, aec.RedF))
+		return fmt.Errorf("%s", output.Red.Apply(errorSummary))
 	}
 	return nil
 }
@@ -215,13 +225,14 @@ func publish(services *stack.Services, queueDepth int, shrinkwrap, quietBuild, m
 			for function := range workChannel {
 				start := time.Now()
 
-				fmt.Printf(aec.YellowF.Apply("[%d] > Building %s.\n"), index, function.Name)
+				fmt.Printf(output.Yellow.Apply("[%d] > Building %s.\n"), index, function.Name)
 				if len(function.Language) == 0 {
 					fmt.Println("Please provide a valid language for your function.")
 				} else {
 					combinedBuildOptions := combineBuildOpts(function.BuildOptions, buildOptions)
 					combinedBuildArgMap := util.MergeMap(function.BuildArgs, buildArgMap)
 					combinedExtraPaths := util.MergeSlice(services.StackConfiguration.CopyExtraPaths, copyExtra)
+					combinedRegistries := util.MergeSlice(services.StackConfiguration.Registries, publishRegistries)
 					err := builder.PublishImage(function.Image,
 						function.Handler,
 						function.Name,
@@ -237,6 +248,7 @@ func publish(services *stack.Services, queueDepth int, shrinkwrap, quietBuild, m
 						combinedExtraPaths,
 						platforms,
 						extraTags,
+						combinedRegistries,
 					)
 
 					if err != nil {
@@ -245,10 +257,10 @@ func publish(services *stack.Services, queueDepth int, shrinkwrap, quietBuild, m
 				}
 
 				duration := time.Since(start)
-				fmt.Printf(aec.YellowF.Apply("[%d] < Building %s done in %1.2fs.\n"), index, function.Name, duration.Seconds())
+				fmt.Printf(output.Yellow.Apply("[%d] < Building %s done in %1.2fs.\n"), index, function.Name, duration.Seconds())
 			}
 
-			fmt.Printf(aec.YellowF.Apply("[%d] Worker done.\n"), index)
+			fmt.Printf(output.Yellow.Apply("[%d] Worker done.\n"), index)
 			wg.Done()
 		}(i)
 
@@ -268,6 +280,6 @@ func publish(services *stack.Services, queueDepth int, shrinkwrap, quietBuild, m
 	wg.Wait()
 
 	duration := time.Since(startOuter)
-	fmt.Printf("\n%s\n", aec.Apply(fmt.Sprintf("Total build time: %1.2fs", duration.Seconds()), aec.YellowF))
+	fmt.Printf("\n%s\n", output.Yellow.Apply(fmt.Sprintf("Total build time: %1.2fs", duration.Seconds())))
 	return errors
 }
@@ -10,14 +10,15 @@ import (
 
 	"github.com/openfaas/faas-cli/exec"
 
-	"github.com/morikuni/aec"
 	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/output"
 	"github.com/openfaas/faas-cli/schema"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/spf13/cobra"
 )
 
 func init() {
+	markMutating(pushCmd)
 	faasCmd.AddCommand(pushCmd)
 
 	pushCmd.Flags().IntVar(&parallel, "parallel", 1, "Push images in parallel to depth specified.")
@@ -102,7 +103,7 @@ func pushStack(services *stack.Services, queueDepth int, tagMode schema.BuildFor
 				}
 				imageName := schema.BuildImageName(tagMode, function.Image, sha, branch)
 
-				fmt.Printf(aec.YellowF.Apply("[%d] > Pushing %s [%s]\n"), index, function.Name, imageName)
+				fmt.Printf(output.Yellow.Apply("[%d] > Pushing %s [%s]\n"), index, function.Name, imageName)
 				if len(function.Image) == 0 {
 					fmt.Println("Please provide a valid Image value in the YAML file.")
 				} else if function.SkipBuild {
@@ -110,11 +111,11 @@ func pushStack(services *stack.Services, queueDepth int, tagMode schema.BuildFor
 				} else {
 
 					pushImage(imageName, quietBuild)
-					fmt.Printf(aec.YellowF.Apply("[%d] < Pushing %s [%s] done.\n"), index, function.Name, imageName)
+					fmt.Printf(output.Yellow.Apply("[%d] < Pushing %s [%s] done.\n"), index, function.Name, imageName)
 				}
 			}
 
-			fmt.Printf(aec.YellowF.Apply("[%d] Worker done.\n"), index)
+			fmt.Printf(output.Yellow.Apply("[%d] Worker done.\n"), index)
 			wg.Done()
 		}(i)
 	}
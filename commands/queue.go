@@ -0,0 +1,391 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/openfaas/faas-cli/jetstream"
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-provider/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// natsURLEnvironment is the default for --nats-url, as read by the nats CLI
+	natsURLEnvironment = "NATS_URL"
+
+	// deadLetterStream is the default stream the queue-worker moves requests
+	// to once their retries are exhausted
+	deadLetterStream = "faas-dead-letters"
+	// deadLetterErrorHeader is the default header with the last error of a
+	// dead-lettered request
+	deadLetterErrorHeader = "X-Dead-Letter-Reason"
+	// queueSubject is where the gateway publishes asynchronous requests when
+	// they don't name a queue
+	queueSubject = "faas-request"
+)
+
+// deadLetterOptions are the flags of "queue dead-letters list", "export" and
+// "resubmit"
+type deadLetterOptions struct {
+	natsURL      string
+	stream       string
+	subject      string
+	function     string
+	errorHeader  string
+	timeout      time.Duration
+	output       string
+	sequences    []uint
+	all          bool
+	queueSubject string
+}
+
+var deadLetterFlags deadLetterOptions
+
+// deadLetterSource reads and re-publishes dead-lettered requests, and is
+// replaced by tests
+type deadLetterSource interface {
+	Messages(stream, subject string, fn func(*jetstream.StoredMsg) error) error
+	Publish(subject string, header http.Header, data []byte) (*jetstream.PubAck, error)
+	Close() error
+}
+
+var dialDeadLetters = func(natsURL string, timeout time.Duration) (deadLetterSource, error) {
+	return jetstream.Dial(natsURL, timeout)
+}
+
+var deadLetterNow = time.Now
+
+// deadLetter is a request which the queue-worker gave up on, as written by
+// "queue dead-letters export"
+type deadLetter struct {
+	Sequence    uint64      `json:"seq"`
+	Subject     string      `json:"subject"`
+	Time        time.Time   `json:"time"`
+	Function    string      `json:"function,omitempty"`
+	Method      string      `json:"method,omitempty"`
+	Path        string      `json:"path,omitempty"`
+	QueryString string      `json:"query_string,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	// Body is the payload as text, or BodyBase64 when it is not UTF-8
+	Body       string `json:"body,omitempty"`
+	BodyBase64 []byte `json:"body_base64,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	// request is nil when the message isn't a request from the gateway
+	request *types.QueueRequest
+	data    []byte
+	size    int
+}
+
+func init() {
+	natsURL := os.Getenv(natsURLEnvironment)
+	if len(natsURL) == 0 {
+		natsURL = jetstream.DefaultURL
+	}
+
+	flags := queueDeadLettersCmd.PersistentFlags()
+	flags.StringVar(&deadLetterFlags.natsURL, "nats-url", natsURL, "URL of NATS, with a user and password or token if required, also set by "+natsURLEnvironment)
+	flags.StringVar(&deadLetterFlags.stream, "stream", deadLetterStream, "JetStream stream which holds the dead-lettered requests")
+	flags.StringVar(&deadLetterFlags.subject, "subject", "", "Only read messages of this subject of the stream, which may contain wildcards")
+	flags.StringVar(&deadLetterFlags.function, "function", "", "Only read the requests for this function")
+	flags.StringVar(&deadLetterFlags.errorHeader, "error-header", deadLetterErrorHeader, "Header of each message with the error of the last attempt")
+	flags.DurationVar(&deadLetterFlags.timeout, "timeout", 5*time.Second, "Timeout for each request to NATS")
+
+	queueDeadLettersExportCmd.Flags().StringVarP(&deadLetterFlags.output, "output", "o", "", "File to write the requests to, instead of stdout")
+
+	queueDeadLettersResubmitCmd.Flags().UintSliceVar(&deadLetterFlags.sequences, "seq", []uint{}, "Sequence of a request to re-submit, as shown by list, can be given more than once")
+	queueDeadLettersResubmitCmd.Flags().BoolVar(&deadLetterFlags.all, "all", false, "Re-submit every request which was read")
+	queueDeadLettersResubmitCmd.Flags().StringVar(&deadLetterFlags.queueSubject, "queue-subject", "", "Subject to re-submit to, the request's own queue or "+queueSubject+" by default")
+
+	markMutating(queueDeadLettersResubmitCmd)
+	queueDeadLettersCmd.AddCommand(queueDeadLettersListCmd, queueDeadLettersExportCmd, queueDeadLettersResubmitCmd)
+	queueCmd.AddCommand(queueDeadLettersCmd)
+	faasCmd.AddCommand(queueCmd)
+}
+
+var queueCmd = &cobra.Command{
+	Use:   `queue dead-letters [list|export|resubmit]`,
+	Short: "Inspect the queue of asynchronous invocations",
+	Long: `Reads the requests of asynchronous invocations from NATS JetStream, which
+the gateway publishes to and the queue-worker consumes from.`,
+}
+
+var queueDeadLettersCmd = &cobra.Command{
+	Use:   `dead-letters [list|export|resubmit] [--function NAME]`,
+	Short: "Inspect, export and re-submit dead-lettered requests",
+	Long: `Reads the requests which failed every attempt of the queue-worker, and were
+moved to a dead-letter stream, ` + deadLetterStream + ` by default. Each message
+is the request as it was published by the gateway, with its error in the
+` + deadLetterErrorHeader + ` header.
+
+Once the cause has been fixed, selected requests can be re-submitted to the
+queue, to be invoked again. Each is published with a Nats-Msg-Id of the stream
+and sequence it came from, so re-submitting it twice within the duplicate window
+of the queue's stream invokes it once.`,
+}
+
+var queueDeadLettersListCmd = &cobra.Command{
+	Use:     `list [--function NAME]`,
+	Aliases: []string{"ls"},
+	Short:   "List the dead-lettered requests",
+	Example: `  faas-cli queue dead-letters list
+  faas-cli queue dead-letters list --function resize-image
+  faas-cli queue dead-letters list --nats-url nats://s3cr3t@nats.openfaas:4222`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		letters, err := fetchDeadLetters(deadLetterFlags)
+		if err != nil {
+			return err
+		}
+		writeDeadLetters(cmd.OutOrStdout(), letters, deadLetterFlags)
+		return nil
+	},
+}
+
+var queueDeadLettersExportCmd = &cobra.Command{
+	Use:   `export [--function NAME] [-o FILE]`,
+	Short: "Write the dead-lettered requests as JSON lines",
+	Long: `Writes each dead-lettered request as a line of JSON, with its payload,
+headers and error, to be kept or read by other tools, such as jq.`,
+	Example: `  faas-cli queue dead-letters export --function resize-image -o failed.jsonl
+  faas-cli queue dead-letters export | jq -r 'select(.error | test("timeout")) | .seq'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		letters, err := fetchDeadLetters(deadLetterFlags)
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		if len(deadLetterFlags.output) > 0 {
+			file, err := os.OpenFile(deadLetterFlags.output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			w = file
+		}
+
+		if err := exportDeadLetters(w, letters); err != nil {
+			return err
+		}
+		if len(deadLetterFlags.output) > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %d request(s) to: %s\n", len(letters), deadLetterFlags.output)
+		}
+		return nil
+	},
+}
+
+var queueDeadLettersResubmitCmd = &cobra.Command{
+	Use:   `resubmit [--function NAME] (--seq SEQ ... | --all)`,
+	Short: "Publish dead-lettered requests to the queue again",
+	Example: `  faas-cli queue dead-letters resubmit --seq 12 --seq 15
+  faas-cli queue dead-letters resubmit --function resize-image --all`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if deadLetterFlags.all == (len(deadLetterFlags.sequences) > 0) {
+			return fmt.Errorf("give the requests to re-submit with --seq, or --all")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runResubmitDeadLetters(cmd.OutOrStdout(), deadLetterFlags)
+	},
+}
+
+// fetchDeadLetters reads the requests of the stream, for the function when
+// one is given
+func fetchDeadLetters(opts deadLetterOptions) ([]deadLetter, error) {
+	source, err := dialDeadLetters(opts.natsURL, opts.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	return readDeadLetters(source, opts)
+}
+
+func readDeadLetters(source deadLetterSource, opts deadLetterOptions) ([]deadLetter, error) {
+	var letters []deadLetter
+	err := source.Messages(opts.stream, opts.subject, func(msg *jetstream.StoredMsg) error {
+		letter := newDeadLetter(msg, opts.errorHeader)
+		if len(opts.function) == 0 || letter.Function == opts.function {
+			letters = append(letters, letter)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read stream %s: %w", opts.stream, err)
+	}
+	return letters, nil
+}
+
+// newDeadLetter decodes the gateway's request from the message, a message
+// which is not a request is kept with its data as the body
+func newDeadLetter(msg *jetstream.StoredMsg, errorHeader string) deadLetter {
+	letter := deadLetter{
+		Sequence: msg.Sequence,
+		Subject:  msg.Subject,
+		Time:     msg.Time,
+		Error:    msg.Header.Get(errorHeader),
+		data:     msg.Data,
+	}
+
+	body := msg.Data
+	var req types.QueueRequest
+	if err := json.Unmarshal(msg.Data, &req); err == nil && len(req.Function) > 0 {
+		letter.request = &req
+		letter.Function = req.Function
+		letter.Method = req.Method
+		letter.Path = req.Path
+		letter.QueryString = req.QueryString
+		letter.Header = req.Header
+		body = req.Body
+	}
+
+	letter.size = len(body)
+	if utf8.Valid(body) {
+		letter.Body = string(body)
+	} else {
+		letter.BodyBase64 = body
+	}
+	return letter
+}
+
+func writeDeadLetters(w io.Writer, letters []deadLetter, opts deadLetterOptions) {
+	if len(letters) == 0 {
+		if len(opts.function) > 0 {
+			fmt.Fprintf(w, "No dead-lettered requests for %s in stream %s\n", opts.function, opts.stream)
+		} else {
+			fmt.Fprintf(w, "No dead-lettered requests in stream %s\n", opts.stream)
+		}
+		return
+	}
+
+	now := deadLetterNow()
+	table := output.NewTable("SEQ", "FUNCTION", "METHOD", "AGE", "SIZE", "ERROR")
+	for _, letter := range letters {
+		table.Row(
+			strconv.FormatUint(letter.Sequence, 10),
+			valueOrDash(letter.Function),
+			valueOrDash(letter.Method),
+			output.Age(letter.Time, now),
+			output.Size(float64(letter.size)),
+			valueOrDash(truncateDeadLetterError(letter.Error)),
+		)
+	}
+	table.Write(w)
+}
+
+// truncateDeadLetterError keeps an error to one line of the table, export
+// has the whole error
+func truncateDeadLetterError(err string) string {
+	const max = 60
+
+	if index := strings.IndexAny(err, "\r\n"); index > -1 {
+		err = err[:index] + "…"
+	}
+	if utf8.RuneCountInString(err) > max {
+		err = string([]rune(err)[:max-1]) + "…"
+	}
+	return err
+}
+
+func exportDeadLetters(w io.Writer, letters []deadLetter) error {
+	encoder := json.NewEncoder(w)
+	for _, letter := range letters {
+		if err := encoder.Encode(letter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runResubmitDeadLetters(w io.Writer, opts deadLetterOptions) error {
+	source, err := dialDeadLetters(opts.natsURL, opts.timeout)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	letters, err := readDeadLetters(source, opts)
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectDeadLetters(letters, opts)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, letter := range selected {
+		subject := opts.queueSubject
+		if len(subject) == 0 {
+			subject = queueSubject
+			if len(letter.request.QueueName) > 0 {
+				subject = letter.request.QueueName
+			}
+		}
+
+		header := http.Header{"Nats-Msg-Id": []string{fmt.Sprintf("%s-%d", opts.stream, letter.Sequence)}}
+		ack, err := source.Publish(subject, header, letter.data)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(w, "%s %d (%s): %s\n", output.Failure("Failed"), letter.Sequence, letter.Function, err)
+		case ack.Duplicate:
+			fmt.Fprintf(w, "%s %d (%s), it was already re-submitted to %s\n", output.Warning("Skipped"), letter.Sequence, letter.Function, subject)
+		default:
+			fmt.Fprintf(w, "%s %d (%s) to %s, as sequence %d of %s\n", output.Success("Re-submitted"), letter.Sequence, letter.Function, subject, ack.Sequence, ack.Stream)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d request(s) could not be re-submitted", failed, len(selected))
+	}
+	return nil
+}
+
+// selectDeadLetters picks the requests given by --seq, or every one with
+// --all. Only requests from the gateway can be re-submitted.
+func selectDeadLetters(letters []deadLetter, opts deadLetterOptions) ([]deadLetter, error) {
+	bySequence := map[uint64]deadLetter{}
+	for _, letter := range letters {
+		bySequence[letter.Sequence] = letter
+	}
+
+	var selected []deadLetter
+	if opts.all {
+		selected = letters
+	} else {
+		for _, seq := range opts.sequences {
+			letter, ok := bySequence[uint64(seq)]
+			if !ok {
+				if len(opts.function) > 0 {
+					return nil, fmt.Errorf("no dead-lettered request %d for %s in stream %s", seq, opts.function, opts.stream)
+				}
+				return nil, fmt.Errorf("no dead-lettered request %d in stream %s", seq, opts.stream)
+			}
+			selected = append(selected, letter)
+		}
+	}
+
+	for _, letter := range selected {
+		if letter.request == nil {
+			return nil, fmt.Errorf("message %d of stream %s is not a request from the gateway, so can't be re-submitted", letter.Sequence, opts.stream)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no dead-lettered requests to re-submit in stream %s", opts.stream)
+	}
+	return selected, nil
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/jetstream"
+	"github.com/openfaas/faas-provider/types"
+)
+
+type fakeDeadLetters struct {
+	messages  []*jetstream.StoredMsg
+	published []string
+	duplicate map[uint64]bool
+	stream    string
+}
+
+func (f *fakeDeadLetters) Messages(stream, subject string, fn func(*jetstream.StoredMsg) error) error {
+	f.stream = stream
+	for _, msg := range f.messages {
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeDeadLetters) Publish(subject string, header http.Header, data []byte) (*jetstream.PubAck, error) {
+	f.published = append(f.published, subject+" "+header.Get("Nats-Msg-Id"))
+	if subject == "broken" {
+		return nil, fmt.Errorf("no stream stores the subject %s", subject)
+	}
+	return &jetstream.PubAck{Stream: "faas-request", Sequence: uint64(100 + len(f.published)), Duplicate: strings.HasSuffix(header.Get("Nats-Msg-Id"), "-9")}, nil
+}
+
+func (f *fakeDeadLetters) Close() error { return nil }
+
+func queueRequestMsg(t *testing.T, seq uint64, function, queueName, reason string, body []byte) *jetstream.StoredMsg {
+	t.Helper()
+	data, err := json.Marshal(types.QueueRequest{
+		Function:  function,
+		Method:    http.MethodPost,
+		Path:      "/",
+		Header:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:      body,
+		QueueName: queueName,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &jetstream.StoredMsg{
+		Sequence: seq,
+		Subject:  "faas-dead-letters." + function,
+		Data:     data,
+		Time:     time.Date(2023, 10, 1, 11, 0, 0, 0, time.UTC),
+		Header:   http.Header{},
+	}
+	if len(reason) > 0 {
+		msg.Header.Set(deadLetterErrorHeader, reason)
+	}
+	return msg
+}
+
+func stubDeadLetters(t *testing.T, source *fakeDeadLetters) {
+	t.Helper()
+	dial, now := dialDeadLetters, deadLetterNow
+	t.Cleanup(func() {
+		dialDeadLetters, deadLetterNow = dial, now
+		deadLetterFlags = deadLetterOptions{
+			natsURL:     jetstream.DefaultURL,
+			stream:      deadLetterStream,
+			errorHeader: deadLetterErrorHeader,
+			timeout:     5 * time.Second,
+			sequences:   []uint{},
+		}
+	})
+
+	dialDeadLetters = func(natsURL string, timeout time.Duration) (deadLetterSource, error) { return source, nil }
+	deadLetterNow = func() time.Time { return time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC) }
+}
+
+func Test_queueDeadLettersList(t *testing.T) {
+	resetForTest()
+	source := &fakeDeadLetters{messages: []*jetstream.StoredMsg{}}
+	source.messages = append(source.messages,
+		queueRequestMsg(t, 3, "resize-image", "", "exit status 1: image too large", []byte(`{"url":"a.png"}`)),
+		queueRequestMsg(t, 7, "figlet", "", "context deadline exceeded", []byte("hi")),
+		&jetstream.StoredMsg{Sequence: 8, Subject: "faas-dead-letters.other", Data: []byte("not a request")},
+	)
+	stubDeadLetters(t, source)
+
+	var buf bytes.Buffer
+	faasCmd.SetOut(&buf)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs([]string{"queue", "dead-letters", "list", "--function", "resize-image", "--stream", "dlq"})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if source.stream != "dlq" {
+		t.Errorf("want the stream from --stream, got: %s", source.stream)
+	}
+	for _, want := range []string{"SEQ", "resize-image", "POST", "2023-10-01T11:00:00Z", "exit status 1: image too large"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "figlet") {
+		t.Errorf("want only the requests of --function, got:\n%s", out)
+	}
+}
+
+func Test_exportDeadLetters(t *testing.T) {
+	letters, err := readDeadLetters(&fakeDeadLetters{messages: []*jetstream.StoredMsg{
+		queueRequestMsg(t, 3, "resize-image", "", "exit status 1", []byte(`{"url":"a.png"}`)),
+		queueRequestMsg(t, 4, "resize-image", "", "", []byte{0xff, 0xfe}),
+	}}, deadLetterOptions{errorHeader: deadLetterErrorHeader})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := exportDeadLetters(&buf, letters); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want a line per request, got:\n%s", buf.String())
+	}
+
+	var first, second deadLetter
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &second)
+	if first.Sequence != 3 || first.Function != "resize-image" || first.Body != `{"url":"a.png"}` || first.Error != "exit status 1" || first.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected export: %s", lines[0])
+	}
+	if !bytes.Equal(second.BodyBase64, []byte{0xff, 0xfe}) || second.Body != "" {
+		t.Errorf("want a binary payload as base64, got: %s", lines[1])
+	}
+}
+
+func Test_queueDeadLettersResubmit(t *testing.T) {
+	resetForTest()
+	source := &fakeDeadLetters{messages: []*jetstream.StoredMsg{
+		queueRequestMsg(t, 3, "resize-image", "", "exit status 1", nil),
+		queueRequestMsg(t, 5, "resize-image", "images", "exit status 1", nil),
+		queueRequestMsg(t, 9, "resize-image", "", "exit status 1", nil),
+		{Sequence: 10, Subject: "faas-dead-letters.other", Data: []byte("not a request")},
+	}}
+	stubDeadLetters(t, source)
+
+	var buf bytes.Buffer
+	faasCmd.SetOut(&buf)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs([]string{"queue", "dead-letters", "resubmit", "--seq", "3", "--seq", "5", "--seq", "9"})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"faas-request faas-dead-letters-3", "images faas-dead-letters-5", "faas-request faas-dead-letters-9"}
+	if strings.Join(source.published, ",") != strings.Join(want, ",") {
+		t.Errorf("want: %q, got: %q", want, source.published)
+	}
+	for _, want := range []string{"Re-submitted 3 (resize-image) to faas-request, as sequence 101", "Skipped 9"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("want %q in:\n%s", want, buf.String())
+		}
+	}
+
+	faasCmd.SetArgs([]string{"queue", "dead-letters", "resubmit", "--seq", "10"})
+	if err := faasCmd.Execute(); err == nil || !strings.Contains(err.Error(), "not a request from the gateway") {
+		t.Errorf("want an error for a message which isn't a request, got: %v", err)
+	}
+}
+
+func Test_queueDeadLettersResubmit_NeedsSelection(t *testing.T) {
+	resetForTest()
+	stubDeadLetters(t, &fakeDeadLetters{})
+
+	faasCmd.SetArgs([]string{"queue", "dead-letters", "resubmit"})
+	if err := faasCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--seq, or --all") {
+		t.Errorf("want an error without --seq or --all, got: %v", err)
+	}
+}
+
+func Test_truncateDeadLetterError(t *testing.T) {
+	if got := truncateDeadLetterError("line one\nline two"); got != "line one…" {
+		t.Errorf("want the first line, got: %q", got)
+	}
+	if got := truncateDeadLetterError(strings.Repeat("x", 100)); len([]rune(got)) != 60 {
+		t.Errorf("want 60 characters, got: %d", len([]rune(got)))
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// readOnlyEnvironment is the default for --read-only
+const readOnlyEnvironment = "OPENFAAS_READ_ONLY"
+
+// readOnly refuses to run the commands which change the gateway, its
+// functions or a registry
+var readOnly bool
+
+// mutatingAnnotation marks the commands refused by --read-only, a command
+// inherits it from its parent so that a new subcommand of a group which makes
+// changes is refused too
+const mutatingAnnotation = "com.openfaas.read-only.mutating"
+
+// markMutating marks a command as making changes, call it where the command
+// is added
+func markMutating(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[mutatingAnnotation] = "true"
+}
+
+// isMutating reports whether the command or one of its parents is marked
+func isMutating(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[mutatingAnnotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// mutatingWhen has the commands which only make changes with some of their
+// flags or arguments, such as invoke --async, which queues the invocation.
+// Each check names the change, or returns "" when there is none.
+var mutatingWhen = map[*cobra.Command]func(cmd *cobra.Command, args []string) string{}
+
+// markMutatingWhen marks a command as making changes when the check names
+// one, call it where the command is added
+func markMutatingWhen(cmd *cobra.Command, check func(cmd *cobra.Command, args []string) string) {
+	mutatingWhen[cmd] = check
+}
+
+func init() {
+	readOnlyDefault, _ := strconv.ParseBool(os.Getenv(readOnlyEnvironment))
+	faasCmd.PersistentFlags().BoolVar(&readOnly, "read-only", readOnlyDefault, "Refuse to run commands which change functions, secrets or the gateway, for inspection only, also set by "+readOnlyEnvironment+"=true")
+
+	faasCmd.PersistentPreRunE = checkReadOnly
+}
+
+// checkReadOnly is run before every command. A function which is invoked
+// synchronously, such as by invoke or test record, is only run, so that is
+// allowed for inspection, but an invocation which is queued is refused.
+func checkReadOnly(cmd *cobra.Command, args []string) error {
+	if !readOnly {
+		return nil
+	}
+
+	change := ""
+	if isMutating(cmd) {
+		change = cmd.CommandPath()
+	} else if check, ok := mutatingWhen[cmd]; ok {
+		change = check(cmd, args)
+	}
+
+	if len(change) > 0 {
+		return fmt.Errorf("%q makes changes, so can't be run with --read-only or %s=true", change, readOnlyEnvironment)
+	}
+	return nil
+}
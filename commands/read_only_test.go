@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// mutatingCommands are the commands which --read-only must refuse, a command
+// which makes changes is added here when it is marked with markMutating
+var mutatingCommands = []string{
+	"faas-cli apply-changeset",
+	"faas-cli deploy",
+	"faas-cli exec",
+	"faas-cli gateway rotate-password",
+	"faas-cli ingress create",
+	"faas-cli maintenance disable",
+	"faas-cli maintenance enable",
+	"faas-cli publish",
+	"faas-cli push",
+	"faas-cli queue dead-letters resubmit",
+	"faas-cli release",
+	"faas-cli remove",
+	"faas-cli run-job",
+	"faas-cli scale-test",
+	"faas-cli schedule create",
+	"faas-cli schedule delete",
+	"faas-cli secret create",
+	"faas-cli secret remove",
+	"faas-cli secret update",
+	"faas-cli seed",
+	"faas-cli store deploy",
+	"faas-cli up",
+}
+
+func Test_markMutating_Commands(t *testing.T) {
+	var marked []string
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if cmd.Runnable() && isMutating(cmd) {
+			marked = append(marked, cmd.CommandPath())
+		}
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(faasCmd)
+	sort.Strings(marked)
+
+	if !reflect.DeepEqual(marked, mutatingCommands) {
+		t.Errorf("want the commands refused by --read-only:\n%s\ngot:\n%s", strings.Join(mutatingCommands, "\n"), strings.Join(marked, "\n"))
+	}
+}
+
+func Test_readOnly_RefusesMutatingCommands(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	var buf bytes.Buffer
+	faasCmd.SetOut(&buf)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs([]string{"deploy", "--read-only", "--image", "alexellis/figlet", "--name", "figlet"})
+	err := faasCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `"faas-cli deploy" makes changes`) {
+		t.Fatalf("want deploy refused with --read-only, got: %v", err)
+	}
+
+	faasCmd.SetArgs([]string{"secret", "remove", "--read-only", "api-key"})
+	if err := faasCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--read-only") {
+		t.Fatalf("want secret remove refused with --read-only, got: %v", err)
+	}
+
+	faasCmd.SetArgs([]string{"--read-only", "release", "--bump", "patch"})
+	if err := faasCmd.Execute(); err == nil || !strings.Contains(err.Error(), `"faas-cli release" makes changes`) {
+		t.Fatalf("want release refused with --read-only, got: %v", err)
+	}
+}
+
+func Test_checkReadOnly_QueuedInvocation(t *testing.T) {
+	savedReadOnly, savedAsync := readOnly, invokeAsync
+	defer func() { readOnly, invokeAsync = savedReadOnly, savedAsync }()
+	readOnly = true
+
+	invokeAsync = false
+	if err := checkReadOnly(invokeCmd, []string{"figlet"}); err != nil {
+		t.Errorf("want a synchronous invocation allowed, got: %s", err)
+	}
+
+	invokeAsync = true
+	if err := checkReadOnly(invokeCmd, []string{"figlet"}); err == nil || !strings.Contains(err.Error(), `"faas-cli invoke --async" makes changes`) {
+		t.Errorf("want a queued invocation refused, got: %v", err)
+	}
+}
+
+func Test_checkReadOnly_CopyIntoFunction(t *testing.T) {
+	savedReadOnly := readOnly
+	defer func() { readOnly = savedReadOnly }()
+	readOnly = true
+
+	if err := checkReadOnly(cpCmd, []string{"env-dump:/tmp/heap.pprof", "./heap.pprof"}); err != nil {
+		t.Errorf("want a copy from a function allowed, got: %s", err)
+	}
+	if err := checkReadOnly(cpCmd, []string{"./config.json", "env-dump:/tmp/config.json"}); err == nil || !strings.Contains(err.Error(), "makes changes") {
+		t.Errorf("want a copy into a function refused, got: %v", err)
+	}
+}
+
+func Test_readOnly_AllowsInspection(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	var buf bytes.Buffer
+	faasCmd.SetOut(&buf)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs([]string{"version", "--short-version", "--read-only"})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatalf("want version to run with --read-only, got: %v", err)
+	}
+}
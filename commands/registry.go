@@ -0,0 +1,216 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	registryTagsLimit    int
+	registryTagsParallel int
+)
+
+func init() {
+	registryTagsCmd.Flags().IntVar(&registryTagsLimit, "limit", 20, "Only show the most recent tags, 0 shows every tag")
+	registryTagsCmd.Flags().IntVar(&registryTagsParallel, "parallel", 8, "Number of tags to look up at once")
+	registryTagsCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+
+	registryCmd.AddCommand(registryTagsCmd)
+	faasCmd.AddCommand(registryCmd)
+}
+
+var registryCmd = &cobra.Command{
+	Use:   `registry [tags]`,
+	Short: "Query the images in a container registry",
+}
+
+var registryTagsCmd = &cobra.Command{
+	Use:   `tags IMAGE|FUNCTION_NAME [--limit N]`,
+	Short: "List the tags of an image, with their digests and creation dates",
+	Long: `Lists the tags of an image through the registry's v2 API, newest first, using
+the credentials saved by "docker login". When the name of a function in the
+stack file is given, the repository of its image is used.
+
+Tags which point at the same digest are the same image. A tag whose manifest
+can't be read, for instance after the registry's garbage collection, is shown
+as unavailable, and should not be used as a rollback target.`,
+	Example: `  faas-cli registry tags ghcr.io/openfaas/figlet
+  faas-cli registry tags figlet -f stack.yml
+  faas-cli registry tags ghcr.io/openfaas/figlet --limit 0`,
+	SilenceUsage: true,
+	RunE:         runRegistryTags,
+}
+
+// imageTag is one tag of a repository, Err is set when it could not be read
+type imageTag struct {
+	Tag     string
+	Digest  string
+	Created time.Time
+	Err     error
+}
+
+// listImageTags and describeImageTag are replaced by tests, crane uses the
+// credential commands of registry-login, then the docker config file
+var listImageTags = func(repository string) ([]string, error) {
+	return crane.ListTags(repository, registryAuth())
+}
+
+var describeImageTag = func(ref string) (string, time.Time, error) {
+	digest, err := crane.Digest(ref, registryAuth())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	data, err := crane.Config(ref, registryAuth())
+	if err != nil {
+		return digest, time.Time{}, err
+	}
+
+	var config struct {
+		Created time.Time `json:"created"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return digest, time.Time{}, err
+	}
+	return digest, config.Created, nil
+}
+
+func runRegistryTags(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the image or function name")
+	}
+	if registryTagsParallel < 1 {
+		return fmt.Errorf("the --parallel flag must be greater than 0")
+	}
+
+	repository, err := registryRepository(args[0])
+	if err != nil {
+		return err
+	}
+
+	tags, err := fetchImageTags(repository, registryTagsParallel)
+	if err != nil {
+		return err
+	}
+
+	if len(tags) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No tags found for: %s\n", repository)
+		return nil
+	}
+
+	if registryTagsLimit > 0 && len(tags) > registryTagsLimit {
+		tags = tags[:registryTagsLimit]
+	}
+
+	printImageTags(cmd.OutOrStdout(), tags)
+	return nil
+}
+
+// registryRepository uses the image of a function in the stack file, when
+// name matches one, and removes any tag or digest
+func registryRepository(name string) (string, error) {
+	image := name
+
+	if len(yamlFile) > 0 && !strings.ContainsAny(name, "/:") {
+		services, err := stack.ParseYAMLFile(yamlFile, "", "", envsubst)
+		if err != nil {
+			return "", err
+		}
+		if function, ok := services.Functions[name]; ok {
+			image = function.Image
+		}
+	}
+
+	if i := strings.Index(image, "@"); i > -1 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+
+	return image, nil
+}
+
+// fetchImageTags looks up each tag in parallel, then orders them newest first,
+// with any which could not be read last
+func fetchImageTags(repository string, parallel int) ([]imageTag, error) {
+	names, err := listImageTags(repository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tags for %s: %w", repository, err)
+	}
+
+	tags := make([]imageTag, len(names))
+	work := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				digest, created, err := describeImageTag(repository + ":" + names[i])
+				tags[i] = imageTag{Tag: names[i], Digest: digest, Created: created, Err: err}
+			}
+		}()
+	}
+
+	for i := range names {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		if (tags[i].Err == nil) != (tags[j].Err == nil) {
+			return tags[i].Err == nil
+		}
+		if !tags[i].Created.Equal(tags[j].Created) {
+			return tags[i].Created.After(tags[j].Created)
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	return tags, nil
+}
+
+func printImageTags(w io.Writer, tags []imageTag) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "TAG\tDIGEST\tCREATED")
+	for _, tag := range tags {
+		if tag.Err != nil {
+			fmt.Fprintf(tw, "%s\t%s\tunavailable: %s\n", tag.Tag, shortDigest(tag.Digest), tag.Err)
+			continue
+		}
+
+		created := "-"
+		if !tag.Created.IsZero() {
+			created = tag.Created.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", tag.Tag, shortDigest(tag.Digest), created)
+	}
+}
+
+func shortDigest(digest string) string {
+	if len(digest) == 0 {
+		return "-"
+	}
+	if i := strings.Index(digest, ":"); i > -1 && len(digest) > i+13 {
+		return digest[:i+13]
+	}
+	return digest
+}
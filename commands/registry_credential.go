@@ -0,0 +1,41 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+// execKeychain runs the credential command which registry-login saved for a
+// registry, registries without one are anonymous so that the docker config
+// file is tried next
+type execKeychain struct{}
+
+func (execKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	registry, err := config.LookupRegistryConfig(target.RegistryStr())
+	if err != nil {
+		return authn.Anonymous, nil
+	}
+
+	credential, err := proxy.RunExecCredential(registry.Exec, proxy.ExecCredentialRegistry, registry.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(credential.Token) > 0 {
+		return &authn.Bearer{Token: credential.Token}, nil
+	}
+	return &authn.Basic{Username: credential.Username, Password: credential.Password}, nil
+}
+
+// registryKeychain is used for every call to a registry
+var registryKeychain = authn.NewMultiKeychain(execKeychain{}, authn.DefaultKeychain)
+
+// registryAuth gives crane the credentials of registryKeychain
+func registryAuth() crane.Option {
+	return crane.WithAuthFromKeychain(registryKeychain)
+}
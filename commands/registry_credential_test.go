@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/openfaas/faas-cli/config"
+)
+
+func Test_execKeychain(t *testing.T) {
+	t.Setenv(config.ConfigLocationEnv, t.TempDir())
+
+	script := filepath.Join(t.TempDir(), "creds.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho '{\"username\": \"ci\", \"password\": \"'$FAAS_CLI_CREDENTIAL_SERVER'\"}'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveRegistryExecCredential("ghcr.io", script); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, _ := name.NewRegistry("ghcr.io")
+	auth, err := execKeychain{}.Resolve(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := auth.Authorization()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Username != "ci" || cfg.Password != "ghcr.io" {
+		t.Errorf("want the command's credentials, got: %+v", cfg)
+	}
+
+	other, _ := name.NewRegistry("quay.io")
+	if auth, err := (execKeychain{}).Resolve(other); err != nil || auth != authn.Anonymous {
+		t.Errorf("want anonymous for a registry without a command, got: %v, %v", auth, err)
+	}
+}
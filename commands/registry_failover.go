@@ -0,0 +1,43 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/openfaas/faas-cli/builder"
+)
+
+// registryFailover deploys an image from a mirror in configuration.registries
+// when it can't be found in its own registry
+var registryFailover bool
+
+// headImage is replaced by tests, crane uses the credentials from registry-login
+// and docker login
+var headImage = func(image string) error {
+	_, err := crane.Head(image, registryAuth())
+	return err
+}
+
+// failoverImage returns image if its registry has it, or otherwise the first
+// mirror which does, in the order of registries
+func failoverImage(image string, registries []string) (string, error) {
+	primaryErr := headImage(image)
+	if primaryErr == nil {
+		return image, nil
+	}
+
+	for _, registry := range registries {
+		mirror := builder.MirrorImage(image, registry)
+		if err := headImage(mirror); err == nil {
+			fmt.Printf("Image %s is unavailable, deploying the mirror: %s\n", image, mirror)
+			return mirror, nil
+		}
+	}
+
+	return "", fmt.Errorf("image %s is unavailable (%s), and was not found in any of the registries: %s",
+		image, primaryErr, strings.Join(registries, ", "))
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_failoverImage(t *testing.T) {
+	available := map[string]bool{
+		"dr2.example.com/acme/fn:0.1": true,
+	}
+
+	head := headImage
+	headImage = func(image string) error {
+		if available[image] {
+			return nil
+		}
+		return fmt.Errorf("not found")
+	}
+	defer func() { headImage = head }()
+
+	registries := []string{"dr1.example.com/acme", "dr2.example.com/acme"}
+
+	got, err := failoverImage("ghcr.io/acme/fn:0.1", registries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "dr2.example.com/acme/fn:0.1" {
+		t.Fatalf("want the first mirror with the image, got: %s", got)
+	}
+
+	available["ghcr.io/acme/fn:0.1"] = true
+	if got, _ := failoverImage("ghcr.io/acme/fn:0.1", registries); got != "ghcr.io/acme/fn:0.1" {
+		t.Fatalf("want the image itself when it is available, got: %s", got)
+	}
+
+	if _, err := failoverImage("ghcr.io/acme/other:0.1", registries); err == nil || !strings.Contains(err.Error(), "dr1.example.com/acme") {
+		t.Fatalf("want an error listing the registries, got: %v", err)
+	}
+}
@@ -12,13 +12,25 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/proxy"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var registryLoginCommand = &cobra.Command{
-	Use:          "registry-login",
-	Short:        "Generate and save the registry authentication file",
+	Use:   "registry-login",
+	Short: "Generate and save the registry authentication file",
+	Long: `Generate and save the registry authentication file, ./credentials/config.json
+
+With --exec-credential, no file is written. The command is saved for the
+--server instead, and run whenever the CLI calls the registry, e.g. to list
+tags or resolve digests. It must print JSON to stdout with either a "token",
+or a "username" and "password", and optionally an "expiresAt" time in RFC3339.
+FAAS_CLI_CREDENTIAL_KIND is set to "registry" and FAAS_CLI_CREDENTIAL_SERVER
+to the registry's host for the command.`,
+	Example: `  faas-cli registry-login --username user --password-stdin
+  faas-cli registry-login --server ghcr.io --exec-credential "vault-registry-creds ghcr"`,
 	SilenceUsage: true,
 	RunE:         generateRegistryAuthFile,
 	PreRunE:      generateRegistryPreRun,
@@ -30,6 +42,8 @@ func init() {
 	registryLoginCommand.Flags().String("password", "", "The registry password")
 	registryLoginCommand.Flags().BoolP("password-stdin", "s", false, "Reads the docker password from stdin, either pipe to the command or remember to press ctrl+d when reading interactively")
 
+	registryLoginCommand.Flags().String("exec-credential", "", "A command which prints the registry's credentials as JSON each time they are needed, instead of writing them to a file")
+
 	registryLoginCommand.Flags().Bool("ecr", false, "If we are using ECR we need a different set of flags, so if this is set, we need to set --account-id and --region")
 	registryLoginCommand.Flags().String("account-id", "", "Your AWS Account id")
 	registryLoginCommand.Flags().String("region", "", "Your AWS region")
@@ -73,6 +87,19 @@ func generateRegistryPreRun(command *cobra.Command, args []string) error {
 		return fmt.Errorf("error with --region usage: %s", err)
 	}
 
+	execCredential, err := command.Flags().GetString("exec-credential")
+	if err != nil {
+		return fmt.Errorf("error with --exec-credential usage: %s", err)
+	}
+	if len(execCredential) > 0 {
+		if ecr {
+			return fmt.Errorf("--exec-credential can't be used with --ecr")
+		}
+		if len(strings.Fields(execCredential)) == 0 {
+			return fmt.Errorf("give the command to run with --exec-credential")
+		}
+	}
+
 	if ecr {
 		if len(accountID) == 0 {
 			return fmt.Errorf("the --account-id flag is required with ECR")
@@ -93,6 +120,11 @@ func generateRegistryAuthFile(command *cobra.Command, _ []string) error {
 	password, _ := command.Flags().GetString("password")
 	server, _ := command.Flags().GetString("server")
 	passStdin, _ := command.Flags().GetBool("password-stdin")
+	execCredential, _ := command.Flags().GetString("exec-credential")
+
+	if len(execCredential) > 0 {
+		return saveRegistryExecCredential(server, execCredential)
+	}
 
 	if ecrEnabled {
 		if err := generateECRFile(accountID, region); err != nil {
@@ -119,6 +151,27 @@ func generateRegistryAuthFile(command *cobra.Command, _ []string) error {
 	return nil
 }
 
+// saveRegistryExecCredential runs the command once to check what it prints,
+// then saves it for the registry
+func saveRegistryExecCredential(server, command string) error {
+	fields := strings.Fields(command)
+	registry := config.RegistryConfig{
+		Server: config.RegistryServer(server),
+		Exec:   config.ExecConfig{Command: fields[0], Args: fields[1:]},
+	}
+
+	if _, err := proxy.RunExecCredential(registry.Exec, proxy.ExecCredentialRegistry, registry.Server); err != nil {
+		return err
+	}
+
+	if err := config.UpdateRegistryConfig(registry); err != nil {
+		return err
+	}
+
+	fmt.Printf("Credentials for %s will be fetched by: %s\n", registry.Server, registry.Exec.String())
+	return nil
+}
+
 func generateFile(username string, password string, server string) error {
 
 	fileBytes, err := generateRegistryAuth(server, username, password)
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_registryRepository(t *testing.T) {
+	resetForTest()
+
+	cases := map[string]string{
+		"ghcr.io/openfaas/figlet:0.1":           "ghcr.io/openfaas/figlet",
+		"localhost:5000/figlet":                 "localhost:5000/figlet",
+		"localhost:5000/figlet:latest":          "localhost:5000/figlet",
+		"ghcr.io/openfaas/figlet@sha256:abcdef": "ghcr.io/openfaas/figlet",
+	}
+
+	for image, want := range cases {
+		got, err := registryRepository(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: want %s, got %s", image, want, got)
+		}
+	}
+}
+
+func Test_fetchImageTags_NewestFirst(t *testing.T) {
+	list, describe := listImageTags, describeImageTag
+	defer func() { listImageTags, describeImageTag = list, describe }()
+
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	listImageTags = func(repository string) ([]string, error) {
+		return []string{"0.1", "0.2", "deleted", "0.3"}, nil
+	}
+	describeImageTag = func(ref string) (string, time.Time, error) {
+		switch ref {
+		case "ghcr.io/acme/fn:0.1":
+			return "sha256:1111111111111111", day, nil
+		case "ghcr.io/acme/fn:0.2":
+			return "sha256:2222222222222222", day.Add(24 * time.Hour), nil
+		case "ghcr.io/acme/fn:0.3":
+			return "sha256:3333333333333333", day.Add(48 * time.Hour), nil
+		}
+		return "", time.Time{}, fmt.Errorf("MANIFEST_UNKNOWN")
+	}
+
+	tags, err := fetchImageTags("ghcr.io/acme/fn", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, tag := range tags {
+		order = append(order, tag.Tag)
+	}
+	if strings.Join(order, ",") != "0.3,0.2,0.1,deleted" {
+		t.Fatalf("want newest first and unavailable last, got: %v", order)
+	}
+
+	var out bytes.Buffer
+	printImageTags(&out, tags)
+	if !strings.Contains(out.String(), "sha256:333333333333 ") || !strings.Contains(out.String(), "unavailable: MANIFEST_UNKNOWN") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
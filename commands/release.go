@@ -0,0 +1,334 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	releaseBump      string
+	releaseChangelog string
+	releaseDryRun    bool
+)
+
+// releaseNow dates the changelog entry, and is replaced by tests
+var releaseNow = time.Now
+
+// releaseUp builds, pushes and deploys the released stack, and is replaced
+// by tests
+var releaseUp = upHandler
+
+// releaseGit runs git in dir, and is replaced by tests
+var releaseGit = func(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+var (
+	// semanticVersion is MAJOR.MINOR.PATCH, with an optional v
+	semanticVersion = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)$`)
+	// releaseVersionLine is the top-level release_version of the stack file
+	releaseVersionLine = regexp.MustCompile(`^release_version:.*$`)
+	// imageLine is an image: of the stack file, with any quotes and comment
+	imageLine = regexp.MustCompile(`^(\s+image:\s*)(["']?)([^"'\s#]+)(["']?)(\s*(#.*)?)$`)
+)
+
+func init() {
+	releaseCmd.Flags().StringVar(&releaseBump, "bump", "", "Part of the version to bump: major, minor or patch")
+	releaseCmd.Flags().StringVar(&releaseChangelog, "changelog", "CHANGELOG.md", "File to add the release's entry to, next to the stack file")
+	releaseCmd.Flags().BoolVar(&releaseDryRun, "dry-run", false, "Print the version, images and changelog entry without changing anything")
+	releaseCmd.Flags().BoolVar(&skipPush, "skip-push", false, "Skip pushing the functions' images")
+	releaseCmd.Flags().BoolVar(&skipDeploy, "skip-deploy", false, "Skip deploying the functions")
+
+	build, _, _ := faasCmd.Find([]string{"build"})
+	releaseCmd.Flags().AddFlagSet(build.Flags())
+
+	push, _, _ := faasCmd.Find([]string{"push"})
+	releaseCmd.Flags().AddFlagSet(push.Flags())
+
+	deploy, _, _ := faasCmd.Find([]string{"deploy"})
+	releaseCmd.Flags().AddFlagSet(deploy.Flags())
+
+	markMutating(releaseCmd)
+	faasCmd.AddCommand(releaseCmd)
+}
+
+var releaseCmd = &cobra.Command{
+	Use:   `release --bump major|minor|patch [--dry-run] [flags from build, push, deploy]`,
+	Short: "Bump the stack's version, tag it in git, then build, push and deploy it",
+	Long: `Releases the functions of a stack file as one version, which is kept in the
+stack file's release_version field, as the version field is that of its schema:
+
+  version: 1.0
+  release_version: 1.4.0
+
+The version is bumped by --bump, and the image of each function which is built
+is tagged with it, e.g. ghcr.io/acme/orders:1.5.0. The subjects of the commits
+since the tag of the last release are added to the changelog as the release's
+entry, then the stack file and changelog are committed, and tagged as v1.5.0.
+Finally the functions are built, pushed and deployed, as by "faas-cli up".
+
+The working tree must be clean, so that the commit only has the release. Push
+the commit and tag with "git push --follow-tags" once it is deployed. With
+--dry-run, the changes are printed, and nothing is changed.`,
+	Example: `  faas-cli release --bump minor
+  faas-cli release --bump patch --dry-run
+  faas-cli release --bump major -f stack.prod.yml --changelog docs/CHANGELOG.md
+  faas-cli release --bump patch --skip-deploy`,
+	PreRunE: preRunRelease,
+	RunE:    runRelease,
+}
+
+func preRunRelease(cmd *cobra.Command, args []string) error {
+	switch releaseBump {
+	case "major", "minor", "patch":
+	case "":
+		return fmt.Errorf("give the part of the version to bump with --bump major, minor or patch")
+	default:
+		return fmt.Errorf("--bump must be major, minor or patch, got: %q", releaseBump)
+	}
+	return preRunUp(cmd, args)
+}
+
+func runRelease(cmd *cobra.Command, args []string) error {
+	// The stack is released as a whole, and the images are matched to the
+	// lines of the file, so variables are not substituted
+	services, err := stack.ParseYAMLFile(yamlFile, "", "", false)
+	if err != nil {
+		return err
+	}
+
+	current := services.ReleaseVersion
+	if len(current) == 0 {
+		current = "0.0.0"
+	}
+	next, err := bumpVersion(current, releaseBump)
+	if err != nil {
+		return fmt.Errorf("release_version of %s: %w", yamlFile, err)
+	}
+	tag := "v" + next
+	dir := filepath.Dir(yamlFile)
+
+	if !releaseDryRun {
+		if status, err := releaseGit(dir, "status", "--porcelain"); err != nil {
+			return err
+		} else if len(status) > 0 {
+			return fmt.Errorf("commit or stash your changes first, the release commit must only have the release:\n%s", status)
+		}
+		if _, err := releaseGit(dir, "rev-parse", "--quiet", "--verify", "refs/tags/"+tag); err == nil {
+			return fmt.Errorf("the tag %s already exists", tag)
+		}
+	}
+
+	commits, since, err := releaseCommits(dir, "v"+strings.TrimPrefix(current, "v"))
+	if err != nil {
+		return err
+	}
+
+	edit, err := releaseStackEdit(yamlFile, services, next)
+	if err != nil {
+		return err
+	}
+	entry := releaseEntry(tag, releaseNow(), commits, since)
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Release %s, from %s\n\n", tag, current)
+	writeLineDiff(out, edit)
+	fmt.Fprintf(out, "\n%s", entry)
+
+	if releaseDryRun {
+		return nil
+	}
+
+	if err := os.WriteFile(edit.path, []byte(strings.Join(edit.after, "\n")), edit.mode); err != nil {
+		return err
+	}
+	changelogPath := filepath.Join(dir, releaseChangelog)
+	if err := prependChangelog(changelogPath, entry); err != nil {
+		return err
+	}
+
+	message := "Release " + tag
+	for _, args := range [][]string{
+		{"add", "--", filepath.Base(yamlFile), releaseChangelog},
+		{"commit", "--quiet", "-m", message},
+		{"tag", "--annotate", tag, "-m", message},
+	} {
+		if _, err := releaseGit(dir, args...); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "\nCommitted and tagged: %s\n\n", tag)
+
+	if err := releaseUp(cmd, args); err != nil {
+		return fmt.Errorf("%s is tagged, but it was not deployed: %w, fix the cause then run: faas-cli up", tag, err)
+	}
+	fmt.Fprintf(out, "\nReleased %s, push it with: git push --follow-tags\n", tag)
+	return nil
+}
+
+// bumpVersion increments a part of a version, resetting the parts after it
+func bumpVersion(version, part string) (string, error) {
+	match := semanticVersion.FindStringSubmatch(strings.TrimSpace(version))
+	if match == nil {
+		return "", fmt.Errorf("want a version such as 1.4.0, got: %q", version)
+	}
+
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+
+	switch part {
+	case "major":
+		major, minor, patch = major+1, 0, 0
+	case "minor":
+		minor, patch = minor+1, 0
+	case "patch":
+		patch++
+	}
+	return fmt.Sprintf("%d.%d.%d", major, minor, patch), nil
+}
+
+// releaseCommits lists the subjects of the commits since the tag of the
+// last release, or every commit when it has no tag, which is returned as ""
+func releaseCommits(dir, previous string) ([]string, string, error) {
+	since := previous
+	revisions := previous + "..HEAD"
+	if _, err := releaseGit(dir, "rev-parse", "--quiet", "--verify", "refs/tags/"+previous); err != nil {
+		since, revisions = "", "HEAD"
+	}
+
+	out, err := releaseGit(dir, "log", "--no-merges", "--format=%s", revisions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var commits []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			commits = append(commits, line)
+		}
+	}
+	return commits, since, nil
+}
+
+func releaseEntry(tag string, now time.Time, commits []string, since string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s - %s\n\n", tag, now.Format("2006-01-02"))
+	if len(commits) == 0 {
+		fmt.Fprintf(&b, "- No changes since %s\n", since)
+	}
+	for _, commit := range commits {
+		fmt.Fprintf(&b, "- %s\n", commit)
+	}
+	return b.String()
+}
+
+// releaseStackEdit sets release_version in the stack file, and tags the
+// images of the functions which are built with the version. The edit is
+// checked by parsing it, as an image in the flow style of YAML can't be
+// matched by its line.
+func releaseStackEdit(path string, services *stack.Services, version string) (*dockerfileEdit, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	images := map[string]string{}
+	names := make([]string, 0, len(services.Functions))
+	for name, function := range services.Functions {
+		if function.SkipBuild || len(function.Image) == 0 {
+			continue
+		}
+		if strings.Contains(function.Image, "$") {
+			return nil, fmt.Errorf("the image of %s is set by a variable, so it can't be tagged with the version: %s", name, function.Image)
+		}
+		images[function.Image] = releaseImage(function.Image, version)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := strings.Split(string(data), "\n")
+	edit := &dockerfileEdit{path: path, mode: info.Mode().Perm(), before: lines, after: make([]string, len(lines))}
+	copy(edit.after, lines)
+
+	versionSet := false
+	for i, line := range lines {
+		if releaseVersionLine.MatchString(line) {
+			edit.after[i] = "release_version: " + version
+			versionSet = true
+			continue
+		}
+		if match := imageLine.FindStringSubmatch(line); match != nil {
+			if image, ok := images[match[3]]; ok {
+				edit.after[i] = match[1] + match[2] + image + match[4] + match[5]
+			}
+		}
+	}
+
+	if !versionSet {
+		// The version goes after the schema's version, or at the top
+		at := 0
+		for i, line := range edit.after {
+			if strings.HasPrefix(line, "version:") {
+				at = i + 1
+				break
+			}
+		}
+		edit.after = insertLine(edit.after, at, "release_version: "+version)
+		edit.before = insertLine(edit.before, at, "")
+	}
+	edit.changed = true
+
+	released, err := stack.ParseYAMLData([]byte(strings.Join(edit.after, "\n")), "", "", false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update %s: %w", path, err)
+	}
+	for _, name := range names {
+		want := images[services.Functions[name].Image]
+		if got := released.Functions[name].Image; got != want {
+			return nil, fmt.Errorf("unable to tag the image of %s in %s, set it to %s and run the release again", name, path, want)
+		}
+	}
+	return edit, nil
+}
+
+// releaseImage replaces the tag of an image with the version, an image
+// without a tag is latest, and any digest is dropped
+func releaseImage(image, version string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if repository, _, ok := splitImageTag(image); ok {
+		image = repository
+	}
+	return image + ":" + version
+}
+
+func insertLine(lines []string, at int, line string) []string {
+	inserted := make([]string, 0, len(lines)+1)
+	inserted = append(inserted, lines[:at]...)
+	inserted = append(inserted, line)
+	return append(inserted, lines[at:]...)
+}
@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_bumpVersion(t *testing.T) {
+	cases := []struct {
+		version, part, want string
+	}{
+		{"1.4.2", "patch", "1.4.3"},
+		{"1.4.2", "minor", "1.5.0"},
+		{"1.4.2", "major", "2.0.0"},
+		{"v0.9.9", "minor", "0.10.0"},
+	}
+	for _, c := range cases {
+		got, err := bumpVersion(c.version, c.part)
+		if err != nil || got != c.want {
+			t.Errorf("bump %s of %s: want %s, got %s: %v", c.part, c.version, c.want, got, err)
+		}
+	}
+
+	if _, err := bumpVersion("1.4", "patch"); err == nil {
+		t.Errorf("want an error for a version without a patch")
+	}
+}
+
+func Test_releaseImage(t *testing.T) {
+	cases := map[string]string{
+		"ghcr.io/acme/orders:0.1.0":           "ghcr.io/acme/orders:1.5.0",
+		"orders":                              "orders:1.5.0",
+		"localhost:5000/orders":               "localhost:5000/orders:1.5.0",
+		"orders:latest@sha256:0123456789abcd": "orders:1.5.0",
+	}
+	for image, want := range cases {
+		if got := releaseImage(image, "1.5.0"); got != want {
+			t.Errorf("%s: want %s, got %s", image, want, got)
+		}
+	}
+}
+
+const releaseTestStack = `version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    lang: go
+    handler: ./orders
+    image: "ghcr.io/acme/orders:dev" # built by CI
+  figlet:
+    skip_build: true
+    image: ghcr.io/openfaas/figlet:latest
+`
+
+func releaseTestGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := releaseGit(dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func newReleaseTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is needed for a release")
+	}
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "stack.yml"), []byte(releaseTestStack), 0644); err != nil {
+		t.Fatal(err)
+	}
+	releaseTestGit(t, dir, "init", "--quiet")
+	releaseTestGit(t, dir, "add", ".")
+	releaseTestGit(t, dir, "commit", "--quiet", "-m", "Add the orders function")
+	releaseTestGit(t, dir, "commit", "--quiet", "--allow-empty", "-m", "Validate the order's total")
+	return dir
+}
+
+func runReleaseTest(t *testing.T, dir string, dryRun bool) (string, error) {
+	yamlFile, releaseBump, releaseChangelog, releaseDryRun = filepath.Join(dir, "stack.yml"), "minor", "CHANGELOG.md", dryRun
+	releaseNow = func() time.Time { return time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC) }
+	deployed := false
+	releaseUp = func(cmd *cobra.Command, args []string) error {
+		deployed = true
+		return nil
+	}
+	defer func() {
+		yamlFile, releaseDryRun = "", false
+		releaseNow, releaseUp = time.Now, upHandler
+	}()
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	err := runRelease(cmd, nil)
+	if err == nil && deployed == dryRun {
+		t.Errorf("want deployed %v, with --dry-run %v", !dryRun, dryRun)
+	}
+	return out.String(), err
+}
+
+func Test_runRelease(t *testing.T) {
+	dir := newReleaseTestRepo(t)
+
+	out, err := runReleaseTest(t, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Released v0.1.0") {
+		t.Errorf("want the release printed, got:\n%s", out)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "stack.yml"))
+	stack := string(data)
+	for _, want := range []string{
+		"version: 1.0\nrelease_version: 0.1.0\n",
+		`    image: "ghcr.io/acme/orders:0.1.0" # built by CI`,
+		"    image: ghcr.io/openfaas/figlet:latest",
+	} {
+		if !strings.Contains(stack, want) {
+			t.Errorf("want %q in the stack file, got:\n%s", want, stack)
+		}
+	}
+
+	changelog, _ := os.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+	want := "# Changelog\n\n## v0.1.0 - 2023-06-01\n\n- Validate the order's total\n- Add the orders function\n"
+	if string(changelog) != want {
+		t.Errorf("want the changelog:\n%s\ngot:\n%s", want, changelog)
+	}
+
+	if got := releaseTestGit(t, dir, "tag", "--list"); got != "v0.1.0" {
+		t.Errorf("want the tag v0.1.0, got: %q", got)
+	}
+	if got := releaseTestGit(t, dir, "status", "--porcelain"); got != "" {
+		t.Errorf("want the release committed, got: %s", got)
+	}
+
+	// The next release's changelog only has the commits since the tag
+	releaseTestGit(t, dir, "commit", "--quiet", "--allow-empty", "-m", "Retry the payment")
+	if _, err := runReleaseTest(t, dir, false); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "stack.yml"))
+	if !strings.Contains(string(data), "release_version: 0.2.0\n") || !strings.Contains(string(data), "orders:0.2.0") {
+		t.Errorf("want the version bumped to 0.2.0, got:\n%s", data)
+	}
+	changelog, _ = os.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+	if !strings.HasPrefix(string(changelog), "# Changelog\n\n## v0.2.0 - 2023-06-01\n\n- Retry the payment\n\n## v0.1.0") {
+		t.Errorf("want the new entry from the commits since v0.1.0, got:\n%s", changelog)
+	}
+}
+
+func Test_runRelease_DryRun(t *testing.T) {
+	dir := newReleaseTestRepo(t)
+
+	out, err := runReleaseTest(t, dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"+release_version: 0.1.0", `+    image: "ghcr.io/acme/orders:0.1.0"`, "## v0.1.0 - 2023-06-01"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q printed, got:\n%s", want, out)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "stack.yml"))
+	if string(data) != releaseTestStack {
+		t.Errorf("want the stack file unchanged, got:\n%s", data)
+	}
+	if got := releaseTestGit(t, dir, "tag", "--list"); got != "" {
+		t.Errorf("want no tag, got: %q", got)
+	}
+}
+
+func Test_runRelease_DirtyTree(t *testing.T) {
+	dir := newReleaseTestRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runReleaseTest(t, dir, false); err == nil || !strings.Contains(err.Error(), "notes.txt") {
+		t.Fatalf("want the uncommitted change to stop the release, got: %v", err)
+	}
+}
@@ -6,13 +6,23 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/spf13/cobra"
 )
 
+var (
+	removeWait        bool
+	removeWaitTimeout time.Duration
+	// removePollInterval is how often the provider is asked whether a function has gone
+	removePollInterval = time.Second
+)
+
 func init() {
 	// Setup flags that are used by multiple commands (variables defined in faas.go)
 	removeCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
@@ -20,7 +30,10 @@ func init() {
 	removeCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
 	removeCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
 	removeCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+	removeCmd.Flags().BoolVar(&removeWait, "wait", false, "Wait until the provider no longer lists the function, so the name can be deployed again")
+	removeCmd.Flags().DurationVar(&removeWaitTimeout, "wait-timeout", 2*time.Minute, "How long to wait for the function to be removed, with --wait")
 
+	markMutating(removeCmd)
 	faasCmd.AddCommand(removeCmd)
 }
 
@@ -32,12 +45,19 @@ var removeCmd = &cobra.Command{
 	Short:   "Remove deployed OpenFaaS functions",
 	Long: `Removes/deletes deployed OpenFaaS functions either via the supplied YAML config
 using the "--yaml" flag (which may contain multiple function definitions), or by
-explicitly specifying a function name.`,
+explicitly specifying a function name.
+
+Functions from a YAML config are removed from each of their namespaces at the
+same time.
+
+With --wait, the command returns once the provider no longer lists the function,
+which avoids a redeployment of the same name picking up the old replicas.`,
 	Example: `  faas-cli remove -f https://domain/path/myfunctions.yml
   faas-cli remove -f ./stack.yml
   faas-cli remove -f ./stack.yml --filter "*gif*"
   faas-cli remove -f ./stack.yml --regex "fn[0-9]_.*"
   faas-cli remove url-ping
+  faas-cli remove url-ping --wait --wait-timeout 5m
   faas-cli remove img2ansi --gateway==http://remote-site.com:8080`,
 	RunE: runDelete,
 }
@@ -73,12 +93,33 @@ func runDelete(cmd *cobra.Command, args []string) error {
 
 	if len(services.Functions) > 0 {
 
+		namespaces := map[string]string{}
 		for k, function := range services.Functions {
-			function.Namespace = getNamespace(functionNamespace, function.Namespace)
-			function.Name = k
-			fmt.Printf("Deleting: %s.%s\n", function.Name, function.Namespace)
+			namespaces[k] = getNamespace(functionNamespace, function.Namespace)
+		}
+
+		var mu sync.Mutex
+		deleted := map[string]string{}
+		eachNamespace(groupByNamespace(namespaces), os.Stdout, func(group namespaceGroup, out io.Writer) {
+			groupClient := proxyclient.WithOutput(out)
+			for _, name := range group.names {
+				fmt.Fprintf(out, "Deleting: %s.%s\n", name, group.namespace)
 
-			proxyclient.DeleteFunction(ctx, function.Name, function.Namespace)
+				if err := groupClient.DeleteFunction(ctx, name, group.namespace); err == nil {
+					mu.Lock()
+					deleted[name] = group.namespace
+					mu.Unlock()
+				}
+			}
+		})
+
+		if removeWait {
+			deadline := time.Now().Add(removeWaitTimeout)
+			for name, namespace := range deleted {
+				if err := waitForRemoval(ctx, proxyclient, name, namespace, time.Until(deadline)); err != nil {
+					return err
+				}
+			}
 		}
 	} else {
 		if len(args) < 1 {
@@ -91,7 +132,51 @@ func runDelete(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+
+		if removeWait {
+			if err := waitForRemoval(ctx, proxyclient, functionName, functionNamespace, removeWaitTimeout); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
+
+// waitForRemoval polls the provider until the function is no longer listed in
+// its namespace, printing the number of replicas which remain as it changes
+func waitForRemoval(ctx context.Context, client *proxy.Client, name, namespace string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	lastReplicas := -1
+
+	for {
+		functions, err := client.ListFunctions(ctx, namespace)
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, function := range functions {
+			if function.Name != name {
+				continue
+			}
+			found = true
+
+			if replicas := int(function.AvailableReplicas); replicas != lastReplicas {
+				fmt.Printf("Waiting for: %s to be removed, %d replica(s) remaining\n", name, replicas)
+				lastReplicas = replicas
+			}
+		}
+
+		if !found {
+			fmt.Printf("Removed: %s\n", name)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("function %s was still present after %s", name, timeout.Round(time.Second))
+		}
+
+		time.Sleep(removePollInterval)
+	}
+}
@@ -4,11 +4,17 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	types "github.com/openfaas/faas-provider/types"
 
 	"github.com/openfaas/faas-cli/test"
 )
@@ -66,3 +72,43 @@ func Test_remove(t *testing.T) {
 		t.Error("test-function should be deleted.")
 	}
 }
+
+func Test_waitForRemoval(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		functions := []types.FunctionStatus{}
+		if calls < 3 {
+			functions = append(functions, types.FunctionStatus{Name: "fn1", AvailableReplicas: 1})
+		}
+		json.NewEncoder(w).Encode(functions)
+	}))
+	defer s.Close()
+
+	interval := removePollInterval
+	removePollInterval = time.Millisecond
+	defer func() { removePollInterval = interval }()
+
+	if err := waitForRemoval(context.Background(), newPrecheckClient(t, s.URL), "fn1", "", time.Second); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	if calls != 3 {
+		t.Fatalf("want 3 polls, got: %d", calls)
+	}
+}
+
+func Test_waitForRemoval_Timeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]types.FunctionStatus{{Name: "fn1"}})
+	}))
+	defer s.Close()
+
+	interval := removePollInterval
+	removePollInterval = time.Millisecond
+	defer func() { removePollInterval = interval }()
+
+	err := waitForRemoval(context.Background(), newPrecheckClient(t, s.URL), "fn1", "", 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "still present") {
+		t.Fatalf("want a timeout error, got: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/util"
+)
+
+// allowReservedEnv skips checkReservedEnvironment when an override is intended
+var allowReservedEnv bool
+
+// coreReservedEnvironment is set by every template, and changing it breaks
+// how the watchdog starts or reaches the function
+var coreReservedEnvironment = []string{
+	"fprocess",
+	"function_process",
+	"mode",
+	"upstream_url",
+	"port",
+	"prefix_logs",
+}
+
+// tunableEnvironment is meant to be set per function, even when a template
+// gives a default in its Dockerfile
+var tunableEnvironment = map[string]bool{
+	"read_timeout":         true,
+	"write_timeout":        true,
+	"exec_timeout":         true,
+	"healthcheck_interval": true,
+	"max_inflight":         true,
+}
+
+var dockerfileEnvPair = regexp.MustCompile(`(?:^|\s)([A-Za-z_][A-Za-z0-9_]*)=("[^"]*"|'[^']*'|\S*)`)
+
+// templateReservedEnvironment returns the watchdog settings which the language's
+// template sets for itself, from the core list and the ENV instructions of its
+// Dockerfile in ./template/
+func templateReservedEnvironment(language string) (map[string]bool, error) {
+	reserved := map[string]bool{}
+	for _, name := range coreReservedEnvironment {
+		reserved[name] = true
+	}
+
+	names, err := dockerfileEnvNames(filepath.Join(templateDirectory, language, "Dockerfile"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return reserved, nil
+		}
+		return nil, err
+	}
+
+	for _, name := range names {
+		if watchdogEnvironment[name] && !tunableEnvironment[name] {
+			reserved[name] = true
+		}
+	}
+
+	return reserved, nil
+}
+
+// dockerfileEnvNames lists the names set by ENV instructions, in either the
+// "ENV name=value ..." or "ENV name value" form
+func dockerfileEnvNames(dockerfile string) ([]string, error) {
+	names, _, err := readDockerfileEnv(dockerfile)
+	return names, err
+}
+
+// dockerfileEnv returns the values set by ENV instructions, the last one wins
+// when a name is set in more than one stage
+func dockerfileEnv(dockerfile string) (map[string]string, error) {
+	_, values, err := readDockerfileEnv(dockerfile)
+	return values, err
+}
+
+// readDockerfileEnv returns the names in the order they are set, and their values
+func readDockerfileEnv(dockerfile string) ([]string, map[string]string, error) {
+	f, err := os.Open(dockerfile)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var names []string
+	values := map[string]string{}
+	set := func(name, value string) {
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = strings.Trim(value, `"'`)
+	}
+
+	var instruction string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, `\`) {
+			instruction += strings.TrimSuffix(line, `\`) + " "
+			continue
+		}
+		instruction += line
+
+		fields := strings.Fields(instruction)
+		if len(fields) > 1 && strings.EqualFold(fields[0], "ENV") {
+			if strings.Contains(fields[1], "=") {
+				for _, match := range dockerfileEnvPair.FindAllStringSubmatch(strings.Join(fields[1:], " "), -1) {
+					set(match[1], match[2])
+				}
+			} else {
+				set(fields[1], strings.Join(fields[2:], " "))
+			}
+		}
+		instruction = ""
+	}
+
+	return names, values, scanner.Err()
+}
+
+// checkReservedEnvironment returns an error for each function which sets an
+// environment variable that its template relies on, through environment or
+// environment_file. Functions built from their own Dockerfile are skipped.
+func checkReservedEnvironment(functions map[string]stack.Function) error {
+	var problems []string
+
+	for name, function := range functions {
+		if !languageExistsNotDockerfile(function.Language) {
+			continue
+		}
+
+		overrides, err := reservedOverrides(function)
+		if err != nil {
+			return err
+		}
+
+		for _, variable := range overrides {
+			problems = append(problems, fmt.Sprintf("function '%s' sets %s, which is reserved by the %s template", name, variable, function.Language))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+	return fmt.Errorf("%s\nremove the variable from the stack file, or give --allow-reserved-env if the override is intended",
+		strings.Join(problems, "\n"))
+}
+
+// reservedOverrides lists the reserved variables set by a function
+func reservedOverrides(function stack.Function) ([]string, error) {
+	reserved, err := templateReservedEnvironment(function.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	fileEnvironment, err := readFiles(function.EnvironmentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides []string
+	for variable := range util.MergeMap(function.Environment, fileEnvironment) {
+		if reserved[variable] {
+			overrides = append(overrides, variable)
+		}
+	}
+
+	sort.Strings(overrides)
+	return overrides, nil
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_dockerfileEnvNames(t *testing.T) {
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+	contents := `FROM ghcr.io/openfaas/of-watchdog:0.9.10 as watchdog
+ENV fprocess="python index.py --opt=1"
+env mode="http" \
+    upstream_url="http://127.0.0.1:5000"
+ENV exec_timeout 10s
+RUN echo "ENV not_this=1"
+`
+	if err := os.WriteFile(dockerfile, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := dockerfileEnvNames(dockerfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"fprocess", "mode", "upstream_url", "exec_timeout"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("want: %v, got: %v", want, names)
+	}
+}
+
+func Test_checkReservedEnvironment(t *testing.T) {
+	functions := map[string]stack.Function{
+		"api": {
+			Language: "reserved-test-lang",
+			Environment: map[string]string{
+				"mode":          "streaming",
+				"write_timeout": "10s",
+			},
+		},
+		"custom": {
+			Language:    "dockerfile",
+			Environment: map[string]string{"fprocess": "./server"},
+		},
+	}
+
+	err := checkReservedEnvironment(functions)
+	if err == nil {
+		t.Fatalf("want an error for the override of mode")
+	}
+
+	if !strings.Contains(err.Error(), "function 'api' sets mode") {
+		t.Fatalf("want the override of mode to be reported, got: %s", err)
+	}
+	if strings.Contains(err.Error(), "write_timeout") || strings.Contains(err.Error(), "custom") {
+		t.Fatalf("only reserved variables of templates should be reported, got: %s", err)
+	}
+}
+
+func Test_checkReservedEnvironment_NoOverrides(t *testing.T) {
+	functions := map[string]stack.Function{
+		"api": {
+			Language:    "reserved-test-lang",
+			Environment: map[string]string{"exec_timeout": "30s"},
+		},
+	}
+
+	if err := checkReservedEnvironment(functions); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-provider/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	routerPathsAnnotation       = "com.openfaas.router.paths"
+	routerMethodsAnnotation     = "com.openfaas.router.methods"
+	routerStripPrefixAnnotation = "com.openfaas.router.strip-prefix"
+)
+
+// reservedRoutePrefixes are served by the gateway itself, so can't be routed
+// to a function
+var reservedRoutePrefixes = []string{"/system", "/function", "/async-function", "/ui", "/healthz", "/metrics"}
+
+var routeMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+func init() {
+	routesListCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	routesListCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the functions")
+	routesListCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	routesListCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+	routesListCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
+
+	routesCmd.AddCommand(routesListCmd)
+	faasCmd.AddCommand(routesCmd)
+}
+
+var routesCmd = &cobra.Command{
+	Use:   `routes [list]`,
+	Short: "View the path-based routes of functions",
+	Long: `View the paths which are routed to functions, as set by the router field of
+the stack file, or --route of deploy.
+
+The routes are deployed as the ` + routerPathsAnnotation + `, ` + routerMethodsAnnotation + ` and
+` + routerStripPrefixAnnotation + ` annotations, for routers and ingresses which
+read them:
+
+  functions:
+    orders:
+      router:
+        paths:
+          - /api/orders
+          - /api/orders/*
+        methods: [GET, POST]
+        strip_prefix: true`,
+}
+
+var routesListCmd = &cobra.Command{
+	Use:   `list [--gateway GATEWAY_URL] [--namespace NAMESPACE]`,
+	Short: "List the paths routed to the deployed functions",
+	Example: `  faas-cli routes list
+  faas-cli routes list --namespace staging`,
+	RunE: runRoutesList,
+}
+
+func runRoutesList(cmd *cobra.Command, args []string) error {
+	var yamlGateway string
+	if len(yamlFile) > 0 {
+		if services, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst); err == nil && services != nil {
+			yamlGateway = services.Provider.GatewayURL
+		}
+	}
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway, os.Getenv(openFaaSURLEnvironment))
+
+	cliAuth, err := proxy.NewCLIAuth(token, gatewayAddress)
+	if err != nil {
+		return err
+	}
+	transport := GetDefaultCLITransport(tlsInsecure, &commandTimeout)
+	client, err := proxy.NewClient(cliAuth, gatewayAddress, transport, &commandTimeout)
+	if err != nil {
+		return err
+	}
+
+	functions, err := client.ListFunctions(context.Background(), functionNamespace)
+	if err != nil {
+		return err
+	}
+
+	printRoutes(cmd.OutOrStdout(), functions)
+	return nil
+}
+
+// routerAnnotations translates the router of a function in the stack file,
+// and any --route paths, to the annotations read by the router
+func routerAnnotations(function stack.Function, extraPaths []string) (map[string]string, error) {
+	var router stack.Router
+	if function.Router != nil {
+		router = *function.Router
+	}
+	paths := append(append([]string{}, router.Paths...), extraPaths...)
+
+	if len(paths) == 0 {
+		if len(router.Methods) > 0 || router.StripPrefix {
+			return nil, fmt.Errorf("function '%s' has a router without any paths", function.Name)
+		}
+		return nil, nil
+	}
+
+	for _, path := range paths {
+		if err := validateRoutePath(path); err != nil {
+			return nil, fmt.Errorf("function '%s' has an invalid route: %w", function.Name, err)
+		}
+	}
+
+	annotations := map[string]string{routerPathsAnnotation: strings.Join(paths, ",")}
+
+	if len(router.Methods) > 0 {
+		methods := make([]string, 0, len(router.Methods))
+		for _, method := range router.Methods {
+			method = strings.ToUpper(method)
+			if !routeMethods[method] {
+				return nil, fmt.Errorf("function '%s' has an invalid route method %q", function.Name, method)
+			}
+			methods = append(methods, method)
+		}
+		annotations[routerMethodsAnnotation] = strings.Join(methods, ",")
+	}
+	if router.StripPrefix {
+		annotations[routerStripPrefixAnnotation] = "true"
+	}
+
+	if function.Annotations != nil {
+		for key, value := range annotations {
+			if existing, ok := (*function.Annotations)[key]; ok && existing != value {
+				return nil, fmt.Errorf("function '%s' sets %s to %q in annotations, and %q in its router, remove one of them",
+					function.Name, key, existing, value)
+			}
+		}
+	}
+	return annotations, nil
+}
+
+// validateRoutePath checks a path starts with /, and only has a wildcard as
+// its last segment
+func validateRoutePath(path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path %q must start with /", path)
+	}
+	if strings.ContainsAny(path, " \t?#,") {
+		return fmt.Errorf("path %q must not have spaces, commas, a query or a fragment", path)
+	}
+	if i := strings.Index(path, "*"); i >= 0 && (i != len(path)-1 || !strings.HasSuffix(path, "/*")) {
+		return fmt.Errorf("path %q may only end in /* to match the paths below it", path)
+	}
+
+	for _, prefix := range reservedRoutePrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return fmt.Errorf("path %q is served by the gateway", path)
+		}
+	}
+	return nil
+}
+
+// checkRouteConflicts stops a deploy which would route the same path to
+// more than one function of a namespace
+func checkRouteConflicts(functions map[string]stack.Function, namespace string) error {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	owners := map[string]string{}
+	for _, name := range names {
+		function := functions[name]
+		if function.Router == nil {
+			continue
+		}
+
+		functionNamespace := getNamespace(namespace, function.Namespace)
+		for _, path := range function.Router.Paths {
+			key := functionNamespace + " " + path
+			if owner, ok := owners[key]; ok && owner != name {
+				return fmt.Errorf("path %s is routed to both '%s' and '%s'", path, owner, name)
+			}
+			owners[key] = name
+		}
+	}
+	return nil
+}
+
+// printRoutes prints a line for each path routed to the functions, in the
+// order of the paths
+func printRoutes(w io.Writer, functions []types.FunctionStatus) {
+	type route struct {
+		path, methods, function, namespace string
+		strip                              bool
+	}
+
+	var routes []route
+	for _, function := range functions {
+		if function.Annotations == nil {
+			continue
+		}
+		annotations := *function.Annotations
+
+		methods := annotations[routerMethodsAnnotation]
+		if len(methods) == 0 {
+			methods = "*"
+		}
+		for _, path := range strings.Split(annotations[routerPathsAnnotation], ",") {
+			if path = strings.TrimSpace(path); len(path) > 0 {
+				routes = append(routes, route{
+					path:      path,
+					methods:   methods,
+					function:  function.Name,
+					namespace: function.Namespace,
+					strip:     annotations[routerStripPrefixAnnotation] == "true",
+				})
+			}
+		}
+	}
+
+	if len(routes) == 0 {
+		fmt.Fprintln(w, "No functions have routes")
+		return
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].path != routes[j].path {
+			return routes[i].path < routes[j].path
+		}
+		return routes[i].namespace < routes[j].namespace
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tMETHODS\tFUNCTION\tNAMESPACE\tSTRIP PREFIX")
+	for _, r := range routes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%v\n", r.path, r.methods, r.function, r.namespace, r.strip)
+	}
+	tw.Flush()
+}
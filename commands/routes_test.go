@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-provider/types"
+)
+
+func Test_routerAnnotations(t *testing.T) {
+	function := stack.Function{
+		Name:   "orders",
+		Router: &stack.Router{Paths: []string{"/api/orders", "/api/orders/*"}, Methods: []string{"get", "POST"}, StripPrefix: true},
+	}
+
+	annotations, err := routerAnnotations(function, []string{"/v2/orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		routerPathsAnnotation:       "/api/orders,/api/orders/*,/v2/orders",
+		routerMethodsAnnotation:     "GET,POST",
+		routerStripPrefixAnnotation: "true",
+	}
+	for key, value := range want {
+		if annotations[key] != value {
+			t.Errorf("want %s=%q, got: %q", key, value, annotations[key])
+		}
+	}
+
+	if annotations, err := routerAnnotations(stack.Function{Name: "orders"}, nil); err != nil || annotations != nil {
+		t.Errorf("want no annotations without a router, got: %v, %v", annotations, err)
+	}
+}
+
+func Test_routerAnnotations_Invalid(t *testing.T) {
+	cases := map[string]stack.Function{
+		"must start with /":     {Router: &stack.Router{Paths: []string{"api/orders"}}},
+		"may only end in /*":    {Router: &stack.Router{Paths: []string{"/api/*/orders"}}},
+		"served by the gateway": {Router: &stack.Router{Paths: []string{"/system/functions"}}},
+		"invalid route method":  {Router: &stack.Router{Paths: []string{"/api"}, Methods: []string{"FETCH"}}},
+		"without any paths":     {Router: &stack.Router{StripPrefix: true}},
+		"remove one of them":    {Router: &stack.Router{Paths: []string{"/api"}}, Annotations: &map[string]string{routerPathsAnnotation: "/other"}},
+		"commas, a query":       {Router: &stack.Router{Paths: []string{"/api?x=1"}}},
+	}
+	for want, function := range cases {
+		function.Name = "orders"
+		if _, err := routerAnnotations(function, nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("want an error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+func Test_checkRouteConflicts(t *testing.T) {
+	functions := map[string]stack.Function{
+		"orders":   {Router: &stack.Router{Paths: []string{"/api/orders"}}},
+		"payments": {Router: &stack.Router{Paths: []string{"/api/orders"}}, Namespace: "staging"},
+	}
+	if err := checkRouteConflicts(functions, ""); err != nil {
+		t.Errorf("want the same path allowed in other namespaces, got: %v", err)
+	}
+
+	if err := checkRouteConflicts(functions, "staging"); err == nil || !strings.Contains(err.Error(), "'orders' and 'payments'") {
+		t.Errorf("want a conflict in one namespace, got: %v", err)
+	}
+}
+
+func Test_printRoutes(t *testing.T) {
+	functions := []types.FunctionStatus{
+		{Name: "orders", Namespace: "openfaas-fn", Annotations: &map[string]string{routerPathsAnnotation: "/api/orders,/api/orders/*", routerMethodsAnnotation: "GET"}},
+		{Name: "figlet", Namespace: "openfaas-fn"},
+		{Name: "auth", Namespace: "openfaas-fn", Annotations: &map[string]string{routerPathsAnnotation: "/api/auth", routerStripPrefixAnnotation: "true"}},
+	}
+
+	var buf bytes.Buffer
+	printRoutes(&buf, functions)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("want a header and 3 routes, got:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "/api/auth * auth openfaas-fn true" {
+		t.Errorf("want the routes sorted by path, got: %s", lines[1])
+	}
+	if fields := strings.Fields(lines[3]); strings.Join(fields, " ") != "/api/orders/* GET orders openfaas-fn false" {
+		t.Errorf("want each path of a function, got: %s", lines[3])
+	}
+
+	buf.Reset()
+	printRoutes(&buf, functions[1:2])
+	if !strings.Contains(buf.String(), "No functions have routes") {
+		t.Errorf("want a message without routes, got: %s", buf.String())
+	}
+}
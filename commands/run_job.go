@@ -0,0 +1,339 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/schema"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runJobPayload      string
+	runJobImage        string
+	runJobContentType  string
+	runJobAsync        bool
+	runJobCallbackURL  string
+	runJobKeep         bool
+	runJobReadyTimeout time.Duration
+	runJobTimeout      time.Duration
+	runJobEnv          []string
+)
+
+// runJobPollInterval is how often readiness is checked, and is shortened by tests
+var runJobPollInterval = time.Second
+
+func init() {
+	runJobCmd.Flags().StringVar(&runJobPayload, "payload", "", "File to send as the request body, or - for stdin, no body is sent by default")
+	runJobCmd.Flags().StringVar(&runJobImage, "image", "", "Image to deploy when there is no stack file")
+	runJobCmd.Flags().StringVar(&runJobContentType, "content-type", "text/plain", "Content-Type of the payload")
+	runJobCmd.Flags().BoolVar(&runJobAsync, "async", false, "Invoke through the queue, for jobs which run for longer than the gateway's timeout")
+	runJobCmd.Flags().StringVar(&runJobCallbackURL, "callback-url", "", "With --async, the URL which the gateway can reach faas-cli on, to receive the result, e.g. http://192.168.0.10:9999/")
+	runJobCmd.Flags().BoolVar(&runJobKeep, "keep", false, "Keep the function deployed after the job has run")
+	runJobCmd.Flags().DurationVar(&runJobReadyTimeout, "ready-timeout", 2*time.Minute, "Time to wait for the function to become ready")
+	runJobCmd.Flags().DurationVar(&runJobTimeout, "timeout", 5*time.Minute, "Time to wait for the result of the job")
+	runJobCmd.Flags().StringArrayVarP(&runJobEnv, "env", "e", []string{}, "Set environment variables for the job's deployment (ENVVAR=VALUE)")
+
+	runJobCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	runJobCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+	runJobCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	runJobCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+	runJobCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
+
+	markMutating(runJobCmd)
+	faasCmd.AddCommand(runJobCmd)
+}
+
+var runJobCmd = &cobra.Command{
+	Use:   `run-job NAME [--payload FILE] [--async --callback-url URL] [--keep]`,
+	Short: "Deploy a function, invoke it once, then remove it",
+	Long: `Runs a batch task which is packaged as a function: the function is deployed
+from the stack file or --image, invoked once when it is ready, and removed
+once its result has been printed.
+
+A function which was already deployed is invoked, but is not removed.
+
+With --async, the job is queued, so it may run for longer than the gateway's
+timeout. faas-cli listens on the port of --callback-url for the result, so the
+URL must be reachable from the gateway and queue-worker.`,
+	Example: `  faas-cli run-job resize-images --payload batch.json
+  faas-cli run-job nightly-report --image ghcr.io/acme/report:0.1.0
+  faas-cli run-job backfill --payload - --async \
+    --callback-url http://192.168.0.10:9999/ --timeout 1h < ids.txt
+  faas-cli run-job migrate-db --keep`,
+	PreRunE: preRunJob,
+	RunE:    runJob,
+}
+
+func preRunJob(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the name of the function to run")
+	}
+
+	if runJobAsync && len(runJobCallbackURL) == 0 {
+		return fmt.Errorf("--async needs a --callback-url, for faas-cli to receive the result on")
+	}
+	if len(runJobCallbackURL) > 0 && !runJobAsync {
+		return fmt.Errorf("--callback-url can only be used with --async")
+	}
+	return nil
+}
+
+func runJob(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	payload, err := readJobPayload(runJobPayload)
+	if err != nil {
+		return err
+	}
+
+	var (
+		fn          *stack.Function
+		yamlGateway string
+	)
+	if len(runJobImage) == 0 && len(yamlFile) > 0 {
+		services, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst)
+		if err != nil {
+			return err
+		}
+		if f, ok := services.Functions[name]; ok {
+			fn = &f
+		}
+		yamlGateway = services.Provider.GatewayURL
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway, os.Getenv(openFaaSURLEnvironment))
+	namespace := functionNamespace
+	if fn != nil {
+		namespace = getNamespace(functionNamespace, fn.Namespace)
+	}
+
+	cliAuth, err := proxy.NewCLIAuth(token, gatewayAddress)
+	if err != nil {
+		return err
+	}
+	transport := GetDefaultCLITransport(tlsInsecure, &commandTimeout)
+	client, err := proxy.NewClient(cliAuth, gatewayAddress, transport, &commandTimeout)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	exists, err := functionExists(ctx, client, name, namespace)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if fn == nil && len(runJobImage) == 0 {
+			return fmt.Errorf("function %s is not deployed, and is not in the stack file, give its --image to deploy it", name)
+		}
+
+		if err := deployJob(name); err != nil {
+			return err
+		}
+
+		if !runJobKeep {
+			defer func() {
+				fmt.Printf("Removing: %s\n", name)
+				if err := client.DeleteFunction(ctx, name, namespace); err != nil {
+					fmt.Fprintf(os.Stderr, "Unable to remove %s: %s\n", name, err)
+				}
+			}()
+		}
+	}
+
+	if err := waitForReady(ctx, client, name, namespace, runJobReadyTimeout); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Running: %s\n", name)
+	result, err := invokeJob(gatewayAddress, name, namespace, payload)
+	if err != nil {
+		return err
+	}
+
+	os.Stdout.Write(result)
+	return nil
+}
+
+// deployJob deploys the function from the stack file, or from --image, with
+// the same code path as faas-cli deploy
+func deployJob(name string) error {
+	flags := DeployFlags{update: true, envvarOpts: runJobEnv}
+
+	if len(runJobImage) > 0 {
+		savedYAML := yamlFile
+		yamlFile = ""
+		defer func() { yamlFile = savedYAML }()
+
+		return runDeployCommand(nil, runJobImage, "", name, flags, schema.DefaultFormat)
+	}
+
+	savedRegex, savedFilter := regex, filter
+	regex, filter = "", name
+	defer func() { regex, filter = savedRegex, savedFilter }()
+
+	return runDeployCommand(nil, "", "", "", flags, schema.DefaultFormat)
+}
+
+func readJobPayload(path string) ([]byte, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func functionExists(ctx context.Context, client *proxy.Client, name, namespace string) (bool, error) {
+	functions, err := client.ListFunctions(ctx, namespace)
+	if err != nil {
+		return false, err
+	}
+	for _, function := range functions {
+		if function.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// waitForReady polls until the function has an available replica, as an
+// invocation made before then would fail, or wait for a cold start
+func waitForReady(ctx context.Context, client *proxy.Client, name, namespace string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		function, err := client.GetFunctionInfo(ctx, name, namespace)
+		if err == nil && function.AvailableReplicas > 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("function %s was not ready after %s: %w", name, timeout.Round(time.Second), err)
+			}
+			return fmt.Errorf("function %s was not ready after %s", name, timeout.Round(time.Second))
+		}
+
+		time.Sleep(runJobPollInterval)
+	}
+}
+
+func invokeJob(gatewayAddress, name, namespace string, payload []byte) ([]byte, error) {
+	if !runJobAsync {
+		retry := proxy.InvokeRetry{Timeout: runJobTimeout}
+		response, err := proxy.InvokeFunctionWithRetry(gatewayAddress, name, &payload, runJobContentType, nil, nil, false, http.MethodPost, tlsInsecure, namespace, retry)
+		if err != nil {
+			return nil, err
+		}
+		if response == nil {
+			return nil, nil
+		}
+		return *response, nil
+	}
+
+	receiver, err := newCallbackReceiver(runJobCallbackURL)
+	if err != nil {
+		return nil, err
+	}
+	defer receiver.Close()
+
+	headers := []string{"X-Callback-Url=" + runJobCallbackURL}
+	if _, err := proxy.InvokeFunction(gatewayAddress, name, &payload, runJobContentType, nil, headers, true, http.MethodPost, tlsInsecure, namespace); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Queued: %s, waiting up to %s for the result\n", name, runJobTimeout)
+	return receiver.Wait(runJobTimeout)
+}
+
+// callbackReceiver serves the X-Callback-Url of an asynchronous invocation,
+// and returns the first result posted to it
+type callbackReceiver struct {
+	server  *http.Server
+	results chan callbackResult
+}
+
+type callbackResult struct {
+	status int
+	body   []byte
+}
+
+func newCallbackReceiver(callbackURL string) (*callbackReceiver, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil || len(u.Host) == 0 {
+		return nil, fmt.Errorf("--callback-url must be a URL such as http://192.168.0.10:9999/")
+	}
+
+	port := u.Port()
+	if len(port) == 0 {
+		port = "80"
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for the callback on port %s: %w", port, err)
+	}
+
+	r := &callbackReceiver{results: make(chan callbackResult, 1)}
+	r.server = &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			status, err := strconv.Atoi(req.Header.Get("X-Function-Status"))
+			if err != nil {
+				status = http.StatusOK
+			}
+
+			select {
+			case r.results <- callbackResult{status: status, body: body}:
+			default:
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}),
+	}
+
+	go func() {
+		if err := r.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Callback receiver stopped: %s\n", err)
+		}
+	}()
+
+	return r, nil
+}
+
+// Wait returns the body of the result, or an error when the function failed
+func (r *callbackReceiver) Wait(timeout time.Duration) ([]byte, error) {
+	select {
+	case result := <-r.results:
+		if result.status >= http.StatusBadRequest {
+			return result.body, fmt.Errorf("the job failed with status %d: %s", result.status, string(result.body))
+		}
+		return result.body, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("no result was received within %s, the job may still be running", timeout)
+	}
+}
+
+func (r *callbackReceiver) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return r.server.Shutdown(ctx)
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/mockgateway"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func runJobForTest(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	resetForTest()
+	runJobImage, runJobPayload, runJobKeep, runJobAsync = "", "", false, false
+
+	var err error
+	out := test.CaptureStdout(func() {
+		faasCmd.SetArgs(append([]string{"run-job"}, args...))
+		err = faasCmd.Execute()
+	})
+	return out, err
+}
+
+func Test_runJob_DeploysInvokesAndRemoves(t *testing.T) {
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+
+	payload := filepath.Join(t.TempDir(), "batch.json")
+	os.WriteFile(payload, []byte(`{"ids":[1,2,3]}`), 0600)
+
+	out, err := runJobForTest(t, "resize", "--image", "acme/resize:0.1.0", "--payload", payload, "-g", s.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s\n%s", err, out)
+	}
+
+	if !strings.Contains(out, `{"ids":[1,2,3]}`) {
+		t.Errorf("want the result of the invocation, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Removing: resize") {
+		t.Errorf("want the function to be removed, got:\n%s", out)
+	}
+
+	client := newPrecheckClient(t, s.URL)
+	if exists, _ := functionExists(context.Background(), client, "resize", ""); exists {
+		t.Errorf("want resize to have been removed")
+	}
+}
+
+func Test_runJob_LeavesExistingFunctionDeployed(t *testing.T) {
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+
+	client := newPrecheckClient(t, s.URL)
+	client.DeployFunction(context.Background(), &proxy.DeployFunctionSpec{FunctionName: "report", Image: "acme/report:0.1.0"})
+
+	out, err := runJobForTest(t, "report", "-g", s.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s\n%s", err, out)
+	}
+
+	if strings.Contains(out, "Removing:") {
+		t.Errorf("want a function which was already deployed to be kept, got:\n%s", out)
+	}
+	if exists, _ := functionExists(context.Background(), client, "report", ""); !exists {
+		t.Errorf("want report to still be deployed")
+	}
+}
+
+func Test_runJob_NotDeployedWithoutImage(t *testing.T) {
+	s := httptest.NewServer(mockgateway.NewServer())
+	defer s.Close()
+
+	_, err := runJobForTest(t, "missing", "-g", s.URL)
+	if err == nil || !strings.Contains(err.Error(), "give its --image") {
+		t.Fatalf("want an error asking for --image, got: %v", err)
+	}
+}
+
+func Test_preRunJob_AsyncNeedsCallback(t *testing.T) {
+	runJobAsync, runJobCallbackURL = true, ""
+	defer func() { runJobAsync = false }()
+
+	if err := preRunJob(nil, []string{"backfill"}); err == nil {
+		t.Fatal("want an error when --async has no --callback-url")
+	}
+}
+
+func Test_callbackReceiver(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  string
+		wantErr bool
+	}{
+		{name: "success", status: "200"},
+		{name: "failure", status: "500", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			receiver, err := newCallbackReceiver("http://127.0.0.1:0/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer receiver.Close()
+
+			// Post to the handler directly, as port 0 picks a random port
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("done"))
+			req.Header.Set("X-Function-Status", c.status)
+			receiver.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			body, err := receiver.Wait(time.Second)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("want error: %v, got: %v", c.wantErr, err)
+			}
+			if string(body) != "done" {
+				t.Errorf("want the body of the result, got %q", string(body))
+			}
+		})
+	}
+}
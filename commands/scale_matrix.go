@@ -0,0 +1,416 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// scaleLabels are shown with the results, as they are what the test checks
+var scaleLabels = []string{
+	"com.openfaas.scale.min",
+	"com.openfaas.scale.max",
+	"com.openfaas.scale.target",
+	"com.openfaas.scale.type",
+	"com.openfaas.scale.zero",
+}
+
+var (
+	scaleTestSteps       string
+	scaleTestDuration    time.Duration
+	scaleTestTargetP95   time.Duration
+	scaleTestPayload     string
+	scaleTestMethod      string
+	scaleTestContentType string
+)
+
+// scaleTestPollInterval is how often the replicas are read, and is shortened
+// by tests
+var scaleTestPollInterval = 2 * time.Second
+
+func init() {
+	scaleTestCmd.Flags().StringVar(&scaleTestSteps, "steps", "1,10,50,100", "Concurrent requests for each step, run in turn")
+	scaleTestCmd.Flags().DurationVar(&scaleTestDuration, "duration", 30*time.Second, "How long each step runs for")
+	scaleTestCmd.Flags().DurationVar(&scaleTestTargetP95, "target-p95", 500*time.Millisecond, "Latency which the p95 of each step must stay under")
+	scaleTestCmd.Flags().StringVar(&scaleTestPayload, "payload", "", "File to send as the body of each request, no body is sent by default")
+	scaleTestCmd.Flags().StringVar(&scaleTestMethod, "method", http.MethodPost, "HTTP method of the requests")
+	scaleTestCmd.Flags().StringVar(&scaleTestContentType, "content-type", "text/plain", "Content-Type of the payload")
+
+	scaleTestCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	scaleTestCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+	scaleTestCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	scaleTestCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+	scaleTestCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
+
+	markMutating(scaleTestCmd)
+	faasCmd.AddCommand(scaleTestCmd)
+}
+
+var scaleTestCmd = &cobra.Command{
+	Use:   `scale-test NAME [--steps 1,10,50,100] [--duration 30s] [--target-p95 500ms]`,
+	Short: "Load a function in steps of concurrency, and check how it scaled",
+	Long: `Invokes a deployed function through the gateway with each number of
+concurrent requests given by --steps in turn, for --duration each. During each
+step the function's replicas are read from the gateway, and afterwards the
+requests, errors, latency and replicas of each step are printed.
+
+A step passes when its p95 latency is under --target-p95 and no request
+failed, so that the function's scaling labels can be checked against the load
+they are meant for. The command fails when any step does not pass.
+
+Run it against a staging gateway, as the load is real.`,
+	Example: `  faas-cli scale-test resize-images
+  faas-cli scale-test resize-images --steps 1,10,50,100 --duration 30s
+  faas-cli scale-test resize-images --target-p95 250ms --payload image.jpg \
+    --content-type image/jpeg --namespace staging`,
+	PreRunE: preRunScaleTest,
+	RunE:    runScaleTest,
+}
+
+// scaleStep is the result of one step of concurrency
+type scaleStep struct {
+	Concurrency int
+	Requests    int
+	Errors      int
+	P50         time.Duration
+	P95         time.Duration
+	MinReplicas uint64
+	MaxReplicas uint64
+	Passed      bool
+}
+
+func preRunScaleTest(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the name of the function to test")
+	}
+
+	if _, err := parseScaleSteps(scaleTestSteps); err != nil {
+		return err
+	}
+
+	if scaleTestDuration <= 0 {
+		return fmt.Errorf("--duration must be more than 0")
+	}
+	if scaleTestTargetP95 <= 0 {
+		return fmt.Errorf("--target-p95 must be more than 0")
+	}
+	return nil
+}
+
+// parseScaleSteps reads a list of concurrencies such as 1,10,50,100
+func parseScaleSteps(value string) ([]int, error) {
+	var steps []int
+	for _, field := range strings.Split(value, ",") {
+		step, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || step < 1 {
+			return nil, fmt.Errorf("each of --steps must be a number of at least 1, got: %q", field)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func runScaleTest(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	steps, _ := parseScaleSteps(scaleTestSteps)
+
+	var payload []byte
+	if len(scaleTestPayload) > 0 {
+		var err error
+		if payload, err = os.ReadFile(scaleTestPayload); err != nil {
+			return err
+		}
+	}
+
+	var yamlGateway string
+	namespace := functionNamespace
+	if len(yamlFile) > 0 {
+		if services, err := stack.ParseYAMLFile(yamlFile, "", "", envsubst); err == nil {
+			yamlGateway = services.Provider.GatewayURL
+			if fn, ok := services.Functions[name]; ok {
+				namespace = getNamespace(functionNamespace, fn.Namespace)
+			}
+		}
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway, os.Getenv(openFaaSURLEnvironment))
+	if msg := checkTLSInsecure(gatewayAddress, tlsInsecure); len(msg) > 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), msg)
+	}
+
+	cliAuth, err := proxy.NewCLIAuth(token, gatewayAddress)
+	if err != nil {
+		return err
+	}
+	transport := GetDefaultCLITransport(tlsInsecure, &commandTimeout)
+	client, err := proxy.NewClient(cliAuth, gatewayAddress, transport, &commandTimeout)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	function, err := client.GetFunctionInfo(ctx, name, namespace)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Scale test of: %s, with p95 target: %s\n", generateFunctionRef(name, namespace), output.Duration(scaleTestTargetP95))
+	printScaleLabels(out, function.Labels)
+
+	invokeURL := fmt.Sprintf("%s/function/%s", gatewayAddress, name)
+	if len(namespace) > 0 {
+		invokeURL += "." + namespace
+	}
+	httpClient := &http.Client{Transport: scaleTestTransport(tlsInsecure, steps)}
+
+	var results []scaleStep
+	for _, concurrency := range steps {
+		fmt.Fprintf(out, "Step: %d concurrent requests for %s\n", concurrency, output.Duration(scaleTestDuration))
+
+		replicas := pollReplicas(ctx, client, name, namespace)
+		latencies, failures := loadFunction(ctx, httpClient, invokeURL, payload, concurrency, scaleTestDuration)
+		minReplicas, maxReplicas := replicas()
+
+		results = append(results, summariseStep(concurrency, latencies, failures, minReplicas, maxReplicas, scaleTestTargetP95))
+	}
+
+	fmt.Fprintln(out)
+	printScaleSteps(out, results)
+
+	failed := 0
+	for _, step := range results {
+		if !step.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d steps did not keep p95 under %s without errors", failed, len(results), output.Duration(scaleTestTargetP95))
+	}
+	return nil
+}
+
+// scaleTestTransport has the gateway's proxy and CA bundle, and keeps a
+// connection open for each request of the largest step, so that the steps
+// are not limited or slowed by opening connections
+func scaleTestTransport(tlsInsecure bool, steps []int) *http.Transport {
+	largest := 0
+	for _, concurrency := range steps {
+		if concurrency > largest {
+			largest = concurrency
+		}
+	}
+
+	tr := proxy.SharedTransport(commandTimeout, tlsInsecure, false).Clone()
+	tr.MaxConnsPerHost = 0
+	if tr.MaxIdleConnsPerHost < largest {
+		tr.MaxIdleConnsPerHost = largest
+	}
+	if tr.MaxIdleConns != 0 && tr.MaxIdleConns < largest {
+		tr.MaxIdleConns = largest
+	}
+	return tr
+}
+
+func printScaleLabels(w io.Writer, labels *map[string]string) {
+	var set []string
+	if labels != nil {
+		for _, label := range scaleLabels {
+			if value, ok := (*labels)[label]; ok {
+				set = append(set, fmt.Sprintf("%s=%s", label, value))
+			}
+		}
+	}
+	if len(set) == 0 {
+		fmt.Fprintln(w, "Scaling labels: none, the provider's defaults apply")
+	} else {
+		fmt.Fprintf(w, "Scaling labels: %s\n", strings.Join(set, ", "))
+	}
+	fmt.Fprintln(w)
+}
+
+// pollReplicas reads the function's replicas until the returned function is
+// called, which returns the fewest and most replicas which were seen
+func pollReplicas(ctx context.Context, client *proxy.Client, name, namespace string) func() (uint64, uint64) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		minCount uint64
+		maxCount uint64
+		seen     bool
+	)
+
+	done := make(chan struct{})
+	read := func() {
+		function, err := client.GetFunctionInfo(ctx, name, namespace)
+		if err != nil {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !seen || function.Replicas < minCount {
+			minCount = function.Replicas
+		}
+		if !seen || function.Replicas > maxCount {
+			maxCount = function.Replicas
+		}
+		seen = true
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(scaleTestPollInterval)
+		defer ticker.Stop()
+
+		read()
+		for {
+			select {
+			case <-done:
+				read()
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				read()
+			}
+		}
+	}()
+
+	return func() (uint64, uint64) {
+		close(done)
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		return minCount, maxCount
+	}
+}
+
+// loadFunction keeps concurrency requests in flight until the duration has
+// passed, and returns the latency of each successful request and the number
+// which failed
+func loadFunction(ctx context.Context, client *http.Client, invokeURL string, payload []byte, concurrency int, duration time.Duration) ([]time.Duration, int) {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		failures  int
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				latency, err := invokeOnce(ctx, client, invokeURL, payload)
+				// A request cut short by the end of the step is not counted
+				if ctx.Err() != nil {
+					return
+				}
+
+				mu.Lock()
+				if err != nil {
+					failures++
+				} else {
+					latencies = append(latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return latencies, failures
+}
+
+func invokeOnce(ctx context.Context, client *http.Client, invokeURL string, payload []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, scaleTestMethod, invokeURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	if len(payload) > 0 {
+		req.Header.Set("Content-Type", scaleTestContentType)
+	}
+
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	latency := time.Since(start)
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return 0, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	return latency, nil
+}
+
+func summariseStep(concurrency int, latencies []time.Duration, failures int, minReplicas, maxReplicas uint64, target time.Duration) scaleStep {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	step := scaleStep{
+		Concurrency: concurrency,
+		Requests:    len(latencies) + failures,
+		Errors:      failures,
+		P50:         percentile(latencies, 50),
+		P95:         percentile(latencies, 95),
+		MinReplicas: minReplicas,
+		MaxReplicas: maxReplicas,
+	}
+	step.Passed = len(latencies) > 0 && failures == 0 && step.P95 < target
+	return step
+}
+
+// percentile uses the nearest rank of the sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func printScaleSteps(w io.Writer, steps []scaleStep) {
+	table := output.NewTable("CONCURRENCY", "REQUESTS", "ERRORS", "P50", "P95", "REPLICAS", "RESULT")
+	for _, step := range steps {
+		replicas := fmt.Sprintf("%d", step.MinReplicas)
+		if step.MaxReplicas != step.MinReplicas {
+			replicas = fmt.Sprintf("%d-%d", step.MinReplicas, step.MaxReplicas)
+		}
+
+		result := "pass"
+		if !step.Passed {
+			result = "FAIL"
+		}
+
+		table.Row(fmt.Sprintf("%d", step.Concurrency), output.Count(int64(step.Requests)), output.Count(int64(step.Errors)),
+			output.Duration(step.P50), output.Duration(step.P95), replicas, result)
+	}
+	table.Write(w)
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+func newScaleTestGateway(t *testing.T, status int) *httptest.Server {
+	var reads, invocations int64
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/system/function/figlet":
+			// Scale up by one replica on each read, as the autoscaler would
+			replicas := uint64(atomic.AddInt64(&reads, 1))
+			json.NewEncoder(w).Encode(types.FunctionStatus{
+				Name:     "figlet",
+				Replicas: replicas,
+				Labels:   &map[string]string{"com.openfaas.scale.max": "5", "team": "data"},
+			})
+		case "/function/figlet":
+			atomic.AddInt64(&invocations, 1)
+			w.WriteHeader(status)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func runScaleTestCmd(t *testing.T, args ...string) (string, error) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+	resetForTest()
+	scaleTestTargetP95 = 500 * time.Millisecond
+
+	savedInterval := scaleTestPollInterval
+	scaleTestPollInterval = 10 * time.Millisecond
+	defer func() { scaleTestPollInterval = savedInterval }()
+
+	var out bytes.Buffer
+	faasCmd.SetOut(&out)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs(append([]string{"scale-test"}, args...))
+	err := faasCmd.Execute()
+	return out.String(), err
+}
+
+func Test_scaleTest_Passes(t *testing.T) {
+	s := newScaleTestGateway(t, http.StatusOK)
+
+	out, err := runScaleTestCmd(t, "figlet", "--gateway", s.URL, "--steps", "1,4", "--duration", "100ms", "--target-p95", "1s")
+	if err != nil {
+		t.Fatalf("want the test to pass, got: %s\n%s", err, out)
+	}
+
+	if !strings.Contains(out, "Scaling labels: com.openfaas.scale.max=5\n") {
+		t.Errorf("want only the scaling labels shown, got:\n%s", out)
+	}
+	if strings.Count(out, "pass") != 2 || strings.Contains(out, "FAIL") {
+		t.Errorf("want both steps to pass, got:\n%s", out)
+	}
+	if !strings.Contains(out, "-") {
+		t.Errorf("want the range of replicas during each step, got:\n%s", out)
+	}
+}
+
+func Test_scaleTest_FailsOnErrors(t *testing.T) {
+	s := newScaleTestGateway(t, http.StatusBadGateway)
+
+	out, err := runScaleTestCmd(t, "figlet", "--gateway", s.URL, "--steps", "2", "--duration", "50ms")
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 steps did not keep p95 under 500ms") {
+		t.Fatalf("want the test to fail, got: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "FAIL") {
+		t.Errorf("want the step marked as failed, got:\n%s", out)
+	}
+}
+
+func Test_scaleTestTransport(t *testing.T) {
+	tr := scaleTestTransport(false, []int{1, 150, 50})
+	if tr.MaxConnsPerHost != 0 || tr.MaxIdleConnsPerHost != 150 {
+		t.Errorf("want no connection limit and 150 idle connections, got: %d and %d idle", tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost)
+	}
+	if tr == proxy.SharedTransport(commandTimeout, false, false) {
+		t.Errorf("want a transport of its own, so the shared one is not changed")
+	}
+}
+
+func Test_parseScaleSteps(t *testing.T) {
+	steps, err := parseScaleSteps("1, 10,50")
+	if err != nil || len(steps) != 3 || steps[1] != 10 {
+		t.Errorf("want 1, 10 and 50, got: %v, %v", steps, err)
+	}
+
+	if _, err := parseScaleSteps("1,,5"); err == nil {
+		t.Errorf("want an error for an empty step")
+	}
+	if _, err := parseScaleSteps("0"); err == nil {
+		t.Errorf("want an error for a step of 0")
+	}
+}
+
+func Test_percentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 20; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	if got := percentile(latencies, 95); got != 19*time.Millisecond {
+		t.Errorf("want the 19th of 20 as the p95, got: %s", got)
+	}
+	if got := percentile(latencies, 50); got != 10*time.Millisecond {
+		t.Errorf("want the 10th of 20 as the p50, got: %s", got)
+	}
+	if got := percentile(nil, 95); got != 0 {
+		t.Errorf("want 0 without samples, got: %s", got)
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// scheduleInvokerImage only needs curl, as the job makes one request
+	scheduleInvokerImage = "curlimages/curl:8.5.0"
+	// scheduleGatewayURL is the gateway's Service, as seen from its namespace
+	scheduleGatewayURL = "http://gateway.openfaas:8080"
+
+	scheduleComponent = "schedule"
+	// schedulePayloadKey is the key of the payload in the ConfigMap, and the
+	// name of the file it is mounted as
+	schedulePayloadKey = "payload"
+	schedulePayloadDir = "/var/openfaas/schedule"
+
+	// A CronJob's name is limited to 52 characters, as a suffix is added
+	// for the name of each Job
+	scheduleMaxNameLength = 52
+)
+
+// scheduleOptions are the flags of "schedule create", "schedule list" and
+// "schedule delete"
+type scheduleOptions struct {
+	name              string
+	cron              string
+	timeZone          string
+	payload           string
+	contentType       string
+	image             string
+	gatewayURL        string
+	functionNamespace string
+	timeout           time.Duration
+	namespace         string
+	kubeContext       string
+	kubeconfig        string
+	print             bool
+}
+
+var scheduleFlags scheduleOptions
+
+var (
+	scheduleNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	scheduleCronPattern = regexp.MustCompile(`^\S+(\s+\S+){4}$`)
+	scheduleCronMacros  = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+	scheduleSelector    = "app.kubernetes.io/managed-by=faas-cli,app.kubernetes.io/component=" + scheduleComponent
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{scheduleCreateCmd, scheduleListCmd, scheduleDeleteCmd} {
+		cmd.Flags().StringVarP(&scheduleFlags.namespace, "namespace", "n", ingressDefaultNamespace, "Namespace of the gateway, where the CronJobs are created")
+		cmd.Flags().StringVar(&scheduleFlags.kubeContext, "context", "", "kubeconfig context to use with kubectl")
+		cmd.Flags().StringVar(&scheduleFlags.kubeconfig, "kubeconfig", "", "Path to a kubeconfig file, instead of the default of kubectl")
+	}
+
+	scheduleCreateCmd.Flags().StringVar(&scheduleFlags.name, "name", "", "Name of the schedule, the function's name by default")
+	scheduleCreateCmd.Flags().StringVar(&scheduleFlags.cron, "cron", "", `Cron expression for when to invoke the function, e.g. "0 3 * * *" or @hourly`)
+	scheduleCreateCmd.Flags().StringVar(&scheduleFlags.timeZone, "time-zone", "", "Time zone of the cron expression, e.g. Europe/London, the time zone of the cluster by default")
+	scheduleCreateCmd.Flags().StringVar(&scheduleFlags.payload, "payload", "", "File to send as the request body, no body is sent by default")
+	scheduleCreateCmd.Flags().StringVar(&scheduleFlags.contentType, "content-type", "text/plain", "Content-Type of the payload")
+	scheduleCreateCmd.Flags().StringVar(&scheduleFlags.image, "image", scheduleInvokerImage, "Image with curl, which invokes the function")
+	scheduleCreateCmd.Flags().StringVar(&scheduleFlags.gatewayURL, "gateway-url", scheduleGatewayURL, "URL of the gateway from inside the cluster")
+	scheduleCreateCmd.Flags().StringVar(&scheduleFlags.functionNamespace, "function-namespace", "", "Namespace of the function, when it is not in the gateway's default namespace")
+	scheduleCreateCmd.Flags().DurationVar(&scheduleFlags.timeout, "timeout", time.Minute, "Time to wait for the function to respond")
+	scheduleCreateCmd.Flags().BoolVar(&scheduleFlags.print, "print", false, "Print the manifests instead of applying them")
+
+	markMutating(scheduleCreateCmd)
+	markMutating(scheduleDeleteCmd)
+	scheduleCmd.AddCommand(scheduleCreateCmd, scheduleListCmd, scheduleDeleteCmd)
+	faasCmd.AddCommand(scheduleCmd)
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   `schedule [create|list|delete]`,
+	Short: "Invoke functions on a schedule with Kubernetes CronJobs",
+	Long: `Manages CronJobs which invoke a function through the gateway, for clusters
+which do not run the cron-connector. Each run of the CronJob sends one request
+with curl, with the payload from a ConfigMap.
+
+kubectl and a kubeconfig with access to the gateway's namespace are required.`,
+}
+
+var scheduleCreateCmd = &cobra.Command{
+	Use:   `create FUNCTION_NAME --cron EXPRESSION [--payload FILE]`,
+	Short: "Invoke a function on a schedule",
+	Example: `  faas-cli schedule create nightly-report --cron "0 3 * * *"
+  faas-cli schedule create resize-images --cron @hourly --payload batch.json \
+    --content-type application/json
+  faas-cli schedule create backup --name backup-weekly --cron "0 1 * * 0" \
+    --time-zone Europe/London --function-namespace staging-fn
+  faas-cli schedule create nightly-report --cron "0 3 * * *" --print`,
+	PreRunE: preRunScheduleCreate,
+	RunE:    runScheduleCreate,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:     `list [--namespace NAMESPACE]`,
+	Aliases: []string{"ls"},
+	Short:   "List the schedules of functions",
+	Example: `  faas-cli schedule list
+  faas-cli schedule list --context production`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("kubectl must be installed to list schedules")
+		}
+		return runExecTransport(scheduleFlags.listCommand(), nil, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	},
+}
+
+var scheduleDeleteCmd = &cobra.Command{
+	Use:     `delete SCHEDULE_NAME`,
+	Aliases: []string{"rm", "remove"},
+	Short:   "Delete the schedule of a function",
+	Example: `  faas-cli schedule delete nightly-report
+  faas-cli schedule delete backup-weekly --context production`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("give the name of the schedule, as shown by faas-cli schedule list")
+		}
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("kubectl must be installed to delete a schedule")
+		}
+		return runExecTransport(scheduleFlags.deleteCommand(args[0]), nil, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	},
+}
+
+func preRunScheduleCreate(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the name of the function")
+	}
+
+	if len(scheduleFlags.name) == 0 {
+		scheduleFlags.name = args[0]
+	}
+	if !scheduleNamePattern.MatchString(scheduleFlags.name) || len(scheduleFlags.name) > scheduleMaxNameLength {
+		return fmt.Errorf("the name of the schedule must be lowercase letters, numbers and dashes, and at most %d characters, give one with --name", scheduleMaxNameLength)
+	}
+
+	if err := validateScheduleCron(scheduleFlags.cron); err != nil {
+		return err
+	}
+
+	if len(scheduleFlags.timeZone) > 0 {
+		if _, err := time.LoadLocation(scheduleFlags.timeZone); err != nil {
+			return fmt.Errorf("unknown --time-zone %q, give a name such as Europe/London", scheduleFlags.timeZone)
+		}
+	}
+
+	if !scheduleFlags.print {
+		if _, err := exec.LookPath("kubectl"); err != nil {
+			return fmt.Errorf("kubectl must be installed to create a schedule, or use --print")
+		}
+	}
+	return nil
+}
+
+// validateScheduleCron accepts the standard five fields, or a macro, which
+// is what a CronJob's schedule supports
+func validateScheduleCron(expression string) error {
+	expression = strings.TrimSpace(expression)
+	if len(expression) == 0 {
+		return fmt.Errorf(`give when to invoke the function with --cron, e.g. "0 3 * * *"`)
+	}
+
+	for _, macro := range scheduleCronMacros {
+		if expression == macro {
+			return nil
+		}
+	}
+
+	if !scheduleCronPattern.MatchString(expression) {
+		return fmt.Errorf("--cron %q must have five fields: minute, hour, day of month, month and day of week", expression)
+	}
+	return nil
+}
+
+func runScheduleCreate(cmd *cobra.Command, args []string) error {
+	var payload []byte
+	if len(scheduleFlags.payload) > 0 {
+		var err error
+		if payload, err = os.ReadFile(scheduleFlags.payload); err != nil {
+			return err
+		}
+	}
+
+	manifests, err := scheduleFlags.manifests(args[0], payload)
+	if err != nil {
+		return err
+	}
+
+	if scheduleFlags.print {
+		fmt.Fprint(cmd.OutOrStdout(), string(manifests))
+		return nil
+	}
+
+	if err := runExecTransport(scheduleFlags.applyCommand(), bytes.NewReader(manifests), cmd.OutOrStdout(), cmd.ErrOrStderr()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%s will be invoked on the schedule: %s\n", args[0], strings.TrimSpace(scheduleFlags.cron))
+	return nil
+}
+
+type scheduleMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// scheduleConfigMap holds the payload, which is mounted into the job
+type scheduleConfigMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   scheduleMetadata  `yaml:"metadata"`
+	BinaryData map[string]string `yaml:"binaryData,omitempty"`
+}
+
+// scheduleCronJob follows the batch/v1 CronJob of Kubernetes
+type scheduleCronJob struct {
+	APIVersion string              `yaml:"apiVersion"`
+	Kind       string              `yaml:"kind"`
+	Metadata   scheduleMetadata    `yaml:"metadata"`
+	Spec       scheduleCronJobSpec `yaml:"spec"`
+}
+
+type scheduleCronJobSpec struct {
+	Schedule                   string              `yaml:"schedule"`
+	TimeZone                   string              `yaml:"timeZone,omitempty"`
+	ConcurrencyPolicy          string              `yaml:"concurrencyPolicy"`
+	SuccessfulJobsHistoryLimit int                 `yaml:"successfulJobsHistoryLimit"`
+	FailedJobsHistoryLimit     int                 `yaml:"failedJobsHistoryLimit"`
+	JobTemplate                scheduleJobTemplate `yaml:"jobTemplate"`
+}
+
+type scheduleJobTemplate struct {
+	Spec scheduleJobSpec `yaml:"spec"`
+}
+
+type scheduleJobSpec struct {
+	BackoffLimit int                 `yaml:"backoffLimit"`
+	Template     schedulePodTemplate `yaml:"template"`
+}
+
+type schedulePodTemplate struct {
+	Spec schedulePodSpec `yaml:"spec"`
+}
+
+type schedulePodSpec struct {
+	RestartPolicy string              `yaml:"restartPolicy"`
+	Containers    []scheduleContainer `yaml:"containers"`
+	Volumes       []scheduleVolume    `yaml:"volumes,omitempty"`
+}
+
+type scheduleContainer struct {
+	Name         string                `yaml:"name"`
+	Image        string                `yaml:"image"`
+	Args         []string              `yaml:"args"`
+	VolumeMounts []scheduleVolumeMount `yaml:"volumeMounts,omitempty"`
+}
+
+type scheduleVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly"`
+}
+
+type scheduleVolume struct {
+	Name      string                  `yaml:"name"`
+	ConfigMap scheduleConfigMapVolume `yaml:"configMap"`
+}
+
+type scheduleConfigMapVolume struct {
+	Name string `yaml:"name"`
+}
+
+// manifests renders the ConfigMap and CronJob as one YAML stream for
+// kubectl apply. Both are named after the schedule, so that creating it again
+// updates it, and share the labels which list and delete select on.
+func (o scheduleOptions) manifests(function string, payload []byte) ([]byte, error) {
+	metadata := scheduleMetadata{
+		Name:      o.name,
+		Namespace: o.namespace,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "faas-cli",
+			"app.kubernetes.io/component":  scheduleComponent,
+			"faas_function":                function,
+		},
+	}
+
+	configMap := scheduleConfigMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   metadata,
+	}
+	if len(payload) > 0 {
+		configMap.BinaryData = map[string]string{
+			schedulePayloadKey: base64.StdEncoding.EncodeToString(payload),
+		}
+	}
+
+	cronJob := scheduleCronJob{
+		APIVersion: "batch/v1",
+		Kind:       "CronJob",
+		Metadata:   metadata,
+		Spec: scheduleCronJobSpec{
+			Schedule:                   strings.TrimSpace(o.cron),
+			TimeZone:                   o.timeZone,
+			ConcurrencyPolicy:          "Forbid",
+			SuccessfulJobsHistoryLimit: 3,
+			FailedJobsHistoryLimit:     1,
+			JobTemplate: scheduleJobTemplate{
+				Spec: scheduleJobSpec{
+					BackoffLimit: 2,
+					Template: schedulePodTemplate{
+						Spec: schedulePodSpec{
+							RestartPolicy: "Never",
+							Containers: []scheduleContainer{{
+								Name:  "invoke",
+								Image: o.image,
+								Args:  o.curlArgs(function, len(payload) > 0),
+								VolumeMounts: []scheduleVolumeMount{
+									{Name: schedulePayloadKey, MountPath: schedulePayloadDir, ReadOnly: true},
+								},
+							}},
+							Volumes: []scheduleVolume{
+								{Name: schedulePayloadKey, ConfigMap: scheduleConfigMapVolume{Name: o.name}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	for i, manifest := range []interface{}{configMap, cronJob} {
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		b, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(b)
+	}
+	return out.Bytes(), nil
+}
+
+// curlArgs fail the job when the function returns an error, so that it is
+// retried and shown as failed by kubectl
+func (o scheduleOptions) curlArgs(function string, withPayload bool) []string {
+	name := function
+	if len(o.functionNamespace) > 0 {
+		name = function + "." + o.functionNamespace
+	}
+
+	args := []string{
+		"--silent", "--show-error", "--fail-with-body",
+		"--max-time", strconv.Itoa(int(o.timeout.Seconds())),
+		"-X", "POST",
+		"-H", "Content-Type: " + o.contentType,
+	}
+	if withPayload {
+		args = append(args, "--data-binary", "@"+schedulePayloadDir+"/"+schedulePayloadKey)
+	}
+	return append(args, strings.TrimRight(o.gatewayURL, "/")+"/function/"+name)
+}
+
+func (o scheduleOptions) kubectl() []string {
+	argv := []string{"kubectl"}
+	if len(o.kubeconfig) > 0 {
+		argv = append(argv, "--kubeconfig", o.kubeconfig)
+	}
+	if len(o.kubeContext) > 0 {
+		argv = append(argv, "--context", o.kubeContext)
+	}
+	return append(argv, "--namespace", o.namespace)
+}
+
+func (o scheduleOptions) applyCommand() []string {
+	return append(o.kubectl(), "apply", "-f", "-")
+}
+
+func (o scheduleOptions) listCommand() []string {
+	return append(o.kubectl(), "get", "cronjobs", "-l", scheduleSelector,
+		"-o", "custom-columns=NAME:.metadata.name,FUNCTION:.metadata.labels.faas_function,SCHEDULE:.spec.schedule,TIMEZONE:.spec.timeZone,LAST RUN:.status.lastScheduleTime")
+}
+
+func (o scheduleOptions) deleteCommand(name string) []string {
+	return append(o.kubectl(), "delete", "cronjob,configmap", "-l", scheduleSelector, "--field-selector", "metadata.name="+name)
+}
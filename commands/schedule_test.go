@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_scheduleOptions_Manifests(t *testing.T) {
+	opts := scheduleOptions{
+		name:        "nightly-report",
+		cron:        "0 3 * * *",
+		timeZone:    "Europe/London",
+		contentType: "application/json",
+		image:       scheduleInvokerImage,
+		gatewayURL:  scheduleGatewayURL,
+		timeout:     time.Minute,
+		namespace:   "openfaas",
+	}
+
+	out, err := opts.manifests("report", []byte(`{"day":"yesterday"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: nightly-report
+  namespace: openfaas
+  labels:
+    app.kubernetes.io/component: schedule
+    app.kubernetes.io/managed-by: faas-cli
+    faas_function: report
+binaryData:
+  payload: eyJkYXkiOiJ5ZXN0ZXJkYXkifQ==
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: nightly-report
+  namespace: openfaas
+  labels:
+    app.kubernetes.io/component: schedule
+    app.kubernetes.io/managed-by: faas-cli
+    faas_function: report
+spec:
+  schedule: 0 3 * * *
+  timeZone: Europe/London
+  concurrencyPolicy: Forbid
+  successfulJobsHistoryLimit: 3
+  failedJobsHistoryLimit: 1
+  jobTemplate:
+    spec:
+      backoffLimit: 2
+      template:
+        spec:
+          restartPolicy: Never
+          containers:
+          - name: invoke
+            image: curlimages/curl:8.5.0
+            args:
+            - --silent
+            - --show-error
+            - --fail-with-body
+            - --max-time
+            - "60"
+            - -X
+            - POST
+            - -H
+            - 'Content-Type: application/json'
+            - --data-binary
+            - '@/var/openfaas/schedule/payload'
+            - http://gateway.openfaas:8080/function/report
+            volumeMounts:
+            - name: payload
+              mountPath: /var/openfaas/schedule
+              readOnly: true
+          volumes:
+          - name: payload
+            configMap:
+              name: nightly-report
+`
+	if string(out) != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, string(out))
+	}
+}
+
+func Test_scheduleOptions_CurlArgs(t *testing.T) {
+	opts := scheduleOptions{
+		contentType:       "text/plain",
+		gatewayURL:        "http://gateway.openfaas:8080/",
+		functionNamespace: "staging-fn",
+		timeout:           30 * time.Second,
+	}
+
+	got := strings.Join(opts.curlArgs("backup", false), " ")
+	want := "--silent --show-error --fail-with-body --max-time 30 -X POST -H Content-Type: text/plain http://gateway.openfaas:8080/function/backup.staging-fn"
+	if got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func Test_validateScheduleCron(t *testing.T) {
+	cases := []struct {
+		expression string
+		wantErr    bool
+	}{
+		{expression: "0 3 * * *"},
+		{expression: "*/5 * * * 1-5"},
+		{expression: "@hourly"},
+		{expression: "", wantErr: true},
+		{expression: "0 3 * *", wantErr: true},
+		{expression: "0 0 3 * * *", wantErr: true},
+		{expression: "@every 5m", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expression, func(t *testing.T) {
+			if err := validateScheduleCron(c.expression); (err != nil) != c.wantErr {
+				t.Fatalf("want error: %v, got: %v", c.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_scheduleOptions_Commands(t *testing.T) {
+	opts := scheduleOptions{namespace: "openfaas", kubeContext: "prod", kubeconfig: "/tmp/kubeconfig"}
+
+	if got := strings.Join(opts.applyCommand(), " "); got != "kubectl --kubeconfig /tmp/kubeconfig --context prod --namespace openfaas apply -f -" {
+		t.Fatalf("unexpected apply command: %s", got)
+	}
+
+	got := strings.Join(opts.deleteCommand("nightly-report"), " ")
+	want := "kubectl --kubeconfig /tmp/kubeconfig --context prod --namespace openfaas delete cronjob,configmap -l " + scheduleSelector + " --field-selector metadata.name=nightly-report"
+	if got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
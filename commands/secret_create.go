@@ -29,13 +29,19 @@ var secretCreateCmd = &cobra.Command{
 			[--from-literal=SECRET_VALUE]
 			[--from-file=/path/to/secret/file]
 			[STDIN]
-			[--tls-no-verify]`,
+			[--tls-no-verify]
+			[--faasd-host=user@host]`,
 	Short: "Create a new secret",
-	Long:  `The create command creates a new secret from file, literal or STDIN`,
+	Long: `The create command creates a new secret from file, literal or STDIN
+
+With --faasd-host, the secret is written into the secrets folder of a faasd
+host over SSH, instead of through the gateway. The SSH user must be root or
+able to run sudo without a password.`,
 	Example: `faas-cli secret create secret-name --from-literal=secret-value
 faas-cli secret create secret-name --from-literal=secret-value --gateway=http://127.0.0.1:8080
 faas-cli secret create secret-name --from-file=/path/to/secret/file --gateway=http://127.0.0.1:8080
-cat /path/to/secret/file | faas-cli secret create secret-name`,
+cat /path/to/secret/file | faas-cli secret create secret-name
+faas-cli secret create secret-name --from-file=/path/to/secret/file --faasd-host=ubuntu@faasd.example.com`,
 	RunE:    runSecretCreate,
 	PreRunE: preRunSecretCreate,
 }
@@ -48,7 +54,11 @@ func init() {
 	secretCreateCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
 	secretCreateCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
 	secretCreateCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+	secretCreateCmd.Flags().StringVar(&faasdHost, "faasd-host", "", "Write the secret to a faasd host over SSH, as user@host")
+	secretCreateCmd.Flags().IntVar(&faasdSSHPort, "faasd-ssh-port", 0, "SSH port of the faasd host, when not the default")
+	secretCreateCmd.Flags().StringVar(&faasdSecretsDst, "faasd-secrets-dir", faasdSecretsDir, "Folder on the faasd host which holds a folder of secrets for each namespace")
 
+	markMutating(secretCreateCmd)
 	secretCmd.AddCommand(secretCreateCmd)
 }
 
@@ -114,6 +124,14 @@ func runSecretCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("must provide a non empty secret via --from-literal, --from-file or STDIN")
 	}
 
+	if len(faasdHost) > 0 {
+		value := []byte(secret.Value)
+		if len(secret.RawValue) > 0 && !trimSecret {
+			value = secret.RawValue
+		}
+		return createFaasdSecret(secret, value)
+	}
+
 	gatewayAddress := getGatewayURL(gateway, defaultGateway, "", os.Getenv(openFaaSURLEnvironment))
 
 	if msg := checkTLSInsecure(gatewayAddress, tlsInsecure); len(msg) > 0 {
@@ -0,0 +1,93 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	types "github.com/openfaas/faas-provider/types"
+)
+
+const (
+	// faasdSecretsDir is where faasd's provider reads secrets from, with a
+	// folder for each namespace
+	faasdSecretsDir = "/var/lib/faasd-provider/secrets"
+	// faasdDefaultNamespace is used by faasd when no namespace is given
+	faasdDefaultNamespace = "openfaas-fn"
+
+	// The permissions which faasd's provider gives to the secrets it writes
+	faasdSecretDirMode  = "0755"
+	faasdSecretFileMode = "0644"
+)
+
+var (
+	faasdHost       string
+	faasdSSHPort    int
+	faasdSecretsDst string
+)
+
+// runSSH is replaced by tests
+var runSSH = runExecTransport
+
+// createFaasdSecret writes a secret into the secrets folder of a faasd host
+// over SSH, as an install can't always be reached through the gateway's
+// secrets API. The value is sent on stdin, so it does not appear in the
+// arguments of any process on either machine.
+func createFaasdSecret(secret types.Secret, value []byte) error {
+	namespace := secret.Namespace
+	if len(namespace) == 0 {
+		namespace = faasdDefaultNamespace
+	}
+
+	fmt.Printf("Creating secret: %s.%s on faasd host: %s\n", secret.Name, namespace, faasdHost)
+
+	argv := faasdSecretCommand(faasdHost, faasdSSHPort, faasdSecretsDst, namespace, secret.Name)
+	if err := runSSH(argv, bytes.NewReader(value), io.Discard, os.Stderr); err != nil {
+		return fmt.Errorf("unable to write the secret to %s over ssh: %w", faasdHost, err)
+	}
+
+	fmt.Printf("Created: %s\n", path.Join(faasdSecretsDst, namespace, secret.Name))
+	return nil
+}
+
+// faasdSecretCommand builds the ssh command which writes stdin to the
+// secret's file. The file is written alongside, then renamed, so that a
+// function never reads a partial value. sudo is needed unless logging in as
+// root, because faasd's folders belong to root.
+func faasdSecretCommand(host string, port int, secretsDir, namespace, name string) []string {
+	dir := path.Join(secretsDir, namespace)
+	file := path.Join(dir, name)
+	tmp := path.Join(dir, "."+name+".tmp")
+
+	script := strings.Join([]string{
+		"umask 077",
+		"mkdir -p " + shellQuote(dir),
+		"chmod " + faasdSecretDirMode + " " + shellQuote(dir),
+		"cat > " + shellQuote(tmp),
+		"chmod " + faasdSecretFileMode + " " + shellQuote(tmp),
+		"mv " + shellQuote(tmp) + " " + shellQuote(file),
+	}, " && ")
+
+	remote := "sh -c " + shellQuote(script)
+	if !strings.HasPrefix(host, "root@") {
+		remote = "sudo " + remote
+	}
+
+	argv := []string{"ssh"}
+	if port > 0 {
+		argv = append(argv, "-p", strconv.Itoa(port))
+	}
+	return append(argv, host, remote)
+}
+
+// shellQuote quotes a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	types "github.com/openfaas/faas-provider/types"
+)
+
+func Test_faasdSecretCommand(t *testing.T) {
+	cases := []struct {
+		name     string
+		host     string
+		port     int
+		wantArgs []string
+		wantSudo bool
+	}{
+		{name: "uses sudo for other users", host: "ubuntu@faasd", wantArgs: []string{"ssh", "ubuntu@faasd"}, wantSudo: true},
+		{name: "root does not need sudo", host: "root@faasd", wantArgs: []string{"ssh", "root@faasd"}},
+		{name: "custom port", host: "ubuntu@faasd", port: 2222, wantArgs: []string{"ssh", "-p", "2222", "ubuntu@faasd"}, wantSudo: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			argv := faasdSecretCommand(c.host, c.port, faasdSecretsDir, "openfaas-fn", "api-key")
+
+			if got := strings.Join(argv[:len(argv)-1], " "); got != strings.Join(c.wantArgs, " ") {
+				t.Errorf("want %v, got %v", c.wantArgs, argv[:len(argv)-1])
+			}
+
+			remote := argv[len(argv)-1]
+			if strings.HasPrefix(remote, "sudo ") != c.wantSudo {
+				t.Errorf("want sudo: %v, got: %s", c.wantSudo, remote)
+			}
+			for _, want := range []string{
+				"mkdir -p '\\''/var/lib/faasd-provider/secrets/openfaas-fn'\\''",
+				"chmod 0644 '\\''/var/lib/faasd-provider/secrets/openfaas-fn/.api-key.tmp'\\''",
+				"mv '\\''/var/lib/faasd-provider/secrets/openfaas-fn/.api-key.tmp'\\'' '\\''/var/lib/faasd-provider/secrets/openfaas-fn/api-key'\\''",
+			} {
+				if !strings.Contains(remote, want) {
+					t.Errorf("want %q in: %s", want, remote)
+				}
+			}
+		})
+	}
+}
+
+func Test_createFaasdSecret_SendsValueOnStdin(t *testing.T) {
+	defer func(previous func([]string, io.Reader, io.Writer, io.Writer) error) { runSSH = previous }(runSSH)
+
+	var gotArgs []string
+	var gotValue string
+	runSSH = func(argv []string, stdin io.Reader, stdout, stderr io.Writer) error {
+		gotArgs = argv
+		data, _ := io.ReadAll(stdin)
+		gotValue = string(data)
+		return nil
+	}
+
+	faasdHost = "root@faasd"
+	defer func() { faasdHost = "" }()
+	faasdSecretsDst = faasdSecretsDir
+
+	if err := createFaasdSecret(types.Secret{Name: "api-key"}, []byte("s3cr3t")); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotValue != "s3cr3t" {
+		t.Errorf("want the value on stdin, got %q", gotValue)
+	}
+	for _, arg := range gotArgs {
+		if strings.Contains(arg, "s3cr3t") {
+			t.Errorf("the value should not be in the arguments: %v", gotArgs)
+		}
+	}
+	if remote := gotArgs[len(gotArgs)-1]; !strings.Contains(remote, "/openfaas-fn/api-key") {
+		t.Errorf("want the default namespace of faasd, got: %s", remote)
+	}
+}
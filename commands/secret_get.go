@@ -0,0 +1,206 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// secretRevealLog records each secret value printed by "secret get --reveal"
+const secretRevealLog = "secret-reveals.log"
+
+var (
+	secretGetTarget execTarget
+	secretReveal    bool
+)
+
+// runSecretGet is replaced by tests
+var runSecretGet = runExecTransport
+
+// secretRevealNow is replaced by tests
+var secretRevealNow = time.Now
+
+func init() {
+	addExecTargetFlags(secretGetCmd, &secretGetTarget)
+	secretGetCmd.Flags().BoolVar(&secretReveal, "reveal", false, "Print the value of the secret, each reveal is recorded in "+secretRevealLog)
+
+	secretCmd.AddCommand(secretGetCmd)
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   `get SECRET_NAME [--reveal] [--provider kubernetes|faasd] [--namespace NAMESPACE]`,
+	Short: "Get a secret, with its value masked unless --reveal is given",
+	Long: `Reads a secret from the cluster, as the gateway's API never returns the values
+of secrets. For Kubernetes, kubectl is used with the current kubeconfig, so the
+secret can only be read with RBAC access to it. For faasd, the secret's file is
+read over SSH with sudo.
+
+The value is masked unless --reveal is given, when only the value is printed.
+Each reveal is recorded, with who revealed which secret and when, in
+` + secretRevealLog + ` within the CLI's config folder, and the value is not
+printed if the record can't be written.`,
+	Example: `  faas-cli secret get api-key
+  faas-cli secret get api-key --namespace staging --reveal
+  faas-cli secret get api-key --provider faasd --ssh ubuntu@faasd.example.com --reveal`,
+	PreRunE: preRunSecretGet,
+	RunE:    runSecretGetCmd,
+}
+
+// secretRevealEntry is one entry of the reveal log
+type secretRevealEntry struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Secret    string    `json:"secret"`
+	Namespace string    `json:"namespace"`
+	Provider  string    `json:"provider"`
+	Target    string    `json:"target,omitempty"`
+}
+
+func preRunSecretGet(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the name of one secret")
+	}
+
+	if isValid, err := validateSecretName(args[0]); !isValid {
+		return err
+	}
+
+	return secretGetTarget.validate()
+}
+
+func runSecretGetCmd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	value, err := readSecretValue(secretGetTarget, name)
+	if err != nil {
+		return err
+	}
+
+	if !secretReveal {
+		fmt.Fprintf(cmd.OutOrStdout(), "Name:\t\t %s\n", name)
+		fmt.Fprintf(cmd.OutOrStdout(), "Namespace:\t %s\n", secretGetTarget.namespace)
+		fmt.Fprintf(cmd.OutOrStdout(), "Provider:\t %s\n", secretGetTarget.provider)
+		fmt.Fprintf(cmd.OutOrStdout(), "Value:\t\t <masked, %d bytes>, print it with --reveal\n", len(value))
+		return nil
+	}
+
+	entry := secretRevealEntry{
+		Time:      secretRevealNow().UTC(),
+		User:      currentUsername(),
+		Secret:    name,
+		Namespace: secretGetTarget.namespace,
+		Provider:  secretGetTarget.provider,
+		Target:    secretGetTarget.kubeContext,
+	}
+	if secretGetTarget.provider == execProviderFaasd {
+		entry.Target = secretGetTarget.sshHost
+	}
+
+	logPath, err := recordSecretReveal(entry)
+	if err != nil {
+		return fmt.Errorf("the secret was not revealed, as the reveal could not be recorded: %w", err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Revealing %s.%s, recorded in: %s\n", name, secretGetTarget.namespace, logPath)
+
+	out := cmd.OutOrStdout()
+	out.Write(value)
+	if !bytes.HasSuffix(value, []byte("\n")) {
+		fmt.Fprintln(out)
+	}
+	return nil
+}
+
+// readSecretValue reads the secret with kubectl or over SSH
+func readSecretValue(target execTarget, name string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	argv := secretGetCommand(target, name)
+	if err := runSecretGet(argv, nil, &stdout, &stderr); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); len(detail) > 0 {
+			return nil, fmt.Errorf("unable to read the secret %s.%s: %s", name, target.namespace, detail)
+		}
+		return nil, fmt.Errorf("unable to read the secret %s.%s: %w", name, target.namespace, err)
+	}
+
+	if target.provider == execProviderFaasd {
+		return stdout.Bytes(), nil
+	}
+	return kubernetesSecretValue(stdout.Bytes(), name)
+}
+
+func secretGetCommand(target execTarget, name string) []string {
+	if target.provider == execProviderFaasd {
+		file := path.Join(faasdSecretsDir, target.namespace, name)
+		return []string{"ssh", target.sshHost, "sudo cat " + shellQuote(file)}
+	}
+
+	argv := []string{"kubectl"}
+	if len(target.kubeContext) > 0 {
+		argv = append(argv, "--context", target.kubeContext)
+	}
+	return append(argv, "get", "secret", name, "--namespace", target.namespace, "-o", "json")
+}
+
+// kubernetesSecretValue decodes the key of the secret which is named after
+// it, as OpenFaaS writes them, or its only key
+func kubernetesSecretValue(data []byte, name string) ([]byte, error) {
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return nil, fmt.Errorf("unable to parse the secret %s: %w", name, err)
+	}
+
+	encoded, ok := secret.Data[name]
+	if !ok && len(secret.Data) == 1 {
+		for _, value := range secret.Data {
+			encoded, ok = value, true
+		}
+	}
+	if !ok {
+		keys := make([]string, 0, len(secret.Data))
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("the secret %s has no key named %s, its keys are: %s", name, name, strings.Join(keys, ", "))
+	}
+
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// recordSecretReveal appends the entry to the reveal log, and returns its path
+func recordSecretReveal(entry secretRevealEntry) (string, error) {
+	logPath := usagePath(secretRevealLog)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0700); err != nil {
+		return "", err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	return logPath, f.Sync()
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_secretGet_MaskedAndRevealed(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("OPENFAAS_CONFIG", configDir)
+
+	savedRun, savedNow := runSecretGet, secretRevealNow
+	defer func() {
+		runSecretGet, secretRevealNow = savedRun, savedNow
+		secretGetTarget, secretReveal = execTarget{}, false
+	}()
+
+	var gotArgv []string
+	runSecretGet = func(argv []string, stdin io.Reader, stdout, stderr io.Writer) error {
+		gotArgv = argv
+		_, err := io.WriteString(stdout, `{"data":{"api-key":"czNjcjN0"}}`)
+		return err
+	}
+	secretRevealNow = func() time.Time { return time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC) }
+	secretGetTarget = execTarget{provider: execProviderKubernetes, namespace: "staging", kubeContext: "prod"}
+
+	var out, errOut bytes.Buffer
+	secretGetCmd.SetOut(&out)
+	secretGetCmd.SetErr(&errOut)
+	defer secretGetCmd.SetOut(nil)
+	defer secretGetCmd.SetErr(nil)
+
+	if err := runSecretGetCmd(secretGetCmd, []string{"api-key"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cr3t") || !strings.Contains(out.String(), "<masked, 6 bytes>") {
+		t.Errorf("want the value masked, got: %s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(configDir, secretRevealLog)); err == nil {
+		t.Errorf("want no reveal recorded without --reveal")
+	}
+
+	wantArgv := []string{"kubectl", "--context", "prod", "get", "secret", "api-key", "--namespace", "staging", "-o", "json"}
+	if !reflect.DeepEqual(gotArgv, wantArgv) {
+		t.Errorf("want: %v, got: %v", wantArgv, gotArgv)
+	}
+
+	out.Reset()
+	secretReveal = true
+	if err := runSecretGetCmd(secretGetCmd, []string{"api-key"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "s3cr3t\n" {
+		t.Errorf("want only the value printed, got: %q", out.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, secretRevealLog))
+	if err != nil {
+		t.Fatalf("want the reveal recorded, got: %s", err)
+	}
+	var entry secretRevealEntry
+	if err := json.Unmarshal(bytes.TrimSpace(data), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Secret != "api-key" || entry.Namespace != "staging" || entry.Target != "prod" || !entry.Time.Equal(secretRevealNow()) {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+}
+
+func Test_secretGetCommand_Faasd(t *testing.T) {
+	target := execTarget{provider: execProviderFaasd, namespace: "openfaas-fn", sshHost: "ubuntu@faasd"}
+
+	got := secretGetCommand(target, "api-key")
+	want := []string{"ssh", "ubuntu@faasd", "sudo cat '/var/lib/faasd-provider/secrets/openfaas-fn/api-key'"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func Test_kubernetesSecretValue_Keys(t *testing.T) {
+	if got, err := kubernetesSecretValue([]byte(`{"data":{"token":"dmFsdWU="}}`), "api-key"); err != nil || string(got) != "value" {
+		t.Errorf("want the only key used, got: %q, %v", got, err)
+	}
+
+	_, err := kubernetesSecretValue([]byte(`{"data":{"a":"","b":""}}`), "api-key")
+	if err == nil || !strings.Contains(err.Error(), "its keys are: a, b") {
+		t.Errorf("want the keys listed, got: %v", err)
+	}
+}
@@ -29,6 +29,7 @@ func init() {
 	secretRemoveCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
 	secretRemoveCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
 	secretRemoveCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+	markMutating(secretRemoveCmd)
 	secretCmd.AddCommand(secretRemoveCmd)
 }
 
@@ -38,6 +38,7 @@ func init() {
 	secretUpdateCmd.Flags().BoolVar(&trimSecret, "trim", true, "trim whitespace from the start and end of the secret value")
 	secretUpdateCmd.Flags().StringVarP(&token, "token", "k", "", "Pass a JWT token to use instead of basic auth")
 	secretUpdateCmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+	markMutating(secretUpdateCmd)
 	secretCmd.AddCommand(secretUpdateCmd)
 }
 
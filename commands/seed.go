@@ -0,0 +1,353 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	seedFile        string
+	seedRate        string
+	seedStateFile   string
+	seedResume      bool
+	seedCallbackURL string
+	seedMaxRetries  int
+	seedNamespace   string
+	seedContentType string
+	seedHeaders     []string
+)
+
+func init() {
+	seedCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	seedCmd.Flags().StringVarP(&seedNamespace, "namespace", "n", "", "Namespace of the deployed function")
+	seedCmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+	seedCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+
+	seedCmd.Flags().StringVar(&seedFile, "file", "", "Newline-delimited file of payloads, one request per line")
+	seedCmd.Flags().StringVar(&seedRate, "rate", "10/s", "Maximum rate of requests, such as 50/s or 600/m")
+	seedCmd.Flags().StringVar(&seedContentType, "content-type", "application/json", "The content-type HTTP header of each request")
+	seedCmd.Flags().StringArrayVarP(&seedHeaders, "header", "H", []string{}, "pass HTTP request header")
+	seedCmd.Flags().StringVar(&seedCallbackURL, "callback-url", "", "X-Callback-Url to receive the result of each invocation")
+	seedCmd.Flags().IntVar(&seedMaxRetries, "max-retries", 3, "Number of times to retry a request which is rejected with a 429 or 5xx status")
+	seedCmd.Flags().StringVar(&seedStateFile, "state", "", "File which records progress, defaults to the --file path with a .seed suffix")
+	seedCmd.Flags().BoolVar(&seedResume, "resume", false, "Continue from the last line recorded in the state file")
+
+	markMutating(seedCmd)
+	faasCmd.AddCommand(seedCmd)
+}
+
+var seedCmd = &cobra.Command{
+	Use:   `seed FUNCTION_NAME --file DATA_FILE [--rate RATE] [--resume]`,
+	Short: "Invoke a function asynchronously with each line of a file",
+	Long: `Streams a newline-delimited file, such as NDJSON, through the asynchronous
+API of the gateway, so that each line becomes one invocation of the function.
+This can be used to backfill an event-driven pipeline.
+
+Requests are limited to --rate. Progress is written to a state file after each
+line is accepted by the gateway, so when the command is stopped or fails, it
+can be run again with --resume to continue from the next line.`,
+	Example: `  faas-cli seed import-orders --file orders.ndjson --rate 50/s
+  faas-cli seed import-orders --file orders.ndjson --resume
+  faas-cli seed import-orders --file orders.ndjson \
+    --callback-url http://gateway:8080/function/collect`,
+	PreRunE: preRunSeed,
+	RunE:    runSeed,
+}
+
+func preRunSeed(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of a function")
+	}
+
+	if len(seedFile) == 0 {
+		return fmt.Errorf("give a file of payloads with --file")
+	}
+
+	if seedMaxRetries < 0 {
+		return fmt.Errorf("--max-retries must be zero or greater")
+	}
+
+	if _, err := parseSeedRate(seedRate); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func runSeed(cmd *cobra.Command, args []string) error {
+	var yamlGateway string
+	if len(yamlFile) > 0 {
+		parsedServices, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst)
+		if err != nil {
+			return err
+		}
+		if parsedServices != nil {
+			yamlGateway = parsedServices.Provider.GatewayURL
+		}
+	}
+
+	headerMap, err := parseSeedHeaders(seedHeaders)
+	if err != nil {
+		return err
+	}
+	if len(seedCallbackURL) > 0 {
+		headerMap.Set("X-Callback-Url", seedCallbackURL)
+	}
+	headerMap.Set("Content-Type", seedContentType)
+
+	interval, _ := parseSeedRate(seedRate)
+
+	statePath := seedStateFile
+	if len(statePath) == 0 {
+		statePath = seedFile + ".seed"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := seeder{
+		gateway:    strings.TrimRight(getGatewayURL(gateway, defaultGateway, yamlGateway, os.Getenv(openFaaSURLEnvironment)), "/"),
+		function:   args[0],
+		namespace:  seedNamespace,
+		header:     headerMap,
+		interval:   interval,
+		maxRetries: seedMaxRetries,
+		backoff:    time.Second,
+		statePath:  statePath,
+		client:     proxy.MakeHTTPClient(&commandTimeout, tlsInsecure),
+		progress:   cmd.ErrOrStderr(),
+	}
+
+	return s.run(ctx, seedFile, seedResume)
+}
+
+// parseSeedRate returns the interval between requests for a rate such as
+// 50/s, 600/m or 36000/h, a plain number is taken as per second
+func parseSeedRate(rate string) (time.Duration, error) {
+	count, unit := rate, "s"
+	if i := strings.Index(rate, "/"); i > -1 {
+		count, unit = rate[:i], rate[i+1:]
+	}
+
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --rate %q, give a number of requests such as 50/s", rate)
+	}
+
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return 0, fmt.Errorf("invalid --rate %q, the unit must be s, m or h", rate)
+	}
+
+	return time.Duration(float64(per) / n), nil
+}
+
+func parseSeedHeaders(values []string) (http.Header, error) {
+	header := http.Header{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			return nil, fmt.Errorf("the --header or -H flag must take the form of key=value")
+		}
+		header.Add(parts[0], parts[1])
+	}
+	return header, nil
+}
+
+// seedState is the progress of a seed, Line is the last line accepted
+type seedState struct {
+	Function string `json:"function"`
+	Line     int    `json:"line"`
+}
+
+type seeder struct {
+	gateway    string
+	function   string
+	namespace  string
+	header     http.Header
+	interval   time.Duration
+	maxRetries int
+	backoff    time.Duration
+	statePath  string
+	client     http.Client
+	progress   io.Writer
+}
+
+func (s *seeder) run(ctx context.Context, dataFile string, resume bool) error {
+	skip := 0
+	if resume {
+		state, err := readSeedState(s.statePath)
+		if err != nil {
+			return err
+		}
+		if state.Function != s.function {
+			return fmt.Errorf("%s records a seed of %s, not %s", s.statePath, state.Function, s.function)
+		}
+		skip = state.Line
+		fmt.Fprintf(s.progress, "Resuming after line %d\n", skip)
+	}
+
+	f, err := os.Open(dataFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	lastReport := start
+	sent := 0
+	line := 0
+
+	reader := bufio.NewReader(f)
+	for {
+		payload, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		if len(payload) == 0 && readErr == io.EOF {
+			break
+		}
+		line++
+
+		payload = bytes.TrimRight(payload, "\r\n")
+		if line > skip && len(bytes.TrimSpace(payload)) > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("stopped before line %d, run again with --resume to continue", line)
+			case <-ticker.C:
+			}
+
+			if err := s.send(ctx, payload); err != nil {
+				return fmt.Errorf("line %d: %w, run again with --resume to continue", line, err)
+			}
+			sent++
+		}
+
+		if line > skip {
+			if err := writeSeedState(s.statePath, seedState{Function: s.function, Line: line}); err != nil {
+				return err
+			}
+		}
+
+		if time.Since(lastReport) >= time.Second {
+			s.report(line, sent, time.Since(start))
+			lastReport = time.Now()
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	s.report(line, sent, time.Since(start))
+	return nil
+}
+
+func (s *seeder) report(line, sent int, elapsed time.Duration) {
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(sent) / elapsed.Seconds()
+	}
+	fmt.Fprintf(s.progress, "Line %d, %d request(s) accepted, %.1f/s\n", line, sent, rate)
+}
+
+// send makes an asynchronous request, retrying when the queue is full or the
+// gateway has an error. A request is only retried when it was not accepted.
+func (s *seeder) send(ctx context.Context, payload []byte) error {
+	u := s.gateway + "/async-function/" + s.function
+	if len(s.namespace) > 0 {
+		u += "." + s.namespace
+	}
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header = s.header.Clone()
+
+		res, err := s.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", s.gateway)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		switch {
+		case res.StatusCode == http.StatusAccepted:
+			return nil
+		case res.StatusCode == http.StatusUnauthorized:
+			return fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+		case (res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError) && attempt < s.maxRetries:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		default:
+			return fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, strings.TrimSpace(string(body)))
+		}
+	}
+}
+
+func readSeedState(path string) (seedState, error) {
+	var state seedState
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, fmt.Errorf("there is no progress to resume in %s", path)
+		}
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("unable to read progress from %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// writeSeedState renames the state into place, so it is never half written
+func writeSeedState(path string, state seedState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_parseSeedRate(t *testing.T) {
+	cases := []struct {
+		rate string
+		want time.Duration
+	}{
+		{rate: "50/s", want: 20 * time.Millisecond},
+		{rate: "60/m", want: time.Second},
+		{rate: "4", want: 250 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		got, err := parseSeedRate(c.rate)
+		if err != nil {
+			t.Fatalf("%s: %s", c.rate, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: want %s, got %s", c.rate, c.want, got)
+		}
+	}
+
+	for _, rate := range []string{"0/s", "fast", "10/d"} {
+		if _, err := parseSeedRate(rate); err == nil {
+			t.Errorf("%s: want an error", rate)
+		}
+	}
+}
+
+type seedRecorder struct {
+	mu       sync.Mutex
+	bodies   []string
+	failFrom int
+}
+
+func (r *seedRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path != "/async-function/fn1" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.failFrom > 0 && len(r.bodies)+1 >= r.failFrom {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	r.bodies = append(r.bodies, string(body))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func newTestSeeder(gatewayURL, statePath string) seeder {
+	return seeder{
+		gateway:   gatewayURL,
+		function:  "fn1",
+		header:    http.Header{},
+		interval:  time.Millisecond,
+		statePath: statePath,
+		client:    http.Client{},
+		progress:  &bytes.Buffer{},
+	}
+}
+
+func Test_seeder_Resume(t *testing.T) {
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "data.ndjson")
+	if err := os.WriteFile(dataFile, []byte("{\"n\":1}\n\n{\"n\":2}\n{\"n\":3}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	statePath := dataFile + ".seed"
+
+	recorder := &seedRecorder{failFrom: 2}
+	s := httptest.NewServer(recorder)
+	defer s.Close()
+
+	seeder := newTestSeeder(s.URL, statePath)
+	err := seeder.run(context.Background(), dataFile, false)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("want an error for line 3, got: %v", err)
+	}
+
+	recorder.failFrom = 0
+	if err := seeder.run(context.Background(), dataFile, true); err != nil {
+		t.Fatalf("want no error on resume, got: %s", err)
+	}
+
+	want := []string{`{"n":1}`, `{"n":2}`, `{"n":3}`}
+	if strings.Join(recorder.bodies, ",") != strings.Join(want, ",") {
+		t.Fatalf("want each payload sent once: %v, got: %v", want, recorder.bodies)
+	}
+}
+
+func Test_seeder_ResumeOtherFunction(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "data.seed")
+	if err := writeSeedState(statePath, seedState{Function: "fn2", Line: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	seeder := newTestSeeder("http://127.0.0.1:1", statePath)
+	if err := seeder.run(context.Background(), "missing.ndjson", true); err == nil || !strings.Contains(err.Error(), "fn2") {
+		t.Fatalf("want an error naming fn2, got: %v", err)
+	}
+}
+
+func Test_seeder_RetriesTooManyRequests(t *testing.T) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer s.Close()
+
+	seeder := newTestSeeder(s.URL, "")
+	seeder.maxRetries = 1
+	seeder.backoff = time.Millisecond
+
+	if err := seeder.send(context.Background(), []byte("{}")); err != nil {
+		t.Fatalf("want no error, got: %s", err)
+	}
+	if calls != 2 {
+		t.Fatalf("want 2 calls, got: %d", calls)
+	}
+}
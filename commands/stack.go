@@ -0,0 +1,18 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	faasCmd.AddCommand(stackCmd)
+}
+
+var stackCmd = &cobra.Command{
+	Use:   `stack`,
+	Short: "OpenFaaS stack file commands",
+	Long:  "Sign, verify and render stack files, or convert them from other frameworks",
+}
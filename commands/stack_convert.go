@@ -0,0 +1,570 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	convertFormatServerless = "serverless"
+	convertFormatSAM        = "sam"
+)
+
+var (
+	convertFrom      string
+	convertOutput    string
+	convertOverwrite bool
+)
+
+// intrinsicTag matches CloudFormation's short form functions, such as !Ref,
+// which the YAML parser would otherwise drop, leaving only their argument
+var intrinsicTag = regexp.MustCompile(`(^|[\s\[,:-])!(Ref|GetAtt|Sub|Join|Select|Split|If|Equals|Not|And|Or|ImportValue|FindInMap|Base64|GetAZs|Cidr)\b`)
+
+var ratePattern = regexp.MustCompile(`^rate\((\d+) (minute|minutes|hour|hours|day|days)\)$`)
+var cronPattern = regexp.MustCompile(`^cron\((.*)\)$`)
+
+func init() {
+	stackConvertCmd.Flags().StringVar(&convertFrom, "from", "", "Path to a serverless.yml of the Serverless Framework, or a template.yaml of AWS SAM")
+	stackConvertCmd.Flags().StringVarP(&convertOutput, "output", "o", defaultYAML, "Path of the stack file to write, or - for stdout")
+	stackConvertCmd.Flags().BoolVar(&convertOverwrite, "overwrite", false, "Overwrite the output file if it exists")
+	stackConvertCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL to write into the stack file")
+
+	stackCmd.AddCommand(stackConvertCmd)
+}
+
+var stackConvertCmd = &cobra.Command{
+	Use:   `convert --from serverless.yml|template.yaml [--output stack.yml]`,
+	Short: "Convert a Serverless Framework or AWS SAM file to a stack file",
+	Long: `Converts the functions of a serverless.yml or an AWS SAM template.yaml into
+a stack file for OpenFaaS, including their environment, memory, timeouts and
+schedules, which become annotations for the cron-connector.
+
+Anything which can't be converted is marked with a TODO comment, such as HTTP
+routes, event sources other than schedules, and CloudFormation references. The
+handlers are not converted, the code needs to be moved into a template.`,
+	Example: `  faas-cli stack convert --from serverless.yml
+  faas-cli stack convert --from template.yaml --output migrated.yml
+  faas-cli stack convert --from serverless.yml --output -`,
+	RunE: runStackConvert,
+}
+
+func runStackConvert(cmd *cobra.Command, args []string) error {
+	if len(convertFrom) == 0 {
+		return fmt.Errorf("give the file to convert with --from")
+	}
+
+	data, err := os.ReadFile(convertFrom)
+	if err != nil {
+		return err
+	}
+
+	functions, err := convertServerlessFile(data)
+	if err != nil {
+		return fmt.Errorf("unable to convert %s: %w", convertFrom, err)
+	}
+
+	out, err := renderConvertedStack(functions, gateway)
+	if err != nil {
+		return err
+	}
+
+	if convertOutput == "-" {
+		_, err := cmd.OutOrStdout().Write(out)
+		return err
+	}
+
+	if _, err := os.Stat(convertOutput); err == nil && !convertOverwrite {
+		return fmt.Errorf("%s already exists, use --overwrite to replace it", convertOutput)
+	}
+	if err := os.WriteFile(convertOutput, out, 0600); err != nil {
+		return err
+	}
+
+	todos := 0
+	for _, fn := range functions {
+		todos += len(fn.todos)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %d function(s) to %s, with %d TODO(s) to review\n", len(functions), convertOutput, todos)
+	return nil
+}
+
+// convertedFunction is a function for the stack file, with notes on what
+// could not be converted
+type convertedFunction struct {
+	name     string
+	function stack.Function
+	todos    []string
+}
+
+// lambdaSettings are the properties shared by both formats, from the
+// function or its defaults
+type lambdaSettings struct {
+	name        string
+	runtime     string
+	handler     string
+	memory      int
+	timeout     int
+	environment map[string]interface{}
+}
+
+// convertServerlessFile detects the format of a Serverless Framework or SAM
+// file, and converts its functions
+func convertServerlessFile(data []byte) ([]convertedFunction, error) {
+	data = intrinsicTag.ReplaceAll(data, []byte("${1}__intrinsic_${2}__ "))
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var functions []convertedFunction
+	switch detectConvertFormat(doc) {
+	case convertFormatServerless:
+		functions = convertServerlessFramework(doc)
+	case convertFormatSAM:
+		functions = convertSAM(doc)
+	default:
+		return nil, fmt.Errorf("expected a serverless.yml with \"service\" and \"functions\", or a SAM template with \"Resources\"")
+	}
+
+	if len(functions) == 0 {
+		return nil, fmt.Errorf("no functions were found")
+	}
+
+	sort.Slice(functions, func(i, j int) bool { return functions[i].name < functions[j].name })
+	return functions, nil
+}
+
+func detectConvertFormat(doc map[string]interface{}) string {
+	if _, ok := doc["Resources"]; ok {
+		return convertFormatSAM
+	}
+	if _, ok := doc["functions"]; ok {
+		return convertFormatServerless
+	}
+	return ""
+}
+
+func convertServerlessFramework(doc map[string]interface{}) []convertedFunction {
+	provider := yamlMap(doc["provider"])
+	defaults := lambdaSettings{
+		runtime:     yamlString(provider["runtime"]),
+		memory:      yamlInt(provider["memorySize"]),
+		timeout:     yamlInt(provider["timeout"]),
+		environment: yamlMap(provider["environment"]),
+	}
+
+	var functions []convertedFunction
+	for key, value := range yamlMap(doc["functions"]) {
+		props := yamlMap(value)
+
+		settings := defaults
+		settings.name = key
+		settings.handler = yamlString(props["handler"])
+		if runtime := yamlString(props["runtime"]); len(runtime) > 0 {
+			settings.runtime = runtime
+		}
+		if memory := yamlInt(props["memorySize"]); memory > 0 {
+			settings.memory = memory
+		}
+		if timeout := yamlInt(props["timeout"]); timeout > 0 {
+			settings.timeout = timeout
+		}
+		settings.environment = mergeYAMLMaps(defaults.environment, yamlMap(props["environment"]))
+
+		fn := convertLambda(settings)
+
+		for _, event := range yamlList(props["events"]) {
+			for eventType, config := range yamlMap(event) {
+				switch eventType {
+				case "schedule":
+					expression := yamlString(config)
+					if expression == "" {
+						expression = yamlString(yamlMap(config)["rate"])
+					}
+					fn.addSchedule(expression)
+				case "http", "httpApi":
+					fn.addRoute(yamlString(yamlMap(config)["method"]), yamlString(yamlMap(config)["path"]), yamlString(config))
+				default:
+					fn.todo("the %s event is not supported, an OpenFaaS connector may provide it", eventType)
+				}
+			}
+		}
+
+		for _, key := range []string{"layers", "vpc", "role", "reservedConcurrency", "provisionedConcurrency", "destinations"} {
+			if _, ok := props[key]; ok {
+				fn.todo("%s has no equivalent in a stack file", key)
+			}
+		}
+
+		functions = append(functions, fn)
+	}
+
+	return functions
+}
+
+func convertSAM(doc map[string]interface{}) []convertedFunction {
+	globals := yamlMap(yamlMap(doc["Globals"])["Function"])
+	defaults := lambdaSettings{
+		runtime:     yamlString(globals["Runtime"]),
+		memory:      yamlInt(globals["MemorySize"]),
+		timeout:     yamlInt(globals["Timeout"]),
+		environment: yamlMap(yamlMap(globals["Environment"])["Variables"]),
+	}
+
+	var functions []convertedFunction
+	for key, value := range yamlMap(doc["Resources"]) {
+		resource := yamlMap(value)
+		if yamlString(resource["Type"]) != "AWS::Serverless::Function" {
+			continue
+		}
+		props := yamlMap(resource["Properties"])
+
+		settings := defaults
+		settings.name = key
+		settings.handler = strings.TrimSpace(yamlString(props["CodeUri"]) + " " + yamlString(props["Handler"]))
+		if runtime := yamlString(props["Runtime"]); len(runtime) > 0 {
+			settings.runtime = runtime
+		}
+		if memory := yamlInt(props["MemorySize"]); memory > 0 {
+			settings.memory = memory
+		}
+		if timeout := yamlInt(props["Timeout"]); timeout > 0 {
+			settings.timeout = timeout
+		}
+		settings.environment = mergeYAMLMaps(defaults.environment, yamlMap(yamlMap(props["Environment"])["Variables"]))
+
+		fn := convertLambda(settings)
+
+		events := yamlMap(props["Events"])
+		eventNames := make([]string, 0, len(events))
+		for name := range events {
+			eventNames = append(eventNames, name)
+		}
+		sort.Strings(eventNames)
+
+		for _, name := range eventNames {
+			event := yamlMap(events[name])
+			config := yamlMap(event["Properties"])
+			switch eventType := yamlString(event["Type"]); eventType {
+			case "Schedule", "ScheduleV2":
+				expression := yamlString(config["Schedule"])
+				if expression == "" {
+					expression = yamlString(config["ScheduleExpression"])
+				}
+				fn.addSchedule(expression)
+			case "Api", "HttpApi":
+				fn.addRoute(yamlString(config["Method"]), yamlString(config["Path"]), "")
+			default:
+				fn.todo("the %s event %s is not supported, an OpenFaaS connector may provide it", eventType, name)
+			}
+		}
+
+		for _, key := range []string{"Layers", "VpcConfig", "Role", "Policies", "ReservedConcurrentExecutions", "ProvisionedConcurrencyConfig"} {
+			if _, ok := props[key]; ok {
+				fn.todo("%s has no equivalent in a stack file", key)
+			}
+		}
+
+		functions = append(functions, fn)
+	}
+
+	return functions
+}
+
+// convertLambda maps the settings common to both formats
+func convertLambda(settings lambdaSettings) convertedFunction {
+	name := functionNameFromKey(settings.name)
+	fn := convertedFunction{
+		name: name,
+		function: stack.Function{
+			Handler:     "./" + name,
+			Image:       name + ":latest",
+			Environment: map[string]string{},
+		},
+	}
+
+	lang, ok := templateForRuntime(settings.runtime)
+	fn.function.Language = lang
+	if !ok {
+		fn.todo("no template was found for the runtime %q, pick one with: faas-cli template store list", settings.runtime)
+	}
+	if len(settings.handler) > 0 {
+		fn.todo("move the code of %s into %s", settings.handler, fn.function.Handler)
+	}
+
+	keys := make([]string, 0, len(settings.environment))
+	for key := range settings.environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, ok := settings.environment[key].(string)
+		if !ok {
+			switch v := settings.environment[key].(type) {
+			case int, float64, bool:
+				value, ok = fmt.Sprint(v), true
+			}
+		}
+
+		if !ok || strings.Contains(value, "__intrinsic_") || strings.Contains(value, "${") {
+			fn.todo("environment variable %s refers to a value which can't be resolved outside of AWS, consider a secret", key)
+			continue
+		}
+		fn.function.Environment[key] = value
+	}
+
+	if settings.memory > 0 {
+		fn.function.Limits = &stack.FunctionResources{Memory: fmt.Sprintf("%dMi", settings.memory)}
+	}
+
+	if settings.timeout > 0 {
+		timeout := fmt.Sprintf("%ds", settings.timeout)
+		fn.function.Environment["read_timeout"] = timeout
+		fn.function.Environment["write_timeout"] = timeout
+		fn.function.Environment["exec_timeout"] = timeout
+	}
+
+	return fn
+}
+
+func (fn *convertedFunction) todo(format string, a ...interface{}) {
+	fn.todos = append(fn.todos, fmt.Sprintf(format, a...))
+}
+
+// addSchedule adds the annotations of the cron-connector for a rate() or
+// cron() expression
+func (fn *convertedFunction) addSchedule(expression string) {
+	schedule, err := convertScheduleExpression(expression)
+	if err != nil {
+		fn.todo("the schedule %q could not be converted: %s", expression, err)
+		return
+	}
+
+	if fn.function.Annotations == nil {
+		fn.function.Annotations = &map[string]string{}
+	}
+	annotations := *fn.function.Annotations
+	if existing, ok := annotations["schedule"]; ok {
+		fn.todo("only one schedule is supported by the cron-connector, %q was kept and %q was dropped", existing, schedule)
+		return
+	}
+
+	annotations["topic"] = "cron-function"
+	annotations["schedule"] = schedule
+}
+
+func (fn *convertedFunction) addRoute(method, path, shorthand string) {
+	if len(shorthand) > 0 {
+		// Serverless accepts "GET users/create" as the http event
+		if parts := strings.Fields(shorthand); len(parts) == 2 {
+			method, path = parts[0], parts[1]
+		}
+	}
+	if len(method) == 0 {
+		method = "any"
+	}
+
+	fn.todo("%s /%s was an HTTP route, the function is served at /function/%s, use \"faas-cli ingress create\" for a custom domain",
+		strings.ToUpper(method), strings.TrimPrefix(path, "/"), fn.name)
+}
+
+// convertScheduleExpression converts rate() and cron() of EventBridge to a
+// cron expression with five fields
+func convertScheduleExpression(expression string) (string, error) {
+	if m := ratePattern.FindStringSubmatch(expression); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 {
+			return "", fmt.Errorf("the rate must be at least 1")
+		}
+
+		switch strings.TrimSuffix(m[2], "s") {
+		case "minute":
+			if n == 1 {
+				return "* * * * *", nil
+			}
+			return fmt.Sprintf("*/%d * * * *", n), nil
+		case "hour":
+			if n == 1 {
+				return "0 * * * *", nil
+			}
+			return fmt.Sprintf("0 */%d * * *", n), nil
+		default:
+			if n == 1 {
+				return "0 0 * * *", nil
+			}
+			return fmt.Sprintf("0 0 */%d * *", n), nil
+		}
+	}
+
+	if m := cronPattern.FindStringSubmatch(expression); m != nil {
+		fields := strings.Fields(m[1])
+		if len(fields) != 6 {
+			return "", fmt.Errorf("expected six fields in cron()")
+		}
+		if fields[5] != "*" {
+			return "", fmt.Errorf("a year can't be given to the cron-connector")
+		}
+
+		// EventBridge numbers the days of the week from 1 for Sunday
+		if strings.ContainsAny(fields[4], "0123456789#L") {
+			return "", fmt.Errorf("numbered days of the week differ, use names such as MON-FRI")
+		}
+
+		for i, field := range fields[:5] {
+			if field == "?" {
+				fields[i] = "*"
+			}
+		}
+		return strings.Join(fields[:5], " "), nil
+	}
+
+	return "", fmt.Errorf("expected rate() or cron()")
+}
+
+// templateForRuntime picks the template from the store which is closest to
+// a Lambda runtime
+func templateForRuntime(runtime string) (string, bool) {
+	switch {
+	case strings.HasPrefix(runtime, "nodejs"):
+		return "node18", true
+	case strings.HasPrefix(runtime, "python3"):
+		return "python3-http", true
+	case strings.HasPrefix(runtime, "go"):
+		return "golang-middleware", true
+	case strings.HasPrefix(runtime, "java"):
+		return "java11", true
+	case strings.HasPrefix(runtime, "ruby"):
+		return "ruby-http", true
+	case strings.HasPrefix(runtime, "dotnet"):
+		return "csharp-httprequest", true
+	}
+	return "dockerfile", false
+}
+
+var functionNameSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+var camelCaseBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// functionNameFromKey converts HelloWorldFunction or hello_world to
+// hello-world, as function names must be valid DNS labels
+func functionNameFromKey(key string) string {
+	if len(key) > len("Function") && strings.HasSuffix(key, "Function") {
+		key = strings.TrimSuffix(key, "Function")
+	}
+	name := strings.ToLower(camelCaseBoundary.ReplaceAllString(key, "${1}-${2}"))
+	return strings.Trim(functionNameSeparator.ReplaceAllString(name, "-"), "-")
+}
+
+// renderConvertedStack writes the stack file, with each function's TODOs as
+// comments above it
+func renderConvertedStack(functions []convertedFunction, gatewayURL string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "version: %s\nprovider:\n  name: openfaas\n  gateway: %s\nfunctions:\n", defaultSchemaVersion, gatewayURL)
+
+	for i, fn := range functions {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, todo := range fn.todos {
+			fmt.Fprintf(&b, "  # TODO: %s\n", todo)
+		}
+
+		out, err := marshalWithoutEmpty(fn.function)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "  %s:\n", fn.name)
+		for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// marshalWithoutEmpty drops the fields of a function which have no value,
+// but are not marked omitempty, such as fprocess
+func marshalWithoutEmpty(function stack.Function) ([]byte, error) {
+	out, err := yaml.Marshal(function)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields yaml.MapSlice
+	if err := yaml.Unmarshal(out, &fields); err != nil {
+		return nil, err
+	}
+
+	var kept yaml.MapSlice
+	for _, field := range fields {
+		switch v := field.Value.(type) {
+		case nil:
+			continue
+		case string:
+			if len(v) == 0 {
+				continue
+			}
+		case yaml.MapSlice:
+			if len(v) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, field)
+	}
+	return yaml.Marshal(kept)
+}
+
+func yamlMap(value interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	if values, ok := value.(map[interface{}]interface{}); ok {
+		for key, v := range values {
+			m[fmt.Sprint(key)] = v
+		}
+	}
+	return m
+}
+
+func yamlList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+func yamlString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+func yamlInt(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+func mergeYAMLMaps(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overlay {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+const testServerlessYAML = `service: orders
+provider:
+  name: aws
+  runtime: nodejs18.x
+  memorySize: 256
+  environment:
+    STAGE: prod
+    TABLE: ${self:service}-table
+functions:
+  createOrder:
+    handler: src/create.handler
+    timeout: 20
+    environment:
+      STAGE: dev
+    events:
+      - http:
+          path: orders
+          method: post
+      - sqs: arn:aws:sqs:us-east-1:123456789012:orders
+  nightly_report:
+    handler: src/report.handler
+    runtime: python3.11
+    events:
+      - schedule: rate(2 hours)
+`
+
+const testSAMTemplate = `AWSTemplateFormatVersion: '2010-09-09'
+Transform: AWS::Serverless-2016-10-31
+Globals:
+  Function:
+    Timeout: 3
+    Environment:
+      Variables:
+        LOG_LEVEL: info
+Resources:
+  HelloWorldFunction:
+    Type: AWS::Serverless::Function
+    Properties:
+      CodeUri: hello_world/
+      Handler: app.lambda_handler
+      Runtime: python3.9
+      MemorySize: 128
+      Environment:
+        Variables:
+          TABLE_NAME: !Ref OrdersTable
+      Events:
+        HelloWorld:
+          Type: Api
+          Properties:
+            Path: /hello
+            Method: get
+        Weekdays:
+          Type: Schedule
+          Properties:
+            Schedule: cron(0 9 ? * MON-FRI *)
+  OrdersTable:
+    Type: AWS::Serverless::SimpleTable
+`
+
+func Test_convertServerlessFile_ServerlessFramework(t *testing.T) {
+	functions, err := convertServerlessFile([]byte(testServerlessYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(functions) != 2 || functions[0].name != "create-order" || functions[1].name != "nightly-report" {
+		t.Fatalf("want create-order and nightly-report, got %v", functions)
+	}
+
+	create := functions[0].function
+	if create.Language != "node18" || create.Handler != "./create-order" || create.Image != "create-order:latest" {
+		t.Errorf("unexpected function: %+v", create)
+	}
+	if create.Environment["STAGE"] != "dev" {
+		t.Errorf("want the function's environment to override the provider's, got %v", create.Environment)
+	}
+	if _, ok := create.Environment["TABLE"]; ok {
+		t.Errorf("want variables which refer to ${...} to be left out, got %v", create.Environment)
+	}
+	if create.Environment["exec_timeout"] != "20s" || create.Limits == nil || create.Limits.Memory != "256Mi" {
+		t.Errorf("want the timeout and memory converted, got %v %v", create.Environment, create.Limits)
+	}
+	assertTodos(t, functions[0].todos, "move the code of src/create.handler", "POST /orders was an HTTP route", "the sqs event is not supported", "TABLE refers to a value")
+
+	report := functions[1].function
+	if report.Language != "python3-http" {
+		t.Errorf("want the function's runtime, got %s", report.Language)
+	}
+	if report.Annotations == nil || (*report.Annotations)["schedule"] != "0 */2 * * *" || (*report.Annotations)["topic"] != "cron-function" {
+		t.Errorf("want cron-connector annotations, got %v", report.Annotations)
+	}
+}
+
+func Test_convertServerlessFile_SAM(t *testing.T) {
+	functions, err := convertServerlessFile([]byte(testSAMTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(functions) != 1 || functions[0].name != "hello-world" {
+		t.Fatalf("want only hello-world, got %v", functions)
+	}
+
+	fn := functions[0].function
+	if fn.Environment["LOG_LEVEL"] != "info" || fn.Environment["exec_timeout"] != "3s" {
+		t.Errorf("want the globals applied, got %v", fn.Environment)
+	}
+	if _, ok := fn.Environment["TABLE_NAME"]; ok {
+		t.Errorf("want !Ref to be left out, got %v", fn.Environment)
+	}
+	if fn.Annotations == nil || (*fn.Annotations)["schedule"] != "0 9 * * MON-FRI" {
+		t.Errorf("want the schedule converted, got %v", fn.Annotations)
+	}
+	assertTodos(t, functions[0].todos, "move the code of hello_world/ app.lambda_handler", "GET /hello", "TABLE_NAME refers to a value")
+}
+
+func Test_convertServerlessFile_UnknownFormat(t *testing.T) {
+	if _, err := convertServerlessFile([]byte("version: 1.0\n")); err == nil {
+		t.Fatal("want an error for a file which is neither format")
+	}
+}
+
+func Test_convertScheduleExpression(t *testing.T) {
+	cases := []struct {
+		expression string
+		want       string
+		wantErr    bool
+	}{
+		{expression: "rate(1 minute)", want: "* * * * *"},
+		{expression: "rate(15 minutes)", want: "*/15 * * * *"},
+		{expression: "rate(1 hour)", want: "0 * * * *"},
+		{expression: "rate(3 days)", want: "0 0 */3 * *"},
+		{expression: "cron(0/5 8-17 ? * MON-FRI *)", want: "0/5 8-17 * * MON-FRI"},
+		{expression: "cron(0 12 * * ? 2024)", wantErr: true},
+		{expression: "cron(0 12 ? * 2 *)", wantErr: true},
+		{expression: "every day", wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, err := convertScheduleExpression(c.expression)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want an error, got %q", c.expression, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: want %q, got %q %v", c.expression, c.want, got, err)
+		}
+	}
+}
+
+func Test_renderConvertedStack(t *testing.T) {
+	functions, err := convertServerlessFile([]byte(testServerlessYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := renderConvertedStack(functions, defaultGateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(out), "  # TODO: the sqs event is not supported") {
+		t.Errorf("want TODOs as comments, got:\n%s", out)
+	}
+	if strings.Contains(string(out), "fprocess") {
+		t.Errorf("want empty fields left out, got:\n%s", out)
+	}
+
+	services, err := stack.ParseYAMLData(out, "", "", false)
+	if err != nil {
+		t.Fatalf("want a valid stack file, got %s:\n%s", err, out)
+	}
+	if fn := services.Functions["nightly-report"]; fn.Language != "python3-http" {
+		t.Errorf("want nightly-report in the stack file, got: %+v", services.Functions)
+	}
+}
+
+func assertTodos(t *testing.T, todos []string, want ...string) {
+	t.Helper()
+
+	all := strings.Join(todos, "\n")
+	for _, w := range want {
+		if !strings.Contains(all, w) {
+			t.Errorf("want a TODO with %q in:\n%s", w, all)
+		}
+	}
+}
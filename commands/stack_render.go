@@ -0,0 +1,283 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	renderOutput      string
+	renderShowSecrets bool
+)
+
+// envReference matches the variables which envsubst replaces, such as $TAG,
+// ${TAG} and ${TAG:-latest}
+var envReference = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+func init() {
+	stackRenderCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+	stackRenderCmd.Flags().StringVarP(&renderOutput, "output", "o", "-", "Path of the file to write, or - for stdout")
+	stackRenderCmd.Flags().BoolVar(&renderShowSecrets, "show-secrets", false, "Print the values of environment variables which look like credentials, they are masked by default")
+
+	stackCmd.AddCommand(stackRenderCmd)
+}
+
+var stackRenderCmd = &cobra.Command{
+	Use:   `render [-f stack.yml] [--profile PROFILE] [--set KEY=VALUE]`,
+	Short: "Print a stack file as the CLI reads it, with where each value came from",
+	Long: `Prints the stack file after environment variables are substituted, the
+enabled expressions of functions are evaluated, and the environment_file of
+each function is merged into its environment, which is what deploy would use.
+
+A comment after a value says where it came from, when it was not written in
+the stack file as it is: the environment variable which was substituted, the
+environment_file which set it, or the provider's namespace. Functions which are
+not enabled for the --profile and --set values are listed at the end.
+
+Values of environment variables which look like credentials are masked, unless
+--show-secrets is given.`,
+	Example: `  faas-cli stack render
+  faas-cli stack render -f stack.yml --profile prod
+  faas-cli stack render --set region=eu --output rendered.yml`,
+	RunE: runStackRender,
+}
+
+func runStackRender(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(yamlFile)
+	if err != nil {
+		return err
+	}
+
+	services, err := stack.ParseYAMLData(data, regex, filter, envsubst)
+	if err != nil {
+		return err
+	}
+
+	// The file as written, before substitution, is read to find the values
+	// which were substituted, or defaulted
+	var written renderedSource
+	if err := yaml.Unmarshal(data, &written); err != nil {
+		return err
+	}
+
+	out, err := renderStack(services, written)
+	if err != nil {
+		return err
+	}
+
+	if renderOutput == "-" {
+		_, err := cmd.OutOrStdout().Write(out)
+		return err
+	}
+	return os.WriteFile(renderOutput, out, 0600)
+}
+
+// renderedSource is the stack file as written, without substitution
+type renderedSource struct {
+	Provider  map[string]interface{}            `yaml:"provider"`
+	Functions map[string]map[string]interface{} `yaml:"functions"`
+}
+
+// renderStack writes the services as YAML, with a comment after each value
+// which did not come from the stack file as written
+func renderStack(services *stack.Services, written renderedSource) ([]byte, error) {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "# Rendered by faas-cli stack render from %s\n", yamlFile)
+	if len(stackProfile) > 0 {
+		fmt.Fprintf(&b, "# --profile %s\n", stackProfile)
+	}
+	for _, value := range stackValues {
+		fmt.Fprintf(&b, "# --set %s\n", value)
+	}
+
+	version := services.Version
+	if len(version) == 0 {
+		version = defaultSchemaVersion
+	}
+	fmt.Fprintf(&b, "version: %s\n", version)
+
+	provider, err := yaml.Marshal(services.Provider)
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString("provider:\n")
+	writeAnnotatedYAML(&b, "  ", provider, func(key string) string {
+		return substitutionNote(written.Provider[key])
+	})
+
+	if config := services.StackConfiguration; len(config.TemplateConfigs) > 0 || len(config.CopyExtraPaths) > 0 || len(config.Registries) > 0 {
+		out, err := yaml.Marshal(yaml.MapSlice{{Key: "configuration", Value: config}})
+		if err != nil {
+			return nil, err
+		}
+		b.Write(out)
+	}
+
+	if len(services.Extensions) > 0 {
+		out, err := yaml.Marshal(services.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(out)
+	}
+
+	names := make([]string, 0, len(services.Functions))
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("functions:\n")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if err := renderFunction(&b, name, services.Functions[name], written.Functions[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	var disabled []string
+	for name, fields := range written.Functions {
+		if _, ok := services.Functions[name]; ok {
+			continue
+		}
+		if expression, ok := fields["enabled"].(string); ok {
+			disabled = append(disabled, fmt.Sprintf("%s (enabled: %s)", name, expression))
+		}
+	}
+	if len(disabled) > 0 {
+		sort.Strings(disabled)
+		b.WriteString("\n# Not enabled:\n")
+		for _, function := range disabled {
+			fmt.Fprintf(&b, "#   %s\n", function)
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+func renderFunction(b *bytes.Buffer, name string, function stack.Function, written map[string]interface{}) error {
+	function.Name = name
+	layers, err := functionEnvLayers(function)
+	if err != nil {
+		return err
+	}
+
+	enabled := function.Enabled
+	function.Enabled = ""
+	function.Environment = nil
+	function.EnvironmentFile = nil
+
+	if len(enabled) > 0 {
+		fmt.Fprintf(b, "  # enabled: %s\n", enabled)
+	}
+	fmt.Fprintf(b, "  %s:\n", name)
+
+	out, err := marshalWithoutEmpty(function)
+	if err != nil {
+		return err
+	}
+	writeAnnotatedYAML(b, "    ", out, func(key string) string {
+		if key == "namespace" && written["namespace"] == nil {
+			return "from provider.namespace"
+		}
+		return substitutionNote(written[key])
+	})
+
+	variables := explainEnvironment(layers)
+	if len(variables) == 0 {
+		return nil
+	}
+
+	writtenEnvironment, _ := written["environment"].(map[interface{}]interface{})
+	b.WriteString("    environment:\n")
+	for _, v := range variables {
+		value, note := v.Value, ""
+		if v.Source == "environment" {
+			note = substitutionNote(writtenEnvironment[v.Name])
+		} else {
+			note = "from " + v.Source
+			if len(v.Overridden) > 0 {
+				note += ", overrides " + strings.Join(v.Overridden, ", ")
+			}
+		}
+		if !renderShowSecrets && looksLikeSecret(v.Name, value) {
+			value = maskValue(value)
+			note = strings.TrimPrefix(note+", masked", ", ")
+		}
+
+		line, err := yaml.Marshal(map[string]string{v.Name: value})
+		if err != nil {
+			return err
+		}
+		writeAnnotatedYAML(b, "      ", line, func(string) string { return note })
+	}
+	return nil
+}
+
+// writeAnnotatedYAML indents the YAML, and adds the note for each top-level
+// key after it
+func writeAnnotatedYAML(b *bytes.Buffer, indent string, out []byte, note func(key string) string) {
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		var comment string
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			if i := strings.Index(line, ":"); i > 0 {
+				comment = note(line[:i])
+			}
+		}
+		writeLine(b, indent, line, comment)
+	}
+}
+
+func writeLine(b *bytes.Buffer, indent, line, comment string) {
+	b.WriteString(indent)
+	b.WriteString(line)
+	if len(comment) > 0 {
+		b.WriteString(" # ")
+		b.WriteString(comment)
+	}
+	b.WriteString("\n")
+}
+
+// substitutionNote names the environment variables which were substituted
+// into a value as it was written, and whether they were set
+func substitutionNote(written interface{}) string {
+	value, ok := written.(string)
+	if !ok || !envsubst {
+		return ""
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	for _, match := range envReference.FindAllStringSubmatch(value, -1) {
+		name := match[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if _, set := os.LookupEnv(name); set {
+			names = append(names, "$"+name)
+		} else {
+			names = append(names, "$"+name+" (not set)")
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+	return "from " + strings.Join(names, ", ")
+}
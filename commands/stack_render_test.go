@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_stackRender_Provenance(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "prod.yml")
+	os.WriteFile(envFile, []byte("environment:\n  mode: live\n  api_token: abcdef123456\n"), 0600)
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	os.WriteFile(stackFile, []byte(`version: 1.0
+provider:
+  name: openfaas
+  gateway: http://127.0.0.1:8080
+  namespace: tenant-a
+functions:
+  charge:
+    lang: go
+    handler: ./charge
+    image: acme/charge:${TAG:-latest}
+    environment:
+      mode: test
+      region: eu
+    environment_file:
+      - `+envFile+`
+  debug:
+    lang: go
+    handler: ./debug
+    image: acme/debug:0.1.0
+    enabled: profile == "dev"
+`), 0600)
+
+	t.Setenv("TAG", "0.2.0")
+
+	resetForTest()
+	renderOutput, renderShowSecrets = "-", false
+	defer func() { stackProfile, stackValues = "", nil }()
+
+	var buf bytes.Buffer
+	faasCmd.SetOut(&buf)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs([]string{"stack", "render", "-f", stackFile, "--profile", "prod"})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# --profile prod",
+		"image: acme/charge:0.2.0 # from $TAG",
+		"namespace: tenant-a # from provider.namespace",
+		"mode: live # from " + envFile + ", overrides environment",
+		"region: eu\n",
+		"api_token: ab******56 # from " + envFile + ", masked",
+		`debug (enabled: profile == "dev")`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in:\n%s", want, out)
+		}
+	}
+
+	rendered, err := stack.ParseYAMLData([]byte(out), "", "", false)
+	if err != nil {
+		t.Fatalf("want the rendered stack to be parsed again, got: %s", err)
+	}
+	if fn := rendered.Functions["charge"]; fn.Environment["mode"] != "live" || fn.Image != "acme/charge:0.2.0" {
+		t.Errorf("want the merged values in the rendered stack, got: %v", fn)
+	}
+	if _, ok := rendered.Functions["debug"]; ok {
+		t.Errorf("want debug left out of the rendered stack")
+	}
+}
+
+func Test_substitutionNote(t *testing.T) {
+	savedEnvsubst := envsubst
+	envsubst = true
+	defer func() { envsubst = savedEnvsubst }()
+
+	t.Setenv("REGISTRY", "ghcr.io")
+	os.Unsetenv("TAG")
+
+	got := substitutionNote("${REGISTRY}/acme/charge:${TAG:-latest}")
+	if want := "from $REGISTRY, $TAG (not set)"; got != want {
+		t.Fatalf("want: %q, got: %q", want, got)
+	}
+
+	if got := substitutionNote("acme/charge:0.1.0"); len(got) > 0 {
+		t.Errorf("want no note without a variable, got: %q", got)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	stackSignatureVersion   = "1"
+	stackSignatureAlgorithm = "ed25519"
+	stackSignatureExt       = ".sig"
+)
+
+var (
+	stackKeyFile           string
+	stackPublicKeyFile     string
+	stackSignatureOverride string
+	stackSignIncludes      []string
+	stackKeygenOutput      string
+)
+
+func init() {
+	stackKeygenCmd.Flags().StringVarP(&stackKeygenOutput, "output", "o", "stack", "Prefix for the generated .key and .pub files")
+
+	stackSignCmd.Flags().StringVar(&stackKeyFile, "key", "", "Private key to sign with, as written by \"faas-cli stack keygen\"")
+	stackSignCmd.Flags().StringArrayVar(&stackSignIncludes, "include", []string{}, "Additional file to sign along with the stack file, such as a lockfile")
+	stackSignCmd.Flags().StringVar(&stackSignatureOverride, "signature", "", "Signature file to write, defaults to the stack file with a .sig suffix")
+
+	stackVerifyCmd.Flags().StringVar(&stackPublicKeyFile, "public-key", "", "Public key which must have made the signature")
+	stackVerifyCmd.Flags().StringVar(&stackSignatureOverride, "signature", "", "Signature file to verify, defaults to the stack file with a .sig suffix")
+
+	stackCmd.AddCommand(stackKeygenCmd)
+	stackCmd.AddCommand(stackSignCmd)
+	stackCmd.AddCommand(stackVerifyCmd)
+}
+
+var stackKeygenCmd = &cobra.Command{
+	Use:   `keygen [--output PREFIX]`,
+	Short: "Generate a key pair for signing stack files",
+	Long: `Generates an ed25519 key pair, the private key is written to PREFIX.key
+and the public key to PREFIX.pub. Keep the private key out of source control,
+and share the public key with whoever needs to verify signatures.`,
+	Example: `  faas-cli stack keygen
+  faas-cli stack keygen --output ~/.openfaas/release`,
+	RunE: runStackKeygen,
+}
+
+var stackSignCmd = &cobra.Command{
+	Use:   `sign -f YAML_FILE --key KEY_FILE [--include FILE ...]`,
+	Short: "Sign a stack file",
+	Long: `Signs the SHA-256 digest of a stack file, and of any additional files given
+with --include, such as lockfiles. The signature is written next to the stack
+file with a .sig suffix, and should be committed along with it.`,
+	Example: `  faas-cli stack sign --key stack.key
+  faas-cli stack sign -f ./prod.yml --key stack.key --include ./prod.lock`,
+	PreRunE: preRunStackFile,
+	RunE:    runStackSign,
+}
+
+var stackVerifyCmd = &cobra.Command{
+	Use:   `verify -f YAML_FILE --public-key PUBLIC_KEY_FILE`,
+	Short: "Verify the signature of a stack file",
+	Long: `Checks that the signature for a stack file was made by the given public key,
+and that neither the stack file nor any other signed file has changed since.
+
+Use "faas-cli deploy --verify-key" to refuse to deploy an unsigned or
+modified stack file.`,
+	Example: `  faas-cli stack verify --public-key stack.pub
+  faas-cli stack verify -f ./prod.yml --public-key stack.pub`,
+	PreRunE: preRunStackFile,
+	RunE:    runStackVerify,
+}
+
+// stackSignature is the content of a .sig file
+type stackSignature struct {
+	Version   string       `json:"version"`
+	Algorithm string       `json:"algorithm"`
+	PublicKey string       `json:"publicKey"`
+	Timestamp time.Time    `json:"timestamp"`
+	Files     []signedFile `json:"files"`
+	Signature string       `json:"signature"`
+}
+
+// signedFile records the digest of a signed file, the path is relative
+// to the folder holding the signature
+type signedFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// payload is the data covered by the signature
+func (s stackSignature) payload() ([]byte, error) {
+	return json.Marshal(struct {
+		Version   string       `json:"version"`
+		Algorithm string       `json:"algorithm"`
+		Timestamp time.Time    `json:"timestamp"`
+		Files     []signedFile `json:"files"`
+	}{s.Version, s.Algorithm, s.Timestamp, s.Files})
+}
+
+func preRunStackFile(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("give a stack file with --yaml/-f")
+	}
+	return nil
+}
+
+func runStackKeygen(cmd *cobra.Command, args []string) error {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	keyFile, pubFile := stackKeygenOutput+".key", stackKeygenOutput+".pub"
+	for _, file := range []string{keyFile, pubFile} {
+		if _, err := os.Stat(file); err == nil {
+			return fmt.Errorf("file: %s already exists", file)
+		}
+	}
+
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote private key: %s\nWrote public key: %s\n", keyFile, pubFile)
+	return nil
+}
+
+func runStackSign(cmd *cobra.Command, args []string) error {
+	if len(stackKeyFile) == 0 {
+		return fmt.Errorf("give a private key with --key")
+	}
+
+	privateKey, err := readStackPrivateKey(stackKeyFile)
+	if err != nil {
+		return err
+	}
+
+	sigFile := stackSignaturePath(yamlFile, stackSignatureOverride)
+	sig, err := signStack(privateKey, sigFile, append([]string{yamlFile}, stackSignIncludes...))
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(sigFile, append(out, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Signed %d file(s), wrote: %s\n", len(sig.Files), sigFile)
+	return nil
+}
+
+func runStackVerify(cmd *cobra.Command, args []string) error {
+	if len(stackPublicKeyFile) == 0 {
+		return fmt.Errorf("give a public key with --public-key")
+	}
+
+	sig, err := verifyStackSignature(yamlFile, stackSignaturePath(yamlFile, stackSignatureOverride), stackPublicKeyFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Verified signature for %d file(s), signed at %s\n", len(sig.Files), sig.Timestamp.Local().Format(time.RFC3339))
+	return nil
+}
+
+func stackSignaturePath(stackFile, override string) string {
+	if len(override) > 0 {
+		return override
+	}
+	return stackFile + stackSignatureExt
+}
+
+// signStack creates a signature over the digests of files, recorded relative
+// to the folder of sigFile.
+func signStack(privateKey ed25519.PrivateKey, sigFile string, files []string) (*stackSignature, error) {
+	sig := &stackSignature{
+		Version:   stackSignatureVersion,
+		Algorithm: stackSignatureAlgorithm,
+		PublicKey: base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+	}
+
+	base := filepath.Dir(sigFile)
+	for _, file := range files {
+		digest, err := fileSHA256(file)
+		if err != nil {
+			return nil, err
+		}
+
+		rel, err := filepath.Rel(base, file)
+		if err != nil {
+			return nil, err
+		}
+
+		sig.Files = append(sig.Files, signedFile{Path: filepath.ToSlash(rel), SHA256: digest})
+	}
+
+	payload, err := sig.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	sig.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, payload))
+	return sig, nil
+}
+
+// verifyStackSignature checks that sigFile was made by the key in publicKeyFile,
+// that it covers stackFile, and that every signed file is unchanged.
+func verifyStackSignature(stackFile, sigFile, publicKeyFile string) (*stackSignature, error) {
+	if u, err := url.Parse(stackFile); err == nil && len(u.Scheme) > 0 {
+		return nil, fmt.Errorf("signatures can only be verified for a local stack file")
+	}
+
+	publicKey, err := readStackPublicKey(publicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(sigFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no signature found for %s, expected: %s", stackFile, sigFile)
+		}
+		return nil, err
+	}
+
+	var sig stackSignature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, fmt.Errorf("unable to parse signature %s: %w", sigFile, err)
+	}
+
+	if sig.Algorithm != stackSignatureAlgorithm {
+		return nil, fmt.Errorf("unsupported signature algorithm: %q", sig.Algorithm)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode signature in %s: %w", sigFile, err)
+	}
+
+	payload, err := sig.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return nil, fmt.Errorf("signature %s was not made by the key in %s", sigFile, publicKeyFile)
+	}
+
+	base := filepath.Dir(sigFile)
+	stackAbs, _ := filepath.Abs(stackFile)
+	coversStack := false
+
+	for _, file := range sig.Files {
+		path := filepath.Join(base, filepath.FromSlash(file.Path))
+
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return nil, fmt.Errorf("signed file %s: %w", file.Path, err)
+		}
+
+		if digest != file.SHA256 {
+			return nil, fmt.Errorf("%s has changed since it was signed", path)
+		}
+
+		if abs, _ := filepath.Abs(path); abs == stackAbs {
+			coversStack = true
+		}
+	}
+
+	if !coversStack {
+		return nil, fmt.Errorf("signature %s does not cover %s", sigFile, stackFile)
+	}
+
+	return &sig, nil
+}
+
+func fileSHA256(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func readStackPrivateKey(file string) (ed25519.PrivateKey, error) {
+	block, err := readPEMFile(file, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key %s: %w", file, err)
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an ed25519 key", file)
+	}
+	return privateKey, nil
+}
+
+func readStackPublicKey(file string) (ed25519.PublicKey, error) {
+	block, err := readPEMFile(file, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse public key %s: %w", file, err)
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an ed25519 key", file)
+	}
+	return publicKey, nil
+}
+
+func readPEMFile(file, blockType string) (*pem.Block, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not contain a PEM encoded %s", file, blockType)
+	}
+	return block, nil
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestPublicKey(t *testing.T, dir string, key ed25519.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "stack.pub")
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func signTestStack(t *testing.T, dir string, privateKey ed25519.PrivateKey, files ...string) string {
+	t.Helper()
+
+	sigFile := filepath.Join(dir, "stack.yml"+stackSignatureExt)
+	sig, err := signStack(privateKey, sigFile, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(sigFile, out, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return sigFile
+}
+
+func Test_verifyStackSignature(t *testing.T) {
+	dir := t.TempDir()
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	lockFile := filepath.Join(dir, "stack.lock")
+	ioutil.WriteFile(stackFile, []byte("version: 1.0\nfunctions: {}\n"), 0600)
+	ioutil.WriteFile(lockFile, []byte("lock\n"), 0600)
+
+	sigFile := signTestStack(t, dir, privateKey, stackFile, lockFile)
+	trusted := writeTestPublicKey(t, dir, publicKey)
+
+	sig, err := verifyStackSignature(stackFile, sigFile, trusted)
+	if err != nil {
+		t.Fatalf("want a valid signature, got: %s", err)
+	}
+
+	if len(sig.Files) != 2 || sig.Files[0].Path != "stack.yml" || sig.Files[1].Path != "stack.lock" {
+		t.Errorf("want relative paths for both files, got %v", sig.Files)
+	}
+
+	untrustedDir := t.TempDir()
+	untrusted := writeTestPublicKey(t, untrustedDir, otherKey)
+	if _, err := verifyStackSignature(stackFile, sigFile, untrusted); err == nil || !strings.Contains(err.Error(), "was not made by") {
+		t.Errorf("want an error for an untrusted key, got: %v", err)
+	}
+
+	ioutil.WriteFile(lockFile, []byte("changed\n"), 0600)
+	if _, err := verifyStackSignature(stackFile, sigFile, trusted); err == nil || !strings.Contains(err.Error(), "has changed") {
+		t.Errorf("want an error for a modified lockfile, got: %v", err)
+	}
+}
+
+func Test_verifyStackSignature_MustCoverStack(t *testing.T) {
+	dir := t.TempDir()
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	otherFile := filepath.Join(dir, "other.yml")
+	ioutil.WriteFile(stackFile, []byte("version: 1.0\n"), 0600)
+	ioutil.WriteFile(otherFile, []byte("version: 1.0\n"), 0600)
+
+	sigFile := signTestStack(t, dir, privateKey, otherFile)
+	trusted := writeTestPublicKey(t, dir, publicKey)
+
+	if _, err := verifyStackSignature(stackFile, sigFile, trusted); err == nil || !strings.Contains(err.Error(), "does not cover") {
+		t.Errorf("want an error when the stack file isn't signed, got: %v", err)
+	}
+}
+
+func Test_verifyStackSignature_Missing(t *testing.T) {
+	dir := t.TempDir()
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	trusted := writeTestPublicKey(t, dir, publicKey)
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	if _, err := verifyStackSignature(stackFile, stackFile+stackSignatureExt, trusted); err == nil || !strings.Contains(err.Error(), "no signature found") {
+		t.Errorf("want an error for a missing signature, got: %v", err)
+	}
+}
@@ -5,9 +5,8 @@ package commands
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"strings"
 	"time"
 
@@ -48,7 +47,10 @@ func init() {
 var storeCmd = &cobra.Command{
 	Use:   `store`,
 	Short: "OpenFaaS store commands",
-	Long:  "Allows browsing and deploying OpenFaaS functions from a store",
+	Long: `Allows browsing and deploying OpenFaaS functions from a store.
+
+The store's index is cached, and revalidated with its ETag on each use. When the
+store can't be reached, the cached copy is used instead.`,
 }
 
 func storeList(store string) ([]storeV2.StoreFunction, error) {
@@ -62,31 +64,18 @@ func storeList(store string) ([]storeV2.StoreFunction, error) {
 
 	client := proxy.MakeHTTPClient(&timeout, tlsInsecure)
 
-	res, err := client.Get(store)
+	index, err := proxy.FetchIndex(&client, store)
 	if err != nil {
+		var statusErr *proxy.IndexStatusError
+		if errors.As(err, &statusErr) {
+			return nil, statusErr
+		}
 		return nil, fmt.Errorf("cannot connect to OpenFaaS store at URL: %s", store)
 	}
 
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	switch res.StatusCode {
-	case http.StatusOK:
-		bytesOut, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return nil, fmt.Errorf("cannot read result from OpenFaaS store at URL: %s", store)
-		}
-
-		jsonErr := json.Unmarshal(bytesOut, &storeData)
-		if jsonErr != nil {
-			return nil, fmt.Errorf("cannot parse result from OpenFaaS store at URL: %s\n%s", store, jsonErr.Error())
-		}
-	default:
-		bytesOut, err := ioutil.ReadAll(res.Body)
-		if err == nil {
-			return nil, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
-		}
+	jsonErr := json.Unmarshal(index.Body, &storeData)
+	if jsonErr != nil {
+		return nil, fmt.Errorf("cannot parse result from OpenFaaS store at URL: %s\n%s", store, jsonErr.Error())
 	}
 
 	return storeData.Functions, nil
@@ -107,7 +96,7 @@ func filterStoreList(functions []storeV2.StoreFunction, platform string) []store
 	return filteredList
 }
 
-//getValueIgnoreCase get a key value from map by ignoring case for key
+// getValueIgnoreCase get a key value from map by ignoring case for key
 func getValueIgnoreCase(kv map[string]string, key string) (string, bool) {
 	for k, v := range kv {
 		if strings.EqualFold(k, key) {
@@ -35,6 +35,7 @@ func init() {
 	// Set bash-completion.
 	_ = storeDeployCmd.Flags().SetAnnotation("handler", cobra.BashCompSubdirsInDir, []string{})
 
+	markMutating(storeDeployCmd)
 	storeCmd.AddCommand(storeDeployCmd)
 }
 
@@ -7,8 +7,8 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
-	"text/tabwriter"
 
+	"github.com/openfaas/faas-cli/output"
 	storeV2 "github.com/openfaas/faas-cli/schema/store/v2"
 	"github.com/spf13/cobra"
 )
@@ -53,16 +53,14 @@ func runStoreList(cmd *cobra.Command, args []string) error {
 
 func storeRenderItems(items []storeV2.StoreFunction) string {
 	var b bytes.Buffer
-	w := tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
-	fmt.Fprintln(w)
-	fmt.Fprintln(w, "FUNCTION\tDESCRIPTION")
-
+	table := output.NewTable("FUNCTION", "DESCRIPTION")
 	for _, item := range items {
-		fmt.Fprintf(w, "%s\t%s\n", item.Title, storeRenderDescription(item.Description))
+		table.Row(item.Title, storeRenderDescription(item.Description))
 	}
 
-	fmt.Fprintln(w)
-	w.Flush()
+	fmt.Fprintln(&b)
+	table.Write(&b)
+	fmt.Fprintln(&b)
 	return b.String()
 }
 
@@ -0,0 +1,315 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// templateChangelog is written in the template's folder
+const templateChangelog = "CHANGELOG.md"
+
+var bumpDepsDryRun bool
+
+// bumpDepsNow dates the changelog entry, and is replaced by tests
+var bumpDepsNow = time.Now
+
+// fromInstruction matches the image of a FROM instruction, after any flags
+var fromInstruction = regexp.MustCompile(`(?i)^(\s*FROM\s+(?:--\S+\s+)*)(\S+)(.*)$`)
+
+// patchVersion matches tags such as 3.18.2, v0.9.11 or 1.21.3-alpine3.18
+var patchVersion = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)(.*)$`)
+
+func init() {
+	templateBumpDepsCmd.Flags().BoolVar(&bumpDepsDryRun, "dry-run", false, "Print the diff and changelog entry without changing the template")
+
+	templateCmd.AddCommand(templateBumpDepsCmd)
+}
+
+var templateBumpDepsCmd = &cobra.Command{
+	Use:   `bump-deps TEMPLATE_DIR [--dry-run]`,
+	Short: "Update the base images of a template to their latest patch releases",
+	Long: `Updates the tag of each image in the FROM instructions of a template's
+Dockerfiles, such as the watchdog and the language's base image, to the latest
+patch release with the same major and minor version, and the same suffix, e.g.
+alpine:3.18.2 to alpine:3.18.4, or golang:1.21.3-alpine to golang:1.21.5-alpine.
+
+The tags are listed from each image's registry with the credentials saved by
+"docker login". Tags without a patch version, those pinned by a digest, and
+those set by a build argument are left as they are.
+
+A diff of the changes is printed, and an entry listing them is added to the
+top of ` + templateChangelog + ` in the template's folder. With --dry-run, the
+template is not changed.`,
+	Example: `  faas-cli template bump-deps ./template/golang-http
+  faas-cli template bump-deps ./template/python3-http --dry-run`,
+	RunE: runTemplateBumpDeps,
+}
+
+// imageBump is a change to the tag of an image
+type imageBump struct {
+	Image string
+	From  string
+	To    string
+}
+
+// dockerfileEdit is a Dockerfile of the template with its updated lines
+type dockerfileEdit struct {
+	path    string
+	mode    os.FileMode
+	before  []string
+	after   []string
+	changed bool
+}
+
+func runTemplateBumpDeps(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the folder of the template, e.g. ./template/golang-http")
+	}
+	dir := args[0]
+
+	dockerfiles, err := templateDockerfiles(dir)
+	if err != nil {
+		return err
+	}
+	if len(dockerfiles) == 0 {
+		return fmt.Errorf("no Dockerfile found in: %s", dir)
+	}
+
+	tags := map[string][]string{}
+	var bumps []imageBump
+	var edits []*dockerfileEdit
+	for _, path := range dockerfiles {
+		edit, fileBumps, err := bumpDockerfile(path, tags)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, edit)
+		bumps = appendBumps(bumps, fileBumps)
+	}
+
+	out := cmd.OutOrStdout()
+	if len(bumps) == 0 {
+		fmt.Fprintln(out, "The template's images are on their latest patch releases")
+		return nil
+	}
+
+	for _, edit := range edits {
+		if edit.changed {
+			writeLineDiff(out, edit)
+		}
+	}
+
+	entry := changelogEntry(bumpDepsNow(), bumps)
+	fmt.Fprintf(out, "\n%s", entry)
+
+	if bumpDepsDryRun {
+		return nil
+	}
+
+	for _, edit := range edits {
+		if !edit.changed {
+			continue
+		}
+		if err := os.WriteFile(edit.path, []byte(strings.Join(edit.after, "\n")), edit.mode); err != nil {
+			return err
+		}
+	}
+
+	changelogPath := filepath.Join(dir, templateChangelog)
+	if err := prependChangelog(changelogPath, entry); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nUpdated %d image(s), and wrote the entry to: %s\n", len(bumps), changelogPath)
+	return nil
+}
+
+// templateDockerfiles finds the files named Dockerfile, or starting with it,
+// within the template's folder
+func templateDockerfiles(dir string) ([]string, error) {
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a folder", dir)
+	}
+
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasPrefix(info.Name(), "Dockerfile") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// bumpDockerfile updates the images of a Dockerfile, the tags are cached so
+// that each repository is only listed once
+func bumpDockerfile(path string, tags map[string][]string) (*dockerfileEdit, []imageBump, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	edit := &dockerfileEdit{path: path, mode: info.Mode().Perm(), before: lines, after: make([]string, len(lines))}
+	copy(edit.after, lines)
+
+	var bumps []imageBump
+	for i, line := range lines {
+		match := fromInstruction.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		repository, tag, ok := splitImageTag(match[2])
+		if !ok || !patchVersion.MatchString(tag) {
+			continue
+		}
+
+		if _, ok := tags[repository]; !ok {
+			list, err := listImageTags(repository)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to list the tags of %s: %w", repository, err)
+			}
+			tags[repository] = list
+		}
+
+		if next := latestPatchTag(tag, tags[repository]); next != tag {
+			edit.after[i] = match[1] + repository + ":" + next + match[3]
+			edit.changed = true
+			bumps = append(bumps, imageBump{Image: repository, From: tag, To: next})
+		}
+	}
+
+	return edit, bumps, nil
+}
+
+// splitImageTag splits an image into its repository and tag. Images which
+// are pinned by a digest or use a build argument are not bumped.
+func splitImageTag(image string) (string, string, bool) {
+	if strings.Contains(image, "@") || strings.Contains(image, "$") {
+		return "", "", false
+	}
+
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return "", "", false
+	}
+	return image[:i], image[i+1:], true
+}
+
+// latestPatchTag is the tag with the highest patch version of the same major
+// and minor version, prefix and suffix as current
+func latestPatchTag(current string, tags []string) string {
+	want := patchVersion.FindStringSubmatch(current)
+	if want == nil {
+		return current
+	}
+
+	best := current
+	bestPatch, _ := strconv.Atoi(want[4])
+	for _, tag := range tags {
+		got := patchVersion.FindStringSubmatch(tag)
+		if got == nil || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] || got[5] != want[5] {
+			continue
+		}
+
+		// A patch with a leading zero, e.g. 3.18.02, is not a release
+		if len(got[4]) > 1 && strings.HasPrefix(got[4], "0") {
+			continue
+		}
+
+		if patch, err := strconv.Atoi(got[4]); err == nil && patch > bestPatch {
+			best, bestPatch = tag, patch
+		}
+	}
+	return best
+}
+
+// appendBumps adds the bumps which are not already listed, as an image may
+// be used by more than one Dockerfile
+func appendBumps(bumps, more []imageBump) []imageBump {
+	for _, bump := range more {
+		found := false
+		for _, existing := range bumps {
+			if existing == bump {
+				found = true
+				break
+			}
+		}
+		if !found {
+			bumps = append(bumps, bump)
+		}
+	}
+	return bumps
+}
+
+// writeLineDiff prints the lines which changed, each image is on one line so
+// no context is needed to read it
+func writeLineDiff(w io.Writer, edit *dockerfileEdit) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", edit.path, edit.path)
+	for i := range edit.before {
+		if edit.before[i] == edit.after[i] {
+			continue
+		}
+		fmt.Fprintf(w, "@@ -%d +%d @@\n-%s\n+%s\n", i+1, i+1, edit.before[i], edit.after[i])
+	}
+}
+
+func changelogEntry(now time.Time, bumps []imageBump) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", now.Format("2006-01-02"))
+	for _, bump := range bumps {
+		fmt.Fprintf(&b, "- Bump %s from %s to %s\n", bump.Image, bump.From, bump.To)
+	}
+	return b.String()
+}
+
+// prependChangelog adds the entry above the previous entries, after the
+// changelog's title when it has one
+func prependChangelog(path, entry string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	existing := string(data)
+	if len(existing) == 0 {
+		return os.WriteFile(path, []byte("# Changelog\n\n"+entry), 0644)
+	}
+
+	title := ""
+	if strings.HasPrefix(existing, "# ") {
+		end := strings.Index(existing, "\n")
+		if end < 0 {
+			end = len(existing)
+		}
+		title = existing[:end] + "\n\n"
+		existing = strings.TrimLeft(existing[end:], "\n")
+	}
+
+	return os.WriteFile(path, []byte(title+entry+"\n"+existing), 0644)
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_latestPatchTag(t *testing.T) {
+	tags := []string{"3.18.2", "3.18.4", "3.18.10", "3.19.0", "3.18.11-rc1", "3.18", "latest", "3.18.012"}
+	if got := latestPatchTag("3.18.2", tags); got != "3.18.10" {
+		t.Errorf("want 3.18.10, got: %s", got)
+	}
+
+	tags = []string{"1.21.3-alpine", "1.21.5-alpine", "1.21.6", "1.21.7-bullseye"}
+	if got := latestPatchTag("1.21.3-alpine", tags); got != "1.21.5-alpine" {
+		t.Errorf("want the same suffix kept, got: %s", got)
+	}
+
+	if got := latestPatchTag("v0.9.11", []string{"0.9.13", "v0.9.12"}); got != "v0.9.12" {
+		t.Errorf("want the same prefix kept, got: %s", got)
+	}
+}
+
+func Test_templateBumpDeps(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "golang-http")
+	os.MkdirAll(dir, 0755)
+	dockerfile := `FROM --platform=${TARGETPLATFORM:-linux/amd64} ghcr.io/openfaas/of-watchdog:0.9.11 as watchdog
+FROM --platform=${BUILDPLATFORM:-linux/amd64} golang:1.21.3-alpine as build
+FROM golang:${GO_VERSION} as other
+FROM alpine:3.18.2@sha256:abc as pinned
+FROM --platform=${TARGETPLATFORM:-linux/amd64} alpine:3.18.2 as ship
+COPY --from=build /go/bin/handler .
+`
+	os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(dockerfile), 0644)
+	os.WriteFile(filepath.Join(dir, templateChangelog), []byte("# Changelog\n\n## 2023-01-01\n\n- First release\n"), 0644)
+
+	savedList, savedNow := listImageTags, bumpDepsNow
+	defer func() { listImageTags, bumpDepsNow, bumpDepsDryRun = savedList, savedNow, false }()
+
+	listed := map[string]int{}
+	listImageTags = func(repository string) ([]string, error) {
+		listed[repository]++
+		return map[string][]string{
+			"ghcr.io/openfaas/of-watchdog": {"0.9.11", "0.9.13", "0.10.1"},
+			"golang":                       {"1.21.3-alpine", "1.21.5-alpine", "1.21.5"},
+			"alpine":                       {"3.18.2", "3.18.4"},
+		}[repository], nil
+	}
+	bumpDepsNow = func() time.Time { return time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC) }
+
+	var out bytes.Buffer
+	templateBumpDepsCmd.SetOut(&out)
+	defer templateBumpDepsCmd.SetOut(nil)
+
+	if err := runTemplateBumpDeps(templateBumpDepsCmd, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, _ := os.ReadFile(filepath.Join(dir, "Dockerfile"))
+	for _, want := range []string{
+		"of-watchdog:0.9.13 as watchdog",
+		"golang:1.21.5-alpine as build",
+		"FROM golang:${GO_VERSION} as other",
+		"FROM alpine:3.18.2@sha256:abc as pinned",
+		"${TARGETPLATFORM:-linux/amd64} alpine:3.18.4 as ship",
+	} {
+		if !strings.Contains(string(updated), want) {
+			t.Errorf("want %q in the Dockerfile, got:\n%s", want, updated)
+		}
+	}
+	if listed["alpine"] != 1 {
+		t.Errorf("want each repository listed once, got: %v", listed)
+	}
+
+	if !strings.Contains(out.String(), "-FROM --platform=${TARGETPLATFORM:-linux/amd64} alpine:3.18.2 as ship\n+FROM --platform=${TARGETPLATFORM:-linux/amd64} alpine:3.18.4 as ship") {
+		t.Errorf("want a diff of the changes, got:\n%s", out.String())
+	}
+
+	changelog, _ := os.ReadFile(filepath.Join(dir, templateChangelog))
+	want := `# Changelog
+
+## 2023-12-01
+
+- Bump ghcr.io/openfaas/of-watchdog from 0.9.11 to 0.9.13
+- Bump golang from 1.21.3-alpine to 1.21.5-alpine
+- Bump alpine from 3.18.2 to 3.18.4
+
+## 2023-01-01
+`
+	if !strings.HasPrefix(string(changelog), want) {
+		t.Errorf("want the entry above the previous ones, got:\n%s", changelog)
+	}
+
+	// Nothing is left to bump
+	out.Reset()
+	bumpDepsDryRun = true
+	if err := runTemplateBumpDeps(templateBumpDepsCmd, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "latest patch releases") {
+		t.Errorf("want no changes, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/versioncontrol"
+)
+
+// templateCacheEnvironment overrides where fetched templates are cached, so
+// that CI systems can persist the folder between jobs
+const templateCacheEnvironment = "OPENFAAS_TEMPLATE_CACHE"
+
+var (
+	// templateCloneDepth is the history fetched by git for template repos
+	templateCloneDepth int
+	// templateArchive downloads a tarball for GitHub and GitLab repos instead of cloning them
+	templateArchive bool
+	// refreshTemplateCache ignores any cached copy of a pinned template repo
+	refreshTemplateCache bool
+)
+
+// commitRef is a full commit SHA, of SHA-1 or SHA-256, which can't be moved
+// like a branch or a tag
+var commitRef = regexp.MustCompile(`^([0-9a-fA-F]{40}|[0-9a-fA-F]{64})$`)
+
+// templateCacheRoot is the folder which holds a copy of the template folder of
+// each remote repo and ref that has been fetched
+func templateCacheRoot() (string, error) {
+	if dir := os.Getenv(templateCacheEnvironment); len(dir) > 0 {
+		return dir, nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "openfaas", "templates"), nil
+}
+
+// templateCacheKey is unique to a repo URL and ref
+func templateCacheKey(repoURL, refName string) string {
+	sum := sha256.Sum256([]byte(repoURL + "#" + refName))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// fetchTemplateSource returns a folder which contains the template folder of
+// the repo, and a func to clean it up afterwards. Remote repos pinned to a
+// commit are served from the cache when possible, as a branch or tag can be
+// moved, and the cache is used as a fallback for any remote repo which can't
+// be fetched.
+func fetchTemplateSource(repoURL, refName string) (string, func(), error) {
+	var cacheDir string
+	if versioncontrol.IsGitRemote(repoURL) {
+		if root, err := templateCacheRoot(); err == nil {
+			cacheDir = filepath.Join(root, templateCacheKey(repoURL, refName))
+		}
+	}
+
+	cached := len(cacheDir) > 0 && templateCacheExists(cacheDir)
+	if cached && commitRef.MatchString(refName) && !refreshTemplateCache {
+		log.Printf("Using cached templates for %s#%s\n", repoURL, refName)
+		return cacheDir, func() {}, nil
+	}
+
+	dir, err := ioutil.TempDir("", "openFaasTemplates")
+	if err != nil {
+		return "", nil, err
+	}
+	pullDebugPrint(fmt.Sprintf("Temp files in %s", dir))
+
+	cleanup := func() {
+		if !pullDebug {
+			os.RemoveAll(dir)
+		}
+	}
+
+	if err := downloadTemplates(repoURL, refName, dir); err != nil {
+		cleanup()
+		if cached {
+			log.Printf("Unable to fetch %s, using cached templates: %s\n", repoURL, err)
+			return cacheDir, func() {}, nil
+		}
+		return "", nil, err
+	}
+
+	if len(cacheDir) > 0 {
+		if err := storeTemplateCache(dir, cacheDir); err != nil {
+			pullDebugPrint(fmt.Sprintf("Unable to cache templates: %s", err))
+		}
+	}
+
+	return dir, cleanup, nil
+}
+
+func templateCacheExists(cacheDir string) bool {
+	info, err := os.Stat(filepath.Join(cacheDir, templateDirectory))
+	return err == nil && info.IsDir()
+}
+
+// storeTemplateCache replaces the cached copy with the template folder from
+// repoPath, the copy is renamed into place so a partial copy is never used
+func storeTemplateCache(repoPath, cacheDir string) error {
+	if err := os.MkdirAll(filepath.Dir(cacheDir), 0700); err != nil {
+		return err
+	}
+
+	staging, err := ioutil.TempDir(filepath.Dir(cacheDir), filepath.Base(cacheDir)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := builder.CopyFiles(filepath.Join(repoPath, templateDirectory), filepath.Join(staging, templateDirectory)); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return err
+	}
+	return os.Rename(staging, cacheDir)
+}
+
+// downloadTemplates fetches the repo into dir, with a tarball where the host
+// offers one, otherwise with a shallow, sparse clone of the template folder
+func downloadTemplates(repoURL, refName, dir string) error {
+	if templateArchive {
+		if archiveURL, ok := templateArchiveURL(repoURL, refName); ok {
+			err := downloadTemplateArchive(archiveURL, dir)
+			if err == nil {
+				return nil
+			}
+
+			pullDebugPrint(fmt.Sprintf("Unable to download %s, falling back to git: %s", archiveURL, err))
+			if err := resetDir(dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	return cloneTemplates(repoURL, refName, dir)
+}
+
+func cloneTemplates(repoURL, refName, dir string) error {
+	args := map[string]string{
+		"dir":   dir,
+		"repo":  repoURL,
+		"depth": strconv.Itoa(templateCloneDepth),
+		"path":  strings.Trim(templateDirectory, "./"),
+	}
+
+	sparse, full := versioncontrol.GitCloneSparseDefault, versioncontrol.GitCloneDefault
+	if refName != "" {
+		args["refname"] = refName
+		sparse, full = versioncontrol.GitCloneSparse, versioncontrol.GitClone
+	}
+
+	err := sparse.Invoke(".", args)
+	if err == nil {
+		err = versioncontrol.GitSparseCheckout.Invoke(".", args)
+	}
+	if err == nil {
+		return nil
+	}
+
+	// Versions of git before 2.25 don't support sparse checkouts
+	pullDebugPrint(fmt.Sprintf("Sparse clone failed, cloning the whole repo: %s", err))
+	if err := resetDir(dir); err != nil {
+		return err
+	}
+	return full.Invoke(".", args)
+}
+
+func resetDir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, 0700)
+}
+
+// templateArchiveURL returns the tarball URL for a public GitHub or GitLab repo
+func templateArchiveURL(repoURL, refName string) (string, bool) {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return "", false
+	}
+
+	repoPath := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	parts := strings.Split(repoPath, "/")
+	if len(parts) < 2 {
+		return "", false
+	}
+
+	switch u.Host {
+	case "github.com":
+		if len(parts) != 2 {
+			return "", false
+		}
+		ref := refName
+		if len(ref) == 0 {
+			ref = "HEAD"
+		}
+		return fmt.Sprintf("https://codeload.github.com/%s/tar.gz/%s", repoPath, ref), true
+	case "gitlab.com":
+		// GitLab needs a ref to name the archive
+		if len(refName) == 0 {
+			return "", false
+		}
+		name := parts[len(parts)-1] + "-" + strings.ReplaceAll(refName, "/", "-")
+		return fmt.Sprintf("https://gitlab.com/%s/-/archive/%s/%s.tar.gz", repoPath, refName, name), true
+	}
+
+	return "", false
+}
+
+func downloadTemplateArchive(archiveURL, dir string) error {
+	client := &http.Client{Timeout: 2 * time.Minute}
+
+	res, err := client.Get(archiveURL)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	return extractTemplateArchive(res.Body, dir)
+}
+
+// extractTemplateArchive writes the template folder of a repo tarball into
+// dir. The tarball has a single top-level folder named after the repo and
+// ref, which is removed.
+func extractTemplateArchive(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	templatePrefix := strings.Trim(templateDirectory, "./") + "/"
+	found := false
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		i := strings.Index(name, "/")
+		if i < 0 {
+			continue
+		}
+		name = name[i+1:]
+		if !strings.HasPrefix(name+"/", templatePrefix) || strings.Contains(name, "..") {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			found = true
+			if err := writeArchiveFile(target, tr, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("no %s folder in the archive", templatePrefix)
+	}
+	return nil
+}
+
+func writeArchiveFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_templateArchiveURL(t *testing.T) {
+	cases := []struct {
+		repo    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{repo: "https://github.com/openfaas/templates", want: "https://codeload.github.com/openfaas/templates/tar.gz/HEAD"},
+		{repo: "https://github.com/openfaas/templates.git", ref: "1.0", want: "https://codeload.github.com/openfaas/templates/tar.gz/1.0"},
+		{repo: "https://gitlab.com/group/sub/templates.git", ref: "v2", want: "https://gitlab.com/group/sub/templates/-/archive/v2/templates-v2.tar.gz"},
+		{repo: "https://gitlab.com/group/templates", wantErr: true},
+		{repo: "git@github.com:openfaas/templates.git", wantErr: true},
+		{repo: "https://git.example.com/team/templates.git", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.repo+"#"+tc.ref, func(t *testing.T) {
+			got, ok := templateArchiveURL(tc.repo, tc.ref)
+			if ok == tc.wantErr {
+				t.Fatalf("want ok: %v, got: %v", !tc.wantErr, ok)
+			}
+			if got != tc.want {
+				t.Fatalf("want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_templateCacheKey(t *testing.T) {
+	a := templateCacheKey("https://github.com/openfaas/templates", "1.0")
+	b := templateCacheKey("https://github.com/openfaas/templates", "1.1")
+
+	if a == b {
+		t.Fatalf("want a different key for each ref")
+	}
+	if a != templateCacheKey("https://github.com/openfaas/templates", "1.0") {
+		t.Fatalf("want the same key for the same URL and ref")
+	}
+}
+
+func Test_extractTemplateArchive(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	files := map[string]string{
+		"templates-1.0/README.md":                 "readme",
+		"templates-1.0/template/go/template.yml":  "language: go",
+		"templates-1.0/template/go/function/h.go": "package function",
+		"templates-1.0/template/../../escape":     "nope",
+	}
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	gz.Close()
+
+	dir := t.TempDir()
+	if err := extractTemplateArchive(&buf, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "template", "go", "template.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "language: go" {
+		t.Fatalf("unexpected contents: %q", string(data))
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "README.md")); err == nil {
+		t.Fatalf("only the template folder should be extracted")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape")); err == nil {
+		t.Fatalf("a path outside of the folder was written")
+	}
+}
+
+func Test_storeTemplateCache(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, "template", "go"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "template", "go", "template.yml"), []byte("language: go"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cacheDir := filepath.Join(t.TempDir(), templateCacheKey("https://github.com/openfaas/templates", "1.0"))
+	if templateCacheExists(cacheDir) {
+		t.Fatalf("cache should start empty")
+	}
+
+	if err := storeTemplateCache(repo, cacheDir); err != nil {
+		t.Fatal(err)
+	}
+	if !templateCacheExists(cacheDir) {
+		t.Fatalf("want the template folder to be cached")
+	}
+
+	// Storing again replaces the cached copy
+	if err := storeTemplateCache(repo, cacheDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "template", "go", "template.yml")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_fetchTemplateSource_OnlyCommitsServedFromCache(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(templateCacheEnvironment, root)
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	// Nothing listens on the port, so a fetch fails and falls back to the cache
+	repoURL := "https://127.0.0.1:1/openfaas/templates.git"
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	for _, ref := range []string{"main", commit} {
+		if err := os.MkdirAll(filepath.Join(root, templateCacheKey(repoURL, ref), "template", "go"), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := fetchTemplateSource(repoURL, commit); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logged.String(), "Using cached templates") {
+		t.Errorf("want the commit served from the cache, got: %s", logged.String())
+	}
+
+	logged.Reset()
+	if _, _, err := fetchTemplateSource(repoURL, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logged.String(), "Unable to fetch") {
+		t.Errorf("want the branch fetched before the cache is used, got: %s", logged.String())
+	}
+}
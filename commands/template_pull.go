@@ -18,6 +18,9 @@ var (
 func init() {
 	templatePullCmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing templates?")
 	templatePullCmd.Flags().BoolVar(&pullDebug, "debug", false, "Enable debug output")
+	templatePullCmd.Flags().IntVar(&templateCloneDepth, "depth", 1, "Number of commits to fetch when cloning with git")
+	templatePullCmd.Flags().BoolVar(&templateArchive, "archive", true, "Download a tarball for GitHub and GitLab repositories instead of cloning them")
+	templatePullCmd.Flags().BoolVar(&refreshTemplateCache, "refresh", false, "Fetch the repository even if a cached copy of the commit exists")
 
 	templateCmd.AddCommand(templatePullCmd)
 }
@@ -30,10 +33,19 @@ var templatePullCmd = &cobra.Command{
 directory from the root of the repo, if it exists.
 
 [REPOSITORY_URL] may specify a specific branch or tag to copy by adding a URL fragment with the branch or tag name.
+
+Only the 'template' directory is fetched, with a tarball for public GitHub and GitLab
+repositories, or a shallow, sparse git clone otherwise. Remote repositories are cached
+by URL and ref in the user's cache directory, or in $OPENFAAS_TEMPLATE_CACHE. A ref
+which is a full commit SHA is then read from the cache until --refresh is given, as a
+branch or tag can be moved, and a cached copy is used for any ref when the repository
+can't be reached.
 	`,
 	Example: `
   faas-cli template pull https://github.com/openfaas/templates
   faas-cli template pull https://github.com/openfaas/templates#1.0
+  faas-cli template pull https://github.com/openfaas/templates#1.0 --refresh
+  faas-cli template pull https://git.example.com/team/templates.git --depth 1
 `,
 	RunE: runTemplatePull,
 }
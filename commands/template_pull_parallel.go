@@ -0,0 +1,191 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/versioncontrol"
+)
+
+// templatePullParallel is how many template repositories are fetched at once
+const templatePullParallel = 4
+
+// templateRepo is a repository and ref, with the templates of the stack file
+// which are pulled from it
+type templateRepo struct {
+	repository string
+	refName    string
+	names      []string
+}
+
+// templatePullResult is what was fetched from a repository
+type templatePullResult struct {
+	repo     templateRepo
+	commit   string
+	fetched  []string
+	existing []string
+	duration time.Duration
+	err      error
+}
+
+// fetchTemplateRepo is replaced by tests, it fetches the repository and moves
+// its templates into ./template, returning the commit which was fetched
+var fetchTemplateRepo = func(repo templateRepo, overwrite bool) (string, []string, []string, error) {
+	dir, cleanup, err := fetchTemplateSource(repo.repository, repo.refName)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	defer cleanup()
+
+	commit := ""
+	if out, err := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").Output(); err == nil {
+		commit = strings.TrimSpace(string(out))
+	}
+
+	// Templates of the same name from two repositories would race
+	templateMoveMu.Lock()
+	defer templateMoveMu.Unlock()
+
+	existing, fetched, err := moveTemplates(dir, overwrite)
+	return commit, fetched, existing, err
+}
+
+var templateMoveMu sync.Mutex
+
+// lookupStoreTemplates is replaced by tests
+var lookupStoreTemplates = func() ([]TemplateInfo, error) {
+	storeURL := getTemplateStoreURL(templateStoreURL, os.Getenv(templateStoreURLEnvironment), DefaultTemplatesStore)
+	return getTemplateInfo(storeURL)
+}
+
+// resolveTemplateRepos finds the repository of each template, from its source
+// or the template store, so that each repository and ref is fetched once
+func resolveTemplateRepos(templateInfo []stack.TemplateSource) ([]templateRepo, error) {
+	var storeTemplates []TemplateInfo
+	byKey := map[string]*templateRepo{}
+	var keys []string
+
+	for _, info := range templateInfo {
+		source := info.Source
+		if len(source) == 0 {
+			if storeTemplates == nil {
+				var err error
+				if storeTemplates, err = lookupStoreTemplates(); err != nil {
+					return nil, fmt.Errorf("error while fetching templates from store: %s", err)
+				}
+			}
+
+			for _, storeTemplate := range storeTemplates {
+				sourceName := fmt.Sprintf("%s/%s", storeTemplate.Source, storeTemplate.TemplateName)
+				if info.Name == storeTemplate.TemplateName || info.Name == sourceName {
+					source = storeTemplate.Repository
+					break
+				}
+			}
+			if len(source) == 0 {
+				return nil, fmt.Errorf("template with name: `%s` does not exist in the repo", info.Name)
+			}
+		}
+
+		if _, err := os.Stat(source); err != nil {
+			if !versioncontrol.IsGitRemote(source) && !versioncontrol.IsPinnedGitRemote(source) {
+				return nil, fmt.Errorf("the source of template %s must be a valid git repo uri, got: %s", info.Name, source)
+			}
+		}
+
+		repository, refName := versioncontrol.ParsePinnedRemote(source)
+		if refName != "" {
+			if err := versioncontrol.GitCheckRefName.Invoke("", map[string]string{"refname": refName}); err != nil {
+				return nil, fmt.Errorf("invalid tag or branch name `%s` for template %s, see: https://git-scm.com/docs/git-check-ref-format", refName, info.Name)
+			}
+		}
+
+		key := repository + "#" + refName
+		repo, ok := byKey[key]
+		if !ok {
+			repo = &templateRepo{repository: repository, refName: refName}
+			byKey[key] = repo
+			keys = append(keys, key)
+		}
+		repo.names = append(repo.names, info.Name)
+	}
+
+	repos := make([]templateRepo, 0, len(keys))
+	for _, key := range keys {
+		repos = append(repos, *byKey[key])
+	}
+	return repos, nil
+}
+
+// pullTemplateRepos fetches the repositories concurrently, every repository
+// is tried so that all which failed are reported together
+func pullTemplateRepos(repos []templateRepo, overwrite bool) []templatePullResult {
+	results := make([]templatePullResult, len(repos))
+	work := make(chan int)
+
+	var wg sync.WaitGroup
+	workers := templatePullParallel
+	if len(repos) < workers {
+		workers = len(repos)
+	}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for index := range work {
+				started := time.Now()
+				result := templatePullResult{repo: repos[index]}
+				result.commit, result.fetched, result.existing, result.err = fetchTemplateRepo(repos[index], overwrite)
+				result.duration = time.Since(started)
+				results[index] = result
+			}
+		}()
+	}
+
+	for i := range repos {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return results
+}
+
+func printTemplatePullSummary(w io.Writer, results []templatePullResult) {
+	table := output.NewTable("REPOSITORY", "REF", "COMMIT", "TEMPLATES", "TIME")
+	for _, result := range results {
+		templates := "-"
+		if result.err != nil {
+			templates = output.Failure("failed")
+		} else if len(result.fetched) > 0 {
+			fetched := append([]string{}, result.fetched...)
+			sort.Strings(fetched)
+			templates = strings.Join(fetched, ", ")
+		}
+
+		ref := result.repo.refName
+		if ref == "" {
+			ref = "default branch"
+		}
+		table.Row(result.repo.repository, ref, valueOrDash(result.commit), templates, output.Duration(result.duration))
+	}
+	table.Write(w)
+
+	for _, result := range results {
+		if len(result.existing) > 0 {
+			fmt.Fprintf(w, "Kept %d existing template(s) from %s, use --overwrite to replace them: %s\n",
+				len(result.existing), result.repo.repository, strings.Join(result.existing, ", "))
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_resolveTemplateRepos_Deduplicates(t *testing.T) {
+	lookups := 0
+	original := lookupStoreTemplates
+	lookupStoreTemplates = func() ([]TemplateInfo, error) {
+		lookups++
+		return []TemplateInfo{
+			{TemplateName: "golang-middleware", Source: "openfaas", Repository: "https://github.com/openfaas/golang-http-template"},
+			{TemplateName: "python3-http", Source: "openfaas", Repository: "https://github.com/openfaas/python-flask-template"},
+		}, nil
+	}
+	defer func() { lookupStoreTemplates = original }()
+
+	repos, err := resolveTemplateRepos([]stack.TemplateSource{
+		{Name: "golang-middleware"},
+		{Name: "golang-http", Source: "https://github.com/openfaas/golang-http-template"},
+		{Name: "openfaas/python3-http"},
+		{Name: "node20", Source: "https://github.com/openfaas/templates#1.2.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lookups != 1 {
+		t.Errorf("want the store listed once, got: %d", lookups)
+	}
+	if len(repos) != 3 {
+		t.Fatalf("want 3 repositories, got: %+v", repos)
+	}
+	if strings.Join(repos[0].names, ",") != "golang-middleware,golang-http" {
+		t.Errorf("want both golang templates from one repository, got: %v", repos[0].names)
+	}
+	if repos[2].repository != "https://github.com/openfaas/templates" || repos[2].refName != "1.2.0" {
+		t.Errorf("want the pinned ref parsed, got: %+v", repos[2])
+	}
+
+	if _, err := resolveTemplateRepos([]stack.TemplateSource{{Name: "missing"}}); err == nil {
+		t.Errorf("want an error for a template which is not in the store")
+	}
+	if _, err := resolveTemplateRepos([]stack.TemplateSource{{Name: "bad", Source: "invalidURL"}}); err == nil {
+		t.Errorf("want an error for an invalid source")
+	}
+}
+
+func Test_pullTemplateRepos_Concurrent(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+
+	original := fetchTemplateRepo
+	fetchTemplateRepo = func(repo templateRepo, overwrite bool) (string, []string, []string, error) {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if strings.HasSuffix(repo.repository, "broken") {
+			return "", nil, nil, fmt.Errorf("not found")
+		}
+		return "abc1234", repo.names, nil, nil
+	}
+	defer func() { fetchTemplateRepo = original }()
+
+	repos := []templateRepo{
+		{repository: "https://github.com/acme/one", names: []string{"one"}},
+		{repository: "https://github.com/acme/two", refName: "v2", names: []string{"two"}},
+		{repository: "https://github.com/acme/broken", names: []string{"three"}},
+	}
+	results := pullTemplateRepos(repos, false)
+
+	if peak < 2 {
+		t.Errorf("want the repositories fetched concurrently, got at most %d at once", peak)
+	}
+	if results[1].commit != "abc1234" || results[2].err == nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	var out bytes.Buffer
+	printTemplatePullSummary(&out, results)
+	for _, want := range []string{"default branch", "v2", "abc1234", "failed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in:\n%s", want, out.String())
+		}
+	}
+}
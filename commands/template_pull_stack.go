@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 
@@ -26,8 +27,11 @@ func init() {
 var templatePullStackCmd = &cobra.Command{
 	Use:   `stack`,
 	Short: `Downloads templates specified in the function definition yaml file`,
-	Long: `Downloads templates specified in the function yaml file, in the current directory
-	`,
+	Long: `Downloads templates specified in the function yaml file, in the current directory.
+
+The repositories of the templates are fetched concurrently, and a repository
+which provides several of the templates is only fetched once. The ref and
+commit fetched from each repository are printed once they have all finished.`,
 	Example: `
   faas-cli template pull stack
   faas-cli template pull stack -f myfunction.yml
@@ -69,21 +73,36 @@ func readStackConfig() (stack.Configuration, error) {
 	return configField, nil
 }
 
+// pullStackTemplates fetches the repositories of the templates concurrently,
+// each repository and ref is fetched once however many of its templates are
+// listed, then prints the commit which was fetched from each
 func pullStackTemplates(templateInfo []stack.TemplateSource, cmd *cobra.Command) error {
-	for _, val := range templateInfo {
-		fmt.Printf("Pulling template: %s from configuration file: %s\n", val.Name, yamlFile)
-		if len(val.Source) == 0 {
-			pullErr := runTemplateStorePull(cmd, []string{val.Name})
-			if pullErr != nil {
-				return pullErr
-			}
-		} else {
-			pullErr := pullTemplate(val.Source)
-			if pullErr != nil {
-				return pullErr
-			}
+	repos, err := resolveTemplateRepos(templateInfo)
+	if err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		return nil
+	}
+
+	out := cmd.OutOrStdout()
+	for _, repo := range repos {
+		fmt.Fprintf(out, "Pulling template(s): %s from configuration file: %s\n", strings.Join(repo.names, ", "), yamlFile)
+	}
+
+	results := pullTemplateRepos(repos, overwrite)
+	fmt.Fprintln(out)
+	printTemplatePullSummary(out, results)
+
+	var failed []string
+	for _, result := range results {
+		if result.err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", result.repo.repository, result.err))
 		}
 	}
+	if len(failed) > 0 {
+		return fmt.Errorf("error while fetching templates:\n%s", strings.Join(failed, "\n"))
+	}
 	return nil
 }
 
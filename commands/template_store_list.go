@@ -5,16 +5,16 @@ package commands
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
 	"text/tabwriter"
 	"time"
 
+	"github.com/openfaas/faas-cli/proxy"
 	"github.com/spf13/cobra"
 )
 
@@ -42,7 +42,8 @@ var templateStoreListCmd = &cobra.Command{
 	Use:     `list`,
 	Short:   `List templates from OpenFaaS organizations`,
 	Aliases: []string{"ls"},
-	Long:    `List templates from official store or from custom URL or set the environmental variable OPENFAAS_TEMPLATE_STORE_URL to be the default store location`,
+	Long: `List templates from official store or from custom URL or set the environmental variable OPENFAAS_TEMPLATE_STORE_URL to be the default store location.
+The store's index is cached, and the cached copy is used when the store can't be reached.`,
 	Example: `  faas-cli template store list
   faas-cli template store ls
   faas-cli template store ls --url=https://raw.githubusercontent.com/openfaas/store/master/templates.json
@@ -68,37 +69,19 @@ func runTemplateStoreList(cmd *cobra.Command, args []string) error {
 }
 
 func getTemplateInfo(repository string) ([]TemplateInfo, error) {
-	req, reqErr := http.NewRequest(http.MethodGet, repository, nil)
-	if reqErr != nil {
-		return nil, fmt.Errorf("error while trying to create request to take template info: %s", reqErr.Error())
-	}
-
-	reqContext, cancel := context.WithTimeout(req.Context(), 5*time.Second)
-	defer cancel()
-	req = req.WithContext(reqContext)
+	client := &http.Client{Timeout: 5 * time.Second}
 
-	client := http.DefaultClient
-	res, clientErr := client.Do(req)
+	index, clientErr := proxy.FetchIndex(client, repository)
 	if clientErr != nil {
+		var statusErr *proxy.IndexStatusError
+		if errors.As(clientErr, &statusErr) {
+			return nil, fmt.Errorf("unexpected status code wanted: %d got: %d", http.StatusOK, statusErr.StatusCode)
+		}
 		return nil, fmt.Errorf("error while requesting template list: %s", clientErr.Error())
 	}
 
-	if res.Body == nil {
-		return nil, fmt.Errorf("error empty response body from: %s", templateStoreURL)
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code wanted: %d got: %d", http.StatusOK, res.StatusCode)
-	}
-
-	body, bodyErr := ioutil.ReadAll(res.Body)
-	if bodyErr != nil {
-		return nil, fmt.Errorf("error while reading data from templates body: %s", bodyErr.Error())
-	}
-
 	templatesInfo := []TemplateInfo{}
-	unmarshallErr := json.Unmarshal(body, &templatesInfo)
+	unmarshallErr := json.Unmarshal(index.Body, &templatesInfo)
 	if unmarshallErr != nil {
 		return nil, fmt.Errorf("error while unmarshalling into templates struct: %s", unmarshallErr.Error())
 	}
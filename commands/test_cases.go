@@ -0,0 +1,655 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/util"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	defaultTestCasesDir = "tests/cases"
+	testCaseExtension   = ".yml"
+	// ignoredValue replaces the items of a list matched by an ignore path,
+	// so that the items after it are still compared at their index
+	ignoredValue = "<ignored>"
+)
+
+var (
+	testCasesDir    string
+	testCaseName    string
+	testPayload     string
+	testMethod      string
+	testContentType string
+	testHeaders     []string
+	testQuery       []string
+	testIgnore      []string
+	testGolden      bool
+	testRunFunction string
+)
+
+func init() {
+	testRecordCmd.Flags().StringVar(&testPayload, "payload", "", "File to send as the body of the request, or - for STDIN, no body is sent by default")
+	testRecordCmd.Flags().StringVar(&testCasesDir, "save", defaultTestCasesDir, "Folder to save the case and its golden file in")
+	testRecordCmd.Flags().StringVar(&testCaseName, "case", "", "Name of the case, by default the function's name and that of the payload file")
+	testRecordCmd.Flags().StringVarP(&testMethod, "method", "m", http.MethodPost, "HTTP method of the request")
+	testRecordCmd.Flags().StringVar(&testContentType, "content-type", "", "Content-Type of the payload, by default application/json for a JSON payload, otherwise text/plain")
+	testRecordCmd.Flags().StringArrayVarP(&testHeaders, "header", "H", []string{}, "HTTP header of the request as KEY=VALUE, can be given more than once")
+	testRecordCmd.Flags().StringArrayVar(&testQuery, "query", []string{}, "Query-string option of the request as KEY=VALUE, can be given more than once")
+	testRecordCmd.Flags().StringArrayVar(&testIgnore, "ignore", []string{}, "Path of a JSON field which changes between responses, such as $.id or items[*].created, which is not compared")
+
+	testRunCmd.Flags().StringVar(&testCasesDir, "cases", defaultTestCasesDir, "Folder of the cases to run")
+	testRunCmd.Flags().BoolVar(&testGolden, "golden", false, "Compare the body of each response to the case's golden file, as well as its status")
+	testRunCmd.Flags().StringArrayVar(&testIgnore, "ignore", []string{}, "Path of a JSON field to leave out of the comparison of every case, as well as those saved with each case")
+	testRunCmd.Flags().StringVar(&testRunFunction, "function", "", "Only run the cases of this function")
+
+	for _, cmd := range []*cobra.Command{testRecordCmd, testRunCmd} {
+		cmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+		cmd.Flags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the function")
+		cmd.Flags().BoolVar(&tlsInsecure, "tls-no-verify", false, "Disable TLS validation")
+		cmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+	}
+
+	testCmd.AddCommand(testRecordCmd)
+	testCmd.AddCommand(testRunCmd)
+	faasCmd.AddCommand(testCmd)
+}
+
+var testCmd = &cobra.Command{
+	Use:   `test [record|run]`,
+	Short: "Record invocations of functions and replay them as tests",
+	Long: `Record an invocation of a deployed function as a case, with its response as
+a golden file, then replay the cases to check the functions still respond the
+same way.
+
+Each case is saved in --save as NAME.yml, which has the request, the status of
+the response and the paths to ignore, next to its payload and golden file. The
+files are meant to be committed, and edited when a response is meant to
+change, or recorded again to replace them.
+
+With --golden, a JSON body is compared to its golden file as data, so that the
+order of the keys and the whitespace don't matter. Fields which change on each
+call, such as IDs and times, are left out with --ignore as dotted paths, where
+* matches every key or item:
+
+  $.id
+  items[*].created_at
+  meta.*.etag
+
+Other bodies are compared byte for byte, apart from a trailing newline.`,
+}
+
+var testRecordCmd = &cobra.Command{
+	Use:   `record NAME [--payload FILE] [--save FOLDER] [--case NAME]`,
+	Short: "Invoke a function and save the request and response as a case",
+	Example: `  faas-cli test record stronghash --payload p.json --save tests/cases/
+  faas-cli test record orders --payload order.json --case orders-create --ignore $.id
+  faas-cli test record env --method GET --query verbose=1 --case env-get`,
+	PreRunE: preRunTestRecord,
+	RunE:    runTestRecord,
+}
+
+var testRunCmd = &cobra.Command{
+	Use:   `run [--cases FOLDER] [--golden]`,
+	Short: "Replay the recorded cases against the deployed functions",
+	Example: `  faas-cli test run
+  faas-cli test run --golden
+  faas-cli test run --golden --cases tests/cases/ --ignore $.request_id
+  faas-cli test run --golden --function stronghash --gateway https://staging.example.com`,
+	RunE: runTestRun,
+}
+
+// testCase is a recorded request, and the response it is expected to have.
+// The payload and golden files are relative to the case.
+type testCase struct {
+	Function    string            `yaml:"function"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Method      string            `yaml:"method"`
+	Query       []string          `yaml:"query,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	ContentType string            `yaml:"content_type,omitempty"`
+	Payload     string            `yaml:"payload,omitempty"`
+	Status      int               `yaml:"status"`
+	Golden      string            `yaml:"golden"`
+	Ignore      []string          `yaml:"ignore,omitempty"`
+}
+
+// testResponse is the part of a response which is saved and compared
+type testResponse struct {
+	Status  int
+	Body    []byte
+	Elapsed time.Duration
+}
+
+func preRunTestRecord(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("give the name of the function to record")
+	}
+	if len(testCaseName) > 0 && strings.ContainsAny(testCaseName, `/\`) {
+		return fmt.Errorf("--case must be a name rather than a path, got: %q", testCaseName)
+	}
+	for _, path := range testIgnore {
+		if _, err := parseIgnorePath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runTestRecord(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	var payload []byte
+	if len(testPayload) > 0 {
+		var err error
+		if testPayload == "-" {
+			payload, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			payload, err = ioutil.ReadFile(testPayload)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read the payload: %s", err.Error())
+		}
+	}
+
+	headerMap, err := util.ParseMap(testHeaders, "header")
+	if err != nil {
+		return err
+	}
+
+	gatewayAddress, namespace := testGateway(cmd, name)
+
+	c := testCase{
+		Function:    name,
+		Namespace:   namespace,
+		Method:      strings.ToUpper(testMethod),
+		Query:       testQuery,
+		Headers:     headerMap,
+		ContentType: testContentType,
+		Ignore:      testIgnore,
+	}
+	if len(c.ContentType) == 0 && len(payload) > 0 {
+		c.ContentType = "text/plain"
+		if json.Valid(payload) {
+			c.ContentType = "application/json"
+		}
+	}
+	if len(c.Headers) == 0 {
+		c.Headers = nil
+	}
+
+	caseName := testCaseName
+	if len(caseName) == 0 {
+		caseName = defaultTestCaseName(name, testPayload)
+	}
+
+	res, err := invokeTestCase(cmd.Context(), testHTTPClient(), gatewayAddress, c, payload)
+	if err != nil {
+		return err
+	}
+
+	path, err := saveTestCase(testCasesDir, caseName, c, payload, res)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Recorded %s: %d, %d bytes in %s, saved to: %s\n",
+		caseName, res.Status, len(res.Body), output.Duration(res.Elapsed), path)
+	return nil
+}
+
+func runTestRun(cmd *cobra.Command, args []string) error {
+	for _, path := range testIgnore {
+		if _, err := parseIgnorePath(path); err != nil {
+			return err
+		}
+	}
+
+	cases, err := loadTestCases(testCasesDir)
+	if err != nil {
+		return err
+	}
+	if len(testRunFunction) > 0 {
+		var matched []string
+		for _, path := range cases {
+			if c, err := readTestCase(path); err == nil && c.Function == testRunFunction {
+				matched = append(matched, path)
+			}
+		}
+		cases = matched
+	}
+	if len(cases) == 0 {
+		return fmt.Errorf("no cases found in %s, record one with: faas-cli test record NAME", testCasesDir)
+	}
+
+	client := testHTTPClient()
+	out := cmd.OutOrStdout()
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	failed := 0
+	for _, path := range cases {
+		name := strings.TrimSuffix(filepath.Base(path), testCaseExtension)
+
+		problem, res := runTestCase(cmd, client, path)
+		if len(problem) > 0 {
+			failed++
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", output.Failure("FAIL"), name, problem)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d in %s\n", output.Success("PASS"), name, res.Status, output.Duration(res.Elapsed))
+	}
+	tw.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", failed, len(cases))
+	}
+	fmt.Fprintf(out, "\n%d case(s) passed\n", len(cases))
+	return nil
+}
+
+// runTestCase replays a case, and describes how its response differs from
+// the one which was recorded, or is empty when it passes
+func runTestCase(cmd *cobra.Command, client *http.Client, path string) (string, *testResponse) {
+	c, err := readTestCase(path)
+	if err != nil {
+		return err.Error(), nil
+	}
+
+	var payload []byte
+	if len(c.Payload) > 0 {
+		if payload, err = ioutil.ReadFile(filepath.Join(filepath.Dir(path), c.Payload)); err != nil {
+			return fmt.Sprintf("unable to read the payload: %s", err.Error()), nil
+		}
+	}
+
+	gatewayAddress, namespace := testGateway(cmd, c.Function)
+	if cmd.Flags().Changed("namespace") || len(c.Namespace) == 0 {
+		c.Namespace = namespace
+	}
+
+	res, err := invokeTestCase(cmd.Context(), client, gatewayAddress, c, payload)
+	if err != nil {
+		return err.Error(), nil
+	}
+
+	if res.Status != c.Status {
+		return fmt.Sprintf("status: want %d, got %d", c.Status, res.Status), res
+	}
+	if !testGolden {
+		return "", res
+	}
+
+	golden, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), c.Golden))
+	if err != nil {
+		return fmt.Sprintf("unable to read the golden file: %s", err.Error()), res
+	}
+	return compareGolden(golden, res.Body, append(append([]string{}, c.Ignore...), testIgnore...)), res
+}
+
+// testGateway resolves the gateway as the other commands do, and the
+// namespace of the function from --namespace or the stack file
+func testGateway(cmd *cobra.Command, name string) (string, string) {
+	var yamlGateway string
+	namespace := functionNamespace
+	if len(yamlFile) > 0 {
+		if services, err := stack.ParseYAMLFile(yamlFile, "", "", envsubst); err == nil && services != nil {
+			yamlGateway = services.Provider.GatewayURL
+			if fn, ok := services.Functions[name]; ok {
+				namespace = getNamespace(functionNamespace, fn.Namespace)
+			}
+		}
+	}
+	return getGatewayURL(gateway, defaultGateway, yamlGateway, os.Getenv(openFaaSURLEnvironment)), namespace
+}
+
+func testHTTPClient() *http.Client {
+	client := &http.Client{Timeout: commandTimeout}
+	if tlsInsecure {
+		client.Transport = GetDefaultCLITransport(tlsInsecure, nil)
+	}
+	return client
+}
+
+// invokeTestCase makes the request of a case through the gateway. Any status
+// is returned as the response, since the one recorded may be an error.
+func invokeTestCase(ctx context.Context, client *http.Client, gatewayAddress string, c testCase, payload []byte) (*testResponse, error) {
+	invokeURL := fmt.Sprintf("%s/function/%s", strings.TrimRight(gatewayAddress, "/"), c.Function)
+	if len(c.Namespace) > 0 {
+		invokeURL += "." + c.Namespace
+	}
+
+	if len(c.Query) > 0 {
+		values := url.Values{}
+		for _, option := range c.Query {
+			key, value, ok := strings.Cut(option, "=")
+			if !ok {
+				return nil, fmt.Errorf("query must be KEY=VALUE, got: %q", option)
+			}
+			values.Add(key, value)
+		}
+		invokeURL += "?" + values.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.Method, invokeURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	if len(c.ContentType) > 0 {
+		req.Header.Set("Content-Type", c.ContentType)
+	}
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to invoke %s: %s", c.Function, err.Error())
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the response of %s: %s", c.Function, err.Error())
+	}
+
+	return &testResponse{
+		Status:  res.StatusCode,
+		Body:    body,
+		Elapsed: time.Since(start),
+	}, nil
+}
+
+// defaultTestCaseName is the function's name, followed by that of its
+// payload file without the extension
+func defaultTestCaseName(function, payload string) string {
+	if len(payload) == 0 || payload == "-" {
+		return function
+	}
+	base := filepath.Base(payload)
+	return function + "-" + strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// saveTestCase writes the case, its payload and its golden file to dir,
+// replacing those of an earlier recording. A JSON body is indented, so that
+// changes to it can be reviewed.
+func saveTestCase(dir, name string, c testCase, payload []byte, res *testResponse) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	if len(payload) > 0 {
+		ext := ".txt"
+		if json.Valid(payload) {
+			ext = ".json"
+		}
+		c.Payload = name + ".payload" + ext
+		if err := ioutil.WriteFile(filepath.Join(dir, c.Payload), payload, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	golden := res.Body
+	c.Golden = name + ".golden.txt"
+	var indented bytes.Buffer
+	if len(bytes.TrimSpace(golden)) > 0 && json.Indent(&indented, golden, "", "  ") == nil {
+		indented.WriteString("\n")
+		golden = indented.Bytes()
+		c.Golden = name + ".golden.json"
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, c.Golden), golden, 0644); err != nil {
+		return "", err
+	}
+
+	c.Status = res.Status
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+testCaseExtension)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// loadTestCases lists the cases in dir, in the order of their names
+func loadTestCases(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cases []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), testCaseExtension) {
+			cases = append(cases, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(cases)
+	return cases, nil
+}
+
+func readTestCase(path string) (testCase, error) {
+	var c testCase
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return c, fmt.Errorf("invalid case %s: %s", path, err.Error())
+	}
+
+	if len(c.Function) == 0 || len(c.Golden) == 0 {
+		return c, fmt.Errorf("invalid case %s: function and golden are required", path)
+	}
+	if len(c.Method) == 0 {
+		c.Method = http.MethodPost
+	}
+	for _, ignore := range c.Ignore {
+		if _, err := parseIgnorePath(ignore); err != nil {
+			return c, fmt.Errorf("invalid case %s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+// compareGolden describes the first difference between two bodies, or is
+// empty when they match. JSON is compared as data, without the ignored paths.
+func compareGolden(golden, body []byte, ignore []string) string {
+	want, wantErr := decodeJSON(golden)
+	if wantErr != nil {
+		if bytes.Equal(bytes.TrimRight(golden, "\r\n"), bytes.TrimRight(body, "\r\n")) {
+			return ""
+		}
+		return fmt.Sprintf("body: want %q, got %q", shorten(golden), shorten(body))
+	}
+
+	got, err := decodeJSON(body)
+	if err != nil {
+		return fmt.Sprintf("body: want JSON, got %q", shorten(body))
+	}
+
+	for _, path := range ignore {
+		segments, _ := parseIgnorePath(path)
+		want = removeJSONPath(want, segments)
+		got = removeJSONPath(got, segments)
+	}
+
+	if difference := jsonDifference("$", want, got); len(difference) > 0 {
+		return "body " + difference
+	}
+	return ""
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("more than one JSON value")
+	}
+	return value, nil
+}
+
+// shorten cuts a body down, so that it fits on the line of its case
+func shorten(body []byte) string {
+	const limit = 60
+	text := strings.TrimSpace(string(body))
+	if len(text) > limit {
+		text = text[:limit] + "..."
+	}
+	return text
+}
+
+// parseIgnorePath splits a path such as $.items[*].id into its keys
+func parseIgnorePath(path string) ([]string, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	trimmed = strings.NewReplacer("[", ".", "]", "").Replace(trimmed)
+
+	segments := strings.Split(trimmed, ".")
+	for _, segment := range segments {
+		if len(segment) == 0 {
+			return nil, fmt.Errorf("ignore path must be a dotted path of keys, such as $.items[*].id, got: %q", path)
+		}
+	}
+	return segments, nil
+}
+
+// removeJSONPath leaves out the values at a path, a key of an object is
+// removed, while an item of a list is replaced so that the rest keep their
+// index
+func removeJSONPath(value interface{}, segments []string) interface{} {
+	if len(segments) == 0 {
+		return value
+	}
+	segment, rest := segments[0], segments[1:]
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if segment != "*" && segment != key {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(v, key)
+			} else {
+				v[key] = removeJSONPath(child, rest)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if segment != "*" && segment != fmt.Sprintf("%d", i) {
+				continue
+			}
+			if len(rest) == 0 {
+				v[i] = ignoredValue
+			} else {
+				v[i] = removeJSONPath(child, rest)
+			}
+		}
+	}
+	return value
+}
+
+// jsonDifference describes the first value which differs, by its path
+func jsonDifference(path string, want, got interface{}) string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(w)+len(g))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		for key := range g {
+			if _, ok := w[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			wantValue, inWant := w[key]
+			gotValue, inGot := g[key]
+			switch {
+			case !inGot:
+				return fmt.Sprintf("at %s.%s: missing", path, key)
+			case !inWant:
+				return fmt.Sprintf("at %s.%s: unexpected %s", path, key, jsonText(gotValue))
+			}
+			if difference := jsonDifference(path+"."+key, wantValue, gotValue); len(difference) > 0 {
+				return difference
+			}
+		}
+		return ""
+
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			break
+		}
+		if len(w) != len(g) {
+			return fmt.Sprintf("at %s: want %d items, got %d", path, len(w), len(g))
+		}
+		for i := range w {
+			if difference := jsonDifference(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); len(difference) > 0 {
+				return difference
+			}
+		}
+		return ""
+	}
+
+	// Numbers are equal by value, so that 1.0 matches 1
+	if w, ok := want.(json.Number); ok {
+		if g, ok := got.(json.Number); ok {
+			wf, wErr := w.Float64()
+			gf, gErr := g.Float64()
+			if wErr == nil && gErr == nil && wf == gf {
+				return ""
+			}
+		}
+	}
+	if reflect.DeepEqual(want, got) {
+		return ""
+	}
+	return fmt.Sprintf("at %s: want %s, got %s", path, jsonText(want), jsonText(got))
+}
+
+func jsonText(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return shorten(data)
+}
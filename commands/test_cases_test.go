@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runTestCmd(t *testing.T, args ...string) (string, error) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+	resetForTest()
+	testCaseName, testPayload, testContentType = "", "", ""
+	testHeaders, testQuery, testIgnore = nil, nil, nil
+	testGolden, testRunFunction = false, ""
+
+	var out bytes.Buffer
+	faasCmd.SetOut(&out)
+	defer faasCmd.SetOut(nil)
+
+	faasCmd.SetArgs(append([]string{"test"}, args...))
+	err := faasCmd.Execute()
+	return out.String(), err
+}
+
+func Test_testRecordAndRun(t *testing.T) {
+	response := `{"id": "a1", "hash": "5d41", "items": [{"n": 1, "at": "10:00"}]}`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/function/stronghash" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"text":"hello"}` || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("want the JSON payload, got %q as %s", body, r.Header.Get("Content-Type"))
+		}
+		w.Write([]byte(response))
+	}))
+	defer s.Close()
+
+	dir := t.TempDir()
+	payload := filepath.Join(dir, "p.json")
+	os.WriteFile(payload, []byte(`{"text":"hello"}`), 0644)
+	cases := filepath.Join(dir, "cases")
+
+	out, err := runTestCmd(t, "record", "stronghash", "--gateway", s.URL, "--payload", payload, "--save", cases, "--ignore", "$.id")
+	if err != nil {
+		t.Fatalf("want the case recorded, got: %s", err)
+	}
+	if !strings.Contains(out, "Recorded stronghash-p: 200") {
+		t.Errorf("want the case and status printed, got: %s", out)
+	}
+	for _, name := range []string{"stronghash-p.yml", "stronghash-p.payload.json", "stronghash-p.golden.json"} {
+		if _, err := os.Stat(filepath.Join(cases, name)); err != nil {
+			t.Errorf("want %s saved: %s", name, err)
+		}
+	}
+
+	// The ignored id changes, and the keys are in another order
+	response = `{"items": [{"at": "10:00", "n": 1}], "hash": "5d41", "id": "b2"}`
+	if out, err := runTestCmd(t, "run", "--gateway", s.URL, "--cases", cases, "--golden"); err != nil {
+		t.Fatalf("want the case to pass, got: %s\n%s", err, out)
+	}
+
+	response = `{"id": "c3", "hash": "5d41", "items": [{"n": 2, "at": "10:00"}]}`
+	out, err = runTestCmd(t, "run", "--gateway", s.URL, "--cases", cases, "--golden")
+	if err == nil || err.Error() != "1 of 1 cases failed" {
+		t.Fatalf("want the case to fail, got: %v", err)
+	}
+	if !strings.Contains(out, "body at $.items[0].n: want 1, got 2") {
+		t.Errorf("want the difference printed, got: %s", out)
+	}
+
+	// Without --golden only the status is checked
+	if out, err := runTestCmd(t, "run", "--gateway", s.URL, "--cases", cases); err != nil {
+		t.Fatalf("want the status to match, got: %s\n%s", err, out)
+	}
+}
+
+func Test_testRun_NoCases(t *testing.T) {
+	_, err := runTestCmd(t, "run", "--cases", filepath.Join(t.TempDir(), "missing"))
+	if err == nil || !strings.Contains(err.Error(), "no cases found") {
+		t.Fatalf("want an error for a folder without cases, got: %v", err)
+	}
+}
+
+func Test_compareGolden(t *testing.T) {
+	cases := []struct {
+		name   string
+		golden string
+		body   string
+		ignore []string
+		want   string
+	}{
+		{
+			name:   "text with a trailing newline",
+			golden: "hello\n",
+			body:   "hello",
+		},
+		{
+			name:   "different text",
+			golden: "hello",
+			body:   "goodbye",
+			want:   `body: want "hello", got "goodbye"`,
+		},
+		{
+			name:   "numbers by value",
+			golden: `{"total": 1.0}`,
+			body:   `{"total": 1}`,
+		},
+		{
+			name:   "every item of a list ignored",
+			golden: `{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]}`,
+			body:   `{"items": [{"id": 7, "name": "a"}, {"id": 8, "name": "b"}]}`,
+			ignore: []string{"items[*].id"},
+		},
+		{
+			name:   "missing key",
+			golden: `{"a": 1, "b": 2}`,
+			body:   `{"a": 1}`,
+			want:   "body at $.b: missing",
+		},
+		{
+			name:   "length of a list",
+			golden: `[1, 2]`,
+			body:   `[1]`,
+			want:   "body at $: want 2 items, got 1",
+		},
+		{
+			name:   "not JSON",
+			golden: `{"a": 1}`,
+			body:   "Internal error",
+			want:   `body: want JSON, got "Internal error"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := compareGolden([]byte(c.golden), []byte(c.body), c.ignore); got != c.want {
+				t.Errorf("want %q, got %q", c.want, got)
+			}
+		})
+	}
+}
+
+func Test_parseIgnorePath(t *testing.T) {
+	segments, err := parseIgnorePath("$.items[*].created_at")
+	if err != nil || strings.Join(segments, " ") != "items * created_at" {
+		t.Errorf("want the keys of the path, got: %v, %v", segments, err)
+	}
+	if _, err := parseIgnorePath("items..id"); err == nil {
+		t.Errorf("want an error for an empty key")
+	}
+}
@@ -31,6 +31,7 @@ func init() {
 	deploy, _, _ := faasCmd.Find([]string{"deploy"})
 	upCmd.Flags().AddFlagSet(deploy.Flags())
 
+	markMutating(upCmd)
 	faasCmd.AddCommand(upCmd)
 }
 
@@ -64,6 +65,13 @@ func preRunUp(cmd *cobra.Command, args []string) error {
 }
 
 func upHandler(cmd *cobra.Command, args []string) error {
+	// Check the gateway before spending time on builds which can't be deployed
+	if !skipDeploy && gatewayPrecheck {
+		if err := precheckStackGateway(); err != nil {
+			return err
+		}
+	}
+
 	if err := runBuild(cmd, args); err != nil {
 		return err
 	}
@@ -0,0 +1,332 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/version"
+	"github.com/spf13/cobra"
+)
+
+const (
+	usageLedgerFile   = "usage.ndjson"
+	usageSettingsFile = "usage.json"
+)
+
+var (
+	usageEndpoint string
+	usageSince    time.Duration
+)
+
+func init() {
+	usageEnableCmd.Flags().StringVar(&usageEndpoint, "endpoint", "", "URL within your organisation to also receive each entry, entries are only kept locally when not set")
+	usageReportCmd.Flags().DurationVar(&usageSince, "since", 0, "Only include commands run within this duration, such as 168h")
+
+	usageCmd.AddCommand(usageEnableCmd, usageDisableCmd, usageReportCmd, usageClearCmd)
+	faasCmd.AddCommand(usageCmd)
+}
+
+var usageCmd = &cobra.Command{
+	Use:   `usage [enable|disable|report|clear]`,
+	Short: "Record and report on how faas-cli is used",
+	Long: `Keeps a local ledger of each command that is run, how long it took and whether
+it succeeded, which can be summarised with "faas-cli usage report".
+
+Nothing is recorded until "faas-cli usage enable" is run. Only the name of the
+command is recorded, never its arguments or flags. Entries stay on this machine
+unless an --endpoint is given, such as a collector run by your platform team.`,
+	Example: `  faas-cli usage enable
+  faas-cli usage enable --endpoint https://usage.example.com/faas-cli
+  faas-cli usage report --since 168h
+  faas-cli usage clear
+  faas-cli usage disable`,
+}
+
+var usageEnableCmd = &cobra.Command{
+	Use:   "enable [--endpoint URL]",
+	Short: "Start recording commands to the local ledger",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := writeUsageSettings(usageSettings{Enabled: true, Endpoint: usageEndpoint}); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Recording usage to: %s\n", usagePath(usageLedgerFile))
+		if len(usageEndpoint) > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "Entries will also be sent to: %s\n", usageEndpoint)
+		}
+		return nil
+	},
+}
+
+var usageDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop recording commands, the ledger is kept",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := writeUsageSettings(usageSettings{}); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage recording disabled")
+		return nil
+	},
+}
+
+var usageReportCmd = &cobra.Command{
+	Use:   "report [--since DURATION]",
+	Short: "Summarise the commands in the local ledger",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := readUsageLedger(usagePath(usageLedgerFile))
+		if err != nil {
+			return err
+		}
+
+		if usageSince > 0 {
+			entries = usageEntriesSince(entries, time.Now().Add(-usageSince))
+		}
+
+		if len(entries) == 0 {
+			settings, _ := readUsageSettings()
+			if !settings.Enabled {
+				fmt.Fprintln(cmd.OutOrStdout(), "No usage recorded, run \"faas-cli usage enable\" to start recording")
+				return nil
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "No usage recorded")
+			return nil
+		}
+
+		printUsageReport(cmd.OutOrStdout(), summariseUsage(entries))
+		return nil
+	},
+}
+
+var usageClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete the local ledger",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := os.Remove(usagePath(usageLedgerFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Usage ledger cleared")
+		return nil
+	},
+}
+
+// usageSettings is written by "usage enable" and "usage disable"
+type usageSettings struct {
+	Enabled  bool   `json:"enabled"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// usageEntry is one command run, arguments are never recorded as they may
+// contain tokens or other secrets
+type usageEntry struct {
+	Command   string    `json:"command"`
+	Started   time.Time `json:"started"`
+	Duration  float64   `json:"durationSeconds"`
+	Succeeded bool      `json:"succeeded"`
+	Version   string    `json:"version,omitempty"`
+}
+
+// usageSummary aggregates the entries of one command
+type usageSummary struct {
+	Command string
+	Runs    int
+	Failed  int
+	Total   time.Duration
+	Max     time.Duration
+}
+
+func usagePath(name string) string {
+	dir, err := homedir.Expand(config.ConfigDir())
+	if err != nil {
+		dir = config.ConfigDir()
+	}
+	return filepath.Join(dir, name)
+}
+
+func readUsageSettings() (usageSettings, error) {
+	var settings usageSettings
+
+	data, err := ioutil.ReadFile(usagePath(usageSettingsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return settings, err
+	}
+
+	err = json.Unmarshal(data, &settings)
+	return settings, err
+}
+
+func writeUsageSettings(settings usageSettings) error {
+	path := usagePath(usageSettingsFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// recordUsage appends the command to the ledger when recording is enabled.
+// It is best effort, so a failure never changes the outcome of the command.
+func recordUsage(cmd *cobra.Command, started time.Time, runErr error) {
+	if cmd == nil || strings.HasPrefix(cmd.CommandPath(), usageCmd.CommandPath()) {
+		return
+	}
+
+	settings, err := readUsageSettings()
+	if err != nil || !settings.Enabled {
+		return
+	}
+
+	entry := usageEntry{
+		Command:   cmd.CommandPath(),
+		Started:   started.UTC(),
+		Duration:  time.Since(started).Seconds(),
+		Succeeded: runErr == nil,
+		Version:   version.BuildVersion(),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	if err := appendUsageEntry(usagePath(usageLedgerFile), data); err != nil {
+		return
+	}
+
+	if len(settings.Endpoint) > 0 {
+		sendUsageEntry(settings.Endpoint, data)
+	}
+}
+
+func appendUsageEntry(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// sendUsageEntry uses a short timeout so that an unreachable endpoint
+// doesn't hold up the command
+func sendUsageEntry(endpoint string, data []byte) {
+	client := http.Client{Timeout: 2 * time.Second}
+
+	res, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	res.Body.Close()
+}
+
+// readUsageLedger skips lines which can't be parsed, a missing ledger is empty
+func readUsageLedger(path string) ([]usageEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []usageEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry usageEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+func usageEntriesSince(entries []usageEntry, since time.Time) []usageEntry {
+	var filtered []usageEntry
+	for _, entry := range entries {
+		if !entry.Started.Before(since) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// summariseUsage orders commands by the total time spent in them, so the
+// biggest bottlenecks come first
+func summariseUsage(entries []usageEntry) []usageSummary {
+	byCommand := map[string]*usageSummary{}
+	for _, entry := range entries {
+		s, ok := byCommand[entry.Command]
+		if !ok {
+			s = &usageSummary{Command: entry.Command}
+			byCommand[entry.Command] = s
+		}
+
+		duration := time.Duration(entry.Duration * float64(time.Second))
+		s.Runs++
+		s.Total += duration
+		if duration > s.Max {
+			s.Max = duration
+		}
+		if !entry.Succeeded {
+			s.Failed++
+		}
+	}
+
+	summaries := make([]usageSummary, 0, len(byCommand))
+	for _, s := range byCommand {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Total == summaries[j].Total {
+			return summaries[i].Command < summaries[j].Command
+		}
+		return summaries[i].Total > summaries[j].Total
+	})
+
+	return summaries
+}
+
+func printUsageReport(w io.Writer, summaries []usageSummary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "COMMAND\tRUNS\tFAILED\tAVERAGE\tMAX\tTOTAL")
+	for _, s := range summaries {
+		average := s.Total / time.Duration(s.Runs)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\n",
+			s.Command, s.Runs, s.Failed,
+			average.Round(time.Millisecond), s.Max.Round(time.Millisecond), s.Total.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_recordUsage_OnlyWhenEnabled(t *testing.T) {
+	t.Setenv("OPENFAAS_CONFIG", t.TempDir())
+
+	recordUsage(versionCmd, time.Now(), nil)
+	if entries, _ := readUsageLedger(usagePath(usageLedgerFile)); len(entries) != 0 {
+		t.Fatalf("want nothing recorded before usage is enabled, got: %v", entries)
+	}
+
+	if err := writeUsageSettings(usageSettings{Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	recordUsage(versionCmd, time.Now(), nil)
+	recordUsage(versionCmd, time.Now(), errors.New("failed"))
+	recordUsage(usageReportCmd, time.Now(), nil)
+
+	entries, err := readUsageLedger(usagePath(usageLedgerFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("want 2 entries, usage commands are not recorded, got: %v", entries)
+	}
+	if entries[0].Command != "faas-cli version" || !entries[0].Succeeded || entries[1].Succeeded {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+}
+
+func Test_summariseUsage(t *testing.T) {
+	now := time.Now()
+	entries := []usageEntry{
+		{Command: "faas-cli up", Started: now, Duration: 30, Succeeded: true},
+		{Command: "faas-cli up", Started: now, Duration: 10, Succeeded: false},
+		{Command: "faas-cli list", Started: now.Add(-48 * time.Hour), Duration: 1, Succeeded: true},
+	}
+
+	summaries := summariseUsage(entries)
+	if len(summaries) != 2 || summaries[0].Command != "faas-cli up" {
+		t.Fatalf("want up first, by total time, got: %v", summaries)
+	}
+	if summaries[0].Runs != 2 || summaries[0].Failed != 1 || summaries[0].Max != 30*time.Second {
+		t.Fatalf("unexpected summary: %+v", summaries[0])
+	}
+
+	if recent := usageEntriesSince(entries, now.Add(-time.Hour)); len(recent) != 2 {
+		t.Fatalf("want 2 recent entries, got: %d", len(recent))
+	}
+
+	var out bytes.Buffer
+	printUsageReport(&out, summaries)
+	if !strings.Contains(out.String(), "20s") {
+		t.Fatalf("want the average duration in the report, got:\n%s", out.String())
+	}
+}
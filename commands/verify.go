@@ -0,0 +1,334 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/output"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifySkipBuild    bool
+	verifyPort         int
+	verifyReadyTimeout time.Duration
+	verifyTestTimeout  time.Duration
+)
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifySkipBuild, "skip-build", false, "Verify the images which were built already, instead of building them")
+	verifyCmd.Flags().IntVarP(&verifyPort, "port", "p", 8081, "First port to run the functions on, each function takes the next free port")
+	verifyCmd.Flags().DurationVar(&verifyReadyTimeout, "ready-timeout", 30*time.Second, "Time to wait for each function's watchdog to respond")
+	verifyCmd.Flags().DurationVar(&verifyTestTimeout, "timeout", 10*time.Second, "Timeout for each smoke test")
+	verifyCmd.Flags().BoolVar(&envsubst, "envsubst", true, "Substitute environment variables in stack.yml file")
+
+	faasCmd.AddCommand(verifyCmd)
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   `verify [-f YAML_FILE] [--skip-build] [--port PORT]`,
+	Short: "Test a stack in local containers before it is deployed",
+	Long: `Builds the images of the functions in the stack file, starts each one with
+local-run on a shared network, then checks that its watchdog becomes ready,
+that its health check passes, and that it gives the responses declared by its
+smoke tests. A table of the results is printed, and verify fails when any
+function does, so that it can gate a deployment in CI without a cluster.
+
+The health check is the path of the function's health: section, or the
+watchdog's /_/health. Smoke tests are declared for each function:
+
+  functions:
+    greet:
+      tests:
+        - name: says hello
+          body: Alex
+          contains: Hello Alex
+        - name: rejects a GET
+          method: GET
+          status: 405
+
+A test is a GET to / unless a method, path or body is given, and passes when
+the response has the status, 200 by default, and contains the text. The
+containers are stopped once the stack is verified.`,
+	Example: `  faas-cli verify
+  faas-cli verify -f stack.yml --filter "api-*"
+  faas-cli verify --skip-build --ready-timeout 1m`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkLocalRunExperimental(); err != nil {
+			return err
+		}
+		if err := validateContainerRuntime(); err != nil {
+			return err
+		}
+		if len(yamlFile) == 0 {
+			return fmt.Errorf("give a stack file with --yaml/-f")
+		}
+		if verifyPort < 1 || verifyPort > 65535 {
+			return fmt.Errorf("--port must be between 1 and 65535, got: %d", verifyPort)
+		}
+		return nil
+	},
+	RunE: runVerify,
+	// Hidden while local-run is experimental
+	Hidden: true,
+}
+
+// verifyResult is the outcome of each stage for a function, a stage which
+// was not reached is empty
+type verifyResult struct {
+	name     string
+	started  bool
+	ready    bool
+	healthy  bool
+	passed   int
+	tests    int
+	failures []string
+	logs     string
+}
+
+func (r verifyResult) ok() bool {
+	return r.started && r.ready && r.healthy && len(r.failures) == 0
+}
+
+// verifyContainerStart and verifyContainerLogs are replaced by tests
+var (
+	verifyContainerStart = func(ctx context.Context, plan *localRunPlan) error {
+		out, err := plan.command(ctx).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s", strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	verifyContainerLogs = func(name string) string {
+		out, _ := exec.Command(containerRuntime(), "logs", "--tail", "20", localRunContainerName(name)).CombinedOutput()
+		return strings.TrimSpace(string(out))
+	}
+)
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter, envsubst)
+	if err != nil {
+		return err
+	}
+	if len(services.Functions) == 0 {
+		return fmt.Errorf("no functions found in the stack file")
+	}
+
+	if !verifySkipBuild {
+		if err := buildLocalRun(cmd, services); err != nil {
+			return err
+		}
+	}
+
+	opts := runOptions{
+		port:         verifyPort,
+		detach:       true,
+		runtime:      containerRuntime(),
+		readyTimeout: verifyReadyTimeout,
+		output:       out,
+		err:          cmd.ErrOrStderr(),
+	}
+
+	hostPorts, err := selectPorts(len(services.Functions), opts)
+	if err != nil {
+		return err
+	}
+	plans, ports, err := planStack(services.Functions, localRunStackNetwork, hostPorts, opts)
+	if err != nil {
+		return err
+	}
+	if err := ensureLocalRunNetwork(ctx, localRunStackNetwork); err != nil {
+		return err
+	}
+
+	results := make([]verifyResult, 0, len(plans))
+	for _, plan := range plans {
+		name := plan.Labels[localRunFunctionLabel]
+		result := verifyResult{name: name}
+		if err := verifyContainerStart(ctx, plan); err != nil {
+			result.failures = append(result.failures, fmt.Sprintf("unable to start: %s", err))
+		} else {
+			result.started = true
+			defer stopLocalRunContainer(name, localRunStopGrace)
+		}
+		results = append(results, result)
+	}
+
+	fmt.Fprintf(out, "Verifying %d function(s)\n\n", len(results))
+	for i := range results {
+		result := &results[i]
+		if !result.started {
+			continue
+		}
+
+		fnc := services.Functions[result.name]
+		verifyFunction(ctx, result, fnc, ports[result.name])
+		if !result.ok() {
+			result.logs = verifyContainerLogs(result.name)
+		}
+	}
+
+	return writeVerifyResults(out, results)
+}
+
+// verifyFunction waits for the watchdog, then makes the health check and
+// smoke tests against the function's port
+func verifyFunction(ctx context.Context, result *verifyResult, fnc stack.Function, port int) {
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	stopped := func() bool { return !localRunContainerRunning(result.name) }
+	if err := waitForWatchdog(ctx, baseURL+watchdogHealthPath, verifyReadyTimeout, stopped); err != nil {
+		result.failures = append(result.failures, fmt.Sprintf("not ready: %s", err))
+		return
+	}
+	result.ready = true
+
+	healthPath := watchdogHealthPath
+	if fnc.Health != nil && len(fnc.Health.Path) > 0 {
+		healthPath = fnc.Health.Path
+	}
+	client := &http.Client{Timeout: verifyTestTimeout}
+	if res, err := client.Get(baseURL + healthPath); err != nil {
+		result.failures = append(result.failures, fmt.Sprintf("health check %s failed: %s", healthPath, err))
+	} else {
+		res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			result.failures = append(result.failures, fmt.Sprintf("health check %s returned %d", healthPath, res.StatusCode))
+		} else {
+			result.healthy = true
+		}
+	}
+
+	result.tests = len(fnc.Tests)
+	for i, test := range fnc.Tests {
+		if err := runSmokeTest(client, baseURL, test); err != nil {
+			name := test.Name
+			if len(name) == 0 {
+				name = "#" + strconv.Itoa(i+1)
+			}
+			result.failures = append(result.failures, fmt.Sprintf("test %q: %s", name, err))
+			continue
+		}
+		result.passed++
+	}
+}
+
+func runSmokeTest(client *http.Client, baseURL string, test stack.SmokeTest) error {
+	method := test.Method
+	if len(method) == 0 {
+		method = http.MethodGet
+		if len(test.Body) > 0 {
+			method = http.MethodPost
+		}
+	}
+	path := test.Path
+	if len(path) == 0 {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path %q must start with /", path)
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(method), baseURL+path, strings.NewReader(test.Body))
+	if err != nil {
+		return err
+	}
+	for key, value := range test.Headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	status := test.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if res.StatusCode != status {
+		return fmt.Errorf("want status %d, got %d: %s", status, res.StatusCode, verifyExcerpt(body))
+	}
+	if len(test.Contains) > 0 && !strings.Contains(string(body), test.Contains) {
+		return fmt.Errorf("want the body to contain %q, got: %s", test.Contains, verifyExcerpt(body))
+	}
+	return nil
+}
+
+// verifyExcerpt is the start of a response body, for the reason a test failed
+func verifyExcerpt(body []byte) string {
+	text := strings.TrimSpace(string(body))
+	if len(text) > 200 {
+		return text[:200] + "..."
+	}
+	return text
+}
+
+func writeVerifyResults(w io.Writer, results []verifyResult) error {
+	stage := func(reached, ok bool) string {
+		switch {
+		case !reached:
+			return "-"
+		case ok:
+			return output.Success("pass")
+		}
+		return output.Failure("fail")
+	}
+
+	failed := 0
+	table := output.NewTable("FUNCTION", "START", "READY", "HEALTH", "TESTS", "RESULT")
+	for _, r := range results {
+		tests := "-"
+		if r.ready && r.tests > 0 {
+			tests = fmt.Sprintf("%d/%d", r.passed, r.tests)
+		}
+		result := output.Success("pass")
+		if !r.ok() {
+			result = output.Failure("fail")
+			failed++
+		}
+		table.Row(r.name, stage(true, r.started), stage(r.started, r.ready), stage(r.ready, r.healthy), tests, result)
+	}
+	table.Write(w)
+
+	for _, r := range results {
+		if len(r.failures) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", r.name)
+		for _, failure := range r.failures {
+			fmt.Fprintf(w, "  - %s\n", failure)
+		}
+		if len(r.logs) > 0 {
+			fmt.Fprintf(w, "  Last lines of its output:\n")
+			for _, line := range strings.Split(r.logs, "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d function(s) failed verification", failed, len(results))
+	}
+	fmt.Fprintf(w, "\nAll %d function(s) passed verification\n", len(results))
+	return nil
+}
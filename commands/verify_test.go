@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_verify(t *testing.T) {
+	t.Setenv("OPENFAAS_EXPERIMENTAL", "1")
+	resetForTest()
+
+	function := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case watchdogHealthPath:
+			w.WriteHeader(http.StatusOK)
+		case "/":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte("Hello " + string(body)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer function.Close()
+	u, _ := url.Parse(function.URL)
+
+	stackFile := filepath.Join(t.TempDir(), "stack.yml")
+	os.WriteFile(stackFile, []byte(`version: 1.0
+provider:
+  name: openfaas
+functions:
+  greet:
+    lang: dockerfile
+    handler: ./greet
+    image: greet:latest
+    fprocess: ./handler
+    tests:
+      - name: says hello
+        body: Alex
+        contains: Hello Alex
+      - name: rejects a GET
+        method: GET
+        status: 405
+      - name: has an about page
+        path: /about
+`), 0600)
+
+	var started []string
+	stopped := map[string]bool{}
+	restore := []func(){}
+	swap := func(undo func()) { restore = append(restore, undo) }
+	defer func() {
+		for _, undo := range restore {
+			undo()
+		}
+	}()
+
+	start, logs, stop, running, network, available := verifyContainerStart, verifyContainerLogs, stopLocalRunContainer, localRunContainerRunning, ensureLocalRunNetwork, portAvailable
+	swap(func() {
+		verifyContainerStart, verifyContainerLogs, stopLocalRunContainer, localRunContainerRunning, ensureLocalRunNetwork, portAvailable = start, logs, stop, running, network, available
+	})
+	verifyContainerStart = func(ctx context.Context, plan *localRunPlan) error {
+		started = append(started, plan.Labels[localRunFunctionLabel])
+		return nil
+	}
+	verifyContainerLogs = func(name string) string { return "Forking fprocess." }
+	stopLocalRunContainer = func(name string, grace time.Duration) error { stopped[name] = true; return nil }
+	localRunContainerRunning = func(string) bool { return true }
+	ensureLocalRunNetwork = func(context.Context, string) error { return nil }
+	portAvailable = func(int) bool { return true }
+
+	var out bytes.Buffer
+	faasCmd.SetOut(&out)
+	defer faasCmd.SetOut(nil)
+	faasCmd.SetArgs([]string{"verify", "-f", stackFile, "--skip-build", "--port", u.Port()})
+	err := faasCmd.Execute()
+
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 function(s) failed") {
+		t.Fatalf("want the failed test to fail verify, got: %v", err)
+	}
+	if len(started) != 1 || !stopped["greet"] {
+		t.Errorf("want greet started and stopped, got: %v, %v", started, stopped)
+	}
+
+	got := out.String()
+	for _, want := range []string{"greet", "2/3", `test "has an about page": want status 200, got 404`, "Forking fprocess."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+}
+
+func Test_runSmokeTest_Defaults(t *testing.T) {
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if err := runSmokeTest(server.Client(), server.URL, stack.SmokeTest{}); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodGet || path != "/" {
+		t.Errorf("want GET /, got: %s %s", method, path)
+	}
+
+	if err := runSmokeTest(server.Client(), server.URL, stack.SmokeTest{Body: "data"}); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPost {
+		t.Errorf("want a POST with a body, got: %s", method)
+	}
+}
@@ -12,7 +12,7 @@ import (
 	"os"
 
 	"github.com/alexellis/arkade/pkg/get"
-	"github.com/morikuni/aec"
+	"github.com/openfaas/faas-cli/output"
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas-cli/version"
@@ -137,9 +137,9 @@ Gateway
 
 // printLogo prints an ASCII logo, which was generated with figlet
 func printLogo() {
-	figletColoured := aec.BlueF.Apply(figletStr)
+	figletColoured := output.Blue.Apply(figletStr)
 	if runtime.GOOS == "windows" {
-		figletColoured = aec.GreenF.Apply(figletStr)
+		figletColoured = output.Green.Apply(figletStr)
 	}
 	fmt.Printf(figletColoured)
 }
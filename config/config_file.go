@@ -26,6 +26,8 @@ const (
 	BasicAuthType = "basic"
 	//Oauth2AuthType oauth2 authentication type
 	Oauth2AuthType = "oauth2"
+	// ExecAuthType runs a command for the credentials each time they are used
+	ExecAuthType = "exec"
 
 	// ConfigLocationEnv is the name of he env variable used
 	// to configure the location of the faas-cli config folder.
@@ -46,14 +48,33 @@ const (
 
 // ConfigFile for OpenFaaS CLI exclusively.
 type ConfigFile struct {
+	// Version of the file's schema, older files are migrated when loaded
+	Version int `yaml:"version"`
+
 	AuthConfigs []AuthConfig `yaml:"auths"`
-	FilePath    string       `yaml:"-"`
+
+	// ConnectionConfigs are the proxy and CA bundle of each gateway, which
+	// are kept when logging out
+	ConnectionConfigs []ConnectionConfig `yaml:"connections,omitempty"`
+
+	// RegistryConfigs are the commands which give the credentials of
+	// container registries
+	RegistryConfigs []RegistryConfig `yaml:"registries,omitempty"`
+
+	FilePath string `yaml:"-"`
+
+	// newerVersion is the version of a file written by a newer release,
+	// which can be read, but not saved without losing its new fields
+	newerVersion int
 }
 
 type AuthConfig struct {
 	Gateway string   `yaml:"gateway,omitempty"`
 	Auth    AuthType `yaml:"auth,omitempty"`
 	Token   string   `yaml:"token,omitempty"`
+
+	// Exec is the command which gives the credentials for the exec auth type
+	Exec *ExecConfig `yaml:"exec,omitempty"`
 }
 
 // New initializes a config file for the given file path
@@ -147,6 +168,11 @@ func fileExists() bool {
 
 // Save writes the config to disk
 func (configFile *ConfigFile) save() error {
+	if configFile.newerVersion > 0 {
+		return fmt.Errorf("the config file %s is version %d, from a newer faas-cli, upgrade faas-cli to change it", configFile.FilePath, configFile.newerVersion)
+	}
+	configFile.Version = CurrentVersion
+
 	file, err := os.OpenFile(configFile.FilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
@@ -175,13 +201,56 @@ func (configFile *ConfigFile) load() error {
 		return err
 	}
 
+	doc := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	migrated := false
+	if len(doc) > 0 {
+		from, err := migrate(doc)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case from > CurrentVersion:
+			configFile.newerVersion = from
+		case from < CurrentVersion:
+			// The file is only written back once it is backed up, either way
+			// the migrated config is used, and a later save writes it
+			if err := backupConfig(configFile.FilePath, data, from); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: unable to back up the config file %s before migrating it: %s\n", configFile.FilePath, err)
+			} else {
+				migrated = true
+			}
+			if data, err = yaml.Marshal(doc); err != nil {
+				return err
+			}
+		}
+	}
+
 	if err := yaml.Unmarshal(data, conf); err != nil {
 		return err
 	}
 
+	configFile.Version = conf.Version
 	if len(conf.AuthConfigs) > 0 {
 		configFile.AuthConfigs = conf.AuthConfigs
 	}
+	if len(conf.ConnectionConfigs) > 0 {
+		configFile.ConnectionConfigs = conf.ConnectionConfigs
+	}
+	if len(conf.RegistryConfigs) > 0 {
+		configFile.RegistryConfigs = conf.RegistryConfigs
+	}
+
+	// A read-only config, such as one mounted in CI, can still be used
+	if migrated {
+		if err := configFile.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: unable to save the migrated config file %s: %s\n", configFile.FilePath, err)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,127 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// DirectProxy as the Proxy of a connection ignores HTTP_PROXY and HTTPS_PROXY
+const DirectProxy = "direct"
+
+// ConnectionConfig is how to reach a gateway, such as through a corporate
+// proxy, or with a CA bundle for its self-signed certificate
+type ConnectionConfig struct {
+	Gateway string `yaml:"gateway"`
+
+	// Proxy is the URL of an HTTP(S) proxy, or "direct" to connect without
+	// one. When empty, the proxy environment variables are used.
+	Proxy string `yaml:"proxy,omitempty"`
+
+	// CABundle is the path of a PEM file of certificates, which are trusted
+	// for the gateway as well as the system's roots
+	CABundle string `yaml:"ca_bundle,omitempty"`
+
+	// ProtectedAnnotations and ProtectedLabels are the keys which deploy will
+	// not change on a function which is already deployed to the gateway, such
+	// as those managed by an operator, a key ending in * matches its prefix
+	ProtectedAnnotations []string `yaml:"protected_annotations,omitempty"`
+	ProtectedLabels      []string `yaml:"protected_labels,omitempty"`
+}
+
+// loadConfig reads the config file, or returns an empty one when there is
+// no file
+func loadConfig() (*ConfigFile, error) {
+	configPath, err := EnsureFile()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := New(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.load(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// UpdateConnectionConfig creates or replaces the settings for a gateway
+func UpdateConnectionConfig(connection ConnectionConfig) error {
+	if _, err := url.ParseRequestURI(connection.Gateway); err != nil || len(connection.Gateway) < 1 {
+		return fmt.Errorf("invalid gateway URL")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, v := range cfg.ConnectionConfigs {
+		if connection.Gateway == v.Gateway {
+			index = i
+			break
+		}
+	}
+
+	if index == -1 {
+		cfg.ConnectionConfigs = append(cfg.ConnectionConfigs, connection)
+	} else {
+		cfg.ConnectionConfigs[index] = connection
+	}
+
+	return cfg.save()
+}
+
+// ListConnectionConfigs returns the settings of each gateway
+func ListConnectionConfigs() ([]ConnectionConfig, error) {
+	if !fileExists() {
+		return nil, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.ConnectionConfigs, nil
+}
+
+// LookupConnectionConfig returns the settings for a gateway
+func LookupConnectionConfig(gateway string) (ConnectionConfig, error) {
+	connections, err := ListConnectionConfigs()
+	if err != nil {
+		return ConnectionConfig{}, err
+	}
+
+	for _, v := range connections {
+		if gateway == v.Gateway {
+			return v, nil
+		}
+	}
+	return ConnectionConfig{}, fmt.Errorf("no connection config found for %s", gateway)
+}
+
+// RemoveConnectionConfig deletes the settings for a gateway
+func RemoveConnectionConfig(gateway string) error {
+	if !fileExists() {
+		return fmt.Errorf("config file not found")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	for i, v := range cfg.ConnectionConfigs {
+		if gateway == v.Gateway {
+			cfg.ConnectionConfigs = append(cfg.ConnectionConfigs[:i], cfg.ConnectionConfigs[i+1:]...)
+			return cfg.save()
+		}
+	}
+	return fmt.Errorf("gateway %s not found in config", gateway)
+}
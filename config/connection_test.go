@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_ConnectionConfig_UpdateLookupRemove(t *testing.T) {
+	t.Setenv(ConfigLocationEnv, t.TempDir())
+
+	if connections, err := ListConnectionConfigs(); err != nil || len(connections) != 0 {
+		t.Fatalf("want no connections without a config file, got: %v, %v", connections, err)
+	}
+
+	prod := ConnectionConfig{Gateway: "https://openfaas.example.com", Proxy: "http://proxy:3128", CABundle: "/etc/ca.pem"}
+	if err := UpdateConnectionConfig(prod); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateConnectionConfig(ConnectionConfig{Gateway: "http://127.0.0.1:8080", Proxy: DirectProxy}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A login rewrites the config file, the connections must be kept
+	if err := UpdateAuthConfig(prod.Gateway, EncodeAuth("admin", "secret"), BasicAuthType); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LookupConnectionConfig(prod.Gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, prod) {
+		t.Errorf("want: %v, got: %v", prod, got)
+	}
+
+	prod.Proxy = ""
+	if err := UpdateConnectionConfig(prod); err != nil {
+		t.Fatal(err)
+	}
+	if connections, _ := ListConnectionConfigs(); len(connections) != 2 || connections[0].Proxy != "" {
+		t.Errorf("want the connection to be replaced, got: %v", connections)
+	}
+
+	if err := RemoveConnectionConfig(prod.Gateway); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LookupConnectionConfig(prod.Gateway); err == nil {
+		t.Errorf("want an error for a removed connection")
+	}
+	if err := RemoveConnectionConfig(prod.Gateway); err == nil {
+		t.Errorf("want an error removing a connection twice")
+	}
+}
+
+func Test_UpdateConnectionConfig_InvalidGateway(t *testing.T) {
+	t.Setenv(ConfigLocationEnv, t.TempDir())
+
+	if err := UpdateConnectionConfig(ConnectionConfig{Gateway: "openfaas", Proxy: DirectProxy}); err == nil {
+		t.Fatalf("want an error for an invalid gateway URL")
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExecConfig is a command which prints short-lived credentials, such as
+// those from a corporate vault, so that they are never saved to disk
+type ExecConfig struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+}
+
+// String is the command line, as it was given to login
+func (e ExecConfig) String() string {
+	return strings.Join(append([]string{e.Command}, e.Args...), " ")
+}
+
+// RegistryConfig is where the credentials of a container registry come from
+type RegistryConfig struct {
+	// Server is the registry's host, such as ghcr.io or index.docker.io
+	Server string     `yaml:"server"`
+	Exec   ExecConfig `yaml:"exec"`
+}
+
+// UpdateExecAuthConfig saves the command which gives the credentials for a
+// gateway, replacing any credentials which were saved for it
+func UpdateExecAuthConfig(gateway string, exec ExecConfig) error {
+	if _, err := url.ParseRequestURI(gateway); err != nil || len(gateway) < 1 {
+		return fmt.Errorf("invalid gateway URL")
+	}
+	if len(exec.Command) == 0 {
+		return fmt.Errorf("the exec credential needs a command")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	auth := AuthConfig{Gateway: gateway, Auth: ExecAuthType, Exec: &exec}
+	for i, v := range cfg.AuthConfigs {
+		if gateway == v.Gateway {
+			cfg.AuthConfigs[i] = auth
+			return cfg.save()
+		}
+	}
+
+	cfg.AuthConfigs = append(cfg.AuthConfigs, auth)
+	return cfg.save()
+}
+
+// RegistryServer is the host of a registry, as it is looked up by image
+// references, so https://index.docker.io/v1/ and docker.io are both
+// index.docker.io
+func RegistryServer(server string) string {
+	host := server
+	if u, err := url.Parse(server); err == nil && len(u.Host) > 0 {
+		host = u.Host
+	}
+	host = strings.TrimSuffix(strings.SplitN(host, "/", 2)[0], "/")
+
+	switch host {
+	case "docker.io", "registry-1.docker.io":
+		return "index.docker.io"
+	}
+	return host
+}
+
+// UpdateRegistryConfig creates or replaces the command for a registry
+func UpdateRegistryConfig(registry RegistryConfig) error {
+	registry.Server = RegistryServer(registry.Server)
+	if len(registry.Server) == 0 {
+		return fmt.Errorf("invalid registry server")
+	}
+	if len(registry.Exec.Command) == 0 {
+		return fmt.Errorf("the exec credential needs a command")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	for i, v := range cfg.RegistryConfigs {
+		if registry.Server == v.Server {
+			cfg.RegistryConfigs[i] = registry
+			return cfg.save()
+		}
+	}
+
+	cfg.RegistryConfigs = append(cfg.RegistryConfigs, registry)
+	return cfg.save()
+}
+
+// LookupRegistryConfig returns the command for a registry
+func LookupRegistryConfig(server string) (RegistryConfig, error) {
+	if !fileExists() {
+		return RegistryConfig{}, fmt.Errorf("config file not found")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return RegistryConfig{}, err
+	}
+
+	server = RegistryServer(server)
+	for _, v := range cfg.RegistryConfigs {
+		if server == v.Server {
+			return v, nil
+		}
+	}
+	return RegistryConfig{}, fmt.Errorf("no registry config found for %s", server)
+}
+
+// RemoveRegistryConfig deletes the command for a registry
+func RemoveRegistryConfig(server string) error {
+	if !fileExists() {
+		return fmt.Errorf("config file not found")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	server = RegistryServer(server)
+	for i, v := range cfg.RegistryConfigs {
+		if server == v.Server {
+			cfg.RegistryConfigs = append(cfg.RegistryConfigs[:i], cfg.RegistryConfigs[i+1:]...)
+			return cfg.save()
+		}
+	}
+	return fmt.Errorf("registry %s not found in config", server)
+}
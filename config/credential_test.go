@@ -0,0 +1,78 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_RegistryServer(t *testing.T) {
+	cases := map[string]string{
+		"https://index.docker.io/v1/": "index.docker.io",
+		"docker.io":                   "index.docker.io",
+		"ghcr.io":                     "ghcr.io",
+		"https://registry.local:5000": "registry.local:5000",
+		"registry.local:5000/team":    "registry.local:5000",
+	}
+	for server, want := range cases {
+		if got := RegistryServer(server); got != want {
+			t.Errorf("%s: want %s, got %s", server, want, got)
+		}
+	}
+}
+
+func Test_RegistryConfig_UpdateLookupRemove(t *testing.T) {
+	t.Setenv(ConfigLocationEnv, t.TempDir())
+
+	ghcr := RegistryConfig{Server: "ghcr.io", Exec: ExecConfig{Command: "vault-creds", Args: []string{"ghcr"}}}
+	if err := UpdateRegistryConfig(ghcr); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateRegistryConfig(RegistryConfig{Server: "https://index.docker.io/v1/", Exec: ExecConfig{Command: "hub-creds"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LookupRegistryConfig("ghcr.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, ghcr) {
+		t.Errorf("want: %v, got: %v", ghcr, got)
+	}
+
+	hub, err := LookupRegistryConfig("docker.io")
+	if err != nil || hub.Exec.Command != "hub-creds" {
+		t.Errorf("want the Docker Hub command by any of its names, got: %v, %v", hub, err)
+	}
+
+	if err := RemoveRegistryConfig("ghcr.io"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LookupRegistryConfig("ghcr.io"); err == nil {
+		t.Errorf("want an error for a removed registry")
+	}
+}
+
+func Test_UpdateExecAuthConfig(t *testing.T) {
+	t.Setenv(ConfigLocationEnv, t.TempDir())
+
+	gateway := "https://openfaas.example.com"
+	if err := UpdateAuthConfig(gateway, EncodeAuth("admin", "secret"), BasicAuthType); err != nil {
+		t.Fatal(err)
+	}
+
+	exec := ExecConfig{Command: "vault-creds", Args: []string{"--role", "dev"}}
+	if err := UpdateExecAuthConfig(gateway, exec); err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := LookupAuthConfig(gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Auth != ExecAuthType || len(auth.Token) > 0 || auth.Exec == nil || !reflect.DeepEqual(*auth.Exec, exec) {
+		t.Errorf("want the saved credentials replaced by the command, got: %+v", auth)
+	}
+	if got := auth.Exec.String(); got != "vault-creds --role dev" {
+		t.Errorf("want the command line, got: %s", got)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CurrentVersion is the version of the config file written by this release
+const CurrentVersion = 2
+
+// configMigration upgrades a config file to version To, from the one before.
+// Migrations work on the YAML document rather than ConfigFile, so that they
+// can move or rename fields which ConfigFile no longer has.
+type configMigration struct {
+	To      int
+	Migrate func(doc map[interface{}]interface{}) error
+}
+
+// configMigrations are run in order on load, a new version of the config
+// file only needs a migration appended here
+var configMigrations = []configMigration{
+	{To: 2, Migrate: normaliseGateways},
+}
+
+// documentVersion is 1 for files written before the version was recorded
+func documentVersion(doc map[interface{}]interface{}) (int, error) {
+	value, ok := doc["version"]
+	if !ok {
+		return 1, nil
+	}
+	version, ok := value.(int)
+	if !ok || version < 1 {
+		return 0, fmt.Errorf("invalid config file version: %v", value)
+	}
+	return version, nil
+}
+
+// migrate upgrades the document to CurrentVersion, and returns the version
+// which it was at. A document from a newer release is left as it is.
+func migrate(doc map[interface{}]interface{}) (int, error) {
+	from, err := documentVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	version := from
+	for _, migration := range configMigrations {
+		if migration.To <= version {
+			continue
+		}
+		if err := migration.Migrate(doc); err != nil {
+			return from, fmt.Errorf("unable to migrate the config file to version %d: %w", migration.To, err)
+		}
+		version = migration.To
+		doc["version"] = version
+	}
+
+	return from, nil
+}
+
+// backupConfig keeps a copy of the file from before it was migrated, a
+// backup from an earlier migration is not replaced
+func backupConfig(filePath string, data []byte, version int) error {
+	backup := fmt.Sprintf("%s.v%d.bak", filePath, version)
+	if _, err := os.Stat(backup); err == nil {
+		return nil
+	}
+	return os.WriteFile(backup, data, 0600)
+}
+
+// normaliseGateways writes each gateway as faas-cli looks it up, in lower
+// case without a trailing slash, so that entries saved by older releases are
+// found. Where that leaves two entries for a gateway, the last is kept.
+// Basic auth entries saved without their type are given it.
+func normaliseGateways(doc map[interface{}]interface{}) error {
+	for _, key := range []string{"auths", "connections"} {
+		entries, ok := doc[key].([]interface{})
+		if !ok {
+			continue
+		}
+
+		kept := []interface{}{}
+		index := map[string]int{}
+		for _, item := range entries {
+			entry, ok := item.(map[interface{}]interface{})
+			if !ok {
+				continue
+			}
+
+			gateway, _ := entry["gateway"].(string)
+			gateway = strings.ToLower(strings.TrimRight(gateway, "/"))
+			entry["gateway"] = gateway
+
+			if key == "auths" {
+				if auth, _ := entry["auth"].(string); len(auth) == 0 {
+					if token, _ := entry["token"].(string); len(token) > 0 {
+						if _, _, err := DecodeAuth(token); err == nil {
+							entry["auth"] = BasicAuthType
+						}
+					}
+				}
+			}
+
+			if i, ok := index[gateway]; ok {
+				kept[i] = entry
+				continue
+			}
+			index[gateway] = len(kept)
+			kept = append(kept, entry)
+		}
+		doc[key] = kept
+	}
+	return nil
+}
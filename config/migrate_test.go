@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_load_MigratesVersion1(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(ConfigLocationEnv, dir)
+
+	legacy := `auths:
+- gateway: HTTP://Gateway.Example.com:8080/
+  token: ` + EncodeAuth("admin", "old") + `
+- gateway: http://gateway.example.com:8080
+  token: ` + EncodeAuth("admin", "new") + `
+- gateway: https://iam.example.com
+  auth: oauth2
+  token: eyJhbGciOi.jwt
+connections:
+- gateway: https://iam.example.com/
+  proxy: direct
+`
+	configPath := filepath.Join(dir, DefaultFile)
+	os.WriteFile(configPath, []byte(legacy), 0600)
+
+	auth, err := LookupAuthConfig("http://gateway.example.com:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Auth != BasicAuthType {
+		t.Errorf("want the auth type set to basic, got: %q", auth.Auth)
+	}
+	if _, password, _ := DecodeAuth(auth.Token); password != "new" {
+		t.Errorf("want the last entry for the gateway kept, got: %s", password)
+	}
+
+	if connection, err := LookupConnectionConfig("https://iam.example.com"); err != nil || connection.Proxy != DirectProxy {
+		t.Errorf("want the connection found without its slash, got: %v, %v", connection, err)
+	}
+
+	migrated, _ := os.ReadFile(configPath)
+	if !strings.HasPrefix(string(migrated), "version: 2\n") || strings.Count(string(migrated), "gateway.example.com") != 1 {
+		t.Errorf("want the migrated file saved, got:\n%s", migrated)
+	}
+
+	backup, err := os.ReadFile(configPath + ".v1.bak")
+	if err != nil || string(backup) != legacy {
+		t.Errorf("want the original kept as a backup, got: %s, %v", backup, err)
+	}
+}
+
+func Test_load_NewerVersionIsReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(ConfigLocationEnv, dir)
+
+	os.WriteFile(filepath.Join(dir, DefaultFile), []byte(`version: 99
+contexts:
+- name: prod
+auths:
+- gateway: http://127.0.0.1:8080
+  auth: basic
+  token: `+EncodeAuth("admin", "secret")+`
+`), 0600)
+
+	if _, err := LookupAuthConfig("http://127.0.0.1:8080"); err != nil {
+		t.Fatalf("want a newer file to be read, got: %s", err)
+	}
+
+	err := UpdateAuthConfig("http://127.0.0.1:8081", "token", Oauth2AuthType)
+	if err == nil || !strings.Contains(err.Error(), "upgrade faas-cli") {
+		t.Fatalf("want saving a newer file refused, got: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, DefaultFile))
+	if !strings.Contains(string(data), "contexts:") {
+		t.Errorf("want the newer file left as it was, got:\n%s", data)
+	}
+}
+
+func Test_migrate_RunsInOrder(t *testing.T) {
+	saved := configMigrations
+	defer func() { configMigrations = saved }()
+
+	var ran []int
+	configMigrations = []configMigration{
+		{To: 2, Migrate: func(doc map[interface{}]interface{}) error { ran = append(ran, 2); return nil }},
+		{To: 3, Migrate: func(doc map[interface{}]interface{}) error { ran = append(ran, 3); return nil }},
+	}
+
+	doc := map[interface{}]interface{}{"version": 2}
+	from, err := migrate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != 2 || len(ran) != 1 || ran[0] != 3 || doc["version"] != 3 {
+		t.Fatalf("want only the migration to 3 run, got from: %d, ran: %v, doc: %v", from, ran, doc)
+	}
+}
+
+func Test_load_MigratesReadOnlyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(ConfigLocationEnv, dir)
+
+	legacy := `auths:
+- gateway: HTTP://Gateway.Example.com:8080/
+  token: ` + EncodeAuth("admin", "secret") + `
+`
+	configPath := filepath.Join(dir, DefaultFile)
+	os.WriteFile(configPath, []byte(legacy), 0400)
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0700) })
+
+	if probe, err := os.Create(filepath.Join(dir, "probe")); err == nil {
+		probe.Close()
+		t.Skip("the directory can be written to, such as by root")
+	}
+
+	auth, err := LookupAuthConfig("http://gateway.example.com:8080")
+	if err != nil {
+		t.Fatalf("want a read-only config migrated in memory, got: %s", err)
+	}
+	if _, password, _ := DecodeAuth(auth.Token); password != "secret" || auth.Auth != BasicAuthType {
+		t.Errorf("want the migrated entry found, got: %v", auth)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if string(data) != legacy {
+		t.Errorf("want the read-only file left as it was, got:\n%s", data)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// The maintenance responder is deployed by "faas-cli maintenance enable" in
+// place of a function, and answers every request with 503 Service Unavailable
+// and the message given by the maintenance_message environment variable.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultMessage = "This function is undergoing maintenance, please try again later."
+
+// retryAfter is sent to callers in seconds, so that clients which honour it
+// back off rather than retrying straight away
+const retryAfter = 60
+
+func newHandler(message string) http.Handler {
+	if len(message) == 0 {
+		message = defaultMessage
+	}
+
+	mux := http.NewServeMux()
+	// The health checks of the watchdog keep the placeholder ready
+	mux.HandleFunc("/_/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/_/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(message + "\n"))
+	})
+	return mux
+}
+
+func main() {
+	port := os.Getenv("port")
+	if len(port) == 0 {
+		port = "8080"
+	}
+
+	s := &http.Server{
+		Addr:              ":" + port,
+		Handler:           newHandler(os.Getenv("maintenance_message")),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Responding to requests with 503 on port %s", port)
+	log.Fatal(s.ListenAndServe())
+}
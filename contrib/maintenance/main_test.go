@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_newHandler_ServiceUnavailable(t *testing.T) {
+	s := httptest.NewServer(newHandler("Back at 14:00 UTC"))
+	defer s.Close()
+
+	res, err := http.Post(s.URL+"/function/figlet", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("want 503, got %d", res.StatusCode)
+	}
+	if string(body) != "Back at 14:00 UTC\n" {
+		t.Errorf("want the maintenance message, got: %q", body)
+	}
+	if res.Header.Get("Retry-After") == "" {
+		t.Errorf("want a Retry-After header")
+	}
+}
+
+func Test_newHandler_Healthy(t *testing.T) {
+	s := httptest.NewServer(newHandler(""))
+	defer s.Close()
+
+	res, err := http.Get(s.URL + "/_/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("want the health check to pass, got %d", res.StatusCode)
+	}
+}
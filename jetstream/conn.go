@@ -0,0 +1,335 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// Package jetstream is a minimal client for the parts of NATS JetStream that
+// faas-cli uses: reading the messages of a stream, and publishing a message
+// to be acknowledged by the stream which stores it. It speaks the NATS text
+// protocol over a single connection, and makes one request at a time.
+package jetstream
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the address of NATS when none is given
+const DefaultURL = "nats://127.0.0.1:4222"
+
+// ErrNoResponders is returned when nothing is subscribed to the subject of
+// a request, such as when JetStream is not enabled, or no stream stores the
+// subject a message is published to
+var ErrNoResponders = errors.New("no responders")
+
+// Msg is a message received on the connection
+type Msg struct {
+	Subject string
+	Header  http.Header
+	Data    []byte
+
+	// status is set by the server on replies such as no responders
+	status int
+}
+
+// Conn is a connection to a NATS server
+type Conn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	inbox   string
+	sid     int
+}
+
+type serverInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	Headers     bool `json:"headers"`
+}
+
+type connectOptions struct {
+	Verbose      bool   `json:"verbose"`
+	Pedantic     bool   `json:"pedantic"`
+	TLSRequired  bool   `json:"tls_required"`
+	Name         string `json:"name"`
+	Lang         string `json:"lang"`
+	Version      string `json:"version"`
+	Protocol     int    `json:"protocol"`
+	Headers      bool   `json:"headers"`
+	NoResponders bool   `json:"no_responders"`
+	User         string `json:"user,omitempty"`
+	Pass         string `json:"pass,omitempty"`
+	Token        string `json:"auth_token,omitempty"`
+}
+
+// Dial connects to the server at rawURL, such as nats://127.0.0.1:4222, with
+// a user and password, or a token as the user, when given in the URL. The
+// tls scheme, or a server which requires it, upgrades the connection to TLS.
+// Each operation on the connection must complete within timeout.
+func Dial(rawURL string, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || len(u.Hostname()) == 0 {
+		return nil, fmt.Errorf("invalid NATS URL %q, want a URL such as %s", rawURL, DefaultURL)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("invalid NATS URL %q, the scheme must be nats or tls", rawURL)
+	}
+
+	address := u.Host
+	if len(u.Port()) == 0 {
+		address = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{conn: conn, r: bufio.NewReader(conn), timeout: timeout, inbox: "_INBOX." + nuid()}
+	if err := c.handshake(u); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to connect to NATS at %s: %w", address, err)
+	}
+	return c, nil
+}
+
+func (c *Conn) handshake(u *url.URL) error {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting: %q", strings.TrimSpace(line))
+	}
+
+	var info serverInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		return fmt.Errorf("unable to read the server's INFO: %w", err)
+	}
+	if !info.Headers {
+		return fmt.Errorf("the server does not support headers, NATS 2.2 or newer is required")
+	}
+
+	useTLS := u.Scheme == "tls" || info.TLSRequired
+	if useTLS {
+		tlsConn := tls.Client(c.conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		c.conn = tlsConn
+		c.r = bufio.NewReader(tlsConn)
+	}
+
+	options := connectOptions{
+		TLSRequired:  useTLS,
+		Name:         "faas-cli",
+		Lang:         "go",
+		Version:      "1.0.0",
+		Protocol:     1,
+		Headers:      true,
+		NoResponders: true,
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			options.User, options.Pass = u.User.Username(), pass
+		} else {
+			options.Token = u.User.Username()
+		}
+	}
+
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return err
+	}
+
+	// The server replies to the PING once it has accepted the CONNECT
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return serverError(line)
+		}
+	}
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Request publishes data to subject, with an inbox to reply to, and waits for
+// the first reply
+func (c *Conn) Request(subject string, header http.Header, data []byte) (*Msg, error) {
+	c.sid++
+	sid := strconv.Itoa(c.sid)
+	reply := c.inbox + "." + sid
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	defer fmt.Fprintf(c.conn, "UNSUB %s\r\n", sid)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "SUB %s %s\r\n", reply, sid)
+	writePublish(&buf, subject, reply, header, data)
+	if _, err := c.conn.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, msgSid, err := c.next()
+		if err != nil {
+			if isTimeout(err) {
+				return nil, fmt.Errorf("no reply to %s within %s", subject, c.timeout)
+			}
+			return nil, err
+		}
+		if msgSid != sid {
+			continue
+		}
+		if msg.status == http.StatusServiceUnavailable && len(msg.Data) == 0 {
+			return nil, ErrNoResponders
+		}
+		return msg, nil
+	}
+}
+
+// writePublish writes PUB, or HPUB when there are headers
+func writePublish(w io.Writer, subject, reply string, header http.Header, data []byte) {
+	if len(header) == 0 {
+		fmt.Fprintf(w, "PUB %s %s %d\r\n", subject, reply, len(data))
+	} else {
+		var block bytes.Buffer
+		block.WriteString("NATS/1.0\r\n")
+		for name, values := range header {
+			for _, value := range values {
+				fmt.Fprintf(&block, "%s: %s\r\n", name, value)
+			}
+		}
+		block.WriteString("\r\n")
+		fmt.Fprintf(w, "HPUB %s %s %d %d\r\n%s", subject, reply, block.Len(), block.Len()+len(data), block.Bytes())
+	}
+	w.Write(data)
+	io.WriteString(w, "\r\n")
+}
+
+// next reads operations until a message, answering the server's PINGs
+func (c *Conn) next() (*Msg, string, error) {
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, "", err
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "PING":
+			if _, err := io.WriteString(c.conn, "PONG\r\n"); err != nil {
+				return nil, "", err
+			}
+		case line == "PONG", line == "+OK", line == "":
+		case strings.HasPrefix(line, "-ERR"):
+			return nil, "", serverError(line)
+		case strings.HasPrefix(line, "MSG "), strings.HasPrefix(line, "HMSG "):
+			return c.readMsg(line)
+		default:
+			return nil, "", fmt.Errorf("unexpected operation from the server: %q", line)
+		}
+	}
+}
+
+// readMsg reads the payload of MSG <subject> <sid> [reply] <size> or
+// HMSG <subject> <sid> [reply] <header size> <total size>
+func (c *Conn) readMsg(line string) (*Msg, string, error) {
+	fields := strings.Fields(line)
+	withHeader := fields[0] == "HMSG"
+
+	want := 4
+	if withHeader {
+		want = 5
+	}
+	if len(fields) != want && len(fields) != want+1 {
+		return nil, "", fmt.Errorf("malformed message from the server: %q", line)
+	}
+
+	total, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return nil, "", fmt.Errorf("malformed message from the server: %q", line)
+	}
+	headerSize := 0
+	if withHeader {
+		if headerSize, err = strconv.Atoi(fields[len(fields)-2]); err != nil || headerSize > total {
+			return nil, "", fmt.Errorf("malformed message from the server: %q", line)
+		}
+	}
+
+	payload := make([]byte, total+2)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return nil, "", err
+	}
+
+	msg := &Msg{Subject: fields[1], Data: payload[headerSize:total]}
+	if withHeader {
+		if msg.Header, msg.status, err = parseHeader(payload[:headerSize]); err != nil {
+			return nil, "", err
+		}
+	}
+	return msg, fields[2], nil
+}
+
+// parseHeader reads a block of NATS/1.0 [status [description]] followed by
+// MIME style headers
+func parseHeader(block []byte) (http.Header, int, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(block)))
+	first, err := r.ReadLine()
+	if err != nil || !strings.HasPrefix(first, "NATS/1.0") {
+		return nil, 0, fmt.Errorf("malformed headers from the server: %q", first)
+	}
+
+	status := 0
+	if fields := strings.Fields(first); len(fields) > 1 {
+		status, _ = strconv.Atoi(fields[1])
+	}
+
+	header, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, 0, fmt.Errorf("malformed headers from the server: %w", err)
+	}
+	return http.Header(header), status, nil
+}
+
+func serverError(line string) error {
+	return fmt.Errorf("server error: %s", strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// nuid is a random suffix for the connection's inbox
+func nuid() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package jetstream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// errCodeNoMessage is the JetStream error code for a sequence or subject
+// which has no message
+const errCodeNoMessage = 10037
+
+// ErrNoMessage is returned by GetMsg when there is no message to return
+var ErrNoMessage = errors.New("no message found")
+
+// APIError is an error returned by the JetStream API
+type APIError struct {
+	Code        int    `json:"code"`
+	ErrCode     int    `json:"err_code"`
+	Description string `json:"description"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Description, e.ErrCode)
+}
+
+// StoredMsg is a message as it is stored in a stream
+type StoredMsg struct {
+	Sequence uint64
+	Subject  string
+	Header   http.Header
+	Data     []byte
+	Time     time.Time
+}
+
+type msgGetRequest struct {
+	Seq        uint64 `json:"seq,omitempty"`
+	NextBySubj string `json:"next_by_subj,omitempty"`
+}
+
+type msgGetResponse struct {
+	Message *struct {
+		Subject string    `json:"subject"`
+		Seq     uint64    `json:"seq"`
+		Header  []byte    `json:"hdrs,omitempty"`
+		Data    []byte    `json:"data,omitempty"`
+		Time    time.Time `json:"time"`
+	} `json:"message,omitempty"`
+	Error *APIError `json:"error,omitempty"`
+}
+
+// PubAck is the stream's acknowledgement of a published message
+type PubAck struct {
+	Stream    string    `json:"stream"`
+	Sequence  uint64    `json:"seq"`
+	Duplicate bool      `json:"duplicate,omitempty"`
+	Error     *APIError `json:"error,omitempty"`
+}
+
+// GetMsg returns the first message of the stream at or after seq whose
+// subject matches subject, which may contain wildcards such as >. When none
+// is left, ErrNoMessage is returned.
+func (c *Conn) GetMsg(stream string, seq uint64, subject string) (*StoredMsg, error) {
+	body, err := json.Marshal(msgGetRequest{Seq: seq, NextBySubj: subject})
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.Request("$JS.API.STREAM.MSG.GET."+stream, nil, body)
+	if err != nil {
+		if errors.Is(err, ErrNoResponders) {
+			return nil, fmt.Errorf("JetStream is not enabled on the server")
+		}
+		return nil, err
+	}
+
+	var res msgGetResponse
+	if err := json.Unmarshal(reply.Data, &res); err != nil {
+		return nil, fmt.Errorf("unable to read the message from stream %s: %w", stream, err)
+	}
+	if res.Error != nil {
+		if res.Error.ErrCode == errCodeNoMessage {
+			return nil, ErrNoMessage
+		}
+		return nil, fmt.Errorf("unable to get a message from stream %s: %w", stream, res.Error)
+	}
+	if res.Message == nil {
+		return nil, fmt.Errorf("unable to get a message from stream %s: empty response", stream)
+	}
+
+	msg := &StoredMsg{
+		Sequence: res.Message.Seq,
+		Subject:  res.Message.Subject,
+		Data:     res.Message.Data,
+		Time:     res.Message.Time,
+	}
+	if len(res.Message.Header) > 0 {
+		if msg.Header, _, err = parseHeader(res.Message.Header); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// Messages calls fn with each message of the stream whose subject matches
+// subject, in order, until there are none left or fn returns an error
+func (c *Conn) Messages(stream, subject string, fn func(*StoredMsg) error) error {
+	if len(subject) == 0 {
+		subject = ">"
+	}
+
+	for seq := uint64(1); ; {
+		msg, err := c.GetMsg(stream, seq, subject)
+		if errors.Is(err, ErrNoMessage) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+		seq = msg.Sequence + 1
+	}
+}
+
+// Publish publishes a message to a subject stored by a stream, and waits for
+// the stream to acknowledge it
+func (c *Conn) Publish(subject string, header http.Header, data []byte) (*PubAck, error) {
+	reply, err := c.Request(subject, header, data)
+	if err != nil {
+		if errors.Is(err, ErrNoResponders) {
+			return nil, fmt.Errorf("no stream stores the subject %s", subject)
+		}
+		return nil, err
+	}
+
+	var ack PubAck
+	if err := json.Unmarshal(reply.Data, &ack); err != nil {
+		return nil, fmt.Errorf("unable to read the acknowledgement of %s: %w", subject, err)
+	}
+	if ack.Error != nil {
+		return nil, fmt.Errorf("unable to publish to %s: %w", subject, ack.Error)
+	}
+	return &ack, nil
+}
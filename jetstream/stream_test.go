@@ -0,0 +1,242 @@
+package jetstream
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer speaks enough of the NATS protocol to answer the requests of
+// the client, with messages stored in one stream
+type fakeServer struct {
+	listener net.Listener
+	stream   string
+	stored   []fakeMsg
+
+	mu        sync.Mutex
+	published []fakeMsg
+	connect   string
+}
+
+type fakeMsg struct {
+	seq     uint64
+	subject string
+	header  string
+	data    string
+}
+
+func newFakeServer(t *testing.T, stream string, stored []fakeMsg) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeServer{listener: listener, stream: stream, stored: stored}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	io.WriteString(conn, `INFO {"server_id":"fake","headers":true,"jetstream":true}`+"\r\n")
+
+	subs := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "CONNECT":
+			s.mu.Lock()
+			s.connect = strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+			s.mu.Unlock()
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "SUB":
+			subs[fields[1]] = fields[2]
+		case "PUB", "HPUB":
+			headerSize, total := 0, 0
+			if fields[0] == "PUB" {
+				total, _ = strconv.Atoi(fields[3])
+			} else {
+				headerSize, _ = strconv.Atoi(fields[3])
+				total, _ = strconv.Atoi(fields[4])
+			}
+			payload := make([]byte, total+2)
+			io.ReadFull(r, payload)
+
+			msg := fakeMsg{subject: fields[1], header: string(payload[:headerSize]), data: string(payload[headerSize:total])}
+			s.reply(conn, subs[fields[2]], fields[2], msg)
+		}
+	}
+}
+
+func (s *fakeServer) reply(conn net.Conn, sid, inbox string, msg fakeMsg) {
+	var body []byte
+	switch {
+	case msg.subject == "$JS.API.STREAM.MSG.GET."+s.stream:
+		var req msgGetRequest
+		json.Unmarshal([]byte(msg.data), &req)
+		body = s.get(req)
+	case msg.subject == "faas-request":
+		s.mu.Lock()
+		s.published = append(s.published, msg)
+		seq := len(s.published)
+		s.mu.Unlock()
+		body, _ = json.Marshal(PubAck{Stream: "faas-request", Sequence: uint64(seq)})
+	default:
+		header := "NATS/1.0 503\r\n\r\n"
+		fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", inbox, sid, len(header), len(header), header)
+		return
+	}
+	fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", inbox, sid, len(body), body)
+}
+
+func (s *fakeServer) get(req msgGetRequest) []byte {
+	for _, stored := range s.stored {
+		if stored.seq < req.Seq {
+			continue
+		}
+		if req.NextBySubj != ">" && req.NextBySubj != stored.subject {
+			continue
+		}
+
+		message := map[string]interface{}{
+			"subject": stored.subject,
+			"seq":     stored.seq,
+			"data":    base64.StdEncoding.EncodeToString([]byte(stored.data)),
+			"time":    time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC),
+		}
+		if stored.header != "" {
+			message["hdrs"] = base64.StdEncoding.EncodeToString([]byte(stored.header))
+		}
+		body, _ := json.Marshal(map[string]interface{}{"message": message})
+		return body
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"error": APIError{Code: 404, ErrCode: errCodeNoMessage, Description: "no message found"}})
+	return body
+}
+
+func Test_Messages(t *testing.T) {
+	s := newFakeServer(t, "dead-letters", []fakeMsg{
+		{seq: 2, subject: "dead.figlet", header: "NATS/1.0\r\nX-Dead-Letter-Reason: timeout\r\n\r\n", data: "one"},
+		{seq: 5, subject: "dead.env", data: "two"},
+		{seq: 9, subject: "dead.figlet", data: "three"},
+	})
+
+	conn, err := Dial(s.url(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var got []string
+	err = conn.Messages("dead-letters", "", func(msg *StoredMsg) error {
+		got = append(got, fmt.Sprintf("%d %s %s %s", msg.Sequence, msg.Subject, msg.Data, msg.Header.Get("X-Dead-Letter-Reason")))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"2 dead.figlet one timeout", "5 dead.env two ", "9 dead.figlet three "}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("want: %q, got: %q", want, got)
+	}
+
+	got = nil
+	conn.Messages("dead-letters", "dead.figlet", func(msg *StoredMsg) error {
+		got = append(got, string(msg.Data))
+		return nil
+	})
+	if strings.Join(got, ",") != "one,three" {
+		t.Fatalf("want the messages of the subject, got: %q", got)
+	}
+}
+
+func Test_GetMsg_NoStream(t *testing.T) {
+	s := newFakeServer(t, "dead-letters", nil)
+
+	conn, err := Dial(s.url(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.GetMsg("dead-letters", 1, ">"); err != ErrNoMessage {
+		t.Errorf("want ErrNoMessage from an empty stream, got: %v", err)
+	}
+	if _, err := conn.GetMsg("missing", 1, ">"); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("want an error without responders, got: %v", err)
+	}
+}
+
+func Test_Publish(t *testing.T) {
+	s := newFakeServer(t, "dead-letters", nil)
+
+	conn, err := Dial(strings.Replace(s.url(), "nats://", "nats://s3cr3t@", 1), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ack, err := conn.Publish("faas-request", http.Header{"Nats-Msg-Id": []string{"resubmit-2"}}, []byte(`{"Function":"figlet"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.Stream != "faas-request" || ack.Sequence != 1 {
+		t.Errorf("unexpected ack: %+v", ack)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.published) != 1 || s.published[0].data != `{"Function":"figlet"}` || !strings.Contains(s.published[0].header, "Nats-Msg-Id: resubmit-2") {
+		t.Errorf("unexpected message published: %+v", s.published)
+	}
+	if !strings.Contains(s.connect, `"auth_token":"s3cr3t"`) {
+		t.Errorf("want the token from the URL sent with CONNECT, got: %s", s.connect)
+	}
+
+	if _, err := conn.Publish("unknown", nil, []byte("x")); err == nil || !strings.Contains(err.Error(), "no stream stores") {
+		t.Errorf("want an error for a subject without a stream, got: %v", err)
+	}
+}
+
+func Test_Dial_InvalidURL(t *testing.T) {
+	for _, rawURL := range []string{"http://127.0.0.1:4222", "nats://"} {
+		if _, err := Dial(rawURL, time.Second); err == nil || !strings.Contains(err.Error(), "invalid NATS URL") {
+			t.Errorf("%s: want an invalid URL error, got: %v", rawURL, err)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// Package mockgateway provides an in-memory implementation of the core
+// OpenFaaS gateway and provider API. Functions are never started, invoking
+// one returns the request body, which is enough to exercise deploy, list,
+// describe, invoke, logs and secret workflows without a cluster.
+package mockgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-provider/logs"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+// DefaultNamespace is used when a request does not give a namespace
+const DefaultNamespace = "openfaas-fn"
+
+// Server is an in-memory OpenFaaS gateway
+type Server struct {
+	mux *http.ServeMux
+
+	mu        sync.RWMutex
+	functions map[string]*types.FunctionStatus
+	secrets   map[string]types.Secret
+	logs      map[string][]logs.Message
+
+	// Now is used for timestamps and can be overridden by tests
+	Now func() time.Time
+}
+
+// NewServer creates an empty gateway
+func NewServer() *Server {
+	s := &Server{
+		mux:       http.NewServeMux(),
+		functions: map[string]*types.FunctionStatus{},
+		secrets:   map[string]types.Secret{},
+		logs:      map[string][]logs.Message{},
+		Now:       time.Now,
+	}
+
+	s.mux.HandleFunc("/healthz", s.handleHealthz)
+	s.mux.HandleFunc("/system/info", s.handleInfo)
+	s.mux.HandleFunc("/system/functions", s.handleFunctions)
+	s.mux.HandleFunc("/system/function/", s.handleFunction)
+	s.mux.HandleFunc("/system/scale-function/", s.handleScale)
+	s.mux.HandleFunc("/system/namespaces", s.handleNamespaces)
+	s.mux.HandleFunc("/system/secrets", s.handleSecrets)
+	s.mux.HandleFunc("/system/logs", s.handleLogs)
+	s.mux.HandleFunc("/function/", s.handleInvoke)
+	s.mux.HandleFunc("/async-function/", s.handleInvoke)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func key(name, namespace string) string {
+	if len(namespace) == 0 {
+		namespace = DefaultNamespace
+	}
+	return name + "." + namespace
+}
+
+func namespaceOf(r *http.Request) string {
+	if ns := r.URL.Query().Get("namespace"); len(ns) > 0 {
+		return ns
+	}
+	return DefaultNamespace
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"provider": types.ProviderInfo{
+			Name:          "mock-gateway",
+			Orchestration: "in-memory",
+			Version:       &types.VersionInfo{Release: "dev", SHA: "dev"},
+		},
+		"version": types.VersionInfo{Release: "dev", SHA: "dev"},
+		"arch":    "mock",
+	})
+}
+
+func (s *Server) handleNamespaces(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := map[string]bool{DefaultNamespace: true}
+	for _, fn := range s.functions {
+		found[fn.Namespace] = true
+	}
+
+	namespaces := []string{}
+	for ns := range found {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	writeJSON(w, http.StatusOK, namespaces)
+}
+
+func (s *Server) handleFunctions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.listFunctions(w, r)
+	case http.MethodPost, http.MethodPut:
+		s.deployFunction(w, r)
+	case http.MethodDelete:
+		s.deleteFunction(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) listFunctions(w http.ResponseWriter, r *http.Request) {
+	namespace := namespaceOf(r)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	functions := []types.FunctionStatus{}
+	for _, fn := range s.functions {
+		if fn.Namespace == namespace {
+			functions = append(functions, *fn)
+		}
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, functions)
+}
+
+func (s *Server) deployFunction(w http.ResponseWriter, r *http.Request) {
+	var req types.FunctionDeployment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid deployment: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Service) == 0 || len(req.Image) == 0 {
+		http.Error(w, "service and image are required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Namespace) == 0 {
+		req.Namespace = DefaultNamespace
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key(req.Service, req.Namespace)
+	existing, exists := s.functions[k]
+
+	if r.Method == http.MethodPut && !exists {
+		http.Error(w, fmt.Sprintf("function %s not found", req.Service), http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPost && exists {
+		http.Error(w, fmt.Sprintf("function %s already exists", req.Service), http.StatusConflict)
+		return
+	}
+
+	status := &types.FunctionStatus{
+		Name:                   req.Service,
+		Image:                  req.Image,
+		Namespace:              req.Namespace,
+		EnvProcess:             req.EnvProcess,
+		EnvVars:                req.EnvVars,
+		Constraints:            req.Constraints,
+		Secrets:                req.Secrets,
+		Labels:                 req.Labels,
+		Annotations:            req.Annotations,
+		Limits:                 req.Limits,
+		Requests:               req.Requests,
+		ReadOnlyRootFilesystem: req.ReadOnlyRootFilesystem,
+		Replicas:               1,
+		AvailableReplicas:      1,
+		CreatedAt:              s.Now(),
+	}
+
+	if exists {
+		status.InvocationCount = existing.InvocationCount
+		status.Replicas = existing.Replicas
+		status.AvailableReplicas = existing.AvailableReplicas
+	}
+
+	s.functions[k] = status
+	s.appendLog(status.Name, status.Namespace, fmt.Sprintf("Deployed image: %s", status.Image))
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) deleteFunction(w http.ResponseWriter, r *http.Request) {
+	var req types.DeleteFunctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key(req.FunctionName, namespaceOf(r))
+	if _, ok := s.functions[k]; !ok {
+		http.Error(w, fmt.Sprintf("function %s not found", req.FunctionName), http.StatusNotFound)
+		return
+	}
+
+	delete(s.functions, k)
+	delete(s.logs, k)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleFunction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/system/function/")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fn, ok := s.functions[key(name, namespaceOf(r))]
+	if !ok {
+		http.Error(w, fmt.Sprintf("function %s not found", name), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, fn)
+}
+
+func (s *Server) handleScale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/system/scale-function/")
+
+	var req types.ScaleServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn, ok := s.functions[key(name, namespaceOf(r))]
+	if !ok {
+		http.Error(w, fmt.Sprintf("function %s not found", name), http.StatusNotFound)
+		return
+	}
+
+	fn.Replicas = req.Replicas
+	fn.AvailableReplicas = req.Replicas
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleSecrets(w http.ResponseWriter, r *http.Request) {
+	namespace := namespaceOf(r)
+
+	if r.Method == http.MethodGet {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		secrets := []types.Secret{}
+		for _, secret := range s.secrets {
+			if secret.Namespace == namespace {
+				secrets = append(secrets, types.Secret{Name: secret.Name, Namespace: secret.Namespace})
+			}
+		}
+		sort.Slice(secrets, func(i, j int) bool {
+			return secrets[i].Name < secrets[j].Name
+		})
+
+		writeJSON(w, http.StatusOK, secrets)
+		return
+	}
+
+	var secret types.Secret
+	if err := json.NewDecoder(r.Body).Decode(&secret); err != nil {
+		http.Error(w, fmt.Sprintf("invalid secret: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(secret.Namespace) == 0 {
+		secret.Namespace = namespace
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key(secret.Name, secret.Namespace)
+	_, exists := s.secrets[k]
+
+	switch r.Method {
+	case http.MethodPost:
+		if exists {
+			http.Error(w, fmt.Sprintf("secret %s already exists", secret.Name), http.StatusConflict)
+			return
+		}
+		s.secrets[k] = secret
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPut:
+		if !exists {
+			http.Error(w, fmt.Sprintf("secret %s not found", secret.Name), http.StatusNotFound)
+			return
+		}
+		s.secrets[k] = secret
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodDelete:
+		if !exists {
+			http.Error(w, fmt.Sprintf("secret %s not found", secret.Name), http.StatusNotFound)
+			return
+		}
+		delete(s.secrets, k)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLogs returns the recorded messages for a function as newline delimited
+// JSON. Follow is accepted but the stream always ends after the last message.
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	name := q.Get("name")
+
+	s.mu.RLock()
+	messages := append([]logs.Message{}, s.logs[key(name, namespaceOf(r))]...)
+	_, exists := s.functions[key(name, namespaceOf(r))]
+	s.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, fmt.Sprintf("function %s not found", name), http.StatusNotFound)
+		return
+	}
+
+	if since := q.Get("since"); len(since) > 0 {
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		filtered := []logs.Message{}
+		for _, msg := range messages {
+			if !msg.Timestamp.Before(sinceTime) {
+				filtered = append(filtered, msg)
+			}
+		}
+		messages = filtered
+	}
+
+	if tail, err := strconv.Atoi(q.Get("tail")); err == nil && tail > 0 && tail < len(messages) {
+		messages = messages[len(messages)-tail:]
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	for _, msg := range messages {
+		encoder.Encode(msg)
+	}
+}
+
+// handleInvoke echoes the request body back to the caller for synchronous
+// invocations, and accepts asynchronous invocations without a response body.
+func (s *Server) handleInvoke(w http.ResponseWriter, r *http.Request) {
+	async := strings.HasPrefix(r.URL.Path, "/async-function/")
+
+	ref := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/async-function/"), "/function/")
+	if i := strings.Index(ref, "/"); i > -1 {
+		ref = ref[:i]
+	}
+
+	name, namespace := ref, DefaultNamespace
+	if i := strings.Index(ref, "."); i > -1 {
+		name, namespace = ref[:i], ref[i+1:]
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mu.Lock()
+	fn, ok := s.functions[key(name, namespace)]
+	if ok {
+		fn.InvocationCount++
+		s.appendLog(name, namespace, fmt.Sprintf("%s %s - %d bytes", r.Method, r.URL.Path, len(body)))
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("error finding function %s: not found", ref), http.StatusNotFound)
+		return
+	}
+
+	start := s.Now()
+	w.Header().Set("X-Call-Id", fmt.Sprintf("%d", start.UnixNano()))
+	w.Header().Set("X-Duration-Seconds", "0.000000")
+
+	if async {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if contentType := r.Header.Get("Content-Type"); len(contentType) > 0 {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// appendLog must be called with the lock held
+func (s *Server) appendLog(name, namespace, text string) {
+	k := key(name, namespace)
+	s.logs[k] = append(s.logs[k], logs.Message{
+		Name:      name,
+		Namespace: namespace,
+		Instance:  name + "-mock",
+		Timestamp: s.Now(),
+		Text:      text,
+	})
+}
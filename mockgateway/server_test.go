@@ -0,0 +1,263 @@
+package mockgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-provider/logs"
+	types "github.com/openfaas/faas-provider/types"
+)
+
+func newTestClient(t *testing.T) (*proxy.Client, *httptest.Server) {
+	t.Helper()
+
+	srv := httptest.NewServer(NewServer())
+	t.Cleanup(srv.Close)
+
+	auth, err := proxy.NewCLIAuth("", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := 5 * time.Second
+	client, err := proxy.NewClient(auth, srv.URL, nil, &timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, srv
+}
+
+func Test_DeployListAndDescribe(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	spec := &proxy.DeployFunctionSpec{
+		FunctionName: "figlet",
+		Image:        "ghcr.io/openfaas/figlet:latest",
+		EnvVars:      map[string]string{"write_debug": "true"},
+		Update:       true,
+	}
+
+	if status := client.DeployFunction(ctx, spec); status != http.StatusAccepted {
+		t.Fatalf("want status %d for a new function, got %d", http.StatusAccepted, status)
+	}
+
+	spec.Image = "ghcr.io/openfaas/figlet:0.2.0"
+	if status := client.DeployFunction(ctx, spec); status != http.StatusAccepted {
+		t.Fatalf("want status %d for an update, got %d", http.StatusAccepted, status)
+	}
+
+	functions, err := client.ListFunctions(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(functions) != 1 {
+		t.Fatalf("want 1 function, got %d", len(functions))
+	}
+
+	fn, err := client.GetFunctionInfo(ctx, "figlet", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fn.Image != spec.Image {
+		t.Errorf("want image %s, got %s", spec.Image, fn.Image)
+	}
+
+	if fn.EnvVars["write_debug"] != "true" {
+		t.Errorf("want env write_debug=true, got %v", fn.EnvVars)
+	}
+
+	if _, err := client.GetFunctionInfo(ctx, "missing", ""); err == nil {
+		t.Errorf("want an error for a missing function")
+	}
+}
+
+func Test_ListFunctions_FiltersByNamespace(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	client.DeployFunction(ctx, &proxy.DeployFunctionSpec{FunctionName: "a", Image: "a:latest", Update: true})
+	client.DeployFunction(ctx, &proxy.DeployFunctionSpec{FunctionName: "b", Image: "b:latest", Update: true, Namespace: "staging"})
+
+	functions, err := client.ListFunctions(ctx, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(functions) != 1 || functions[0].Name != "b" {
+		t.Fatalf("want only function b in staging, got %v", functions)
+	}
+
+	namespaces, err := client.ListNamespaces(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(namespaces) != 2 || namespaces[0] != DefaultNamespace || namespaces[1] != "staging" {
+		t.Fatalf("want namespaces [%s staging], got %v", DefaultNamespace, namespaces)
+	}
+}
+
+func Test_InvokeEchoesBodyAndCounts(t *testing.T) {
+	client, srv := newTestClient(t)
+	ctx := context.Background()
+
+	client.DeployFunction(ctx, &proxy.DeployFunctionSpec{FunctionName: "echo", Image: "echo:latest", Update: true})
+
+	res, err := http.Post(srv.URL+"/function/echo."+DefaultNamespace, "text/plain", bytes.NewBufferString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatalf("want 200 and hello, got %d and %q", res.StatusCode, string(body))
+	}
+
+	res, err = http.Post(srv.URL+"/async-function/echo", "text/plain", bytes.NewBufferString("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("want 202 for an async invocation, got %d", res.StatusCode)
+	}
+
+	fn, err := client.GetFunctionInfo(ctx, "echo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fn.InvocationCount != 2 {
+		t.Errorf("want 2 invocations, got %v", fn.InvocationCount)
+	}
+
+	res, err = http.Get(srv.URL + "/function/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("want 404 for a missing function, got %d", res.StatusCode)
+	}
+}
+
+func Test_LogsTail(t *testing.T) {
+	client, srv := newTestClient(t)
+	ctx := context.Background()
+
+	client.DeployFunction(ctx, &proxy.DeployFunctionSpec{FunctionName: "echo", Image: "echo:latest", Update: true})
+	for i := 0; i < 3; i++ {
+		res, err := http.Get(srv.URL + "/function/echo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	messages, err := client.GetLogs(ctx, logs.Request{Name: "echo", Tail: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []logs.Message{}
+	for msg := range messages {
+		got = append(got, msg)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("want 2 messages, got %d: %v", len(got), got)
+	}
+
+	for _, msg := range got {
+		if msg.Name != "echo" || msg.Namespace != DefaultNamespace {
+			t.Errorf("want messages for echo.%s, got %s.%s", DefaultNamespace, msg.Name, msg.Namespace)
+		}
+	}
+}
+
+func Test_ScaleAndDelete(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	client.DeployFunction(ctx, &proxy.DeployFunctionSpec{FunctionName: "echo", Image: "echo:latest", Update: true})
+
+	if err := client.ScaleFunction(ctx, "echo", "", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	fn, _ := client.GetFunctionInfo(ctx, "echo", "")
+	if fn.Replicas != 3 {
+		t.Errorf("want 3 replicas, got %d", fn.Replicas)
+	}
+
+	if err := client.DeleteFunction(ctx, "echo", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.DeleteFunction(ctx, "echo", ""); err == nil {
+		t.Errorf("want an error deleting a missing function")
+	}
+}
+
+func Test_Secrets(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	secret := types.Secret{Name: "api-key", Value: "s3cr3t"}
+
+	if status, _ := client.CreateSecret(ctx, secret); status != http.StatusCreated {
+		t.Fatalf("want status %d, got %d", http.StatusCreated, status)
+	}
+
+	if status, _ := client.CreateSecret(ctx, secret); status != http.StatusConflict {
+		t.Fatalf("want status %d for a duplicate, got %d", http.StatusConflict, status)
+	}
+
+	secrets, err := client.GetSecretList(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(secrets) != 1 || secrets[0].Name != "api-key" || len(secrets[0].Value) > 0 {
+		t.Fatalf("want api-key listed without its value, got %v", secrets)
+	}
+
+	if err := client.RemoveSecret(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_SystemInfo(t *testing.T) {
+	_, srv := newTestClient(t)
+
+	res, err := http.Get(srv.URL + "/system/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	info := map[string]json.RawMessage{}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"provider", "version", "arch"} {
+		if _, ok := info[key]; !ok {
+			t.Errorf("want %s in system info", key)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package output
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration rounds d to the two most significant units, e.g. 1.2s or 3m05s
+func Duration(d time.Duration) string {
+	if Plain() {
+		return d.String()
+	}
+
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+}
+
+// Age describes how long before now t was, in its largest unit, e.g. 3d ago,
+// a script reading the output gets the time instead
+func Age(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	if !Humanized() {
+		return t.Format(time.RFC3339)
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours())/24)
+}
+
+// Size formats a number of bytes with binary units, e.g. 1.5 MiB
+func Size(bytes float64) string {
+	if Plain() {
+		return strconv.FormatFloat(bytes, 'f', 0, 64)
+	}
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%.0f %s", bytes, units[unit])
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[unit])
+}
+
+// Count groups the digits of n by thousands, with the separator used by the
+// locale in LC_ALL, LC_NUMERIC or LANG, a script reading the output gets the
+// digits alone
+func Count(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	if !Humanized() {
+		return digits
+	}
+
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	separator := thousandsSeparator(locale())
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(separator)
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
+
+func locale() string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if value := os.Getenv(name); len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
+
+// thousandsSeparator picks the separator for a locale such as de_DE.UTF-8,
+// from the language alone, as regional variations are rare
+func thousandsSeparator(locale string) string {
+	language := strings.ToLower(locale)
+	if i := strings.IndexAny(language, "_.@-"); i > -1 {
+		language = language[:i]
+	}
+
+	switch language {
+	case "de", "es", "it", "nl", "pt", "da", "id", "tr", "el":
+		return "."
+	case "fr", "sv", "nb", "nn", "fi", "pl", "cs", "sk", "ru", "uk", "hu":
+		return " "
+	}
+	return ","
+}
@@ -0,0 +1,103 @@
+package output
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Duration(t *testing.T) {
+	cases := map[time.Duration]string{
+		350 * time.Millisecond:                        "350ms",
+		1240 * time.Millisecond:                       "1.2s",
+		3*time.Minute + 5*time.Second:                 "3m05s",
+		2*time.Hour + 7*time.Minute:                   "2h07m",
+		50*time.Hour + 30*time.Minute:                 "2d2h",
+		time.Minute + 500*time.Millisecond:            "1m00s",
+		23*time.Hour + 59*time.Minute + 1*time.Second: "23h59m",
+	}
+
+	for d, want := range cases {
+		if got := Duration(d); got != want {
+			t.Errorf("%s: want %q, got %q", d, want, got)
+		}
+	}
+
+	Configure(false, true)
+	defer Configure(false, false)
+	if got := Duration(1240 * time.Millisecond); got != "1.24s" {
+		t.Errorf("plain: want the full duration, got %q", got)
+	}
+}
+
+func Test_Age(t *testing.T) {
+	withTerminal(t, true)
+
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[time.Duration]string{
+		30 * time.Second: "30s ago",
+		5 * time.Minute:  "5m ago",
+		3 * time.Hour:    "3h ago",
+		80 * time.Hour:   "3d ago",
+	}
+
+	for d, want := range cases {
+		if got := Age(now.Add(-d), now); got != want {
+			t.Errorf("%s: want %q, got %q", d, want, got)
+		}
+	}
+
+	if got := Age(time.Time{}, now); got != "-" {
+		t.Errorf("zero time: want -, got %q", got)
+	}
+
+	Configure(false, true)
+	defer Configure(false, false)
+	if got := Age(now, now); got != "2023-06-01T12:00:00Z" {
+		t.Errorf("plain: want RFC3339, got %q", got)
+	}
+}
+
+func Test_Size(t *testing.T) {
+	cases := map[float64]string{
+		512:                "512 B",
+		1536:               "1.5 KiB",
+		1024 * 1024 * 1024: "1.0 GiB",
+	}
+
+	for bytes, want := range cases {
+		if got := Size(bytes); got != want {
+			t.Errorf("%.0f: want %q, got %q", bytes, want, got)
+		}
+	}
+}
+
+func Test_Count(t *testing.T) {
+	withTerminal(t, true)
+
+	cases := []struct {
+		locale string
+		n      int64
+		want   string
+	}{
+		{"", 999, "999"},
+		{"", 999999, "999,999"},
+		{"en_US.UTF-8", -1234567, "-1,234,567"},
+		{"de_DE.UTF-8", 1234567, "1.234.567"},
+		{"fr_FR", 1234567, "1 234 567"},
+	}
+
+	for _, c := range cases {
+		t.Setenv("LC_ALL", "")
+		t.Setenv("LC_NUMERIC", "")
+		t.Setenv("LANG", c.locale)
+
+		if got := Count(c.n); got != c.want {
+			t.Errorf("%s %d: want %q, got %q", c.locale, c.n, c.want, got)
+		}
+	}
+	// A script reading the output gets the digits alone
+	withTerminal(t, false)
+	if got := Count(1234567); got != "1234567" {
+		t.Errorf("not a terminal: want the digits, got %q", got)
+	}
+}
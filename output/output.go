@@ -0,0 +1,101 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// Package output renders text for people reading a terminal: colours,
+// aligned tables and humanized values. Colours are only used for an
+// interactive terminal, and are disabled by NO_COLOR, TERM=dumb, --no-color
+// and --plain. Plain output is also meant for scripts, so tables are
+// separated by tabs and values are printed in full instead of humanized.
+// The counts and ages of a table are only humanized for an interactive
+// terminal, as scripts read the tables of commands such as list.
+package output
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/moby/term"
+	"github.com/morikuni/aec"
+)
+
+var (
+	mu        sync.RWMutex
+	noColor   bool
+	plain     bool
+	stdoutTTY = func() bool { return term.IsTerminal(os.Stdout.Fd()) }
+)
+
+// Configure applies the --no-color and --plain flags
+func Configure(noColorFlag, plainFlag bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	noColor = noColorFlag
+	plain = plainFlag
+}
+
+// Plain reports whether --plain was given
+func Plain() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return plain
+}
+
+// Humanized reports whether the values of a table are humanized, which is
+// done when stdout is a terminal, unless --plain was given
+func Humanized() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return !plain && stdoutTTY()
+}
+
+// ColorEnabled reports whether escape codes should be written
+func ColorEnabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if noColor || plain {
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return stdoutTTY()
+}
+
+// Color applies an ANSI style when colours are enabled
+type Color struct {
+	ansi aec.ANSI
+}
+
+var (
+	Bold   = Color{aec.Bold}
+	Red    = Color{aec.RedF}
+	Green  = Color{aec.GreenF}
+	Yellow = Color{aec.YellowF}
+	Blue   = Color{aec.BlueF}
+)
+
+// Apply styles text, or returns it unchanged when colours are disabled
+func (c Color) Apply(text string) string {
+	if !ColorEnabled() {
+		return text
+	}
+	return c.ansi.Apply(text)
+}
+
+// Sprintf formats, then styles the result
+func (c Color) Sprintf(format string, a ...interface{}) string {
+	return c.Apply(fmt.Sprintf(format, a...))
+}
+
+// Success, Warning and Failure style a message by its outcome
+func Success(text string) string { return Green.Apply(text) }
+func Warning(text string) string { return Yellow.Apply(text) }
+func Failure(text string) string { return Red.Apply(text) }
@@ -0,0 +1,63 @@
+package output
+
+import (
+	"testing"
+)
+
+func withTerminal(t *testing.T, tty bool) {
+	t.Helper()
+
+	previous := stdoutTTY
+	stdoutTTY = func() bool { return tty }
+	t.Cleanup(func() {
+		stdoutTTY = previous
+		Configure(false, false)
+	})
+}
+
+func Test_ColorEnabled(t *testing.T) {
+	cases := []struct {
+		name    string
+		tty     bool
+		noColor bool
+		plain   bool
+		env     map[string]string
+		want    bool
+	}{
+		{name: "terminal", tty: true, want: true},
+		{name: "not a terminal", tty: false, want: false},
+		{name: "--no-color", tty: true, noColor: true, want: false},
+		{name: "--plain", tty: true, plain: true, want: false},
+		{name: "NO_COLOR is set", tty: true, env: map[string]string{"NO_COLOR": ""}, want: false},
+		{name: "dumb terminal", tty: true, env: map[string]string{"TERM": "dumb"}, want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			withTerminal(t, c.tty)
+			t.Setenv("TERM", "xterm")
+			for key, value := range c.env {
+				t.Setenv(key, value)
+			}
+			Configure(c.noColor, c.plain)
+
+			if got := ColorEnabled(); got != c.want {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func Test_Color_Apply(t *testing.T) {
+	t.Setenv("TERM", "xterm")
+	withTerminal(t, true)
+
+	if got := Green.Apply("done"); got != "\x1b[32mdone\x1b[0m" {
+		t.Errorf("want green text, got %q", got)
+	}
+
+	Configure(true, false)
+	if got := Green.Apply("done"); got != "done" {
+		t.Errorf("want text without escape codes, got %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package output
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// columnGap separates the columns of an aligned table
+const columnGap = "  "
+
+var escapeCodes = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+
+// Table aligns rows into columns. Unlike text/tabwriter, the width of a cell
+// ignores escape codes, so cells can be coloured without breaking alignment.
+type Table struct {
+	header []string
+	rows   [][]string
+}
+
+// NewTable creates a table with a header row
+func NewTable(header ...string) *Table {
+	return &Table{header: header}
+}
+
+// Row adds a row, missing cells are left empty
+func (t *Table) Row(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// Len is the number of rows, excluding the header
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+// Write renders the table, or separates its cells with a tab for --plain
+func (t *Table) Write(w io.Writer) error {
+	rows := t.rows
+	if len(t.header) > 0 {
+		header := t.header
+		if !Plain() {
+			header = make([]string, len(t.header))
+			for i, name := range t.header {
+				header[i] = Bold.Apply(name)
+			}
+		}
+		rows = append([][]string{header}, rows...)
+	}
+
+	var b strings.Builder
+	if Plain() {
+		for _, row := range rows {
+			b.WriteString(strings.Join(row, "\t"))
+			b.WriteString("\n")
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := VisibleWidth(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-VisibleWidth(cell)))
+				line.WriteString(columnGap)
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// VisibleWidth is the number of characters of text shown by a terminal
+func VisibleWidth(text string) int {
+	return utf8.RuneCountInString(escapeCodes.ReplaceAllString(text, ""))
+}
@@ -0,0 +1,56 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_Table_Write(t *testing.T) {
+	cases := []struct {
+		name  string
+		color bool
+		plain bool
+		want  string
+	}{
+		{
+			name: "aligns columns",
+			want: "NAME      REPLICAS  IMAGE\nfiglet    1         openfaas/figlet\nnodeinfo  10\n",
+		},
+		{
+			name:  "plain separates cells with a tab",
+			plain: true,
+			want:  "NAME\tREPLICAS\tIMAGE\nfiglet\t1\topenfaas/figlet\nnodeinfo\t10\n",
+		},
+		{
+			name:  "escape codes do not change the alignment",
+			color: true,
+			want:  "\x1b[1mNAME\x1b[0m      \x1b[1mREPLICAS\x1b[0m  \x1b[1mIMAGE\x1b[0m\nfiglet    1         openfaas/figlet\nnodeinfo  10\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("TERM", "xterm")
+			withTerminal(t, c.color)
+			Configure(false, c.plain)
+
+			table := NewTable("NAME", "REPLICAS", "IMAGE")
+			table.Row("figlet", "1", "openfaas/figlet")
+			table.Row("nodeinfo", "10")
+
+			var b bytes.Buffer
+			if err := table.Write(&b); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != c.want {
+				t.Errorf("want:\n%q\ngot:\n%q", c.want, b.String())
+			}
+		})
+	}
+}
+
+func Test_VisibleWidth(t *testing.T) {
+	if got := VisibleWidth("\x1b[32mdoné\x1b[0m"); got != 4 {
+		t.Errorf("want 4, got %d", got)
+	}
+}
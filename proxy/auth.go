@@ -58,6 +58,15 @@ func NewCLIAuth(token string, gateway string) (ClientAuth, error) {
 
 	}
 
+	if authConfig.Auth == config.ExecAuthType && authConfig.Exec != nil && len(token) == 0 {
+		credential, err := RunExecCredential(*authConfig.Exec, ExecCredentialGateway, gateway)
+		if err != nil {
+			return nil, err
+		}
+
+		return credential.ClientAuth(), nil
+	}
+
 	// User specified token gets priority
 	if len(token) > 0 {
 		bearerToken = token
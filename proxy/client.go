@@ -24,6 +24,10 @@ type Client struct {
 	GatewayURL *url.URL
 	//UserAgent user agent for the client
 	UserAgent string
+
+	// output is where the progress of a deployment or removal is written,
+	// stdout when nil
+	output io.Writer
 }
 
 // ClientAuth an interface for client authentication.
@@ -57,6 +61,22 @@ func NewClient(auth ClientAuth, gatewayURL string, transport http.RoundTripper,
 	}, nil
 }
 
+// WithOutput returns a copy of the client which writes the progress of a
+// deployment or removal to out, so that those made at the same time can each
+// be written in turn
+func (c *Client) WithOutput(out io.Writer) *Client {
+	copied := *c
+	copied.output = out
+	return &copied
+}
+
+func (c *Client) out() io.Writer {
+	if c.output == nil {
+		return os.Stdout
+	}
+	return c.output
+}
+
 // newRequest create a new HTTP request with authentication
 func (c *Client) newRequest(method, path string, query url.Values, body io.Reader) (*http.Request, error) {
 
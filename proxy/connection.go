@@ -0,0 +1,181 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+// connectionSettings applies the proxy and CA bundle saved for each gateway
+// with "faas-cli connection set" to the requests made to it
+type connectionSettings struct {
+	// proxies by the scheme and host of each gateway
+	proxies map[string]*url.URL
+	// direct gateways ignore the proxy environment variables
+	direct map[string]bool
+	// bundles are the PEM files by the scheme and host of each gateway
+	bundles map[string]caBundle
+	// errors by the scheme and host of each gateway, such as a CA bundle
+	// which could not be read, are returned for each request made to it
+	errors map[string]error
+}
+
+// loadConnectionSettings reads the connections of the config file, it can't
+// return an error, as one is only returned to requests for the gateway whose
+// settings were invalid
+var loadConnectionSettings = func() *connectionSettings {
+	connections, err := config.ListConnectionConfigs()
+	if err != nil {
+		return newConnectionSettings(nil)
+	}
+	return newConnectionSettings(connections)
+}
+
+func newConnectionSettings(connections []config.ConnectionConfig) *connectionSettings {
+	settings := &connectionSettings{
+		proxies: map[string]*url.URL{},
+		direct:  map[string]bool{},
+		bundles: map[string]caBundle{},
+		errors:  map[string]error{},
+	}
+
+	for _, connection := range connections {
+		gatewayURL, err := url.Parse(connection.Gateway)
+		if err != nil || len(gatewayURL.Host) == 0 {
+			continue
+		}
+		key := origin(gatewayURL)
+
+		switch connection.Proxy {
+		case "":
+		case config.DirectProxy:
+			settings.direct[key] = true
+		default:
+			proxyURL, err := url.Parse(connection.Proxy)
+			if err != nil || len(proxyURL.Host) == 0 {
+				settings.errors[key] = fmt.Errorf("invalid proxy for %s: %s", connection.Gateway, connection.Proxy)
+				continue
+			}
+			settings.proxies[key] = proxyURL
+		}
+
+		if len(connection.CABundle) > 0 {
+			bundle, err := loadCABundle(connection.CABundle)
+			if err != nil {
+				settings.errors[key] = fmt.Errorf("unable to load the CA bundle for %s: %w", connection.Gateway, err)
+				continue
+			}
+			settings.bundles[key] = caBundle{hostname: strings.ToLower(gatewayURL.Hostname()), data: bundle}
+		}
+	}
+
+	return settings
+}
+
+type caBundle struct {
+	hostname string
+	data     []byte
+}
+
+// loadCABundle reads a bundle, which must have at least one certificate
+func loadCABundle(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return data, nil
+}
+
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// proxy is the transport's Proxy, it uses the gateway's saved proxy, or the
+// environment
+func (s *connectionSettings) proxy(req *http.Request) (*url.URL, error) {
+	key := origin(req.URL)
+	if err, ok := s.errors[key]; ok {
+		return nil, err
+	}
+	if proxyURL, ok := s.proxies[key]; ok {
+		return proxyURL, nil
+	}
+	if s.direct[key] {
+		return nil, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// tlsConfig trusts the CA bundles of the gateways as well as the system's
+// roots, so that a gateway behind a public and a private CA can both be
+// verified. The standard verification can only be given one set of roots for
+// every host, so verifyConnection then checks each gateway against the
+// bundles saved for it alone, or the system's roots when it has none.
+func (s *connectionSettings) tlsConfig(tlsInsecure bool) *tls.Config {
+	if tlsInsecure {
+		return &tls.Config{InsecureSkipVerify: true}
+	}
+	if len(s.bundles) == 0 {
+		return nil
+	}
+
+	// A certificate names a host and not a port, so the gateways on one host
+	// share their bundles
+	roots := systemRoots()
+	hosts := map[string]*x509.CertPool{}
+	for _, bundle := range s.bundles {
+		roots.AppendCertsFromPEM(bundle.data)
+		if hosts[bundle.hostname] == nil {
+			hosts[bundle.hostname] = systemRoots()
+		}
+		hosts[bundle.hostname].AppendCertsFromPEM(bundle.data)
+	}
+
+	return &tls.Config{
+		RootCAs: roots,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return verifyConnection(state, hosts)
+		},
+	}
+}
+
+func systemRoots() *x509.CertPool {
+	roots, err := x509.SystemCertPool()
+	if err != nil || roots == nil {
+		return x509.NewCertPool()
+	}
+	return roots
+}
+
+// verifyConnection runs after the standard verification. A gateway reached
+// by its IP address sends no server name, so only the standard verification
+// applies to it.
+func verifyConnection(state tls.ConnectionState, hosts map[string]*x509.CertPool) error {
+	if len(state.ServerName) == 0 {
+		return nil
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         hosts[strings.ToLower(state.ServerName)],
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,201 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+func Test_connectionSettings_proxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	t.Setenv("NO_PROXY", "")
+
+	settings := newConnectionSettings([]config.ConnectionConfig{
+		{Gateway: "https://openfaas.example.com", Proxy: "http://corp-proxy:8080"},
+		{Gateway: "http://openfaas.local:8080", Proxy: config.DirectProxy},
+		{Gateway: "https://broken.example.com", Proxy: "::"},
+	})
+
+	cases := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://openfaas.example.com/system/functions", want: "http://corp-proxy:8080"},
+		{url: "http://openfaas.local:8080/system/functions", want: ""},
+		{url: "https://other.example.com/system/functions", want: "http://env-proxy:3128"},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodGet, c.url, nil)
+		proxyURL, err := settings.proxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := ""
+		if proxyURL != nil {
+			got = proxyURL.String()
+		}
+		if got != c.want {
+			t.Errorf("%s: want proxy %q, got: %q", c.url, c.want, got)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://broken.example.com/", nil)
+	if _, err := settings.proxy(req); err == nil || !strings.Contains(err.Error(), "invalid proxy") {
+		t.Errorf("want an error for the invalid proxy, got: %v", err)
+	}
+}
+
+func Test_connectionSettings_CABundle(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600)
+
+	// The certificate of the test server is for example.com, which is dialled
+	// at the server's address, so that the server name is verified
+	gatewayURL := strings.Replace(s.URL, "127.0.0.1", "example.com", 1)
+	dialer := &net.Dialer{}
+	get := func(settings *connectionSettings) error {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: settings.tlsConfig(false),
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, s.Listener.Addr().String())
+			},
+		}}
+		res, err := client.Get(gatewayURL)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(newConnectionSettings(nil)); err == nil {
+		t.Errorf("want the test server not to be trusted without a CA bundle")
+	}
+
+	trusted := newConnectionSettings([]config.ConnectionConfig{{Gateway: gatewayURL, CABundle: bundle}})
+	if err := get(trusted); err != nil {
+		t.Errorf("want the gateway to be trusted with its CA bundle, got: %s", err)
+	}
+
+	other := newConnectionSettings([]config.ConnectionConfig{{Gateway: "https://openfaas.example.com", CABundle: bundle}})
+	if err := get(other); err == nil {
+		t.Errorf("want the CA bundle of another gateway not to be trusted")
+	}
+
+	missing := newConnectionSettings([]config.ConnectionConfig{{Gateway: s.URL, CABundle: bundle + ".missing"}})
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	if _, err := missing.proxy(req); err == nil || !strings.Contains(err.Error(), "CA bundle") {
+		t.Errorf("want an error for a missing CA bundle, got: %v", err)
+	}
+
+	if tlsConfig := newConnectionSettings(nil).tlsConfig(false); tlsConfig != nil {
+		t.Errorf("want the default TLS config without CA bundles")
+	}
+}
+
+// newTestCA signs a certificate for example.com with a new CA, and writes the
+// CA to a bundle
+func newTestCA(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600)
+	return tls.Certificate{Certificate: [][]byte{leafDER}, PrivateKey: key}, bundle
+}
+
+func Test_connectionSettings_CABundleOfAnotherGateway(t *testing.T) {
+	cert, privateCA := newTestCA(t)
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	s.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	s.StartTLS()
+	defer s.Close()
+
+	// A bundle which does not sign the server's certificate
+	_, otherCA := newTestCA(t)
+
+	gatewayURL := strings.Replace(s.URL, "127.0.0.1", "example.com", 1)
+	dialer := &net.Dialer{}
+	get := func(settings *connectionSettings) error {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: settings.tlsConfig(false),
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, s.Listener.Addr().String())
+			},
+		}}
+		res, err := client.Get(gatewayURL)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	crossed := newConnectionSettings([]config.ConnectionConfig{
+		{Gateway: gatewayURL, CABundle: otherCA},
+		{Gateway: "https://openfaas.example.com", CABundle: privateCA},
+	})
+	if err := get(crossed); err == nil {
+		t.Errorf("want the gateway not to trust the CA bundle of another gateway")
+	}
+
+	own := newConnectionSettings([]config.ConnectionConfig{
+		{Gateway: gatewayURL, CABundle: privateCA},
+		{Gateway: "https://openfaas.example.com", CABundle: otherCA},
+	})
+	if err := get(own); err != nil {
+		t.Errorf("want the gateway to be trusted with its own CA bundle, got: %s", err)
+	}
+}
@@ -30,13 +30,13 @@ func (c *Client) DeleteFunction(ctx context.Context, functionName string, namesp
 
 	req, err := c.newRequest(http.MethodDelete, deleteEndpoint, query, reader)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(c.out(), err)
 		return err
 	}
 
 	res, err := c.doRequest(ctx, req)
 	if err != nil {
-		fmt.Printf("Error removing existing function: %s, gateway=%s, functionName=%s\n",
+		fmt.Fprintf(c.out(), "Error removing existing function: %s, gateway=%s, functionName=%s\n",
 			err.Error(), c.GatewayURL.String(), functionName)
 		return err
 	}
@@ -47,7 +47,7 @@ func (c *Client) DeleteFunction(ctx context.Context, functionName string, namesp
 
 	switch res.StatusCode {
 	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
-		fmt.Println("Removing old function.")
+		fmt.Fprintln(c.out(), "Removing old function.")
 	case http.StatusNotFound:
 		err = fmt.Errorf("No existing function to remove")
 	case http.StatusUnauthorized:
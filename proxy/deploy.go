@@ -11,8 +11,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/openfaas/faas-cli/output"
 	"github.com/openfaas/faas-cli/stack"
 
 	types "github.com/openfaas/faas-provider/types"
@@ -64,24 +66,32 @@ func generateFuncStr(spec *DeployFunctionSpec) string {
 // DeployFunction first tries to deploy a function and if it exists will then attempt
 // a rolling update. Warnings are suppressed for the second API call (if required.)
 func (c *Client) DeployFunction(context context.Context, spec *DeployFunctionSpec) int {
+	statusCode, _ := c.DeployFunctionResult(context, spec)
+	return statusCode
+}
+
+// DeployFunctionResult deploys a function in the same way as DeployFunction,
+// and also returns the message the gateway gave when it rejected the function
+func (c *Client) DeployFunctionResult(context context.Context, spec *DeployFunctionSpec) (int, string) {
 
 	rollingUpdateInfo := fmt.Sprintf("Function %s already exists, attempting rolling-update.", spec.FunctionName)
-	statusCode, deployOutput := c.deploy(context, spec, spec.Update)
+	statusCode, deployOutput, message := c.deploy(context, spec, spec.Update)
 
 	if spec.Update == true && statusCode == http.StatusNotFound {
 		// Re-run the function with update=false
 
-		statusCode, deployOutput = c.deploy(context, spec, false)
+		statusCode, deployOutput, message = c.deploy(context, spec, false)
 	} else if statusCode == http.StatusOK {
-		fmt.Println(rollingUpdateInfo)
+		fmt.Fprintln(c.out(), rollingUpdateInfo)
 	}
-	fmt.Println()
-	fmt.Println(deployOutput)
-	return statusCode
+	fmt.Fprintln(c.out())
+	fmt.Fprintln(c.out(), deployOutput)
+	return statusCode, message
 }
 
-// deploy a function to an OpenFaaS gateway over REST
-func (c *Client) deploy(context context.Context, spec *DeployFunctionSpec, update bool) (int, string) {
+// deploy a function to an OpenFaaS gateway over REST, the message is the body
+// of a response with an unexpected status
+func (c *Client) deploy(context context.Context, spec *DeployFunctionSpec, update bool) (int, string, string) {
 
 	var deployOutput string
 	// Need to alter Gateway to allow nil/empty string as fprocess, to avoid this repetition.
@@ -156,7 +166,7 @@ func (c *Client) deploy(context context.Context, spec *DeployFunctionSpec, updat
 
 	if err != nil {
 		deployOutput += fmt.Sprintln(err)
-		return http.StatusInternalServerError, deployOutput
+		return http.StatusInternalServerError, deployOutput, ""
 	}
 
 	res, err := c.doRequest(context, request)
@@ -164,7 +174,7 @@ func (c *Client) deploy(context context.Context, spec *DeployFunctionSpec, updat
 	if err != nil {
 		deployOutput += fmt.Sprintln("Is OpenFaaS deployed? Do you need to specify the --gateway flag?")
 		deployOutput += fmt.Sprintln(err)
-		return http.StatusInternalServerError, deployOutput
+		return http.StatusInternalServerError, deployOutput, ""
 	}
 
 	if res.Body != nil {
@@ -173,7 +183,7 @@ func (c *Client) deploy(context context.Context, spec *DeployFunctionSpec, updat
 
 	switch res.StatusCode {
 	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
-		deployOutput += fmt.Sprintf("Deployed. %s.\n", res.Status)
+		deployOutput += output.Success(fmt.Sprintf("Deployed. %s.", res.Status)) + "\n"
 
 		deployedURL := fmt.Sprintf("URL: %s/function/%s", c.GatewayURL.String(), generateFuncStr(spec))
 		deployOutput += fmt.Sprintln(deployedURL)
@@ -183,9 +193,10 @@ func (c *Client) deploy(context context.Context, spec *DeployFunctionSpec, updat
 	default:
 		bytesOut, err := ioutil.ReadAll(res.Body)
 		if err == nil {
-			deployOutput += fmt.Sprintf("Unexpected status: %d, message: %s\n", res.StatusCode, string(bytesOut))
+			deployOutput += output.Failure(fmt.Sprintf("Unexpected status: %d, message: %s", res.StatusCode, string(bytesOut))) + "\n"
+			return res.StatusCode, deployOutput, strings.TrimSpace(string(bytesOut))
 		}
 	}
 
-	return res.StatusCode, deployOutput
+	return res.StatusCode, deployOutput, ""
 }
@@ -54,6 +54,9 @@ func runDeployProxyTest(t *testing.T, deployTest deployProxyTest) {
 			tlsNoVerify,
 			"",
 			"",
+			nil,
+			false,
+			"",
 		})
 	})
 
@@ -123,6 +126,9 @@ func Test_DeployFunction_generateFuncStr(t *testing.T) {
 				tlsNoVerify,
 				"",
 				"",
+				nil,
+				false,
+				"",
 			},
 			expectedStr: "funcName",
 		},
@@ -146,6 +152,9 @@ func Test_DeployFunction_generateFuncStr(t *testing.T) {
 				tlsNoVerify,
 				"",
 				"nameSpace",
+				nil,
+				false,
+				"",
 			},
 			expectedStr: "funcName.nameSpace",
 		},
@@ -0,0 +1,101 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+const (
+	// ExecCredentialGateway is the kind of credential used for a gateway
+	ExecCredentialGateway = "gateway"
+	// ExecCredentialRegistry is the kind of credential used for a registry
+	ExecCredentialRegistry = "registry"
+
+	execCredentialTimeout = time.Minute
+)
+
+// ExecCredential is printed by a credential command to stdout as JSON, with
+// either a token, or a username and password. The command is told what the
+// credential is for by the FAAS_CLI_CREDENTIAL_KIND and
+// FAAS_CLI_CREDENTIAL_SERVER environment variables, its stdin and stderr are
+// those of the CLI so that it can prompt, e.g. for a second factor.
+type ExecCredential struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+
+	// ExpiresAt is when the credential must be fetched again, when it is
+	// not set the command is run each time the CLI is
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// execCredentialCache holds credentials in memory only, so that a command
+// which makes many calls runs the credential command once
+var (
+	execCredentialCache   = map[string]ExecCredential{}
+	execCredentialCacheMu sync.Mutex
+)
+
+// RunExecCredential runs the credential command and parses what it printed
+func RunExecCredential(cfg config.ExecConfig, kind, server string) (ExecCredential, error) {
+	key := strings.Join([]string{kind, server, cfg.String()}, "\x00")
+
+	execCredentialCacheMu.Lock()
+	defer execCredentialCacheMu.Unlock()
+
+	if cached, ok := execCredentialCache[key]; ok && cached.ExpiresAt != nil && time.Now().Before(*cached.ExpiresAt) {
+		return cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), execCredentialTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Env = append(os.Environ(),
+		"FAAS_CLI_CREDENTIAL_KIND="+kind,
+		"FAAS_CLI_CREDENTIAL_SERVER="+server)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ExecCredential{}, fmt.Errorf("credential command %q timed out after %s", cfg.Command, execCredentialTimeout)
+		}
+		return ExecCredential{}, fmt.Errorf("credential command %q failed: %s", cfg.Command, err)
+	}
+
+	credential := ExecCredential{}
+	if err := json.Unmarshal(stdout.Bytes(), &credential); err != nil {
+		return ExecCredential{}, fmt.Errorf("credential command %q must print JSON with a token, or a username and password: %s", cfg.Command, err)
+	}
+	if len(credential.Token) == 0 && (len(credential.Username) == 0 || len(credential.Password) == 0) {
+		return ExecCredential{}, fmt.Errorf("credential command %q printed neither a token, nor a username and password", cfg.Command)
+	}
+
+	if credential.ExpiresAt != nil {
+		execCredentialCache[key] = credential
+	}
+	return credential, nil
+}
+
+// ClientAuth is the authentication for the credential, a token is preferred
+func (c ExecCredential) ClientAuth() ClientAuth {
+	if len(c.Token) > 0 {
+		return &BearerToken{token: c.Token}
+	}
+	return &BasicAuth{username: c.Username, password: c.Password}
+}
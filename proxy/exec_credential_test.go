@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+// writeCredentialScript writes a command which prints body, and appends a
+// line to a count file each time it runs
+func writeCredentialScript(t *testing.T, body string) (config.ExecConfig, string) {
+	t.Helper()
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+	script := filepath.Join(dir, "creds.sh")
+	content := "#!/bin/sh\necho \"$FAAS_CLI_CREDENTIAL_KIND $FAAS_CLI_CREDENTIAL_SERVER\" >> " + count + "\ncat <<'JSON'\n" + body + "\nJSON\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return config.ExecConfig{Command: script}, count
+}
+
+func Test_RunExecCredential_Token(t *testing.T) {
+	cfg, count := writeCredentialScript(t, `{"token": "abc"}`)
+
+	credential, err := RunExecCredential(cfg, ExecCredentialGateway, "https://gw.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://gw.example.com", nil)
+	credential.ClientAuth().Set(req)
+	if got := req.Header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("want a bearer token, got: %s", got)
+	}
+
+	data, _ := os.ReadFile(count)
+	if got := strings.TrimSpace(string(data)); got != "gateway https://gw.example.com" {
+		t.Errorf("want the kind and server in the environment, got: %q", got)
+	}
+
+	// Without an expiry the command runs each time
+	RunExecCredential(cfg, ExecCredentialGateway, "https://gw.example.com")
+	if data, _ := os.ReadFile(count); strings.Count(string(data), "\n") != 2 {
+		t.Errorf("want the command run twice, got: %q", data)
+	}
+}
+
+func Test_RunExecCredential_CachedUntilExpiry(t *testing.T) {
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	cfg, count := writeCredentialScript(t, `{"username": "ci", "password": "s3cret", "expiresAt": "`+expires+`"}`)
+
+	for i := 0; i < 3; i++ {
+		credential, err := RunExecCredential(cfg, ExecCredentialRegistry, "ghcr.io")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if credential.Username != "ci" || credential.Password != "s3cret" {
+			t.Fatalf("want the username and password, got: %+v", credential)
+		}
+	}
+
+	if data, _ := os.ReadFile(count); strings.Count(string(data), "\n") != 1 {
+		t.Errorf("want the command run once, got: %q", data)
+	}
+}
+
+func Test_RunExecCredential_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"not json":      `password`,
+		"no credential": `{"username": "ci"}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, _ := writeCredentialScript(t, body)
+			if _, err := RunExecCredential(cfg, ExecCredentialGateway, "https://gw.example.com"); err == nil {
+				t.Errorf("want an error")
+			}
+		})
+	}
+
+	if _, err := RunExecCredential(config.ExecConfig{Command: "/bin/false"}, ExecCredentialGateway, "x"); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("want an error when the command fails, got: %v", err)
+	}
+}
+
+func Test_NewCLIAuth_Exec(t *testing.T) {
+	t.Setenv(config.ConfigLocationEnv, t.TempDir())
+
+	cfg, _ := writeCredentialScript(t, `{"username": "admin", "password": "fetched"}`)
+	gateway := "https://gw.example.com"
+	if err := config.UpdateExecAuthConfig(gateway, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := NewCLIAuth("", gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, gateway, nil)
+	auth.Set(req)
+	if user, pass, ok := req.BasicAuth(); !ok || user != "admin" || pass != "fetched" {
+		t.Errorf("want the fetched credentials, got: %s %s", user, pass)
+	}
+
+	// The --token flag still takes priority
+	auth, _ = NewCLIAuth("flag", gateway)
+	req, _ = http.NewRequest(http.MethodGet, gateway, nil)
+	auth.Set(req)
+	if got := req.Header.Get("Authorization"); got != "Bearer flag" {
+		t.Errorf("want the --token flag, got: %s", got)
+	}
+}
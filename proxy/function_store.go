@@ -2,10 +2,9 @@ package proxy
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	v2 "github.com/openfaas/faas-cli/schema/store/v2"
-	"io/ioutil"
-	"net/http"
 	"strings"
 	"time"
 )
@@ -27,31 +26,18 @@ func FunctionStoreList(store string) ([]v2.StoreFunction, error) {
 
 	client := MakeHTTPClient(&timeout, tlsInsecure)
 
-	res, err := client.Get(store)
+	index, err := FetchIndex(&client, store)
 	if err != nil {
+		var statusErr *IndexStatusError
+		if errors.As(err, &statusErr) {
+			return nil, statusErr
+		}
 		return nil, fmt.Errorf("cannot connect to OpenFaaS store at URL: %s", store)
 	}
 
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	switch res.StatusCode {
-	case http.StatusOK:
-		bytesOut, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return nil, fmt.Errorf("cannot read result from OpenFaaS store at URL: %s", store)
-		}
-
-		jsonErr := json.Unmarshal(bytesOut, &storeResults)
-		if jsonErr != nil {
-			return nil, fmt.Errorf("cannot parse result from OpenFaaS store at URL: %s\n%s", store, jsonErr.Error())
-		}
-	default:
-		bytesOut, err := ioutil.ReadAll(res.Body)
-		if err == nil {
-			return nil, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
-		}
+	jsonErr := json.Unmarshal(index.Body, &storeResults)
+	if jsonErr != nil {
+		return nil, fmt.Errorf("cannot parse result from OpenFaaS store at URL: %s\n%s", store, jsonErr.Error())
 	}
 	return storeResults.Functions, nil
 }
@@ -0,0 +1,192 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IndexCacheEnvironment overrides the folder used to cache store indexes
+const IndexCacheEnvironment = "OPENFAAS_INDEX_CACHE"
+
+// CachedIndex is the body of an index such as the function store or the
+// template store
+type CachedIndex struct {
+	Body []byte
+	// Stale is true when the index could not be fetched, and was read from the cache
+	Stale bool
+	// FetchedAt is when the body was last confirmed by the server
+	FetchedAt time.Time
+}
+
+// IndexStatusError is returned when the server responds with a status code
+// other than 200 or 304, and there is no cached copy to fall back to
+type IndexStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *IndexStatusError) Error() string {
+	return fmt.Sprintf("server returned unexpected status code: %d - %s", e.StatusCode, e.Body)
+}
+
+type indexCacheMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+// FetchIndex gets indexURL, revalidating any cached copy with its ETag. The
+// cached copy is returned as stale, with a warning on stderr, when the server
+// can't be reached or returns a 5xx error.
+func FetchIndex(client *http.Client, indexURL string) (*CachedIndex, error) {
+	cacheDir := indexCacheDir()
+	bodyPath, metaPath := indexCachePaths(cacheDir, indexURL)
+
+	meta, cachedBody := readIndexCache(bodyPath, metaPath)
+
+	req, err := http.NewRequest(http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cachedBody != nil {
+		if len(meta.ETag) > 0 {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if len(meta.LastModified) > 0 {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		if cachedBody != nil {
+			return staleIndex(indexURL, meta, cachedBody, err.Error()), nil
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cachedBody != nil:
+		meta.FetchedAt = time.Now()
+		writeIndexMeta(metaPath, meta)
+		return &CachedIndex{Body: cachedBody, FetchedAt: meta.FetchedAt}, nil
+
+	case res.StatusCode == http.StatusOK:
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		meta = indexCacheMeta{
+			URL:          indexURL,
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+		}
+		if len(cacheDir) > 0 {
+			writeIndexCache(cacheDir, bodyPath, metaPath, body, meta)
+		}
+		return &CachedIndex{Body: body, FetchedAt: meta.FetchedAt}, nil
+
+	case res.StatusCode >= http.StatusInternalServerError && cachedBody != nil:
+		return staleIndex(indexURL, meta, cachedBody, fmt.Sprintf("status code: %d", res.StatusCode)), nil
+	}
+
+	body, _ := ioutil.ReadAll(res.Body)
+	return nil, &IndexStatusError{StatusCode: res.StatusCode, Body: string(body)}
+}
+
+func staleIndex(indexURL string, meta indexCacheMeta, body []byte, reason string) *CachedIndex {
+	fmt.Fprintf(os.Stderr, "Unable to fetch %s (%s), using the copy cached at %s\n",
+		indexURL, reason, meta.FetchedAt.Local().Format(time.RFC1123))
+
+	return &CachedIndex{Body: body, Stale: true, FetchedAt: meta.FetchedAt}
+}
+
+// indexCacheDir is empty when there is nowhere to cache indexes
+func indexCacheDir() string {
+	if dir := os.Getenv(IndexCacheEnvironment); len(dir) > 0 {
+		return dir
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "openfaas", "indexes")
+}
+
+func indexCachePaths(cacheDir, indexURL string) (string, string) {
+	if len(cacheDir) == 0 {
+		return "", ""
+	}
+
+	sum := sha256.Sum256([]byte(indexURL))
+	name := hex.EncodeToString(sum[:])[:16]
+	return filepath.Join(cacheDir, name+".json"), filepath.Join(cacheDir, name+".meta.json")
+}
+
+// readIndexCache returns a nil body when there is no usable cached copy
+func readIndexCache(bodyPath, metaPath string) (indexCacheMeta, []byte) {
+	var meta indexCacheMeta
+	if len(bodyPath) == 0 {
+		return meta, nil
+	}
+
+	metaBytes, err := ioutil.ReadFile(metaPath)
+	if err != nil {
+		return meta, nil
+	}
+	if err := json.Unmarshal(metaBytes, &meta); err != nil {
+		return meta, nil
+	}
+
+	body, err := ioutil.ReadFile(bodyPath)
+	if err != nil {
+		return meta, nil
+	}
+	return meta, body
+}
+
+// writeIndexCache is best effort, a failure to cache should not fail the command
+func writeIndexCache(cacheDir, bodyPath, metaPath string, body []byte, meta indexCacheMeta) {
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return
+	}
+
+	// The body is renamed into place so a partial write is never read back
+	tmp := bodyPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, body, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, bodyPath); err != nil {
+		os.Remove(tmp)
+		return
+	}
+
+	writeIndexMeta(metaPath, meta)
+}
+
+func writeIndexMeta(metaPath string, meta indexCacheMeta) {
+	if len(metaPath) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return
+	}
+	ioutil.WriteFile(metaPath, data, 0600)
+}
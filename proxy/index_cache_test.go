@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_FetchIndex_RevalidatesWithETag(t *testing.T) {
+	t.Setenv(IndexCacheEnvironment, t.TempDir())
+
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"version":"1"}`))
+	}))
+	defer s.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	first, err := FetchIndex(client, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := FetchIndex(client, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 {
+		t.Fatalf("want 2 requests, got %d", requests)
+	}
+	if string(second.Body) != string(first.Body) || second.Stale {
+		t.Fatalf("want the cached body to be revalidated, got: %q, stale: %v", string(second.Body), second.Stale)
+	}
+}
+
+func Test_FetchIndex_FallsBackWhenOffline(t *testing.T) {
+	t.Setenv(IndexCacheEnvironment, t.TempDir())
+
+	status := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"version":"1"}`))
+	}))
+	defer s.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	if _, err := FetchIndex(client, s.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	status = http.StatusBadGateway
+	index, err := FetchIndex(client, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !index.Stale || string(index.Body) != `{"version":"1"}` {
+		t.Fatalf("want the stale cached copy, got: %q, stale: %v", string(index.Body), index.Stale)
+	}
+
+	url := s.URL
+	s.Close()
+	index, err = FetchIndex(client, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !index.Stale {
+		t.Fatalf("want the stale cached copy when the server is down")
+	}
+}
+
+func Test_FetchIndex_StatusErrorWithoutCache(t *testing.T) {
+	t.Setenv(IndexCacheEnvironment, t.TempDir())
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+	defer s.Close()
+
+	_, err := FetchIndex(&http.Client{}, s.URL)
+
+	want := "server returned unexpected status code: 404 - not found"
+	if err == nil || err.Error() != want {
+		t.Fatalf("want error: %q, got: %v", want, err)
+	}
+}
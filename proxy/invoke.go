@@ -5,6 +5,8 @@ package proxy
 
 import (
 	"bytes"
+	"errors"
+	"net"
 	"os"
 
 	"fmt"
@@ -14,25 +16,57 @@ import (
 	"time"
 )
 
+// InvokeRetry controls the time budget and retries of an invocation
+type InvokeRetry struct {
+	// Timeout for each attempt, zero means no timeout
+	Timeout time.Duration
+	// MaxRetries is the number of attempts made after the first one fails
+	MaxRetries int
+	// RetryOn lists the status codes which may be retried, such as 502 and 503
+	RetryOn []int
+	// RetryNonIdempotent retries POST and PATCH as GET is retried, although
+	// the function may then run more than once
+	RetryNonIdempotent bool
+	// Backoff is the delay before the first retry, it doubles for each retry after
+	Backoff time.Duration
+	// Verbose writes the timing of each attempt to stderr
+	Verbose bool
+}
+
 // InvokeFunction a function
 func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType string, query []string, headers []string, async bool, httpMethod string, tlsInsecure bool, namespace string) (*[]byte, error) {
-	var resBytes []byte
+	return InvokeFunctionWithRetry(gateway, name, bytesIn, contentType, query, headers, async, httpMethod, tlsInsecure, namespace, InvokeRetry{})
+}
 
-	gateway = strings.TrimRight(gateway, "/")
+// InvokeFunctionWithRetry invokes a function, retrying connection errors and the
+// status codes given in retry.RetryOn. Unless retry.RetryNonIdempotent is set,
+// a POST or PATCH is only retried when the gateway could not be dialled, as
+// the function may have already run.
+func InvokeFunctionWithRetry(gateway string, name string, bytesIn *[]byte, contentType string, query []string, headers []string, async bool, httpMethod string, tlsInsecure bool, namespace string, retry InvokeRetry) (*[]byte, error) {
+	res, _, err := InvokeFunctionWithMetadata(gateway, name, bytesIn, contentType, query, headers, async, httpMethod, tlsInsecure, namespace, retry)
+	return res, err
+}
 
-	reader := bytes.NewReader(*bytesIn)
+// InvokeFunctionWithMetadata is InvokeFunctionWithRetry, and also returns
+// the metadata of the last attempt, which is set even when it failed, as long
+// as the gateway responded
+func InvokeFunctionWithMetadata(gateway string, name string, bytesIn *[]byte, contentType string, query []string, headers []string, async bool, httpMethod string, tlsInsecure bool, namespace string, retry InvokeRetry) (*[]byte, *InvokeMetadata, error) {
+	gateway = strings.TrimRight(gateway, "/")
 
-	var disableFunctionTimeout *time.Duration
-	client := MakeHTTPClient(disableFunctionTimeout, tlsInsecure)
+	var functionTimeout *time.Duration
+	if retry.Timeout > 0 {
+		functionTimeout = &retry.Timeout
+	}
+	client := MakeHTTPClient(functionTimeout, tlsInsecure)
 
 	qs, qsErr := buildQueryString(query)
 	if qsErr != nil {
-		return nil, qsErr
+		return nil, nil, qsErr
 	}
 
 	headerMap, headerErr := parseHeaders(headers)
 	if headerErr != nil {
-		return nil, headerErr
+		return nil, nil, headerErr
 	}
 
 	functionEndpoint := "/function/"
@@ -42,7 +76,7 @@ func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType st
 
 	httpMethodErr := validateHTTPMethod(httpMethod)
 	if httpMethodErr != nil {
-		return nil, httpMethodErr
+		return nil, nil, httpMethodErr
 	}
 
 	gatewayURL := gateway + functionEndpoint + name
@@ -51,11 +85,108 @@ func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType st
 	}
 	gatewayURL += qs
 
+	start := time.Now()
+	backoff := retry.Backoff
+	for attempt := 0; ; attempt++ {
+		attemptStart := time.Now()
+		resBytes, metadata, err := invokeOnce(&client, gateway, gatewayURL, bytesIn, contentType, headerMap, httpMethod)
+		statusCode := metadata.StatusCode
+
+		if retry.Verbose {
+			outcome := fmt.Sprintf("%d", statusCode)
+			if statusCode == 0 && err != nil {
+				outcome = "error"
+			}
+			fmt.Fprintf(os.Stderr, "Attempt %d: %s in %1.3fs\n", attempt+1, outcome, time.Since(attemptStart).Seconds())
+		}
+
+		if attempt >= retry.MaxRetries || !retry.retryable(httpMethod, statusCode, err) {
+			if retry.Verbose {
+				fmt.Fprintf(os.Stderr, "Total: %d attempt(s) in %1.3fs\n", attempt+1, time.Since(start).Seconds())
+			}
+			metadata.Attempts = attempt + 1
+			metadata.Elapsed = time.Since(start)
+			return resBytes, metadata, err
+		}
+
+		if backoff > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+// retryable decides whether a failed attempt can be made again
+func (r InvokeRetry) retryable(httpMethod string, statusCode int, err error) bool {
+	if err == nil {
+		return false
+	}
+	idempotent := r.RetryNonIdempotent || IsIdempotentMethod(httpMethod)
+
+	// Once the request may have been sent, such as when the connection was
+	// reset or timed out, a non-idempotent method could run twice
+	var connErr *invokeError
+	if errors.As(err, &connErr) {
+		return idempotent || requestNotSent(err)
+	}
+
+	if !idempotent {
+		return false
+	}
+	for _, code := range r.RetryOn {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIdempotentMethod reports whether a request with the method can be made
+// again without changing its result
+func IsIdempotentMethod(httpMethod string) bool {
+	switch httpMethod {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestNotSent reports whether the connection to the gateway failed before
+// any of the request was written, i.e. it could not be resolved or dialled
+func requestNotSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
+
+// invokeError is a failed attempt where the gateway could not be reached, the
+// message is kept separate from the underlying error so it can be inspected
+type invokeError struct {
+	message string
+	err     error
+}
+
+func (e *invokeError) Error() string { return e.message }
+
+func (e *invokeError) Unwrap() error { return e.err }
+
+// invokeOnce makes a single attempt, the status code of the metadata is zero
+// when no response was received
+func invokeOnce(client *http.Client, gateway, gatewayURL string, bytesIn *[]byte, contentType string, headerMap map[string]string, httpMethod string) (*[]byte, *InvokeMetadata, error) {
+	var resBytes []byte
+	metadata := &InvokeMetadata{}
+
+	reader := bytes.NewReader(*bytesIn)
+
 	req, err := http.NewRequest(httpMethod, gatewayURL, reader)
 	if err != nil {
 		fmt.Println()
 		fmt.Println(err)
-		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+		return nil, metadata, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
 	}
 
 	req.Header.Add("Content-Type", contentType)
@@ -73,13 +204,15 @@ func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType st
 	if err != nil {
 		fmt.Println()
 		fmt.Println(err)
-		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+		return nil, metadata, &invokeError{message: fmt.Sprintf("cannot connect to OpenFaaS on URL: %s", gateway), err: err}
 	}
 
 	if res.Body != nil {
 		defer res.Body.Close()
 	}
 
+	metadata = invokeMetadataFromHeaders(res.StatusCode, res.Header)
+
 	switch res.StatusCode {
 	case http.StatusAccepted:
 		fmt.Fprintf(os.Stderr, "Function submitted asynchronously.\n")
@@ -87,18 +220,18 @@ func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType st
 		var readErr error
 		resBytes, readErr = ioutil.ReadAll(res.Body)
 		if readErr != nil {
-			return nil, fmt.Errorf("cannot read result from OpenFaaS on URL: %s %s", gateway, readErr)
+			return nil, metadata, fmt.Errorf("cannot read result from OpenFaaS on URL: %s %s", gateway, readErr)
 		}
 	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+		return nil, metadata, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
 	default:
 		bytesOut, err := ioutil.ReadAll(res.Body)
 		if err == nil {
-			return nil, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+			return nil, metadata, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
 		}
 	}
 
-	return &resBytes, nil
+	return &resBytes, metadata, nil
 }
 
 func buildQueryString(query []string) (string, error) {
@@ -0,0 +1,85 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InvokeMetadata is read from the response to an invocation, for correlating
+// it with the logs and metrics of the gateway and function
+type InvokeMetadata struct {
+	// StatusCode of the response, zero when the gateway could not be reached
+	StatusCode int `json:"status_code"`
+
+	// CallID is the X-Call-Id which the gateway gives to each invocation
+	CallID string `json:"call_id,omitempty"`
+
+	// Duration is the X-Duration-Seconds of the function, as measured by the
+	// gateway or watchdog, zero when it was not sent
+	Duration time.Duration `json:"-"`
+
+	// ServedBy is the replica which handled the invocation, when the provider
+	// or function sends it
+	ServedBy string `json:"served_by,omitempty"`
+
+	// ColdStart is set when the response says the function was scaled up
+	// from zero, or started, to serve the invocation
+	ColdStart bool `json:"cold_start"`
+
+	// Attempts made, including retries
+	Attempts int `json:"attempts"`
+
+	// Elapsed is the time taken by all attempts, as seen by the CLI
+	Elapsed time.Duration `json:"-"`
+
+	// DeliverAt is the X-Deliver-At of an asynchronous invocation, which
+	// confirms that the queue will invoke the function at that time
+	DeliverAt *time.Time `json:"deliver_at,omitempty"`
+}
+
+// DeliverAtHeader asks the queue to invoke an asynchronous request at an
+// RFC3339 time, queues which support deferred messages send it back
+const DeliverAtHeader = "X-Deliver-At"
+
+// Headers which name the replica, or say there was a cold start, are not
+// standard across providers, so the names used by each are checked in order
+var (
+	servedByHeaders  = []string{"X-Served-By", "X-Function-Replica"}
+	coldStartHeaders = []string{"X-Cold-Start", "X-Scale-From-Zero"}
+)
+
+func invokeMetadataFromHeaders(statusCode int, header http.Header) *InvokeMetadata {
+	metadata := &InvokeMetadata{
+		StatusCode: statusCode,
+		CallID:     header.Get("X-Call-Id"),
+	}
+
+	if seconds, err := strconv.ParseFloat(header.Get("X-Duration-Seconds"), 64); err == nil {
+		metadata.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	if deliverAt, err := time.Parse(time.RFC3339, header.Get(DeliverAtHeader)); err == nil {
+		metadata.DeliverAt = &deliverAt
+	}
+
+	for _, name := range servedByHeaders {
+		if value := header.Get(name); len(value) > 0 {
+			metadata.ServedBy = value
+			break
+		}
+	}
+
+	for _, name := range coldStartHeaders {
+		if value := strings.ToLower(header.Get(name)); value == "true" || value == "1" {
+			metadata.ColdStart = true
+			break
+		}
+	}
+
+	return metadata
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_InvokeFunctionWithMetadata(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Call-Id", "0b4d1c6e-8d1c-4a3c-9a51-7c5e3e9b6a10")
+		w.Header().Set("X-Duration-Seconds", "0.250000")
+		w.Header().Set("X-Served-By", "env-5d8f7c9b4-x2x7q")
+		w.Header().Set("X-Cold-Start", "true")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("done"))
+	}))
+	defer s.Close()
+
+	bytesIn := []byte("")
+	res, metadata, err := InvokeFunctionWithMetadata(s.URL, "env", &bytesIn, "text/plain", nil, nil, false, http.MethodPost, false, "", InvokeRetry{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(*res) != "done" {
+		t.Errorf("want the body, got: %q", string(*res))
+	}
+
+	want := InvokeMetadata{
+		StatusCode: http.StatusOK,
+		CallID:     "0b4d1c6e-8d1c-4a3c-9a51-7c5e3e9b6a10",
+		Duration:   250 * time.Millisecond,
+		ServedBy:   "env-5d8f7c9b4-x2x7q",
+		ColdStart:  true,
+		Attempts:   1,
+	}
+	metadata.Elapsed = 0
+	if *metadata != want {
+		t.Fatalf("want: %+v, got: %+v", want, *metadata)
+	}
+}
+
+func Test_InvokeFunctionWithMetadata_KeptOnError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Call-Id", "call-1")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	bytesIn := []byte("")
+	retry := InvokeRetry{MaxRetries: 1, RetryOn: []int{http.StatusInternalServerError}}
+	_, metadata, err := InvokeFunctionWithMetadata(s.URL, "env", &bytesIn, "text/plain", nil, nil, false, http.MethodGet, false, "", retry)
+	if err == nil {
+		t.Fatal("want an error for a 500")
+	}
+
+	if metadata.CallID != "call-1" || metadata.StatusCode != http.StatusInternalServerError || metadata.Attempts != 2 {
+		t.Fatalf("want the metadata of the last attempt, got: %+v", *metadata)
+	}
+	if metadata.Duration != 0 || metadata.ColdStart {
+		t.Fatalf("want headers which were not sent to be empty, got: %+v", *metadata)
+	}
+}
@@ -5,7 +5,12 @@ package proxy
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
 
 	"testing"
 
@@ -166,3 +171,164 @@ func compareMaps(mapA map[string]string, mapB map[string]string) bool {
 	}
 	return true
 }
+
+func Test_InvokeFunctionWithRetry(t *testing.T) {
+	cases := []struct {
+		name         string
+		method       string
+		statuses     []int
+		retry        InvokeRetry
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "retries a status given by RetryOn",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusOK},
+			retry:        InvokeRetry{MaxRetries: 2, RetryOn: []int{http.StatusServiceUnavailable}},
+			wantAttempts: 2,
+		},
+		{
+			name:         "does not retry a status given by RetryOn for POST",
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusOK},
+			retry:        InvokeRetry{MaxRetries: 2, RetryOn: []int{http.StatusServiceUnavailable}},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "retries a status given by RetryOn for POST with RetryNonIdempotent",
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusOK},
+			retry:        InvokeRetry{MaxRetries: 2, RetryOn: []int{http.StatusServiceUnavailable}, RetryNonIdempotent: true},
+			wantAttempts: 2,
+		},
+		{
+			name:         "does not retry a status without RetryOn",
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusOK},
+			retry:        InvokeRetry{MaxRetries: 2},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "stops after MaxRetries",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			retry:        InvokeRetry{MaxRetries: 2, RetryOn: []int{http.StatusBadGateway}},
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statuses[attempts])
+				attempts++
+			}))
+			defer s.Close()
+
+			bytesIn := []byte("test data")
+			_, err := InvokeFunctionWithRetry(s.URL, "function", &bytesIn, "text/plain", []string{}, []string{}, false, tc.method, tlsNoVerify, "", tc.retry)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("want error: %v, got: %v", tc.wantErr, err)
+			}
+			if attempts != tc.wantAttempts {
+				t.Fatalf("want %d attempt(s), got %d", tc.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func Test_InvokeFunctionWithRetry_TimeoutOnlyRetriesIdempotent(t *testing.T) {
+	cases := []struct {
+		method       string
+		wantAttempts int
+	}{
+		{method: http.MethodPost, wantAttempts: 1},
+		{method: http.MethodGet, wantAttempts: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			var attempts int32
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				time.Sleep(200 * time.Millisecond)
+			}))
+			defer s.Close()
+
+			bytesIn := []byte("test data")
+			retry := InvokeRetry{Timeout: 20 * time.Millisecond, MaxRetries: 1}
+			_, err := InvokeFunctionWithRetry(s.URL, "function", &bytesIn, "text/plain", []string{}, []string{}, false, tc.method, tlsNoVerify, "", retry)
+
+			if err == nil {
+				t.Fatalf("want a timeout error")
+			}
+			if got := int(atomic.LoadInt32(&attempts)); got != tc.wantAttempts {
+				t.Fatalf("want %d attempt(s), got %d", tc.wantAttempts, got)
+			}
+		})
+	}
+}
+
+func Test_InvokeRetry_retryableConnectionError(t *testing.T) {
+	dial := &invokeError{message: "cannot connect", err: &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("connection refused")}}
+	if !(InvokeRetry{}).retryable(http.MethodPost, 0, dial) {
+		t.Fatalf("a dial error should be retryable for any method, as nothing was sent")
+	}
+
+	reset := &invokeError{message: "cannot connect", err: &net.OpError{Op: "read", Net: "tcp", Err: fmt.Errorf("connection reset by peer")}}
+	if (InvokeRetry{}).retryable(http.MethodPost, 0, reset) {
+		t.Fatalf("a reset should not be retried for POST, as the function may have run")
+	}
+	if !(InvokeRetry{}).retryable(http.MethodPut, 0, reset) {
+		t.Fatalf("a reset should be retried for PUT")
+	}
+}
+
+func Test_InvokeFunctionWithRetry_ResetAfterBodySent(t *testing.T) {
+	cases := []struct {
+		method       string
+		wantAttempts int
+	}{
+		{method: http.MethodPost, wantAttempts: 1},
+		{method: http.MethodPut, wantAttempts: 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			var attempts int32
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				ioutil.ReadAll(r.Body)
+
+				// The function has the request, then the connection is reset
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if tcp, ok := conn.(*net.TCPConn); ok {
+					tcp.SetLinger(0)
+				}
+				conn.Close()
+			}))
+			defer s.Close()
+
+			bytesIn := []byte("test data")
+			retry := InvokeRetry{MaxRetries: 2}
+			_, err := InvokeFunctionWithRetry(s.URL, "function", &bytesIn, "text/plain", []string{}, []string{}, false, tc.method, tlsNoVerify, "", retry)
+
+			if err == nil {
+				t.Fatalf("want a connection error")
+			}
+			if got := int(atomic.LoadInt32(&attempts)); got != tc.wantAttempts {
+				t.Fatalf("want %d attempt(s), got %d", tc.wantAttempts, got)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package proxy
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -42,10 +41,17 @@ func (c *Client) GetLogs(ctx context.Context, params logs.Request) (<-chan logs.
 				msg := logs.Message{}
 				err := decoder.Decode(&msg)
 				if err != nil {
-					log.Printf("cannot parse log results: %s\n", err.Error())
+					// A cancelled request ends part way through a message
+					if ctx.Err() == nil {
+						log.Printf("cannot parse log results: %s\n", err.Error())
+					}
+					return
+				}
+				select {
+				case logStream <- msg:
+				case <-ctx.Done():
 					return
 				}
-				logStream <- msg
 			}
 		}()
 	case http.StatusUnauthorized:
@@ -71,7 +77,8 @@ func reqAsQueryValues(r logs.Request) url.Values {
 	}
 
 	if r.Since != nil {
-		query.Add("since", r.Since.Format(time.RFC3339))
+		// Sub-second precision lets the logs be paged from the last message
+		query.Add("since", r.Since.Format(time.RFC3339Nano))
 	}
 
 	if r.Tail != 0 {
@@ -82,19 +89,5 @@ func reqAsQueryValues(r logs.Request) url.Values {
 }
 
 func makeStreamingHTTPClient(tlsInsecure bool) http.Client {
-	client := http.Client{}
-
-	if tlsInsecure {
-		tr := &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-		}
-
-		if tlsInsecure {
-			tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: tlsInsecure}
-		}
-
-		client.Transport = tr
-	}
-
-	return client
+	return http.Client{Transport: StreamingTransport(tlsInsecure)}
 }
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 
@@ -54,3 +55,43 @@ func (c *Client) ListNamespaces(ctx context.Context) ([]string, error) {
 	}
 	return namespaces, nil
 }
+
+// NamespaceRequest is the body of a request to create a namespace, from the
+// gateway's namespaces API
+type NamespaceRequest struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// CreateNamespace creates a function namespace, which the provider marks
+// for use by OpenFaaS. It is not an error when the namespace already exists.
+func (c *Client) CreateNamespace(ctx context.Context, namespace NamespaceRequest) error {
+	reqBytes, _ := json.Marshal(&namespace)
+
+	request, err := c.newRequest(http.MethodPost, namespacePath, url.Values{}, bytes.NewReader(reqBytes))
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", c.GatewayURL.String())
+	}
+
+	res, err := c.doRequest(ctx, request)
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", c.GatewayURL.String())
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusConflict:
+		return nil
+	case http.StatusUnauthorized:
+		return fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return fmt.Errorf("the gateway at %s can't create namespaces, create %q with kubectl instead", c.GatewayURL.String(), namespace.Name)
+	default:
+		bytesOut, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
@@ -4,8 +4,6 @@
 package proxy
 
 import (
-	"crypto/tls"
-	"net"
 	"net/http"
 	"time"
 )
@@ -20,28 +18,13 @@ func makeHTTPClientWithDisableKeepAlives(timeout *time.Duration, tlsInsecure boo
 	client := http.Client{}
 
 	if timeout != nil || tlsInsecure {
-		tr := &http.Transport{
-			Proxy:             http.ProxyFromEnvironment,
-			DisableKeepAlives: disableKeepAlives,
-		}
-
+		var dialTimeout time.Duration
 		if timeout != nil {
 			client.Timeout = *timeout
-			tr.DialContext = (&net.Dialer{
-				Timeout: *timeout,
-			}).DialContext
-
-			tr.IdleConnTimeout = 120 * time.Millisecond
-			tr.ExpectContinueTimeout = 1500 * time.Millisecond
+			dialTimeout = *timeout
 		}
 
-		if tlsInsecure {
-			tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: tlsInsecure}
-		}
-
-		tr.DisableKeepAlives = disableKeepAlives
-
-		client.Transport = tr
+		client.Transport = SharedTransport(dialTimeout, tlsInsecure, disableKeepAlives)
 	}
 
 	return client
@@ -0,0 +1,121 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// tokenExchangePath is served by the gateway when OpenFaaS IAM is enabled
+	tokenExchangePath = "/oidc/token"
+
+	tokenExchangeGrant = "urn:ietf:params:oauth:grant-type:token-exchange"
+	accessTokenType    = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// ErrTokenExchangeUnsupported is returned by a gateway without OpenFaaS IAM
+var ErrTokenExchangeUnsupported = errors.New("the gateway does not support token exchange, OpenFaaS IAM is required")
+
+// TokenExchange asks the gateway for a new token, in exchange for a token it
+// already accepts, as per RFC 8693
+type TokenExchange struct {
+	// SubjectToken is the caller's own token
+	SubjectToken string
+
+	// Audience is the service which the new token is for
+	Audience string
+
+	// Scope narrows what the new token grants
+	Scope []string
+}
+
+// ExchangedToken is the token given by the gateway
+type ExchangedToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// Expiry is when the token expires, relative to now, or zero when the
+// gateway did not say
+func (t ExchangedToken) Expiry(now time.Time) time.Time {
+	if t.ExpiresIn <= 0 {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+type tokenExchangeError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// ExchangeToken exchanges the subject token for a new one
+func (c *Client) ExchangeToken(ctx context.Context, exchange TokenExchange) (*ExchangedToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", tokenExchangeGrant)
+	form.Set("subject_token", exchange.SubjectToken)
+	form.Set("subject_token_type", accessTokenType)
+	form.Set("requested_token_type", accessTokenType)
+	if len(exchange.Audience) > 0 {
+		form.Set("audience", exchange.Audience)
+	}
+	if len(exchange.Scope) > 0 {
+		form.Set("scope", strings.Join(exchange.Scope, " "))
+	}
+
+	req, err := c.newRequest(http.MethodPost, tokenExchangePath, url.Values{}, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", c.GatewayURL.String())
+	}
+	// The subject token authenticates the exchange, not the client's login
+	req.Header.Del("Authorization")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := c.doRequest(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", c.GatewayURL.String())
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		var token ExchangedToken
+		if err := json.Unmarshal(body, &token); err != nil || len(token.AccessToken) == 0 {
+			return nil, fmt.Errorf("cannot parse the token from OpenFaaS on URL: %s", c.GatewayURL.String())
+		}
+		return &token, nil
+
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return nil, ErrTokenExchangeUnsupported
+
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+		var exchangeErr tokenExchangeError
+		if json.Unmarshal(body, &exchangeErr) == nil && len(exchangeErr.Error) > 0 {
+			if len(exchangeErr.Description) > 0 {
+				return nil, fmt.Errorf("token exchange refused: %s: %s", exchangeErr.Error, exchangeErr.Description)
+			}
+			return nil, fmt.Errorf("token exchange refused: %s", exchangeErr.Error)
+		}
+		return nil, fmt.Errorf("token exchange refused with status code: %d - %s", res.StatusCode, strings.TrimSpace(string(body)))
+
+	default:
+		return nil, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
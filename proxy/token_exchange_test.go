@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ExchangeToken(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tokenExchangePath || r.Method != http.MethodPost {
+			t.Errorf("want POST %s, got: %s %s", tokenExchangePath, r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); len(auth) > 0 {
+			t.Errorf("want no Authorization header, got: %s", auth)
+		}
+		r.ParseForm()
+		if r.Form.Get("grant_type") != tokenExchangeGrant || r.Form.Get("subject_token") != "id-token" {
+			t.Errorf("want a token exchange of the subject token, got: %v", r.Form)
+		}
+		if r.Form.Get("audience") != "openfaas-dashboard" || r.Form.Get("scope") != "dashboard:read dashboard:write" {
+			t.Errorf("want the audience and scope, got: %v", r.Form)
+		}
+		w.Write([]byte(`{"access_token":"short-lived","token_type":"Bearer","expires_in":900}`))
+	}))
+	defer s.Close()
+
+	client, _ := NewClient(&BearerToken{token: "login"}, s.URL, nil, &defaultCommandTimeout)
+	token, err := client.ExchangeToken(context.Background(), TokenExchange{
+		SubjectToken: "id-token",
+		Audience:     "openfaas-dashboard",
+		Scope:        []string{"dashboard:read", "dashboard:write"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.AccessToken != "short-lived" {
+		t.Errorf("want the exchanged token, got: %s", token.AccessToken)
+	}
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	if expiry := token.Expiry(now); !expiry.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("want an expiry 15m from now, got: %s", expiry)
+	}
+}
+
+func Test_ExchangeToken_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "no IAM", status: http.StatusNotFound, want: ErrTokenExchangeUnsupported.Error()},
+		{name: "refused", status: http.StatusBadRequest, body: `{"error":"invalid_scope","error_description":"dashboard:write is not allowed"}`, want: "token exchange refused: invalid_scope: dashboard:write is not allowed"},
+		{name: "refused without a description", status: http.StatusForbidden, body: `{"error":"access_denied"}`, want: "token exchange refused: access_denied"},
+		{name: "no token", status: http.StatusOK, body: `{}`, want: "cannot parse the token"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(c.status)
+				w.Write([]byte(c.body))
+			}))
+			defer s.Close()
+
+			client, _ := NewClient(NewTestAuth(nil), s.URL, nil, &defaultCommandTimeout)
+			_, err := client.ExchangeToken(context.Background(), TokenExchange{SubjectToken: "id-token"})
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("want an error with %q, got: %v", c.want, err)
+			}
+		})
+	}
+}
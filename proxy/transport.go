@@ -0,0 +1,188 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxConnsEnvironment limits the number of connections which are opened
+	// to each host, there is no limit unless it is set
+	MaxConnsEnvironment = "OPENFAAS_MAX_CONNS_PER_HOST"
+	// DNSCacheTTLEnvironment overrides how long a host's addresses are
+	// cached, as a duration such as 1m, or 0 to look them up on each dial
+	DNSCacheTTLEnvironment = "OPENFAAS_DNS_CACHE_TTL"
+
+	// defaultMaxIdleConnsPerHost keeps enough connections open for the
+	// concurrent requests of a command, such as a deploy of many namespaces
+	defaultMaxIdleConnsPerHost = 16
+	defaultDNSCacheTTL         = 30 * time.Second
+)
+
+type transportKey struct {
+	dialTimeout       time.Duration
+	tlsInsecure       bool
+	disableKeepAlives bool
+}
+
+var (
+	transportsMu sync.Mutex
+	transports   = map[transportKey]*http.Transport{}
+	sharedDNS    *dnsCache
+	connections  *connectionSettings
+)
+
+// SharedTransport returns the transport for the given settings, which is
+// shared by every client which asks for them. A command which makes many
+// requests, such as a deploy of a large stack, then reuses connections and
+// TLS sessions to the gateway, and only resolves its name once. The proxy
+// and CA bundle saved for each gateway are applied to the requests to it.
+func SharedTransport(dialTimeout time.Duration, tlsInsecure bool, disableKeepAlives bool) *http.Transport {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+
+	key := transportKey{dialTimeout: dialTimeout, tlsInsecure: tlsInsecure, disableKeepAlives: disableKeepAlives}
+	if tr, ok := transports[key]; ok {
+		return tr
+	}
+
+	if sharedDNS == nil {
+		sharedDNS = newDNSCache(envDuration(DNSCacheTTLEnvironment, defaultDNSCacheTTL))
+	}
+	if connections == nil {
+		connections = loadConnectionSettings()
+	}
+	maxConns := envInt(MaxConnsEnvironment, 0)
+	maxIdleConns := defaultMaxIdleConnsPerHost
+	if maxConns > 0 {
+		maxIdleConns = maxConns
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+	tr := &http.Transport{
+		Proxy:                 connections.proxy,
+		DialContext:           sharedDNS.dialContext(dialer.DialContext),
+		MaxConnsPerHost:       maxConns,
+		MaxIdleConnsPerHost:   maxIdleConns,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1500 * time.Millisecond,
+		DisableKeepAlives:     disableKeepAlives,
+		TLSClientConfig:       connections.tlsConfig(tlsInsecure),
+	}
+
+	transports[key] = tr
+	return tr
+}
+
+// StreamingTransport is the shared transport for a stream, such as the logs,
+// which is read by a client without a timeout, so only the dial is limited
+func StreamingTransport(tlsInsecure bool) *http.Transport {
+	return SharedTransport(defaultCommandTimeout, tlsInsecure, false)
+}
+
+func envInt(name string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(name)); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// dnsCache keeps the addresses of each host for its ttl, for gateways behind
+// a slow resolver. The operating system's resolver does not cache on every
+// platform, and each new connection would otherwise look the name up again.
+type dnsCache struct {
+	ttl     time.Duration
+	lookup  func(ctx context.Context, host string) ([]string, error)
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:     ttl,
+		lookup:  net.DefaultResolver.LookupHost,
+		now:     time.Now,
+		entries: map[string]dnsEntry{},
+	}
+}
+
+func (c *dnsCache) lookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[host] = dnsEntry{addrs: addrs, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+func (c *dnsCache) forget(host string) {
+	c.mu.Lock()
+	delete(c.entries, host)
+	c.mu.Unlock()
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialContext dials each cached address of the host in turn. When none of
+// them can be reached, the entry is dropped, so that the next dial looks the
+// host up again, in case it has moved.
+func (c *dnsCache) dialContext(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || c.ttl <= 0 || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		addrs, err := c.lookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return dial(ctx, network, addr)
+		}
+
+		var dialErr error
+		for _, ip := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+
+		c.forget(host)
+		return nil, dialErr
+	}
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_SharedTransport_ReusedForTheSameSettings(t *testing.T) {
+	a := SharedTransport(7*time.Second, false, false)
+	b := SharedTransport(7*time.Second, false, false)
+	c := SharedTransport(7*time.Second, true, false)
+
+	if a != b {
+		t.Errorf("want the same transport for the same settings")
+	}
+	if a == c || c.TLSClientConfig == nil || !c.TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("want a separate transport which skips TLS verification")
+	}
+}
+
+func Test_StreamingTransport_Shared(t *testing.T) {
+	client := makeStreamingHTTPClient(false)
+	if client.Transport != SharedTransport(defaultCommandTimeout, false, false) || client.Timeout != 0 {
+		t.Errorf("want the logs streamed over the shared transport without a timeout")
+	}
+	if tr := StreamingTransport(true); tr.TLSClientConfig == nil || !tr.TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("want the streaming transport to skip TLS verification with tlsInsecure")
+	}
+}
+
+func Test_SharedTransport_NoMaxConnsByDefault(t *testing.T) {
+	tr := SharedTransport(13*time.Second, false, false)
+	if tr.MaxConnsPerHost != 0 || tr.MaxIdleConnsPerHost != defaultMaxIdleConnsPerHost {
+		t.Fatalf("want no limit with %d idle connections per host, got: %d and %d idle", defaultMaxIdleConnsPerHost, tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost)
+	}
+}
+
+func Test_SharedTransport_MaxConnsFromEnvironment(t *testing.T) {
+	t.Setenv(MaxConnsEnvironment, "64")
+
+	tr := SharedTransport(11*time.Second, false, false)
+	if tr.MaxConnsPerHost != 64 || tr.MaxIdleConnsPerHost != 64 {
+		t.Fatalf("want 64 connections per host, got: %d and %d idle", tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost)
+	}
+}
+
+func Test_dnsCache_CachesUntilExpiry(t *testing.T) {
+	now := time.Now()
+	lookups := 0
+
+	cache := newDNSCache(30 * time.Second)
+	cache.now = func() time.Time { return now }
+	cache.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		return []string{"10.0.0.1"}, nil
+	}
+
+	var dialed []string
+	dial := cache.dialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := dial(context.Background(), "tcp", "gateway.example.com:443"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("want one lookup within the ttl, got: %d", lookups)
+	}
+	if dialed[0] != "10.0.0.1:443" {
+		t.Fatalf("want the cached address to be dialed, got: %s", dialed[0])
+	}
+
+	now = now.Add(time.Minute)
+	dial(context.Background(), "tcp", "gateway.example.com:443")
+	if lookups != 2 {
+		t.Fatalf("want another lookup after the ttl, got: %d", lookups)
+	}
+}
+
+func Test_dnsCache_ForgetsUnreachableHost(t *testing.T) {
+	lookups := 0
+
+	cache := newDNSCache(time.Minute)
+	cache.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		return []string{"10.0.0.1", "10.0.0.2"}, nil
+	}
+
+	var dialed []string
+	dial := cache.dialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, errors.New("connection refused")
+	})
+
+	if _, err := dial(context.Background(), "tcp", "gateway.example.com:8080"); err == nil {
+		t.Fatal("want an error when no address can be reached")
+	}
+	if len(dialed) != 2 {
+		t.Fatalf("want each address to be tried, got: %v", dialed)
+	}
+
+	dial(context.Background(), "tcp", "gateway.example.com:8080")
+	if lookups != 2 {
+		t.Fatalf("want the host to be looked up again, got %d lookups", lookups)
+	}
+}
+
+func Test_dnsCache_SkipsAddresses(t *testing.T) {
+	cache := newDNSCache(time.Minute)
+	cache.lookup = func(ctx context.Context, host string) ([]string, error) {
+		t.Fatalf("want no lookup for %s", host)
+		return nil, nil
+	}
+
+	dial := cache.dialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	})
+
+	if _, err := dial(context.Background(), "tcp", "127.0.0.1:8080"); err != nil {
+		t.Fatal(err)
+	}
+}
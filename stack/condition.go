@@ -0,0 +1,273 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// envPrefix reads an environment variable in an enabled expression
+const envPrefix = "env."
+
+var (
+	conditionProfile string
+	conditionSet     []string
+)
+
+// SetConditions gives the profile and the KEY=VALUE pairs of --set to the
+// enabled expressions of the functions in the stack files which are parsed
+// afterwards
+func SetConditions(profile string, set []string) {
+	conditionProfile = profile
+	conditionSet = set
+}
+
+// conditionValues are the names which an expression can read, the profile is
+// read as "profile", unless it is given with --set
+func conditionValues() (map[string]string, error) {
+	values := map[string]string{}
+	if len(conditionProfile) > 0 {
+		values["profile"] = conditionProfile
+	}
+	for _, pair := range conditionSet {
+		i := strings.Index(pair, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("--set values must be in the form KEY=VALUE, but got: %q", pair)
+		}
+		values[strings.TrimSpace(pair[:i])] = pair[i+1:]
+	}
+	return values, nil
+}
+
+// Enabled evaluates an enabled expression against values, an empty
+// expression is always enabled.
+//
+// An expression compares names and quoted strings with == and !=, and
+// combines them with &&, || and !, for instance:
+//
+//	profile == "prod" && env.REGION != "eu"
+//
+// A name on its own is true unless it is empty, "false", "0", "no" or "off".
+// Names are read from values, and names prefixed with env. from the
+// environment, a name which is not set is empty. Numbers, true and false
+// are read as they are.
+func Enabled(expression string, values map[string]string) (bool, error) {
+	if len(strings.TrimSpace(expression)) == 0 {
+		return true, nil
+	}
+
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return false, err
+	}
+
+	p := &conditionParser{tokens: tokens, values: values}
+	value, err := p.or()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return truthy(value), nil
+}
+
+func truthy(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "false", "0", "no", "off":
+		return false
+	}
+	return true
+}
+
+type tokenKind int
+
+const (
+	tokenName tokenKind = iota
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string in %q", expression)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[i+1 : end])})
+			i = end + 1
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{kind: tokenOperator, text: string(r)})
+			i++
+		case r == '!' || r == '=' || r == '&' || r == '|':
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				if pair == "==" || pair == "!=" || pair == "&&" || pair == "||" {
+					tokens = append(tokens, token{kind: tokenOperator, text: pair})
+					i += 2
+					continue
+				}
+			}
+			if r != '!' {
+				return nil, fmt.Errorf("unexpected %q in %q", string(r), expression)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: "!"})
+			i++
+		case isNameRune(r):
+			end := i
+			for end < len(runes) && isNameRune(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: string(runes[i:end])})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", string(r), expression)
+		}
+	}
+	return tokens, nil
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+// conditionParser evaluates the tokens as it parses them, with the usual
+// precedence of ! over == and != over && over ||
+type conditionParser struct {
+	tokens []token
+	pos    int
+	values map[string]string
+}
+
+func (p *conditionParser) peek(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].text == text
+}
+
+func (p *conditionParser) or() (string, error) {
+	left, err := p.and()
+	if err != nil {
+		return "", err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return "", err
+		}
+		left = boolString(truthy(left) || truthy(right))
+	}
+	return left, nil
+}
+
+func (p *conditionParser) and() (string, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return "", err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.comparison()
+		if err != nil {
+			return "", err
+		}
+		left = boolString(truthy(left) && truthy(right))
+	}
+	return left, nil
+}
+
+func (p *conditionParser) comparison() (string, error) {
+	left, err := p.unary()
+	if err != nil {
+		return "", err
+	}
+	if p.peek("==") || p.peek("!=") {
+		equal := p.peek("==")
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return "", err
+		}
+		return boolString((left == right) == equal), nil
+	}
+	return left, nil
+}
+
+func (p *conditionParser) unary() (string, error) {
+	if p.peek("!") {
+		p.pos++
+		value, err := p.unary()
+		if err != nil {
+			return "", err
+		}
+		return boolString(!truthy(value)), nil
+	}
+	return p.primary()
+}
+
+func (p *conditionParser) primary() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch {
+	case t.kind == tokenString:
+		return t.text, nil
+	case t.kind == tokenName:
+		return p.lookup(t.text), nil
+	case t.text == "(":
+		value, err := p.or()
+		if err != nil {
+			return "", err
+		}
+		if !p.peek(")") {
+			return "", fmt.Errorf("missing )")
+		}
+		p.pos++
+		return value, nil
+	}
+	return "", fmt.Errorf("unexpected %q", t.text)
+}
+
+func (p *conditionParser) lookup(name string) string {
+	switch name {
+	case "true", "false":
+		return name
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		return name
+	}
+	if strings.HasPrefix(name, envPrefix) {
+		return os.Getenv(strings.TrimPrefix(name, envPrefix))
+	}
+	return p.values[name]
+}
+
+func boolString(value bool) string {
+	if value {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,73 @@
+package stack
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Enabled(t *testing.T) {
+	t.Setenv("REGION", "eu-west-1")
+
+	values := map[string]string{"profile": "prod", "debug": "false", "replicas": "3"}
+
+	cases := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: "", want: true},
+		{expression: "true", want: true},
+		{expression: "false", want: false},
+		{expression: "profile", want: true},
+		{expression: "debug", want: false},
+		{expression: "missing", want: false},
+		{expression: "!debug", want: true},
+		{expression: `profile == "prod"`, want: true},
+		{expression: `profile == 'dev'`, want: false},
+		{expression: `profile != "dev"`, want: true},
+		{expression: "replicas == 3", want: true},
+		{expression: `env.REGION == "eu-west-1"`, want: true},
+		{expression: `profile == "dev" || env.REGION == "eu-west-1"`, want: true},
+		{expression: `profile == "prod" && debug`, want: false},
+		{expression: `!(profile == "dev" || debug)`, want: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expression, func(t *testing.T) {
+			got, err := Enabled(c.expression, values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("want: %v, got: %v", c.want, got)
+			}
+		})
+	}
+}
+
+func Test_Enabled_Invalid(t *testing.T) {
+	for _, expression := range []string{`profile ==`, `profile = "prod"`, `"prod`, `(profile`, `profile "prod"`, `profile > 1`} {
+		t.Run(expression, func(t *testing.T) {
+			if _, err := Enabled(expression, nil); err == nil {
+				t.Errorf("want an error for: %s", expression)
+			}
+		})
+	}
+}
+
+func Test_conditionValues(t *testing.T) {
+	SetConditions("staging", []string{"profile=prod", "region=eu=west"})
+	defer SetConditions("", nil)
+
+	values, err := conditionValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["profile"] != "prod" || values["region"] != "eu=west" {
+		t.Errorf("want --set to override the profile and keep = in values, got: %v", values)
+	}
+
+	SetConditions("", []string{"region"})
+	if _, err := conditionValues(); err == nil || !strings.Contains(err.Error(), "KEY=VALUE") {
+		t.Errorf("want an error for a value without =, got: %v", err)
+	}
+}
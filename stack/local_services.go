@@ -0,0 +1,114 @@
+// Copyright (c) OpenFaaS Author(s) 2023. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LocalServicesExtension lists the containers, such as Redis or Postgres,
+// which local-run starts next to the functions
+const LocalServicesExtension = "x-local-services"
+
+// localServiceName is a valid host name on the functions' network
+var localServiceName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// LocalService is a container which the functions depend on, which is
+// reached by its name, as on a docker-compose network
+type LocalService struct {
+	Image string `yaml:"image"`
+
+	// Command replaces the image's command, as a list or a string which is
+	// split on spaces
+	Command LocalServiceCommand `yaml:"command,omitempty"`
+
+	Environment map[string]string `yaml:"environment,omitempty"`
+
+	// Ports are published on the host as HOST:CONTAINER, such as 6379:6379,
+	// so that the service can be reached from outside the network too
+	Ports []string `yaml:"ports,omitempty"`
+
+	// Volumes are mounted as SRC:DST or SRC:DST:ro
+	Volumes []string `yaml:"volumes,omitempty"`
+}
+
+// LocalServiceCommand is the command of a LocalService
+type LocalServiceCommand []string
+
+// UnmarshalYAML reads the command as a list, or as a string
+func (c *LocalServiceCommand) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var command string
+	if err := unmarshal(&command); err == nil {
+		*c = strings.Fields(command)
+		return nil
+	}
+
+	var args []string
+	if err := unmarshal(&args); err != nil {
+		return fmt.Errorf("command must be a string or a list of strings")
+	}
+	*c = args
+	return nil
+}
+
+// LocalServices reads the x-local-services of the stack file, which is
+// empty when there are none
+func (s *Services) LocalServices() (map[string]LocalService, error) {
+	value, ok := s.Extensions.Lookup(LocalServicesExtension)
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	// The extension was parsed without a type, so it is read again as one
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var services map[string]LocalService
+	if err := yaml.UnmarshalStrict(out, &services); err != nil {
+		return nil, fmt.Errorf("%s: %s", LocalServicesExtension, err.Error())
+	}
+
+	for name, service := range services {
+		if !localServiceName.MatchString(name) {
+			return nil, fmt.Errorf("%s: %q must be a host name of lower case letters, digits and dashes", LocalServicesExtension, name)
+		}
+		if _, ok := s.Functions[name]; ok {
+			return nil, fmt.Errorf("%s: %q has the name of a function, which the functions reach it by", LocalServicesExtension, name)
+		}
+		if len(service.Image) == 0 {
+			return nil, fmt.Errorf("%s: %q must have an image", LocalServicesExtension, name)
+		}
+		for _, port := range service.Ports {
+			if _, _, err := ParseLocalServicePort(port); err != nil {
+				return nil, fmt.Errorf("%s: %q %s", LocalServicesExtension, name, err.Error())
+			}
+		}
+	}
+	return services, nil
+}
+
+// ParseLocalServicePort reads a port of a LocalService, given as
+// HOST:CONTAINER, or as one port which is published on the same port
+func ParseLocalServicePort(port string) (int, int, error) {
+	host, container := port, port
+	if i := strings.Index(port, ":"); i >= 0 {
+		host, container = port[:i], port[i+1:]
+	}
+
+	h, err := strconv.Atoi(host)
+	if err != nil || h < 1 || h > 65535 {
+		return 0, 0, fmt.Errorf("port must be HOST:CONTAINER, such as 6379:6379, got: %q", port)
+	}
+	c, err := strconv.Atoi(container)
+	if err != nil || c < 1 || c > 65535 {
+		return 0, 0, fmt.Errorf("port must be HOST:CONTAINER, such as 6379:6379, got: %q", port)
+	}
+	return h, c, nil
+}
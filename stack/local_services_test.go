@@ -0,0 +1,97 @@
+package stack
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseLocalServicesYAML(t *testing.T, yaml string) *Services {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "stack.yml")
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	services, err := ParseYAMLFile(path, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return services
+}
+
+func Test_LocalServices(t *testing.T) {
+	services := parseLocalServicesYAML(t, `version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    lang: dockerfile
+    handler: ./orders
+    image: acme/orders:0.1.0
+x-local-services:
+  redis:
+    image: redis:7-alpine
+    command: redis-server --appendonly yes
+    ports:
+      - 6379:6379
+  postgres:
+    image: postgres:16
+    command: ["postgres", "-c", "log_statement=all"]
+    environment:
+      POSTGRES_PASSWORD: local
+`)
+
+	local, err := services.LocalServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(local) != 2 {
+		t.Fatalf("want 2 services, got: %v", local)
+	}
+	if got := local["redis"].Command; !reflect.DeepEqual(got, LocalServiceCommand{"redis-server", "--appendonly", "yes"}) {
+		t.Errorf("want a string command split on spaces, got: %q", got)
+	}
+	if got := local["postgres"].Command; !reflect.DeepEqual(got, LocalServiceCommand{"postgres", "-c", "log_statement=all"}) {
+		t.Errorf("want a list command kept, got: %q", got)
+	}
+	if local["postgres"].Environment["POSTGRES_PASSWORD"] != "local" {
+		t.Errorf("want the environment, got: %v", local["postgres"].Environment)
+	}
+}
+
+func Test_LocalServices_None(t *testing.T) {
+	services := &Services{}
+	if local, err := services.LocalServices(); err != nil || local != nil {
+		t.Errorf("want no services, got: %v, %v", local, err)
+	}
+}
+
+func Test_LocalServices_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"redis:\n    ports: [6379]":                         "must have an image",
+		"orders:\n    image: redis":                         "name of a function",
+		"Redis:\n    image: redis":                          "host name",
+		"redis:\n    image: redis\n    ports: [\"a:6379\"]": "HOST:CONTAINER",
+		"redis:\n    image: redis\n    restart: always":     "restart",
+	}
+	for services, want := range cases {
+		parsed := parseLocalServicesYAML(t, `version: 1.0
+provider:
+  name: openfaas
+functions:
+  orders:
+    lang: dockerfile
+    handler: ./orders
+    image: acme/orders:0.1.0
+x-local-services:
+  `+services+"\n")
+
+		if _, err := parsed.LocalServices(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: want an error containing %q, got: %v", services, want, err)
+		}
+	}
+}
@@ -3,10 +3,19 @@
 
 package stack
 
+import "strings"
+
+// ExtensionPrefix marks the custom fields of the stack file which are kept
+// in Extensions
+const ExtensionPrefix = "x-"
+
 // Provider for the FaaS set of functions.
 type Provider struct {
 	Name       string `yaml:"name"`
 	GatewayURL string `yaml:"gateway"`
+
+	// Namespace for the functions which do not set their own namespace
+	Namespace string `yaml:"namespace,omitempty"`
 }
 
 // Function as deployed or built on FaaS
@@ -57,6 +66,10 @@ type Function struct {
 	// Namespace of the function
 	Namespace string `yaml:"namespace,omitempty"`
 
+	// Enabled is an expression which must be true for the function to be
+	// part of the stack, such as: profile == "prod", see Enabled
+	Enabled string `yaml:"enabled,omitempty"`
+
 	// BuildArgs for providing build-args
 	BuildArgs map[string]string `yaml:"build_args,omitempty"`
 
@@ -66,14 +79,105 @@ type Function struct {
 	// BuildSecrets is a set of secrets to mount with buildkit
 	BuildSecrets map[string]string `yaml:"build_secrets,omitempty"`
 
+	// RemoteContext is an object store location such as s3://bucket/prefix to
+	// upload the build context to, for builders which fetch contexts by URL
+	RemoteContext string `yaml:"remote_context,omitempty"`
+
 	//Shm regions for the function
 	Shms []string `yaml:"shm,omitempty"`
 
+	// ShmSize, Ulimits and Tmpfs are given to the container by local-run,
+	// as for docker run --shm-size, --ulimit NAME=SOFT[:HARD] and --tmpfs
+	// PATH[:OPTIONS], such as nofile: 1024:4096 or /tmp:size=64m
+	ShmSize string            `yaml:"shm_size,omitempty"`
+	Ulimits map[string]string `yaml:"ulimits,omitempty"`
+	Tmpfs   []string          `yaml:"tmpfs,omitempty"`
+
 	//Describes if the function pod is pivileged or not
 	Privileged bool `yaml:"privileged,omitempty"`
 
 	//Runasuser value of the function pod
 	RunAsUser string `yaml:"runasuser,omitempty"`
+
+	// Health is the liveness check of the function, deployed as the
+	// com.openfaas.health.http.* annotations
+	Health *HealthCheck `yaml:"health,omitempty"`
+
+	// Readiness is the readiness check of the function, deployed as the
+	// com.openfaas.ready.http.* annotations
+	Readiness *HealthCheck `yaml:"readiness,omitempty"`
+
+	// Router is the path-based routing of the function, deployed as the
+	// com.openfaas.router.* annotations
+	Router *Router `yaml:"router,omitempty"`
+
+	// Tests are smoke tests which "faas-cli verify" makes against the
+	// function in a local container, before it is deployed
+	Tests []SmokeTest `yaml:"tests,omitempty"`
+
+	// Extensions are the fields prefixed with x-, which the CLI ignores, but
+	// keeps for other tools and plugins which read or write the stack file
+	Extensions Extensions `yaml:",inline"`
+}
+
+// Extensions are custom fields of the stack file, keyed by their name
+// including the x- prefix
+type Extensions map[string]interface{}
+
+// Lookup returns the value of an extension, with or without its x- prefix
+func (e Extensions) Lookup(name string) (interface{}, bool) {
+	if !strings.HasPrefix(name, ExtensionPrefix) {
+		name = ExtensionPrefix + name
+	}
+	value, ok := e[name]
+	return value, ok
+}
+
+// HealthCheck is an HTTP check made by the provider against the watchdog
+type HealthCheck struct {
+	// Path to request, the watchdog serves /_/health by default
+	Path string `yaml:"path,omitempty"`
+
+	// InitialDelay before the first check, as a duration such as 2s
+	InitialDelay string `yaml:"initial_delay,omitempty"`
+
+	// Period between checks, as a duration in whole seconds such as 5s
+	Period string `yaml:"period,omitempty"`
+}
+
+// Router routes paths of the gateway's domain to a function, for routers and
+// ingresses which read the function's annotations
+type Router struct {
+	// Paths are routed to the function, a path which ends in /* matches
+	// every path below it
+	Paths []string `yaml:"paths,omitempty"`
+
+	// Methods limits the routes to these HTTP methods, all by default
+	Methods []string `yaml:"methods,omitempty"`
+
+	// StripPrefix removes the path from the request before it reaches the
+	// function
+	StripPrefix bool `yaml:"strip_prefix,omitempty"`
+}
+
+// SmokeTest is a request to a function, and the response it must give
+type SmokeTest struct {
+	Name string `yaml:"name"`
+
+	// Method is GET by default, or POST when there is a body
+	Method string `yaml:"method,omitempty"`
+
+	// Path to request, / by default
+	Path string `yaml:"path,omitempty"`
+
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+
+	// Status the response must have, 200 by default
+	Status int `yaml:"status,omitempty"`
+
+	// Contains is text which the body of the response must contain
+	Contains string `yaml:"contains,omitempty"`
 }
 
 // Configuration for the stack.yml file
@@ -92,6 +196,58 @@ type StackConfiguration struct {
 	//
 	// The yaml uses the shorter name `copy` to make it easier for developers to read and use
 	CopyExtraPaths []string `yaml:"copy"`
+
+	// Registries are mirrors which publish also pushes each image to, such as a
+	// registry for disaster recovery. Each entry replaces the registry and owner
+	// of the image, e.g. "dr.example.com/acme".
+	Registries []string `yaml:"registries,omitempty"`
+
+	// Protected are annotations and labels which deploy will not change on a
+	// function which is already deployed, unless --allow-protected-changes is
+	// given. They are added to those protected for the gateway in the CLI's
+	// config, which the stack file can't remove.
+	Protected ProtectedKeys `yaml:"protected,omitempty"`
+
+	// ImageBudget sets the sizes which build warns about when a function's
+	// image grows beyond them.
+	ImageBudget ImageBudget `yaml:"image_budget,omitempty"`
+
+	// Debug sets how "local-run --debug-port" starts a debugger for each
+	// language, replacing the conventions built into faas-cli.
+	Debug map[string]DebugConvention `yaml:"debug,omitempty"`
+}
+
+// DebugConvention is how a debugger is started within a function's container
+type DebugConvention struct {
+	// Port the debugger listens on within the container
+	Port int `yaml:"port"`
+
+	// Environment to set, such as NODE_OPTIONS for Node.js
+	Environment map[string]string `yaml:"environment,omitempty"`
+
+	// FProcess replaces the function's fprocess, "{fprocess}" is replaced by
+	// the original, and "{args}" by the original without its first word.
+	FProcess string `yaml:"fprocess,omitempty"`
+}
+
+// ImageBudget limits the images built for functions, e.g. max_size: 200MB
+// and max_growth: 10%
+type ImageBudget struct {
+	// MaxSize of an image, such as 150MB or 1.5GiB
+	MaxSize string `yaml:"max_size,omitempty"`
+
+	// MaxGrowth of an image since the previous build, as a percentage
+	MaxGrowth string `yaml:"max_growth,omitempty"`
+
+	// MaxLayers of an image
+	MaxLayers int `yaml:"max_layers,omitempty"`
+}
+
+// ProtectedKeys lists the keys of annotations and labels, a key ending in *
+// matches every key with that prefix, e.g. "com.openfaas.iam.*"
+type ProtectedKeys struct {
+	Annotations []string `yaml:"annotations,omitempty"`
+	Labels      []string `yaml:"labels,omitempty"`
 }
 
 // TemplateSource for build templates
@@ -104,6 +260,10 @@ type TemplateSource struct {
 type FunctionResources struct {
 	Memory string `yaml:"memory"`
 	CPU    string `yaml:"cpu"`
+
+	// GPU is the GPUs given to the function by local-run, as for docker run
+	// --gpus, such as 1, all or device=0
+	GPU string `yaml:"gpu,omitempty"`
 }
 
 // EnvironmentFile represents external file for environment data
@@ -117,6 +277,14 @@ type Services struct {
 	Functions          map[string]Function `yaml:"functions,omitempty"`
 	Provider           Provider            `yaml:"provider,omitempty"`
 	StackConfiguration StackConfiguration  `yaml:"configuration,omitempty"`
+
+	// ReleaseVersion is the semantic version of the stack, such as 1.4.0,
+	// which "faas-cli release" bumps and tags the images with. Version is
+	// that of the stack file's schema.
+	ReleaseVersion string `yaml:"release_version,omitempty"`
+
+	// Extensions are the top-level fields prefixed with x-
+	Extensions Extensions `yaml:",inline"`
 }
 
 // LanguageTemplate read from template.yml within root of a language template folder
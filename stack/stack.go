@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	envsubst "github.com/drone/envsubst"
@@ -64,6 +65,47 @@ func substituteEnvironment(data []byte) ([]byte, error) {
 	return []byte(res), resErr
 }
 
+// withPrefix drops the unknown fields which the parser collected, but which
+// are not prefixed with x-, as they are more likely to be a typo than data
+// for another tool
+func (e Extensions) withPrefix() Extensions {
+	var kept Extensions
+	for name, value := range e {
+		if strings.HasPrefix(name, ExtensionPrefix) {
+			if kept == nil {
+				kept = Extensions{}
+			}
+			kept[name] = value
+		}
+	}
+	return kept
+}
+
+// dropDisabled removes the functions whose enabled expression is false
+func dropDisabled(services *Services) error {
+	var values map[string]string
+	for name, f := range services.Functions {
+		if len(f.Enabled) == 0 {
+			continue
+		}
+		if values == nil {
+			var err error
+			if values, err = conditionValues(); err != nil {
+				return err
+			}
+		}
+
+		enabled, err := Enabled(f.Enabled, values)
+		if err != nil {
+			return fmt.Errorf("invalid enabled expression for function %s: %w", name, err)
+		}
+		if !enabled {
+			delete(services.Functions, name)
+		}
+	}
+	return nil
+}
+
 // ParseYAMLData parse YAML data into a stack of "services".
 func ParseYAMLData(fileData []byte, regex string, filter string, envsubst bool) (*Services, error) {
 	var services Services
@@ -88,6 +130,19 @@ func ParseYAMLData(fileData []byte, regex string, filter string, envsubst bool)
 		return nil, err
 	}
 
+	services.Extensions = services.Extensions.withPrefix()
+	for name, f := range services.Functions {
+		f.Extensions = f.Extensions.withPrefix()
+		if len(f.Namespace) == 0 {
+			f.Namespace = services.Provider.Namespace
+		}
+		services.Functions[name] = f
+	}
+
+	if err := dropDisabled(&services); err != nil {
+		return nil, err
+	}
+
 	for _, f := range services.Functions {
 		if f.Language == "Dockerfile" {
 			f.Language = "dockerfile"
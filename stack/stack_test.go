@@ -10,6 +10,8 @@ import (
 	"sort"
 	"strings"
 	"testing"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 const TestData_1 string = `version: 1.0
@@ -500,3 +502,127 @@ func Test_substituteEnvironment_DefaultLeftWhenNil(t *testing.T) {
 		t.Errorf("subst, want: %s, got: %s", want, string(res))
 	}
 }
+
+func Test_ParseYAMLData_Extensions(t *testing.T) {
+	file := `version: 1.0
+provider:
+  name: openfaas
+x-team: payments
+functions:
+  charge:
+    lang: go
+    handler: ./charge
+    image: acme/charge:0.1.0
+    x-owner:
+      name: alice
+      oncall: true
+    scaling_typo: 5
+`
+
+	services, err := ParseYAMLData([]byte(file), "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if team, ok := services.Extensions.Lookup("team"); !ok || team != "payments" {
+		t.Errorf("want the top-level x-team, got: %v", team)
+	}
+
+	charge := services.Functions["charge"]
+	if _, ok := charge.Extensions.Lookup("x-owner"); !ok {
+		t.Errorf("want x-owner on the function, got: %v", charge.Extensions)
+	}
+	if _, ok := charge.Extensions["scaling_typo"]; ok {
+		t.Errorf("want fields without the x- prefix to be dropped, got: %v", charge.Extensions)
+	}
+
+	out, err := yaml.Marshal(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"x-team: payments", "x-owner:", "oncall: true"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("want %q to be kept when the stack is written, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "scaling_typo") {
+		t.Errorf("want unknown fields to be dropped when the stack is written, got:\n%s", out)
+	}
+}
+
+func Test_ParseYAMLData_ProviderNamespace(t *testing.T) {
+	file := `version: 1.0
+provider:
+  name: openfaas
+  namespace: tenant-a
+functions:
+  charge:
+    image: acme/charge:0.1.0
+  invoices:
+    image: acme/invoices:0.1.0
+    namespace: tenant-b
+`
+
+	services, err := ParseYAMLData([]byte(file), "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ns := services.Functions["charge"].Namespace; ns != "tenant-a" {
+		t.Errorf("want the provider's namespace for charge, got: %q", ns)
+	}
+	if ns := services.Functions["invoices"].Namespace; ns != "tenant-b" {
+		t.Errorf("want the function's own namespace for invoices, got: %q", ns)
+	}
+}
+
+func Test_ParseYAMLData_Enabled(t *testing.T) {
+	file := `version: 1.0
+provider:
+  name: openfaas
+functions:
+  charge:
+    image: acme/charge:0.1.0
+  debug:
+    image: acme/debug:0.1.0
+    enabled: debug
+  eu-tax:
+    image: acme/eu-tax:0.1.0
+    enabled: profile == "prod" && region == "eu"
+`
+
+	SetConditions("prod", []string{"region=eu"})
+	defer SetConditions("", nil)
+
+	services, err := ParseYAMLData([]byte(file), "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	want := []string{"charge", "eu-tax"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("want functions: %v, got: %v", want, names)
+	}
+}
+
+func Test_ParseYAMLData_EnabledInvalid(t *testing.T) {
+	file := `version: 1.0
+provider:
+  name: openfaas
+functions:
+  charge:
+    image: acme/charge:0.1.0
+    enabled: profile ==
+`
+
+	_, err := ParseYAMLData([]byte(file), "", "", false)
+	if err == nil || !strings.Contains(err.Error(), "charge") {
+		t.Fatalf("want an error naming the function, got: %v", err)
+	}
+}
@@ -22,6 +22,31 @@ var GitCloneDefault = &vcsCmd{
 	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
 }
 
+// GitCloneSparse defines the command to clone only the commits of a single branch
+// or tag of a repo, without checking out any folders until GitSparseCheckout is run
+var GitCloneSparse = &vcsCmd{
+	name:   "Git",
+	cmd:    "git",
+	cmds:   []string{"clone {repo} {dir} --depth={depth} --single-branch --filter=blob:none --sparse --config core.autocrlf=false -b {refname}"},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
+
+// GitCloneSparseDefault defines the command to sparsely clone the default branch of a repo
+var GitCloneSparseDefault = &vcsCmd{
+	name:   "Git",
+	cmd:    "git",
+	cmds:   []string{"clone {repo} {dir} --depth={depth} --single-branch --filter=blob:none --sparse --config core.autocrlf=false"},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
+
+// GitSparseCheckout defines the command to check out a single folder of a sparse clone
+var GitSparseCheckout = &vcsCmd{
+	name:   "Git",
+	cmd:    "git",
+	cmds:   []string{"-C {dir} sparse-checkout set {path}"},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
+
 // GitCheckout defines the command to clone a specific REF of repo into a directory
 var GitCheckout = &vcsCmd{
 	name:   "Git",